@@ -0,0 +1,145 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// BakeOptions are the options for Canvas.Bake.
+type BakeOptions struct {
+	Flatten        FlattenMethod // flatten curves into linear segments (optional)
+	OutlineStrokes bool          // convert strokes into filled outlines
+}
+
+// Bake returns a canvas of flat primitives that renders the same as c, which is trivial to consume by minimal renderers. Transformations are baked into the path coordinates so that all paths have the identity matrix, texts are converted to their outlines, and curves are flattened if opts.Flatten is set. If opts.OutlineStrokes is set, strokes including their dashes are converted to filled paths. Paints are transformed along with the paths, except for radial gradients under a non-uniform scaling or skew, patterns and custom paints whose paths keep their transformation matrix. Images keep their transformation matrix, groups are kept as they affect compositing, and clipping paths are transformed as paths.
+func (c *Canvas) Bake(opts BakeOptions) *Canvas {
+	baked := New(c.W, c.H)
+	c.Render(&bakeRenderer{baked, opts})
+	baked.err = c.err
+	return baked
+}
+
+type bakeRenderer struct {
+	c    *Canvas
+	opts BakeOptions
+}
+
+func (r *bakeRenderer) Size() (float64, float64) {
+	return r.c.Size()
+}
+
+func (r *bakeRenderer) RenderPath(path *Path, style Style, m Matrix) {
+	if !m.Equals(Identity) {
+		fillPaint, okFill := transformPaint(style.FillPaint, m)
+		strokePaint, okStroke := transformPaint(style.StrokePaint, m)
+		if !okFill || !okStroke {
+			r.c.RenderPath(path, style, m)
+			return
+		}
+		path = path.Transform(m)
+		style.FillPaint, style.StrokePaint = fillPaint, strokePaint
+	}
+
+	if r.opts.OutlineStrokes {
+		if style.StrokePaint != nil || style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth {
+			stroke := path
+			if 0 < len(style.Dashes) {
+				stroke = stroke.Dash(style.DashOffset, style.Dashes...)
+			}
+			stroke = stroke.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
+
+			strokeStyle := DefaultStyle
+			strokeStyle.FillColor = style.StrokeColor
+			strokeStyle.FillPaint = style.StrokePaint
+			style.StrokeColor = Transparent
+			style.StrokePaint = nil
+			if style.FillPaint != nil || style.FillColor.A != 0 {
+				r.renderPath(path, style)
+			}
+			r.renderPath(stroke, strokeStyle)
+			return
+		}
+	}
+	r.renderPath(path, style)
+}
+
+func (r *bakeRenderer) renderPath(path *Path, style Style) {
+	if r.opts.Flatten != nil {
+		path = path.FlattenWith(r.opts.Flatten)
+	}
+	r.c.RenderPath(path, style, Identity)
+}
+
+func (r *bakeRenderer) RenderShadow(path *Path, blur float64, col color.RGBA, m Matrix) {
+	path = path.Transform(m)
+	if r.opts.Flatten != nil {
+		path = path.FlattenWith(r.opts.Flatten)
+	}
+	r.c.RenderShadow(path, blur, col, Identity)
+}
+
+func (r *bakeRenderer) RenderInsetShadow(path *Path, dx, dy, blur float64, col color.RGBA, m Matrix) {
+	offset := m.Dot(Point{dx, dy}).Sub(m.Dot(Point{}))
+	path = path.Transform(m)
+	if r.opts.Flatten != nil {
+		path = path.FlattenWith(r.opts.Flatten)
+	}
+	r.c.RenderInsetShadow(path, offset.X, offset.Y, blur, col, Identity)
+}
+
+func (r *bakeRenderer) RenderText(text *Text, m Matrix) {
+	RenderTextAsPath(r, text, m)
+}
+
+func (r *bakeRenderer) RenderImage(img image.Image, m Matrix) {
+	r.c.RenderImage(img, m)
+}
+
+func (r *bakeRenderer) BeginGroup(isolated, knockout bool) {
+	r.c.BeginGroup(isolated, knockout)
+}
+
+func (r *bakeRenderer) EndGroup() {
+	r.c.EndGroup()
+}
+
+func (r *bakeRenderer) PushClip(path *Path, m Matrix) {
+	path = path.Transform(m)
+	if r.opts.Flatten != nil {
+		path = path.FlattenWith(r.opts.Flatten)
+	}
+	r.c.PushClip(path, Identity)
+}
+
+func (r *bakeRenderer) PopClip() {
+	r.c.PopClip()
+}
+
+// transformPaint returns the paint in the coordinate system after transforming by m, or false if the paint cannot be transformed.
+func transformPaint(paint Paint, m Matrix) (Paint, bool) {
+	switch p := paint.(type) {
+	case nil, SolidPaint:
+		return paint, true
+	case LinearGradient:
+		// the gradient direction is transformed by the inverse transpose, so that the offset of each point is preserved
+		d := p.End.Sub(p.Start)
+		inv := m.Inv()
+		g := Point{inv[0][0]*d.X + inv[1][0]*d.Y, inv[0][1]*d.X + inv[1][1]*d.Y}.Div(d.Dot(d))
+		p.Start = m.Dot(p.Start)
+		p.End = p.Start.Add(g.Div(g.Dot(g)))
+		return p, true
+	case RadialGradient:
+		// only similarity transformations keep circles circular
+		if !Equal(m[0][0]*m[0][0]+m[1][0]*m[1][0], m[0][1]*m[0][1]+m[1][1]*m[1][1]) || !Equal(m[0][0]*m[0][1]+m[1][0]*m[1][1], 0.0) {
+			return nil, false
+		}
+		p.Center = m.Dot(p.Center)
+		p.Radius *= math.Sqrt(math.Abs(m.Det()))
+		return p, true
+	case ImagePaint:
+		p.Transform = m.Mul(p.Transform)
+		return p, true
+	}
+	return nil, false
+}
@@ -0,0 +1,89 @@
+package canvas
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestCanvasBake(t *testing.T) {
+	dejaVuSerif := NewFontFamily("dejavu-serif")
+	dejaVuSerif.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := dejaVuSerif.Face(10.0, Black, FontRegular, FontNormal)
+
+	gradient := LinearGradient{Point{0.0, 0.0}, Point{4.0, 0.0}, []GradientStop{{0.0, Red}, {1.0, Blue}}, ExtendClamp}
+	radial := RadialGradient{Point{2.0, 2.0}, 2.0, []GradientStop{{0.0, Red}, {1.0, Blue}}, ExtendClamp}
+
+	c := New(20.0, 20.0)
+	ctx := NewContext(c)
+	ctx.Translate(5.0, 5.0)
+	ctx.Rotate(30.0)
+	ctx.Scale(2.0, 1.0)
+	ctx.SetFillPaint(gradient)
+	ctx.SetStrokeColor(Red)
+	ctx.SetDashes(0.0, 1.0)
+	ctx.DrawPath(0.0, 0.0, Circle(2.0))
+	ctx.BeginGroup(true, false)
+	ctx.SetFillPaint(radial)
+	ctx.SetStrokeColor(Transparent)
+	ctx.DrawPath(0.0, 0.0, Rectangle(4.0, 4.0))
+	ctx.EndGroup()
+	ctx.DrawText(1.0, 1.0, NewTextLine(face, "ab", Left))
+
+	baked := c.Bake(BakeOptions{Flatten: UniformSteps(4), OutlineStrokes: true})
+	test.T(t, baked.W, c.W)
+	test.T(t, baked.H, c.H)
+	dl := baked.DisplayList()
+	test.That(t, 5 < len(dl), len(dl))
+
+	// the circle is split into its fill and its dashed stroke
+	m := c.layers[0].m
+	fill := dl[0].(DrawPathCommand)
+	test.T(t, fill.Matrix, Identity)
+	test.That(t, !strings.ContainsAny(fill.Path.String(), "QCA"))
+	test.T(t, fill.Style.StrokeColor, Transparent)
+	for _, p := range []Point{{0.0, 0.0}, {1.0, 1.0}, {3.0, -1.0}} {
+		q := m.Dot(p)
+		test.T(t, fill.Style.FillPaint.At(q.X, q.Y), gradient.At(p.X, p.Y), p)
+	}
+	stroke := dl[1].(DrawPathCommand)
+	test.T(t, stroke.Matrix, Identity)
+	test.T(t, stroke.Style.FillColor, Red)
+	test.T(t, stroke.Style.StrokeColor, Transparent)
+	test.That(t, 1 < len(stroke.Path.Split()))
+
+	// radial gradients under non-uniform scaling keep their matrix
+	_, ok := dl[2].(BeginGroupCommand)
+	test.That(t, ok)
+	test.T(t, dl[3].(DrawPathCommand).Matrix, c.layers[2].m)
+	_, ok = dl[4].(EndGroupCommand)
+	test.That(t, ok)
+
+	// text is converted to paths
+	for _, cmd := range dl[5:] {
+		path, ok := cmd.(DrawPathCommand)
+		test.That(t, ok)
+		test.T(t, path.Matrix, Identity)
+		test.T(t, path.Style.FillColor, Black)
+	}
+}
+
+func TestTransformPaint(t *testing.T) {
+	radial := RadialGradient{Point{1.0, 2.0}, 2.0, []GradientStop{{0.0, Red}, {1.0, Blue}}, ExtendClamp}
+	m := Identity.Translate(1.0, 2.0).Rotate(45.0).Scale(3.0, 3.0)
+	paint, ok := transformPaint(radial, m)
+	test.That(t, ok)
+	test.T(t, paint.(RadialGradient).Center, m.Dot(radial.Center))
+	test.Float(t, paint.(RadialGradient).Radius, 6.0)
+
+	_, ok = transformPaint(radial, Identity.Shear(1.0, 0.0))
+	test.That(t, !ok)
+	_, ok = transformPaint(Pattern{SolidPaint{Red}, 1.0, 1.0}, m)
+	test.That(t, !ok)
+
+	img := ImagePaint{Image: nil, Transform: Identity.Scale(2.0, 2.0)}
+	paint, ok = transformPaint(img, m)
+	test.That(t, ok)
+	test.T(t, paint.(ImagePaint).Transform, m.Scale(2.0, 2.0))
+}
@@ -1,9 +1,12 @@
 package canvas
 
 import (
+	"bytes"
+	"fmt"
 	"image"
 	"image/color"
 	"io"
+	"math"
 	"os"
 )
 
@@ -27,18 +30,80 @@ type DPMM float64
 // DPI is a shortcut for Dots-per-Inch for the resolution of raster images.
 const DPI = DPMM(1 / 25.4)
 
+// Pixel returns the size of a pixel at the resolution as units, so that a canvas of width W is W*resolution pixels wide.
+func (resolution DPMM) Pixel() Units {
+	return Units(1.0 / float64(resolution))
+}
+
+// Units is a unit of length given by its length in millimeters. All coordinates, sizes, stroke widths and measurements of the canvas and its renderers are in millimeters, so that a canvas of width 100 is 100 mm wide in every output format. Renderers convert millimeters to the units of their format: points for PDF and EPS, pixels for raster images depending on the resolution, see DPMM.Pixel, and SVG user units, which are millimeters. The only exception are font sizes, which are given in points, see FontFamily.Face.
+type Units float64
+
+// see Units
+const (
+	Millimeter       Units = 1.0
+	Centimeter       Units = 10.0
+	Inch             Units = mmPerInch
+	TypographicPoint Units = mmPerPt // 1/72 inch, used by PDF and for font sizes
+)
+
+// ToMillimeters converts a length in the units to millimeters.
+func (u Units) ToMillimeters(v float64) float64 {
+	return v * float64(u)
+}
+
+// FromMillimeters converts a length in millimeters to the units.
+func (u Units) FromMillimeters(mm float64) float64 {
+	return mm / float64(u)
+}
+
 ////////////////////////////////////////////////////////////////
 
-// Style is the path style that defines how to draw the path. When FillColor is transparent it will not fill the path. If StrokeColor is transparent or StrokeWidth is zero, it will not stroke the path. If Dashes is an empty array, it will not draw dashes but instead a solid stroke line. FillRule determines how to fill the path when paths overlap and have certain directions (clockwise, counter clockwise).
+// Style is the path style that defines how to draw the path. When FillColor is transparent it will not fill the path. When FillPaint is set it fills the path instead of FillColor, renderers that do not support paints fall back to FillColor. If StrokeColor is transparent or StrokeWidth is zero, it will not stroke the path. When StrokePaint is set it strokes the path instead of StrokeColor, which must not be transparent and is used by renderers that do not support paints. If Dashes is an empty array, it will not draw dashes but instead a solid stroke line. When DashGapColor is not transparent, the gaps between dashes are stroked in that color. FillRule determines how to fill the path when paths overlap and have certain directions (clockwise, counter clockwise). FillOpenPaths determines how Context fills subpaths that are not closed, which are implicitly closed by default as renderers do. CompositeOp determines how the path is composited onto what was drawn before. Overprint prints the path on top of the inks below when separated for prepress, see Context.SetOverprint.
 type Style struct {
 	FillColor    color.RGBA
+	FillPaint    Paint
 	StrokeColor  color.RGBA
+	StrokePaint  Paint
 	StrokeWidth  float64
 	StrokeCapper Capper
 	StrokeJoiner Joiner
 	DashOffset   float64
 	Dashes       []float64
+	DashGapColor color.RGBA // color of the gaps between dashes applied by Context, transparent by default
 	FillRule
+	FillOpenPaths FillOpenPaths
+	Sketch        *Sketch // hand-drawn style applied by Context, ignored by renderers
+	CompositeOp   CompositeOp
+	Overprint     bool // print on top of the inks below instead of knocking them out, only supported by PDF
+}
+
+// CompositeOp is the Porter-Duff operator that composites a path onto what was drawn before, see Context.SetCompositeOp. Only SourceOver paints on top, the other operators can replace or erase the backdrop and are implemented by the rasterizer and the HTML canvas. Vector renderers cannot erase previously drawn content and do not draw paths with DestinationIn, DestinationOut or Clear, and draw the other operators as SourceOver, see CompositeOp.Erases.
+type CompositeOp int
+
+// see CompositeOp
+const (
+	SourceOver     CompositeOp = iota // paint the path on top of the backdrop
+	Source                            // replace the backdrop by the path
+	DestinationIn                     // keep the backdrop only where the path is
+	DestinationOut                    // erase the backdrop where the path is, in proportion to its alpha
+	SourceAtop                        // paint the path only where the backdrop is
+	Xor                               // keep the path and the backdrop where they do not overlap
+	Clear                             // erase the backdrop where the path is, regardless of the alpha of its color
+)
+
+var compositeOpNames = []string{"source-over", "source", "destination-in", "destination-out", "source-atop", "xor", "clear"}
+
+// String returns the name of the operator, such as "destination-out".
+func (op CompositeOp) String() string {
+	if 0 <= op && int(op) < len(compositeOpNames) {
+		return compositeOpNames[op]
+	}
+	return fmt.Sprintf("CompositeOp(%d)", int(op))
+}
+
+// Erases returns true if the operator only removes content from the backdrop without painting the path.
+func (op CompositeOp) Erases() bool {
+	return op == DestinationIn || op == DestinationOut || op == Clear
 }
 
 // DefaultStyle is the default style for paths. It fills the path with a black color.
@@ -61,6 +126,58 @@ type Renderer interface {
 	RenderImage(img image.Image, m Matrix)
 }
 
+// GroupRenderer is implemented by renderers that support transparency groups, see Context.BeginGroup.
+type GroupRenderer interface {
+	BeginGroup(isolated, knockout bool)
+	EndGroup()
+}
+
+// ClipRenderer is implemented by renderers that support clipping paths, see Context.ClipPath. PushClip intersects the current clipping region with the area of the path, and PopClip restores the clipping region from before the matching PushClip.
+type ClipRenderer interface {
+	PushClip(path *Path, m Matrix)
+	PopClip()
+}
+
+// ShadowRenderer is implemented by renderers that support soft shadows, see Context.DrawShadow.
+type ShadowRenderer interface {
+	RenderShadow(path *Path, blur float64, col color.RGBA, m Matrix)
+}
+
+// renderShadow renders a soft shadow of the path, or fills the path with the color for renderers that do not support shadows.
+func renderShadow(r Renderer, path *Path, blur float64, col color.RGBA, m Matrix) {
+	if sr, ok := r.(ShadowRenderer); ok {
+		sr.RenderShadow(path, blur, col, m)
+		return
+	}
+	style := DefaultStyle
+	style.FillColor = col
+	r.RenderPath(path, style, m)
+}
+
+// InsetShadowRenderer is implemented by renderers that support soft shadows inside of paths, see Context.DrawInsetShadow.
+type InsetShadowRenderer interface {
+	RenderInsetShadow(path *Path, dx, dy, blur float64, col color.RGBA, m Matrix)
+}
+
+// renderInsetShadow renders a soft shadow inside of the path, or strokes the inside of the edges of the path for renderers that do not support inset shadows but support clipping.
+func renderInsetShadow(r Renderer, path *Path, dx, dy, blur float64, col color.RGBA, m Matrix) {
+	if sr, ok := r.(InsetShadowRenderer); ok {
+		sr.RenderInsetShadow(path, dx, dy, blur, col, m)
+		return
+	}
+	cr, ok := r.(ClipRenderer)
+	if !ok || blur <= 0.0 {
+		return
+	}
+	style := DefaultStyle
+	style.FillColor = Transparent
+	style.StrokeColor = col
+	style.StrokeWidth = 2.0 * blur
+	cr.PushClip(path, m)
+	r.RenderPath(path, style, m.Translate(dx, dy))
+	cr.PopClip()
+}
+
 ////////////////////////////////////////////////////////////////
 
 type CoordSystem int
@@ -83,11 +200,15 @@ type Context struct {
 	viewStack      []Matrix
 	coordView      Matrix
 	coordViewStack []Matrix
+	clips          int
+	clipsStack     []int
+	groups         []int // number of clips at the start of each group
+	err            error
 }
 
 // NewContext returns a new Context which is a wrapper around a Renderer. Context maintains state for the current path, path style, and view transformation matrix.
 func NewContext(r Renderer) *Context {
-	return &Context{r, &Path{}, DefaultStyle, nil, Identity, nil, Identity, nil}
+	return &Context{r, &Path{}, DefaultStyle, nil, Identity, nil, Identity, nil, 0, nil, nil, nil}
 }
 
 // Width returns the width of the canvas.
@@ -107,6 +228,7 @@ func (c *Context) Push() {
 	c.styleStack = append(c.styleStack, c.Style)
 	c.viewStack = append(c.viewStack, c.view)
 	c.coordViewStack = append(c.coordViewStack, c.coordView)
+	c.clipsStack = append(c.clipsStack, c.clips)
 }
 
 // Pop restores the last pushed draw state and uses that as the current draw state. If there are no states on the stack, this will do nothing.
@@ -120,6 +242,11 @@ func (c *Context) Pop() {
 	c.viewStack = c.viewStack[:len(c.viewStack)-1]
 	c.coordView = c.coordViewStack[len(c.coordViewStack)-1]
 	c.coordViewStack = c.coordViewStack[:len(c.coordViewStack)-1]
+	clips := c.clipsStack[len(c.clipsStack)-1]
+	c.clipsStack = c.clipsStack[:len(c.clipsStack)-1]
+	for ; clips < c.clips; c.clips-- {
+		c.Renderer.(ClipRenderer).PopClip()
+	}
 }
 
 // SetCoordView sets the current affine transformation matrix through which all operation coordinates will be transformed.
@@ -223,12 +350,25 @@ func (c *Context) SetFillColor(col color.Color) {
 	c.Style.FillColor = color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
 }
 
+// SetFillPaint sets the paint to be used for filling operations instead of the fill color, such as an ImagePaint. Set to nil to fill using the fill color.
+func (c *Context) SetFillPaint(paint Paint) {
+	c.Style.FillPaint = paint
+}
+
 // SetStrokeColor sets the color to be used for stroking operations.
 func (c *Context) SetStrokeColor(col color.Color) {
 	r, g, b, a := col.RGBA()
 	c.Style.StrokeColor = color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
 }
 
+// SetStrokePaint sets the paint to be used for stroking operations instead of the stroke color, such as a LinearGradient. The stroke color is set to a color representing the paint for renderers that do not support it. Set to nil to stroke using the stroke color.
+func (c *Context) SetStrokePaint(paint Paint) {
+	c.Style.StrokePaint = paint
+	if paint != nil {
+		c.Style.StrokeColor = paintColor(paint, c.Style.StrokeColor)
+	}
+}
+
 // SetStrokeWidth sets the width in mm for stroking operations.
 func (c *Context) SetStrokeWidth(width float64) {
 	c.Style.StrokeWidth = width
@@ -250,11 +390,129 @@ func (c *Context) SetDashes(offset float64, dashes ...float64) {
 	c.Style.Dashes = dashes
 }
 
+// SetDashGapColor sets the color of the gaps between dashes, so that dashed lines alternate between two colors such as for railways on maps. The gaps are stroked as dashes of their own with butt caps below the dashes, or as a solid line below the dashes when the stroke color is opaque so that the colors meet without seams. The default is transparent, which leaves the gaps empty.
+func (c *Context) SetDashGapColor(col color.Color) {
+	r, g, b, a := col.RGBA()
+	c.Style.DashGapColor = color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+}
+
 // SetFillRule sets the fill rule to be used for filling paths.
 func (c *Context) SetFillRule(rule FillRule) {
 	c.Style.FillRule = rule
 }
 
+// SetFillOpenPaths sets the policy for filling subpaths that are not closed, which are closed implicitly by default. With SkipOpenPaths only the closed subpaths are filled while open subpaths are still stroked. With ErrorOpenPaths paths with open subpaths are not filled and Err returns ErrOpenPath.
+func (c *Context) SetFillOpenPaths(policy FillOpenPaths) {
+	c.Style.FillOpenPaths = policy
+}
+
+// SetSketch sets the hand-drawn style for drawing paths, such as Sketchy(seed, roughness), where outlines are stroked as wobbly lines and fills become hatch lines in the fill color. Set to nil to draw paths exactly, which is the default.
+func (c *Context) SetSketch(sketch *Sketch) {
+	c.Style.Sketch = sketch
+}
+
+// SetCompositeOp sets the operator that composites paths onto what was drawn before, such as DestinationOut to erase content where a path is drawn so that the gaps of dashes reveal the background. The default is SourceOver.
+func (c *Context) SetCompositeOp(op CompositeOp) {
+	c.Style.CompositeOp = op
+}
+
+// SetOverprint sets whether paths are printed on top of the inks below instead of knocking them out, such as for black text outlines and lines in prepress so that misregistration of the printing plates does not leave gaps. Overprinting has an effect in separation color spaces, such as for spot colors (see SpotColor) and DeviceCMYK when separated by prepress tools, and is written to PDF only, where it applies to the fills and strokes of paths. The default is false.
+func (c *Context) SetOverprint(overprint bool) {
+	c.Style.Overprint = overprint
+}
+
+// Err returns ErrOpenPath if a path with open subpaths was filled while the fill policy is ErrorOpenPaths, ErrUnbalancedGroup if EndGroup was called without a matching BeginGroup, or otherwise the error of the renderer if it has an Err method, such as Canvas.Err.
+func (c *Context) Err() error {
+	if c.err != nil {
+		return c.err
+	} else if r, ok := c.Renderer.(interface{ Err() error }); ok {
+		return r.Err()
+	}
+	return nil
+}
+
+// renderPath renders the path while applying the policy for filling open subpaths, where the fill and stroke are rendered separately when they use different paths.
+func (c *Context) renderPath(path *Path, style Style, m Matrix) {
+	if style.Sketch != nil {
+		c.renderSketch(path, style, m)
+		return
+	}
+	if style.DashGapColor.A != 0 && 0 < len(style.Dashes) && 0.0 < style.StrokeWidth {
+		c.renderDashGaps(path, style, m)
+		style.DashGapColor = Transparent
+	}
+	if style.FillOpenPaths == CloseOpenPaths || style.FillColor.A == 0 && style.FillPaint == nil {
+		c.RenderPath(path, style, m) // renderers close subpaths implicitly
+		return
+	}
+
+	fill, err := path.ResolveOpenPaths(style.FillOpenPaths)
+	if err != nil {
+		c.err = err
+		fill = &Path{}
+	} else if fill == path {
+		c.RenderPath(path, style, m)
+		return
+	}
+
+	if !fill.Empty() {
+		fillStyle := style
+		fillStyle.StrokeColor, fillStyle.StrokePaint = Transparent, nil
+		c.RenderPath(fill, fillStyle, m)
+	}
+	if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth || style.StrokePaint != nil {
+		strokeStyle := style
+		strokeStyle.FillColor, strokeStyle.FillPaint = Transparent, nil
+		c.RenderPath(path, strokeStyle, m)
+	}
+}
+
+// renderDashGaps strokes the gaps between the dashes of the style in the dash gap color.
+func (c *Context) renderDashGaps(path *Path, style Style, m Matrix) {
+	gapStyle := style
+	gapStyle.FillColor, gapStyle.FillPaint = Transparent, nil
+	gapStyle.StrokeColor, gapStyle.StrokePaint = style.DashGapColor, nil
+	gapStyle.DashGapColor = Transparent
+	if style.StrokeColor.A == 255 && style.StrokePaint == nil {
+		gapStyle.Dashes = nil
+	} else {
+		// the gaps are the dashes of the dash pattern rotated by the first dash
+		dashes := style.Dashes
+		if len(dashes)%2 == 1 {
+			dashes = append(dashes[:len(dashes):len(dashes)], dashes...)
+		}
+		gapStyle.Dashes = append(append([]float64{}, dashes[1:]...), dashes[0])
+		gapStyle.DashOffset = style.DashOffset - dashes[0]
+		gapStyle.StrokeCapper = ButtCap
+	}
+	c.RenderPath(path, gapStyle, m)
+}
+
+// renderSketch renders the path in the hand-drawn style of the style, see Sketch.
+func (c *Context) renderSketch(path *Path, style Style, m Matrix) {
+	sketch := style.Sketch
+	style.Sketch = nil
+	if style.FillColor.A != 0 || style.FillPaint != nil {
+		fill, err := path.ResolveOpenPaths(style.FillOpenPaths)
+		if err != nil {
+			c.err = err
+		} else if hachure := sketch.Hachure(fill, style.FillRule); !hachure.Empty() {
+			fillStyle := style
+			fillStyle.StrokeColor, fillStyle.StrokePaint = style.FillColor, style.FillPaint
+			fillStyle.FillColor, fillStyle.FillPaint = Transparent, nil
+			fillStyle.StrokeWidth = sketch.HachureWidth
+			fillStyle.StrokeCapper, fillStyle.StrokeJoiner = RoundCap, RoundJoin
+			fillStyle.Dashes = nil
+			c.RenderPath(hachure, fillStyle, m)
+		}
+	}
+	if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth || style.StrokePaint != nil {
+		strokeStyle := style
+		strokeStyle.FillColor, strokeStyle.FillPaint = Transparent, nil
+		c.RenderPath(sketch.Outline(path), strokeStyle, m)
+	}
+}
+
 // ResetStyle resets the draw state to its default (colors, stroke widths, dashes, ...).
 func (c *Context) ResetStyle() {
 	c.Style = DefaultStyle
@@ -312,7 +570,8 @@ func (c *Context) Close() {
 func (c *Context) Fill() {
 	style := c.Style
 	style.StrokeColor = Transparent
-	c.RenderPath(c.path, style, c.view)
+	style.StrokePaint = nil
+	c.renderPath(c.path, style, c.view)
 	c.path = &Path{}
 }
 
@@ -320,19 +579,20 @@ func (c *Context) Fill() {
 func (c *Context) Stroke() {
 	style := c.Style
 	style.FillColor = Transparent
-	c.RenderPath(c.path, style, c.view)
+	style.FillPaint = nil
+	c.renderPath(c.path, style, c.view)
 	c.path = &Path{}
 }
 
 // FillStroke fills and then strokes the current path and resets it.
 func (c *Context) FillStroke() {
-	c.RenderPath(c.path, c.Style, c.view)
+	c.renderPath(c.path, c.Style, c.view)
 	c.path = &Path{}
 }
 
-// DrawPath draws a path at position (x,y) using the current draw state.
+// DrawPath draws a path at position (x,y) using the current draw state, where coordinates are in millimeters unless changed by the view or coordinate system of the context.
 func (c *Context) DrawPath(x, y float64, paths ...*Path) {
-	if c.Style.FillColor.A == 0 && (c.Style.StrokeColor.A == 0 || c.Style.StrokeWidth == 0.0) {
+	if c.Style.FillColor.A == 0 && c.Style.FillPaint == nil && (c.Style.StrokeColor.A == 0 || c.Style.StrokeWidth == 0.0) {
 		return
 	}
 
@@ -346,7 +606,55 @@ func (c *Context) DrawPath(x, y float64, paths ...*Path) {
 		}
 		style := c.Style
 		style.Dashes = dashes
-		c.RenderPath(path, style, m)
+		c.renderPath(path, style, m)
+	}
+}
+
+// FillPath fills a path with the paint using the current draw state, such as the fill rule and the affine transformation matrix, without changing the fill style of the context. A SolidPaint fills with its color.
+func (c *Context) FillPath(p *Path, paint Paint) {
+	style := c.Style
+	style.FillColor, style.FillPaint = paintColor(paint, style.FillColor), paint
+	if _, ok := paint.(SolidPaint); ok {
+		style.FillPaint = nil
+	}
+	style.StrokeColor, style.StrokePaint = Transparent, nil
+	if !p.Empty() {
+		c.renderPath(p, style, c.view)
+	}
+}
+
+// DrawShadow draws a soft shadow of the path, which is its silhouette translated by offset, given as (dx,dy), and blurred by a Gaussian blur with a standard deviation of blur, in the given color. Shadows are drawn behind everything that is drawn afterwards. The path and offset are in the coordinate system of the view, similar to Context.FillPath, while the blur is in millimeters of the canvas similar to the stroke width. The shadow extends up to three times the blur beyond the path. Renderers that do not implement ShadowRenderer draw the silhouette without blur.
+func (c *Context) DrawShadow(p *Path, offset [2]float64, blur float64, col color.Color) {
+	r, g, b, a := col.RGBA()
+	if a == 0 || p.Empty() {
+		return
+	}
+	rgba := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+	renderShadow(c.Renderer, p, math.Max(0.0, blur), rgba, c.view.Translate(offset[0], offset[1]))
+}
+
+// DrawInsetShadow draws a soft shadow inside of the path, such as for pressed buttons or inner glows. It is the inverse of the silhouette of the path offset by (dx,dy) and blurred by a Gaussian blur with a standard deviation of blur, in the given color, that is clipped to the path so that it darkens the inside of its edges and never extends beyond the path. The path and offset are in the coordinate system of the view, while the blur is in millimeters of the canvas, see DrawShadow. Renderers that do not implement InsetShadowRenderer stroke the inside of the edges of the path without blur, or draw nothing if they do not support clipping.
+func (c *Context) DrawInsetShadow(p *Path, dx, dy, blur float64, col color.RGBA) {
+	if col.A == 0 || p.Empty() {
+		return
+	}
+	renderInsetShadow(c.Renderer, p, dx, dy, math.Max(0.0, blur), col, c.view)
+}
+
+// StrokePath strokes a path with the paint using the current draw state, such as the stroke width, capper, joiner and dashes and the affine transformation matrix, without changing the stroke style of the context. A SolidPaint strokes with its color.
+func (c *Context) StrokePath(p *Path, paint Paint) {
+	style := c.Style
+	style.StrokeColor, style.StrokePaint = paintColor(paint, style.StrokeColor), paint
+	if _, ok := paint.(SolidPaint); ok {
+		style.StrokePaint = nil
+	}
+	style.FillColor, style.FillPaint = Transparent, nil
+
+	var dashes []float64
+	p, dashes = p.checkDash(style.DashOffset, style.Dashes)
+	style.Dashes = dashes
+	if !p.Empty() && 0.0 < style.StrokeWidth {
+		c.renderPath(p, style, c.view)
 	}
 }
 
@@ -364,12 +672,14 @@ func (c *Context) DrawText(x, y float64, texts ...*Text) {
 
 // RenderTextAsPath renders the text converted to paths (calling r.RenderPath)
 func RenderTextAsPath(r Renderer, text *Text, m Matrix) {
-	paths, colors := text.ToPaths()
-	for i, path := range paths {
-		style := DefaultStyle
-		style.FillColor = colors[i]
-		r.RenderPath(path, style, m)
-	}
+	text.RenderLayers(r, m, func() {
+		paths, colors := text.glyphPaths()
+		for i, path := range paths {
+			style := DefaultStyle
+			style.FillColor = colors[i]
+			r.RenderPath(path, style, m)
+		}
+	})
 }
 
 // DrawImage draws an image at position (x,y), using an image encoding (Lossy or Lossless) and DPM (dots-per-millimeter). A higher DPM will draw a smaller image.
@@ -383,27 +693,104 @@ func (c *Context) DrawImage(x, y float64, img image.Image, dpm float64) {
 	c.RenderImage(img, m)
 }
 
+// BeginGroup starts a transparency group, all drawing operations until the matching EndGroup are composited together before being composited with the backdrop as a unit. An isolated group is composited onto a fully transparent backdrop instead of onto what was drawn before. In a knockout group each element is composited onto the initial backdrop of the group, replacing the elements drawn before it within the group instead of compositing on top of them, so that overlapping semi-transparent shapes do not darken where they overlap. Groups can be nested and are ignored by renderers that do not implement GroupRenderer.
+func (c *Context) BeginGroup(isolated, knockout bool) {
+	c.groups = append(c.groups, c.clips)
+	if r, ok := c.Renderer.(GroupRenderer); ok {
+		r.BeginGroup(isolated, knockout)
+	}
+}
+
+// EndGroup ends the transparency group started by BeginGroup. Clipping paths set within the group must be restored by Pop before. Otherwise, or when there is no group, the call is ignored and Err returns ErrUnbalancedGroup.
+func (c *Context) EndGroup() {
+	if len(c.groups) == 0 || c.groups[len(c.groups)-1] != c.clips {
+		c.err = ErrUnbalancedGroup
+		return
+	}
+	c.groups = c.groups[:len(c.groups)-1]
+	if r, ok := c.Renderer.(GroupRenderer); ok {
+		r.EndGroup()
+	}
+}
+
+// ClipPath intersects the clipping region with the area of the path at position (x,y), so that all drawing operations that follow only show through the path. The clipping region is restored upon Pop, and the path is filled with the non-zero winding rule. It is ignored by renderers that do not implement ClipRenderer.
+func (c *Context) ClipPath(x, y float64, p *Path) {
+	r, ok := c.Renderer.(ClipRenderer)
+	if !ok {
+		return
+	}
+	coord := c.coordView.Dot(Point{x, y})
+	r.PushClip(p, c.view.Translate(coord.X, coord.Y))
+	c.clips++
+}
+
+// ClipText intersects the clipping region with the outlines of the glyphs of the text line in the given font face at position (x,y), so that images and gradients drawn afterwards only show through the letters, see ClipPath. Decorations of the font face are included, but emoji that are drawn separately by an EmojiProvider are not.
+func (c *Context) ClipText(x, y float64, ff FontFace, s string) {
+	paths, _ := NewTextLine(ff, s, Left).ToPaths()
+	clip := &Path{}
+	for _, path := range paths {
+		clip = clip.Append(path)
+	}
+	c.ClipPath(x, y, clip)
+}
+
+// DrawCMYKImage draws a CMYK JPEG or TIFF image given by its raw bytes, where m transforms the image from pixel coordinates. The image is embedded without conversion to RGB by renderers that support CMYK (such as PDF), see NewCMYKImage.
+func (c *Context) DrawCMYKImage(data []byte, m Matrix) error {
+	img, err := NewCMYKImage(bytes.NewReader(data))
+	if err != nil {
+		return err
+	} else if img.Bounds().Size().Eq(image.Point{}) {
+		return nil
+	}
+	c.RenderImage(img, c.view.Mul(m))
+	return nil
+}
+
 ////////////////////////////////////////////////////////////////
 ////////////////////////////////////////////////////////////////
 ////////////////////////////////////////////////////////////////
 
 type layer struct {
-	// path, text OR img is set
-	path *Path
-	text *Text
-	img  image.Image
+	// path, text, img, group OR clip is set, shadow is set together with path
+	path   *Path
+	text   *Text
+	img    image.Image
+	group  *groupLayer
+	clip   *clipLayer
+	shadow *shadowLayer
 
 	m     Matrix
 	style Style // only for path
 }
 
+// groupLayer marks the begin or end of a transparency group.
+type groupLayer struct {
+	end                bool
+	isolated, knockout bool
+}
+
+// clipLayer marks the push of a clipping path, or its pop when path is nil.
+type clipLayer struct {
+	path *Path
+}
+
+// shadowLayer is a soft shadow of the path of the layer, or inside of the path for inset shadows.
+type shadowLayer struct {
+	blur  float64
+	color color.RGBA
+
+	inset  bool
+	offset Point // offset of inset shadows, which is part of the matrix for other shadows
+}
+
 // Canvas stores all drawing operations as layers that can be re-rendered to other renderers.
 type Canvas struct {
 	layers []layer
 	W, H   float64
+	err    error
 }
 
-// New returns a new Canvas that records all drawing operations into layers. The canvas can then be rendered to any other renderer.
+// New returns a new Canvas of width and height in millimeters that records all drawing operations into layers. The canvas can then be rendered to any other renderer.
 func New(width, height float64) *Canvas {
 	return &Canvas{
 		layers: []layer{},
@@ -417,14 +804,27 @@ func (c *Canvas) Size() (float64, float64) {
 	return c.W, c.H
 }
 
+// SizeIn returns the size of the canvas in the given units, such as TypographicPoint for the size of a PDF page or resolution.Pixel() for the size of a raster image.
+func (c *Canvas) SizeIn(u Units) (float64, float64) {
+	return u.FromMillimeters(c.W), u.FromMillimeters(c.H)
+}
+
 // RenderPath renders a path to the canvas using a style and a transformation matrix.
 func (c *Canvas) RenderPath(path *Path, style Style, m Matrix) {
+	if 0 < MaxPathPoints && MaxPathPoints < path.segments() {
+		c.err = ErrLimitExceeded
+		return
+	}
 	path = path.Copy()
 	c.layers = append(c.layers, layer{path: path, m: m, style: style})
 }
 
 // RenderText renders a text object to the canvas using a transformation matrix.
 func (c *Canvas) RenderText(text *Text, m Matrix) {
+	if err := text.Err(); err != nil {
+		c.err = err
+		return
+	}
 	c.layers = append(c.layers, layer{text: text, m: m})
 }
 
@@ -433,6 +833,51 @@ func (c *Canvas) RenderImage(img image.Image, m Matrix) {
 	c.layers = append(c.layers, layer{img: img, m: m})
 }
 
+// RenderShadow renders a soft shadow of a path to the canvas, see Context.DrawShadow.
+func (c *Canvas) RenderShadow(path *Path, blur float64, col color.RGBA, m Matrix) {
+	if 0 < MaxPathPoints && MaxPathPoints < path.segments() {
+		c.err = ErrLimitExceeded
+		return
+	}
+	path = path.Copy()
+	c.layers = append(c.layers, layer{path: path, m: m, shadow: &shadowLayer{blur: blur, color: col}})
+}
+
+// RenderInsetShadow renders a soft shadow inside of a path to the canvas, see Context.DrawInsetShadow.
+func (c *Canvas) RenderInsetShadow(path *Path, dx, dy, blur float64, col color.RGBA, m Matrix) {
+	if 0 < MaxPathPoints && MaxPathPoints < path.segments() {
+		c.err = ErrLimitExceeded
+		return
+	}
+	path = path.Copy()
+	c.layers = append(c.layers, layer{path: path, m: m, shadow: &shadowLayer{blur: blur, color: col, inset: true, offset: Point{dx, dy}}})
+}
+
+// BeginGroup starts a transparency group on the canvas, see Context.BeginGroup.
+func (c *Canvas) BeginGroup(isolated, knockout bool) {
+	c.layers = append(c.layers, layer{group: &groupLayer{isolated: isolated, knockout: knockout}})
+}
+
+// EndGroup ends a transparency group on the canvas.
+func (c *Canvas) EndGroup() {
+	c.layers = append(c.layers, layer{group: &groupLayer{end: true}})
+}
+
+// PushClip intersects the clipping region of the canvas with the area of the path, see Context.ClipPath.
+func (c *Canvas) PushClip(path *Path, m Matrix) {
+	c.layers = append(c.layers, layer{clip: &clipLayer{path: path.Copy()}, m: m})
+}
+
+// PopClip restores the clipping region of the canvas from before the matching PushClip.
+func (c *Canvas) PopClip() {
+	c.layers = append(c.layers, layer{clip: &clipLayer{}})
+}
+
+// Err returns ErrLimitExceeded if a path exceeding MaxPathPoints or a text exceeding MaxTextLength or MaxGlyphs was rendered to the canvas, in which case it was left out. The error is returned by WriteFile as well.
+func (c *Canvas) Err() error {
+	return c.err
+}
+
 // Empty return true if the canvas is empty.
 func (c *Canvas) Empty() bool {
 	return len(c.layers) == 0
@@ -441,6 +886,7 @@ func (c *Canvas) Empty() bool {
 // Reset empties the canvas.
 func (c *Canvas) Reset() {
 	c.layers = c.layers[:0]
+	c.err = nil
 }
 
 // Fit shrinks the canvas size so all elements fit. The elements are translated towards the origin when any left/bottom margins exist and the canvas size is decreased if any margins exist. It will maintain a given margin.
@@ -451,11 +897,24 @@ func (c *Canvas) Fit(margin float64) {
 		return
 	}
 
+	rect := c.bounds()
+	for i := range c.layers {
+		c.layers[i].m = Identity.Translate(-rect.X+margin, -rect.Y+margin).Mul(c.layers[i].m)
+	}
+	c.W = rect.W + 2*margin
+	c.H = rect.H + 2*margin
+}
+
+// bounds returns the bounding box of all drawing operations, including strokes and shadows.
+func (c *Canvas) bounds() Rect {
 	rect := Rect{}
+	first := true
 	// TODO: slow when we have many paths (see Graph example)
-	for i, l := range c.layers {
+	for _, l := range c.layers {
 		bounds := Rect{}
-		if l.path != nil {
+		if l.group != nil || l.clip != nil {
+			continue
+		} else if l.path != nil {
 			bounds = l.path.Bounds()
 			if l.style.StrokeColor.A != 0 && 0.0 < l.style.StrokeWidth {
 				bounds.X -= l.style.StrokeWidth / 2.0
@@ -470,35 +929,63 @@ func (c *Canvas) Fit(margin float64) {
 			bounds = Rect{0.0, 0.0, float64(size.X), float64(size.Y)}
 		}
 		bounds = bounds.Transform(l.m)
-		if i == 0 {
+		if l.shadow != nil && !l.shadow.inset {
+			margin := 3.0 * l.shadow.blur
+			bounds = Rect{bounds.X - margin, bounds.Y - margin, bounds.W + 2.0*margin, bounds.H + 2.0*margin}
+		}
+		if first {
 			rect = bounds
+			first = false
 		} else {
 			rect = rect.Add(bounds)
 		}
 	}
-	for i := range c.layers {
-		c.layers[i].m = Identity.Translate(-rect.X+margin, -rect.Y+margin).Mul(c.layers[i].m)
-	}
-	c.W = rect.W + 2*margin
-	c.H = rect.H + 2*margin
+	return rect
 }
 
-// Render renders the accumulated canvas drawing operations to another renderer.
-func (c *Canvas) Render(r Renderer) {
-	view := Identity
-	if viewer, ok := r.(interface{ View() Matrix }); ok {
-		view = viewer.View()
+// ContactSheet returns a canvas that tiles the given canvases in a grid with cols columns, where each canvas is scaled to fit and centered in a square cell of size cellSize. The number of rows is as many as needed to fit all canvases, leaving the remaining cells of the last row blank. When labels are given, each cell receives a caption below the canvas using font face ff, the caption area adds the line height of the font face to the height of each row. Labels may be empty or missing for some canvases.
+func ContactSheet(canvases []*Canvas, cols int, cellSize float64, labels []string, ff FontFace) *Canvas {
+	if cols < 1 {
+		cols = 1
 	}
-	for _, l := range c.layers {
-		m := view.Mul(l.m)
-		if l.path != nil {
-			r.RenderPath(l.path, l.style, m)
-		} else if l.text != nil {
-			r.RenderText(l.text, m)
-		} else if l.img != nil {
-			r.RenderImage(l.img, m)
+	rows := (len(canvases) + cols - 1) / cols
+
+	captionHeight := 0.0
+	if 0 < len(labels) && ff.Font != nil {
+		captionHeight = ff.Metrics().LineHeight
+	}
+	rowHeight := cellSize + captionHeight
+
+	sheet := New(float64(cols)*cellSize, float64(rows)*rowHeight)
+	for i, c := range canvases {
+		x := float64(i%cols) * cellSize
+		y := sheet.H - float64(i/cols+1)*rowHeight // bottom of the cell
+		if c != nil && 0.0 < c.W && 0.0 < c.H {
+			scale := math.Min(cellSize/c.W, cellSize/c.H)
+			dx := (cellSize - scale*c.W) / 2.0
+			dy := (cellSize - scale*c.H) / 2.0
+			m := Identity.Translate(x+dx, y+captionHeight+dy).Scale(scale, scale)
+			for _, l := range c.layers {
+				l.m = m.Mul(l.m)
+				sheet.layers = append(sheet.layers, l)
+			}
+		}
+		if i < len(labels) && labels[i] != "" && 0.0 < captionHeight {
+			text := NewTextLine(ff, labels[i], Center)
+			sheet.RenderText(text, Identity.Translate(x+cellSize/2.0, y+captionHeight-ff.Metrics().Ascent))
 		}
 	}
+	return sheet
+}
+
+// Render renders the accumulated canvas drawing operations to another renderer.
+func (c *Canvas) Render(r Renderer) {
+	c.DisplayList().Render(r)
+}
+
+// RenderProgress renders the accumulated canvas drawing operations to another renderer as Render, and reports the number of rendered drawing operations to progress, see DisplayList.RenderProgress.
+func (c *Canvas) RenderProgress(r Renderer, progress Progress) {
+	c.DisplayList().RenderProgress(r, progress)
 }
 
 // Writer can write a canvas to a writer
@@ -506,6 +993,9 @@ type Writer func(w io.Writer, c *Canvas) error
 
 // WriteFile writes the canvas to a file named by filename using the given Writer (for the encoding).
 func (c *Canvas) WriteFile(filename string, w Writer) error {
+	if c.err != nil {
+		return c.err
+	}
 	f, err := os.Create(filename)
 	if err != nil {
 		return err
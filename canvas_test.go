@@ -65,3 +65,185 @@ func TestCanvasFit(t *testing.T) {
 	test.Float(t, c.W, 20)
 	test.Float(t, c.H, 20)
 }
+
+func TestCanvasGroup(t *testing.T) {
+	c := New(100, 100)
+	ctx := NewContext(c)
+	ctx.BeginGroup(true, false)
+	ctx.DrawPath(20.0, 20.0, Rectangle(10.0, 10.0))
+	ctx.EndGroup()
+	test.T(t, len(c.layers), 3)
+
+	// groups are ignored for the bounds
+	c.Fit(0.0)
+	test.Float(t, c.W, 10)
+	test.Float(t, c.H, 10)
+
+	c2 := New(100, 100)
+	c.Render(c2)
+	test.T(t, len(c2.layers), 3)
+	test.T(t, *c2.layers[0].group, groupLayer{false, true, false})
+	test.T(t, *c2.layers[2].group, groupLayer{true, false, false})
+
+	// unbalanced groups
+	c = New(100, 100)
+	ctx = NewContext(c)
+	ctx.EndGroup()
+	test.T(t, ctx.Err(), ErrUnbalancedGroup)
+	test.T(t, len(c.layers), 0)
+
+	c = New(100, 100)
+	ctx = NewContext(c)
+	ctx.BeginGroup(true, false)
+	ctx.ClipPath(0.0, 0.0, Rectangle(10.0, 10.0))
+	ctx.EndGroup()
+	test.T(t, ctx.Err(), ErrUnbalancedGroup)
+	test.T(t, len(c.layers), 2)
+}
+
+func TestContextClip(t *testing.T) {
+	c := New(100, 100)
+	ctx := NewContext(c)
+	ctx.Push()
+	ctx.Translate(10.0, 0.0)
+	ctx.ClipPath(10.0, 10.0, Rectangle(20.0, 20.0))
+	ctx.Push()
+	ctx.ClipPath(0.0, 0.0, Circle(10.0))
+	ctx.DrawPath(0.0, 0.0, Rectangle(100.0, 100.0))
+	ctx.Pop()
+	ctx.Pop()
+	test.T(t, len(c.layers), 5)
+	test.T(t, c.layers[0].m, Identity.Translate(20.0, 10.0))
+	test.T(t, c.layers[3].clip.path, (*Path)(nil))
+	test.T(t, c.layers[4].clip.path, (*Path)(nil))
+
+	// clips are popped at the end of the display list
+	ctx.ClipPath(0.0, 0.0, Rectangle(20.0, 20.0))
+	c2 := New(100, 100)
+	c.Render(c2)
+	test.T(t, len(c2.layers), 7)
+	test.T(t, c2.layers[6].clip.path, (*Path)(nil))
+}
+
+func TestContactSheet(t *testing.T) {
+	dejaVuSerif := NewFontFamily("dejavu-serif")
+	dejaVuSerif.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := dejaVuSerif.Face(10.0, Black, FontRegular, FontNormal)
+	lineHeight := face.Metrics().LineHeight
+
+	canvases := []*Canvas{}
+	for i := 0; i < 5; i++ {
+		c := New(10.0, 20.0)
+		c.RenderPath(Rectangle(10.0, 20.0), DefaultStyle, Identity)
+		canvases = append(canvases, c)
+	}
+
+	sheet := ContactSheet(canvases, 2, 40.0, []string{"a", "b", "", "d"}, face)
+	test.Float(t, sheet.W, 80.0)
+	test.Float(t, sheet.H, 3.0*(40.0+lineHeight))
+
+	paths, texts := []Rect{}, 0
+	for _, l := range sheet.layers {
+		if l.path != nil {
+			paths = append(paths, l.path.Bounds().Transform(l.m))
+		} else if l.text != nil {
+			texts++
+		}
+	}
+	test.T(t, len(paths), 5)
+	test.T(t, texts, 3)
+	for i, bounds := range paths {
+		x := float64(i%2)*40.0 + 10.0
+		y := sheet.H - float64(i/2+1)*(40.0+lineHeight) + lineHeight
+		test.T(t, bounds, Rect{x, y, 20.0, 40.0})
+	}
+
+	sheet = ContactSheet(canvases[:1], 3, 40.0, nil, FontFace{})
+	test.Float(t, sheet.W, 120.0)
+	test.Float(t, sheet.H, 40.0)
+}
+
+func TestCanvasLimits(t *testing.T) {
+	maxTextLength, maxGlyphs, maxPathPoints := MaxTextLength, MaxGlyphs, MaxPathPoints
+	defer func() {
+		MaxTextLength, MaxGlyphs, MaxPathPoints = maxTextLength, maxGlyphs, maxPathPoints
+	}()
+
+	dejaVuSerif := NewFontFamily("dejavu-serif")
+	dejaVuSerif.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := dejaVuSerif.Face(10.0, Black, FontRegular, FontNormal)
+
+	MaxTextLength, MaxGlyphs, MaxPathPoints = 12, 8, 4
+	test.Error(t, NewTextLine(face, "ααα", Left).Err())
+	test.T(t, NewTextLine(face, "ααααααα", Left).Err(), ErrLimitExceeded) // 14 bytes
+	test.T(t, face.SingleLine("aaaaaaaaa").Err(), ErrLimitExceeded)       // 9 glyphs
+
+	rt := NewRichText()
+	rt.Add(face, "aaaa")
+	test.Error(t, rt.Err())
+	rt.Add(face, "aaaaa")
+	test.T(t, rt.Err(), ErrLimitExceeded)
+	text := rt.ToText(0.0, 0.0, Left, Top, 0.0, 0.0)
+	test.T(t, text.Err(), ErrLimitExceeded)
+	test.That(t, text.Empty(), "text must be empty")
+
+	_, err := ParseSVG("M0 0L1 0L1 1L0 1z")
+	test.T(t, err, ErrLimitExceeded)
+	_, err = ParseSVG("M0 0L1 0L1 1z")
+	test.Error(t, err)
+
+	c := New(10.0, 10.0)
+	c.RenderPath(Rectangle(1.0, 1.0), DefaultStyle, Identity) // M L L L z
+	test.T(t, c.Err(), ErrLimitExceeded)
+	test.That(t, c.Empty(), "path must not be rendered")
+	test.T(t, c.WriteFile("", nil), ErrLimitExceeded)
+
+	c.Reset()
+	c.RenderText(text, Identity)
+	test.T(t, c.Err(), ErrLimitExceeded)
+}
+
+func TestContextFillOpenPaths(t *testing.T) {
+	open := MustParseSVG("M0 0A5 5 0 0 1 10 0")
+	mixed := MustParseSVG("M0 0A5 5 0 0 1 10 0M20 0L30 0L30 10z")
+
+	c := New(40.0, 20.0)
+	ctx := NewContext(c)
+	ctx.DrawPath(0.0, 0.0, mixed)
+	test.T(t, len(c.layers), 1)
+	test.T(t, c.layers[0].path, mixed) // renderers close implicitly
+
+	c.Reset()
+	ctx.SetFillOpenPaths(SkipOpenPaths)
+	ctx.SetStrokeColor(Red)
+	ctx.DrawPath(0.0, 0.0, mixed)
+	test.T(t, len(c.layers), 2)
+	test.T(t, c.layers[0].path, MustParseSVG("M20 0L30 0L30 10z"))
+	test.T(t, c.layers[0].style.StrokeColor, Transparent)
+	test.T(t, c.layers[1].path, mixed) // open subpaths are still stroked
+	test.T(t, c.layers[1].style.FillColor, Transparent)
+
+	c.Reset()
+	ctx.FillPath(open, SolidPaint{Black})
+	test.T(t, len(c.layers), 0)
+	test.Error(t, ctx.Err())
+
+	ctx.SetFillOpenPaths(ErrorOpenPaths)
+	ctx.SetStrokeColor(Transparent)
+	ctx.DrawPath(0.0, 0.0, mixed)
+	test.T(t, len(c.layers), 0)
+	test.T(t, ctx.Err(), ErrOpenPath)
+}
+
+func TestUnits(t *testing.T) {
+	test.Float(t, Inch.ToMillimeters(2.0), 50.8)
+	test.Float(t, Centimeter.FromMillimeters(25.0), 2.5)
+	test.Float(t, TypographicPoint.FromMillimeters(25.4), 72.0)
+	test.Float(t, Millimeter.ToMillimeters(3.0), 3.0)
+	test.Float(t, float64(DPMM(10.0).Pixel()), 0.1)
+	test.Float(t, (300.0 * DPI).Pixel().FromMillimeters(25.4), 300.0)
+
+	w, h := New(254.0, 25.4).SizeIn(Inch)
+	test.Float(t, w, 10.0)
+	test.Float(t, h, 1.0)
+}
@@ -0,0 +1,179 @@
+package canvas
+
+import (
+	"math"
+	"sort"
+)
+
+// AxisOrientation is the direction of a chart axis, see DrawAxis.
+type AxisOrientation int
+
+// see AxisOrientation
+const (
+	HorizontalAxis AxisOrientation = iota // axis runs to the right with labels below it
+	VerticalAxis                          // axis runs upwards with labels left of it
+)
+
+// Tick is a tick mark of a chart axis, at a distance Pos from the start of the axis and with an optional label.
+type Tick struct {
+	Pos   float64
+	Label string
+}
+
+// DrawAxis draws a chart axis of the given length starting at (x,y), with tick marks and their labels in the given font face. The axis and ticks are stroked using the current stroke style of the context. Ticks point away from the chart, ie. downwards for horizontal axes and to the left for vertical axes, and their length depends on the font size. Coordinates are in the coordinate system of the view, similar to Context.StrokePath.
+func DrawAxis(c *Context, x, y, length float64, orientation AxisOrientation, ticks []Tick, ff FontFace) {
+	metrics := ff.Metrics()
+	tickLength := 0.5 * metrics.CapHeight
+
+	p := &Path{}
+	p.MoveTo(x, y)
+	if orientation == HorizontalAxis {
+		p.LineTo(x+length, y)
+	} else {
+		p.LineTo(x, y+length)
+	}
+	for _, tick := range ticks {
+		if orientation == HorizontalAxis {
+			p.MoveTo(x+tick.Pos, y)
+			p.LineTo(x+tick.Pos, y-tickLength)
+		} else {
+			p.MoveTo(x, y+tick.Pos)
+			p.LineTo(x-tickLength, y+tick.Pos)
+		}
+	}
+	if stroke, ok := strokePaint(c.Style); ok {
+		c.StrokePath(p, stroke)
+	}
+
+	for _, tick := range ticks {
+		if tick.Label == "" {
+			continue
+		}
+		if orientation == HorizontalAxis {
+			text := NewTextLine(ff, tick.Label, Center)
+			c.RenderText(text, c.view.Translate(x+tick.Pos, y-2.0*tickLength-metrics.CapHeight))
+		} else {
+			text := NewTextLine(ff, tick.Label, Right)
+			c.RenderText(text, c.view.Translate(x-2.0*tickLength, y+tick.Pos-metrics.XHeight/2.0))
+		}
+	}
+}
+
+// ThinLabels returns which of the labels of an axis to show so that they do not overlap, where each label is centered at its position along the axis and has the given width. Labels are thinned evenly by showing every n-th label starting at the first, with n as small as possible, so that for dense ticks every other label or fewer remain. Labels of which the position is outside of the axis are not shown. See ThinRotatedLabels to rotate labels when thinning removes too many.
+func ThinLabels(positions, labelWidths []float64, axisLength float64) []bool {
+	return thinLabels(positions, labelWidths, 0.0, 0.0, axisLength)
+}
+
+// ThinRotatedLabels is like ThinLabels but rotates labels when they do not fit otherwise, such as for long category names. It returns the labels to show and the rotation angle of the labels in degrees, which is 0, 45 or 90. The smallest angle is returned at which all labels fit, or 90 degrees when labels must be thinned even so. Rotated labels are separated by their height, which is usually the line height of the font.
+func ThinRotatedLabels(positions, labelWidths []float64, labelHeight, axisLength float64) ([]bool, float64) {
+	var show []bool
+	for _, angle := range []float64{0.0, 45.0, 90.0} {
+		show = thinLabels(positions, labelWidths, labelHeight, angle, axisLength)
+		all := true
+		for i, pos := range positions {
+			if !show[i] && 0.0 <= pos && pos <= axisLength {
+				all = false
+			}
+		}
+		if all {
+			return show, angle
+		}
+	}
+	return show, 90.0
+}
+
+// thinLabels returns the labels to show at every n-th position with the smallest n for which labels rotated by angle do not overlap. Rotated labels are parallel and do not overlap when they are separated either along their width or along their height.
+func thinLabels(positions, widths []float64, height, angle, axisLength float64) []bool {
+	indices := []int{}
+	for i, pos := range positions {
+		if 0.0 <= pos && pos <= axisLength {
+			indices = append(indices, i)
+		}
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return positions[indices[i]] < positions[indices[j]]
+	})
+
+	sin, cos := math.Sincos(angle * math.Pi / 180.0)
+	overlaps := func(i, j int) bool {
+		dist := positions[j] - positions[i] + Epsilon
+		alongHeight := Epsilon < sin && height/sin <= dist
+		alongWidth := Epsilon < cos && (widths[i]+widths[j])/2.0/cos <= dist
+		return !alongHeight && !alongWidth
+	}
+
+	show := make([]bool, len(positions))
+	for n := 1; n <= len(indices); n++ {
+		fits := true
+		for k := n; k < len(indices); k += n {
+			if overlaps(indices[k-n], indices[k]) {
+				fits = false
+				break
+			}
+		}
+		if fits || n == len(indices) {
+			for k := 0; k < len(indices); k += n {
+				show[indices[k]] = true
+			}
+			break
+		}
+	}
+	return show
+}
+
+// DrawBars draws a bar chart of the values in rect, with one bar per value from left to right. The vertical range of rect spans from the smallest to the largest value, including zero, and bars extend from zero to their value. Each bar takes 80% of the width per value so that bars are separated by a gap. Bars are filled and stroked using the current style of the context. Coordinates are in the coordinate system of the view, similar to Context.FillPath.
+func DrawBars(c *Context, values []float64, rect Rect) {
+	lo, hi := 0.0, 0.0
+	for _, v := range values {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	if len(values) == 0 || hi == lo {
+		return
+	}
+
+	fill, hasFill := fillPaint(c.Style)
+	stroke, hasStroke := strokePaint(c.Style)
+	slot := rect.W / float64(len(values))
+	zero := rect.Y + -lo/(hi-lo)*rect.H
+	for i, v := range values {
+		y := rect.Y + (v-lo)/(hi-lo)*rect.H
+		bar := Rectangle(0.8*slot, math.Abs(y-zero)).Translate(rect.X+(float64(i)+0.1)*slot, math.Min(y, zero))
+		if hasFill {
+			c.FillPath(bar, fill)
+		}
+		if hasStroke {
+			c.StrokePath(bar, stroke)
+		}
+	}
+}
+
+// DrawLineSeries draws a line through the points of a data series, using the current stroke style of the context. Coordinates are in the coordinate system of the view, similar to Context.StrokePath.
+func DrawLineSeries(c *Context, points []Point) {
+	if len(points) < 2 {
+		return
+	}
+	p := &Path{}
+	p.MoveTo(points[0].X, points[0].Y)
+	for _, point := range points[1:] {
+		p.LineTo(point.X, point.Y)
+	}
+	if stroke, ok := strokePaint(c.Style); ok {
+		c.StrokePath(p, stroke)
+	}
+}
+
+// fillPaint returns the fill paint of the style, or false when the style does not fill.
+func fillPaint(style Style) (Paint, bool) {
+	if style.FillPaint != nil {
+		return style.FillPaint, true
+	}
+	return SolidPaint{style.FillColor}, style.FillColor.A != 0
+}
+
+// strokePaint returns the stroke paint of the style, or false when the style does not stroke.
+func strokePaint(style Style) (Paint, bool) {
+	if style.StrokePaint != nil {
+		return style.StrokePaint, 0.0 < style.StrokeWidth
+	}
+	return SolidPaint{style.StrokeColor}, style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth
+}
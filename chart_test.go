@@ -0,0 +1,102 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestDrawBars(t *testing.T) {
+	c := New(100.0, 100.0)
+	ctx := NewContext(c)
+	DrawBars(ctx, []float64{1.0, 2.0, -1.0}, Rect{10.0, 10.0, 30.0, 30.0})
+	test.T(t, len(c.layers), 3)
+	test.T(t, c.layers[0].path.Bounds(), Rect{11.0, 20.0, 8.0, 10.0})
+	test.T(t, c.layers[1].path.Bounds(), Rect{21.0, 20.0, 8.0, 20.0})
+	test.T(t, c.layers[2].path.Bounds(), Rect{31.0, 10.0, 8.0, 10.0})
+	test.T(t, c.layers[0].style.FillColor, Black)
+
+	// stroke only
+	c.Reset()
+	ctx.SetFillColor(Transparent)
+	ctx.SetStrokeColor(Red)
+	DrawBars(ctx, []float64{1.0}, Rect{0.0, 0.0, 10.0, 10.0})
+	test.T(t, len(c.layers), 1)
+	test.T(t, c.layers[0].style.StrokeColor, Red)
+	test.T(t, c.layers[0].style.FillColor, Transparent)
+}
+
+func TestDrawAxis(t *testing.T) {
+	dejaVuSerif := NewFontFamily("dejavu-serif")
+	dejaVuSerif.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	ff := dejaVuSerif.Face(12.0, Black, FontRegular, FontNormal)
+
+	c := New(100.0, 100.0)
+	ctx := NewContext(c)
+	ctx.SetStrokeColor(Black)
+	DrawAxis(ctx, 10.0, 10.0, 50.0, HorizontalAxis, []Tick{{0.0, "0"}, {25.0, ""}, {50.0, "10"}}, ff)
+	test.T(t, len(c.layers), 3)
+	tickLength := 0.5 * ff.Metrics().CapHeight
+	test.T(t, c.layers[0].path.Bounds(), Rect{10.0, 10.0 - tickLength, 50.0, tickLength})
+	test.That(t, c.layers[1].text != nil, "must draw tick label")
+	test.T(t, c.layers[2].m.Dot(Point{}), Point{60.0, 10.0 - 2.0*tickLength - ff.Metrics().CapHeight})
+
+	c.Reset()
+	DrawAxis(ctx, 10.0, 10.0, 50.0, VerticalAxis, []Tick{{20.0, "5"}}, ff)
+	test.T(t, c.layers[0].path.Bounds(), Rect{10.0 - tickLength, 10.0, tickLength, 50.0})
+	test.T(t, c.layers[1].m.Dot(Point{}), Point{10.0 - 2.0*tickLength, 30.0 - ff.Metrics().XHeight/2.0})
+}
+
+func TestDrawLineSeries(t *testing.T) {
+	c := New(100.0, 100.0)
+	ctx := NewContext(c)
+	DrawLineSeries(ctx, []Point{{0.0, 0.0}, {10.0, 5.0}, {20.0, 0.0}})
+	test.T(t, len(c.layers), 0) // no stroke by default
+
+	ctx.SetStrokeColor(Blue)
+	DrawLineSeries(ctx, []Point{{0.0, 0.0}, {10.0, 5.0}, {20.0, 0.0}})
+	test.T(t, len(c.layers), 1)
+	test.T(t, c.layers[0].path, MustParseSVG("M0 0L10 5L20 0"))
+	test.T(t, c.layers[0].style.StrokeColor, Blue)
+}
+
+func TestThinLabels(t *testing.T) {
+	positions := []float64{0.0, 5.0, 10.0, 15.0, 20.0, 25.0, 30.0}
+	widths := func(w float64) []float64 {
+		ws := make([]float64, len(positions))
+		for i := range ws {
+			ws[i] = w
+		}
+		return ws
+	}
+	test.T(t, ThinLabels(positions, widths(4.0), 30.0), []bool{true, true, true, true, true, true, true})
+	test.T(t, ThinLabels(positions, widths(8.0), 30.0), []bool{true, false, true, false, true, false, true})
+	test.T(t, ThinLabels(positions, widths(12.0), 30.0), []bool{true, false, false, true, false, false, true})
+	test.T(t, ThinLabels(positions, widths(100.0), 30.0), []bool{true, false, false, false, false, false, false})
+	test.T(t, ThinLabels(positions, widths(4.0), 22.0), []bool{true, true, true, true, true, false, false})
+
+	// shown labels of different widths do not overlap
+	ws := []float64{3.0, 9.0, 4.0, 12.0, 2.0, 6.0, 7.0}
+	show := ThinLabels(positions, ws, 30.0)
+	prev := -1
+	for i := range positions {
+		if show[i] {
+			if prev != -1 {
+				test.That(t, positions[prev]+ws[prev]/2.0 <= positions[i]-ws[i]/2.0, "labels overlap:", prev, i)
+			}
+			prev = i
+		}
+	}
+
+	// rotate when labels do not fit horizontally
+	show, angle := ThinRotatedLabels(positions, widths(4.0), 3.0, 30.0)
+	test.Float(t, angle, 0.0)
+	show, angle = ThinRotatedLabels(positions, widths(12.0), 3.0, 30.0)
+	test.Float(t, angle, 45.0)
+	test.T(t, show, []bool{true, true, true, true, true, true, true})
+	show, angle = ThinRotatedLabels(positions, widths(12.0), 4.0, 30.0)
+	test.Float(t, angle, 90.0)
+	show, angle = ThinRotatedLabels(positions, widths(12.0), 8.0, 30.0)
+	test.Float(t, angle, 90.0)
+	test.T(t, show, []bool{true, false, true, false, true, false, true})
+}
@@ -0,0 +1,272 @@
+package canvas
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
+
+// DisplayCommand is a draw operation of a display list, see DisplayList. Commands are DrawPathCommand, DrawShadowCommand, DrawInsetShadowCommand, DrawTextCommand, DrawImageCommand, BeginGroupCommand, EndGroupCommand, PushClipCommand and PopClipCommand.
+type DisplayCommand interface {
+	render(r Renderer, view Matrix)
+	String() string
+}
+
+// DrawPathCommand draws a path using a style and a transformation matrix, see Renderer.RenderPath.
+type DrawPathCommand struct {
+	Path   *Path
+	Style  Style
+	Matrix Matrix
+}
+
+func (cmd DrawPathCommand) render(r Renderer, view Matrix) {
+	r.RenderPath(cmd.Path, cmd.Style, view.Mul(cmd.Matrix))
+}
+
+// String returns the path, the fill and stroke of its style and the transformation matrix.
+func (cmd DrawPathCommand) String() string {
+	sb := strings.Builder{}
+	fmt.Fprintf(&sb, "DrawPath %v fill=%v", cmd.Path, CSSColor(cmd.Style.FillColor))
+	if cmd.Style.FillPaint != nil {
+		fmt.Fprintf(&sb, " fillPaint=%T", cmd.Style.FillPaint)
+	}
+	if cmd.Style.FillRule == EvenOdd {
+		fmt.Fprintf(&sb, " fillRule=evenodd")
+	}
+	if cmd.Style.CompositeOp != SourceOver {
+		fmt.Fprintf(&sb, " composite=%v", cmd.Style.CompositeOp)
+	}
+	if cmd.Style.StrokeColor.A != 0 || cmd.Style.StrokePaint != nil {
+		fmt.Fprintf(&sb, " stroke=%v width=%g", CSSColor(cmd.Style.StrokeColor), cmd.Style.StrokeWidth)
+		if cmd.Style.StrokePaint != nil {
+			fmt.Fprintf(&sb, " strokePaint=%T", cmd.Style.StrokePaint)
+		}
+		if 0 < len(cmd.Style.Dashes) {
+			fmt.Fprintf(&sb, " dashes=%g offset=%g", cmd.Style.Dashes, cmd.Style.DashOffset)
+		}
+	}
+	fmt.Fprintf(&sb, " matrix=%v", cmd.Matrix)
+	return sb.String()
+}
+
+// DrawShadowCommand draws a soft shadow of a path using a transformation matrix, see Context.DrawShadow.
+type DrawShadowCommand struct {
+	Path   *Path
+	Blur   float64
+	Color  color.RGBA
+	Matrix Matrix
+}
+
+func (cmd DrawShadowCommand) render(r Renderer, view Matrix) {
+	renderShadow(r, cmd.Path, cmd.Blur, cmd.Color, view.Mul(cmd.Matrix))
+}
+
+// String returns the path, the blur and color of the shadow and the transformation matrix.
+func (cmd DrawShadowCommand) String() string {
+	return fmt.Sprintf("DrawShadow %v blur=%g color=%v matrix=%v", cmd.Path, cmd.Blur, CSSColor(cmd.Color), cmd.Matrix)
+}
+
+// DrawInsetShadowCommand draws a soft shadow inside of a path with an offset in the coordinates of the path using a transformation matrix, see Context.DrawInsetShadow.
+type DrawInsetShadowCommand struct {
+	Path   *Path
+	Offset Point
+	Blur   float64
+	Color  color.RGBA
+	Matrix Matrix
+}
+
+func (cmd DrawInsetShadowCommand) render(r Renderer, view Matrix) {
+	renderInsetShadow(r, cmd.Path, cmd.Offset.X, cmd.Offset.Y, cmd.Blur, cmd.Color, view.Mul(cmd.Matrix))
+}
+
+// String returns the path, the offset, blur and color of the shadow and the transformation matrix.
+func (cmd DrawInsetShadowCommand) String() string {
+	return fmt.Sprintf("DrawInsetShadow %v offset=%v blur=%g color=%v matrix=%v", cmd.Path, cmd.Offset, cmd.Blur, CSSColor(cmd.Color), cmd.Matrix)
+}
+
+// DrawTextCommand draws a text using a transformation matrix, see Renderer.RenderText.
+type DrawTextCommand struct {
+	Text   *Text
+	Matrix Matrix
+}
+
+func (cmd DrawTextCommand) render(r Renderer, view Matrix) {
+	r.RenderText(cmd.Text, view.Mul(cmd.Matrix))
+}
+
+// String returns the text content of each line and the transformation matrix.
+func (cmd DrawTextCommand) String() string {
+	lines := []string{}
+	for _, l := range cmd.Text.lines {
+		s := ""
+		for _, span := range l.spans {
+			s += span.Text
+		}
+		lines = append(lines, s)
+	}
+	return fmt.Sprintf("DrawText %q matrix=%v", lines, cmd.Matrix)
+}
+
+// DrawImageCommand draws an image using a transformation matrix, see Renderer.RenderImage.
+type DrawImageCommand struct {
+	Image  image.Image
+	Matrix Matrix
+}
+
+func (cmd DrawImageCommand) render(r Renderer, view Matrix) {
+	r.RenderImage(cmd.Image, view.Mul(cmd.Matrix))
+}
+
+// String returns the size of the image in pixels and the transformation matrix.
+func (cmd DrawImageCommand) String() string {
+	size := cmd.Image.Bounds().Size()
+	return fmt.Sprintf("DrawImage %dx%d matrix=%v", size.X, size.Y, cmd.Matrix)
+}
+
+// BeginGroupCommand starts a transparency group, see Context.BeginGroup. It is ignored by renderers that do not implement GroupRenderer.
+type BeginGroupCommand struct {
+	Isolated, Knockout bool
+}
+
+func (cmd BeginGroupCommand) render(r Renderer, view Matrix) {
+	if gr, ok := r.(GroupRenderer); ok {
+		gr.BeginGroup(cmd.Isolated, cmd.Knockout)
+	}
+}
+
+// String returns whether the group is isolated and knockout.
+func (cmd BeginGroupCommand) String() string {
+	return fmt.Sprintf("BeginGroup isolated=%v knockout=%v", cmd.Isolated, cmd.Knockout)
+}
+
+// EndGroupCommand ends a transparency group.
+type EndGroupCommand struct{}
+
+func (cmd EndGroupCommand) render(r Renderer, view Matrix) {
+	if gr, ok := r.(GroupRenderer); ok {
+		gr.EndGroup()
+	}
+}
+
+// String returns the name of the command.
+func (cmd EndGroupCommand) String() string {
+	return "EndGroup"
+}
+
+// PushClipCommand intersects the clipping region with the area of a path using a transformation matrix, see Context.ClipPath. It is ignored by renderers that do not implement ClipRenderer.
+type PushClipCommand struct {
+	Path   *Path
+	Matrix Matrix
+}
+
+func (cmd PushClipCommand) render(r Renderer, view Matrix) {
+	if cr, ok := r.(ClipRenderer); ok {
+		cr.PushClip(cmd.Path, view.Mul(cmd.Matrix))
+	}
+}
+
+// String returns the path and the transformation matrix.
+func (cmd PushClipCommand) String() string {
+	return fmt.Sprintf("PushClip %v matrix=%v", cmd.Path, cmd.Matrix)
+}
+
+// PopClipCommand restores the clipping region from before the matching PushClipCommand.
+type PopClipCommand struct{}
+
+func (cmd PopClipCommand) render(r Renderer, view Matrix) {
+	if cr, ok := r.(ClipRenderer); ok {
+		cr.PopClip()
+	}
+}
+
+// String returns the name of the command.
+func (cmd PopClipCommand) String() string {
+	return "PopClip"
+}
+
+// DisplayList is the ordered list of draw operations that a canvas issues to a renderer, see Canvas.DisplayList. It can be inspected, compared between versions for debugging, or rendered to any renderer.
+type DisplayList []DisplayCommand
+
+// Progress is called with the number of completed steps of a total number of steps during a long operation, such as to update a progress bar of a user interface. Calls are throttled so that it is called at most about a hundred times per operation, where done increases monotonically and the last call has done equal to total. The total is known up front and does not change during the operation.
+type Progress func(done, total int)
+
+// progressSteps is the number of times a Progress callback is called at most, not counting the final call.
+const progressSteps = 100
+
+// Render renders the draw operations to a renderer, as Canvas.Render. Clipping paths that were not popped are popped at the end.
+func (dl DisplayList) Render(r Renderer) {
+	dl.RenderProgress(r, nil)
+}
+
+// RenderProgress renders the draw operations to a renderer as Render, and reports the number of draw operations that have been rendered to progress if it is not nil. The draw operations are weighted equally, so that progress is approximate when some operations are much slower than others.
+func (dl DisplayList) RenderProgress(r Renderer, progress Progress) {
+	view := Identity
+	if viewer, ok := r.(interface{ View() Matrix }); ok {
+		view = viewer.View()
+	}
+	step := len(dl)/progressSteps + 1
+	clips := 0
+	for i, cmd := range dl {
+		cmd.render(r, view)
+		switch cmd.(type) {
+		case PushClipCommand:
+			clips++
+		case PopClipCommand:
+			clips--
+		}
+		if progress != nil && (i+1)%step == 0 && i+1 < len(dl) {
+			progress(i+1, len(dl))
+		}
+	}
+	if cr, ok := r.(ClipRenderer); ok {
+		// pop the clipping paths that are still in effect
+		for ; 0 < clips; clips-- {
+			cr.PopClip()
+		}
+	}
+	if progress != nil {
+		progress(len(dl), len(dl))
+	}
+}
+
+// String returns the draw operations with one operation per line.
+func (dl DisplayList) String() string {
+	sb := strings.Builder{}
+	for _, cmd := range dl {
+		sb.WriteString(cmd.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// DisplayList returns the draw operations of the canvas in the order they are rendered. Paths, texts and images are shared with the canvas and should not be modified.
+func (c *Canvas) DisplayList() DisplayList {
+	dl := make(DisplayList, 0, len(c.layers))
+	for _, l := range c.layers {
+		if l.group != nil {
+			if l.group.end {
+				dl = append(dl, EndGroupCommand{})
+			} else {
+				dl = append(dl, BeginGroupCommand{l.group.isolated, l.group.knockout})
+			}
+		} else if l.clip != nil {
+			if l.clip.path == nil {
+				dl = append(dl, PopClipCommand{})
+			} else {
+				dl = append(dl, PushClipCommand{l.clip.path, l.m})
+			}
+		} else if l.shadow != nil && l.shadow.inset {
+			dl = append(dl, DrawInsetShadowCommand{l.path, l.shadow.offset, l.shadow.blur, l.shadow.color, l.m})
+		} else if l.shadow != nil {
+			dl = append(dl, DrawShadowCommand{l.path, l.shadow.blur, l.shadow.color, l.m})
+		} else if l.path != nil {
+			dl = append(dl, DrawPathCommand{l.path, l.style, l.m})
+		} else if l.text != nil {
+			dl = append(dl, DrawTextCommand{l.text, l.m})
+		} else if l.img != nil {
+			dl = append(dl, DrawImageCommand{l.img, l.m})
+		}
+	}
+	return dl
+}
@@ -0,0 +1,92 @@
+package canvas
+
+import (
+	"image"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestDisplayList(t *testing.T) {
+	dejaVuSerif := NewFontFamily("dejavu-serif")
+	dejaVuSerif.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := dejaVuSerif.Face(10.0, Black, FontRegular, FontNormal)
+
+	c := New(20.0, 20.0)
+	ctx := NewContext(c)
+	ctx.SetStrokeColor(Red)
+	ctx.SetDashes(1.0, 2.0)
+	ctx.DrawPath(1.0, 2.0, MustParseSVG("L10 0L10 10z"))
+	ctx.BeginGroup(true, false)
+	ctx.SetFillPaint(LinearGradient{Point{0.0, 0.0}, Point{1.0, 0.0}, []GradientStop{{0.0, Red}, {1.0, Blue}}, ExtendClamp})
+	ctx.SetStrokeColor(Transparent)
+	ctx.DrawPath(0.0, 0.0, Rectangle(2.0, 2.0))
+	ctx.EndGroup()
+	ctx.DrawText(3.0, 4.0, NewTextLine(face, "ab\ncd", Left))
+	ctx.DrawImage(0.0, 0.0, image.NewRGBA(image.Rect(0, 0, 2, 3)), 1.0)
+
+	dl := c.DisplayList()
+	test.T(t, len(dl), 6)
+	test.String(t, dl.String(), `DrawPath M0 0L10 0L10 10z fill=#000 stroke=#f00 width=1 dashes=[2] offset=1 matrix=(1 0; 0 1) + (1,2)
+BeginGroup isolated=true knockout=false
+DrawPath M0 0L2 0L2 2L0 2z fill=#000 fillPaint=canvas.LinearGradient matrix=(1 0; 0 1) + (0,0)
+EndGroup
+DrawText ["ab\n" "cd"] matrix=(1 0; 0 1) + (3,4)
+DrawImage 2x3 matrix=(1 0; 0 1) + (0,0)
+`)
+
+	// replaying gives the same draw operations
+	replay := New(c.W, c.H)
+	dl.Render(replay)
+	test.String(t, replay.DisplayList().String(), dl.String())
+	test.T(t, replay.layers[0].style.Dashes, []float64{2.0})
+}
+
+func TestDisplayListShadow(t *testing.T) {
+	c := New(20.0, 20.0)
+	ctx := NewContext(c)
+	ctx.DrawShadow(Rectangle(2.0, 2.0), [2]float64{1.0, -1.0}, 0.5, Red)
+	dl := c.DisplayList()
+	test.String(t, dl.String(), "DrawShadow M0 0L2 0L2 2L0 2z blur=0.5 color=#f00 matrix=(1 0; 0 1) + (1,-1)\n")
+
+	// renderers without support for shadows fill the silhouette
+	rec := &pathRecorder{}
+	dl.Render(rec)
+	test.T(t, len(rec.paths), 1)
+	test.T(t, rec.styles[0].FillColor, Red)
+
+	// fitting the canvas includes the blur
+	c.Fit(0.0)
+	test.Float(t, c.W, 5.0)
+	test.Float(t, c.H, 5.0)
+}
+
+func TestDisplayListRenderProgress(t *testing.T) {
+	c := New(20.0, 20.0)
+	ctx := NewContext(c)
+	for i := 0; i < 250; i++ {
+		ctx.DrawPath(0.0, 0.0, Rectangle(1.0, 1.0))
+	}
+
+	calls := [][2]int{}
+	rec := &pathRecorder{}
+	c.RenderProgress(rec, func(done, total int) {
+		calls = append(calls, [2]int{done, total})
+	})
+	test.T(t, len(rec.paths), 250)
+	test.That(t, len(calls) <= 101, "progress must be throttled")
+	for i, call := range calls {
+		test.T(t, call[1], 250)
+		if 0 < i {
+			test.That(t, calls[i-1][0] < call[0], "progress must increase")
+		}
+	}
+	test.T(t, calls[len(calls)-1], [2]int{250, 250})
+
+	calls = calls[:0]
+	New(1.0, 1.0).RenderProgress(rec, func(done, total int) {
+		calls = append(calls, [2]int{done, total})
+	})
+	test.T(t, calls, [][2]int{{0, 0}})
+	c.RenderProgress(rec, nil)
+}
@@ -0,0 +1,178 @@
+package canvas
+
+import (
+	"context"
+	"image"
+	"math"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	canvasFont "github.com/tdewolff/canvas/font"
+)
+
+// Emoji is an emoji sequence of a text, such as a single emoji, an emoji with a skin tone modifier, a zero width joiner sequence or a flag, see Text.Emojis.
+type Emoji struct {
+	Text string // the characters of the emoji sequence
+	Rect Rect   // the box extending from the descent to the ascent over the advance of the emoji, relative to the text origin
+
+	glyph, glyphs int // index of the first glyph in the text and the number of glyphs
+}
+
+// EmojiProvider provides the images of emoji, such as from a sprite service or a directory of PNG files. Get may be slow and is called concurrently, see Context.DrawTextWithEmoji.
+type EmojiProvider interface {
+	Get(ctx context.Context, emoji string) (image.Image, error)
+}
+
+// BitmapEmoji returns an emoji provider that gets the images of emoji from the sbix or CBDT bitmaps of the font at the given size in pixels per em, such as for Apple color emoji or Noto Color Emoji, instead of from an external source. Only emoji of a single character are supported, optionally followed by an emoji presentation selector. Other emoji and emoji without a bitmap return canvasFont.ErrNoBitmap, so that they are drawn as glyphs.
+func (f *Font) BitmapEmoji(ppem int) EmojiProvider {
+	return bitmapEmoji{f, ppem}
+}
+
+type bitmapEmoji struct {
+	font *Font
+	ppem int
+}
+
+func (p bitmapEmoji) Get(ctx context.Context, emoji string) (image.Image, error) {
+	runes := []rune(strings.TrimSuffix(emoji, "\uFE0F"))
+	if len(runes) != 1 {
+		return nil, canvasFont.ErrNoBitmap
+	}
+	index := p.font.IndicesOf(string(runes))[0]
+	if index == 0 {
+		return nil, canvasFont.ErrNoBitmap
+	}
+	return p.font.tables.BitmapGlyph(index, p.ppem)
+}
+
+// Emojis returns the emoji sequences of the text in the order of the lines and spans of the text.
+func (t *Text) Emojis() []Emoji {
+	emojis := []Emoji{}
+	glyph := 0
+	for _, line := range t.lines {
+		for _, span := range line.spans {
+			metrics := span.Face.Metrics()
+			positions := span.GlyphPositions()
+			breaks := append(graphemeBreaks(span.Text), len(span.Text))
+			start, i := 0, 0
+			for _, end := range breaks {
+				cluster := span.Text[start:end]
+				n := len([]rune(cluster))
+				if isEmoji(cluster) {
+					x0 := positions[i]
+					x1 := span.width
+					if i+n < len(positions) {
+						x1 = positions[i+n]
+					}
+					emojis = append(emojis, Emoji{
+						Text:   cluster,
+						Rect:   Rect{span.dx + x0, line.y + span.Face.Voffset - metrics.Descent, x1 - x0, metrics.Ascent + metrics.Descent},
+						glyph:  glyph + i,
+						glyphs: n,
+					})
+				}
+				start, i = end, i+n
+			}
+			glyph += len(positions)
+		}
+	}
+	return emojis
+}
+
+// isEmoji returns true if the grapheme cluster is an emoji sequence that is displayed as an emoji by default or by an emoji presentation selector.
+func isEmoji(cluster string) bool {
+	if strings.ContainsRune(cluster, '\uFE0E') {
+		return false // text presentation selector
+	} else if strings.ContainsRune(cluster, '\uFE0F') || strings.ContainsRune(cluster, '\u20E3') {
+		return true // emoji presentation selector or keycap
+	}
+	r, _ := utf8.DecodeRuneInString(cluster)
+	return '\U0001F000' <= r && r <= '\U0001FAFF' || '\u2600' <= r && r <= '\u27BF' || r == '\u231A' || r == '\u231B' || '\u23E9' <= r && r <= '\u23F3' || r == '\u2B50' || r == '\u2B55'
+}
+
+// DrawTextWithEmoji draws the text at (x,y) as Context.DrawText, but draws its emoji, see Text.Emojis, as images of the provider scaled to fit the boxes of the emoji. The images of all emoji are fetched concurrently with at most parallelism requests at the same time before drawing, so that drawing does not wait for each request in turn. Each distinct emoji is fetched once. Emoji whose image could not be fetched are drawn as glyphs of the font, or as the missing glyph when the font has no glyph for it. The glyphs of the text are rendered as paths when any emoji is drawn as an image, see Text.SetGlyphOpacity.
+func (c *Context) DrawTextWithEmoji(ctx context.Context, x, y float64, text *Text, provider EmojiProvider, parallelism int) {
+	emojis := text.Emojis()
+	images := fetchEmoji(ctx, emojis, provider, parallelism)
+	if len(images) == 0 {
+		c.DrawText(x, y, text)
+		return
+	}
+
+	// hide the glyphs of the emoji that are drawn as images
+	hidden := map[int]bool{}
+	for _, emoji := range emojis {
+		if _, ok := images[emoji.Text]; ok {
+			for i := 0; i < emoji.glyphs; i++ {
+				hidden[emoji.glyph+i] = true
+			}
+		}
+	}
+	opacity := text.opacity
+	withoutEmoji := *text
+	withoutEmoji.opacity = func(glyph int, x float64) float64 {
+		if hidden[glyph] {
+			return 0.0
+		} else if opacity != nil {
+			return opacity(glyph, x)
+		}
+		return 1.0
+	}
+	c.DrawText(x, y, &withoutEmoji)
+
+	coord := c.coordView.Dot(Point{x, y})
+	for _, emoji := range emojis {
+		img, ok := images[emoji.Text]
+		if !ok {
+			continue
+		}
+		size := img.Bounds().Size()
+		if size.X == 0 || size.Y == 0 {
+			continue
+		}
+
+		// fit the image in the box of the emoji while keeping its aspect ratio
+		scale := math.Min(emoji.Rect.W/float64(size.X), emoji.Rect.H/float64(size.Y))
+		dx := emoji.Rect.X + (emoji.Rect.W-scale*float64(size.X))/2.0
+		dy := emoji.Rect.Y + (emoji.Rect.H-scale*float64(size.Y))/2.0
+		c.RenderImage(img, c.view.Translate(coord.X+dx, coord.Y+dy).Scale(scale, scale))
+	}
+}
+
+// fetchEmoji returns the images of the emoji, fetched concurrently by at most parallelism goroutines. Emoji that could not be fetched are omitted.
+func fetchEmoji(ctx context.Context, emojis []Emoji, provider EmojiProvider, parallelism int) map[string]image.Image {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	images := map[string]image.Image{}
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	sem := make(chan struct{}, parallelism)
+	fetched := map[string]bool{}
+	for _, emoji := range emojis {
+		if fetched[emoji.Text] {
+			continue
+		}
+		fetched[emoji.Text] = true
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(s string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			img, err := provider.Get(ctx, s)
+			if err != nil || img == nil {
+				return
+			}
+			mu.Lock()
+			images[s] = img
+			mu.Unlock()
+		}(emoji.Text)
+	}
+	wg.Wait()
+	return images
+}
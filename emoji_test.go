@@ -0,0 +1,147 @@
+package canvas
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	canvasFont "github.com/tdewolff/canvas/font"
+	"github.com/tdewolff/test"
+)
+
+type mockEmojiProvider struct {
+	sync.Mutex
+	active, maxActive int
+	requests          []string
+}
+
+func (p *mockEmojiProvider) Get(ctx context.Context, emoji string) (image.Image, error) {
+	p.Lock()
+	p.active++
+	if p.maxActive < p.active {
+		p.maxActive = p.active
+	}
+	p.requests = append(p.requests, emoji)
+	p.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	p.Lock()
+	p.active--
+	p.Unlock()
+	if emoji == "\U0001F1F3\U0001F1F1" {
+		return nil, fmt.Errorf("not found")
+	}
+	return image.NewRGBA(image.Rect(0, 0, 64, 64)), nil
+}
+
+func TestTextEmojis(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	text := NewTextLine(face, "a\U0001F44D\U0001F3FD b\U0001F1F3\U0001F1F1 ❤️ ❤︎", Left)
+	emojis := text.Emojis()
+	test.T(t, len(emojis), 3)
+	test.String(t, emojis[0].Text, "\U0001F44D\U0001F3FD")
+	test.String(t, emojis[1].Text, "\U0001F1F3\U0001F1F1")
+	test.String(t, emojis[2].Text, "❤️")
+	test.T(t, emojis[0].glyph, 1)
+	test.T(t, emojis[0].glyphs, 2)
+	test.T(t, emojis[1].glyph, 5)
+
+	metrics := face.Metrics()
+	test.Float(t, emojis[0].Rect.X, face.TextWidth("a"))
+	test.Float(t, emojis[0].Rect.Y, -metrics.Descent)
+	test.Float(t, emojis[0].Rect.H, metrics.Ascent+metrics.Descent)
+	test.That(t, 0.0 < emojis[0].Rect.W, "emoji must have an advance")
+}
+
+func TestDrawTextWithEmoji(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	text := NewTextLine(face, "\U0001F44D \U0001F600 \U0001F1F3\U0001F1F1 \U0001F44D ⭐", Left)
+	provider := &mockEmojiProvider{}
+	c := New(100.0, 100.0)
+	ctx := NewContext(c)
+	start := time.Now()
+	ctx.DrawTextWithEmoji(context.Background(), 10.0, 50.0, text, provider, 2)
+
+	// four distinct emoji are fetched at most two at a time
+	test.T(t, len(provider.requests), 4)
+	test.T(t, provider.maxActive, 2)
+	test.That(t, time.Since(start) < 70*time.Millisecond, "emoji must be fetched concurrently")
+
+	// the flag could not be fetched and is drawn as text
+	images := 0
+	var drawn *Text
+	for _, l := range c.layers {
+		if l.img != nil {
+			images++
+		} else if l.text != nil {
+			drawn = l.text
+		}
+	}
+	test.T(t, images, 4)
+	test.That(t, drawn != nil && drawn.opacity != nil, "text must be drawn without the fetched emoji")
+	test.T(t, drawn.opacity(0, 0.0), 0.0)
+	test.T(t, drawn.opacity(4, 0.0), 1.0)
+	test.T(t, drawn.opacity(5, 0.0), 1.0)
+	test.That(t, text.opacity == nil, "text must not be modified")
+
+	// fall back to drawing the text when no emoji could be fetched
+	c = New(100.0, 100.0)
+	NewContext(c).DrawTextWithEmoji(context.Background(), 10.0, 50.0, NewTextLine(face, "\U0001F1F3\U0001F1F1", Left), provider, 2)
+	test.T(t, len(c.layers), 1)
+	test.That(t, c.layers[0].text != nil && c.layers[0].text.opacity == nil, "text must be drawn unchanged")
+}
+
+func TestFontBitmapEmoji(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFont(b, FontRegular)
+	font := family.Face(12.0, Black, FontRegular, FontNormal).Font
+	maxp, _ := font.SFNT().Table("maxp")
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+	index := font.IndicesOf("A")[0]
+
+	// a single strike of 16 pixels per em with a bitmap for the glyph A
+	bitmap := &bytes.Buffer{}
+	test.Error(t, png.Encode(bitmap, image.NewRGBA(image.Rect(0, 0, 16, 16))))
+	strike := make([]byte, 4+4*(numGlyphs+1))
+	binary.BigEndian.PutUint16(strike, 16) // ppem
+	for i := 0; i <= numGlyphs; i++ {
+		offset := len(strike)
+		if int(index) < i {
+			offset += 8 + bitmap.Len()
+		}
+		binary.BigEndian.PutUint32(strike[4+4*i:], uint32(offset))
+	}
+	strike = append(strike, 0, 0, 0, 0, 'p', 'n', 'g', ' ')
+	strike = append(strike, bitmap.Bytes()...)
+	sbix := []byte{0, 1, 0, 1, 0, 0, 0, 1, 0, 0, 0, 12}
+	sbix = append(sbix, strike...)
+
+	family = NewFontFamily("dejavu-serif-sbix")
+	test.Error(t, family.LoadFont(addFontTables(b, map[string][]byte{"sbix": sbix}), FontRegular))
+	provider := family.Face(12.0, Black, FontRegular, FontNormal).Font.BitmapEmoji(32)
+
+	img, err := provider.Get(context.Background(), "A")
+	test.Error(t, err)
+	test.T(t, img.Bounds(), image.Rect(0, 0, 32, 32))
+	_, err = provider.Get(context.Background(), "B")
+	test.T(t, err, canvasFont.ErrNoBitmap)
+	_, err = provider.Get(context.Background(), "AB")
+	test.T(t, err, canvasFont.ErrNoBitmap)
+}
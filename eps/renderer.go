@@ -46,12 +46,15 @@ type Renderer struct {
 	w             io.Writer
 	width, height float64
 	color         color.RGBA
+	flatten       canvas.FlattenMethod
 }
 
-// New creates an encapsulated PostScript renderer.
+// New creates an encapsulated PostScript renderer of width and height in millimeters. The bounding box is written in points and drawing operations are scaled from millimeters to points.
 func New(w io.Writer, width, height float64) *Renderer {
-	fmt.Fprintf(w, "%%!PS-Adobe-3.0 EPSF-3.0\n%%%%BoundingBox: 0 0 %v %v\n", dec(width), dec(height))
+	wPt, hPt := canvas.TypographicPoint.FromMillimeters(width), canvas.TypographicPoint.FromMillimeters(height)
+	fmt.Fprintf(w, "%%!PS-Adobe-3.0 EPSF-3.0\n%%%%BoundingBox: 0 0 %v %v\n%%%%HiResBoundingBox: 0 0 %v %v\n", math.Ceil(wPt), math.Ceil(hPt), dec(wPt), dec(hPt))
 	fmt.Fprintf(w, psEllipseDef)
+	fmt.Fprintf(w, "\n%v %v scale", dec(canvas.TypographicPoint.FromMillimeters(1.0)), dec(canvas.TypographicPoint.FromMillimeters(1.0)))
 	// TODO: (EPS) generate and add preview
 
 	return &Renderer{
@@ -62,6 +65,11 @@ func New(w io.Writer, width, height float64) *Renderer {
 	}
 }
 
+// SetFlattenMethod sets the method used to flatten curves into linear segments, such as canvas.UniformSteps. By default curves are written as curves.
+func (r *Renderer) SetFlattenMethod(method canvas.FlattenMethod) {
+	r.flatten = method
+}
+
 func (r *Renderer) setColor(color color.RGBA) {
 	if color != r.color {
 		fmt.Fprintf(r.w, " %v %v %v setrgbcolor", dec(float64(color.R)/255.0), dec(float64(color.G)/255.0), dec(float64(color.B)/255.0))
@@ -77,9 +85,16 @@ func (r *Renderer) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Ma
 	// TODO: (EPS) test ellipse, rotations etc
 	// TODO: (EPS) add drawState support
 	// TODO: (EPS) use dither to fake transparency
+	if style.CompositeOp.Erases() {
+		return // cannot erase previously drawn content
+	}
+	path = path.Transform(m)
+	if r.flatten != nil {
+		path = path.FlattenWith(r.flatten)
+	}
 	r.setColor(style.FillColor)
 	r.w.Write([]byte(" "))
-	r.w.Write([]byte(path.Transform(m).ToPS()))
+	r.w.Write([]byte(path.ToPS()))
 	r.w.Write([]byte(" fill"))
 }
 
@@ -2,9 +2,11 @@ package eps
 
 import (
 	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
 )
 
 func TestEPS(t *testing.T) {
@@ -13,3 +15,15 @@ func TestEPS(t *testing.T) {
 	eps.setColor(canvas.Red)
 	//test.String(t, string(w.Bytes()), "")
 }
+
+func TestEPSBoundingBox(t *testing.T) {
+	w := &bytes.Buffer{}
+	New(w, 210.0, 297.0) // A4
+
+	// the bounding box is in points and the hi-res bounding box is not rounded up
+	lines := strings.Split(w.String(), "\n")
+	test.String(t, lines[0], "%!PS-Adobe-3.0 EPSF-3.0")
+	test.String(t, lines[1], "%%BoundingBox: 0 0 596 842")
+	test.String(t, lines[2], "%%HiResBoundingBox: 0 0 595.27559 841.88976")
+	test.That(t, strings.HasSuffix(w.String(), "\n2.8346457 2.8346457 scale"), "drawing operations must be scaled from millimeters to points")
+}
@@ -1,7 +1,9 @@
 package canvas
 
 import (
+	"image/color"
 	"strings"
+	"sync"
 	"unicode"
 	"unicode/utf8"
 
@@ -17,24 +19,84 @@ type TypographicOptions int
 const (
 	NoTypography TypographicOptions = 2 << iota
 	NoRequiredLigatures
-	CommonLigatures
-	DiscretionaryLigatures
-	HistoricalLigatures
+	CommonLigatures        // standard ligatures (liga) such as fi and ffl, these are enabled by default
+	DiscretionaryLigatures // decorative ligatures (dlig) such as st, these change the look of the text significantly
+	HistoricalLigatures    // ligatures of historical typesetting (hlig) such as ſt
+	NoCommonLigatures      // disables the standard ligatures
 )
 
+// MissingGlyph defines how codepoints that are not covered by the font are rendered when converting text to paths.
+type MissingGlyph int
+
+// see MissingGlyph
+const (
+	MissingNotdef MissingGlyph = iota // use the font's .notdef glyph
+	MissingHexBox                     // draw a box containing the codepoint's hexadecimal digits
+)
+
+// DefectiveCluster defines how combining marks without a base character are handled, such as a combining mark at the start of the text or after a new line.
+type DefectiveCluster int
+
+// see DefectiveCluster
+const (
+	DefectiveDottedCircle DefectiveCluster = iota // insert a dotted circle (U+25CC) as base character
+	DefectiveDrop                                 // remove the combining marks
+)
+
+// ControlCharacter defines how control characters in text are handled, which are the C0 and C1 control codes except for tabs and newlines, such as stray characters in copy-pasted text.
+type ControlCharacter int
+
+// see ControlCharacter
+const (
+	ControlDrop    ControlCharacter = iota // remove the control characters
+	ControlReplace                         // replace the control characters by the replacement character (U+FFFD)
+)
+
+// CustomGlyph returns the path and advance of a custom glyph that is used in place of the font's glyph for a rune, such as to draw icons for codepoints in the Private Use Area. The path and advance are given for a font size of one, ie. in units of the em size, with the baseline at y=0 and the y-axis pointing up, and are scaled by the size of the font face. It returns false for runes that use the font's glyph.
+type CustomGlyph func(r rune) (*Path, float64, bool)
+
+// MissingAdvance returns the advance that is reserved for a rune that the font has no glyph for, in units of the em size, such as for an emoji image that is placed over the text later. It returns false for runes that use the advance of the missing glyph, see MissingGlyph.
+type MissingAdvance func(r rune) (float64, bool)
+
 // Font defines a font of type TTF or OTF which which a FontFace can be generated for use in text drawing operations.
 type Font struct {
-	// TODO: extend to fully read in sfnt data and read liga tables, generate Raw font data (base on used glyphs), etc
+	// TODO: extend to fully read in sfnt data, generate Raw font data (base on used glyphs), etc
 	name      string
 	mediatype string
 	raw       []byte
 	sfnt      *sfnt.Font
+	tables    *canvasFont.SFNT
 
 	// TODO: use sub/superscript Unicode transformations in ToPath etc. if they exist
 	typography  bool
-	ligatures   []textSubstitution
+	ligatures   TypographicOptions // ligature options of the font, see Font.Use
+	common      []textSubstitution // presentation forms of the common ligatures for fonts without liga feature
 	superscript []textSubstitution
 	subscript   []textSubstitution
+
+	missing   MissingGlyph
+	defective DefectiveCluster
+	control   ControlCharacter
+
+	custom         CustomGlyph
+	missingAdvance MissingAdvance
+	customID       int // incremented when custom or missingAdvance changes, invalidates measured text widths
+
+	colored  bool            // has a COLR table with color glyphs
+	palettes [][]color.NRGBA // CPAL palettes for color glyphs
+
+	baseScripts map[string]canvasFont.BaseScript // BASE baselines by script tag
+	features    map[string]bool                  // GSUB and GPOS feature tags
+	mathConsts  *canvasFont.MathConstants        // MATH constants, nil for fonts without MATH table
+
+	substitutionsMu   *sync.Mutex
+	substitutions     map[string]map[uint16]uint16                  // GSUB single substitutions by feature tag, parsed on first use
+	ligatureLookups   map[string][]map[uint16][]canvasFont.Ligature // GSUB ligature substitutions by feature tag, parsed on first use
+	ligatureTexts     map[rune]string                               // components of the ligature glyphs without presentation form, see Font.LigatureText
+	presentationForms map[uint16]rune                               // presentation forms of ligature glyphs, such as U+FB01 for fi
+
+	windingsMu *sync.Mutex
+	windings   map[sfnt.GlyphIndex]bool // whether glyph contours are wound inconsistently, determined on first use
 }
 
 func parseFont(name string, b []byte) (*Font, error) {
@@ -43,7 +105,15 @@ func parseFont(name string, b []byte) (*Font, error) {
 		return nil, err
 	}
 
-	sfntFont, err := canvasFont.ParseFont(b)
+	sfntBytes, err := canvasFont.ToSFNT(b)
+	if err != nil {
+		return nil, err
+	}
+	sfntFont, err := canvasFont.ParseSFNT(sfntBytes)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := canvasFont.NewSFNT(sfntBytes)
 	if err != nil {
 		return nil, err
 	}
@@ -53,6 +123,37 @@ func parseFont(name string, b []byte) (*Font, error) {
 		mediatype: mediatype,
 		raw:       b,
 		sfnt:      (*sfnt.Font)(sfntFont),
+		tables:    tables,
+
+		substitutionsMu: &sync.Mutex{},
+		substitutions:   map[string]map[uint16]uint16{},
+		ligatureLookups: map[string][]map[uint16][]canvasFont.Ligature{},
+		ligatureTexts:   map[rune]string{},
+
+		windingsMu: &sync.Mutex{},
+		windings:   map[sfnt.GlyphIndex]bool{},
+	}
+	f.baseScripts = tables.BaseScripts()
+	f.features = map[string]bool{}
+	for _, feature := range tables.Features() {
+		f.features[feature.Tag] = true
+	}
+	if c, ok := tables.MathConstants(); ok {
+		f.mathConsts = &c
+	}
+	if _, ok := tables.Table("COLR"); ok {
+		f.colored = true
+		f.palettes = tables.Palettes()
+	}
+	f.common = f.supportedSubstitutions(commonLigatures)
+	f.presentationForms = map[uint16]rune{}
+	buffer := &sfnt.Buffer{}
+	for r := range ligatures {
+		if index, err := f.sfnt.GlyphIndex(buffer, r); err == nil && index != 0 {
+			if prev, ok := f.presentationForms[uint16(index)]; !ok || r < prev {
+				f.presentationForms[uint16(index)] = r
+			}
+		}
 	}
 	f.superscript = f.supportedSubstitutions(superscriptSubstitutes)
 	f.subscript = f.supportedSubstitutions(subscriptSubstitutes)
@@ -70,6 +171,109 @@ func (f *Font) Raw() (string, []byte) {
 	return f.mediatype, f.raw
 }
 
+// SFNT returns the SFNT font data, which gives access to the raw font tables.
+func (f *Font) SFNT() *canvasFont.SFNT {
+	return f.tables
+}
+
+// NumPalettes returns the number of CPAL color palettes of the font, which can be selected with FontFace.Palette.
+func (f *Font) NumPalettes() int {
+	return len(f.palettes)
+}
+
+// HasFeature returns true if the font has the OpenType feature with the given tag in its GSUB or GPOS table for any script, such as "smcp" or "kern".
+func (f *Font) HasFeature(tag string) bool {
+	return f.features[tag]
+}
+
+// singleSubstitutions returns the GSUB single substitutions of a feature, see font.SFNT.SingleSubstitutions. The substitutions are parsed once per feature and are safe for concurrent use.
+func (f *Font) singleSubstitutions(tag string) map[uint16]uint16 {
+	f.substitutionsMu.Lock()
+	defer f.substitutionsMu.Unlock()
+	substitutions, ok := f.substitutions[tag]
+	if !ok {
+		substitutions = f.tables.SingleSubstitutions(tag)
+		f.substitutions[tag] = substitutions
+	}
+	return substitutions
+}
+
+// ligatureSubstitutions returns the GSUB ligature substitutions of a feature, see font.SFNT.LigatureSubstitutions. The substitutions are parsed once per feature and are safe for concurrent use.
+func (f *Font) ligatureSubstitutions(tag string) []map[uint16][]canvasFont.Ligature {
+	f.substitutionsMu.Lock()
+	defer f.substitutionsMu.Unlock()
+	lookups, ok := f.ligatureLookups[tag]
+	if !ok {
+		lookups = f.tables.LigatureSubstitutions(tag)
+		f.ligatureLookups[tag] = lookups
+	}
+	return lookups
+}
+
+// ligatureBase is the first character of the Supplementary Private Use Area-A, which represents the ligature glyphs without presentation form by their glyph index, see Font.LigatureText.
+const ligatureBase = '\U000F0000'
+
+// LigatureText returns the components of a ligature glyph that has no presentation form in Unicode. Text layout represents such ligatures by the character U+F0000 plus the glyph index, which is in the Supplementary Private Use Area-A. It returns false for other characters.
+func (f *Font) LigatureText(r rune) (string, bool) {
+	if r < ligatureBase || ligatureBase+0xFFFF < r {
+		return "", false
+	}
+	f.substitutionsMu.Lock()
+	defer f.substitutionsMu.Unlock()
+	s, ok := f.ligatureTexts[r]
+	return s, ok
+}
+
+// ligatureRune returns the character that represents a ligature glyph, which is its presentation form or otherwise a character of the Supplementary Private Use Area-A for which the components are kept, see Font.LigatureText.
+func (f *Font) ligatureRune(glyphID uint16, components string) rune {
+	if r, ok := f.presentationForms[glyphID]; ok {
+		return r
+	}
+	r := ligatureBase + rune(glyphID)
+	f.substitutionsMu.Lock()
+	if _, ok := f.ligatureTexts[r]; !ok {
+		f.ligatureTexts[r] = components
+	}
+	f.substitutionsMu.Unlock()
+	return r
+}
+
+// decomposeLigature returns the components of a ligature character, which is either a presentation form such as U+FB01 for fi or a ligature glyph without presentation form, see Font.LigatureText.
+func (f *Font) decomposeLigature(r rune) (string, bool) {
+	if s, ok := ligatures[r]; ok {
+		return s, true
+	}
+	return f.LigatureText(r)
+}
+
+// glyphIndex returns the glyph index of a rune, including the ligature glyphs without presentation form, see Font.LigatureText.
+func (f *Font) glyphIndex(buffer *sfnt.Buffer, r rune) (sfnt.GlyphIndex, error) {
+	if _, ok := f.LigatureText(r); ok {
+		return sfnt.GlyphIndex(r - ligatureBase), nil
+	}
+	return f.sfnt.GlyphIndex(buffer, r)
+}
+
+// inconsistentWinding returns true if the contours of a glyph are wound inconsistently, such as counters that have the same direction as their outer contour, so that filling the glyph with NonZero and EvenOdd gives different results. This is determined once per glyph from its outline, as scaling and faux italic do not change the winding, and is safe for concurrent use.
+func (f *Font) inconsistentWinding(index sfnt.GlyphIndex, glyph *Path) bool {
+	f.windingsMu.Lock()
+	defer f.windingsMu.Unlock()
+	inconsistent, ok := f.windings[index]
+	if !ok {
+		if 1 < len(glyph.Split()) {
+			nonZero, evenOdd := glyph.Filling(NonZero), glyph.Filling(EvenOdd)
+			for i := range nonZero {
+				if nonZero[i] != evenOdd[i] {
+					inconsistent = true
+					break
+				}
+			}
+		}
+		f.windings[index] = inconsistent
+	}
+	return inconsistent
+}
+
 // UnitsPerEm returns the number of units per em for f.
 func (f *Font) UnitsPerEm() float64 {
 	return float64(f.sfnt.UnitsPerEm())
@@ -80,11 +284,11 @@ func (f *Font) UnitsPerEm() float64 {
 func (f *Font) Kerning(left, right rune, ppem float64) (float64, error) {
 	var sfntBuffer sfnt.Buffer
 
-	iLeft, err := f.sfnt.GlyphIndex(&sfntBuffer, left)
+	iLeft, err := f.glyphIndex(&sfntBuffer, left)
 	if err != nil {
 		return 0, err
 	}
-	iRight, err := f.sfnt.GlyphIndex(&sfntBuffer, right)
+	iRight, err := f.glyphIndex(&sfntBuffer, right)
 	if err != nil {
 		return 0, err
 	}
@@ -162,7 +366,7 @@ func (f *Font) IndicesOf(s string) []uint16 {
 	runes := []rune(s)
 	indices := make([]uint16, len(runes))
 	for i, r := range runes {
-		index, err := f.sfnt.GlyphIndex(buffer, r)
+		index, err := f.glyphIndex(buffer, r)
 		if err == nil {
 			indices[i] = uint16(index)
 		}
@@ -175,7 +379,7 @@ type textSubstitution struct {
 	dst rune
 }
 
-// TODO: read from liga tables in OpenType (clig, dlig, hlig) with rlig default enabled
+// commonLigatures are the presentation forms of the standard ligatures, used for fonts without liga feature
 var commonLigatures = []textSubstitution{
 	{"ffi", '\uFB03'},
 	{"ffl", '\uFB04'},
@@ -281,27 +485,180 @@ func (f *Font) supportedSubstitutions(substitutions []textSubstitution) []textSu
 	buffer := &sfnt.Buffer{}
 	supported := []textSubstitution{}
 	for _, stn := range substitutions {
-		if _, err := f.sfnt.GlyphIndex(buffer, stn.dst); err == nil {
+		if index, err := f.sfnt.GlyphIndex(buffer, stn.dst); err == nil && index != 0 {
 			supported = append(supported, stn)
 		}
 	}
 	return supported
 }
 
-// Use enables typographic options on the font such as ligatures.
+// Use enables typographic options on the font such as ligatures. The common ligatures are enabled unless NoCommonLigatures is given, and the discretionary and historical ligatures are enabled with DiscretionaryLigatures and HistoricalLigatures. Ligatures are substituted by the GSUB ligature lookups of their feature (liga, dlig and hlig), and are ignored when the font does not have the feature, except that fonts without liga feature use the presentation forms of the common ligatures that they have, such as U+FB01 for fi.
 func (f *Font) Use(options TypographicOptions) {
 	if options&NoTypography == 0 {
 		f.typography = true
 	}
 
-	f.ligatures = []textSubstitution{}
-	if options&CommonLigatures != 0 {
-		f.ligatures = append(f.ligatures, f.supportedSubstitutions(commonLigatures)...)
+	f.ligatures = options & (NoCommonLigatures | DiscretionaryLigatures | HistoricalLigatures)
+}
+
+// ligatureTags returns the tags of the GSUB ligature features that are enabled by the options, see Font.Use.
+func ligatureTags(options TypographicOptions) []string {
+	tags := []string{}
+	if options&NoCommonLigatures == 0 {
+		tags = append(tags, "liga")
+	}
+	if options&DiscretionaryLigatures != 0 {
+		tags = append(tags, "dlig")
+	}
+	if options&HistoricalLigatures != 0 {
+		tags = append(tags, "hlig")
+	}
+	return tags
+}
+
+// SetMissingGlyph sets how codepoints that are missing from the font are rendered, see MissingGlyph.
+func (f *Font) SetMissingGlyph(missing MissingGlyph) {
+	f.missing = missing
+}
+
+// SetCustomGlyph sets the custom glyphs that are used in place of the font's glyphs during layout and when converting text to paths, see CustomGlyph. Custom glyphs are not kerned. Renderers that draw text using the embedded font, such as SVG and PDF, draw the font's glyph instead unless the text is rendered as paths. A nil function disables custom glyphs.
+func (f *Font) SetCustomGlyph(custom CustomGlyph) {
+	f.custom = custom
+	f.customID++
+}
+
+// SetMissingAdvance sets the advances of runes that the font has no glyph for, which are used during layout and measurement instead of the advance of the missing glyph, see MissingAdvance. Such runes are not drawn when converting text to paths, so that callers can draw their own rendering in the reserved space. A nil function disables missing advances.
+func (f *Font) SetMissingAdvance(advance MissingAdvance) {
+	f.missingAdvance = advance
+	f.customID++
+}
+
+// SetDefectiveCluster sets how combining marks without a base character are handled, see DefectiveCluster.
+func (f *Font) SetDefectiveCluster(defective DefectiveCluster) {
+	f.defective = defective
+}
+
+// SetControlCharacter sets how control characters in text are handled, see ControlCharacter. By default they are dropped.
+func (f *Font) SetControlCharacter(control ControlCharacter) {
+	f.control = control
+}
+
+// cleanText removes or replaces the control characters of s and fixes the combining marks without a base character, where rPrev is the character preceding s or zero at the start of the text, see Font.SetControlCharacter and Font.SetDefectiveCluster.
+func (f *Font) cleanText(s string, rPrev rune) string {
+	return f.fixDefectiveClusters(f.fixControlCharacters(s), rPrev)
+}
+
+// fixControlCharacters removes or replaces the control characters of s, see ControlCharacter.
+func (f *Font) fixControlCharacters(s string) string {
+	if strings.IndexFunc(s, isControl) == -1 {
+		return s
+	}
+	if f.control == ControlReplace {
+		return strings.Map(func(r rune) rune {
+			if isControl(r) {
+				return '\uFFFD'
+			}
+			return r
+		}, s)
+	}
+	return strings.Map(func(r rune) rune {
+		if isControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// fixDefectiveClusters inserts a dotted circle before or removes combining marks that have no base character, where rPrev is the character preceding s or zero at the start of the text.
+func (f *Font) fixDefectiveClusters(s string, rPrev rune) string {
+	var sb strings.Builder
+	i, changed := 0, false
+	for j, r := range s {
+		if isCombiningMark(r) && (rPrev == 0 || isNewline(rPrev)) {
+			sb.WriteString(s[i:j])
+			changed = true
+			if f.defective == DefectiveDottedCircle {
+				sb.WriteRune('\u25CC')
+				rPrev = '\u25CC'
+				i = j
+			} else {
+				i = j + utf8.RuneLen(r)
+			}
+			continue
+		}
+		rPrev = r
+	}
+	if !changed {
+		return s
+	}
+	sb.WriteString(s[i:])
+	return sb.String()
+}
+
+// substituteLigatures replaces the character sequences of s by the ligatures of the GSUB ligature substitutions of the features, in the order of the features and of their lookups. Ligature glyphs are represented by their presentation form or otherwise by a character of the Supplementary Private Use Area-A, see Font.LigatureText. Fonts without liga feature use the presentation forms of the common ligatures that they have instead.
+func (f *Font) substituteLigatures(s string, tags []string) string {
+	for _, tag := range tags {
+		if !f.HasFeature(tag) {
+			if tag == "liga" {
+				s = substitute(s, f.common)
+			}
+			continue
+		}
+		for _, lookup := range f.ligatureSubstitutions(tag) {
+			s = f.applyLigatures(s, lookup)
+		}
 	}
+	return s
 }
 
-func (f *Font) substituteLigatures(s string) string {
-	for _, stn := range f.ligatures {
+// applyLigatures substitutes the ligatures of a single GSUB ligature lookup, where the ligatures are matched by glyph index from the start of s and the first ligature of the font that matches is used.
+func (f *Font) applyLigatures(s string, lookup map[uint16][]canvasFont.Ligature) string {
+	buffer := &sfnt.Buffer{}
+	runes := []rune(s)
+	glyphIDs := make([]uint16, len(runes))
+	covered := false
+	for i, r := range runes {
+		if index, err := f.glyphIndex(buffer, r); err == nil {
+			glyphIDs[i] = uint16(index)
+			if _, ok := lookup[glyphIDs[i]]; ok && index != 0 {
+				covered = true
+			}
+		}
+	}
+	if !covered {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(runes); {
+		n := 0
+		if glyphIDs[i] != 0 {
+		Ligatures:
+			for _, ligature := range lookup[glyphIDs[i]] {
+				if len(runes)-i < len(ligature.Components) {
+					continue
+				}
+				for j, component := range ligature.Components {
+					if glyphIDs[i+j] != component {
+						continue Ligatures
+					}
+				}
+				n = len(ligature.Components)
+				sb.WriteRune(f.ligatureRune(ligature.Glyph, string(runes[i:i+n])))
+				break
+			}
+		}
+		if n == 0 {
+			sb.WriteRune(runes[i])
+			n = 1
+		}
+		i += n
+	}
+	return sb.String()
+}
+
+func substitute(s string, substitutions []textSubstitution) string {
+	for _, stn := range substitutions {
 		s = strings.ReplaceAll(s, stn.src, string(stn.dst))
 	}
 	return s
@@ -457,6 +814,10 @@ func stringReplace(s string, i, n int, target string) (string, int) {
 	return s, len(target)
 }
 
+func isCombiningMark(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc)
+}
+
 func isWordBoundary(r rune) bool {
 	return r == 0 || isspace(r) || ispunct(r)
 }
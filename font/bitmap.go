@@ -0,0 +1,71 @@
+package font
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"golang.org/x/image/draw"
+)
+
+// ErrNoBitmap is returned if the font has no bitmap for a glyph, in which case its outline is to be used.
+var ErrNoBitmap = fmt.Errorf("no bitmap for glyph")
+
+// bitmapStrike is a set of bitmap glyphs for a single size, from either the sbix or the CBDT table.
+type bitmapStrike struct {
+	ppem   uint16
+	bitmap func(glyphID uint16) (image.Image, error) // returns ErrNoBitmap if the strike has no bitmap for the glyph
+}
+
+// BitmapGlyph returns the bitmap of a glyph from the sbix table, such as used by Apple color emoji fonts, or from the CBDT and CBLC tables, such as used by Noto Color Emoji, scaled to the given size in pixels per em. It uses the strike of the smallest size that is not smaller than ppem and has a bitmap for the glyph, which is scaled down to ppem, or else the largest strike which is scaled up. It returns ErrNoBitmap if the font has no bitmap tables or no strike has a bitmap for the glyph. Only PNG and JPEG bitmaps are supported.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/sbix and https://docs.microsoft.com/en-us/typography/opentype/spec/cbdt
+func (sfnt *SFNT) BitmapGlyph(glyphID uint16, ppem int) (image.Image, error) {
+	strikes, err := sfnt.sbixStrikes()
+	if err != nil {
+		return nil, err
+	}
+	cbdtStrikes, err := sfnt.cbdtStrikes()
+	if err != nil {
+		return nil, err
+	}
+	strikes = append(strikes, cbdtStrikes...)
+
+	// order the strikes by preference: the smallest strike not smaller than ppem first, and the largest strike first for strikes that are smaller
+	best := func(a, b bitmapStrike) bool {
+		if (int(a.ppem) < ppem) != (int(b.ppem) < ppem) {
+			return ppem <= int(a.ppem)
+		} else if ppem <= int(a.ppem) {
+			return a.ppem < b.ppem
+		}
+		return b.ppem < a.ppem
+	}
+	for len(strikes) != 0 {
+		k := 0
+		for i := range strikes {
+			if best(strikes[i], strikes[k]) {
+				k = i
+			}
+		}
+		strike := strikes[k]
+		strikes = append(strikes[:k], strikes[k+1:]...)
+
+		img, err := strike.bitmap(glyphID)
+		if err == ErrNoBitmap {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if int(strike.ppem) == ppem || strike.ppem == 0 {
+			return img, nil
+		}
+
+		scale := float64(ppem) / float64(strike.ppem)
+		size := img.Bounds().Size()
+		w := int(math.Max(1.0, math.Round(float64(size.X)*scale)))
+		h := int(math.Max(1.0, math.Round(float64(size.Y)*scale)))
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+		return dst, nil
+	}
+	return nil, ErrNoBitmap
+}
@@ -0,0 +1,178 @@
+package font
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+)
+
+// cbdtStrikes returns the strikes of the CBLC table, whose bitmaps are stored in the CBDT table.
+func (sfnt *SFNT) cbdtStrikes() ([]bitmapStrike, error) {
+	cblc, ok := sfnt.Table("CBLC")
+	if !ok {
+		return nil, nil
+	}
+	cbdt, ok := sfnt.Table("CBDT")
+	if !ok || len(cblc) < 8 {
+		return nil, ErrInvalidFontData
+	}
+
+	r := newBinaryReader(cblc)
+	_ = r.ReadUint16() // majorVersion
+	_ = r.ReadUint16() // minorVersion
+	numSizes := r.ReadUint32()
+	n := uint32(len(cblc))
+	if (n-8)/48 < numSizes {
+		return nil, ErrInvalidFontData
+	}
+	strikes := make([]bitmapStrike, 0, numSizes)
+	for i := uint32(0); i < numSizes; i++ {
+		r.Seek(8 + 48*i)
+		indexSubTableArrayOffset := r.ReadUint32()
+		_ = r.ReadUint32() // indexTablesSize
+		numberOfIndexSubTables := r.ReadUint32()
+		_ = r.ReadUint32()  // colorRef
+		_ = r.ReadBytes(24) // hori and vert line metrics
+		startGlyphIndex := r.ReadUint16()
+		endGlyphIndex := r.ReadUint16()
+		_ = r.ReadByte() // ppemX
+		ppemY := r.ReadByte()
+		if n < indexSubTableArrayOffset || (n-indexSubTableArrayOffset)/8 < numberOfIndexSubTables {
+			return nil, ErrInvalidFontData
+		}
+		strikes = append(strikes, bitmapStrike{uint16(ppemY), func(glyphID uint16) (image.Image, error) {
+			if glyphID < startGlyphIndex || endGlyphIndex < glyphID {
+				return nil, ErrNoBitmap
+			}
+			return cbdtBitmap(cblc, cbdt, indexSubTableArrayOffset, numberOfIndexSubTables, glyphID)
+		}})
+	}
+	return strikes, nil
+}
+
+// cbdtBitmap decodes the bitmap of a glyph in the strike with the given index subtables.
+func cbdtBitmap(cblc, cbdt []byte, arrayOffset, numSubTables uint32, glyphID uint16) (image.Image, error) {
+	r := newBinaryReader(cblc)
+	n := uint32(len(cblc))
+	for i := uint32(0); i < numSubTables; i++ {
+		r.Seek(arrayOffset + 8*i)
+		firstGlyphIndex := r.ReadUint16()
+		lastGlyphIndex := r.ReadUint16()
+		offset := arrayOffset + r.ReadUint32()
+		if glyphID < firstGlyphIndex || lastGlyphIndex < glyphID {
+			continue
+		} else if lastGlyphIndex < firstGlyphIndex || n < offset || n-offset < 8 {
+			return nil, ErrInvalidFontData
+		}
+
+		r.Seek(offset)
+		indexFormat := r.ReadUint16()
+		imageFormat := r.ReadUint16()
+		imageDataOffset := r.ReadUint32()
+		k := uint32(glyphID - firstGlyphIndex)
+		count := uint32(lastGlyphIndex-firstGlyphIndex) + 1
+
+		// find the offsets of the glyph in the CBDT table
+		var start, end uint32
+		switch indexFormat {
+		case 1:
+			if (n-offset-8)/4 < count+1 {
+				return nil, ErrInvalidFontData
+			}
+			r.Seek(offset + 8 + 4*k)
+			start, end = r.ReadUint32(), r.ReadUint32()
+		case 2:
+			if n-offset < 20 {
+				return nil, ErrInvalidFontData
+			}
+			imageSize := r.ReadUint32()
+			start, end = k*imageSize, (k+1)*imageSize
+		case 3:
+			if (n-offset-8)/2 < count+1 {
+				return nil, ErrInvalidFontData
+			}
+			r.Seek(offset + 8 + 2*k)
+			start, end = uint32(r.ReadUint16()), uint32(r.ReadUint16())
+		case 4:
+			if n-offset < 12 {
+				return nil, ErrInvalidFontData
+			}
+			numGlyphs := r.ReadUint32()
+			if (n-offset-12)/4 <= numGlyphs {
+				return nil, ErrInvalidFontData
+			}
+			found := false
+			for j := uint32(0); j < numGlyphs; j++ {
+				r.Seek(offset + 12 + 4*j)
+				if r.ReadUint16() == glyphID {
+					start = uint32(r.ReadUint16())
+					_ = r.ReadUint16() // glyphID of the next pair
+					end = uint32(r.ReadUint16())
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, ErrNoBitmap
+			}
+		case 5:
+			if n-offset < 24 {
+				return nil, ErrInvalidFontData
+			}
+			imageSize := r.ReadUint32()
+			_ = r.ReadBytes(8) // bigMetrics
+			numGlyphs := r.ReadUint32()
+			if (n-offset-24)/2 < numGlyphs {
+				return nil, ErrInvalidFontData
+			}
+			found := false
+			for j := uint32(0); j < numGlyphs; j++ {
+				if r.ReadUint16() == glyphID {
+					start, end = j*imageSize, (j+1)*imageSize
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, ErrNoBitmap
+			}
+		default:
+			return nil, fmt.Errorf("CBLC: unsupported index format %d", indexFormat)
+		}
+		if start == end {
+			return nil, ErrNoBitmap
+		}
+		start += imageDataOffset
+		end += imageDataOffset
+		if end < start || uint32(len(cbdt)) < end {
+			return nil, ErrInvalidFontData
+		}
+		return cbdtImage(cbdt[start:end], imageFormat)
+	}
+	return nil, ErrNoBitmap
+}
+
+// cbdtImage decodes the PNG image of a glyph in the CBDT table, which is preceded by its glyph metrics for image formats 17 and 18.
+func cbdtImage(data []byte, imageFormat uint16) (image.Image, error) {
+	var metrics uint32
+	switch imageFormat {
+	case 17:
+		metrics = 5 // smallGlyphMetrics
+	case 18:
+		metrics = 8 // bigGlyphMetrics
+	case 19:
+		metrics = 0 // metrics are in the CBLC table
+	default:
+		return nil, fmt.Errorf("CBDT: unsupported image format %d", imageFormat)
+	}
+	if uint32(len(data)) < metrics+4 {
+		return nil, ErrInvalidFontData
+	}
+	r := newBinaryReader(data[metrics:])
+	dataLen := r.ReadUint32()
+	if uint32(len(data))-metrics-4 < dataLen {
+		return nil, ErrInvalidFontData
+	}
+	return png.Decode(bytes.NewReader(r.ReadBytes(dataLen)))
+}
@@ -0,0 +1,113 @@
+package font
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestSFNTBitmapGlyphCBDT(t *testing.T) {
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+
+	// glyph 1 is in an index subtable of format 1 with image format 17, glyph 3 in one of format 5 with image format 18
+	cbdt := newBinaryWriter([]byte{})
+	cbdt.WriteUint16(3) // majorVersion
+	cbdt.WriteUint16(0) // minorVersion
+	png1 := sbixPNG(32, red)
+	cbdt.WriteBytes(make([]byte, 5)) // smallGlyphMetrics
+	cbdt.WriteUint32(uint32(len(png1)))
+	cbdt.WriteBytes(png1)
+	offset3 := cbdt.Len()
+	png3 := sbixPNG(32, blue)
+	cbdt.WriteBytes(make([]byte, 8)) // bigGlyphMetrics
+	cbdt.WriteUint32(uint32(len(png3)))
+	cbdt.WriteBytes(png3)
+	size3 := cbdt.Len() - offset3
+
+	cblc := newBinaryWriter([]byte{})
+	cblc.WriteUint16(3)  // majorVersion
+	cblc.WriteUint16(0)  // minorVersion
+	cblc.WriteUint32(1)  // numSizes
+	cblc.WriteUint32(56) // indexSubTableArrayOffset
+	cblc.WriteUint32(0)  // indexTablesSize
+	cblc.WriteUint32(2)  // numberOfIndexSubTables
+	cblc.WriteUint32(0)  // colorRef
+	cblc.WriteBytes(make([]byte, 24))
+	cblc.WriteUint16(1) // startGlyphIndex
+	cblc.WriteUint16(3) // endGlyphIndex
+	cblc.WriteBytes([]byte{32, 32, 32, 1})
+
+	cblc.WriteUint16(1) // firstGlyphIndex
+	cblc.WriteUint16(2) // lastGlyphIndex
+	cblc.WriteUint32(16)
+	cblc.WriteUint16(3) // firstGlyphIndex
+	cblc.WriteUint16(3) // lastGlyphIndex
+	cblc.WriteUint32(36)
+
+	cblc.WriteUint16(1)  // indexFormat
+	cblc.WriteUint16(17) // imageFormat
+	cblc.WriteUint32(4)  // imageDataOffset
+	cblc.WriteUint32(0)  // sbitOffsets
+	cblc.WriteUint32(offset3 - 4)
+	cblc.WriteUint32(offset3 - 4) // glyph 2 has no bitmap
+
+	cblc.WriteUint16(5)  // indexFormat
+	cblc.WriteUint16(18) // imageFormat
+	cblc.WriteUint32(offset3)
+	cblc.WriteUint32(size3)
+	cblc.WriteBytes(make([]byte, 8)) // bigMetrics
+	cblc.WriteUint32(1)              // numGlyphs
+	cblc.WriteUint16(3)
+
+	sfnt := &SFNT{tables: map[string][]byte{"CBLC": cblc.Bytes(), "CBDT": cbdt.Bytes()}}
+	img, err := sfnt.BitmapGlyph(1, 32)
+	test.Error(t, err)
+	test.T(t, img.Bounds(), image.Rect(0, 0, 32, 32))
+	test.T(t, color.RGBAModel.Convert(img.At(16, 16)), red)
+
+	img, err = sfnt.BitmapGlyph(3, 16)
+	test.Error(t, err)
+	test.T(t, img.Bounds(), image.Rect(0, 0, 16, 16))
+	test.T(t, color.RGBAModel.Convert(img.At(8, 8)), blue)
+
+	_, err = sfnt.BitmapGlyph(2, 32)
+	test.T(t, err, ErrNoBitmap)
+	_, err = sfnt.BitmapGlyph(4, 32)
+	test.T(t, err, ErrNoBitmap)
+
+	delete(sfnt.tables, "CBDT")
+	_, err = sfnt.BitmapGlyph(1, 32)
+	test.T(t, err, ErrInvalidFontData)
+}
+
+func TestSFNTBitmapGlyphCBDTInvalid(t *testing.T) {
+	// index subtable of format 4 with a number of glyphs that overflows
+	cblc := newBinaryWriter([]byte{})
+	cblc.WriteUint16(3)  // majorVersion
+	cblc.WriteUint16(0)  // minorVersion
+	cblc.WriteUint32(1)  // numSizes
+	cblc.WriteUint32(56) // indexSubTableArrayOffset
+	cblc.WriteUint32(0)  // indexTablesSize
+	cblc.WriteUint32(1)  // numberOfIndexSubTables
+	cblc.WriteUint32(0)  // colorRef
+	cblc.WriteBytes(make([]byte, 24))
+	cblc.WriteUint16(7) // startGlyphIndex
+	cblc.WriteUint16(7) // endGlyphIndex
+	cblc.WriteBytes([]byte{32, 32, 32, 1})
+
+	cblc.WriteUint16(7) // firstGlyphIndex
+	cblc.WriteUint16(7) // lastGlyphIndex
+	cblc.WriteUint32(8)
+
+	cblc.WriteUint16(4)          // indexFormat
+	cblc.WriteUint16(17)         // imageFormat
+	cblc.WriteUint32(4)          // imageDataOffset
+	cblc.WriteUint32(0xFFFFFFFF) // numGlyphs
+	cblc.WriteBytes(make([]byte, 8))
+
+	sfnt := &SFNT{tables: map[string][]byte{"CBLC": cblc.Bytes(), "CBDT": make([]byte, 16)}}
+	_, err := sfnt.BitmapGlyph(7, 32)
+	test.T(t, err, ErrInvalidFontData)
+}
@@ -0,0 +1,140 @@
+package font
+
+// cursiveAnchor is the entry or exit anchor point of a glyph for cursive attachment, in font units.
+type cursiveAnchor struct {
+	x, y int16
+	ok   bool
+}
+
+// CursiveOffsets returns the vertical offset in font units of each glyph by the cursive attachment lookups (GPOS lookup type 3) of the curs feature, which connect glyphs of scripts such as Arabic Nastaliq along a cursive baseline. The exit anchor of each glyph is attached to the entry anchor of the next glyph, where the glyphs are in logical order. Of a sequence of connected glyphs, the first glyph stays on the baseline, or the last glyph for lookups with the right-to-left flag as used for right-to-left scripts. Horizontal positions are not adjusted. It returns nil if the font has no cursive attachments.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/gpos#lookup-type-3-cursive-attachment-positioning-subtable
+func (sfnt *SFNT) CursiveOffsets(glyphIDs []uint16) []int16 {
+	table, ok := sfnt.Table("GPOS")
+	if !ok || len(table) < 10 {
+		return nil
+	}
+	r := newBinaryReader(table)
+	_ = r.ReadUint32() // version
+	_ = r.ReadUint16() // scriptList
+	featureList := uint32(r.ReadUint16())
+	lookupList := uint32(r.ReadUint16())
+
+	// lookup indices of the curs features of all scripts, in increasing order
+	lookups := map[uint16]bool{}
+	r.Seek(featureList)
+	numFeatures := r.ReadUint16()
+	for i := 0; i < int(numFeatures) && !r.EOF(); i++ {
+		r.Seek(featureList + 2 + 6*uint32(i))
+		if r.ReadString(4) != "curs" {
+			continue
+		}
+		feature := featureList + uint32(r.ReadUint16())
+		r.Seek(feature + 2) // skip featureParamsOffset
+		numIndices := r.ReadUint16()
+		for j := 0; j < int(numIndices); j++ {
+			if index := r.ReadUint16(); !r.EOF() {
+				lookups[index] = true
+			}
+		}
+	}
+
+	var offsets []int16
+	r.Seek(lookupList)
+	numLookups := r.ReadUint16()
+	for index := 0; index < int(numLookups); index++ {
+		if !lookups[uint16(index)] {
+			continue
+		}
+		r.Seek(lookupList + 2 + 2*uint32(index))
+		lookup := lookupList + uint32(r.ReadUint16())
+		r.Seek(lookup)
+		lookupType := r.ReadUint16()
+		lookupFlag := r.ReadUint16()
+		numSubtables := r.ReadUint16()
+		subtables := []uint32{}
+		for j := 0; j < int(numSubtables); j++ {
+			r.Seek(lookup + 6 + 2*uint32(j))
+			subtable := lookup + uint32(r.ReadUint16())
+			if lookupType == 9 {
+				// extension subtable
+				r.Seek(subtable + 2)
+				if r.ReadUint16() != 3 {
+					continue
+				}
+				subtable += r.ReadUint32()
+			} else if lookupType != 3 {
+				break
+			}
+			if !r.EOF() {
+				subtables = append(subtables, subtable)
+			}
+		}
+		if len(subtables) == 0 {
+			continue
+		}
+
+		entries := make([]cursiveAnchor, len(glyphIDs))
+		exits := make([]cursiveAnchor, len(glyphIDs))
+		for i, glyphID := range glyphIDs {
+			entries[i], exits[i] = cursiveAnchors(r, subtables, glyphID)
+		}
+		if offsets == nil {
+			offsets = make([]int16, len(glyphIDs))
+		}
+		ys := make([]int16, len(glyphIDs))
+		if lookupFlag&0x0001 == 0 {
+			for i := 0; i+1 < len(glyphIDs); i++ {
+				if exits[i].ok && entries[i+1].ok {
+					ys[i+1] = ys[i] + exits[i].y - entries[i+1].y
+				}
+			}
+		} else {
+			// right-to-left: the last glyph is on the baseline
+			for i := len(glyphIDs) - 2; 0 <= i; i-- {
+				if exits[i].ok && entries[i+1].ok {
+					ys[i] = ys[i+1] + entries[i+1].y - exits[i].y
+				}
+			}
+		}
+		for i := range offsets {
+			offsets[i] += ys[i]
+		}
+	}
+	return offsets
+}
+
+// cursiveAnchors returns the entry and exit anchors of a glyph from the first cursive attachment subtable that covers the glyph.
+func cursiveAnchors(r *binaryReader, subtables []uint32, glyphID uint16) (cursiveAnchor, cursiveAnchor) {
+	for _, subtable := range subtables {
+		r.Seek(subtable)
+		if format := r.ReadUint16(); format != 1 {
+			continue
+		}
+		coverage := uint32(r.ReadUint16())
+		count := r.ReadUint16()
+		i, ok := coverageIndex(r, subtable+coverage, glyphID)
+		if !ok || int(count) <= i {
+			continue
+		}
+		r.Seek(subtable + 6 + 4*uint32(i))
+		entryOffset := uint32(r.ReadUint16())
+		exitOffset := uint32(r.ReadUint16())
+		if r.EOF() {
+			continue
+		}
+		return readAnchor(r, subtable, entryOffset), readAnchor(r, subtable, exitOffset)
+	}
+	return cursiveAnchor{}, cursiveAnchor{}
+}
+
+// readAnchor reads an anchor table at the offset from the subtable, where an offset of zero means there is no anchor. Contour points and device tables of anchor formats 2 and 3 are ignored.
+func readAnchor(r *binaryReader, subtable, offset uint32) cursiveAnchor {
+	if offset == 0 {
+		return cursiveAnchor{}
+	}
+	r.Seek(subtable + offset)
+	_ = r.ReadUint16() // anchorFormat
+	x := r.ReadInt16()
+	y := r.ReadInt16()
+	return cursiveAnchor{x, y, !r.EOF()}
+}
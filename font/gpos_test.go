@@ -0,0 +1,76 @@
+package font
+
+import (
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+// cursiveTable returns a GPOS table with a curs feature of which the lookup attaches glyphs 1, 2 and 3, optionally wrapped in an extension lookup.
+func cursiveTable(lookupFlag uint16, extension bool) []byte {
+	w := newBinaryWriter([]byte{})
+	w.WriteUint32(0x00010000)
+	w.WriteUint16(0)  // scriptListOffset
+	w.WriteUint16(10) // featureListOffset
+	w.WriteUint16(24) // lookupListOffset
+
+	// feature list
+	w.WriteUint16(1)
+	w.WriteString("curs")
+	w.WriteUint16(8)
+	w.WriteUint16(0) // featureParamsOffset
+	w.WriteUint16(1)
+	w.WriteUint16(0)
+
+	// lookup list
+	w.WriteUint16(1)
+	w.WriteUint16(4)
+	if extension {
+		w.WriteUint16(9)
+	} else {
+		w.WriteUint16(3)
+	}
+	w.WriteUint16(lookupFlag)
+	w.WriteUint16(1)
+	w.WriteUint16(8)
+	if extension {
+		w.WriteUint16(1)
+		w.WriteUint16(3)
+		w.WriteUint32(8)
+	}
+
+	// cursive attachment subtable
+	w.WriteUint16(1)
+	w.WriteUint16(18) // coverageOffset
+	w.WriteUint16(3)
+	for _, offsets := range [][2]uint16{{0, 28}, {34, 40}, {46, 0}} {
+		w.WriteUint16(offsets[0])
+		w.WriteUint16(offsets[1])
+	}
+	w.WriteUint16(1)
+	w.WriteUint16(3)
+	w.WriteUint16(1)
+	w.WriteUint16(2)
+	w.WriteUint16(3)
+	for _, anchor := range [][2]int16{{500, 100}, {0, 0}, {500, 300}, {0, 50}} {
+		w.WriteUint16(1)
+		w.WriteInt16(anchor[0])
+		w.WriteInt16(anchor[1])
+	}
+	return w.Bytes()
+}
+
+func TestSFNTCursiveOffsets(t *testing.T) {
+	sfnt := &SFNT{tables: map[string][]byte{"GPOS": cursiveTable(0, false)}}
+	test.T(t, sfnt.CursiveOffsets([]uint16{1, 2, 3, 4}), []int16{0, 100, 350, 0})
+	test.T(t, sfnt.CursiveOffsets([]uint16{2, 4, 2, 3}), []int16{0, 0, 0, 250})
+
+	// right-to-left
+	sfnt.tables["GPOS"] = cursiveTable(0x0001, false)
+	test.T(t, sfnt.CursiveOffsets([]uint16{1, 2, 3, 4}), []int16{-350, -250, 0, 0})
+
+	sfnt.tables["GPOS"] = cursiveTable(0, true)
+	test.T(t, sfnt.CursiveOffsets([]uint16{1, 2, 3}), []int16{0, 100, 350})
+
+	test.T(t, (&SFNT{}).CursiveOffsets([]uint16{1, 2}), []int16(nil))
+}
@@ -0,0 +1,273 @@
+package font
+
+// SingleSubstitutions returns the glyph substitutions of the single substitution lookups (GSUB lookup type 1) of a feature of all scripts, such as the small capitals of the smcp feature, mapping glyph IDs to the substituted glyph IDs. When several lookups substitute the same glyph, the lookups are applied in the order of the lookup list. It returns nil if the font has no single substitutions for the feature, or if the feature has lookups of other types, such as the contextual substitutions of fractions, as the single substitutions alone would not give the result of the feature.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/gsub#lookup-type-1-single-substitution-subtable
+func (sfnt *SFNT) SingleSubstitutions(tag string) map[uint16]uint16 {
+	table, ok := sfnt.Table("GSUB")
+	if !ok || len(table) < 10 {
+		return nil
+	}
+	r := newBinaryReader(table)
+	_ = r.ReadUint32() // version
+	_ = r.ReadUint16() // scriptList
+	featureList := uint32(r.ReadUint16())
+	lookupList := uint32(r.ReadUint16())
+
+	// lookup indices of the features of all scripts, in increasing order
+	lookups := map[uint16]bool{}
+	r.Seek(featureList)
+	numFeatures := r.ReadUint16()
+	for i := 0; i < int(numFeatures) && !r.EOF(); i++ {
+		r.Seek(featureList + 2 + 6*uint32(i))
+		if r.ReadString(4) != tag {
+			continue
+		}
+		feature := featureList + uint32(r.ReadUint16())
+		r.Seek(feature + 2) // skip featureParamsOffset
+		numIndices := r.ReadUint16()
+		for j := 0; j < int(numIndices); j++ {
+			if index := r.ReadUint16(); !r.EOF() {
+				lookups[index] = true
+			}
+		}
+	}
+
+	var substitutions map[uint16]uint16
+	r.Seek(lookupList)
+	numLookups := r.ReadUint16()
+	for index := 0; index < int(numLookups); index++ {
+		if !lookups[uint16(index)] {
+			continue
+		}
+		r.Seek(lookupList + 2 + 2*uint32(index))
+		lookup := lookupList + uint32(r.ReadUint16())
+		r.Seek(lookup)
+		lookupType := r.ReadUint16()
+		_ = r.ReadUint16() // lookupFlag
+		numSubtables := r.ReadUint16()
+
+		// the first subtable that covers a glyph substitutes it
+		lookupSubstitutions := map[uint16]uint16{}
+		for j := 0; j < int(numSubtables); j++ {
+			r.Seek(lookup + 6 + 2*uint32(j))
+			subtable := lookup + uint32(r.ReadUint16())
+			if lookupType == 7 {
+				// extension subtable
+				r.Seek(subtable + 2)
+				if r.ReadUint16() != 1 {
+					return nil
+				}
+				subtable += r.ReadUint32()
+			} else if lookupType != 1 {
+				return nil
+			}
+			if r.EOF() {
+				continue
+			}
+			for glyphID, substitute := range singleSubstitutions(r, subtable) {
+				if _, ok := lookupSubstitutions[glyphID]; !ok {
+					lookupSubstitutions[glyphID] = substitute
+				}
+			}
+		}
+		if len(lookupSubstitutions) == 0 {
+			continue
+		}
+
+		if substitutions == nil {
+			substitutions = map[uint16]uint16{}
+		}
+		for glyphID, substitute := range substitutions {
+			// substitute the result of earlier lookups
+			if next, ok := lookupSubstitutions[substitute]; ok {
+				substitutions[glyphID] = next
+			}
+		}
+		for glyphID, substitute := range lookupSubstitutions {
+			if _, ok := substitutions[glyphID]; !ok {
+				substitutions[glyphID] = substitute
+			}
+		}
+	}
+	return substitutions
+}
+
+// singleSubstitutions returns the glyph substitutions of a single substitution subtable of format 1 (delta) or 2 (substitute array).
+func singleSubstitutions(r *binaryReader, subtable uint32) map[uint16]uint16 {
+	r.Seek(subtable)
+	format := r.ReadUint16()
+	coverage := uint32(r.ReadUint16())
+	if r.EOF() || format != 1 && format != 2 {
+		return nil
+	}
+	delta := r.ReadUint16()
+	count := int(delta) // glyphCount for format 2
+
+	substitutions := map[uint16]uint16{}
+	glyphIDs := coverageGlyphs(r, subtable+coverage)
+	for i, glyphID := range glyphIDs {
+		if format == 1 {
+			substitutions[glyphID] = glyphID + delta // addition modulo 65536
+		} else if i < count {
+			r.Seek(subtable + 6 + 2*uint32(i))
+			if substitute := r.ReadUint16(); !r.EOF() {
+				substitutions[glyphID] = substitute
+			}
+		}
+	}
+	return substitutions
+}
+
+// coverageGlyphs returns the glyphs of the coverage table at the given offset in the order of their coverage indices, see coverageIndex.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/chapter2#coverage-table
+func coverageGlyphs(r *binaryReader, offset uint32) []uint16 {
+	r.Seek(offset)
+	format := r.ReadUint16()
+	count := r.ReadUint16()
+	glyphIDs := []uint16{}
+	switch format {
+	case 1:
+		for i := 0; i < int(count); i++ {
+			if id := r.ReadUint16(); !r.EOF() {
+				glyphIDs = append(glyphIDs, id)
+			}
+		}
+	case 2:
+		for i := 0; i < int(count); i++ {
+			start := r.ReadUint16()
+			end := r.ReadUint16()
+			_ = r.ReadUint16() // startCoverageIndex, ranges are in increasing order
+			if r.EOF() {
+				break
+			}
+			for id := uint32(start); id <= uint32(end) && len(glyphIDs) < 0x10000; id++ { // bound malformed ranges
+				glyphIDs = append(glyphIDs, uint16(id))
+			}
+		}
+	}
+	return glyphIDs
+}
+
+// Ligature is a ligature substitution that replaces a sequence of component glyphs by a single ligature glyph.
+type Ligature struct {
+	Components []uint16 // glyph IDs of the components, including the first
+	Glyph      uint16
+}
+
+// LigatureSubstitutions returns the ligature substitutions of the ligature substitution lookups (GSUB lookup type 4) of a feature of all scripts, such as the fi and ffl ligatures of the liga feature. It returns the ligatures per lookup in the order of the lookup list, where the ligatures of a lookup are mapped by their first component in the order of preference of the font. Lookups of other types, such as the contextual substitutions that prevent ligatures in some contexts, are skipped, and the lookup flags are ignored. It returns nil if the font has no ligature substitutions for the feature.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/gsub#lookup-type-4-ligature-substitution-subtable
+func (sfnt *SFNT) LigatureSubstitutions(tag string) []map[uint16][]Ligature {
+	table, ok := sfnt.Table("GSUB")
+	if !ok || len(table) < 10 {
+		return nil
+	}
+	r := newBinaryReader(table)
+	_ = r.ReadUint32() // version
+	_ = r.ReadUint16() // scriptList
+	featureList := uint32(r.ReadUint16())
+	lookupList := uint32(r.ReadUint16())
+
+	// lookup indices of the features of all scripts, in increasing order
+	lookups := map[uint16]bool{}
+	r.Seek(featureList)
+	numFeatures := r.ReadUint16()
+	for i := 0; i < int(numFeatures) && !r.EOF(); i++ {
+		r.Seek(featureList + 2 + 6*uint32(i))
+		if r.ReadString(4) != tag {
+			continue
+		}
+		feature := featureList + uint32(r.ReadUint16())
+		r.Seek(feature + 2) // skip featureParamsOffset
+		numIndices := r.ReadUint16()
+		for j := 0; j < int(numIndices); j++ {
+			if index := r.ReadUint16(); !r.EOF() {
+				lookups[index] = true
+			}
+		}
+	}
+
+	var substitutions []map[uint16][]Ligature
+	r.Seek(lookupList)
+	numLookups := r.ReadUint16()
+	for index := 0; index < int(numLookups); index++ {
+		if !lookups[uint16(index)] {
+			continue
+		}
+		r.Seek(lookupList + 2 + 2*uint32(index))
+		lookup := lookupList + uint32(r.ReadUint16())
+		r.Seek(lookup)
+		lookupType := r.ReadUint16()
+		_ = r.ReadUint16() // lookupFlag
+		numSubtables := r.ReadUint16()
+
+		// the first subtable that covers a glyph substitutes it
+		lookupLigatures := map[uint16][]Ligature{}
+		for j := 0; j < int(numSubtables); j++ {
+			r.Seek(lookup + 6 + 2*uint32(j))
+			subtable := lookup + uint32(r.ReadUint16())
+			if lookupType == 7 {
+				// extension subtable
+				r.Seek(subtable + 2)
+				if r.ReadUint16() != 4 {
+					break
+				}
+				subtable += r.ReadUint32()
+			} else if lookupType != 4 {
+				break
+			}
+			if r.EOF() {
+				continue
+			}
+			for glyphID, ligatures := range ligatureSubstitutions(r, subtable) {
+				if _, ok := lookupLigatures[glyphID]; !ok {
+					lookupLigatures[glyphID] = ligatures
+				}
+			}
+		}
+		if len(lookupLigatures) != 0 {
+			substitutions = append(substitutions, lookupLigatures)
+		}
+	}
+	return substitutions
+}
+
+// ligatureSubstitutions returns the ligatures of a ligature substitution subtable of format 1 by their first component.
+func ligatureSubstitutions(r *binaryReader, subtable uint32) map[uint16][]Ligature {
+	r.Seek(subtable)
+	format := r.ReadUint16()
+	coverage := uint32(r.ReadUint16())
+	count := int(r.ReadUint16()) // ligatureSetCount
+	if r.EOF() || format != 1 {
+		return nil
+	}
+
+	substitutions := map[uint16][]Ligature{}
+	glyphIDs := coverageGlyphs(r, subtable+coverage)
+	for i, glyphID := range glyphIDs {
+		if count <= i {
+			break
+		}
+		r.Seek(subtable + 6 + 2*uint32(i))
+		ligatureSet := subtable + uint32(r.ReadUint16())
+		r.Seek(ligatureSet)
+		numLigatures := r.ReadUint16()
+		for j := 0; j < int(numLigatures) && !r.EOF(); j++ {
+			r.Seek(ligatureSet + 2 + 2*uint32(j))
+			r.Seek(ligatureSet + uint32(r.ReadUint16()))
+			ligature := Ligature{Glyph: r.ReadUint16()}
+			numComponents := r.ReadUint16()
+			if r.EOF() || numComponents == 0 {
+				continue
+			}
+			ligature.Components = make([]uint16, numComponents)
+			ligature.Components[0] = glyphID
+			for k := 1; k < int(numComponents); k++ {
+				ligature.Components[k] = r.ReadUint16()
+			}
+			if !r.EOF() {
+				substitutions[glyphID] = append(substitutions[glyphID], ligature)
+			}
+		}
+	}
+	return substitutions
+}
@@ -0,0 +1,168 @@
+package font
+
+import (
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+// singleSubstitutionTable returns a GSUB table with an smcp feature of which the first lookup substitutes glyphs 1 and 2 by a delta of 10 and the second lookup substitutes glyphs 3 and 11 by glyphs 30 and 20, optionally wrapped in an extension lookup.
+func singleSubstitutionTable(extension bool) []byte {
+	w := newBinaryWriter([]byte{})
+	w.WriteUint32(0x00010000)
+	w.WriteUint16(0)  // scriptListOffset
+	w.WriteUint16(10) // featureListOffset
+	w.WriteUint16(26) // lookupListOffset
+
+	// feature list
+	w.WriteUint16(1)
+	w.WriteString("smcp")
+	w.WriteUint16(8)
+	w.WriteUint16(0) // featureParamsOffset
+	w.WriteUint16(2)
+	w.WriteUint16(0)
+	w.WriteUint16(1)
+
+	// lookup list
+	w.WriteUint16(2)
+	w.WriteUint16(6)
+	w.WriteUint16(30)
+
+	// single substitution lookup of format 1 with a coverage of format 2
+	w.WriteUint16(1)
+	w.WriteUint16(0) // lookupFlag
+	w.WriteUint16(1)
+	w.WriteUint16(8)
+	w.WriteUint16(1)
+	w.WriteUint16(6) // coverageOffset
+	w.WriteUint16(10)
+	w.WriteUint16(2)
+	w.WriteUint16(1)
+	w.WriteUint16(1)
+	w.WriteUint16(2)
+	w.WriteUint16(0)
+
+	// single substitution lookup of format 2 with a coverage of format 1
+	if extension {
+		w.WriteUint16(7)
+	} else {
+		w.WriteUint16(1)
+	}
+	w.WriteUint16(0) // lookupFlag
+	w.WriteUint16(1)
+	w.WriteUint16(8)
+	if extension {
+		w.WriteUint16(1)
+		w.WriteUint16(1)
+		w.WriteUint32(8)
+	}
+	w.WriteUint16(2)
+	w.WriteUint16(10) // coverageOffset
+	w.WriteUint16(2)
+	w.WriteUint16(30)
+	w.WriteUint16(20)
+	w.WriteUint16(1)
+	w.WriteUint16(2)
+	w.WriteUint16(3)
+	w.WriteUint16(11)
+	return w.Bytes()
+}
+
+func TestSFNTSingleSubstitutions(t *testing.T) {
+	sfnt := &SFNT{tables: map[string][]byte{"GSUB": singleSubstitutionTable(false)}}
+	test.T(t, sfnt.SingleSubstitutions("smcp"), map[uint16]uint16{1: 20, 2: 12, 3: 30, 11: 20})
+	test.T(t, sfnt.SingleSubstitutions("c2sc"), map[uint16]uint16(nil))
+
+	sfnt.tables["GSUB"] = singleSubstitutionTable(true)
+	test.T(t, sfnt.SingleSubstitutions("smcp"), map[uint16]uint16{1: 20, 2: 12, 3: 30, 11: 20})
+
+	// truncated
+	sfnt.tables["GSUB"] = singleSubstitutionTable(false)[:62]
+	test.T(t, sfnt.SingleSubstitutions("smcp"), map[uint16]uint16{1: 11, 2: 12})
+
+	// chaining contextual substitution lookup
+	table := singleSubstitutionTable(false)
+	table[57] = 6
+	sfnt.tables["GSUB"] = table
+	test.T(t, sfnt.SingleSubstitutions("smcp"), map[uint16]uint16(nil))
+
+	test.T(t, (&SFNT{}).SingleSubstitutions("smcp"), map[uint16]uint16(nil))
+}
+
+// ligatureSubstitutionTable returns a GSUB table with a liga feature of which the lookup substitutes glyphs 5, 6 and 7 by glyph 100 and glyphs 5 and 6 by glyph 101, optionally wrapped in an extension lookup.
+func ligatureSubstitutionTable(extension bool) []byte {
+	w := newBinaryWriter([]byte{})
+	w.WriteUint32(0x00010000)
+	w.WriteUint16(0)  // scriptListOffset
+	w.WriteUint16(10) // featureListOffset
+	w.WriteUint16(24) // lookupListOffset
+
+	// feature list
+	w.WriteUint16(1)
+	w.WriteString("liga")
+	w.WriteUint16(8)
+	w.WriteUint16(0) // featureParamsOffset
+	w.WriteUint16(1)
+	w.WriteUint16(0)
+
+	// lookup list
+	w.WriteUint16(1)
+	w.WriteUint16(4)
+
+	// ligature substitution lookup with a coverage of format 1
+	if extension {
+		w.WriteUint16(7)
+	} else {
+		w.WriteUint16(4)
+	}
+	w.WriteUint16(0) // lookupFlag
+	w.WriteUint16(1)
+	w.WriteUint16(8)
+	if extension {
+		w.WriteUint16(1)
+		w.WriteUint16(4)
+		w.WriteUint32(8)
+	}
+	w.WriteUint16(1)
+	w.WriteUint16(8) // coverageOffset
+	w.WriteUint16(1)
+	w.WriteUint16(14)
+	w.WriteUint16(1)
+	w.WriteUint16(1)
+	w.WriteUint16(5)
+
+	// ligature set
+	w.WriteUint16(2)
+	w.WriteUint16(6)
+	w.WriteUint16(14)
+	w.WriteUint16(100)
+	w.WriteUint16(3)
+	w.WriteUint16(6)
+	w.WriteUint16(7)
+	w.WriteUint16(101)
+	w.WriteUint16(2)
+	w.WriteUint16(6)
+	return w.Bytes()
+}
+
+func TestSFNTLigatureSubstitutions(t *testing.T) {
+	ligatures := []map[uint16][]Ligature{{5: {{[]uint16{5, 6, 7}, 100}, {[]uint16{5, 6}, 101}}}}
+	sfnt := &SFNT{tables: map[string][]byte{"GSUB": ligatureSubstitutionTable(false)}}
+	test.T(t, sfnt.LigatureSubstitutions("liga"), ligatures)
+	test.T(t, sfnt.LigatureSubstitutions("dlig"), []map[uint16][]Ligature(nil))
+
+	sfnt.tables["GSUB"] = ligatureSubstitutionTable(true)
+	test.T(t, sfnt.LigatureSubstitutions("liga"), ligatures)
+
+	// truncated
+	sfnt.tables["GSUB"] = ligatureSubstitutionTable(false)[:len(ligatureSubstitutionTable(false))-2]
+	test.T(t, sfnt.LigatureSubstitutions("liga"), []map[uint16][]Ligature{{5: {{[]uint16{5, 6, 7}, 100}}}})
+
+	// single substitution lookup
+	table := ligatureSubstitutionTable(false)
+	table[29] = 1
+	sfnt.tables["GSUB"] = table
+	test.T(t, sfnt.LigatureSubstitutions("liga"), []map[uint16][]Ligature(nil))
+
+	test.T(t, (&SFNT{}).LigatureSubstitutions("liga"), []map[uint16][]Ligature(nil))
+}
@@ -0,0 +1,265 @@
+package font
+
+// MathConstants are the constants of the MATH table for positioning the elements of mathematical formulas, see https://docs.microsoft.com/en-us/typography/opentype/spec/math#mathconstants-table. Percentages are the scale of scripts and of the degree bottom of radicals, and all other values are in font units.
+type MathConstants struct {
+	ScriptPercentScaleDown                   int16
+	ScriptScriptPercentScaleDown             int16
+	DelimitedSubFormulaMinHeight             uint16
+	DisplayOperatorMinHeight                 uint16
+	MathLeading                              int16
+	AxisHeight                               int16
+	AccentBaseHeight                         int16
+	FlattenedAccentBaseHeight                int16
+	SubscriptShiftDown                       int16
+	SubscriptTopMax                          int16
+	SubscriptBaselineDropMin                 int16
+	SuperscriptShiftUp                       int16
+	SuperscriptShiftUpCramped                int16
+	SuperscriptBottomMin                     int16
+	SuperscriptBaselineDropMax               int16
+	SubSuperscriptGapMin                     int16
+	SuperscriptBottomMaxWithSubscript        int16
+	SpaceAfterScript                         int16
+	UpperLimitGapMin                         int16
+	UpperLimitBaselineRiseMin                int16
+	LowerLimitGapMin                         int16
+	LowerLimitBaselineDropMin                int16
+	StackTopShiftUp                          int16
+	StackTopDisplayStyleShiftUp              int16
+	StackBottomShiftDown                     int16
+	StackBottomDisplayStyleShiftDown         int16
+	StackGapMin                              int16
+	StackDisplayStyleGapMin                  int16
+	StretchStackTopShiftUp                   int16
+	StretchStackBottomShiftDown              int16
+	StretchStackGapAboveMin                  int16
+	StretchStackGapBelowMin                  int16
+	FractionNumeratorShiftUp                 int16
+	FractionNumeratorDisplayStyleShiftUp     int16
+	FractionDenominatorShiftDown             int16
+	FractionDenominatorDisplayStyleShiftDown int16
+	FractionNumeratorGapMin                  int16
+	FractionNumDisplayStyleGapMin            int16
+	FractionRuleThickness                    int16
+	FractionDenominatorGapMin                int16
+	FractionDenomDisplayStyleGapMin          int16
+	SkewedFractionHorizontalGap              int16
+	SkewedFractionVerticalGap                int16
+	OverbarVerticalGap                       int16
+	OverbarRuleThickness                     int16
+	OverbarExtraAscender                     int16
+	UnderbarVerticalGap                      int16
+	UnderbarRuleThickness                    int16
+	UnderbarExtraDescender                   int16
+	RadicalVerticalGap                       int16
+	RadicalDisplayStyleVerticalGap           int16
+	RadicalRuleThickness                     int16
+	RadicalExtraAscender                     int16
+	RadicalKernBeforeDegree                  int16
+	RadicalKernAfterDegree                   int16
+	RadicalDegreeBottomRaisePercent          int16
+}
+
+// MathGlyphVariant is a larger variant of a glyph for stretchy operators and delimiters from the MATH table, with its advance height (for vertical variants) or width (for horizontal variants) in font units.
+type MathGlyphVariant struct {
+	GlyphID uint16
+	Advance uint16
+}
+
+// MathGlyphPart is a part of a glyph assembly, where extenders can be repeated any number of times, including zero. Connector lengths are the lengths at the start and end of the part that may overlap with adjacent parts, in font units.
+type MathGlyphPart struct {
+	GlyphID                                  uint16
+	StartConnectorLength, EndConnectorLength uint16
+	FullAdvance                              uint16
+	Extender                                 bool
+}
+
+// MathGlyphAssembly is a construction of a stretchy glyph of arbitrary size from parts, from bottom to top for vertical assemblies and from left to right for horizontal assemblies. Adjacent parts must overlap by at least MinConnectorOverlap font units.
+type MathGlyphAssembly struct {
+	ItalicsCorrection   int16
+	MinConnectorOverlap uint16
+	Parts               []MathGlyphPart
+}
+
+// mathTable returns a reader of the MATH table and the offsets of its subtables, which is nil if the font has no valid MATH table.
+func (sfnt *SFNT) mathTable() (*binaryReader, uint32, uint32, uint32) {
+	table, ok := sfnt.Table("MATH")
+	if !ok || len(table) < 10 {
+		return nil, 0, 0, 0
+	}
+	r := newBinaryReader(table)
+	if majorVersion := r.ReadUint16(); majorVersion != 1 {
+		return nil, 0, 0, 0
+	}
+	_ = r.ReadUint16() // minorVersion
+	constants := uint32(r.ReadUint16())
+	glyphInfo := uint32(r.ReadUint16())
+	variants := uint32(r.ReadUint16())
+	return r, constants, glyphInfo, variants
+}
+
+// MathConstants returns the constants of the MATH table, and false if the font has no MATH table, such as fonts that are not math fonts.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/math
+func (sfnt *SFNT) MathConstants() (MathConstants, bool) {
+	r, offset, _, _ := sfnt.mathTable()
+	if r == nil || offset == 0 {
+		return MathConstants{}, false
+	}
+
+	r.Seek(offset)
+	c := MathConstants{}
+	c.ScriptPercentScaleDown = r.ReadInt16()
+	c.ScriptScriptPercentScaleDown = r.ReadInt16()
+	c.DelimitedSubFormulaMinHeight = r.ReadUint16()
+	c.DisplayOperatorMinHeight = r.ReadUint16()
+	for _, v := range []*int16{
+		&c.MathLeading, &c.AxisHeight, &c.AccentBaseHeight, &c.FlattenedAccentBaseHeight,
+		&c.SubscriptShiftDown, &c.SubscriptTopMax, &c.SubscriptBaselineDropMin,
+		&c.SuperscriptShiftUp, &c.SuperscriptShiftUpCramped, &c.SuperscriptBottomMin, &c.SuperscriptBaselineDropMax,
+		&c.SubSuperscriptGapMin, &c.SuperscriptBottomMaxWithSubscript, &c.SpaceAfterScript,
+		&c.UpperLimitGapMin, &c.UpperLimitBaselineRiseMin, &c.LowerLimitGapMin, &c.LowerLimitBaselineDropMin,
+		&c.StackTopShiftUp, &c.StackTopDisplayStyleShiftUp, &c.StackBottomShiftDown, &c.StackBottomDisplayStyleShiftDown,
+		&c.StackGapMin, &c.StackDisplayStyleGapMin,
+		&c.StretchStackTopShiftUp, &c.StretchStackBottomShiftDown, &c.StretchStackGapAboveMin, &c.StretchStackGapBelowMin,
+		&c.FractionNumeratorShiftUp, &c.FractionNumeratorDisplayStyleShiftUp,
+		&c.FractionDenominatorShiftDown, &c.FractionDenominatorDisplayStyleShiftDown,
+		&c.FractionNumeratorGapMin, &c.FractionNumDisplayStyleGapMin, &c.FractionRuleThickness,
+		&c.FractionDenominatorGapMin, &c.FractionDenomDisplayStyleGapMin,
+		&c.SkewedFractionHorizontalGap, &c.SkewedFractionVerticalGap,
+		&c.OverbarVerticalGap, &c.OverbarRuleThickness, &c.OverbarExtraAscender,
+		&c.UnderbarVerticalGap, &c.UnderbarRuleThickness, &c.UnderbarExtraDescender,
+		&c.RadicalVerticalGap, &c.RadicalDisplayStyleVerticalGap, &c.RadicalRuleThickness, &c.RadicalExtraAscender,
+		&c.RadicalKernBeforeDegree, &c.RadicalKernAfterDegree,
+	} {
+		*v = r.ReadInt16()
+		_ = r.ReadUint16() // device table offset
+	}
+	c.RadicalDegreeBottomRaisePercent = r.ReadInt16()
+	if r.EOF() {
+		return MathConstants{}, false
+	}
+	return c, true
+}
+
+// MathItalicsCorrection returns the italics correction of a glyph from the MATH table in font units, which is the extra space after slanted glyphs that is added before superscripts, and false if the glyph has no italics correction.
+func (sfnt *SFNT) MathItalicsCorrection(glyphID uint16) (int16, bool) {
+	r, _, glyphInfo, _ := sfnt.mathTable()
+	if r == nil || glyphInfo == 0 {
+		return 0, false
+	}
+
+	r.Seek(glyphInfo)
+	italics := uint32(r.ReadUint16())
+	if r.EOF() || italics == 0 {
+		return 0, false
+	}
+	italics += glyphInfo
+	r.Seek(italics)
+	coverage := uint32(r.ReadUint16())
+	count := r.ReadUint16()
+	i, ok := coverageIndex(r, italics+coverage, glyphID)
+	if !ok || int(count) <= i {
+		return 0, false
+	}
+	r.Seek(italics + 4 + 4*uint32(i))
+	correction := r.ReadInt16()
+	return correction, !r.EOF()
+}
+
+// MathVariants returns the larger variants of a glyph from the MATH table in increasing size, and the assembly to build a glyph of arbitrary size from parts, which is nil if the glyph cannot be assembled. Vertical variants are for stretchy delimiters such as parentheses and braces, and horizontal variants for stretchy accents and arrows.
+func (sfnt *SFNT) MathVariants(glyphID uint16, vertical bool) ([]MathGlyphVariant, *MathGlyphAssembly) {
+	r, _, _, variants := sfnt.mathTable()
+	if r == nil || variants == 0 {
+		return nil, nil
+	}
+
+	r.Seek(variants)
+	minConnectorOverlap := r.ReadUint16()
+	vertCoverage := uint32(r.ReadUint16())
+	horizCoverage := uint32(r.ReadUint16())
+	vertCount := uint32(r.ReadUint16())
+	horizCount := uint32(r.ReadUint16())
+	if r.EOF() {
+		return nil, nil
+	}
+
+	coverage, count, first := vertCoverage, vertCount, uint32(0)
+	if !vertical {
+		coverage, count, first = horizCoverage, horizCount, vertCount
+	}
+	if coverage == 0 {
+		return nil, nil
+	}
+	i, ok := coverageIndex(r, variants+coverage, glyphID)
+	if !ok || count <= uint32(i) {
+		return nil, nil
+	}
+	r.Seek(variants + 10 + 2*(first+uint32(i)))
+	construction := variants + uint32(r.ReadUint16())
+	r.Seek(construction)
+	assemblyOffset := uint32(r.ReadUint16())
+	variantCount := r.ReadUint16()
+	glyphVariants := make([]MathGlyphVariant, 0, variantCount)
+	for j := 0; j < int(variantCount); j++ {
+		variant := MathGlyphVariant{r.ReadUint16(), r.ReadUint16()}
+		if r.EOF() {
+			return nil, nil
+		}
+		glyphVariants = append(glyphVariants, variant)
+	}
+	if assemblyOffset == 0 {
+		return glyphVariants, nil
+	}
+
+	r.Seek(construction + assemblyOffset)
+	assembly := &MathGlyphAssembly{
+		ItalicsCorrection:   r.ReadInt16(),
+		MinConnectorOverlap: minConnectorOverlap,
+	}
+	_ = r.ReadUint16() // device table offset
+	partCount := r.ReadUint16()
+	for j := 0; j < int(partCount); j++ {
+		part := MathGlyphPart{
+			GlyphID:              r.ReadUint16(),
+			StartConnectorLength: r.ReadUint16(),
+			EndConnectorLength:   r.ReadUint16(),
+			FullAdvance:          r.ReadUint16(),
+			Extender:             r.ReadUint16()&0x0001 != 0,
+		}
+		if r.EOF() {
+			return glyphVariants, nil
+		}
+		assembly.Parts = append(assembly.Parts, part)
+	}
+	return glyphVariants, assembly
+}
+
+// coverageIndex returns the index of a glyph in the coverage table at the given offset, and false if the glyph is not covered.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/chapter2#coverage-table
+func coverageIndex(r *binaryReader, offset uint32, glyphID uint16) (int, bool) {
+	r.Seek(offset)
+	format := r.ReadUint16()
+	count := r.ReadUint16()
+	switch format {
+	case 1:
+		for i := 0; i < int(count); i++ {
+			if id := r.ReadUint16(); r.EOF() {
+				return 0, false
+			} else if id == glyphID {
+				return i, true
+			}
+		}
+	case 2:
+		for i := 0; i < int(count); i++ {
+			start := r.ReadUint16()
+			end := r.ReadUint16()
+			index := r.ReadUint16()
+			if r.EOF() {
+				return 0, false
+			} else if start <= glyphID && glyphID <= end {
+				return int(index) + int(glyphID-start), true
+			}
+		}
+	}
+	return 0, false
+}
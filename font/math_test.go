@@ -0,0 +1,120 @@
+package font
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func mathTable() []byte {
+	w := newBinaryWriter([]byte{})
+	w.WriteUint16(1)
+	w.WriteUint16(0)
+	w.WriteUint16(10)       // constants
+	w.WriteUint16(10 + 214) // glyph info
+	w.WriteUint16(10 + 214 + 8 + 20)
+
+	// constants, where each value is its index
+	w.WriteUint16(70)
+	w.WriteUint16(50)
+	w.WriteUint16(1300)
+	w.WriteUint16(1800)
+	for i := 0; i < 51; i++ {
+		w.WriteInt16(int16(i))
+		w.WriteUint16(0)
+	}
+	w.WriteInt16(-60)
+
+	// glyph info with italics correction of glyphs 5 and 7
+	w.WriteUint16(8)
+	w.WriteUint16(0)
+	w.WriteUint16(0)
+	w.WriteUint16(0)
+	w.WriteUint16(12) // coverage
+	w.WriteUint16(2)
+	w.WriteInt16(30)
+	w.WriteUint16(0)
+	w.WriteInt16(-40)
+	w.WriteUint16(0)
+	w.WriteUint16(1) // coverage format 1
+	w.WriteUint16(2)
+	w.WriteUint16(5)
+	w.WriteUint16(7)
+
+	// variants of the vertical glyph 3
+	w.WriteUint16(20)
+	w.WriteUint16(12) // vertical coverage
+	w.WriteUint16(0)
+	w.WriteUint16(1)
+	w.WriteUint16(0)
+	w.WriteUint16(12 + 10) // construction
+	w.WriteUint16(2)       // coverage format 2
+	w.WriteUint16(1)
+	w.WriteUint16(3)
+	w.WriteUint16(3)
+	w.WriteUint16(0)
+	w.WriteUint16(12) // assembly
+	w.WriteUint16(2)
+	w.WriteUint16(100)
+	w.WriteUint16(1200)
+	w.WriteUint16(101)
+	w.WriteUint16(1800)
+	w.WriteInt16(0)
+	w.WriteUint16(0)
+	w.WriteUint16(2)
+	for _, part := range [][5]uint16{{102, 0, 150, 600, 0}, {103, 150, 150, 300, 1}} {
+		for _, v := range part {
+			w.WriteUint16(v)
+		}
+	}
+	return w.Bytes()
+}
+
+func TestSFNTMath(t *testing.T) {
+	sfnt := &SFNT{tables: map[string][]byte{"MATH": mathTable()}}
+	c, ok := sfnt.MathConstants()
+	test.That(t, ok)
+	test.T(t, c.ScriptPercentScaleDown, int16(70))
+	test.T(t, c.DisplayOperatorMinHeight, uint16(1800))
+	test.T(t, c.AxisHeight, int16(1))
+	test.T(t, c.FractionRuleThickness, int16(34))
+	test.T(t, c.RadicalKernAfterDegree, int16(50))
+	test.T(t, c.RadicalDegreeBottomRaisePercent, int16(-60))
+
+	correction, ok := sfnt.MathItalicsCorrection(7)
+	test.That(t, ok)
+	test.T(t, correction, int16(-40))
+	_, ok = sfnt.MathItalicsCorrection(6)
+	test.That(t, !ok)
+
+	variants, assembly := sfnt.MathVariants(3, true)
+	test.T(t, variants, []MathGlyphVariant{{100, 1200}, {101, 1800}})
+	test.T(t, assembly, &MathGlyphAssembly{0, 20, []MathGlyphPart{{102, 0, 150, 600, false}, {103, 150, 150, 300, true}}})
+	variants, assembly = sfnt.MathVariants(3, false)
+	test.T(t, len(variants), 0)
+	test.That(t, assembly == nil)
+
+	b, err := ioutil.ReadFile("DejaVuSerif.ttf")
+	test.Error(t, err)
+	sfnt, err = NewSFNT(b)
+	test.Error(t, err)
+	c, ok = sfnt.MathConstants()
+	test.That(t, ok)
+	test.T(t, c.AxisHeight, int16(642))
+	test.T(t, c.FractionRuleThickness, int16(90))
+	test.T(t, c.ScriptPercentScaleDown, int16(80))
+
+	b, err = ioutil.ReadFile("EBGaramond12-Regular.otf")
+	test.Error(t, err)
+	sfnt, err = NewSFNT(b)
+	test.Error(t, err)
+	_, ok = sfnt.MathConstants()
+	test.That(t, !ok, "font without MATH table")
+
+	sfnt.tables["MATH"] = mathTable()[:100]
+	_, ok = sfnt.MathConstants()
+	test.That(t, !ok, "truncated MATH table")
+	_, ok = (&SFNT{}).MathConstants()
+	test.That(t, !ok, "no MATH table")
+}
@@ -0,0 +1,83 @@
+package font
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+)
+
+// sbixStrikes returns the strikes of the sbix table.
+func (sfnt *SFNT) sbixStrikes() ([]bitmapStrike, error) {
+	sbix, ok := sfnt.Table("sbix")
+	if !ok || len(sbix) < 8 {
+		return nil, nil
+	}
+	maxp, ok := sfnt.Table("maxp")
+	if !ok || len(maxp) < 6 {
+		return nil, ErrInvalidFontData
+	}
+	numGlyphs := uint32(newBinaryReader(maxp[4:]).ReadUint16())
+
+	r := newBinaryReader(sbix)
+	_ = r.ReadUint16() // version
+	_ = r.ReadUint16() // flags
+	numStrikes := r.ReadUint32()
+	n := uint32(len(sbix))
+	if (n-8)/4 < numStrikes {
+		return nil, ErrInvalidFontData
+	}
+	strikes := make([]bitmapStrike, 0, numStrikes)
+	for i := uint32(0); i < numStrikes; i++ {
+		r.Seek(8 + 4*i)
+		offset := r.ReadUint32()
+		if n < offset || n-offset < 4 || (n-offset-4)/4 < numGlyphs+1 {
+			return nil, ErrInvalidFontData
+		}
+		r.Seek(offset)
+		strikes = append(strikes, bitmapStrike{r.ReadUint16(), func(glyphID uint16) (image.Image, error) {
+			if numGlyphs <= uint32(glyphID) {
+				return nil, ErrNoBitmap
+			}
+			return sbixBitmap(sbix, offset, numGlyphs, glyphID, 0)
+		}})
+	}
+	return strikes, nil
+}
+
+// sbixBitmap decodes the bitmap of a glyph in the strike at the given offset, following duplicates of other glyphs up to a limited depth.
+func sbixBitmap(sbix []byte, offset, numGlyphs uint32, glyphID uint16, depth int) (image.Image, error) {
+	if numGlyphs <= uint32(glyphID) {
+		return nil, ErrInvalidFontData
+	}
+	r := newBinaryReader(sbix)
+	r.Seek(offset + 4 + 4*uint32(glyphID))
+	start := offset + r.ReadUint32()
+	end := offset + r.ReadUint32()
+	if end < start || uint32(len(sbix)) < end {
+		return nil, ErrInvalidFontData
+	} else if start == end {
+		return nil, ErrNoBitmap
+	} else if end-start < 8 {
+		return nil, ErrInvalidFontData
+	}
+
+	r.Seek(start)
+	_ = r.ReadInt16() // originOffsetX
+	_ = r.ReadInt16() // originOffsetY
+	graphicType := r.ReadString(4)
+	data := sbix[start+8 : end]
+	switch graphicType {
+	case "png ":
+		return png.Decode(bytes.NewReader(data))
+	case "jpg ":
+		return jpeg.Decode(bytes.NewReader(data))
+	case "dupe":
+		if len(data) < 2 || 4 < depth {
+			return nil, ErrInvalidFontData
+		}
+		return sbixBitmap(sbix, offset, numGlyphs, newBinaryReader(data).ReadUint16(), depth+1)
+	}
+	return nil, fmt.Errorf("sbix: unsupported graphic type %q", graphicType)
+}
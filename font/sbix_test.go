@@ -0,0 +1,101 @@
+package font
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func sbixPNG(size int, col color.RGBA) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = col.R, col.G, col.B, col.A
+	}
+	buf := &bytes.Buffer{}
+	_ = png.Encode(buf, img)
+	return buf.Bytes()
+}
+
+func TestSFNTBitmapGlyph(t *testing.T) {
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+
+	// glyph 1 has bitmaps in both strikes, glyph 2 is a duplicate of glyph 1 and glyph 3 only has a bitmap in the small strike
+	strike := func(ppem int, col color.RGBA, glyph3 bool) []byte {
+		glyphs := [][]byte{nil, sbixPNG(ppem, col), {0, 1}, nil}
+		types := []string{"", "png ", "dupe", ""}
+		if glyph3 {
+			glyphs[3], types[3] = sbixPNG(ppem, col), "png "
+		}
+		w := newBinaryWriter([]byte{})
+		w.WriteUint16(uint16(ppem))
+		w.WriteUint16(72) // ppi
+		offset := uint32(4 + 4*(len(glyphs)+1))
+		for _, glyph := range glyphs {
+			w.WriteUint32(offset)
+			if glyph != nil {
+				offset += 8 + uint32(len(glyph))
+			}
+		}
+		w.WriteUint32(offset)
+		for i, glyph := range glyphs {
+			if glyph != nil {
+				w.WriteInt16(0) // originOffsetX
+				w.WriteInt16(0) // originOffsetY
+				w.WriteString(types[i])
+				w.WriteBytes(glyph)
+			}
+		}
+		return w.Bytes()
+	}
+	small, large := strike(20, red, true), strike(40, blue, false)
+
+	w := newBinaryWriter([]byte{})
+	w.WriteUint16(1) // version
+	w.WriteUint16(1) // flags
+	w.WriteUint32(2) // numStrikes
+	w.WriteUint32(16)
+	w.WriteUint32(16 + uint32(len(small)))
+	w.WriteBytes(small)
+	w.WriteBytes(large)
+	sbix := w.Bytes()
+
+	maxp := newBinaryWriter([]byte{})
+	maxp.WriteUint32(0x00005000) // version
+	maxp.WriteUint16(4)          // numGlyphs
+
+	sfnt := &SFNT{tables: map[string][]byte{"sbix": sbix, "maxp": maxp.Bytes()}}
+	img, err := sfnt.BitmapGlyph(1, 20)
+	test.Error(t, err)
+	test.T(t, img.Bounds(), image.Rect(0, 0, 20, 20))
+	test.T(t, color.RGBAModel.Convert(img.At(10, 10)), red)
+
+	img, err = sfnt.BitmapGlyph(1, 30) // scaled down from the large strike
+	test.Error(t, err)
+	test.T(t, img.Bounds(), image.Rect(0, 0, 30, 30))
+	test.T(t, color.RGBAModel.Convert(img.At(15, 15)), blue)
+
+	img, err = sfnt.BitmapGlyph(2, 80) // scaled up from the largest strike
+	test.Error(t, err)
+	test.T(t, img.Bounds(), image.Rect(0, 0, 80, 80))
+	test.T(t, color.RGBAModel.Convert(img.At(40, 40)), blue)
+
+	img, err = sfnt.BitmapGlyph(3, 40) // only in the small strike
+	test.Error(t, err)
+	test.T(t, img.Bounds(), image.Rect(0, 0, 40, 40))
+	test.T(t, color.RGBAModel.Convert(img.At(20, 20)), red)
+
+	_, err = sfnt.BitmapGlyph(0, 20)
+	test.T(t, err, ErrNoBitmap)
+	_, err = sfnt.BitmapGlyph(4, 20)
+	test.T(t, err, ErrNoBitmap)
+	_, err = (&SFNT{}).BitmapGlyph(1, 20)
+	test.T(t, err, ErrNoBitmap)
+
+	sfnt.tables["sbix"] = sbix[:len(sbix)-len(large)+8] // truncated strike
+	_, err = sfnt.BitmapGlyph(1, 40)
+	test.T(t, err, ErrInvalidFontData)
+}
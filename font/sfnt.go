@@ -1,6 +1,14 @@
 package font
 
 import (
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
 	"golang.org/x/image/font/sfnt"
 )
 
@@ -8,3 +16,786 @@ func ParseSFNT(b []byte) (*Font, error) {
 	font, err := sfnt.Parse(b)
 	return (*Font)(font), err
 }
+
+// SFNT gives access to the raw tables of an SFNT font (TTF or OTF).
+type SFNT struct {
+	tables map[string][]byte
+}
+
+// NewSFNT reads the table directory of the SFNT font format (TTF or OTF). Use ToSFNT to convert other font formats first.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/otff
+func NewSFNT(b []byte) (*SFNT, error) {
+	if len(b) < 12 {
+		return nil, ErrInvalidFontData
+	}
+
+	r := newBinaryReader(b)
+	sfntVersion := r.ReadString(4)
+	if sfntVersion == "ttcf" {
+		return nil, fmt.Errorf("collections are unsupported")
+	} else if sfntVersion != "true" && sfntVersion != "OTTO" && sfntVersion != "\x00\x01\x00\x00" {
+		return nil, fmt.Errorf("bad sfntVersion")
+	}
+	numTables := r.ReadUint16()
+	_ = r.ReadUint16() // searchRange
+	_ = r.ReadUint16() // entrySelector
+	_ = r.ReadUint16() // rangeShift
+	if uint32(len(b)) < 12+16*uint32(numTables) {
+		return nil, ErrInvalidFontData
+	}
+
+	tables := make(map[string][]byte, numTables)
+	for i := 0; i < int(numTables); i++ {
+		tag := r.ReadString(4)
+		_ = r.ReadUint32() // checksum
+		offset := r.ReadUint32()
+		length := r.ReadUint32()
+		if uint32(len(b)) < offset || uint32(len(b))-offset < length {
+			return nil, ErrInvalidFontData
+		} else if _, ok := tables[tag]; ok {
+			return nil, fmt.Errorf("%s: table defined more than once", tag)
+		}
+		tables[tag] = b[offset : offset+length : offset+length]
+	}
+	return &SFNT{
+		tables: tables,
+	}, nil
+}
+
+// Table returns the raw bytes of the table with the given 4-character tag, such as "head" or "GPOS", and whether it exists. The returned bytes must not be modified.
+func (sfnt *SFNT) Table(tag string) ([]byte, bool) {
+	table, ok := sfnt.tables[tag]
+	return table, ok
+}
+
+// TableTags returns the tags of all tables in the font in sorted order.
+func (sfnt *SFNT) TableTags() []string {
+	tags := make([]string, 0, len(sfnt.tables))
+	for tag := range sfnt.tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Name returns the string with the given name ID from the name table, such as 1 for the font family or 6 for the PostScript name, see https://docs.microsoft.com/en-us/typography/opentype/spec/name#name-ids. When there are multiple entries, it returns the entry with a language that matches the first of the preferred languages given as BCP 47 language tags (such as "en-US" or "nl"), falling back to English. Windows platform entries are preferred over Unicode and Macintosh platform entries. It returns an empty string if the name is not found.
+func (sfnt *SFNT) Name(nameID uint16, preferredLangs ...string) string {
+	table, ok := sfnt.tables["name"]
+	if !ok || len(table) < 6 {
+		return ""
+	}
+
+	r := newBinaryReader(table)
+	format := r.ReadUint16()
+	count := r.ReadUint16()
+	storageOffset := uint32(r.ReadUint16())
+	if r.Len() < 12*uint32(count) {
+		return ""
+	}
+
+	var langTags []string
+	if format == 1 {
+		r.Seek(6 + 12*uint32(count))
+		langTagCount := r.ReadUint16()
+		for i := 0; i < int(langTagCount) && !r.EOF(); i++ {
+			length := uint32(r.ReadUint16())
+			offset := uint32(r.ReadUint16())
+			langTags = append(langTags, sfnt.nameString(table, 0, storageOffset+offset, length))
+		}
+	}
+
+	preferredLangs = append(preferredLangs, "en")
+	name := ""
+	bestLang, bestPlatform := 2*len(preferredLangs)+1, 3
+	for i := 0; i < int(count); i++ {
+		r.Seek(6 + 12*uint32(i))
+		platformID := r.ReadUint16()
+		encodingID := r.ReadUint16()
+		languageID := r.ReadUint16()
+		id := r.ReadUint16()
+		length := uint32(r.ReadUint16())
+		offset := uint32(r.ReadUint16())
+		if id != nameID {
+			continue
+		}
+
+		platform := 0 // order of preference
+		lang := ""
+		switch platformID {
+		case 0: // Unicode
+			platform = 1
+			if 0x8000 <= languageID && int(languageID-0x8000) < len(langTags) {
+				lang = langTags[languageID-0x8000]
+			}
+		case 1: // Macintosh
+			if encodingID != 0 {
+				continue // only Roman is supported
+			}
+			platform = 2
+			lang = macLanguages[languageID]
+			if 0x8000 <= languageID && int(languageID-0x8000) < len(langTags) {
+				lang = langTags[languageID-0x8000]
+			}
+		case 3: // Windows
+			if encodingID != 0 && encodingID != 1 && encodingID != 10 {
+				continue // only Unicode is supported
+			}
+			lang = windowsLanguages[languageID]
+			if 0x8000 <= languageID && int(languageID-0x8000) < len(langTags) {
+				lang = langTags[languageID-0x8000]
+			}
+		default:
+			continue
+		}
+
+		langRank := 2 * len(preferredLangs)
+		for j, preferredLang := range preferredLangs {
+			if match := matchLanguage(lang, preferredLang); match != 0 {
+				langRank = 2*j + match - 1
+				break
+			}
+		}
+		if langRank < bestLang || langRank == bestLang && platform < bestPlatform {
+			if s := sfnt.nameString(table, platformID, storageOffset+offset, length); s != "" {
+				name = s
+				bestLang, bestPlatform = langRank, platform
+			}
+		}
+	}
+	return name
+}
+
+// FamilyName returns the typographic family name of the font, or the font family name if it does not exist.
+func (sfnt *SFNT) FamilyName() string {
+	if name := sfnt.Name(16); name != "" {
+		return name
+	}
+	return sfnt.Name(1)
+}
+
+// SubfamilyName returns the typographic subfamily name of the font (the style, such as "Bold Italic"), or the font subfamily name if it does not exist.
+func (sfnt *SFNT) SubfamilyName() string {
+	if name := sfnt.Name(17); name != "" {
+		return name
+	}
+	return sfnt.Name(2)
+}
+
+// PostScriptName returns the PostScript name of the font.
+func (sfnt *SFNT) PostScriptName() string {
+	return sfnt.Name(6)
+}
+
+// Weight returns the weight class of the font from the OS/2 table, ranging from 100 (thin) to 900 (black) where 400 is regular. Without an OS/2 table it uses the bold flag of the head table, returning either 400 or 700.
+func (sfnt *SFNT) Weight() int {
+	if os2, ok := sfnt.Table("OS/2"); ok && 6 <= len(os2) {
+		if weight := int(binary.BigEndian.Uint16(os2[4:])); weight != 0 {
+			return weight
+		}
+	}
+	if head, ok := sfnt.Table("head"); ok && 46 <= len(head) && binary.BigEndian.Uint16(head[44:])&0x0001 != 0 {
+		return 700
+	}
+	return 400
+}
+
+// Italic returns true if the font is italic or oblique, as indicated by the OS/2 table or otherwise the head table.
+func (sfnt *SFNT) Italic() bool {
+	if os2, ok := sfnt.Table("OS/2"); ok && 64 <= len(os2) {
+		return binary.BigEndian.Uint16(os2[62:])&0x0201 != 0 // ITALIC or OBLIQUE
+	}
+	if head, ok := sfnt.Table("head"); ok && 46 <= len(head) {
+		return binary.BigEndian.Uint16(head[44:])&0x0002 != 0
+	}
+	return false
+}
+
+// Advance returns the advance width of a glyph in font units from the hmtx table, or zero if the font has no horizontal metrics. Glyphs beyond the number of horizontal metrics, as given by the hhea table, have the advance of the last metric.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/hmtx
+func (sfnt *SFNT) Advance(glyphID uint16) uint16 {
+	hhea, ok := sfnt.Table("hhea")
+	if !ok || len(hhea) < 36 {
+		return 0
+	}
+	hmtx, ok := sfnt.Table("hmtx")
+	if !ok {
+		return 0
+	}
+
+	numberOfHMetrics := binary.BigEndian.Uint16(hhea[34:])
+	if numberOfHMetrics == 0 {
+		return 0
+	} else if numberOfHMetrics <= glyphID {
+		glyphID = numberOfHMetrics - 1
+	}
+	if len(hmtx) < 4*int(glyphID)+2 {
+		return 0
+	}
+	return binary.BigEndian.Uint16(hmtx[4*int(glyphID):])
+}
+
+// ColorLayer is a layer of a color glyph, which is the outline of GlyphID filled with the color at PaletteIndex of the selected palette. PaletteIndex is 0xFFFF for the foreground color of the text.
+type ColorLayer struct {
+	GlyphID      uint16
+	PaletteIndex uint16
+}
+
+// ColorLayers returns the layers of a color glyph from the COLR table (version 0) ordered from bottom to top, or nil if the glyph is not a color glyph.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/colr
+func (sfnt *SFNT) ColorLayers(glyphID uint16) []ColorLayer {
+	colr, ok := sfnt.Table("COLR")
+	if !ok || len(colr) < 14 {
+		return nil
+	}
+
+	r := newBinaryReader(colr)
+	_ = r.ReadUint16() // version
+	numBaseGlyphRecords := uint32(r.ReadUint16())
+	baseGlyphRecordsOffset := r.ReadUint32()
+	layerRecordsOffset := r.ReadUint32()
+	numLayerRecords := uint32(r.ReadUint16())
+	n := uint32(len(colr))
+	if n < baseGlyphRecordsOffset || (n-baseGlyphRecordsOffset)/6 < numBaseGlyphRecords || n < layerRecordsOffset || (n-layerRecordsOffset)/4 < numLayerRecords {
+		return nil
+	}
+
+	// base glyph records are sorted by glyph ID
+	i := sort.Search(int(numBaseGlyphRecords), func(i int) bool {
+		return glyphID <= binary.BigEndian.Uint16(colr[baseGlyphRecordsOffset+6*uint32(i):])
+	})
+	if i == int(numBaseGlyphRecords) {
+		return nil
+	}
+	r.Seek(baseGlyphRecordsOffset + 6*uint32(i))
+	if r.ReadUint16() != glyphID {
+		return nil
+	}
+	firstLayerIndex := uint32(r.ReadUint16())
+	numLayers := uint32(r.ReadUint16())
+	if numLayerRecords < firstLayerIndex || numLayerRecords-firstLayerIndex < numLayers {
+		return nil
+	}
+
+	layers := make([]ColorLayer, numLayers)
+	r.Seek(layerRecordsOffset + 4*firstLayerIndex)
+	for j := range layers {
+		layers[j].GlyphID = r.ReadUint16()
+		layers[j].PaletteIndex = r.ReadUint16()
+	}
+	return layers
+}
+
+// Palettes returns the color palettes of the CPAL table, where each palette has the same number of colors. It returns nil if the font has no CPAL table.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/cpal
+func (sfnt *SFNT) Palettes() [][]color.NRGBA {
+	cpal, ok := sfnt.Table("CPAL")
+	if !ok || len(cpal) < 12 {
+		return nil
+	}
+
+	r := newBinaryReader(cpal)
+	_ = r.ReadUint16() // version
+	numPaletteEntries := uint32(r.ReadUint16())
+	numPalettes := uint32(r.ReadUint16())
+	numColorRecords := uint32(r.ReadUint16())
+	colorRecordsArrayOffset := r.ReadUint32()
+	n := uint32(len(cpal))
+	if (n-12)/2 < numPalettes || n < colorRecordsArrayOffset || (n-colorRecordsArrayOffset)/4 < numColorRecords {
+		return nil
+	}
+
+	palettes := make([][]color.NRGBA, numPalettes)
+	for i := range palettes {
+		r.Seek(12 + 2*uint32(i))
+		colorRecordIndex := uint32(r.ReadUint16())
+		if numColorRecords < colorRecordIndex || numColorRecords-colorRecordIndex < numPaletteEntries {
+			return nil
+		}
+		palettes[i] = make([]color.NRGBA, numPaletteEntries)
+		for j := range palettes[i] {
+			b := cpal[colorRecordsArrayOffset+4*(colorRecordIndex+uint32(j)):]
+			palettes[i][j] = color.NRGBA{b[2], b[1], b[0], b[3]} // stored as BGRA
+		}
+	}
+	return palettes
+}
+
+// GaspBehavior are the flags of the gasp table that tell whether to grid-fit and antialias glyphs for a range of sizes.
+type GaspBehavior uint16
+
+// see GaspBehavior
+const (
+	GaspGridFit            GaspBehavior = 0x0001 // grid-fit by hinting
+	GaspDoGray             GaspBehavior = 0x0002 // antialias
+	GaspSymmetricGridFit   GaspBehavior = 0x0004 // grid-fit for ClearType, version 1 only
+	GaspSymmetricSmoothing GaspBehavior = 0x0008 // smooth along both axes for ClearType, version 1 only
+)
+
+// Gasp returns the rendering behavior in the gasp table for the given size in pixels per em, where the ranges are given by their largest size. Fonts without gasp table, or with an invalid table, are antialiased and not grid-fitted.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/gasp
+func (sfnt *SFNT) Gasp(ppem uint16) GaspBehavior {
+	gasp, ok := sfnt.Table("gasp")
+	if !ok || len(gasp) < 4 {
+		return GaspDoGray | GaspSymmetricSmoothing
+	}
+
+	r := newBinaryReader(gasp)
+	version := r.ReadUint16()
+	numRanges := r.ReadUint16()
+	if 1 < version || uint32(len(gasp)-4)/4 < uint32(numRanges) {
+		return GaspDoGray | GaspSymmetricSmoothing
+	}
+	mask := GaspGridFit | GaspDoGray
+	if version == 1 {
+		mask |= GaspSymmetricGridFit | GaspSymmetricSmoothing
+	}
+	for i := uint16(0); i < numRanges; i++ {
+		rangeMaxPPEM := r.ReadUint16()
+		behavior := GaspBehavior(r.ReadUint16())
+		if ppem <= rangeMaxPPEM {
+			return behavior & mask
+		}
+	}
+	return 0 // sizes beyond the last range, which should be 0xFFFF
+}
+
+// BaseScript holds the baselines of a script from the BASE table. Coords are the positions of the baselines above the alphabetic baseline in font units, by baseline tag such as "romn", "hang" or "ideo".
+type BaseScript struct {
+	DefaultBaseline string
+	Coords          map[string]int16
+}
+
+// BaseScripts returns the baselines of the horizontal axis of the BASE table by script tag, such as "latn" or "deva". It returns nil if the font has no BASE table.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/base
+func (sfnt *SFNT) BaseScripts() map[string]BaseScript {
+	base, ok := sfnt.Table("BASE")
+	if !ok || len(base) < 8 {
+		return nil
+	}
+
+	r := newBinaryReader(base)
+	_ = r.ReadUint32() // version
+	horizAxisOffset := uint32(r.ReadUint16())
+	if horizAxisOffset == 0 {
+		return nil
+	}
+
+	r.Seek(horizAxisOffset)
+	baseTagListOffset := uint32(r.ReadUint16())
+	baseScriptListOffset := uint32(r.ReadUint16())
+	if r.EOF() || baseTagListOffset == 0 || baseScriptListOffset == 0 {
+		return nil
+	}
+
+	r.Seek(horizAxisOffset + baseTagListOffset)
+	tags := make([]string, r.ReadUint16())
+	for i := range tags {
+		tags[i] = r.ReadString(4)
+	}
+	if r.EOF() {
+		return nil
+	}
+
+	list := horizAxisOffset + baseScriptListOffset
+	r.Seek(list)
+	numScripts := r.ReadUint16()
+	if r.EOF() {
+		return nil
+	}
+	scripts := make(map[string]BaseScript, numScripts)
+	for i := 0; i < int(numScripts); i++ {
+		r.Seek(list + 2 + 6*uint32(i))
+		scriptTag := r.ReadString(4)
+		script := list + uint32(r.ReadUint16())
+		r.Seek(script)
+		baseValuesOffset := uint32(r.ReadUint16())
+		if r.EOF() || baseValuesOffset == 0 {
+			continue
+		}
+
+		values := script + baseValuesOffset
+		r.Seek(values)
+		defaultIndex := r.ReadUint16()
+		numCoords := r.ReadUint16()
+		if r.EOF() || int(numCoords) != len(tags) {
+			return nil
+		}
+		baseScript := BaseScript{
+			Coords: make(map[string]int16, numCoords),
+		}
+		if int(defaultIndex) < len(tags) {
+			baseScript.DefaultBaseline = tags[defaultIndex]
+		}
+		for j := 0; j < int(numCoords); j++ {
+			r.Seek(values + 4 + 2*uint32(j))
+			r.Seek(values + uint32(r.ReadUint16()))
+			_ = r.ReadUint16() // format, all formats start with the coordinate
+			coord := r.ReadInt16()
+			if r.EOF() {
+				return nil
+			}
+			baseScript.Coords[tags[j]] = coord
+		}
+		scripts[scriptTag] = baseScript
+	}
+	return scripts
+}
+
+// FeatureInfo is an OpenType feature of the GSUB or GPOS tables for a script and language system. Language is "dflt" for the default language system of the script. Name is the user interface name of stylistic sets (ssNN) and character variants (cvNN) from the name table, or empty if the font does not define it. Substitution and Positioning indicate whether the feature is in the GSUB table, the GPOS table, or both.
+type FeatureInfo struct {
+	Tag, Script, Language string
+	Name                  string
+	Substitution          bool
+	Positioning           bool
+}
+
+// Features returns the features of the GSUB and GPOS tables for each script and language system, sorted by tag, script and language. Features that are in both tables are returned once.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/chapter2
+func (sfnt *SFNT) Features() []FeatureInfo {
+	features := map[[3]string]*FeatureInfo{}
+	for _, tableTag := range []string{"GSUB", "GPOS"} {
+		table, ok := sfnt.Table(tableTag)
+		if !ok || len(table) < 10 {
+			continue
+		}
+
+		r := newBinaryReader(table)
+		_ = r.ReadUint32() // version
+		scriptList := uint32(r.ReadUint16())
+		featureList := uint32(r.ReadUint16())
+
+		// tags and names of the features by index
+		r.Seek(featureList)
+		tags := make([]string, r.ReadUint16())
+		names := make([]string, len(tags))
+		for i := range tags {
+			r.Seek(featureList + 2 + 6*uint32(i))
+			tags[i] = r.ReadString(4)
+			feature := featureList + uint32(r.ReadUint16())
+			if (strings.HasPrefix(tags[i], "ss") || strings.HasPrefix(tags[i], "cv")) && !r.EOF() {
+				r.Seek(feature)
+				if featureParamsOffset := uint32(r.ReadUint16()); featureParamsOffset != 0 {
+					// both the stylistic set and character variant parameters have the name ID after the version or format
+					r.Seek(feature + featureParamsOffset + 2)
+					if nameID := r.ReadUint16(); !r.EOF() {
+						names[i] = sfnt.Name(nameID)
+					}
+				}
+			}
+		}
+		if r.EOF() {
+			continue
+		}
+
+		r.Seek(scriptList)
+		numScripts := r.ReadUint16()
+		for i := 0; i < int(numScripts) && !r.EOF(); i++ {
+			r.Seek(scriptList + 2 + 6*uint32(i))
+			scriptTag := r.ReadString(4)
+			script := scriptList + uint32(r.ReadUint16())
+
+			r.Seek(script)
+			langSyses := map[string]uint32{}
+			if defaultLangSysOffset := uint32(r.ReadUint16()); defaultLangSysOffset != 0 {
+				langSyses["dflt"] = script + defaultLangSysOffset
+			}
+			numLangSyses := r.ReadUint16()
+			for j := 0; j < int(numLangSyses); j++ {
+				langSysTag := r.ReadString(4)
+				langSyses[langSysTag] = script + uint32(r.ReadUint16())
+			}
+
+			for langSysTag, langSys := range langSyses {
+				r.Seek(langSys + 2) // skip lookupOrderOffset
+				indices := []uint16{r.ReadUint16()}
+				numIndices := r.ReadUint16()
+				for k := 0; k < int(numIndices); k++ {
+					indices = append(indices, r.ReadUint16())
+				}
+				if r.EOF() {
+					break
+				}
+				for _, index := range indices {
+					if int(index) < len(tags) { // the required feature index is 0xFFFF if there is none
+						key := [3]string{tags[index], scriptTag, langSysTag}
+						info, ok := features[key]
+						if !ok {
+							info = &FeatureInfo{Tag: tags[index], Script: scriptTag, Language: langSysTag}
+							features[key] = info
+						}
+						if info.Name == "" {
+							info.Name = names[index]
+						}
+						if tableTag == "GSUB" {
+							info.Substitution = true
+						} else {
+							info.Positioning = true
+						}
+					}
+				}
+			}
+		}
+	}
+
+	infos := make([]FeatureInfo, 0, len(features))
+	for _, info := range features {
+		infos = append(infos, *info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Tag != infos[j].Tag {
+			return infos[i].Tag < infos[j].Tag
+		} else if infos[i].Script != infos[j].Script {
+			return infos[i].Script < infos[j].Script
+		}
+		return infos[i].Language < infos[j].Language
+	})
+	return infos
+}
+
+// VariationAxis is a design axis of a variable font from the fvar table, such as "wght" for the weight. Name is the user interface name of the axis from the name table.
+type VariationAxis struct {
+	Tag               string
+	Name              string
+	Min, Default, Max float64
+	Hidden            bool
+}
+
+// NamedInstance is a predefined style of a variable font from the fvar table, such as "Bold Condensed". Name is the subfamily name of the instance and PostScriptName its PostScript name from the name table, which is empty if the font does not define it. Coordinates are the positions on the design axes by axis tag.
+type NamedInstance struct {
+	Name           string
+	PostScriptName string
+	Coordinates    map[string]float64
+}
+
+// fvar parses the axis and instance records of the fvar table, returning the table reader and the offset and size of the instance records.
+func (sfnt *SFNT) fvar() ([]VariationAxis, *binaryReader, uint32, uint16, uint16) {
+	fvar, ok := sfnt.Table("fvar")
+	if !ok || len(fvar) < 16 {
+		return nil, nil, 0, 0, 0
+	}
+
+	r := newBinaryReader(fvar)
+	majorVersion := r.ReadUint16()
+	_ = r.ReadUint16() // minorVersion
+	axesArrayOffset := uint32(r.ReadUint16())
+	_ = r.ReadUint16() // reserved
+	axisCount := r.ReadUint16()
+	axisSize := r.ReadUint16()
+	instanceCount := r.ReadUint16()
+	instanceSize := r.ReadUint16()
+	if majorVersion != 1 || axisSize < 20 || instanceSize == 0 || uint32(instanceSize) < 4+4*uint32(axisCount) {
+		return nil, nil, 0, 0, 0
+	}
+
+	instancesOffset := axesArrayOffset + uint32(axisCount)*uint32(axisSize)
+	if uint32(len(fvar)) < instancesOffset || (uint32(len(fvar))-instancesOffset)/uint32(instanceSize) < uint32(instanceCount) {
+		return nil, nil, 0, 0, 0
+	}
+
+	axes := make([]VariationAxis, axisCount)
+	for i := range axes {
+		r.Seek(axesArrayOffset + uint32(i)*uint32(axisSize))
+		axes[i].Tag = r.ReadString(4)
+		axes[i].Min = fixedToFloat(r.ReadUint32())
+		axes[i].Default = fixedToFloat(r.ReadUint32())
+		axes[i].Max = fixedToFloat(r.ReadUint32())
+		axes[i].Hidden = r.ReadUint16()&0x0001 != 0
+		axes[i].Name = sfnt.Name(r.ReadUint16())
+	}
+	return axes, r, instancesOffset, instanceCount, instanceSize
+}
+
+// VariationAxes returns the design axes of a variable font in the order of the fvar table. It returns nil if the font is not a variable font.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/fvar
+func (sfnt *SFNT) VariationAxes() []VariationAxis {
+	axes, _, _, _, _ := sfnt.fvar()
+	return axes
+}
+
+// NamedInstances returns the named instances of a variable font in the order of the fvar table, such as to list the styles of the font in a style picker. It returns nil if the font is not a variable font.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/fvar
+func (sfnt *SFNT) NamedInstances() []NamedInstance {
+	axes, r, offset, count, size := sfnt.fvar()
+	if axes == nil {
+		return nil
+	}
+
+	instances := make([]NamedInstance, count)
+	for i := range instances {
+		r.Seek(offset + uint32(i)*uint32(size))
+		subfamilyNameID := r.ReadUint16()
+		_ = r.ReadUint16() // flags
+		instances[i].Name = sfnt.Name(subfamilyNameID)
+		instances[i].Coordinates = make(map[string]float64, len(axes))
+		for _, axis := range axes {
+			instances[i].Coordinates[axis.Tag] = fixedToFloat(r.ReadUint32())
+		}
+		if 6+4*uint32(len(axes)) <= uint32(size) {
+			// the PostScript name ID is optional, where 0xFFFF means it is not defined
+			if postScriptNameID := r.ReadUint16(); postScriptNameID != 0xFFFF {
+				instances[i].PostScriptName = sfnt.Name(postScriptNameID)
+			}
+		}
+	}
+	return instances
+}
+
+// NormalizedCoordinates returns the normalized coordinates in [-1,1] of a variable font for the positions on the design axes by axis tag, in the order of the fvar table, as used to interpolate the glyph variation deltas of the gvar table. Positions are clamped to the range of the axis and mapped linearly to -1 at the minimum, 0 at the default and 1 at the maximum, where missing axes are at the default. If the font has an avar table, its segment maps then remap the coordinates of each axis non-linearly, so that intermediate positions such as a medium weight are at the design intended by the type designer. It returns nil if the font is not a variable font. Note that glyph outlines are not varied since the gvar table is not supported.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/otvaroverview#coordinate-scales-and-normalization
+func (sfnt *SFNT) NormalizedCoordinates(coords map[string]float64) []float64 {
+	axes, _, _, _, _ := sfnt.fvar()
+	if axes == nil {
+		return nil
+	}
+
+	normalized := make([]float64, len(axes))
+	for i, axis := range axes {
+		v, ok := coords[axis.Tag]
+		if !ok {
+			continue
+		}
+		v = math.Max(axis.Min, math.Min(axis.Max, v))
+		if v < axis.Default && axis.Min < axis.Default {
+			normalized[i] = (v - axis.Default) / (axis.Default - axis.Min)
+		} else if axis.Default < v && axis.Default < axis.Max {
+			normalized[i] = (v - axis.Default) / (axis.Max - axis.Default)
+		}
+	}
+
+	if segmentMaps := sfnt.avar(len(axes)); segmentMaps != nil {
+		for i, segmentMap := range segmentMaps {
+			normalized[i] = segmentMap.apply(normalized[i])
+		}
+	}
+	return normalized
+}
+
+// avarSegmentMap maps normalized coordinates of an axis piecewise linearly, with fromCoordinates in increasing order.
+type avarSegmentMap struct {
+	from, to []float64
+}
+
+func (segmentMap avarSegmentMap) apply(v float64) float64 {
+	from, to := segmentMap.from, segmentMap.to
+	if len(from) == 0 {
+		return v
+	} else if v <= from[0] {
+		return v + to[0] - from[0]
+	}
+	for k := 1; k < len(from); k++ {
+		if v < from[k] {
+			return to[k-1] + (to[k]-to[k-1])*(v-from[k-1])/(from[k]-from[k-1])
+		}
+	}
+	return v + to[len(to)-1] - from[len(from)-1]
+}
+
+// avar parses the segment maps of the avar table for each of the axes of the fvar table. It returns nil if the font has no (valid) avar table, in which case normalization is linear. Segment maps of which the coordinates are not in increasing order are ignored.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/avar
+func (sfnt *SFNT) avar(axisCount int) []avarSegmentMap {
+	avar, ok := sfnt.Table("avar")
+	if !ok || len(avar) < 8 {
+		return nil
+	}
+
+	r := newBinaryReader(avar)
+	majorVersion := r.ReadUint16()
+	_ = r.ReadUint16() // minorVersion
+	_ = r.ReadUint16() // reserved
+	if majorVersion != 1 || int(r.ReadUint16()) != axisCount {
+		return nil
+	}
+
+	segmentMaps := make([]avarSegmentMap, axisCount)
+	for i := range segmentMaps {
+		positionMapCount := r.ReadUint16()
+		from := make([]float64, positionMapCount)
+		to := make([]float64, positionMapCount)
+		for j := 0; j < int(positionMapCount); j++ {
+			from[j] = float64(r.ReadInt16()) / 16384.0
+			to[j] = float64(r.ReadInt16()) / 16384.0
+		}
+		if r.EOF() {
+			return nil
+		}
+		increasing := true
+		for j := 1; j < len(from); j++ {
+			if from[j] <= from[j-1] {
+				increasing = false
+			}
+		}
+		if increasing {
+			segmentMaps[i] = avarSegmentMap{from, to}
+		}
+	}
+	return segmentMaps
+}
+
+// fixedToFloat converts a signed 16.16 fixed-point number.
+func fixedToFloat(v uint32) float64 {
+	return float64(int32(v)) / 65536.0
+}
+
+// nameString decodes a string of the name table, which is UTF-16BE for the Unicode and Windows platforms and Mac OS Roman for the Macintosh platform.
+func (sfnt *SFNT) nameString(table []byte, platformID uint16, offset, length uint32) string {
+	if uint32(len(table)) < offset || uint32(len(table))-offset < length {
+		return ""
+	}
+	b := table[offset : offset+length]
+	if platformID == 1 {
+		rs := make([]rune, len(b))
+		for i, c := range b {
+			if c < 0x80 {
+				rs[i] = rune(c)
+			} else {
+				rs[i] = macRoman[c-0x80]
+			}
+		}
+		return string(rs)
+	}
+
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.BigEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// matchLanguage returns 1 if the BCP 47 language tags are equal or if one is the primary language of the other (such as "en" and "en-US"), 2 if they have the same primary language (such as "en-US" and "en-GB"), and 0 otherwise.
+func matchLanguage(lang, preferred string) int {
+	if lang == "" {
+		return 0
+	}
+	lang, preferred = strings.ToLower(lang), strings.ToLower(preferred)
+	if lang == preferred || strings.HasPrefix(lang, preferred+"-") || strings.HasPrefix(preferred, lang+"-") {
+		return 1
+	} else if i, j := strings.IndexByte(lang, '-'), strings.IndexByte(preferred, '-'); 0 < i && 0 < j && lang[:i] == preferred[:j] {
+		return 2
+	}
+	return 0
+}
+
+// macRoman maps the upper half of the Mac OS Roman encoding to Unicode.
+var macRoman = []rune("ÄÅÇÉÑÖÜáàâäãåçéèêëíìîïñóòôöõúùûü†°¢£§•¶ß®©™´¨≠ÆØ∞±≤≥¥µ∂∑∏π∫ªºΩæø¿¡¬√ƒ≈∆«»…\u00A0ÀÃÕŒœ–—“”‘’÷◊ÿŸ⁄€‹›ﬁﬂ‡·‚„‰ÂÊÁËÈÍÎÏÌÓÔ\uF8FFÒÚÛÙıˆ˜¯˘˙˚¸˝˛ˇ")
+
+// macLanguages maps the language IDs of the Macintosh platform to BCP 47 language tags.
+var macLanguages = map[uint16]string{
+	0: "en", 1: "fr", 2: "de", 3: "it", 4: "nl", 5: "sv", 6: "es", 7: "da", 8: "pt", 9: "no",
+	10: "he", 11: "ja", 12: "ar", 13: "fi", 14: "el", 15: "is", 16: "mt", 17: "tr", 18: "hr", 19: "zh-Hant",
+	20: "ur", 21: "hi", 22: "th", 23: "ko", 24: "lt", 25: "pl", 26: "hu", 27: "et", 28: "lv", 30: "fo",
+	31: "fa", 32: "ru", 33: "zh-Hans", 34: "nl-BE", 35: "ga", 36: "sq", 37: "ro", 38: "cs", 39: "sk",
+	40: "sl", 41: "yi", 42: "sr", 43: "mk", 44: "bg", 45: "uk", 46: "be", 47: "uz", 48: "kk",
+}
+
+// windowsLanguages maps common language IDs of the Windows platform to BCP 47 language tags.
+var windowsLanguages = map[uint16]string{
+	0x0401: "ar-SA", 0x0402: "bg-BG", 0x0403: "ca-ES", 0x0404: "zh-TW", 0x0405: "cs-CZ", 0x0406: "da-DK",
+	0x0407: "de-DE", 0x0408: "el-GR", 0x0409: "en-US", 0x040A: "es-ES", 0x040B: "fi-FI", 0x040C: "fr-FR",
+	0x040D: "he-IL", 0x040E: "hu-HU", 0x040F: "is-IS", 0x0410: "it-IT", 0x0411: "ja-JP", 0x0412: "ko-KR",
+	0x0413: "nl-NL", 0x0414: "nb-NO", 0x0415: "pl-PL", 0x0416: "pt-BR", 0x0418: "ro-RO", 0x0419: "ru-RU",
+	0x041A: "hr-HR", 0x041B: "sk-SK", 0x041D: "sv-SE", 0x041E: "th-TH", 0x041F: "tr-TR", 0x0422: "uk-UA",
+	0x0424: "sl-SI", 0x0425: "et-EE", 0x0426: "lv-LV", 0x0427: "lt-LT", 0x042A: "vi-VN", 0x0439: "hi-IN",
+	0x0804: "zh-CN", 0x0807: "de-CH", 0x0809: "en-GB", 0x080A: "es-MX", 0x080C: "fr-BE", 0x0813: "nl-BE",
+	0x0816: "pt-PT", 0x0C04: "zh-HK", 0x0C07: "de-AT", 0x0C09: "en-AU", 0x0C0A: "es-ES", 0x0C0C: "fr-CA",
+	0x1004: "zh-SG", 0x1009: "en-CA", 0x100C: "fr-CH", 0x1409: "en-NZ", 0x1809: "en-IE",
+}
@@ -0,0 +1,481 @@
+package font
+
+import (
+	"encoding/binary"
+	"image/color"
+	"io/ioutil"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestSFNTTable(t *testing.T) {
+	for _, filename := range []string{"DejaVuSerif.ttf", "DejaVuSerif.woff", "EBGaramond12-Regular.otf"} {
+		t.Run(filename, func(t *testing.T) {
+			b, err := ioutil.ReadFile(filename)
+			test.Error(t, err)
+			b, err = ToSFNT(b)
+			test.Error(t, err)
+			sfnt, err := NewSFNT(b)
+			test.Error(t, err)
+
+			head, ok := sfnt.Table("head")
+			test.That(t, ok, "head table must exist")
+			test.T(t, len(head), 54)
+			test.T(t, binary.BigEndian.Uint32(head[12:]), uint32(0x5F0F3CF5)) // magicNumber
+
+			_, ok = sfnt.Table("zzzz")
+			test.That(t, !ok, "zzzz table must not exist")
+
+			tags := sfnt.TableTags()
+			test.That(t, 0 < len(tags))
+			for i, tag := range tags {
+				test.T(t, len(tag), 4)
+				_, ok := sfnt.Table(tag)
+				test.That(t, ok, tag, "table must exist")
+				if 0 < i {
+					test.That(t, tags[i-1] < tag, "tags must be sorted")
+				}
+			}
+		})
+	}
+}
+
+func TestSFNTError(t *testing.T) {
+	var tts = []struct {
+		data string
+		err  string
+	}{
+		{"\x00\x01\x00\x00", "invalid font data"},
+		{"ttcf\x00\x00\x00\x00\x00\x00\x00\x00", "collections are unsupported"},
+		{"wOFF\x00\x00\x00\x00\x00\x00\x00\x00", "bad sfntVersion"},
+		{"\x00\x01\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00", "invalid font data"},
+		{"\x00\x01\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00head\x00\x00\x00\x00\x00\x00\x00\x1C\x00\x00\x00\x01", "invalid font data"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.err, func(t *testing.T) {
+			_, err := NewSFNT([]byte(tt.data))
+			test.T(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestSFNTName(t *testing.T) {
+	var tts = []struct {
+		filename       string
+		family         string
+		subfamily      string
+		postScriptName string
+		weight         int
+	}{
+		{"DejaVuSerif.ttf", "DejaVu Serif", "Book", "DejaVuSerif", 400},
+		{"DejaVuSerif.woff", "DejaVu Serif", "Book", "DejaVuSerif", 400},
+		{"EBGaramond12-Regular.otf", "EB Garamond", "12 Regular", "EBGaramond12-Regular", 400},
+	}
+	for _, tt := range tts {
+		t.Run(tt.filename, func(t *testing.T) {
+			b, err := ioutil.ReadFile(tt.filename)
+			test.Error(t, err)
+			b, err = ToSFNT(b)
+			test.Error(t, err)
+			sfnt, err := NewSFNT(b)
+			test.Error(t, err)
+
+			test.T(t, sfnt.FamilyName(), tt.family)
+			test.T(t, sfnt.SubfamilyName(), tt.subfamily)
+			test.T(t, sfnt.PostScriptName(), tt.postScriptName)
+			test.T(t, sfnt.Name(1, "nl-NL"), sfnt.Name(1))
+			test.T(t, sfnt.Name(1000), "")
+			test.T(t, sfnt.Weight(), tt.weight)
+			test.That(t, !sfnt.Italic(), "must not be italic")
+		})
+	}
+}
+
+type nameRecord struct {
+	platformID, encodingID, languageID uint16
+	s                                  string
+}
+
+func nameTable(records []nameRecord) []byte {
+	w := newBinaryWriter([]byte{})
+	w.WriteUint16(0)
+	w.WriteUint16(uint16(len(records)))
+	w.WriteUint16(uint16(6 + 12*len(records)))
+	offset := 0
+	for _, record := range records {
+		w.WriteUint16(record.platformID)
+		w.WriteUint16(record.encodingID)
+		w.WriteUint16(record.languageID)
+		w.WriteUint16(1) // family name
+		w.WriteUint16(uint16(len(record.s)))
+		w.WriteUint16(uint16(offset))
+		offset += len(record.s)
+	}
+	for _, record := range records {
+		w.WriteString(record.s)
+	}
+	return w.Bytes()
+}
+
+func TestSFNTNameLanguages(t *testing.T) {
+	mac := nameRecord{1, 0, 0, "Caf\x8e"}
+	windows := nameRecord{3, 1, 0x0409, "\x00C\x00a\x00f\x00\xe9"}
+	windowsDutch := nameRecord{3, 1, 0x0413, "\x00K\x00o\x00f\x00f\x00i\x00e"}
+
+	sfnt := &SFNT{tables: map[string][]byte{"name": nameTable([]nameRecord{mac, windows, windowsDutch})}}
+	test.T(t, sfnt.Name(1), "Café")
+	test.T(t, sfnt.Name(1, "en-GB"), "Café")
+	test.T(t, sfnt.Name(1, "nl"), "Koffie")
+	test.T(t, sfnt.Name(1, "fr", "nl-BE"), "Koffie")
+
+	sfnt.tables["name"] = nameTable([]nameRecord{mac})
+	test.T(t, sfnt.Name(1), "Café")
+
+	sfnt.tables["name"] = nameTable([]nameRecord{mac, windows})[:6+12] // truncated records
+	test.T(t, sfnt.Name(1), "")
+}
+
+func TestSFNTColorLayers(t *testing.T) {
+	w := newBinaryWriter([]byte{})
+	w.WriteUint16(0)  // version
+	w.WriteUint16(2)  // numBaseGlyphRecords
+	w.WriteUint32(14) // baseGlyphRecordsOffset
+	w.WriteUint32(26) // layerRecordsOffset
+	w.WriteUint16(3)  // numLayerRecords
+	for _, v := range []uint16{5, 0, 2, 9, 2, 1} {
+		w.WriteUint16(v) // base glyph records
+	}
+	for _, v := range []uint16{20, 0, 21, 0xFFFF, 22, 1} {
+		w.WriteUint16(v) // layer records
+	}
+	colr := w.Bytes()
+
+	sfnt := &SFNT{tables: map[string][]byte{"COLR": colr}}
+	test.T(t, sfnt.ColorLayers(5), []ColorLayer{{20, 0}, {21, 0xFFFF}})
+	test.T(t, sfnt.ColorLayers(9), []ColorLayer{{22, 1}})
+	test.T(t, len(sfnt.ColorLayers(7)), 0)
+	test.T(t, len(sfnt.ColorLayers(10)), 0)
+
+	sfnt.tables["COLR"] = colr[:len(colr)-2] // truncated layer records
+	test.T(t, len(sfnt.ColorLayers(5)), 0)
+}
+
+func TestSFNTPalettes(t *testing.T) {
+	w := newBinaryWriter([]byte{})
+	w.WriteUint16(0)  // version
+	w.WriteUint16(2)  // numPaletteEntries
+	w.WriteUint16(2)  // numPalettes
+	w.WriteUint16(4)  // numColorRecords
+	w.WriteUint32(16) // colorRecordsArrayOffset
+	w.WriteUint16(0)  // colorRecordIndices
+	w.WriteUint16(2)
+	w.WriteBytes([]byte{0, 0, 255, 255, 255, 0, 0, 128}) // BGRA
+	w.WriteBytes([]byte{0, 255, 0, 255, 10, 20, 30, 40})
+	cpal := w.Bytes()
+
+	sfnt := &SFNT{tables: map[string][]byte{"CPAL": cpal}}
+	test.T(t, sfnt.Palettes(), [][]color.NRGBA{
+		{{255, 0, 0, 255}, {0, 0, 255, 128}},
+		{{0, 255, 0, 255}, {30, 20, 10, 40}},
+	})
+
+	sfnt.tables["CPAL"] = cpal[:len(cpal)-4] // truncated color records
+	test.T(t, len(sfnt.Palettes()), 0)
+	test.T(t, len((&SFNT{}).Palettes()), 0)
+}
+
+func TestSFNTGasp(t *testing.T) {
+	w := newBinaryWriter([]byte{})
+	w.WriteUint16(1) // version
+	w.WriteUint16(3) // numRanges
+	w.WriteUint16(8)
+	w.WriteUint16(uint16(GaspDoGray))
+	w.WriteUint16(16)
+	w.WriteUint16(uint16(GaspGridFit))
+	w.WriteUint16(0xFFFF)
+	w.WriteUint16(uint16(GaspGridFit | GaspDoGray | GaspSymmetricSmoothing))
+	gasp := w.Bytes()
+
+	sfnt := &SFNT{tables: map[string][]byte{"gasp": gasp}}
+	test.T(t, sfnt.Gasp(6), GaspDoGray)
+	test.T(t, sfnt.Gasp(8), GaspDoGray)
+	test.T(t, sfnt.Gasp(12), GaspGridFit)
+	test.T(t, sfnt.Gasp(100), GaspGridFit|GaspDoGray|GaspSymmetricSmoothing)
+
+	// version 0 has no ClearType flags
+	gasp[1] = 0
+	test.T(t, sfnt.Gasp(100), GaspGridFit|GaspDoGray)
+
+	sfnt.tables["gasp"] = gasp[:8] // truncated ranges
+	test.T(t, sfnt.Gasp(12), GaspDoGray|GaspSymmetricSmoothing)
+	test.T(t, (&SFNT{}).Gasp(12), GaspDoGray|GaspSymmetricSmoothing)
+}
+
+func TestSFNTBaseScripts(t *testing.T) {
+	w := newBinaryWriter([]byte{})
+	w.WriteUint32(0x00010000) // version
+	w.WriteUint16(8)          // horizAxisOffset
+	w.WriteUint16(0)          // vertAxisOffset
+	w.WriteUint16(4)          // baseTagListOffset
+	w.WriteUint16(14)         // baseScriptListOffset
+	w.WriteUint16(2)          // baseTagCount
+	w.WriteString("hang")
+	w.WriteString("romn")
+	w.WriteUint16(2) // baseScriptCount
+	w.WriteString("deva")
+	w.WriteUint16(14)
+	w.WriteString("latn")
+	w.WriteUint16(36)
+	for _, script := range [][2]int16{{0, 1600}, {1, 1500}} {
+		w.WriteUint16(6) // baseValuesOffset
+		w.WriteUint16(0) // defaultMinMaxOffset
+		w.WriteUint16(0) // baseLangSysCount
+		w.WriteUint16(uint16(script[0]))
+		w.WriteUint16(2) // baseCoordCount
+		w.WriteUint16(8)
+		w.WriteUint16(12)
+		w.WriteUint16(1) // format
+		w.WriteInt16(script[1])
+		w.WriteUint16(1) // format
+		w.WriteInt16(0)
+	}
+	base := w.Bytes()
+
+	sfnt := &SFNT{tables: map[string][]byte{"BASE": base}}
+	test.T(t, sfnt.BaseScripts(), map[string]BaseScript{
+		"deva": {"hang", map[string]int16{"hang": 1600, "romn": 0}},
+		"latn": {"romn", map[string]int16{"hang": 1500, "romn": 0}},
+	})
+
+	sfnt.tables["BASE"] = base[:len(base)-2] // truncated coordinate
+	test.T(t, len(sfnt.BaseScripts()), 0)
+	test.T(t, len((&SFNT{}).BaseScripts()), 0)
+}
+
+// fvarTable returns an fvar table with the wght and wdth axes and two named instances, of which the instance records have PostScript name IDs when instanceSize is 14.
+func fvarTable(instanceSize uint16) []byte {
+	w := newBinaryWriter([]byte{})
+	w.WriteUint16(1)
+	w.WriteUint16(0)
+	w.WriteUint16(16) // axesArrayOffset
+	w.WriteUint16(2)
+	w.WriteUint16(2)  // axisCount
+	w.WriteUint16(20) // axisSize
+	w.WriteUint16(2)  // instanceCount
+	w.WriteUint16(instanceSize)
+	for i, axis := range [][4]uint32{{100, 400, 900, 0}, {75, 100, 100, 1}} {
+		w.WriteString([]string{"wght", "wdth"}[i])
+		w.WriteUint32(axis[0] << 16)
+		w.WriteUint32(axis[1] << 16)
+		w.WriteUint32(axis[2] << 16)
+		w.WriteUint16(uint16(axis[3]))
+		w.WriteUint16(uint16(256 + i))
+	}
+	for i, coords := range [][2]uint32{{400 << 16, 100 << 16}, {700 << 16, 87<<16 + 1<<15}} {
+		w.WriteUint16(uint16(258 + i))
+		w.WriteUint16(0)
+		w.WriteUint32(coords[0])
+		w.WriteUint32(coords[1])
+		if instanceSize == 14 {
+			w.WriteUint16([]uint16{0xFFFF, 260}[i])
+		}
+	}
+	return w.Bytes()
+}
+
+func TestSFNTNamedInstances(t *testing.T) {
+	names := []string{"Weight", "Width", "Regular", "Bold Condensed", "Font-BoldCondensed"}
+	name := newBinaryWriter([]byte{})
+	name.WriteUint16(0)
+	name.WriteUint16(uint16(len(names)))
+	name.WriteUint16(uint16(6 + 12*len(names)))
+	offset := 0
+	for i, s := range names {
+		name.WriteUint16(1) // Macintosh
+		name.WriteUint16(0)
+		name.WriteUint16(0)
+		name.WriteUint16(uint16(256 + i))
+		name.WriteUint16(uint16(len(s)))
+		name.WriteUint16(uint16(offset))
+		offset += len(s)
+	}
+	for _, s := range names {
+		name.WriteString(s)
+	}
+
+	sfnt := &SFNT{tables: map[string][]byte{"fvar": fvarTable(14), "name": name.Bytes()}}
+	test.T(t, sfnt.VariationAxes(), []VariationAxis{
+		{"wght", "Weight", 100.0, 400.0, 900.0, false},
+		{"wdth", "Width", 75.0, 100.0, 100.0, true},
+	})
+	test.T(t, sfnt.NamedInstances(), []NamedInstance{
+		{"Regular", "", map[string]float64{"wght": 400.0, "wdth": 100.0}},
+		{"Bold Condensed", "Font-BoldCondensed", map[string]float64{"wght": 700.0, "wdth": 87.5}},
+	})
+
+	sfnt.tables["fvar"] = fvarTable(12) // without PostScript name IDs
+	test.T(t, sfnt.NamedInstances()[1].PostScriptName, "")
+
+	sfnt.tables["fvar"] = fvarTable(14)[:60] // truncated instance records
+	test.T(t, len(sfnt.NamedInstances()), 0)
+	test.T(t, len((&SFNT{}).NamedInstances()), 0)
+
+	// axis count for which the minimum instance size overflows
+	b := append(fvarTable(14), make([]byte, 20*0x3FFF)...)
+	binary.BigEndian.PutUint16(b[8:], 0x3FFF) // axisCount
+	binary.BigEndian.PutUint16(b[14:], 0)     // instanceSize
+	sfnt.tables["fvar"] = b
+	test.T(t, len(sfnt.NamedInstances()), 0)
+	test.T(t, len(sfnt.VariationAxes()), 0)
+}
+
+func TestSFNTNormalizedCoordinates(t *testing.T) {
+	sfnt := &SFNT{tables: map[string][]byte{"fvar": fvarTable(14)}}
+
+	// linear normalization without avar
+	test.T(t, sfnt.NormalizedCoordinates(map[string]float64{"wght": 650.0, "wdth": 87.5}), []float64{0.5, -0.5})
+	test.T(t, sfnt.NormalizedCoordinates(map[string]float64{"wght": 1000.0}), []float64{1.0, 0.0})
+	test.T(t, sfnt.NormalizedCoordinates(map[string]float64{"wght": 250.0, "wdth": 50.0}), []float64{-0.5, -1.0})
+	test.T(t, (&SFNT{}).NormalizedCoordinates(nil), []float64(nil))
+
+	avar := newBinaryWriter([]byte{})
+	avar.WriteUint16(1)
+	avar.WriteUint16(0)
+	avar.WriteUint16(0) // reserved
+	avar.WriteUint16(2) // axisCount
+	for _, segmentMap := range [][][2]int16{{{-16384, -16384}, {0, 0}, {8192, 11469}, {16384, 16384}}, {{-16384, -16384}, {0, 0}, {16384, 16384}}} {
+		avar.WriteUint16(uint16(len(segmentMap)))
+		for _, m := range segmentMap {
+			avar.WriteInt16(m[0])
+			avar.WriteInt16(m[1])
+		}
+	}
+	sfnt.tables["avar"] = avar.Bytes()
+	coords := sfnt.NormalizedCoordinates(map[string]float64{"wght": 650.0, "wdth": 87.5})
+	test.Float(t, coords[0], 11469.0/16384.0) // remapped medium weight
+	test.Float(t, coords[1], -0.5)
+	coords = sfnt.NormalizedCoordinates(map[string]float64{"wght": 775.0})
+	test.Float(t, coords[0], (11469.0/16384.0+1.0)/2.0)
+	test.T(t, sfnt.NormalizedCoordinates(map[string]float64{"wght": 250.0})[0], -0.5)
+
+	sfnt.tables["avar"] = avar.Bytes()[:20] // truncated segment maps
+	test.T(t, sfnt.NormalizedCoordinates(map[string]float64{"wght": 650.0})[0], 0.5)
+}
+
+func TestSFNTAdvance(t *testing.T) {
+	b, err := ioutil.ReadFile("DejaVuSerif.ttf")
+	test.Error(t, err)
+	sfnt, err := NewSFNT(b)
+	test.Error(t, err)
+	test.T(t, sfnt.Advance(0), uint16(1229)) // .notdef
+	test.T(t, sfnt.Advance(1), uint16(0))
+	test.T(t, sfnt.Advance(36), uint16(1479)) // A
+
+	// the last advance repeats for glyphs beyond numberOfHMetrics
+	b, err = ioutil.ReadFile("EBGaramond12-Regular.otf")
+	test.Error(t, err)
+	sfnt, err = NewSFNT(b)
+	test.Error(t, err)
+	hhea, _ := sfnt.Table("hhea")
+	numberOfHMetrics := binary.BigEndian.Uint16(hhea[34:])
+	test.T(t, numberOfHMetrics, uint16(3083))
+	test.T(t, sfnt.Advance(numberOfHMetrics-1), uint16(407))
+	test.T(t, sfnt.Advance(numberOfHMetrics), uint16(407))
+	test.T(t, sfnt.Advance(0xFFFF), uint16(407))
+
+	sfnt.tables = map[string][]byte{}
+	test.T(t, sfnt.Advance(36), uint16(0))
+}
+
+// layoutTable returns a GSUB or GPOS table with the features for the default language system of the script, where features with a non-zero name ID have feature parameters. The language system, if not empty, has the first feature as its required feature.
+func layoutTable(script, langSys string, tags []string, nameIDs []uint16) []byte {
+	w := newBinaryWriter([]byte{})
+	w.WriteUint32(0x00010000)
+	w.WriteUint16(10) // scriptListOffset
+	w.WriteUint16(uint16(10 + 8 + 10 + 6 + 2*len(tags) + 6))
+	w.WriteUint16(0) // lookupListOffset
+
+	// script list
+	w.WriteUint16(1)
+	w.WriteString(script)
+	w.WriteUint16(8)
+	w.WriteUint16(10) // defaultLangSysOffset
+	if langSys != "" {
+		w.WriteUint16(1)
+		w.WriteString(langSys)
+		w.WriteUint16(uint16(10 + 6 + 2*len(tags)))
+	} else {
+		w.WriteUint16(0)
+		w.WriteString("    ")
+		w.WriteUint16(0)
+	}
+	w.WriteUint16(0)      // lookupOrderOffset
+	w.WriteUint16(0xFFFF) // requiredFeatureIndex
+	w.WriteUint16(uint16(len(tags)))
+	for i := range tags {
+		w.WriteUint16(uint16(i))
+	}
+	w.WriteUint16(0) // lookupOrderOffset
+	w.WriteUint16(0) // requiredFeatureIndex
+	w.WriteUint16(0)
+
+	// feature list
+	w.WriteUint16(uint16(len(tags)))
+	offset := 2 + 6*len(tags)
+	for i, tag := range tags {
+		w.WriteString(tag)
+		w.WriteUint16(uint16(offset))
+		offset += 4
+		if nameIDs[i] != 0 {
+			offset += 4
+		}
+	}
+	for i := range tags {
+		if nameIDs[i] != 0 {
+			w.WriteUint16(4) // featureParamsOffset
+			w.WriteUint16(0)
+			w.WriteUint16(0) // version
+			w.WriteUint16(nameIDs[i])
+		} else {
+			w.WriteUint16(0)
+			w.WriteUint16(0)
+		}
+	}
+	return w.Bytes()
+}
+
+func TestSFNTFeatures(t *testing.T) {
+	name := newBinaryWriter([]byte{})
+	name.WriteUint16(0)
+	name.WriteUint16(1)
+	name.WriteUint16(6 + 12)
+	name.WriteUint16(3)
+	name.WriteUint16(1)
+	name.WriteUint16(0x0409)
+	name.WriteUint16(256) // nameID
+	name.WriteUint16(12)
+	name.WriteUint16(0)
+	name.WriteString("\x00S\x00i\x00n\x00g\x00l\x00e")
+
+	sfnt := &SFNT{tables: map[string][]byte{
+		"GSUB": layoutTable("latn", "TRK ", []string{"ss01", "liga"}, []uint16{256, 0}),
+		"GPOS": layoutTable("latn", "", []string{"kern", "ss01"}, []uint16{0, 0}),
+		"name": name.Bytes(),
+	}}
+	test.T(t, sfnt.Features(), []FeatureInfo{
+		{"kern", "latn", "dflt", "", false, true},
+		{"liga", "latn", "dflt", "", true, false},
+		{"ss01", "latn", "TRK ", "Single", true, false},
+		{"ss01", "latn", "dflt", "Single", true, true},
+	})
+
+	sfnt.tables = map[string][]byte{"GSUB": layoutTable("latn", "", []string{"ss01"}, []uint16{256})[:30]}
+	test.T(t, len(sfnt.Features()), 0)
+
+	b, err := ioutil.ReadFile("DejaVuSerif.ttf")
+	test.Error(t, err)
+	sfnt, err = NewSFNT(b)
+	test.Error(t, err)
+	test.That(t, 0 < len(sfnt.Features()))
+}
@@ -29,6 +29,9 @@ func TestParseTTF(t *testing.T) {
 
 	indices := font.IndicesOf("test")
 	test.T(t, len(indices), 4)
+
+	_, ok := font.SFNT().Table("head")
+	test.That(t, ok, "head table must exist")
 }
 
 func TestParseOTF(t *testing.T) {
@@ -56,11 +59,41 @@ func TestSubstitutes(t *testing.T) {
 	font, err := parseFont("dejavu-serif", b)
 	test.Error(t, err)
 
-	font.Use(CommonLigatures)
-
-	test.String(t, font.substituteLigatures("fi fl ffi ffl"), "ﬁ ﬂ ﬃ ﬄ")
+	test.String(t, font.substituteLigatures("fi fl ffi ffl", ligatureTags(font.ligatures)), "ﬁ ﬂ ﬀi ﬄ") // the lookup of ff precedes that of ffi
+	font.Use(NoCommonLigatures)
+	test.String(t, font.substituteLigatures("fi fl ffi ffl", ligatureTags(font.ligatures)), "fi fl ffi ffl")
 	s, inSingleQuote, inDoubleQuote := font.substituteTypography(`... . . . --- -- (c) (r) (tm) 1/2 1/4 3/4 +/- '' ""`, false, false)
 	test.String(t, s, "… … — – © ® ™ ½ ¼ ¾ ± ‘’ “”")
 	test.That(t, !inSingleQuote)
 	test.That(t, !inDoubleQuote)
 }
+
+func TestDefectiveClusters(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+
+	font, err := parseFont("dejavu-serif", b)
+	test.Error(t, err)
+
+	var tts = []struct {
+		s         string
+		rPrev     rune
+		defective DefectiveCluster
+		expected  string
+	}{
+		{"é", 0, DefectiveDottedCircle, "é"},
+		{"́e", 0, DefectiveDottedCircle, "◌́e"},
+		{"̧́e", 0, DefectiveDottedCircle, "◌̧́e"},
+		{"́e", 'a', DefectiveDottedCircle, "́e"},
+		{"a\ńe", 0, DefectiveDottedCircle, "a\n◌́e"},
+		{"́e", '\n', DefectiveDottedCircle, "◌́e"},
+		{"̧́e", 0, DefectiveDrop, "e"},
+		{"a\ńe", 0, DefectiveDrop, "a\ne"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.s, func(t *testing.T) {
+			font.SetDefectiveCluster(tt.defective)
+			test.String(t, font.fixDefectiveClusters(tt.s, tt.rPrev), tt.expected)
+		})
+	}
+}
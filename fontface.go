@@ -5,8 +5,12 @@ import (
 	"image/color"
 	"io/ioutil"
 	"math"
+	"math/rand"
 	"os/exec"
 	"reflect"
+	"sort"
+	"strings"
+	"unicode"
 
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/sfnt"
@@ -40,11 +44,23 @@ const (
 	FontSmallcaps
 )
 
+// FallbackPolicy defines how an OpenType feature that is enabled for a font face is handled when the font does not have the feature, see FontFace.Features.
+type FallbackPolicy int
+
+// see FallbackPolicy
+const (
+	FallbackIgnore     FallbackPolicy = iota // lay out the text without the feature
+	FallbackSynthesize                       // imitate the feature, which is only defined for smcp and c2sc (scaled capitals) and frac (scaled digits), other features fail as with FallbackError
+	FallbackError                            // fail with ErrMissingFeature, such as to catch fonts that lack decorative features of a design
+)
+
 // FontFamily contains a family of fonts (bold, italic, ...). Selecting an italic style will pick the native italic font or use faux italic if not present.
 type FontFamily struct {
-	name    string
-	fonts   map[FontStyle]*Font
-	options TypographicOptions
+	name      string
+	fonts     map[FontStyle]*Font
+	options   TypographicOptions
+	missing   MissingGlyph
+	defective DefectiveCluster
 }
 
 // NewFontFamily returns a new FontFamily.
@@ -100,9 +116,15 @@ func (family *FontFamily) LoadFont(b []byte, style FontStyle) error {
 	if err != nil {
 		return err
 	}
+	family.addFont(font, style)
+	return nil
+}
+
+func (family *FontFamily) addFont(font *Font, style FontStyle) {
 	font.Use(family.options)
+	font.SetMissingGlyph(family.missing)
+	font.SetDefectiveCluster(family.defective)
 	family.fonts[style] = font
-	return nil
 }
 
 // Use specifies which typographic options shall be used, ie. whether to use common typographic substitutions and which ligatures classes to use.
@@ -113,7 +135,23 @@ func (family *FontFamily) Use(options TypographicOptions) {
 	}
 }
 
-// Face gets the font face given by the font size (in pt).
+// SetMissingGlyph sets how codepoints that are missing from the fonts are rendered, see MissingGlyph.
+func (family *FontFamily) SetMissingGlyph(missing MissingGlyph) {
+	family.missing = missing
+	for _, font := range family.fonts {
+		font.SetMissingGlyph(missing)
+	}
+}
+
+// SetDefectiveCluster sets how combining marks without a base character are handled for the fonts, see DefectiveCluster.
+func (family *FontFamily) SetDefectiveCluster(defective DefectiveCluster) {
+	family.defective = defective
+	for _, font := range family.fonts {
+		font.SetDefectiveCluster(defective)
+	}
+}
+
+// Face gets the font face given by the font size in points, see TypographicPoint. All measurements of the font face, such as its metrics and text widths, are in millimeters.
 func (family *FontFamily) Face(size float64, col color.Color, style FontStyle, variant FontVariant, deco ...FontDecorator) FontFace {
 	size *= mmPerPt
 
@@ -188,12 +226,212 @@ type FontFace struct {
 	Color   color.RGBA
 	deco    []FontDecorator
 
+	// CellWidth sets a fixed advance in mm for all glyphs when non-zero, as for terminal output. Glyphs advance by a single cell, or by two cells for wide characters (East Asian Wide and Fullwidth) or glyphs that are wider than a cell. Combining marks and other zero-width characters do not advance, and kerning is disabled.
+	CellWidth float64
+
+	// Palette is the index of the CPAL palette used for the color glyphs of COLR fonts, such as a dark mode palette. Palette 0 is the default and is used as well when the index is out of range. Color glyphs are only drawn in color when text is rendered as paths, such as by the rasterizer.
+	Palette int
+
+	// Ligatures enables DiscretionaryLigatures and HistoricalLigatures for text using this font face, in addition to the ligatures enabled for the font, see Font.Use. Ligatures are substituted by the GSUB ligature lookups of the dlig and hlig features, and are ignored when the font does not have the feature.
+	Ligatures TypographicOptions
+
+	// Features enables OpenType features by tag with the policy for when the font does not have the feature, such as "smcp" for small capitals, see FallbackPolicy. Of the features that the font has, only features that consist of single substitutions (GSUB lookup type 1) are applied, such as the small capitals of smcp or the oldstyle figures of onum, which substitute the glyphs for measuring and rendering text as paths or in PDF, while SVG text enables the features natively. Other substitutions, such as contextual alternates, are not applied, and ligatures are enabled by Ligatures and Font.Use instead.
+	Features map[string]FallbackPolicy
+
+	// Fallbacks are the fonts used in order for characters that Font has no glyph for, such as CJK characters in text set in a Latin font, see ResolveFace. They are used by the text layout of NewTextLine, NewTextBox and RichText, which split the text into spans of the font face with the font that has the glyphs.
+	Fallbacks []*Font
+
 	Scale, Voffset, FauxBold, FauxItalic float64 // consequences of font style and variant
 }
 
 // Equals returns true when two font face are equal. In particular this allows two adjacent text spans that use the same decoration to allow the decoration to span both elements instead of two separately.
 func (ff FontFace) Equals(other FontFace) bool {
-	return ff.Font == other.Font && ff.Size == other.Size && ff.Style == other.Style && ff.Variant == other.Variant && ff.Color == other.Color && reflect.DeepEqual(ff.deco, other.deco)
+	return ff.Font == other.Font && ff.Size == other.Size && ff.Style == other.Style && ff.Variant == other.Variant && ff.Color == other.Color && ff.CellWidth == other.CellWidth && ff.Palette == other.Palette && ff.Ligatures == other.Ligatures && ff.Scale == other.Scale && ff.Voffset == other.Voffset && reflect.DeepEqual(ff.Features, other.Features) && reflect.DeepEqual(ff.deco, other.deco) && equalFonts(ff.Fallbacks, other.Fallbacks)
+}
+
+func equalFonts(a, b []*Font) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// cellAdvance returns the advance of a glyph snapped to CellWidth, see FontFace.CellWidth.
+func (ff FontFace) cellAdvance(r rune, advance float64) float64 {
+	if ff.CellWidth == 0.0 {
+		return advance
+	} else if isZeroWidth(r) {
+		return 0.0
+	} else if isWide(r) || ff.CellWidth+Epsilon < advance {
+		return 2.0 * ff.CellWidth
+	}
+	return ff.CellWidth
+}
+
+// substituteLigatures replaces character sequences by their ligatures as enabled for the font and for the font face.
+func (ff FontFace) substituteLigatures(s string) string {
+	options := ff.Font.ligatures | ff.Ligatures&(DiscretionaryLigatures|HistoricalLigatures)
+	return ff.Font.substituteLigatures(s, ligatureTags(options))
+}
+
+// prepareText returns the text as it is laid out, where the text is cleaned and the ligatures are substituted, see Font.cleanText. It is used by all text layouts so that they give the same result.
+func (ff FontFace) prepareText(s string, rPrev rune) string {
+	return ff.substituteLigatures(ff.Font.cleanText(s, rPrev))
+}
+
+// LigatureFeatures returns the tags of the GSUB ligature features that are enabled for the font face and that the font has, in the order liga, dlig and hlig, see Font.Use and FontFace.Ligatures.
+func (ff FontFace) LigatureFeatures() []string {
+	options := ff.Font.ligatures | ff.Ligatures&(DiscretionaryLigatures|HistoricalLigatures)
+	tags := []string{}
+	for _, tag := range ligatureTags(options) {
+		if ff.Font.HasFeature(tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// featureRun is a run of text with the font face that imitates the features of the run, see FontFace.featureRuns.
+type featureRun struct {
+	Face FontFace
+	Text string
+}
+
+// featureTags returns the tags of the enabled features in sorted order, see FontFace.Features.
+func (ff FontFace) featureTags() []string {
+	tags := make([]string, 0, len(ff.Features))
+	for tag := range ff.Features {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// glyphIndex returns the glyph index of a rune, substituted by the GSUB single substitutions of the enabled features that the font has in the order of their tags, such as the small capitals of smcp, see FontFace.Features.
+func (ff FontFace) glyphIndex(buffer *sfnt.Buffer, r rune) (sfnt.GlyphIndex, error) {
+	index, err := ff.Font.glyphIndex(buffer, r)
+	if err != nil || index == 0 || len(ff.Features) == 0 {
+		return index, err
+	}
+	for _, tag := range ff.featureTags() {
+		if !ff.Font.HasFeature(tag) {
+			continue
+		} else if substitute, ok := ff.Font.singleSubstitutions(tag)[uint16(index)]; ok {
+			index = sfnt.GlyphIndex(substitute)
+		}
+	}
+	return index, nil
+}
+
+// IndicesOf returns the glyph indices of the runes of s, where the glyphs are substituted by the enabled features that the font has, see Font.IndicesOf and FontFace.Features.
+func (ff FontFace) IndicesOf(s string) []uint16 {
+	buffer := &sfnt.Buffer{}
+	indices := make([]uint16, 0, len(s))
+	for _, r := range s {
+		index, _ := ff.glyphIndex(buffer, r)
+		indices = append(indices, uint16(index))
+	}
+	return indices
+}
+
+// featureRuns returns the runs of text and their font faces that synthesize the features of the font face that the font does not have, see FallbackPolicy. The features smcp, c2sc and frac are synthesized as well when the font has the feature but not as single substitutions, such as fractions by contextual substitutions, which are not applied. Small capitals are capitals scaled to the x-height and fractions such as 1/2 are digits scaled to 60% with the numerator raised to the cap height and a fraction slash. It returns nil when no features are synthesized, and ErrMissingFeature when a missing feature cannot be synthesized or its policy is FallbackError.
+func (ff FontFace) featureRuns(s string) ([]featureRun, error) {
+	var smcp, c2sc, frac bool
+	for _, tag := range ff.featureTags() {
+		policy := ff.Features[tag]
+		if policy == FallbackIgnore || ff.Font.HasFeature(tag) && (tag != "smcp" && tag != "c2sc" && tag != "frac" || ff.Font.singleSubstitutions(tag) != nil) {
+			continue // the font has the feature, of which the synthesizable features are applied by their single substitutions
+		} else if policy == FallbackSynthesize && (tag == "smcp" || tag == "c2sc" || tag == "frac") {
+			smcp, c2sc, frac = smcp || tag == "smcp", c2sc || tag == "c2sc", frac || tag == "frac"
+			continue
+		}
+		return nil, fmt.Errorf("%w: %s", ErrMissingFeature, tag)
+	}
+	if !smcp && !c2sc && !frac {
+		return nil, nil
+	}
+
+	// derived font faces do not synthesize features again
+	plain := ff
+	plain.Features = nil
+	metrics := ff.Metrics()
+	smallCaps, numerator, denominator := plain, plain, plain
+	if 0.0 < metrics.CapHeight && 0.0 < metrics.XHeight {
+		smallCaps.Scale *= metrics.XHeight / metrics.CapHeight
+	} else {
+		smallCaps.Scale *= 0.7
+	}
+	smallCaps.FauxBold += 0.01 * ff.Size * smallCaps.Scale // compensate for the thinner strokes
+	numerator.Scale *= 0.6
+	numerator.Voffset += 0.4 * metrics.CapHeight
+	denominator.Scale *= 0.6
+
+	fractionSlash := "/"
+	if index, err := ff.Font.sfnt.GlyphIndex(&sfnt.Buffer{}, '⁄'); err == nil && index != 0 {
+		fractionSlash = "⁄"
+	}
+
+	// classify each rune, where fractions are digits, a slash and digits
+	const (
+		normalKind = iota
+		smallCapsKind
+		numeratorKind
+		slashKind
+		denominatorKind
+	)
+	runes := []rune(s)
+	kinds := make([]int, len(runes))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if smcp && unicode.IsLower(r) || c2sc && unicode.IsUpper(r) {
+			kinds[i] = smallCapsKind
+		} else if frac && unicode.IsDigit(r) && (i == 0 || !unicode.IsDigit(runes[i-1])) {
+			j := i
+			for j < len(runes) && unicode.IsDigit(runes[j]) {
+				j++
+			}
+			if j+1 < len(runes) && runes[j] == '/' && unicode.IsDigit(runes[j+1]) {
+				k := j + 1
+				for k < len(runes) && unicode.IsDigit(runes[k]) {
+					kinds[k] = denominatorKind
+					k++
+				}
+				for ; i < j; i++ {
+					kinds[i] = numeratorKind
+				}
+				kinds[j] = slashKind
+				i = k - 1
+			}
+		}
+	}
+
+	runs := []featureRun{}
+	for i := 0; i < len(runes); {
+		j := i + 1
+		for j < len(runes) && kinds[j] == kinds[i] {
+			j++
+		}
+		text := string(runes[i:j])
+		switch kinds[i] {
+		case normalKind:
+			runs = append(runs, featureRun{plain, text})
+		case smallCapsKind:
+			runs = append(runs, featureRun{smallCaps, strings.ToUpper(text)})
+		case numeratorKind:
+			runs = append(runs, featureRun{numerator, text})
+		case slashKind:
+			runs = append(runs, featureRun{plain, fractionSlash})
+		case denominatorKind:
+			runs = append(runs, featureRun{denominator, text})
+		}
+		i = j
+	}
+	return runs, nil
 }
 
 // Name returns the name of the underlying font
@@ -201,7 +439,20 @@ func (ff FontFace) Name() string {
 	return ff.Font.name
 }
 
-// Metrics returns the font metrics. See https://developer.apple.com/library/archive/documentation/TextFonts/Conceptual/CocoaTextArchitecture/Art/glyph_metrics_2x.png for an explanation of the different metrics.
+// ItalicAngle returns the angle in counter-clockwise degrees from the vertical that the glyphs lean by, such as to slant a cursor or an icon next to the text to match. It is the italic angle of the font, see Font.ItalicAngle, or the angle of the faux italic when the font face uses faux italic. It is zero for upright text and negative for text that leans to the right.
+func (ff FontFace) ItalicAngle() float64 {
+	if ff.FauxItalic != 0.0 {
+		return -math.Atan(ff.FauxItalic) * 180.0 / math.Pi
+	}
+	return ff.Font.ItalicAngle()
+}
+
+// slant returns the horizontal displacement per unit of height of the leaning glyphs.
+func (ff FontFace) slant() float64 {
+	return -math.Tan(ff.ItalicAngle() * math.Pi / 180.0)
+}
+
+// Metrics returns the font metrics in mm. See https://developer.apple.com/library/archive/documentation/TextFonts/Conceptual/CocoaTextArchitecture/Art/glyph_metrics_2x.png for an explanation of the different metrics.
 func (ff FontFace) Metrics() FontMetrics {
 	m := ff.Font.Metrics(ff.Size * ff.Scale)
 	return FontMetrics{
@@ -215,103 +466,217 @@ func (ff FontFace) Metrics() FontMetrics {
 
 // Kerning returns the eventual kerning between two runes in mm (ie. the adjustment on the advance).
 func (ff FontFace) Kerning(rPrev, rNext rune) float64 {
+	if ff.CellWidth != 0.0 {
+		return 0.0
+	}
 	k, _ := ff.Font.Kerning(rPrev, rNext, ff.Size*ff.Scale)
 	return k
 }
 
-// TextWidth returns the width of a given string in mm.
+// TextWidth returns the width of a given string in mm. Widths are memoized when the text measurement cache is enabled, see SetTextMeasureCacheSize.
 func (ff FontFace) TextWidth(s string) float64 {
-	buffer := &sfnt.Buffer{}
+	key := newMeasureKey(ff, s)
+	if w, ok := textMeasureCache.get(key); ok {
+		return w.(float64)
+	}
+	w := ff.featureWidth(s, &sfnt.Buffer{})
+	textMeasureCache.put(key, w)
+	return w
+}
+
+// MeasureBuffer is scratch space for measuring text widths that is reused between calls, see FontFace.MeasureInto. The zero value is ready to use, and a buffer must not be used concurrently.
+type MeasureBuffer struct {
+	sfnt sfnt.Buffer
+}
+
+// MeasureInto returns the width of a given string in mm, which is equal to TextWidth, using the buffer as scratch space so that measuring in hot loops does not allocate unless the font face enables features. It bypasses the text measurement cache, as building cache keys allocates.
+func (ff FontFace) MeasureInto(s string, buf *MeasureBuffer) float64 {
+	return ff.featureWidth(s, &buf.sfnt)
+}
+
+// featureWidth returns the width of a string including the features that are synthesized, which is the sum of the widths of the runs of text in their derived font faces, see FontFace.featureRuns.
+func (ff FontFace) featureWidth(s string, buffer *sfnt.Buffer) float64 {
+	if len(ff.Features) == 0 {
+		return ff.textWidth(s, buffer)
+	}
+	runs, err := ff.featureRuns(s)
+	if err != nil || runs == nil {
+		return ff.textWidth(s, buffer)
+	}
 	w := 0.0
-	var prevIndex sfnt.GlyphIndex
-	for i, r := range s {
-		index, err := ff.Font.sfnt.GlyphIndex(buffer, r)
-		if err != nil {
-			continue
+	for _, run := range runs {
+		w += run.Face.textWidth(run.Text, buffer)
+	}
+	return w
+}
+
+func (ff FontFace) textWidth(s string, buffer *sfnt.Buffer) float64 {
+	m := textMeasurer{ff: ff, buffer: buffer}
+	for _, r := range s {
+		m.add(r)
+	}
+	return m.width
+}
+
+// textMeasurer accumulates the width of a text rune by rune, so that the widths of all prefixes of a text are measured in linear time, see FontFace.textWidth.
+type textMeasurer struct {
+	ff        FontFace
+	buffer    *sfnt.Buffer
+	width     float64
+	prevIndex sfnt.GlyphIndex
+	started   bool
+}
+
+func newTextMeasurer(ff FontFace) *textMeasurer {
+	return &textMeasurer{ff: ff, buffer: &sfnt.Buffer{}}
+}
+
+// add adds the advance of the next rune and the kerning with the previous glyph.
+func (m *textMeasurer) add(r rune) {
+	ff := m.ff
+	first := !m.started
+	m.started = true
+	if isFormat(r) {
+		return // no advance and keep kerning between the adjacent glyphs
+	}
+	if _, advance, ok := ff.customGlyph(r); ok {
+		m.width += ff.cellAdvance(r, advance)
+		m.prevIndex = 0
+		return
+	}
+	index, err := ff.glyphIndex(m.buffer, r)
+	if err != nil {
+		return
+	} else if advance, ok := ff.missingAdvance(index, r); ok {
+		m.width += ff.cellAdvance(r, advance)
+		m.prevIndex = 0
+		return
+	} else if index == 0 && ff.Font.missing == MissingHexBox {
+		m.width += ff.cellAdvance(r, ff.hexBoxAdvance(r))
+		m.prevIndex = index
+		return
+	}
+
+	if !first && ff.CellWidth == 0.0 {
+		kern, err := ff.Font.sfnt.Kern(m.buffer, m.prevIndex, index, toI26_6(ff.Size*ff.Scale), font.HintingNone)
+		if err == nil {
+			m.width += fromI26_6(kern)
 		}
+	}
+	advance, err := ff.Font.sfnt.GlyphAdvance(m.buffer, index, toI26_6(ff.Size*ff.Scale), font.HintingNone)
+	if err == nil {
+		m.width += ff.cellAdvance(r, fromI26_6(advance))
+	}
+	m.prevIndex = index
+}
 
-		if i != 0 {
-			kern, err := ff.Font.sfnt.Kern(buffer, prevIndex, index, toI26_6(ff.Size*ff.Scale), font.HintingNone)
-			if err == nil {
-				w += fromI26_6(kern)
-			}
+// Advance returns the advance width in mm of the glyph for a rune as given by the horizontal metrics of the font, without kerning, hinting or any other adjustments. It is a cheap way to estimate text widths, see TextWidth for exact widths. It returns the advance of the missing glyph if the font has no glyph for r.
+func (ff FontFace) Advance(r rune) float64 {
+	if _, advance, ok := ff.customGlyph(r); ok {
+		return advance
+	}
+	index, err := ff.glyphIndex(&sfnt.Buffer{}, r)
+	if err != nil {
+		return 0.0
+	} else if advance, ok := ff.missingAdvance(index, r); ok {
+		return advance
+	}
+	return float64(ff.Font.tables.Advance(uint16(index))) * ff.Size * ff.Scale / ff.Font.UnitsPerEm()
+}
+
+// CanRender returns the fraction of runes in s that the font has a glyph for, and the runes it has no glyph for in order of first occurrence. Whitespace and control characters are not counted. Coverage is 1.0 when there are no countable runes. A coverage near zero means the text would render (almost) entirely as missing glyphs, so that another font should be chosen.
+func (ff FontFace) CanRender(s string) (float64, []rune) {
+	buffer := &sfnt.Buffer{}
+	n, covered := 0, 0
+	missing := []rune{}
+	seen := map[rune]bool{}
+	for _, r := range s {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || isFormat(r) {
+			continue
 		}
-		advance, err := ff.Font.sfnt.GlyphAdvance(buffer, index, toI26_6(ff.Size*ff.Scale), font.HintingNone)
-		if err == nil {
-			w += fromI26_6(advance)
+		n++
+		if ff.hasGlyph(buffer, r) {
+			covered++
+		} else if !seen[r] {
+			seen[r] = true
+			missing = append(missing, r)
 		}
-		prevIndex = index
 	}
-	return w
+	if n == 0 {
+		return 1.0, missing
+	}
+	return float64(covered) / float64(n), missing
 }
 
-// Decorate will return a path from the decorations specified in the FontFace over a given width in mm.
+// Decorate will return a path from the decorations specified in the FontFace over a given width in mm. Highlights are not included as they are drawn in their own color, see FontHighlight.
 func (ff FontFace) Decorate(width float64) *Path {
 	p := &Path{}
 	if ff.deco != nil {
 		for _, deco := range ff.deco {
+			if _, ok := deco.(highlight); !ok {
+				p = p.Append(deco.Decorate(ff, width))
+			}
+		}
+	}
+	return p
+}
+
+// decorate returns a path from the decorations specified in the FontFace that are drawn either over the glyphs (such as strikethroughs) or under the glyphs (all other decorations), except for highlights which are drawn in their own color.
+func (ff FontFace) decorate(width float64, over bool) *Path {
+	p := &Path{}
+	for _, deco := range ff.deco {
+		if _, ok := deco.(highlight); ok {
+			continue
+		} else if _, ok := deco.(strikethrough); ok == over {
 			p = p.Append(deco.Decorate(ff, width))
 		}
 	}
 	return p
 }
 
-// ToPath converts a string to a path and also returns its advance in mm.
+// ToPath converts a string to a path and also returns its advance in mm. Glyphs are moved vertically by the cursive attachments of fonts with a curs feature, such as for Arabic Nastaliq.
 func (ff FontFace) ToPath(s string) (*Path, float64) {
 	buffer := &sfnt.Buffer{}
 	p := &Path{}
 	x := 0.0
 	var prevIndex sfnt.GlyphIndex
+	offsets := ff.cursiveOffsets(s)
+	k := -1 // rune index
 	for i, r := range s {
-		index, err := ff.Font.sfnt.GlyphIndex(buffer, r)
+		k++
+		if isFormat(r) {
+			continue
+		}
+		if glyph, advance, ok := ff.customGlyph(r); ok {
+			p = p.Append(glyph.Translate(x, 0.0))
+			x += ff.cellAdvance(r, advance)
+			prevIndex = 0
+			continue
+		}
+		index, err := ff.glyphIndex(buffer, r)
 		if err != nil {
 			return p, 0.0
+		} else if advance, ok := ff.missingAdvance(index, r); ok {
+			x += ff.cellAdvance(r, advance)
+			prevIndex = 0
+			continue
+		} else if index == 0 && ff.Font.missing == MissingHexBox {
+			box, advance := ff.hexBox(r)
+			p = p.Append(box.Translate(x, 0.0))
+			x += ff.cellAdvance(r, advance)
+			prevIndex = index
+			continue
 		}
 
-		segments, err := ff.Font.sfnt.LoadGlyph(buffer, index, toI26_6(ff.Size*ff.Scale), nil)
+		glyph, err := ff.glyphPath(buffer, index, x)
 		if err != nil {
 			return p, 0.0
+		} else if offsets != nil {
+			glyph = glyph.Translate(0.0, offsets[k])
 		}
+		p = p.Append(glyph)
 
-		var start0, end Point
-		for i, segment := range segments {
-			switch segment.Op {
-			case sfnt.SegmentOpMoveTo:
-				if i != 0 && start0.Equals(end) {
-					p.Close()
-				}
-				end = fromP26_6(segment.Args[0])
-				end.X += ff.FauxItalic * -end.Y
-				p.MoveTo(x+end.X, ff.Voffset-end.Y)
-				start0 = end
-			case sfnt.SegmentOpLineTo:
-				end = fromP26_6(segment.Args[0])
-				end.X += ff.FauxItalic * -end.Y
-				p.LineTo(x+end.X, ff.Voffset-end.Y)
-			case sfnt.SegmentOpQuadTo:
-				cp := fromP26_6(segment.Args[0])
-				end = fromP26_6(segment.Args[1])
-				cp.X += ff.FauxItalic * -cp.Y
-				end.X += ff.FauxItalic * -end.Y
-				p.QuadTo(x+cp.X, ff.Voffset-cp.Y, x+end.X, ff.Voffset-end.Y)
-			case sfnt.SegmentOpCubeTo:
-				cp1 := fromP26_6(segment.Args[0])
-				cp2 := fromP26_6(segment.Args[1])
-				end = fromP26_6(segment.Args[2])
-				cp1.X += ff.FauxItalic * -cp1.Y
-				cp2.X += ff.FauxItalic * -cp2.Y
-				end.X += ff.FauxItalic * -end.Y
-				p.CubeTo(x+cp1.X, ff.Voffset-cp1.Y, x+cp2.X, ff.Voffset-cp2.Y, x+end.X, ff.Voffset-end.Y)
-			}
-		}
-		if !p.Empty() && start0.Equals(end) {
-			p.Close()
-		}
-		if ff.FauxBold != 0.0 {
-			p = p.Offset(ff.FauxBold, NonZero)
-		}
-
-		if i != 0 {
+		if i != 0 && ff.CellWidth == 0.0 {
 			kern, err := ff.Font.sfnt.Kern(buffer, prevIndex, index, toI26_6(ff.Size*ff.Scale), font.HintingNone)
 			if err == nil {
 				x += fromI26_6(kern)
@@ -319,13 +684,249 @@ func (ff FontFace) ToPath(s string) (*Path, float64) {
 		}
 		advance, err := ff.Font.sfnt.GlyphAdvance(buffer, index, toI26_6(ff.Size*ff.Scale), font.HintingNone)
 		if err == nil {
-			x += fromI26_6(advance)
+			x += ff.cellAdvance(r, fromI26_6(advance))
 		}
 		prevIndex = index
 	}
 	return p, x
 }
 
+// cursiveOffsets returns the vertical offset in mm of each rune of s by the cursive attachments of the font, or nil if the font has no curs feature, see font.SFNT.CursiveOffsets.
+func (ff FontFace) cursiveOffsets(s string) []float64 {
+	if !ff.Font.features["curs"] {
+		return nil
+	}
+	buffer := &sfnt.Buffer{}
+	glyphIDs := []uint16{}
+	for _, r := range s {
+		index, _ := ff.glyphIndex(buffer, r)
+		glyphIDs = append(glyphIDs, uint16(index))
+	}
+	units := ff.Font.tables.CursiveOffsets(glyphIDs)
+	if units == nil {
+		return nil
+	}
+	f := ff.Size * ff.Scale / ff.Font.UnitsPerEm()
+	offsets := make([]float64, len(units))
+	for i, y := range units {
+		offsets[i] = f * float64(y)
+	}
+	return offsets
+}
+
+// ResolveFace returns the font face of the fallback chain that renders the rune, which is the font face itself when its font has a glyph for the rune, or otherwise the font face with the first font of Fallbacks that has a glyph. It returns the font face itself and false when none of the fonts have a glyph, so that the rune renders as a missing glyph. Whitespace and control characters always resolve to the font face itself.
+func (ff FontFace) ResolveFace(r rune) (FontFace, bool) {
+	return ff.resolveFace(&sfnt.Buffer{}, r)
+}
+
+func (ff FontFace) resolveFace(buffer *sfnt.Buffer, r rune) (FontFace, bool) {
+	if unicode.IsSpace(r) || unicode.IsControl(r) || isFormat(r) || ff.hasGlyph(buffer, r) {
+		return ff, true
+	}
+	for _, font := range ff.Fallbacks {
+		face := ff
+		face.Font = font
+		if face.hasGlyph(buffer, r) {
+			return face, true
+		}
+	}
+	return ff, false
+}
+
+// hasGlyph returns true if the font has a glyph or a custom glyph for the rune.
+func (ff FontFace) hasGlyph(buffer *sfnt.Buffer, r rune) bool {
+	if _, _, ok := ff.customGlyph(r); ok {
+		return true
+	}
+	index, err := ff.Font.glyphIndex(buffer, r)
+	return err == nil && index != 0
+}
+
+// fallbackRuns splits the runs of text by the font face of the fallback chain that renders each character, see FontFace.ResolveFace. Combining marks and other characters that extend a grapheme cluster stay in the font face of their base character, and whitespace stays in the font face of the preceding character. It returns the runs unchanged when the font face has no fallbacks.
+func (ff FontFace) fallbackRuns(runs []featureRun) []featureRun {
+	if len(ff.Fallbacks) == 0 {
+		return runs
+	}
+	buffer := &sfnt.Buffer{}
+	split := []featureRun{}
+	for _, run := range runs {
+		start, face := 0, run.Face
+		for i, r := range run.Text {
+			if i != 0 && (isGraphemeExtend(r) || unicode.IsSpace(r) || unicode.IsControl(r) || isFormat(r)) {
+				continue
+			}
+			resolved, _ := run.Face.resolveFace(buffer, r)
+			if i == 0 {
+				face = resolved
+			} else if resolved.Font != face.Font {
+				split = append(split, featureRun{face, run.Text[start:i]})
+				start, face = i, resolved
+			}
+		}
+		split = append(split, featureRun{face, run.Text[start:]})
+	}
+	return split
+}
+
+// customGlyph returns the path and advance in mm of the custom glyph for a rune scaled to the font face, see Font.SetCustomGlyph.
+func (ff FontFace) customGlyph(r rune) (*Path, float64, bool) {
+	if ff.Font.custom == nil {
+		return nil, 0.0, false
+	}
+	glyph, advance, ok := ff.Font.custom(r)
+	if !ok {
+		return nil, 0.0, false
+	} else if glyph == nil {
+		glyph = &Path{}
+	}
+	scale := ff.Size * ff.Scale
+	return glyph.Transform(Identity.Translate(0.0, ff.Voffset).Scale(scale, scale)), advance * scale, true
+}
+
+// missingAdvance returns the advance in mm that is reserved for a rune without a glyph scaled to the font face, see Font.SetMissingAdvance.
+func (ff FontFace) missingAdvance(index sfnt.GlyphIndex, r rune) (float64, bool) {
+	if index != 0 || ff.Font.missingAdvance == nil {
+		return 0.0, false
+	}
+	advance, ok := ff.Font.missingAdvance(r)
+	return advance * ff.Size * ff.Scale, ok
+}
+
+// glyphPath returns the outline of a glyph at horizontal position x, with the faux styles and vertical offset of the font face applied. Outlines are memoized when the glyph cache is enabled, see SetGlyphCacheSize.
+func (ff FontFace) glyphPath(buffer *sfnt.Buffer, index sfnt.GlyphIndex, x float64) (*Path, error) {
+	key := newGlyphKey(ff, index)
+	if glyph, ok := glyphOutlineCache.get(key); ok {
+		return glyph.(*Path).Translate(x, 0.0), nil
+	}
+	glyph, err := ff.decodeGlyph(buffer, index)
+	if err != nil {
+		return nil, err
+	}
+	glyphOutlineCache.put(key, glyph)
+	return glyph.Translate(x, 0.0), nil
+}
+
+// decodeGlyph returns the outline of a glyph at the origin, see glyphPath.
+func (ff FontFace) decodeGlyph(buffer *sfnt.Buffer, index sfnt.GlyphIndex) (*Path, error) {
+	segments, err := ff.Font.sfnt.LoadGlyph(buffer, index, toI26_6(ff.Size*ff.Scale), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	glyph := &Path{}
+	var start0, end Point
+	for i, segment := range segments {
+		switch segment.Op {
+		case sfnt.SegmentOpMoveTo:
+			if i != 0 && start0.Equals(end) {
+				glyph.Close()
+			}
+			end = fromP26_6(segment.Args[0])
+			end.X += ff.FauxItalic * -end.Y
+			glyph.MoveTo(end.X, ff.Voffset-end.Y)
+			start0 = end
+		case sfnt.SegmentOpLineTo:
+			end = fromP26_6(segment.Args[0])
+			end.X += ff.FauxItalic * -end.Y
+			glyph.LineTo(end.X, ff.Voffset-end.Y)
+		case sfnt.SegmentOpQuadTo:
+			cp := fromP26_6(segment.Args[0])
+			end = fromP26_6(segment.Args[1])
+			cp.X += ff.FauxItalic * -cp.Y
+			end.X += ff.FauxItalic * -end.Y
+			glyph.QuadTo(cp.X, ff.Voffset-cp.Y, end.X, ff.Voffset-end.Y)
+		case sfnt.SegmentOpCubeTo:
+			cp1 := fromP26_6(segment.Args[0])
+			cp2 := fromP26_6(segment.Args[1])
+			end = fromP26_6(segment.Args[2])
+			cp1.X += ff.FauxItalic * -cp1.Y
+			cp2.X += ff.FauxItalic * -cp2.Y
+			end.X += ff.FauxItalic * -end.Y
+			glyph.CubeTo(cp1.X, ff.Voffset-cp1.Y, cp2.X, ff.Voffset-cp2.Y, end.X, ff.Voffset-end.Y)
+		}
+	}
+	if !glyph.Empty() && start0.Equals(end) {
+		glyph.Close()
+	}
+
+	// fonts may have inconsistent winding directions of contours, make sure that counters remain holes and overlapping contours remain filled when filling with NonZero, for each glyph separately so that overlapping glyphs do not cancel out
+	if ff.Font.inconsistentWinding(index, glyph) {
+		glyph = glyph.NormalizeWinding(NonZero)
+	}
+	if ff.FauxBold != 0.0 {
+		glyph = glyph.Offset(ff.FauxBold, NonZero)
+	}
+	return glyph, nil
+}
+
+// baseline returns the position of a baseline above the alphabetic baseline from the BASE table for the given script tag, or for the default script when the script is not listed. It returns false for fonts without BASE table or when the baseline is not defined.
+func (ff FontFace) baseline(script, tag string) (float64, bool) {
+	baseScript, ok := ff.Font.baseScripts[script]
+	if !ok {
+		baseScript = ff.Font.baseScripts["DFLT"]
+	}
+	coord, ok := baseScript.Coords[tag]
+	return float64(coord) * ff.Size * ff.Scale / ff.Font.UnitsPerEm(), ok
+}
+
+// defaultBaseline returns the tag of the default baseline from the BASE table for the given script tag, which is "romn" (alphabetic) when not defined.
+func (ff FontFace) defaultBaseline(script string) string {
+	baseScript, ok := ff.Font.baseScripts[script]
+	if !ok {
+		baseScript = ff.Font.baseScripts["DFLT"]
+	}
+	if baseScript.DefaultBaseline == "" {
+		return "romn"
+	}
+	return baseScript.DefaultBaseline
+}
+
+// colorGlyph returns the color layers of a glyph of a COLR font by its glyph index after substitution, with its outline at position (x,y), and the colors from the selected palette. It returns no paths for glyphs that have no color layers.
+func (ff FontFace) colorGlyph(index sfnt.GlyphIndex, x, y float64) ([]*Path, []color.RGBA) {
+	if index == 0 {
+		return nil, nil
+	}
+	buffer := &sfnt.Buffer{}
+	layers := ff.Font.tables.ColorLayers(uint16(index))
+	if len(layers) == 0 {
+		return nil, nil
+	}
+
+	var palette []color.NRGBA
+	if 0 <= ff.Palette && ff.Palette < len(ff.Font.palettes) {
+		palette = ff.Font.palettes[ff.Palette]
+	} else if 0 < len(ff.Font.palettes) {
+		palette = ff.Font.palettes[0]
+	}
+
+	paths := []*Path{}
+	colors := []color.RGBA{}
+	for _, layer := range layers {
+		glyph, err := ff.glyphPath(buffer, sfnt.GlyphIndex(layer.GlyphID), x)
+		if err != nil {
+			return nil, nil
+		} else if y != 0.0 {
+			glyph = glyph.Translate(0.0, y)
+		}
+
+		// palette index 0xFFFF is the text color, the alpha of the text color applies to all layers
+		col := ff.Color
+		if int(layer.PaletteIndex) < len(palette) {
+			c := palette[layer.PaletteIndex]
+			a := float64(c.A) / 255.0 * float64(ff.Color.A) / 255.0
+			col = color.RGBA{
+				uint8(float64(c.R)*a + 0.5),
+				uint8(float64(c.G)*a + 0.5),
+				uint8(float64(c.B)*a + 0.5),
+				uint8(a*255.0 + 0.5),
+			}
+		}
+		paths = append(paths, glyph)
+		colors = append(colors, col)
+	}
+	return paths, colors
+}
+
 func (ff FontFace) Boldness() int {
 	boldness := 400
 	if ff.Style&FontExtraLight == FontExtraLight {
@@ -356,6 +957,96 @@ func (ff FontFace) Boldness() int {
 
 ////////////////////////////////////////////////////////////////
 
+// hexBoxDigits are the 3x5 pixel bitmaps of the hexadecimal digits used for hex boxes, from top-left to bottom-right.
+var hexBoxDigits = [16]string{
+	"####.##.##.####", // 0
+	".#.##..#..#.###", // 1
+	"###..#####..###", // 2
+	"###..####..####", // 3
+	"#.##.####..#..#", // 4
+	"####..###..####", // 5
+	"####..####.####", // 6
+	"###..#..#..#..#", // 7
+	"####.#####.####", // 8
+	"####.####..####", // 9
+	".#.#.#####.##.#", // A
+	"##.#.###.#.###.", // B
+	".###..#..#...##", // C
+	"##.#.##.##.###.", // D
+	"####..##.#..###", // E
+	"####..##.#..#..", // F
+}
+
+// hexBoxRows returns the hexadecimal digits of a codepoint divided over two rows. Codepoints in the BMP use two rows of two digits, astral codepoints use two rows of three digits.
+func hexBoxRows(r rune) [2]string {
+	if r <= 0xFFFF {
+		s := fmt.Sprintf("%04X", r)
+		return [2]string{s[:2], s[2:]}
+	}
+	s := fmt.Sprintf("%06X", r)
+	return [2]string{s[:3], s[3:]}
+}
+
+// hexBoxPixel returns the size in mm of one pixel of the hex box, the box is 15 pixels high and spans about 70% of the font size.
+func (ff FontFace) hexBoxPixel() float64 {
+	return 0.7 * ff.Size * ff.Scale / 15.0
+}
+
+// hexBoxAdvance returns the advance in mm of the hex box for the given codepoint.
+func (ff FontFace) hexBoxAdvance(r rune) float64 {
+	cols := len(hexBoxRows(r)[0])
+	return float64(4*cols+5) * ff.hexBoxPixel()
+}
+
+// hexBox returns the path and advance of a box containing the hexadecimal digits of the codepoint, which is used to render missing glyphs when MissingHexBox is set.
+func (ff FontFace) hexBox(r rune) (*Path, float64) {
+	rows := hexBoxRows(r)
+	cols := len(rows[0])
+	px := ff.hexBoxPixel()
+	w := float64(4*cols+3) * px
+	h := 15.0 * px
+	x0, y0 := px, ff.Voffset
+
+	rect := func(p *Path, x, y, w, h float64) {
+		p.MoveTo(x, y)
+		p.LineTo(x+w, y)
+		p.LineTo(x+w, y+h)
+		p.LineTo(x, y+h)
+		p.Close()
+	}
+
+	// the frame is counter clockwise with a clockwise hole, digits are counter clockwise inside the hole
+	p := &Path{}
+	rect(p, x0, y0, w, h)
+	p.MoveTo(x0+px, y0+px)
+	p.LineTo(x0+px, y0+h-px)
+	p.LineTo(x0+w-px, y0+h-px)
+	p.LineTo(x0+w-px, y0+px)
+	p.Close()
+	for j, row := range rows {
+		for i, c := range row {
+			digit := hexBoxDigits[strings.IndexRune("0123456789ABCDEF", c)]
+			x := x0 + float64(2+4*i)*px
+			y := y0 + h - float64(2+6*j)*px
+			for k := 0; k < 5; k++ {
+				// merge horizontal runs of pixels into rectangles
+				for l := 0; l < 3; l++ {
+					if digit[3*k+l] != '#' {
+						continue
+					}
+					n := 1
+					for l+n < 3 && digit[3*k+l+n] == '#' {
+						n++
+					}
+					rect(p, x+float64(l)*px, y-float64(k+1)*px, float64(n)*px, px)
+					l += n
+				}
+			}
+		}
+	}
+	return p, w + 2.0*px
+}
+
 // FontDecorator is an interface that returns a path given a font face and a width in mm.
 type FontDecorator interface {
 	Decorate(FontFace, float64) *Path
@@ -388,8 +1079,8 @@ func (overline) Decorate(ff FontFace, w float64) *Path {
 	r := ff.Size * underlineThickness
 	y := ff.Metrics().XHeight + ff.Size*underlineDistance
 
-	dx := ff.FauxItalic * y
-	w += ff.FauxItalic * y
+	dx := ff.slant() * y
+	w += ff.slant() * y
 
 	p := &Path{}
 	p.MoveTo(dx, y)
@@ -406,8 +1097,8 @@ func (strikethrough) Decorate(ff FontFace, w float64) *Path {
 	r := ff.Size * underlineThickness
 	y := ff.Metrics().XHeight / 2.0
 
-	dx := ff.FauxItalic * y
-	w += ff.FauxItalic * y
+	dx := ff.slant() * y
+	w += ff.slant() * y
 
 	p := &Path{}
 	p.MoveTo(dx, y)
@@ -529,3 +1220,53 @@ func (sawtoothUnderline) Decorate(ff FontFace, w float64) *Path {
 	}
 	return p.Stroke(r, ButtCap, MiterJoin)
 }
+
+// FontHighlight returns a font decoration that draws a rough band behind the text in the given color, like a marker pen. The band is slightly offset and skewed and its edges are jittered, where the jitter is deterministic for the seed and the width of the band so that the text renders the same every time. Each line of the text gets its own band. The band is drawn behind all other decorations and the glyphs in its own color, which should be translucent, such as color.RGBA{128, 128, 0, 128}, so that overlapping bands and content remain visible, as there are no blend modes.
+func FontHighlight(col color.RGBA, seed int64) FontDecorator {
+	return highlight{col, seed}
+}
+
+type highlight struct {
+	color color.RGBA
+	seed  int64
+}
+
+func (h highlight) Decorate(ff FontFace, w float64) *Path {
+	size := ff.Size * ff.Scale
+	rnd := rand.New(rand.NewSource(h.seed ^ int64(math.Float64bits(w))))
+	jitter := func(d float64) float64 {
+		return (2.0*rnd.Float64() - 1.0) * d * size
+	}
+
+	// the band covers the x-height and part of the ascenders and descenders, and extends beyond the text
+	pad := 0.1 * size
+	y0 := -0.2*size + jitter(0.03)
+	y1 := ff.Metrics().XHeight + 0.25*size + jitter(0.03)
+	skew := jitter(0.02)
+	n := int(w/size) + 1
+
+	p := &Path{}
+	p.MoveTo(-pad+jitter(0.03), y0)
+	for i := 1; i <= n; i++ {
+		x := -pad + (w+2.0*pad)*float64(i)/float64(n)
+		p.LineTo(x+jitter(0.02), y0+skew*float64(i)+jitter(0.02))
+	}
+	for i := n; 0 <= i; i-- {
+		x := -pad + 0.5*pad + (w+pad)*float64(i)/float64(n)
+		p.LineTo(x+jitter(0.02), y1+skew*float64(i)+jitter(0.02))
+	}
+	p.Close()
+	return p
+}
+
+// highlights returns the paths and colors of the highlight decorations of the font face, see FontHighlight.
+func (ff FontFace) highlights(width float64) ([]*Path, []color.RGBA) {
+	paths, colors := []*Path{}, []color.RGBA{}
+	for _, deco := range ff.deco {
+		if h, ok := deco.(highlight); ok {
+			paths = append(paths, h.Decorate(ff, width))
+			colors = append(colors, h.color)
+		}
+	}
+	return paths, colors
+}
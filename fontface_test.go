@@ -1,9 +1,17 @@
 package canvas
 
 import (
+	"encoding/binary"
+	"errors"
+	"image/color"
+	"io/ioutil"
+	"math"
+	"sort"
 	"testing"
+	"unicode"
 
 	"github.com/tdewolff/test"
+	"golang.org/x/image/font/sfnt"
 )
 
 func TestFontFamily(t *testing.T) {
@@ -46,7 +54,7 @@ func TestFontFace(t *testing.T) {
 
 	Epsilon = 1e-3
 	p, width := face.ToPath("AO")
-	test.T(t, p, MustParseSVG("M2.4062 3.1719L5.6094 3.1719L4.0156 7.3281L2.4062 3.1719zM-0.078125 0L-0.078125 0.625L0.70312 0.625L3.8125 8.75L4.7969 8.75L7.9219 0.625L8.7812 0.625L8.7812 0L5.6094 0L5.6094 0.625L6.5781 0.625L5.8438 2.5469L2.1562 2.5469L1.4375 0.625L2.3906 0.625L2.3906 0L-0.078125 0zM13.594 0.45312Q15.031 0.45312 15.766 1.4375Q16.5 2.4375 16.5 4.3594Q16.5 6.3125 15.766 7.2969Q15.031 8.2812 13.594 8.2812Q12.156 8.2812 11.422 7.2969Q10.688 6.3125 10.688 4.3594Q10.688 2.4375 11.422 1.4375Q12.156 0.45312 13.594 0.45312zM13.594 -0.17188Q12.703 -0.17188 11.953 0.125Q11.203 0.42188 10.641 0.98438Q9.9844 1.6406 9.6562 2.4688Q9.3438 3.3125 9.3438 4.3594Q9.3438 5.4219 9.6562 6.2656Q9.9844 7.0938 10.641 7.75Q11.219 8.3281 11.953 8.6094Q12.688 8.9062 13.594 8.9062Q15.5 8.9062 16.672 7.6562Q17.844 6.4062 17.844 4.3594Q17.844 3.3125 17.516 2.4688Q17.203 1.6406 16.547 0.98438Q15.969 0.40625 15.234 0.125Q14.484 -0.17188 13.594 -0.17188z"))
+	test.T(t, p, MustParseSVG("M2.40625 3.171875L5.609375 3.171875L4.015625 7.328125zM-0.078125 0L-0.078125 0.625L0.703125 0.625L3.8125 8.75L4.796875 8.75L7.921875 0.625L8.78125 0.625L8.78125 0L5.609375 0L5.609375 0.625L6.578125 0.625L5.84375 2.546875L2.15625 2.546875L1.4375 0.625L2.390625 0.625L2.390625 0zM13.59375 0.453125Q15.03125 0.453125 15.765625 1.4375Q16.5 2.4375 16.5 4.359375Q16.5 6.3125 15.765625 7.296875Q15.03125 8.28125 13.59375 8.28125Q12.15625 8.28125 11.421875 7.296875Q10.6875 6.3125 10.6875 4.359375Q10.6875 2.4375 11.421875 1.4375Q12.15625 0.453125 13.59375 0.453125zM13.59375 -0.171875Q12.703125 -0.171875 11.953125 0.125Q11.203125 0.421875 10.640625 0.984375Q9.984375 1.640625 9.65625 2.46875Q9.34375 3.3125 9.34375 4.359375Q9.34375 5.421875 9.65625 6.265625Q9.984375 7.09375 10.640625 7.75Q11.21875 8.328125 11.953125 8.609375Q12.6875 8.90625 13.59375 8.90625Q15.5 8.90625 16.671875 7.65625Q17.84375 6.40625 17.84375 4.359375Q17.84375 3.3125 17.515625 2.46875Q17.203125 1.640625 16.546875 0.984375Q15.96875 0.40625 15.234375 0.125Q14.484375 -0.171875 13.59375 -0.171875z"))
 	test.Float(t, width, 18.515625)
 }
 
@@ -79,3 +87,469 @@ func TestFontDecoration(t *testing.T) {
 	face = family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal, FontSawtoothUnderline)
 	test.T(t, face.Decorate(4.0), MustParseSVG("M0.20564070832143055 -1.9305089057915699L0.7511207083214305 -3.7305089057915697L1.612439291678569 -3.7305089057915697L1.7272599999999998 -3.3516182498947904L1.8420807083214306 -3.7305089057915697L2.703399291678569 -3.7305089057915697L2.8182199999999997 -3.3516182498947904L2.9330407083214305 -3.7305089057915697L3.794359291678569 -3.4694910942084296L3.248879291678569 -1.6694910942084298L2.3875607083214305 -1.6694910942084298L2.2727399999999998 -2.0483817501052095L2.157919291678569 -1.6694910942084298L1.2966007083214306 -1.6694910942084298L1.1817799999999998 -2.0483817501052095L1.066959291678569 -1.6694910942084298z"))
 }
+
+func TestFontFaceHexBox(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	family.SetMissingGlyph(MissingHexBox)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	test.T(t, hexBoxRows('\uE000'), [2]string{"E0", "00"})
+	test.T(t, hexBoxRows('\U0001F600'), [2]string{"01F", "600"})
+
+	_, width := face.ToPath("\U0001F600")
+	test.Float(t, width, face.TextWidth("\U0001F600"))
+	test.Float(t, width, 17.0*face.hexBoxPixel())
+
+	// read back the digits from the rendered path by sampling the pixel centers
+	px := face.hexBoxPixel()
+	p, _ := face.ToPath("A\U0001F600")
+	x0 := face.TextWidth("A") + px
+	rows := [2]string{}
+	for j := 0; j < 2; j++ {
+		for i := 0; i < 3; i++ {
+			bitmap := ""
+			for k := 0; k < 5; k++ {
+				for l := 0; l < 3; l++ {
+					x := x0 + float64(2+4*i+l)*px + px/2.0
+					y := 15.0*px - float64(2+6*j+k)*px - px/2.0
+					if p.Interior(x, y, NonZero) {
+						bitmap += "#"
+					} else {
+						bitmap += "."
+					}
+				}
+			}
+			for d, digit := range hexBoxDigits {
+				if digit == bitmap {
+					rows[j] += string("0123456789ABCDEF"[d])
+				}
+			}
+		}
+	}
+	test.T(t, rows, [2]string{"01F", "600"})
+
+	family.SetMissingGlyph(MissingNotdef)
+	test.Float(t, face.TextWidth("\U0001F600"), face.TextWidth("\uFFFF"))
+}
+
+func TestFontFaceCanRender(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	coverage, missing := face.CanRender("Hello world\n")
+	test.Float(t, coverage, 1.0)
+	test.T(t, len(missing), 0)
+
+	coverage, missing = face.CanRender("日本語 日本")
+	test.Float(t, coverage, 0.0)
+	test.T(t, string(missing), "日本語")
+
+	coverage, _ = face.CanRender("ab日本")
+	test.Float(t, coverage, 0.5)
+
+	coverage, _ = face.CanRender(" \t")
+	test.Float(t, coverage, 1.0)
+}
+
+// addFontTables returns the font with the tables added to its table directory.
+func addFontTables(b []byte, tables map[string][]byte) []byte {
+	numTables := int(binary.BigEndian.Uint16(b[4:]))
+	dir := map[string][]byte{}
+	for i := 0; i < numTables; i++ {
+		rec := b[12+16*i:]
+		offset, length := binary.BigEndian.Uint32(rec[8:]), binary.BigEndian.Uint32(rec[12:])
+		dir[string(rec[:4])] = b[offset : offset+length]
+	}
+	for tag, table := range tables {
+		dir[tag] = table
+	}
+	tags := []string{}
+	for tag := range dir {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	out := make([]byte, 12+16*len(tags))
+	copy(out, b[:12])
+	binary.BigEndian.PutUint16(out[4:], uint16(len(tags)))
+	for i, tag := range tags {
+		rec := out[12+16*i:]
+		copy(rec, tag)
+		binary.BigEndian.PutUint32(rec[8:], uint32(len(out)))
+		binary.BigEndian.PutUint32(rec[12:], uint32(len(dir[tag])))
+		out = append(out, dir[tag]...)
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+	}
+	return out
+}
+
+func TestFontFacePalette(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFont(b, FontRegular)
+	indices := family.Face(12.0, Black, FontRegular, FontNormal).Font.IndicesOf("AB")
+
+	// glyph A has two layers, the outline of A with palette entry 0 and the outline of B with palette entry 1
+	colr := make([]byte, 28)
+	binary.BigEndian.PutUint16(colr[2:], 1)  // numBaseGlyphRecords
+	binary.BigEndian.PutUint32(colr[4:], 14) // baseGlyphRecordsOffset
+	binary.BigEndian.PutUint32(colr[8:], 20) // layerRecordsOffset
+	binary.BigEndian.PutUint16(colr[12:], 2) // numLayerRecords
+	binary.BigEndian.PutUint16(colr[14:], indices[0])
+	binary.BigEndian.PutUint16(colr[18:], 2) // numLayers
+	binary.BigEndian.PutUint16(colr[20:], indices[0])
+	binary.BigEndian.PutUint16(colr[24:], indices[1])
+	binary.BigEndian.PutUint16(colr[26:], 1) // paletteIndex
+
+	cpal := make([]byte, 16)
+	binary.BigEndian.PutUint16(cpal[2:], 2)             // numPaletteEntries
+	binary.BigEndian.PutUint16(cpal[4:], 2)             // numPalettes
+	binary.BigEndian.PutUint16(cpal[6:], 4)             // numColorRecords
+	binary.BigEndian.PutUint32(cpal[8:], 16)            // colorRecordsArrayOffset
+	binary.BigEndian.PutUint16(cpal[14:], 2)            // colorRecordIndices
+	cpal = append(cpal, 0, 0, 255, 255, 0, 255, 0, 255) // BGRA
+	cpal = append(cpal, 255, 0, 0, 255, 0, 255, 255, 255)
+
+	family = NewFontFamily("dejavu-serif-color")
+	test.Error(t, family.LoadFont(addFontTables(b, map[string][]byte{"COLR": colr, "CPAL": cpal}), FontRegular))
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	test.T(t, face.Font.NumPalettes(), 2)
+
+	layerColors := func(face FontFace) []color.RGBA {
+		_, colors := NewTextLine(face, "A", Left).glyphPaths()
+		return colors
+	}
+	red, green := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 255, 0, 255}
+	blue, yellow := color.RGBA{0, 0, 255, 255}, color.RGBA{255, 255, 0, 255}
+	test.T(t, layerColors(face), []color.RGBA{red, green})
+
+	face.Palette = 1
+	test.T(t, layerColors(face), []color.RGBA{blue, yellow})
+
+	face.Palette = 5 // out of range falls back to palette 0
+	test.T(t, layerColors(face), []color.RGBA{red, green})
+
+	_, colors := NewTextLine(face, "BA", Left).glyphPaths()
+	test.T(t, colors, []color.RGBA{Black, red, green}) // B is not a color glyph
+
+	face.Palette = 0
+	face.Color = color.RGBA{0, 0, 0, 128}
+	test.T(t, layerColors(face)[0], color.RGBA{128, 0, 0, 128})
+
+	// the color layers of the fi ligature apply to the substituted glyph
+	ligature := append([]byte{}, colr...)
+	binary.BigEndian.PutUint16(ligature[14:], family.Face(12.0, Black, FontRegular, FontNormal).Font.IndicesOf("\uFB01")[0])
+	family = NewFontFamily("dejavu-serif-color-ligature")
+	test.Error(t, family.LoadFont(addFontTables(b, map[string][]byte{"COLR": ligature, "CPAL": cpal}), FontRegular))
+	face = family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	_, colors = NewTextLine(face, "fi", Left).glyphPaths()
+	test.T(t, colors, []color.RGBA{red, green})
+	face.Font.Use(NoCommonLigatures)
+	_, colors = NewTextLine(face, "fi", Left).glyphPaths()
+	test.T(t, colors, []color.RGBA{Black})
+}
+
+func TestFontFaceItalicAngle(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFont(b, FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	test.Float(t, face.ItalicAngle(), 0.0)
+	test.Float(t, face.slant(), 0.0)
+
+	face = family.Face(12.0*ptPerMm, Black, FontItalic, FontNormal)
+	test.Float(t, face.ItalicAngle(), -16.69924423)
+	test.Float(t, face.slant(), 0.3)
+
+	// italic angle of -12 degrees in the post table
+	table, _ := face.Font.SFNT().Table("post")
+	post := append([]byte{}, table...)
+	angle := int32(-12 * 65536) // Fixed 16.16
+	binary.BigEndian.PutUint32(post[4:], uint32(angle))
+
+	family = NewFontFamily("dejavu-serif-italic")
+	test.Error(t, family.LoadFont(addFontTables(b, map[string][]byte{"post": post}), FontItalic))
+	face = family.Face(12.0*ptPerMm, Black, FontItalic, FontNormal)
+	test.Float(t, face.FauxItalic, 0.0)
+	test.Float(t, face.ItalicAngle(), -12.0)
+	test.Float(t, face.slant(), math.Tan(12.0*math.Pi/180.0))
+}
+
+func TestFontFaceCustomGlyph(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFont(b, FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	wa, wb := face.TextWidth("a"), face.TextWidth("b")
+
+	// a square icon of half an em with a margin of a tenth of an em on either side
+	face.Font.SetCustomGlyph(func(r rune) (*Path, float64, bool) {
+		if r != '\uE000' {
+			return nil, 0.0, false
+		}
+		return Rectangle(0.5, 0.5).Translate(0.1, 0.0), 0.7, true
+	})
+
+	test.Float(t, face.Advance('\uE000'), 0.7*face.Size)
+	test.Float(t, face.TextWidth("a\uE000b"), wa+0.7*face.Size+wb)
+	coverage, _ := face.CanRender("\uE000")
+	test.Float(t, coverage, 1.0)
+
+	p, advance := face.ToPath("\uE000")
+	test.Float(t, advance, 0.7*face.Size)
+	test.T(t, p.Bounds(), Rect{0.1 * face.Size, 0.0, 0.5 * face.Size, 0.5 * face.Size})
+
+	// laid out inline between the glyphs
+	text := NewTextLine(face, "a\uE000b", Left)
+	positions := text.lines[0].spans[0].GlyphPositions()
+	test.Float(t, positions[1], wa)
+	test.Float(t, positions[2], wa+0.7*face.Size)
+	paths, _ := text.glyphPaths()
+	found := false
+	for _, q := range paths[0].Split() {
+		if q.Bounds().Equals(Rect{wa + 0.1*face.Size, text.lines[0].y, 0.5 * face.Size, 0.5 * face.Size}) {
+			found = true
+		}
+	}
+	test.That(t, found, "icon must be drawn after the first glyph")
+}
+
+func TestFontFaceResolveFace(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFont(b, FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	// a second font for CJK characters
+	cjk := NewFontFamily("cjk")
+	cjk.LoadFont(b, FontRegular)
+	cjkFace := cjk.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	cjkFace.Font.SetCustomGlyph(func(r rune) (*Path, float64, bool) {
+		if r != '中' {
+			return nil, 0.0, false
+		}
+		return Rectangle(0.9, 0.9), 1.0, true
+	})
+
+	coverage, _ := face.CanRender("中")
+	test.Float(t, coverage, 0.0)
+	resolved, ok := face.ResolveFace('中')
+	test.That(t, !ok, "no fallbacks must not cover the CJK character")
+	test.That(t, resolved.Font == face.Font)
+
+	face.Fallbacks = []*Font{cjkFace.Font}
+	resolved, ok = face.ResolveFace('a')
+	test.That(t, ok)
+	test.That(t, resolved.Font == face.Font, "primary face must render a")
+	resolved, ok = face.ResolveFace('中')
+	test.That(t, ok)
+	test.That(t, resolved.Font == cjkFace.Font, "CJK character must resolve to the second face")
+	test.Float(t, resolved.Size, face.Size)
+	_, ok = face.ResolveFace('\U0001F600')
+	test.That(t, !ok)
+
+	// combining marks stay in the face of their base character
+	text := NewTextLine(face, "a中\u0301 b", Left)
+	spans := text.lines[0].spans
+	test.T(t, len(spans), 3)
+	test.String(t, spans[0].Text, "a")
+	test.String(t, spans[1].Text, "中\u0301 ")
+	test.That(t, spans[1].Face.Font == cjkFace.Font)
+	test.String(t, spans[2].Text, "b")
+	test.That(t, text.fonts[cjkFace.Font], "fallback font must be used by the text")
+
+	rt := NewRichText()
+	rt.Add(face, "中a")
+	spans = rt.ToText(100.0, 100.0, Left, Top, 0.0, 0.0).lines[0].spans
+	test.T(t, len(spans), 2)
+	test.That(t, spans[0].Face.Font == cjkFace.Font)
+	test.That(t, spans[1].Face.Font == face.Font)
+
+	// single lines have the same layout as text boxes
+	spans = face.SingleLine("a中").lines[0].spans
+	test.T(t, len(spans), 2)
+	test.That(t, spans[0].Face.Font == face.Font)
+	test.That(t, spans[1].Face.Font == cjkFace.Font)
+}
+
+func TestFontFaceMissingAdvance(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFont(b, FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	wa, wb, notdef := face.TextWidth("a"), face.TextWidth("b"), face.TextWidth("\U0001F601")
+
+	// reserve one and a half em for an emoji image
+	face.Font.SetMissingAdvance(func(r rune) (float64, bool) {
+		return 1.5, r == '\U0001F600'
+	})
+	test.Float(t, face.Advance('\U0001F600'), 1.5*face.Size)
+	test.Float(t, face.TextWidth("a\U0001F600b"), wa+1.5*face.Size+wb)
+	test.Float(t, face.TextWidth("\U0001F601"), notdef)
+	coverage, _ := face.CanRender("\U0001F600")
+	test.Float(t, coverage, 0.0)
+
+	p, advance := face.ToPath("\U0001F600")
+	test.Float(t, advance, 1.5*face.Size)
+	test.That(t, p.Empty(), "missing glyph must not be drawn")
+
+	// the layout reserves the width, also when wrapping
+	text := NewTextBox(face, "a\U0001F600b a", wa+1.5*face.Size+wb+Epsilon, 0.0, Left, Top, 0.0, 0.0)
+	test.T(t, len(text.lines), 2)
+	positions := text.lines[0].spans[0].GlyphPositions()
+	test.Float(t, positions[2], wa+1.5*face.Size)
+}
+
+func TestFontFaceAdvance(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	for _, r := range "AVa i." {
+		// TextWidth rounds to 1/64
+		test.That(t, math.Abs(face.Advance(r)-face.TextWidth(string(r))) <= 1.0/64.0, string(r))
+	}
+	test.Float(t, face.Advance('A'), 1479.0*12.0/2048.0)
+	test.Float(t, face.Advance('\uFFFF'), 1229.0*12.0/2048.0) // missing glyph
+}
+
+func TestFontHighlight(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	marker := color.RGBA{128, 128, 0, 128}
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal, FontHighlight(marker, 42), FontUnderline)
+
+	// deterministic for the seed
+	paths, colors := face.highlights(50.0)
+	again, _ := face.highlights(50.0)
+	test.T(t, colors, []color.RGBA{marker})
+	test.T(t, paths[0], again[0])
+	bounds := paths[0].Bounds()
+	test.That(t, bounds.X < 0.0 && 50.0 < bounds.X+bounds.W, "highlight must extend beyond the text")
+	test.That(t, bounds.Y < 0.0 && face.Metrics().XHeight < bounds.Y+bounds.H, "highlight must cover the x-height")
+	other, _ := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal, FontHighlight(marker, 7)).highlights(50.0)
+	test.That(t, !paths[0].Equals(other[0]), "highlight must depend on the seed")
+	test.T(t, face.Decorate(50.0), family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal, FontUnderline).Decorate(50.0))
+
+	// the highlight is drawn behind the underline and the glyphs, once for each line
+	text := NewTextBox(face, "highlight across lines", 100.0, 0.0, Left, Top, 0.0, 0.0)
+	test.T(t, len(text.lines), 2)
+	c := New(100.0, 100.0)
+	RenderTextAsPath(c, text, Identity)
+	dl := c.DisplayList()
+	for i, cmd := range dl {
+		col := cmd.(DrawPathCommand).Style.FillColor
+		if i < 2 {
+			test.T(t, col, marker)
+		} else {
+			test.T(t, col, Black)
+		}
+	}
+	test.That(t, dl[0].(DrawPathCommand).Path.Bounds().Y > dl[1].(DrawPathCommand).Path.Bounds().Y, "one highlight per line")
+}
+
+func TestFontFaceFeatures(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0, Black, FontRegular, FontNormal)
+	test.That(t, !face.Font.HasFeature("smcp"), "DejaVu Serif must not have small capitals")
+	test.That(t, face.Font.HasFeature("liga"))
+
+	spans := func(text *Text) ([]string, []float64) {
+		texts, scales := []string{}, []float64{}
+		text.WalkSpans(func(_, _ float64, span TextSpan) {
+			texts = append(texts, span.Text)
+			scales = append(scales, span.Face.Scale)
+		})
+		return texts, scales
+	}
+
+	face.Features = map[string]FallbackPolicy{"smcp": FallbackSynthesize}
+	for _, text := range []*Text{NewTextLine(face, "Hello", Left), NewTextBox(face, "Hello", 0.0, 0.0, Left, Top, 0.0, 0.0)} {
+		test.Error(t, text.Err())
+		texts, scales := spans(text)
+		test.T(t, texts, []string{"H", "ELLO"})
+		test.Float(t, scales[0], 1.0)
+		test.That(t, 0.6 < scales[1] && scales[1] < 0.8, "small capitals must be scaled to the x-height:", scales[1])
+	}
+	test.That(t, face.TextWidth("HELLO") > NewTextLine(face, "Hello", Left).Bounds().W, "small capitals must be narrower than capitals")
+
+	face.Features = map[string]FallbackPolicy{"frac": FallbackSynthesize}
+	text := NewTextLine(face, "a 1/2", Left)
+	texts, scales := spans(text)
+	test.T(t, texts, []string{"a ", "1", "⁄", "2"})
+	test.T(t, scales, []float64{1.0, 0.6, 1.0, 0.6})
+	var voffsets []float64
+	text.WalkSpans(func(_, _ float64, span TextSpan) {
+		voffsets = append(voffsets, span.Face.Voffset)
+	})
+	test.That(t, 0.0 < voffsets[1] && voffsets[3] == 0.0, "numerator must be raised")
+
+	face.Features = map[string]FallbackPolicy{"smcp": FallbackIgnore}
+	texts, _ = spans(NewTextLine(face, "Hello", Left))
+	test.T(t, texts, []string{"Hello"})
+
+	face.Features = map[string]FallbackPolicy{"swsh": FallbackError}
+	test.That(t, errors.Is(NewTextLine(face, "Hello", Left).Err(), ErrMissingFeature))
+	test.That(t, errors.Is(NewRichText().Add(face, "Hello").Err(), ErrMissingFeature))
+	face.Features = map[string]FallbackPolicy{"swsh": FallbackSynthesize}
+	test.That(t, errors.Is(NewTextBox(face, "Hello", 0.0, 0.0, Left, Top, 0.0, 0.0).Err(), ErrMissingFeature), "features without fallback cannot be synthesized")
+	face.Features = map[string]FallbackPolicy{"liga": FallbackError}
+	test.Error(t, NewTextLine(face, "Hello", Left).Err())
+
+	// synthesized features are measured
+	SetTextMeasureCacheSize(100)
+	defer SetTextMeasureCacheSize(0)
+	plain := face
+	plain.Features = nil
+	face.Features = map[string]FallbackPolicy{"smcp": FallbackSynthesize}
+	test.Float(t, face.TextWidth("Hello"), NewTextLine(face, "Hello", Left).Bounds().W)
+	test.Float(t, face.MeasureInto("Hello", &MeasureBuffer{}), face.TextWidth("Hello"))
+	test.That(t, face.TextWidth("Hello") != plain.TextWidth("Hello"), "cached widths must depend on the features")
+}
+
+func TestFontFaceFeaturesSubstitution(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0, Black, FontRegular, FontNormal)
+
+	// a font with small capitals as single substitutions, where the capitals stand in for the small capitals
+	buffer := &sfnt.Buffer{}
+	substitutions := map[uint16]uint16{}
+	for r := 'a'; r <= 'z'; r++ {
+		lower, _ := face.Font.sfnt.GlyphIndex(buffer, r)
+		upper, _ := face.Font.sfnt.GlyphIndex(buffer, unicode.ToUpper(r))
+		substitutions[uint16(lower)] = uint16(upper)
+	}
+	face.Font.features["smcp"] = true
+	face.Font.substitutions["smcp"] = substitutions
+
+	plain := face
+	face.Features = map[string]FallbackPolicy{"smcp": FallbackSynthesize}
+	test.T(t, face.IndicesOf("Hello"), plain.IndicesOf("HELLO"))
+	test.Float(t, face.TextWidth("Hello"), plain.TextWidth("HELLO"))
+	test.Float(t, face.Advance('e'), plain.Advance('E'))
+	p, width := face.ToPath("Hello")
+	q, _ := plain.ToPath("HELLO")
+	test.T(t, p, q)
+
+	text := NewTextLine(face, "Hello", Left)
+	test.Error(t, text.Err())
+	test.Float(t, text.Bounds().W, width)
+	text.WalkSpans(func(_, _ float64, span TextSpan) {
+		test.T(t, span.Text, "Hello", "the font applies the feature")
+	})
+}
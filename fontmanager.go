@@ -0,0 +1,196 @@
+package canvas
+
+import (
+	"fmt"
+	"image/color"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FontManager holds a collection of font families loaded from files, from which font faces can be selected by family name, weight and slant similar to how browsers match fonts.
+type FontManager struct {
+	families map[string]*FontFamily
+	names    []string                 // family names in the order of loading
+	fonts    map[string][]managedFont // fonts of each family by their exact weight and slant
+}
+
+// managedFont is a font of a FontManager with its weight class and slant.
+type managedFont struct {
+	font   *Font
+	weight int
+	italic bool
+}
+
+// NewFontManager returns a new FontManager.
+func NewFontManager() *FontManager {
+	return &FontManager{
+		families: map[string]*FontFamily{},
+		fonts:    map[string][]managedFont{},
+	}
+}
+
+// LoadDir loads all fonts of type TTF, OTF, WOFF and WOFF2 in the directory (not recursively). Fonts are grouped into families by the family name of their name table, and their weight and slant are read from the OS/2 table. When two fonts have the same family, weight and slant, the last one loaded is used. Fonts of different weights that round to the same FontStyle, such as 350 and 400, are all matched by their weight, but only the last one loaded is in the FontFamily.
+func (m *FontManager) LoadDir(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		switch strings.ToLower(filepath.Ext(file.Name())) {
+		case ".ttf", ".otf", ".woff", ".woff2":
+			if file.IsDir() {
+				continue
+			}
+			if err := m.LoadFontFile(filepath.Join(dir, file.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// LoadFontFile loads a font from a file.
+func (m *FontManager) LoadFontFile(filename string) error {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("failed to load font file '%s': %w", filename, err)
+	}
+	if err := m.LoadFont(b); err != nil {
+		return fmt.Errorf("failed to load font file '%s': %w", filename, err)
+	}
+	return nil
+}
+
+// LoadFont loads a font from memory.
+func (m *FontManager) LoadFont(b []byte) error {
+	font, err := parseFont("", b)
+	if err != nil {
+		return err
+	}
+
+	name := font.SFNT().FamilyName()
+	if name == "" {
+		return fmt.Errorf("font has no family name")
+	}
+	font.name = name
+
+	key := strings.ToLower(name)
+	family, ok := m.families[key]
+	if !ok {
+		family = NewFontFamily(name)
+		m.families[key] = family
+		m.names = append(m.names, name)
+	}
+
+	weight, italic := font.SFNT().Weight(), font.SFNT().Italic()
+	style := weightStyle(weight)
+	if italic {
+		style |= FontItalic
+	}
+	family.addFont(font, style)
+
+	fonts := m.fonts[key]
+	for i, f := range fonts {
+		if f.weight == weight && f.italic == italic {
+			fonts = append(fonts[:i], fonts[i+1:]...)
+			break
+		}
+	}
+	m.fonts[key] = append(fonts, managedFont{font, weight, italic})
+	return nil
+}
+
+// Families returns the names of all loaded font families.
+func (m *FontManager) Families() []string {
+	return m.names
+}
+
+// Family returns the font family by name (case-insensitive), or nil if it does not exist.
+func (m *FontManager) Family(name string) *FontFamily {
+	return m.families[strings.ToLower(name)]
+}
+
+// Match returns the font face of the given family (case-insensitive) that matches the weight (100 to 900, where 400 is regular and 700 is bold) and slant best, using font size (in pt) and color. It follows the CSS font matching algorithm: fonts of the requested slant are preferred, and if the weight is not available the nearest weight is chosen, favouring lighter weights for weights below 400, heavier weights for weights above 500, and for weights from 400 to 500 first heavier weights up to 500, then lighter and finally heavier weights. When no italic font is available, faux italic is used.
+func (m *FontManager) Match(name string, weight int, italic bool, size float64, col color.Color) (FontFace, error) {
+	family := m.Family(name)
+	if family == nil || len(family.fonts) == 0 {
+		return FontFace{}, fmt.Errorf("font family '%s' not found", name)
+	}
+
+	fonts := []managedFont{}
+	for _, f := range m.fonts[strings.ToLower(name)] {
+		if f.italic == italic {
+			fonts = append(fonts, f)
+		}
+	}
+	if len(fonts) == 0 {
+		fonts = append(fonts, m.fonts[strings.ToLower(name)]...)
+	}
+	sort.SliceStable(fonts, func(i, j int) bool {
+		return weightPreferred(weight, fonts[i].weight, fonts[j].weight)
+	})
+
+	// the font face is of the matched font, also when another font of the family has the same style
+	best := fonts[0]
+	style := weightStyle(best.weight)
+	if best.italic {
+		style |= FontItalic
+	}
+	face := family.Face(size, col, style, FontNormal)
+	face.Font = best.font
+	if italic && !best.italic {
+		face.Style |= FontItalic
+		face.FauxItalic = 0.3
+	}
+	return face, nil
+}
+
+// weightPreferred returns true if weight a is a better match than weight b for the desired weight, according to CSS font matching.
+func weightPreferred(desired, a, b int) bool {
+	rank := func(w int) int {
+		// lower is better, weights in the preferred direction are ranked before the other direction
+		if 400 <= desired && desired <= 500 {
+			if desired <= w && w <= 500 {
+				return w - desired // heavier weights up to 500 first
+			} else if w < desired {
+				return 1000 + desired - w
+			}
+			return 2000 + w - desired
+		} else if desired < 400 {
+			if w <= desired {
+				return desired - w
+			}
+			return 1000 + w - desired
+		}
+		if desired <= w {
+			return w - desired
+		}
+		return 1000 + desired - w
+	}
+	return rank(a) < rank(b)
+}
+
+// weightStyle returns the font style of a weight class, rounded to the nearest hundred.
+func weightStyle(weight int) FontStyle {
+	switch (weight + 50) / 100 {
+	case 0, 1:
+		return FontExtraLight
+	case 2:
+		return FontLight
+	case 3:
+		return FontBook
+	case 4:
+		return FontRegular
+	case 5:
+		return FontMedium
+	case 6:
+		return FontSemibold
+	case 7:
+		return FontBold
+	case 8:
+		return FontBlack
+	}
+	return FontExtraBlack
+}
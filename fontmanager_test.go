@@ -0,0 +1,82 @@
+package canvas
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestFontManager(t *testing.T) {
+	m := NewFontManager()
+	test.Error(t, m.LoadDir("font"))
+	test.T(t, m.Families(), []string{"DejaVu Serif", "EB Garamond"})
+
+	face, err := m.Match("dejavu serif", 700, true, 12.0, Black)
+	test.Error(t, err)
+	test.String(t, face.Font.Name(), "DejaVu Serif")
+	test.T(t, face.Style, FontItalic) // nearest weight is regular, with faux italic
+	test.Float(t, face.FauxItalic, 0.3)
+	test.Float(t, face.Size, 12.0*mmPerPt)
+
+	face, err = m.Match("EB Garamond", 300, false, 10.0, Red)
+	test.Error(t, err)
+	test.String(t, face.Font.Name(), "EB Garamond")
+	test.T(t, face.Style, FontRegular)
+	test.Float(t, face.FauxItalic, 0.0)
+	test.T(t, face.Color, Red)
+
+	_, err = m.Match("Roboto", 700, true, 12.0, Black)
+	test.That(t, err != nil, "must fail for missing family")
+
+	// a light font with the same FontStyle as the regular font
+	regular := m.fonts["dejavu serif"][0].font
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+	light, err := parseFont("DejaVu Serif", b)
+	test.Error(t, err)
+	m.fonts["dejavu serif"] = append(m.fonts["dejavu serif"], managedFont{light, 350, false})
+
+	face, err = m.Match("DejaVu Serif", 300, false, 12.0, Black)
+	test.Error(t, err)
+	test.That(t, face.Font == light, "must match the light font")
+	test.T(t, face.Style, FontRegular)
+	test.Float(t, face.FauxBold, 0.0)
+
+	face, err = m.Match("DejaVu Serif", 450, false, 12.0, Black)
+	test.Error(t, err)
+	test.That(t, face.Font == regular, "must match the regular font")
+}
+
+func TestFontManagerWeights(t *testing.T) {
+	var tts = []struct {
+		desired int
+		weights []int
+		best    int
+	}{
+		{400, []int{300, 500, 700}, 500},
+		{400, []int{300, 700}, 300},
+		{500, []int{400, 600}, 400},
+		{500, []int{300, 600}, 300},
+		{300, []int{200, 400}, 200},
+		{300, []int{400, 500}, 400},
+		{700, []int{600, 900}, 900},
+		{700, []int{400, 600}, 600},
+		{700, []int{400, 700}, 700},
+		{450, []int{400, 600}, 400},
+		{450, []int{480, 400}, 480},
+		{450, []int{500, 420}, 500},
+	}
+	for _, tt := range tts {
+		best := tt.weights[0]
+		for _, w := range tt.weights[1:] {
+			if weightPreferred(tt.desired, w, best) {
+				best = w
+			}
+		}
+		test.T(t, best, tt.best, tt.desired, tt.weights)
+	}
+	test.T(t, weightStyle(700), FontBold)
+	test.T(t, weightStyle(350), FontRegular)
+	test.T(t, weightStyle(100), FontExtraLight)
+}
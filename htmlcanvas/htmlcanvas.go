@@ -60,6 +60,27 @@ func (r *htmlCanvas) RenderPath(path *canvas.Path, style canvas.Style, m canvas.
 		r.ctx.Call("closePath")
 	})
 
+	if style.CompositeOp != canvas.SourceOver {
+		// the composite operation of the HTML canvas applies to the entire canvas, so that operators that affect the canvas outside of the path are clipped to the path, the state is restored afterwards so that the next paths, texts and images are drawn with source-over
+		r.ctx.Call("save")
+		op := style.CompositeOp.String()
+		if style.CompositeOp == canvas.Source {
+			op = "source-over" // copy clears the canvas outside of the path too
+		} else if style.CompositeOp == canvas.DestinationIn {
+			r.ctx.Call("clip")
+		} else if style.CompositeOp == canvas.Clear {
+			// erase regardless of the alpha of the colors
+			op = "destination-out"
+			if style.FillColor.A != 0 {
+				style.FillColor = canvas.Black
+			}
+			if style.StrokeColor.A != 0 {
+				style.StrokeColor = canvas.Black
+			}
+		}
+		r.ctx.Set("globalCompositeOperation", op)
+		defer r.ctx.Call("restore")
+	}
 	if style.FillColor.A != 0 {
 		if style.FillColor != r.style.FillColor {
 			r.ctx.Set("fillStyle", canvas.CSSColor(style.FillColor).String())
@@ -123,7 +144,10 @@ func (r *htmlCanvas) RenderPath(path *canvas.Path, style canvas.Style, m canvas.
 		}
 		r.ctx.Call("stroke")
 	}
-	r.style = style
+	if style.CompositeOp == canvas.SourceOver {
+		// otherwise the style set within save and restore is discarded
+		r.style = style
+	}
 }
 
 func (r *htmlCanvas) RenderText(text *canvas.Text, m canvas.Matrix) {
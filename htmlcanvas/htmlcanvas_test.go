@@ -0,0 +1,69 @@
+//go:build js
+// +build js
+
+package htmlcanvas
+
+import (
+	"image/color"
+	"syscall/js"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+// newContext returns a fake HTML canvas element of which the 2D context records the names of the called methods, and saves and restores its composite operation.
+func newContext(calls *[]string) js.Value {
+	ctx := js.Global().Get("Object").New()
+	ctx.Set("globalCompositeOperation", "source-over")
+	stack := []js.Value{}
+	for _, name := range []string{"clearRect", "beginPath", "moveTo", "lineTo", "quadraticCurveTo", "bezierCurveTo", "closePath", "fill", "stroke", "clip", "save", "restore", "setLineDash"} {
+		name := name
+		ctx.Set(name, js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			*calls = append(*calls, name)
+			if name == "save" {
+				stack = append(stack, this.Get("globalCompositeOperation"))
+			} else if name == "restore" {
+				this.Set("globalCompositeOperation", stack[len(stack)-1])
+				stack = stack[:len(stack)-1]
+			}
+			return nil
+		}))
+	}
+
+	c := js.Global().Get("Object").New()
+	c.Set("getContext", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		return ctx
+	}))
+	return c
+}
+
+func TestRenderPathCompositeOp(t *testing.T) {
+	calls := []string{}
+	c := newContext(&calls)
+	r := New(c, 10.0, 10.0, 1.0)
+	ctx := c.Call("getContext", "2d")
+
+	style := canvas.DefaultStyle
+	style.CompositeOp = canvas.DestinationOut
+	r.RenderPath(canvas.Rectangle(5.0, 5.0), style, canvas.Identity)
+	test.String(t, ctx.Get("globalCompositeOperation").String(), "source-over")
+	test.T(t, calls[len(calls)-1], "restore")
+
+	// destination-in is clipped to the path
+	calls = calls[:0]
+	style.CompositeOp = canvas.DestinationIn
+	r.RenderPath(canvas.Rectangle(5.0, 5.0), style, canvas.Identity)
+	test.T(t, calls[len(calls)-4:], []string{"save", "clip", "fill", "restore"})
+	test.String(t, ctx.Get("globalCompositeOperation").String(), "source-over")
+
+	// clear erases with an opaque color
+	style.CompositeOp = canvas.SourceOver
+	style.FillColor = canvas.Red
+	r.RenderPath(canvas.Rectangle(5.0, 5.0), style, canvas.Identity)
+	style.CompositeOp = canvas.Clear
+	style.FillColor = color.RGBA{0, 0, 64, 64}
+	r.RenderPath(canvas.Rectangle(5.0, 5.0), style, canvas.Identity)
+	test.String(t, ctx.Get("fillStyle").String(), canvas.CSSColor(canvas.Black).String())
+	test.String(t, ctx.Get("globalCompositeOperation").String(), "source-over")
+}
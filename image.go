@@ -2,10 +2,14 @@ package canvas
 
 import (
 	"bytes"
+	"encoding/binary"
+	"fmt"
 	"image"
+	"image/color"
 	"image/jpeg"
 	"image/png"
 	"io"
+	"io/ioutil"
 )
 
 // JPEGImage gives access to the raw bytes
@@ -69,3 +73,120 @@ func newBufferedImage(decode func(io.Reader) (image.Image, error), r io.Reader)
 		bytes: buffer.Bytes(),
 	}, err
 }
+
+// NewCMYKImage parses a CMYK JPEG or TIFF image. JPEG images keep their raw bytes and are returned as a JPEGImage, TIFF images are returned as an *image.CMYK. Renderers that support CMYK such as PDF embed these images without conversion to RGB, other renderers convert them to RGB.
+func NewCMYKImage(r io.Reader) (image.Image, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var img image.Image
+	if 2 <= len(b) && b[0] == 0xFF && b[1] == 0xD8 {
+		img, err = NewJPEGImage(bytes.NewReader(b))
+	} else if 4 <= len(b) && (string(b[:4]) == "II*\x00" || string(b[:4]) == "MM\x00*") {
+		img, err = decodeCMYKTIFF(b)
+	} else {
+		return nil, fmt.Errorf("unknown CMYK image format")
+	}
+	if err != nil {
+		return nil, err
+	} else if img.ColorModel() != color.CMYKModel {
+		return nil, fmt.Errorf("image is not in the CMYK color space")
+	}
+	return img, nil
+}
+
+// decodeCMYKTIFF decodes an uncompressed 8-bit CMYK TIFF image with interleaved samples, which is the common format for separated prepress images.
+func decodeCMYKTIFF(b []byte) (*image.CMYK, error) {
+	if len(b) < 8 {
+		return nil, fmt.Errorf("invalid TIFF image")
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if b[0] == 'M' {
+		order = binary.BigEndian
+	}
+	offset := int(order.Uint32(b[4:]))
+	if len(b) < offset+2 {
+		return nil, fmt.Errorf("invalid TIFF image")
+	}
+
+	var width, height int
+	bitsPerSample, compression, photometric, samplesPerPixel, planar := 8, 1, -1, 1, 1
+	var stripOffsets, stripByteCounts []int
+	n := int(order.Uint16(b[offset:]))
+	if len(b) < offset+2+12*n {
+		return nil, fmt.Errorf("invalid TIFF image")
+	}
+	for i := 0; i < n; i++ {
+		entry := b[offset+2+12*i:]
+		tag, typ, count := order.Uint16(entry), order.Uint16(entry[2:]), int(order.Uint32(entry[4:]))
+		size := 4 // LONG
+		if typ == 3 {
+			size = 2 // SHORT
+		} else if typ != 4 {
+			continue
+		}
+		data := entry[8:12]
+		if 4 < size*count {
+			pos := int(order.Uint32(data))
+			if pos < 0 || len(b) < pos+size*count {
+				return nil, fmt.Errorf("invalid TIFF image")
+			}
+			data = b[pos:]
+		}
+		values := make([]int, count)
+		for j := range values {
+			if size == 2 {
+				values[j] = int(order.Uint16(data[2*j:]))
+			} else {
+				values[j] = int(order.Uint32(data[4*j:]))
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		switch tag {
+		case 256:
+			width = values[0]
+		case 257:
+			height = values[0]
+		case 258:
+			bitsPerSample = values[0]
+		case 259:
+			compression = values[0]
+		case 262:
+			photometric = values[0]
+		case 273:
+			stripOffsets = values
+		case 277:
+			samplesPerPixel = values[0]
+		case 279:
+			stripByteCounts = values
+		case 284:
+			planar = values[0]
+		}
+	}
+	if photometric != 5 || samplesPerPixel != 4 {
+		return nil, fmt.Errorf("TIFF image is not in the CMYK color space")
+	} else if bitsPerSample != 8 || compression != 1 || planar != 1 {
+		return nil, fmt.Errorf("unsupported TIFF image: only uncompressed 8-bit interleaved CMYK images are supported")
+	} else if width <= 0 || height <= 0 || len(stripOffsets) != len(stripByteCounts) {
+		return nil, fmt.Errorf("invalid TIFF image")
+	}
+
+	img := image.NewCMYK(image.Rect(0, 0, width, height))
+	pix := img.Pix[:0]
+	for i, stripOffset := range stripOffsets {
+		if stripOffset < 0 || len(b) < stripOffset+stripByteCounts[i] {
+			return nil, fmt.Errorf("invalid TIFF image")
+		}
+		pix = append(pix, b[stripOffset:stripOffset+stripByteCounts[i]]...)
+	}
+	if len(pix) < len(img.Pix) {
+		return nil, fmt.Errorf("invalid TIFF image: %d bytes of image data while %d are expected", len(pix), len(img.Pix))
+	}
+	img.Pix = pix[:len(img.Pix)]
+	return img, nil
+}
@@ -0,0 +1,66 @@
+package canvas
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func cmykTIFF(width, height int, pix []byte) []byte {
+	entries := [][3]uint32{
+		{256, 4, uint32(width)},  // ImageWidth
+		{257, 4, uint32(height)}, // ImageLength
+		{258, 3, 8},              // BitsPerSample
+		{259, 3, 1},              // Compression
+		{262, 3, 5},              // PhotometricInterpretation
+		{273, 4, 0},              // StripOffsets, set below
+		{277, 3, 4},              // SamplesPerPixel
+		{279, 4, uint32(len(pix))},
+	}
+	entries[5][2] = uint32(8 + 2 + 12*len(entries) + 4)
+
+	buf := &bytes.Buffer{}
+	buf.WriteString("II*\x00")
+	binary.Write(buf, binary.LittleEndian, uint32(8))
+	binary.Write(buf, binary.LittleEndian, uint16(len(entries)))
+	for _, entry := range entries {
+		binary.Write(buf, binary.LittleEndian, uint16(entry[0]))
+		binary.Write(buf, binary.LittleEndian, uint16(entry[1]))
+		binary.Write(buf, binary.LittleEndian, uint32(1))
+		if entry[1] == 3 {
+			binary.Write(buf, binary.LittleEndian, uint16(entry[2]))
+			binary.Write(buf, binary.LittleEndian, uint16(0))
+		} else {
+			binary.Write(buf, binary.LittleEndian, entry[2])
+		}
+	}
+	binary.Write(buf, binary.LittleEndian, uint32(0)) // no next IFD
+	buf.Write(pix)
+	return buf.Bytes()
+}
+
+func TestCMYKImage(t *testing.T) {
+	pix := []byte{255, 0, 0, 0, 0, 0, 0, 255}
+	img, err := NewCMYKImage(bytes.NewReader(cmykTIFF(2, 1, pix)))
+	test.Error(t, err)
+	test.T(t, img.Bounds(), image.Rect(0, 0, 2, 1))
+	test.T(t, img.(*image.CMYK).Pix, pix)
+
+	R, G, B, _ := img.At(0, 0).RGBA()
+	test.T(t, []uint32{R >> 8, G >> 8, B >> 8}, []uint32{0, 255, 255}) // cyan
+	R, G, B, _ = img.At(1, 0).RGBA()
+	test.T(t, []uint32{R >> 8, G >> 8, B >> 8}, []uint32{0, 0, 0}) // black
+
+	c := New(10.0, 10.0)
+	ctx := NewContext(c)
+	test.Error(t, ctx.DrawCMYKImage(cmykTIFF(2, 1, pix), Identity.Scale(5.0, 5.0)))
+	test.That(t, !c.Empty(), "image must be drawn")
+
+	_, err = NewCMYKImage(bytes.NewReader(cmykTIFF(2, 2, pix)))
+	test.That(t, err != nil, "must fail on missing image data")
+	_, err = NewCMYKImage(bytes.NewReader([]byte("GIF89a")))
+	test.That(t, err != nil, "must fail on unknown format")
+}
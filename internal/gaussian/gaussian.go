@@ -0,0 +1,55 @@
+// Package gaussian implements the Gaussian blur of the masks of soft shadows for the renderers that rasterize them, see canvas.ShadowRenderer.
+package gaussian
+
+import (
+	"image"
+	"math"
+)
+
+// Blur blurs the mask in place with a Gaussian kernel with a standard deviation of sigma pixels, where pixels outside of the mask are transparent.
+func Blur(mask *image.Alpha, sigma float64) {
+	if sigma <= 0.0 {
+		return
+	}
+	radius := int(math.Ceil(3.0 * sigma))
+	kernel := make([]float64, 2*radius+1)
+	sum := 0.0
+	for i := range kernel {
+		x := float64(i - radius)
+		kernel[i] = math.Exp(-x * x / (2.0 * sigma * sigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+
+	// the kernel is separable, blur horizontally and then vertically
+	w, h := mask.Rect.Dx(), mask.Rect.Dy()
+	tmp := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			k := radius - x // first kernel index inside of the mask
+			if k < 0 {
+				k = 0
+			}
+			v := 0.0
+			for ; k < len(kernel) && x+k-radius < w; k++ {
+				v += kernel[k] * float64(mask.Pix[y*mask.Stride+x+k-radius])
+			}
+			tmp[y*w+x] = v
+		}
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			k := radius - y
+			if k < 0 {
+				k = 0
+			}
+			v := 0.0
+			for ; k < len(kernel) && y+k-radius < h; k++ {
+				v += kernel[k] * tmp[(y+k-radius)*w+x]
+			}
+			mask.Pix[y*mask.Stride+x] = uint8(math.Min(v+0.5, 255.0))
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package gaussian
+
+import (
+	"image"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestBlur(t *testing.T) {
+	mask := image.NewAlpha(image.Rect(0, 0, 9, 9))
+	mask.Pix[40] = 255
+	Blur(mask, 1.0)
+	row := mask.Pix[36:45]
+	test.That(t, row[4] < 255, "center must be blurred")
+	test.That(t, row[4] > row[3] && row[3] > row[2], "blur must decrease away from the center")
+	for i := 0; i < 4; i++ {
+		test.T(t, row[i], row[8-i], i)
+		test.T(t, mask.Pix[9*i+4], row[i], i) // separable in both directions
+	}
+	test.T(t, mask.Pix[0], uint8(0)) // beyond three standard deviations
+
+	sum := 0
+	for _, v := range mask.Pix {
+		sum += int(v)
+	}
+	test.That(t, 240 <= sum && sum <= 270, "blur must preserve the coverage")
+
+	// zero sigma does not blur
+	mask = image.NewAlpha(image.Rect(0, 0, 3, 3))
+	mask.Pix[4] = 255
+	Blur(mask, 0.0)
+	test.T(t, mask.Pix[4], uint8(255))
+}
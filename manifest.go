@@ -0,0 +1,66 @@
+package canvas
+
+import (
+	"image"
+	"sort"
+)
+
+// RenderManifest is a summary of the contents of a canvas, such as to audit the fonts and images of rendered assets, see Canvas.Manifest.
+type RenderManifest struct {
+	Fonts  []ManifestFont
+	Images []image.Point // size in pixels of each image, in drawing order
+	Paths  int           // number of paths, including shadows
+	Texts  int           // number of texts
+	Bounds Rect          // bounding box of all drawing operations in mm, including strokes and shadows
+}
+
+// ManifestFont is a font that is used by the texts of a canvas, see RenderManifest.
+type ManifestFont struct {
+	Name   string // name the font was loaded with
+	Family string // typographic family name from the name table
+	Style  string // typographic subfamily name from the name table, such as "Bold Italic"
+	Glyphs int    // number of distinct characters that are used
+}
+
+// Manifest returns a summary of the fonts, images, paths and texts drawn on the canvas, and the bounding box of all drawing operations. Fonts are sorted by name and include the fonts of all text spans, images are listed in drawing order. This allows to catch accidental fonts, oversized images or large numbers of paths before rendering.
+func (c *Canvas) Manifest() RenderManifest {
+	manifest := RenderManifest{
+		Fonts:  []ManifestFont{},
+		Images: []image.Point{},
+	}
+	glyphs := map[*Font]map[rune]bool{}
+	for _, l := range c.layers {
+		if l.path != nil {
+			manifest.Paths++
+		} else if l.text != nil {
+			manifest.Texts++
+			l.text.WalkSpans(func(_, _ float64, span TextSpan) {
+				if glyphs[span.Face.Font] == nil {
+					glyphs[span.Face.Font] = map[rune]bool{}
+				}
+				for _, r := range span.Text {
+					if !isFormat(r) {
+						glyphs[span.Face.Font][r] = true
+					}
+				}
+			})
+		} else if l.img != nil {
+			manifest.Images = append(manifest.Images, l.img.Bounds().Size())
+		}
+	}
+	for font, runes := range glyphs {
+		manifest.Fonts = append(manifest.Fonts, ManifestFont{
+			Name:   font.Name(),
+			Family: font.tables.FamilyName(),
+			Style:  font.tables.SubfamilyName(),
+			Glyphs: len(runes),
+		})
+	}
+	sort.Slice(manifest.Fonts, func(i, j int) bool {
+		return manifest.Fonts[i].Name < manifest.Fonts[j].Name
+	})
+	if 0 < len(c.layers) {
+		manifest.Bounds = c.bounds()
+	}
+	return manifest
+}
@@ -0,0 +1,32 @@
+package canvas
+
+import (
+	"image"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestCanvasManifest(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0, Black, FontRegular, FontNormal)
+
+	c := New(100, 100)
+	c.RenderPath(Rectangle(20, 10), DefaultStyle, Identity.Translate(10, 10))
+	c.RenderPath(Circle(5), DefaultStyle, Identity.Translate(50, 50))
+	c.RenderText(NewTextLine(face, "Hello", Left), Identity.Translate(20, 40))
+	c.RenderImage(image.NewRGBA(image.Rect(0, 0, 40, 30)), Identity.Translate(60, 60))
+
+	manifest := c.Manifest()
+	test.T(t, manifest.Paths, 2)
+	test.T(t, manifest.Texts, 1)
+	test.T(t, manifest.Images, []image.Point{{40, 30}})
+	test.T(t, len(manifest.Fonts), 1)
+	test.T(t, manifest.Fonts[0].Family, "DejaVu Serif")
+	test.T(t, manifest.Fonts[0].Style, "Book")
+	test.T(t, manifest.Fonts[0].Glyphs, 4)
+	test.T(t, manifest.Bounds, Rect{10, 10, 90, 80})
+
+	test.T(t, New(10, 10).Manifest().Bounds, Rect{})
+}
@@ -0,0 +1,216 @@
+package canvas
+
+import "math"
+
+// MathNode is a node of a mathematical expression tree, see LayoutMath. Nodes are created with MathText, MathRow, MathFraction, MathSup, MathSub, MathSubSup and MathSqrt.
+type MathNode interface {
+	layoutMath(mathStyle) mathBox
+}
+
+// mathBox is a laid out node with its baseline at y=0 and starting at x=0.
+type mathBox struct {
+	path            *Path
+	width           float64
+	ascent, descent float64 // extent above and below the baseline
+}
+
+// mathStyle is the font face used to lay out a node, where minScale limits the shrinking of nested scripts and fractions.
+type mathStyle struct {
+	ff       FontFace
+	minScale float64
+}
+
+// scaled returns the style for a smaller child node, such as a script or the numerator of a fraction.
+func (s mathStyle) scaled(scale float64) mathStyle {
+	s.ff.Scale = math.Max(s.ff.Scale*scale, s.minScale)
+	return s
+}
+
+// mathMetrics are the parameters for positioning math nodes, similar to the constants in the OpenType MATH table. Fonts without math metrics use values relative to the font size.
+type mathMetrics struct {
+	axisHeight    float64 // height of the fraction bar center above the baseline
+	ruleThickness float64 // thickness of the fraction bar and the radical sign
+	gap           float64 // minimum gap between a fraction bar or radical and its contents
+	supShift      float64 // minimum shift up of superscripts
+	subShift      float64 // minimum shift down of subscripts
+	scriptScale   float64 // scale of scripts
+	fractionScale float64 // scale of numerators and denominators
+}
+
+// metrics returns the metrics from the MATH table of math fonts, or else approximations from the font size.
+func (s mathStyle) metrics() mathMetrics {
+	size := s.ff.Size * s.ff.Scale
+	if c := s.ff.Font.mathConsts; c != nil {
+		f := size / s.ff.Font.UnitsPerEm()
+		scriptScale := 0.7
+		if 0 < c.ScriptPercentScaleDown {
+			scriptScale = float64(c.ScriptPercentScaleDown) / 100.0
+		}
+		return mathMetrics{
+			axisHeight:    f * float64(c.AxisHeight),
+			ruleThickness: f * float64(c.FractionRuleThickness),
+			gap:           f * float64(c.FractionNumeratorGapMin),
+			supShift:      f * float64(c.SuperscriptShiftUp),
+			subShift:      f * float64(c.SubscriptShiftDown),
+			scriptScale:   scriptScale,
+			fractionScale: 0.85,
+		}
+	}
+	return mathMetrics{
+		axisHeight:    s.ff.Metrics().XHeight / 2.0,
+		ruleThickness: 0.05 * size,
+		gap:           0.1 * size,
+		supShift:      0.4 * size,
+		subShift:      0.2 * size,
+		scriptScale:   0.7,
+		fractionScale: 0.85,
+	}
+}
+
+// LayoutMath lays out a mathematical expression tree of fractions, sub- and superscripts and square roots in the given font face. It returns the outlines of the expression with its baseline at y=0 and its advance width, similar to FontFace.ToPath. Nested fractions and scripts are laid out progressively smaller, down to half the size of the font face. The math axis, the thickness of fraction bars and the positions of scripts are taken from the MATH table of math fonts, and approximated from the font size for other fonts.
+func LayoutMath(ff FontFace, node MathNode) (*Path, float64) {
+	box := node.layoutMath(mathStyle{ff, ff.Scale * 0.5})
+	return box.path, box.width
+}
+
+type mathText string
+
+// MathText returns a node of text, such as a variable, number or operator. Spaces can be used to add space around operators.
+func MathText(s string) MathNode {
+	return mathText(s)
+}
+
+func (n mathText) layoutMath(s mathStyle) mathBox {
+	p, width := s.ff.ToPath(string(n))
+	bounds := p.Bounds()
+	return mathBox{p, width, math.Max(0.0, bounds.Y+bounds.H), math.Max(0.0, -bounds.Y)}
+}
+
+type mathRow []MathNode
+
+// MathRow returns a node that places nodes next to each other on the same baseline.
+func MathRow(nodes ...MathNode) MathNode {
+	return mathRow(nodes)
+}
+
+func (n mathRow) layoutMath(s mathStyle) mathBox {
+	box := mathBox{path: &Path{}}
+	for _, node := range n {
+		child := node.layoutMath(s)
+		box.path = box.path.Append(child.path.Translate(box.width, 0.0))
+		box.width += child.width
+		box.ascent = math.Max(box.ascent, child.ascent)
+		box.descent = math.Max(box.descent, child.descent)
+	}
+	return box
+}
+
+type mathFraction struct {
+	num, den MathNode
+}
+
+// MathFraction returns a node of a numerator above a denominator, separated by a horizontal bar. The numerator and denominator are centered and laid out smaller.
+func MathFraction(num, den MathNode) MathNode {
+	return mathFraction{num, den}
+}
+
+func (n mathFraction) layoutMath(s mathStyle) mathBox {
+	m := s.metrics()
+	num := n.num.layoutMath(s.scaled(m.fractionScale))
+	den := n.den.layoutMath(s.scaled(m.fractionScale))
+
+	width := math.Max(num.width, den.width) + 2.0*m.ruleThickness
+	barBottom := m.axisHeight - m.ruleThickness/2.0
+	barTop := m.axisHeight + m.ruleThickness/2.0
+	numY := barTop + m.gap + num.descent
+	denY := barBottom - m.gap - den.ascent
+
+	p := Rectangle(width, m.ruleThickness).Translate(0.0, barBottom)
+	p = p.Append(num.path.Translate((width-num.width)/2.0, numY))
+	p = p.Append(den.path.Translate((width-den.width)/2.0, denY))
+	return mathBox{p, width, numY + num.ascent, den.descent - denY}
+}
+
+type mathScripts struct {
+	base, sub, sup MathNode
+}
+
+// MathSup returns a node of a base with a superscript.
+func MathSup(base, sup MathNode) MathNode {
+	return mathScripts{base, nil, sup}
+}
+
+// MathSub returns a node of a base with a subscript.
+func MathSub(base, sub MathNode) MathNode {
+	return mathScripts{base, sub, nil}
+}
+
+// MathSubSup returns a node of a base with both a subscript and a superscript.
+func MathSubSup(base, sub, sup MathNode) MathNode {
+	return mathScripts{base, sub, sup}
+}
+
+func (n mathScripts) layoutMath(s mathStyle) mathBox {
+	m := s.metrics()
+	box := n.base.layoutMath(s)
+	x := box.width
+
+	var sub, sup mathBox
+	var subShift, supShift float64
+	if n.sup != nil {
+		sup = n.sup.layoutMath(s.scaled(m.scriptScale))
+		supShift = math.Max(m.supShift, box.ascent-sup.ascent/2.0)
+	}
+	if n.sub != nil {
+		sub = n.sub.layoutMath(s.scaled(m.scriptScale))
+		subShift = math.Max(m.subShift, math.Max(box.descent, sub.ascent-0.8*s.ff.Metrics().XHeight))
+		if n.sup != nil {
+			// keep a gap between both scripts
+			if gap := (supShift - sup.descent) - (sub.ascent - subShift); gap < 4.0*m.ruleThickness {
+				subShift += 4.0*m.ruleThickness - gap
+			}
+		}
+	}
+
+	if n.sup != nil {
+		box.path = box.path.Append(sup.path.Translate(x, supShift))
+		box.width = math.Max(box.width, x+sup.width)
+		box.ascent = math.Max(box.ascent, supShift+sup.ascent)
+	}
+	if n.sub != nil {
+		box.path = box.path.Append(sub.path.Translate(x, -subShift))
+		box.width = math.Max(box.width, x+sub.width)
+		box.descent = math.Max(box.descent, subShift+sub.descent)
+	}
+	return box
+}
+
+type mathSqrt struct {
+	radicand MathNode
+}
+
+// MathSqrt returns a node of a square root, where the radical sign and its overbar cover the radicand.
+func MathSqrt(radicand MathNode) MathNode {
+	return mathSqrt{radicand}
+}
+
+func (n mathSqrt) layoutMath(s mathStyle) mathBox {
+	m := s.metrics()
+	rad := n.radicand.layoutMath(s)
+
+	bottom := -rad.descent - m.gap
+	top := rad.ascent + m.gap + m.ruleThickness/2.0
+	h := top - bottom
+	signWidth := 0.2*h + 0.15*s.ff.Size*s.ff.Scale
+	width := signWidth + rad.width + 2.0*m.gap
+
+	sign := &Path{}
+	sign.MoveTo(0.0, bottom+0.3*h)
+	sign.LineTo(0.35*signWidth, bottom)
+	sign.LineTo(signWidth, top)
+	sign.LineTo(width, top)
+
+	p := sign.Stroke(m.ruleThickness, ButtCap, RoundJoin)
+	p = p.Append(rad.path.Translate(signWidth+m.gap, 0.0))
+	return mathBox{p, width, top + m.ruleThickness/2.0, -bottom + m.ruleThickness/2.0}
+}
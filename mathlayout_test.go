@@ -0,0 +1,87 @@
+package canvas
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestLayoutMath(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	s := mathStyle{face, 0.5}
+	m := s.metrics()
+
+	// a/b: the bar is at the math axis with the numerator above and the denominator below
+	frac := MathFraction(MathText("a"), MathText("b")).layoutMath(s)
+	ps := frac.path.Split()
+	bar := ps[0].Bounds()
+	test.Float(t, bar.Y+bar.H/2.0, m.axisHeight)
+	test.Float(t, bar.W, frac.width)
+	for _, p := range ps[1:] {
+		bounds := p.Bounds()
+		above := bar.Y+bar.H+m.gap-Epsilon <= bounds.Y
+		below := bounds.Y+bounds.H <= bar.Y-m.gap+Epsilon
+		test.That(t, above || below, "numerator and denominator must not touch the bar:", bounds)
+	}
+
+	// c²: the superscript is raised and placed after the base
+	_, cWidth := face.ToPath("c")
+	sup := MathSup(MathText("c"), MathText("2")).layoutMath(s)
+	ps = sup.path.Split()
+	two := ps[len(ps)-1].Bounds()
+	test.That(t, m.supShift-Epsilon <= two.Y, "superscript must be raised:", two.Y)
+	test.That(t, cWidth <= two.X, "superscript must follow the base:", two.X)
+	test.That(t, two.H < face.Metrics().CapHeight, "superscript must be smaller")
+
+	// a/b + c²
+	path, width := LayoutMath(face, MathRow(MathFraction(MathText("a"), MathText("b")), MathText(" + "), MathSup(MathText("c"), MathText("2"))))
+	_, plusWidth := face.ToPath(" + ")
+	test.Float(t, width, frac.width+plusWidth+sup.width)
+	bounds := path.Bounds()
+	test.Float(t, bounds.Y+bounds.H, math.Max(frac.ascent, sup.ascent))
+	test.Float(t, bounds.Y, -frac.descent)
+
+	// nested fractions are laid out smaller
+	nested := MathFraction(MathFraction(MathText("a"), MathText("b")), MathText("c")).layoutMath(s)
+	test.That(t, MathFraction(MathText("a"), MathText("b")).layoutMath(s.scaled(m.fractionScale)).width < nested.width, "nested fraction must be wider than its numerator")
+	test.That(t, frac.ascent < nested.ascent, "nested fraction must be taller")
+	innerBar := nested.path.Split()[1].Bounds()
+	test.That(t, innerBar.W < bar.W, "inner fraction must be smaller:", innerBar.W, bar.W)
+
+	// square root covers its radicand
+	sqrt := MathSqrt(MathText("x")).layoutMath(s)
+	x := MathText("x").layoutMath(s)
+	test.That(t, x.width < sqrt.width && x.ascent < sqrt.ascent && x.descent < sqrt.descent, "radical must cover the radicand")
+	sqrtBounds := sqrt.path.Bounds()
+	test.That(t, sqrtBounds.Y+sqrtBounds.H <= sqrt.ascent+Epsilon, "radical must be within its box")
+}
+
+func TestLayoutMathConstants(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	f := face.Size / face.Font.UnitsPerEm()
+
+	// DejaVu Serif has a MATH table
+	s := mathStyle{face, 0.5}
+	m := s.metrics()
+	test.Float(t, m.axisHeight, 642.0*f)
+	test.Float(t, m.ruleThickness, 90.0*f)
+	test.Float(t, m.scriptScale, 0.8)
+
+	frac := MathFraction(MathText("a"), MathText("b")).layoutMath(s)
+	bar := frac.path.Split()[0].Bounds()
+	test.Float(t, bar.Y+bar.H/2.0, 642.0*f)
+	test.Float(t, bar.H, 90.0*f)
+
+	// fonts without a MATH table use approximations
+	font := *face.Font
+	font.mathConsts = nil
+	face.Font = &font
+	m = mathStyle{face, 0.5}.metrics()
+	test.Float(t, m.axisHeight, face.Metrics().XHeight/2.0)
+	test.Float(t, m.ruleThickness, 0.05*face.Size)
+}
@@ -0,0 +1,290 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// ExtendMode defines how a paint is sampled outside of the area it is defined in, such as outside of the bounds of the image of an ImagePaint.
+type ExtendMode int
+
+// see ExtendMode
+const (
+	ExtendNone    ExtendMode = iota // transparent outside
+	ExtendRepeat                    // tile the paint
+	ExtendReflect                   // tile the paint while mirroring every other tile
+	ExtendClamp                     // extend the edges of the paint
+)
+
+// Paint is a source of color that varies over the plane and can be used to fill and stroke paths instead of a solid color, see Style.FillPaint, Style.StrokePaint, Context.FillPath and Context.StrokePath. At returns the color at a position in the coordinate system of the path being drawn. Paints are implemented by SolidPaint, LinearGradient, RadialGradient, Pattern, ImagePaint and SpotColor. The rasterizer supports all paints, vector renderers support the paints they can express natively and otherwise fall back to the fill or stroke color.
+type Paint interface {
+	At(x, y float64) color.RGBA
+}
+
+// SolidPaint is a paint of a single color.
+type SolidPaint struct {
+	Color color.RGBA
+}
+
+// At returns the color of the paint.
+func (paint SolidPaint) At(x, y float64) color.RGBA {
+	return paint.Color
+}
+
+// GradientStop is a color of a gradient at an offset between 0 and 1 along the gradient.
+type GradientStop struct {
+	Offset float64
+	Color  color.RGBA
+}
+
+// gradientAt returns the color at t of the gradient stops, which must be ordered by offset, where t is first mapped to [0,1] by the extend mode. Colors are interpolated linearly between the stops in premultiplied space.
+func gradientAt(stops []GradientStop, extend ExtendMode, t float64) color.RGBA {
+	if len(stops) == 0 || math.IsNaN(t) {
+		return Transparent
+	}
+	switch extend {
+	case ExtendNone:
+		if t < 0.0 || 1.0 < t {
+			return Transparent
+		}
+	case ExtendRepeat:
+		t -= math.Floor(t)
+	case ExtendReflect:
+		t = math.Abs(t - 2.0*math.Floor(t/2.0+0.5))
+	}
+
+	if t <= stops[0].Offset {
+		return stops[0].Color
+	}
+	for i := 1; i < len(stops); i++ {
+		if t < stops[i].Offset {
+			s0, s1 := stops[i-1], stops[i]
+			f := (t - s0.Offset) / (s1.Offset - s0.Offset)
+			return color.RGBA{
+				uint8(float64(s0.Color.R) + f*(float64(s1.Color.R)-float64(s0.Color.R)) + 0.5),
+				uint8(float64(s0.Color.G) + f*(float64(s1.Color.G)-float64(s0.Color.G)) + 0.5),
+				uint8(float64(s0.Color.B) + f*(float64(s1.Color.B)-float64(s0.Color.B)) + 0.5),
+				uint8(float64(s0.Color.A) + f*(float64(s1.Color.A)-float64(s0.Color.A)) + 0.5),
+			}
+		}
+	}
+	return stops[len(stops)-1].Color
+}
+
+// LinearGradient is a paint with colors that vary along the line from Start to End, and are constant perpendicular to it. Stops must be ordered by offset, where offset 0 is at Start and offset 1 at End. Extend determines the color before Start and after End, renderers that do not support ExtendNone, ExtendRepeat or ExtendReflect for gradients use ExtendClamp.
+type LinearGradient struct {
+	Start, End Point
+	Stops      []GradientStop
+	Extend     ExtendMode
+}
+
+// At returns the color of the gradient at (x,y).
+func (paint LinearGradient) At(x, y float64) color.RGBA {
+	d := paint.End.Sub(paint.Start)
+	t := Point{x, y}.Sub(paint.Start).Dot(d) / d.Dot(d)
+	return gradientAt(paint.Stops, paint.Extend, t)
+}
+
+// RadialGradient is a paint with colors that vary with the distance from Center, where offset 0 is at Center and offset 1 at Radius. Stops must be ordered by offset. Extend determines the color outside of the radius, renderers that do not support ExtendNone, ExtendRepeat or ExtendReflect for gradients use ExtendClamp.
+type RadialGradient struct {
+	Center Point
+	Radius float64
+	Stops  []GradientStop
+	Extend ExtendMode
+}
+
+// At returns the color of the gradient at (x,y).
+func (paint RadialGradient) At(x, y float64) color.RGBA {
+	t := Point{x, y}.Sub(paint.Center).Length() / paint.Radius
+	return gradientAt(paint.Stops, paint.Extend, t)
+}
+
+// Pattern is a paint that repeats the cell of width and height of another paint, with its bottom-left corner at the origin, in both directions.
+type Pattern struct {
+	Cell          Paint
+	Width, Height float64
+}
+
+// At returns the color of the cell of the pattern at (x,y).
+func (paint Pattern) At(x, y float64) color.RGBA {
+	if paint.Cell == nil || paint.Width <= 0.0 || paint.Height <= 0.0 {
+		return Transparent
+	}
+	x -= math.Floor(x/paint.Width) * paint.Width
+	y -= math.Floor(y/paint.Height) * paint.Height
+	return paint.Cell.At(x, y)
+}
+
+// paintColor returns a color that represents the paint for renderers that do not support it, or col for paints without a representative color.
+func paintColor(paint Paint, col color.RGBA) color.RGBA {
+	switch p := paint.(type) {
+	case SolidPaint:
+		return p.Color
+	case LinearGradient:
+		return gradientAt(p.Stops, ExtendClamp, 0.5)
+	case RadialGradient:
+		return gradientAt(p.Stops, ExtendClamp, 0.5)
+	case SpotColor:
+		return p.At(0.0, 0.0)
+	}
+	if col.A == 0 {
+		return Black
+	}
+	return col
+}
+
+// ImagePaint is a paint that fills paths with an image. Transform maps the image, with one unit per pixel and its bottom-left corner at the origin, to the coordinate system of the path. Extend determines how the image is sampled outside of its bounds.
+type ImagePaint struct {
+	Image     image.Image
+	Transform Matrix
+	Extend    ExtendMode
+}
+
+// At returns the color of the image at (x,y) using nearest-neighbour sampling.
+func (paint ImagePaint) At(x, y float64) color.RGBA {
+	bounds := paint.Image.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return Transparent
+	}
+
+	pos := paint.Transform.Inv().Dot(Point{x, y})
+	i, j := int(math.Floor(pos.X)), h-1-int(math.Floor(pos.Y))
+	switch paint.Extend {
+	case ExtendNone:
+		if i < 0 || w <= i || j < 0 || h <= j {
+			return Transparent
+		}
+	case ExtendRepeat:
+		i, j = extendRepeat(i, w), extendRepeat(j, h)
+	case ExtendReflect:
+		i, j = extendReflect(i, w), extendReflect(j, h)
+	case ExtendClamp:
+		i, j = extendClamp(i, w), extendClamp(j, h)
+	}
+	return color.RGBAModel.Convert(paint.Image.At(bounds.Min.X+i, bounds.Min.Y+j)).(color.RGBA)
+}
+
+// ReflectedImage returns an image of twice the width and height of the paint's image, where the image is mirrored horizontally and vertically. Tiling this image is equivalent to ExtendReflect.
+func (paint ImagePaint) ReflectedImage() image.Image {
+	bounds := paint.Image.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	img := image.NewRGBA(image.Rect(0, 0, 2*w, 2*h))
+	for j := 0; j < 2*h; j++ {
+		for i := 0; i < 2*w; i++ {
+			img.Set(i, j, paint.Image.At(bounds.Min.X+extendReflect(i, w), bounds.Min.Y+extendReflect(j, h)))
+		}
+	}
+	return img
+}
+
+// ClampedImage returns an image that covers rect (in the coordinate system of the path), where the edge pixels of the paint's image extend outwards as for ExtendClamp. It also returns the transformation of the returned image to the coordinate system of the path, similar to Transform.
+func (paint ImagePaint) ClampedImage(rect Rect) (image.Image, Matrix) {
+	bounds := paint.Image.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	// bounds of rect in pixel coordinates of the image
+	r := rect.Transform(paint.Transform.Inv())
+	x0, y0 := int(math.Floor(math.Min(r.X, 0.0))), int(math.Floor(math.Min(r.Y, 0.0)))
+	x1, y1 := int(math.Ceil(math.Max(r.X+r.W, float64(w)))), int(math.Ceil(math.Max(r.Y+r.H, float64(h))))
+
+	img := image.NewRGBA(image.Rect(0, 0, x1-x0, y1-y0))
+	for j := 0; j < y1-y0; j++ {
+		for i := 0; i < x1-x0; i++ {
+			// rows of the image run downwards while the y-axis runs upwards
+			img.Set(i, j, paint.Image.At(bounds.Min.X+extendClamp(x0+i, w), bounds.Min.Y+extendClamp(j-(y1-h), h)))
+		}
+	}
+	return img, paint.Transform.Translate(float64(x0), float64(y0))
+}
+
+func extendRepeat(i, n int) int {
+	i %= n
+	if i < 0 {
+		i += n
+	}
+	return i
+}
+
+func extendReflect(i, n int) int {
+	i = extendRepeat(i, 2*n)
+	if n <= i {
+		i = 2*n - 1 - i
+	}
+	return i
+}
+
+func extendClamp(i, n int) int {
+	if i < 0 {
+		return 0
+	} else if n <= i {
+		return n - 1
+	}
+	return i
+}
+
+// SpotColor is a paint of a spot color, which is a separate ink such as a Pantone color, at a tint between 0 (no ink) and 1 (full ink). The appearance of the full ink on devices without the spot ink is given by an alternate color, which is CMYK by default and is given more accurately by Lab or by the components of an ICC profile's color space, where ICC takes precedence over Lab. PDF output keeps the spot color as a separation with the alternate color for proofing, other renderers use the RGB preview of At.
+type SpotColor struct {
+	Name string
+	Tint float64
+	CMYK color.CMYK
+	Lab  *LabColor // optional
+	ICC  *ICCColor // optional
+}
+
+// LabColor is a color in the CIE L*a*b* color space under the D50 illuminant, with L between 0 and 100 and a and b between -128 and 127.
+type LabColor struct {
+	L, A, B float64
+}
+
+// ICCColor is a color in the color space of an ICC profile, such as a characterized printing condition. Components are the values between 0 and 1 in the color space of the profile, such as three values for an RGB profile or four values for a CMYK profile.
+type ICCColor struct {
+	Profile    []byte
+	Components []float64
+}
+
+// At returns the RGB preview of the spot color from its Lab color if set, or from its CMYK color otherwise, where the tint mixes the color with white.
+func (paint SpotColor) At(x, y float64) color.RGBA {
+	var r, g, b float64
+	if paint.Lab != nil {
+		r, g, b = labToSRGB(*paint.Lab)
+	} else {
+		k := 1.0 - float64(paint.CMYK.K)/255.0
+		r = (1.0 - float64(paint.CMYK.C)/255.0) * k
+		g = (1.0 - float64(paint.CMYK.M)/255.0) * k
+		b = (1.0 - float64(paint.CMYK.Y)/255.0) * k
+	}
+	tint := math.Max(0.0, math.Min(1.0, paint.Tint))
+	mix := func(v float64) uint8 {
+		return uint8(255.0*(1.0-tint*(1.0-v)) + 0.5)
+	}
+	return color.RGBA{mix(r), mix(g), mix(b), 255}
+}
+
+// labToSRGB converts a CIE L*a*b* color under D50 to sRGB with values between 0 and 1, see http://www.brucelindbloom.com/
+func labToSRGB(lab LabColor) (float64, float64, float64) {
+	fy := (lab.L + 16.0) / 116.0
+	fx := fy + lab.A/500.0
+	fz := fy - lab.B/200.0
+	finv := func(t float64) float64 {
+		if 6.0/29.0 < t {
+			return t * t * t
+		}
+		return 3.0 * (6.0 / 29.0) * (6.0 / 29.0) * (t - 4.0/29.0)
+	}
+	X, Y, Z := 0.9642*finv(fx), finv(fy), 0.8249*finv(fz)
+
+	// XYZ (D50) to linear sRGB (D65) with Bradford adaptation
+	gamma := func(v float64) float64 {
+		v = math.Max(0.0, math.Min(1.0, v))
+		if v <= 0.0031308 {
+			return 12.92 * v
+		}
+		return 1.055*math.Pow(v, 1.0/2.4) - 0.055
+	}
+	r := 3.1338561*X - 1.6168667*Y - 0.4906146*Z
+	g := -0.9787684*X + 1.9161415*Y + 0.0334540*Z
+	b := 0.0719453*X - 0.2289914*Y + 1.4052427*Z
+	return gamma(r), gamma(g), gamma(b)
+}
@@ -0,0 +1,85 @@
+package canvas
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestGradients(t *testing.T) {
+	stops := []GradientStop{{0.0, Red}, {0.5, Lime}, {1.0, Blue}}
+	linear := LinearGradient{Point{0.0, 0.0}, Point{10.0, 0.0}, stops, ExtendClamp}
+	test.T(t, linear.At(0.0, 5.0), Red)
+	test.T(t, linear.At(5.0, -5.0), Lime)
+	test.T(t, linear.At(2.5, 0.0), color.RGBA{128, 128, 0, 255})
+	test.T(t, linear.At(20.0, 0.0), Blue)
+
+	linear.Extend = ExtendNone
+	test.T(t, linear.At(-1.0, 0.0), Transparent)
+	linear.Extend = ExtendRepeat
+	test.T(t, linear.At(15.0, 0.0), Lime)
+	linear.Extend = ExtendReflect
+	test.T(t, linear.At(12.5, 0.0), color.RGBA{0, 128, 128, 255})
+	test.T(t, linear.At(-5.0, 0.0), Lime)
+
+	radial := RadialGradient{Point{5.0, 5.0}, 10.0, stops, ExtendClamp}
+	test.T(t, radial.At(5.0, 5.0), Red)
+	test.T(t, radial.At(5.0, 15.0), Blue)
+	test.T(t, radial.At(-5.0, 5.0), Blue)
+	test.T(t, radial.At(8.0, 9.0), Lime)
+
+	test.T(t, LinearGradient{}.At(0.0, 0.0), Transparent)
+}
+
+func TestPattern(t *testing.T) {
+	cell := LinearGradient{Point{0.0, 0.0}, Point{2.0, 0.0}, []GradientStop{{0.0, Black}, {1.0, White}}, ExtendClamp}
+	pattern := Pattern{cell, 2.0, 2.0}
+	test.T(t, pattern.At(0.0, 0.0), Black)
+	test.T(t, pattern.At(4.0, 7.0), Black)
+	test.T(t, pattern.At(-1.0, 0.5), cell.At(1.0, 0.5))
+	test.T(t, Pattern{}.At(1.0, 1.0), Transparent)
+	test.T(t, SolidPaint{Red}.At(1.0, 2.0), Red)
+}
+
+func TestContextStrokePath(t *testing.T) {
+	c := New(10.0, 10.0)
+	ctx := NewContext(c)
+	ctx.SetStrokeWidth(2.0)
+	gradient := LinearGradient{Point{0.0, 0.0}, Point{10.0, 0.0}, []GradientStop{{0.0, Red}, {1.0, Blue}}, ExtendClamp}
+	ctx.StrokePath(MustParseSVG("M0 5L10 5"), gradient)
+	ctx.FillPath(Rectangle(2.0, 2.0), SolidPaint{Green})
+
+	test.T(t, len(c.layers), 2)
+	test.T(t, c.layers[0].style.StrokePaint, Paint(gradient))
+	test.T(t, c.layers[0].style.FillColor, Transparent)
+	test.T(t, c.layers[0].style.StrokeColor, gradient.At(5.0, 0.0)) // fallback color
+	test.T(t, c.layers[1].style.FillPaint, Paint(nil))
+	test.T(t, c.layers[1].style.FillColor, Green)
+	test.T(t, c.layers[1].style.StrokeColor, Transparent)
+	test.T(t, ctx.Style.StrokePaint, Paint(nil)) // the style of the context is not changed
+}
+
+func TestSpotColor(t *testing.T) {
+	spot := SpotColor{Name: "PANTONE 185 C", Tint: 1.0, CMYK: color.CMYK{0, 255, 255, 0}}
+	test.T(t, spot.At(0.0, 0.0), color.RGBA{255, 0, 0, 255})
+	spot.Tint = 0.5
+	test.T(t, spot.At(0.0, 0.0), color.RGBA{255, 128, 128, 255})
+	spot.Tint = 0.0
+	test.T(t, spot.At(0.0, 0.0), White)
+
+	// Lab takes precedence for the preview, D50 white and black map to sRGB white and black
+	spot.Tint = 1.0
+	spot.Lab = &LabColor{100.0, 0.0, 0.0}
+	test.T(t, spot.At(0.0, 0.0), White)
+	spot.Lab = &LabColor{0.0, 0.0, 0.0}
+	test.T(t, spot.At(0.0, 0.0), Black)
+	spot.Lab = &LabColor{54.29, 80.8, 69.89} // sRGB red under D50
+	col := spot.At(0.0, 0.0)
+	test.That(t, 240 < col.R && col.G < 30 && col.B < 40, "must be red:", col)
+
+	// fallback color for renderers without spot colors
+	c := New(10.0, 10.0)
+	NewContext(c).FillPath(Rectangle(2.0, 2.0), spot)
+	test.T(t, c.layers[0].style.FillColor, col)
+}
@@ -22,6 +22,16 @@ const (
 	EvenOdd
 )
 
+// FillOpenPaths is the policy for filling subpaths that are not closed, see Style.FillOpenPaths.
+type FillOpenPaths int
+
+// see FillOpenPaths
+const (
+	CloseOpenPaths FillOpenPaths = iota // fill open subpaths as if they were closed
+	SkipOpenPaths                       // fill only the closed subpaths
+	ErrorOpenPaths                      // do not fill paths with open subpaths
+)
+
 const (
 	moveToCmd = 1.0 << iota //  1.0
 	lineToCmd               //  2.0
@@ -76,6 +86,15 @@ func (p *Path) Empty() bool {
 	return len(p.d) <= cmdLen(moveToCmd)
 }
 
+// segments returns the number of path segments including MoveTo and Close commands.
+func (p *Path) segments() int {
+	n := 0
+	for i := 0; i < len(p.d); i += cmdLen(p.d[i]) {
+		n++
+	}
+	return n
+}
+
 // Equals returns true if p and q are equal within tolerance Epsilon.
 func (p *Path) Equals(q *Path) bool {
 	if len(p.d) != len(q.d) {
@@ -94,6 +113,33 @@ func (p *Path) Closed() bool {
 	return 0 < len(p.d) && p.d[len(p.d)-1] == closeCmd
 }
 
+// ResolveOpenPaths returns the path to be filled according to the policy for subpaths that are not closed. CloseOpenPaths closes each open subpath, SkipOpenPaths removes open subpaths, and ErrorOpenPaths returns ErrOpenPath if there are open subpaths. It returns p itself if all subpaths are closed.
+func (p *Path) ResolveOpenPaths(policy FillOpenPaths) (*Path, error) {
+	ps := p.Split()
+	open := false
+	for _, pi := range ps {
+		if !pi.Closed() {
+			open = true
+			break
+		}
+	}
+	if !open {
+		return p, nil
+	} else if policy == ErrorOpenPaths {
+		return nil, ErrOpenPath
+	}
+
+	q := &Path{}
+	for _, pi := range ps {
+		if pi.Closed() {
+			q = q.Append(pi)
+		} else if policy == CloseOpenPaths {
+			q = q.Append(pi.Close())
+		}
+	}
+	return q, nil
+}
+
 // Copy returns a copy of p.
 func (p *Path) Copy() *Path {
 	q := &Path{}
@@ -471,6 +517,52 @@ func (p *Path) Filling(fillRule FillRule) []bool {
 	return fillings
 }
 
+// NormalizeWinding returns the path with its subpaths reoriented so that the intended fill is obtained when filling with the given fill rule, regardless of the winding directions of the original subpaths. This is useful for paths imported from SVG files or traced bitmaps, where holes often have the same direction as their outer contours. Subpaths are filled or not depending on how many other subpaths contain them: outer contours are filled, holes inside them are empty, islands inside holes are filled again, and so on. For the NonZero fill rule, outer contours and islands become counter clockwise and holes become clockwise, which gives the same fill for the NonZero and EvenOdd fill rules. Subpaths that overlap without one containing the other, such as the overlapping contours of glyphs of variable fonts, are both outer contours so that their union is filled with the NonZero fill rule. For the EvenOdd fill rule the fill does not depend on the winding directions, so that the path is returned unchanged, and the intersection of overlapping subpaths remains empty. Unclosed subpaths are implicitly closed and subpaths are assumed not to intersect themselves.
+func (p *Path) NormalizeWinding(fillRule FillRule) *Path {
+	if fillRule == EvenOdd {
+		return p.Copy()
+	}
+
+	ps := p.Split()
+	polylines := make([]*Polyline, len(ps))
+	for i, pi := range ps {
+		polylines[i] = PolylineFromPath(pi.Close())
+	}
+
+	q := &Path{}
+	for i, pi := range ps {
+		coords := polylines[i].coords
+		if len(coords) < 2 {
+			q = q.Append(pi)
+			continue
+		}
+
+		// count the subpaths that entirely contain this subpath, the direction of the containing subpaths does not matter
+		depth := 0
+		for j, polyline := range polylines {
+			if j != i && polylineContains(polyline, polylines[i]) {
+				depth++
+			}
+		}
+		if pi.CCW() != (depth%2 == 0) {
+			pi = pi.Reverse()
+		}
+		q = q.Append(pi)
+	}
+	return q
+}
+
+// polylineContains returns true if the polygon q is entirely inside the polygon p, which is tested for the midpoints of the edges of q so that vertices where q touches p are ignored.
+func polylineContains(p, q *Polyline) bool {
+	for k := 1; k < len(q.coords); k++ {
+		test := q.coords[k-1].Interpolate(q.coords[k], 0.5)
+		if p.FillCount(test.X, test.Y) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // Interior is true when the point (x,y) is in the interior of the path, ie. gets filled. This depends on the FillRule.
 func (p *Path) Interior(x, y float64, fillRule FillRule) bool {
 	fillCount := 0
@@ -728,11 +820,226 @@ func (p *Path) Translate(x, y float64) *Path {
 	return p.Transform(Identity.Translate(x, y))
 }
 
-// Flatten flattens all Bézier and arc curves into linear segments and returns a new path. It uses Tolerance as the maximum deviation.
+// Snap rounds all coordinates of the path, including control points and arc end points, to the nearest multiple of gridX and gridY and returns a new path. A grid size of zero leaves that axis unchanged. Segments that become degenerate are removed and closed subpaths remain closed. Snapping to the pixel grid avoids blurry edges of axis-aligned shapes when rasterizing, see SnapStroke for strokes.
+func (p *Path) Snap(gridX, gridY float64) *Path {
+	return p.snap(gridX, gridY, 0.0, 0.0)
+}
+
+// SnapStroke is like Snap but for paths that will be stroked with the given stroke width. When the stroke width is an odd multiple of the grid size, the coordinates are snapped to the centers between grid lines, so that the edges of the stroke fall on the grid, such as for 1px wide lines.
+func (p *Path) SnapStroke(gridX, gridY, strokeWidth float64) *Path {
+	offset := func(grid float64) float64 {
+		if grid != 0.0 && math.Mod(math.Abs(math.Round(strokeWidth/grid)), 2.0) == 1.0 {
+			return grid / 2.0
+		}
+		return 0.0
+	}
+	return p.snap(gridX, gridY, offset(gridX), offset(gridY))
+}
+
+func (p *Path) snap(gridX, gridY, offsetX, offsetY float64) *Path {
+	snap := func(x, y float64) (float64, float64) {
+		if gridX != 0.0 {
+			x = math.Round((x-offsetX)/gridX)*gridX + offsetX
+		}
+		if gridY != 0.0 {
+			y = math.Round((y-offsetY)/gridY)*gridY + offsetY
+		}
+		return x, y
+	}
+
+	q := &Path{}
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		switch cmd {
+		case moveToCmd:
+			q.MoveTo(snap(p.d[i+1], p.d[i+2]))
+		case lineToCmd:
+			q.LineTo(snap(p.d[i+1], p.d[i+2]))
+		case quadToCmd:
+			cpx, cpy := snap(p.d[i+1], p.d[i+2])
+			x, y := snap(p.d[i+3], p.d[i+4])
+			q.QuadTo(cpx, cpy, x, y)
+		case cubeToCmd:
+			cpx1, cpy1 := snap(p.d[i+1], p.d[i+2])
+			cpx2, cpy2 := snap(p.d[i+3], p.d[i+4])
+			x, y := snap(p.d[i+5], p.d[i+6])
+			q.CubeTo(cpx1, cpy1, cpx2, cpy2, x, y)
+		case arcToCmd:
+			large, sweep := toArcFlags(p.d[i+4])
+			x, y := snap(p.d[i+5], p.d[i+6])
+			q.ArcTo(p.d[i+1], p.d[i+2], p.d[i+3]*180.0/math.Pi, large, sweep, x, y)
+		case closeCmd:
+			q.Close()
+		}
+		i += cmdLen(cmd)
+	}
+	return q
+}
+
+// DefaultFlattenMethod is the method used by Path.Flatten, which includes the flattening done by functions such as PolylineFromPath and Path.VariableStroke. When nil, curves are flattened using Tolerance as the maximum deviation.
+var DefaultFlattenMethod FlattenMethod
+
+// Flatten flattens all Bézier and arc curves into linear segments and returns a new path. It uses DefaultFlattenMethod if set, or Tolerance as the maximum deviation otherwise.
 func (p *Path) Flatten() *Path {
+	if DefaultFlattenMethod != nil {
+		return p.FlattenWith(DefaultFlattenMethod)
+	}
 	return p.replace(nil, flattenQuadraticBezier, flattenCubicBezier, flattenEllipticArc)
 }
 
+// FlattenMethod is a strategy to flatten curves into linear segments, see Path.FlattenWith. Use UniformSteps, AdaptiveDeviation or RecursiveSubdivision.
+type FlattenMethod interface {
+	flattenCubic(p0, p1, p2, p3 Point) *Path
+	flattenArc(start Point, rx, ry, phi float64, large, sweep bool, end Point) *Path
+}
+
+type uniformSteps int
+
+// UniformSteps returns a flatten method that divides each curve into exactly n linear segments of equal parameter steps, which is useful to sample curves for animations. Arcs are divided into equal angles.
+func UniformSteps(n int) FlattenMethod {
+	if n < 1 {
+		n = 1
+	}
+	return uniformSteps(n)
+}
+
+func (n uniformSteps) flattenCubic(p0, p1, p2, p3 Point) *Path {
+	p := &Path{}
+	p.MoveTo(p0.X, p0.Y)
+	for i := 1; i < int(n); i++ {
+		pos := cubicBezierPos(p0, p1, p2, p3, float64(i)/float64(n))
+		p.LineTo(pos.X, pos.Y)
+	}
+	p.LineTo(p3.X, p3.Y)
+	return p
+}
+
+func (n uniformSteps) flattenArc(start Point, rx, ry, phi float64, large, sweep bool, end Point) *Path {
+	cx, cy, theta0, theta1 := ellipseToCenter(start.X, start.Y, rx, ry, phi, large, sweep, end.X, end.Y)
+	p := &Path{}
+	p.MoveTo(start.X, start.Y)
+	for i := 1; i < int(n); i++ {
+		pos := ellipsePos(rx, ry, phi, cx, cy, theta0+(theta1-theta0)*float64(i)/float64(n))
+		p.LineTo(pos.X, pos.Y)
+	}
+	p.LineTo(end.X, end.Y)
+	return p
+}
+
+type adaptiveDeviation float64
+
+// AdaptiveDeviation returns a flatten method that places segments according to the curvature so that the maximum deviation from the curve is within tolerance, using few segments. It is suited for rendering. Unlike the estimate of Path.Flatten, the deviation of each segment is verified.
+func AdaptiveDeviation(tolerance float64) FlattenMethod {
+	return adaptiveDeviation(tolerance)
+}
+
+func (tolerance adaptiveDeviation) flattenCubic(p0, p1, p2, p3 Point) *Path {
+	p := &Path{}
+	p.MoveTo(p0.X, p0.Y)
+
+	// split at the inflection points, as the steps are estimated from the curvature
+	t1, t2 := findInflectionPointsCubicBezier(p0, p1, p2, p3)
+	t0 := 0.0
+	for _, t := range []float64{t1, t2} {
+		if t0 < t { // false for NaN
+			var q0, q1, q2, q3 Point
+			q0, q1, q2, q3, p0, p1, p2, p3 = cubicBezierSplit(p0, p1, p2, p3, (t-t0)/(1.0-t0))
+			tolerance.flattenSmooth(p, q0, q1, q2, q3)
+			t0 = t
+		}
+	}
+	tolerance.flattenSmooth(p, p0, p1, p2, p3)
+	return p
+}
+
+func (tolerance adaptiveDeviation) flattenArc(start Point, rx, ry, phi float64, large, sweep bool, end Point) *Path {
+	return arcToCube(start, rx, ry, phi, large, sweep, end).replace(nil, nil, tolerance.flattenCubic, nil)
+}
+
+// flattenSmooth adds the line segments of a cubic Bézier without inflection points. Each step is estimated by approximating the curve by a parabola, see Hain et al. in flattenSmoothCubicBezier, and is shortened until the segment lies within tolerance of the curve.
+func (tolerance adaptiveDeviation) flattenSmooth(p *Path, p0, p1, p2, p3 Point) {
+	for {
+		t := 1.0
+		s2nom := (p2.X-p0.X)*(p1.Y-p0.Y) - (p2.Y-p0.Y)*(p1.X-p0.X)
+		if denom := math.Hypot(p1.X-p0.X, p1.Y-p0.Y); s2nom*denom != 0.0 {
+			t = math.Min(2.0*math.Sqrt(float64(tolerance)/3.0/math.Abs(s2nom/denom)), 1.0)
+		}
+
+		q0, q1, q2, q3, r0, r1, r2, r3 := cubicBezierSplit(p0, p1, p2, p3, t)
+		for float64(tolerance) < cubicBezierChordDeviation(q0, q1, q2, q3) {
+			t *= 0.9
+			q0, q1, q2, q3, r0, r1, r2, r3 = cubicBezierSplit(p0, p1, p2, p3, t)
+		}
+		if t == 1.0 {
+			p.LineTo(p3.X, p3.Y)
+			return
+		}
+		p.LineTo(q3.X, q3.Y)
+		p0, p1, p2, p3 = r0, r1, r2, r3
+	}
+}
+
+// cubicBezierChordDeviation returns the maximum distance of a cubic Bézier to the line through its end points, or the maximum distance of its control points to the start point if the end points coincide.
+func cubicBezierChordDeviation(p0, p1, p2, p3 Point) float64 {
+	chord := p3.Sub(p0)
+	if chord.IsZero() {
+		return math.Max(p1.Sub(p0).Length(), p2.Sub(p0).Length())
+	}
+
+	// the signed distance to the chord is a cubic Bézier in one dimension with control values 0, c1, c2 and 0
+	n := chord.Rot90CCW().Norm(1.0)
+	c1, c2 := n.Dot(p1.Sub(p0)), n.Dot(p2.Sub(p0))
+	dist := func(t float64) float64 {
+		return math.Abs(3.0 * (1.0 - t) * t * ((1.0-t)*c1 + t*c2))
+	}
+	dev := 0.0
+	t1, t2 := solveQuadraticFormula(9.0*(c1-c2), 6.0*c2-12.0*c1, 3.0*c1)
+	for _, t := range []float64{t1, t2} {
+		if 0.0 <= t && t <= 1.0 {
+			dev = math.Max(dev, dist(t))
+		}
+	}
+	return dev
+}
+
+type recursiveSubdivision float64
+
+// RecursiveSubdivision returns a flatten method that recursively splits curves in half until the control points lie within tolerance of the line between the end points. The segments lie within tolerance of the curve, but there are usually more segments than for AdaptiveDeviation.
+func RecursiveSubdivision(tolerance float64) FlattenMethod {
+	return recursiveSubdivision(tolerance)
+}
+
+func (tolerance recursiveSubdivision) flattenCubic(p0, p1, p2, p3 Point) *Path {
+	p := &Path{}
+	p.MoveTo(p0.X, p0.Y)
+	tolerance.subdivide(p, p0, p1, p2, p3, 0)
+	return p
+}
+
+func (tolerance recursiveSubdivision) subdivide(p *Path, p0, p1, p2, p3 Point, depth int) {
+	// the curve lies within the convex hull of its control points
+	if depth == 16 || distanceToSegment(p1, p0, p3) <= float64(tolerance) && distanceToSegment(p2, p0, p3) <= float64(tolerance) {
+		p.LineTo(p3.X, p3.Y)
+		return
+	}
+	r0, r1, r2, r3, q0, q1, q2, q3 := cubicBezierSplit(p0, p1, p2, p3, 0.5)
+	tolerance.subdivide(p, r0, r1, r2, r3, depth+1)
+	tolerance.subdivide(p, q0, q1, q2, q3, depth+1)
+}
+
+func (tolerance recursiveSubdivision) flattenArc(start Point, rx, ry, phi float64, large, sweep bool, end Point) *Path {
+	return arcToCube(start, rx, ry, phi, large, sweep, end).replace(nil, nil, tolerance.flattenCubic, nil)
+}
+
+// FlattenWith flattens all Bézier and arc curves into linear segments using the given method and returns a new path. Quadratic Béziers are flattened as their equivalent cubic Béziers.
+func (p *Path) FlattenWith(method FlattenMethod) *Path {
+	quad := func(p0, p1, p2 Point) *Path {
+		cp1, cp2 := quadraticToCubicBezier(p0, p1, p2)
+		return method.flattenCubic(p0, cp1, cp2, p2)
+	}
+	return p.replace(nil, quad, method.flattenCubic, method.flattenArc)
+}
+
 // ReplaceArcs replaces ArcTo commands by CubeTo commands.
 func (p *Path) ReplaceArcs() *Path {
 	return p.replace(nil, nil, nil, arcToCube)
@@ -903,6 +1210,36 @@ func (p *Path) Split() []*Path {
 	return ps
 }
 
+// HatchStroke returns copies of the symbol placed at regular intervals of spacing along the path, such as ticks for railroads or hatches for cliffs on maps. The symbol is oriented with its x-axis along the path direction and its y-axis towards the path's left, as for Markers, and positions are measured by arc length regardless of the vertices of the path so that symbols are not cut at corners. Symbols are placed half the spacing from the start and end of open subpaths, and for closed subpaths the spacing is adjusted slightly so that the symbols are spread evenly.
+func (p *Path) HatchStroke(symbol *Path, spacing float64) *Path {
+	q := &Path{}
+	if spacing <= 0.0 || symbol.Empty() {
+		return q
+	}
+	for _, ps := range p.Split() {
+		length := ps.Length()
+		if length == 0.0 {
+			continue
+		}
+		d := spacing
+		if ps.Closed() {
+			d = length / math.Max(1.0, math.Floor(length/spacing+0.5))
+		}
+		ts := []float64{}
+		for t := d / 2.0; t < length; t += d {
+			ts = append(ts, t)
+		}
+
+		// each piece ends at a position, where its end marker is the oriented symbol
+		pieces := ps.SplitAt(ts...)
+		for _, piece := range pieces[:len(ts)] {
+			markers := piece.Markers(&Path{}, &Path{}, symbol, true)
+			q = q.Append(markers[len(markers)-1])
+		}
+	}
+	return q
+}
+
 // SplitAt splits the path into separate paths at the specified intervals (given in millimeters) along the path.
 func (p *Path) SplitAt(ts ...float64) []*Path {
 	if len(ts) == 0 {
@@ -1044,7 +1381,18 @@ func (p *Path) SplitAt(ts ...float64) []*Path {
 						theta := invL(ts[j] - T)
 						mid, large1, large2, ok := ellipseSplit(rx, ry, phi, cx, cy, startTheta, theta2, theta)
 						if !ok {
-							panic("theta not in elliptic arc range for splitting")
+							// the approximated arc length puts the split position at an end of the arc
+							if ts[j]-T < dT/2.0 {
+								push()
+								q.MoveTo(start.X, start.Y)
+							} else {
+								q.ArcTo(rx, ry, phi*180.0/math.Pi, nextLarge, sweep, end.X, end.Y)
+								push()
+								q.MoveTo(end.X, end.Y)
+								startTheta = theta2
+							}
+							j++
+							continue
 						}
 
 						q.ArcTo(rx, ry, phi*180.0/math.Pi, large1, sweep, mid.X, mid.Y)
@@ -1070,6 +1418,20 @@ func (p *Path) SplitAt(ts ...float64) []*Path {
 	return qs
 }
 
+// SplitN splits the path into n separate paths of equal length along the path, such as for the segments of a progress ring that are colored or animated independently. Unlike Dash, the paths are contiguous and together cover the whole path. If n is less than two or the path has no length, the path is returned as a whole.
+func (p *Path) SplitN(n int) []*Path {
+	length := p.Length()
+	if n < 2 || length <= 0.0 {
+		return []*Path{p}
+	}
+
+	ts := make([]float64, n-1)
+	for i := range ts {
+		ts[i] = float64(i+1) * length / float64(n)
+	}
+	return p.SplitAt(ts...)
+}
+
 //type intersection struct {
 //	i int     // index into path
 //	t float64 // parametric value
@@ -1201,6 +1563,52 @@ func (p *Path) checkDash(offset float64, d []float64) (*Path, []float64) {
 	return p, d
 }
 
+// Partial returns the part of the path between two fractions of its total length, ranging from 0.0 to 1.0, such as to draw a path progressively in an animation. The fractions span all subpaths in order, so that subpaths before the part are left out and only the part of the subpath where a fraction falls is included. The result is meant to be stroked, where subpaths that are cut are open and subpaths that are included in full remain closed. It returns an empty path when to is not larger than from.
+func (p *Path) Partial(from, to float64) *Path {
+	from = math.Max(0.0, math.Min(1.0, from))
+	to = math.Max(0.0, math.Min(1.0, to))
+	q := &Path{}
+	if to <= from {
+		return q
+	}
+
+	ps := p.Split()
+	lengths := make([]float64, len(ps))
+	length := 0.0
+	for i := range ps {
+		lengths[i] = ps[i].Length()
+		length += lengths[i]
+	}
+	a, b := from*length, to*length
+
+	T := 0.0 // length of the preceding subpaths
+	for i := range ps {
+		t0, t1 := a-T, b-T
+		T += lengths[i]
+		if t1 <= 0.0 || lengths[i] <= t0 {
+			continue
+		} else if t0 <= 0.0 && lengths[i] <= t1 {
+			q = q.Append(ps[i])
+			continue
+		}
+
+		ts := []float64{}
+		if 0.0 < t0 {
+			ts = append(ts, t0)
+		}
+		if t1 < lengths[i] {
+			ts = append(ts, t1)
+		}
+		pieces := ps[i].SplitAt(ts...)
+		if 0.0 < t0 {
+			q = q.Append(pieces[1])
+		} else {
+			q = q.Append(pieces[0])
+		}
+	}
+	return q
+}
+
 // Dash returns a new path that consists of dashes. The elements in d specify the width of the dashes and gaps. It will alternate between dashes and gaps when picking widths. If d is an array of odd length, it is equivalent of passing d twice in sequence. The offset specifies the offset used into d (or negative offset onto the path). Dash will be applied to each subpath independently.
 func (p *Path) Dash(offset float64, d ...float64) *Path {
 	offset, d = dashCanonical(offset, d)
@@ -1258,6 +1666,249 @@ func (p *Path) Dash(offset float64, d ...float64) *Path {
 	return q
 }
 
+// RoundCorners returns a new path where the sharp corners between two straight segments are replaced by circular arcs of the given radius that are tangent to both segments. The radius is reduced where the adjacent segments are too short to fit the arc. Corners involving curved segments and collinear vertices are left untouched.
+func (p *Path) RoundCorners(radius float64) *Path {
+	if radius <= 0.0 {
+		return p.Copy()
+	}
+
+	type segment struct {
+		cmd        float64
+		start, end Point
+		d          []float64
+	}
+
+	q := &Path{}
+	for _, ps := range p.Split() {
+		closed := false
+		segs := []segment{}
+		var start, end Point
+		for i := 0; i < len(ps.d); {
+			cmd := ps.d[i]
+			i += cmdLen(cmd)
+
+			start = end
+			end = Point{ps.d[i-3], ps.d[i-2]}
+			if cmd == closeCmd {
+				closed = true
+				if start.Equals(end) {
+					continue
+				}
+			}
+			if cmd != moveToCmd {
+				segs = append(segs, segment{cmd, start, end, ps.d[i-cmdLen(cmd) : i]})
+			}
+		}
+		if len(segs) == 0 {
+			continue
+		}
+
+		// corners[k] is the fillet between segs[k] and segs[k+1], with the tangent points at distance d from the vertex
+		type corner struct {
+			d, r  float64
+			sweep bool
+		}
+		corners := make([]corner, len(segs))
+		for k := range segs {
+			if k+1 == len(segs) && !closed {
+				break
+			}
+			a, b := segs[k], segs[(k+1)%len(segs)]
+			if a.cmd != lineToCmd && a.cmd != closeCmd || b.cmd != lineToCmd && b.cmd != closeCmd {
+				continue
+			}
+			u := a.start.Sub(a.end).Norm(1.0)
+			v := b.end.Sub(b.start).Norm(1.0)
+			theta := math.Acos(math.Max(-1.0, math.Min(1.0, u.Dot(v)))) // angle at the vertex
+			if Equal(theta, 0.0) || Equal(theta, math.Pi) {
+				continue // reversal or collinear
+			}
+
+			t := math.Tan(theta / 2.0)
+			d := radius / t
+			d = math.Min(d, a.start.Sub(a.end).Length()/2.0)
+			d = math.Min(d, b.end.Sub(b.start).Length()/2.0)
+			corners[k] = corner{d, d * t, 0.0 < v.PerpDot(u)}
+		}
+
+		// trim returns the tangent point at the start or end of segs[k]
+		trimStart := func(k int) Point {
+			prev := k - 1
+			if prev < 0 {
+				if !closed {
+					return segs[k].start
+				}
+				prev = len(segs) - 1
+			}
+			return segs[k].start.Interpolate(segs[k].end, corners[prev].d/segs[k].end.Sub(segs[k].start).Length())
+		}
+		trimEnd := func(k int) Point {
+			return segs[k].end.Interpolate(segs[k].start, corners[k].d/segs[k].end.Sub(segs[k].start).Length())
+		}
+
+		pos := trimStart(0)
+		q.MoveTo(pos.X, pos.Y)
+		for k, seg := range segs {
+			switch seg.cmd {
+			case lineToCmd, closeCmd:
+				end := trimEnd(k)
+				q.LineTo(end.X, end.Y)
+			case quadToCmd:
+				q.QuadTo(seg.d[1], seg.d[2], seg.d[3], seg.d[4])
+			case cubeToCmd:
+				q.CubeTo(seg.d[1], seg.d[2], seg.d[3], seg.d[4], seg.d[5], seg.d[6])
+			case arcToCmd:
+				large, sweep := toArcFlags(seg.d[4])
+				q.ArcTo(seg.d[1], seg.d[2], seg.d[3]*180.0/math.Pi, large, sweep, seg.d[5], seg.d[6])
+			}
+			if c := corners[k]; c.d != 0.0 {
+				end := trimStart((k + 1) % len(segs))
+				q.ArcTo(c.r, c.r, 0.0, false, c.sweep, end.X, end.Y)
+			}
+		}
+		if closed {
+			q.Close()
+		}
+	}
+	return q
+}
+
+// ClipRect clips the path to the rectangle and returns a new path, where all parts of the path outside of the rectangle are removed. Subpaths are split where they leave the rectangle and closed subpaths that cross the boundary of the rectangle are opened, this is meant for culling and clipping of strokes and does not preserve the filled area of the path. Line segments are clipped exactly using the Liang-Barsky algorithm, Bézier curves are subdivided until they are inside or outside of the rectangle or flat enough to be clipped as a line, and elliptical arcs are converted to cubic Béziers when they cross the boundary. A path entirely inside the rectangle is returned unchanged, and a path entirely outside returns an empty path.
+func (p *Path) ClipRect(r Rect) *Path {
+	q := &Path{}
+	for _, ps := range p.Split() {
+		bounds := ps.Bounds()
+		if r.ContainsRect(bounds) {
+			q = q.Append(ps)
+			continue
+		} else if !bounds.Overlaps(r) {
+			continue
+		}
+
+		qs := &Path{}
+		connected := false // pen of qs is at the start of the current segment
+		clipLine := func(a, b Point) {
+			d := b.Sub(a)
+			t0, t1 := 0.0, 1.0
+			for _, pq := range [4][2]float64{{-d.X, a.X - r.X}, {d.X, r.X + r.W - a.X}, {-d.Y, a.Y - r.Y}, {d.Y, r.Y + r.H - a.Y}} {
+				if pq[0] == 0.0 {
+					if pq[1] < 0.0 {
+						connected = false
+						return
+					}
+				} else if t := pq[1] / pq[0]; pq[0] < 0.0 {
+					t0 = math.Max(t0, t)
+				} else {
+					t1 = math.Min(t1, t)
+				}
+			}
+			if t1 < t0 {
+				connected = false
+				return
+			}
+			c0, c1 := a.Add(d.Mul(t0)), a.Add(d.Mul(t1))
+			if !connected || 0.0 < t0 {
+				if c0.Equals(c1) {
+					// only touches the rectangle
+					connected = false
+					return
+				}
+				qs.MoveTo(c0.X, c0.Y)
+			}
+			qs.LineTo(c1.X, c1.Y)
+			connected = t1 == 1.0
+		}
+
+		var clipCurve func([]Point)
+		clipCurve = func(cps []Point) {
+			bounds := pointsBounds(cps)
+			if r.ContainsRect(bounds) {
+				if !connected {
+					qs.MoveTo(cps[0].X, cps[0].Y)
+				}
+				if len(cps) == 3 {
+					qs.QuadTo(cps[1].X, cps[1].Y, cps[2].X, cps[2].Y)
+				} else {
+					qs.CubeTo(cps[1].X, cps[1].Y, cps[2].X, cps[2].Y, cps[3].X, cps[3].Y)
+				}
+				connected = true
+				return
+			} else if !bounds.Overlaps(r) {
+				connected = false
+				return
+			}
+
+			// control points within tolerance of the chord
+			start, end := cps[0], cps[len(cps)-1]
+			flat := true
+			for _, cp := range cps[1 : len(cps)-1] {
+				if Tolerance < distanceToLine(cp, start, end) {
+					flat = false
+				}
+			}
+			if flat {
+				clipLine(start, end)
+			} else if len(cps) == 3 {
+				q0, q1, q2, r0, r1, r2 := quadraticBezierSplit(cps[0], cps[1], cps[2], 0.5)
+				clipCurve([]Point{q0, q1, q2})
+				clipCurve([]Point{r0, r1, r2})
+			} else {
+				q0, q1, q2, q3, r0, r1, r2, r3 := cubicBezierSplit(cps[0], cps[1], cps[2], cps[3], 0.5)
+				clipCurve([]Point{q0, q1, q2, q3})
+				clipCurve([]Point{r0, r1, r2, r3})
+			}
+		}
+
+		ps.ReplaceArcs().Iterate(func(_, _ Point) {
+			connected = false
+		}, clipLine, func(p0, p1, p2 Point) {
+			clipCurve([]Point{p0, p1, p2})
+		}, func(p0, p1, p2, p3 Point) {
+			clipCurve([]Point{p0, p1, p2, p3})
+		}, func(Point, float64, float64, float64, bool, bool, Point) {
+			panic("arcs should have been replaced")
+		}, clipLine)
+
+		// rejoin the first and last parts of closed subpaths where they meet at the start
+		if parts := qs.Split(); ps.Closed() && 1 < len(parts) && parts[0].StartPos().Equals(ps.StartPos()) && qs.Pos().Equals(ps.StartPos()) {
+			qs = parts[len(parts)-1].Join(parts[0])
+			for _, part := range parts[1 : len(parts)-1] {
+				qs = qs.Append(part)
+			}
+		}
+		q = q.Append(qs)
+	}
+	return q
+}
+
+func pointsBounds(ps []Point) Rect {
+	xmin, xmax := ps[0].X, ps[0].X
+	ymin, ymax := ps[0].Y, ps[0].Y
+	for _, p := range ps[1:] {
+		xmin, xmax = math.Min(xmin, p.X), math.Max(xmax, p.X)
+		ymin, ymax = math.Min(ymin, p.Y), math.Max(ymax, p.Y)
+	}
+	return Rect{xmin, ymin, xmax - xmin, ymax - ymin}
+}
+
+// distanceToLine returns the distance of p to the line through a and b.
+func distanceToLine(p, a, b Point) float64 {
+	d := b.Sub(a)
+	if d.IsZero() {
+		return p.Sub(a).Length()
+	}
+	return math.Abs(d.PerpDot(p.Sub(a))) / d.Length()
+}
+
+func distanceToSegment(p, a, b Point) float64 {
+	d := b.Sub(a)
+	if d.IsZero() {
+		return p.Sub(a).Length()
+	}
+	t := math.Max(0.0, math.Min(1.0, p.Sub(a).Dot(d)/d.Dot(d)))
+	return p.Sub(a.Add(d.Mul(t))).Length()
+}
+
 // Reverse returns a new path that is the same path as p but in the reverse direction.
 func (p *Path) Reverse() *Path {
 	rp := &Path{}
@@ -1410,10 +2061,12 @@ func ParseSVG(s string) (*Path, error) {
 	var q, c Point
 	var p0, p1 Point
 	prevCmd := byte('z')
-	for {
+	for n := 0; ; n++ {
 		i += skipCommaWhitespace(path[i:])
 		if len(path) <= i {
 			break
+		} else if 0 < MaxPathPoints && MaxPathPoints <= n {
+			return nil, ErrLimitExceeded
 		}
 
 		cmd := prevCmd
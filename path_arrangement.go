@@ -0,0 +1,269 @@
+package canvas
+
+import (
+	"math"
+	"sort"
+)
+
+// Region is a face of the planar arrangement of a set of paths, see Arrangement. Mask has an element for each path, which is true when the region is covered by that path.
+type Region struct {
+	Path *Path
+	Mask []bool
+}
+
+// Arrangement overlays the paths and returns all distinct regions they form, ie. the faces of the planar arrangement of their edges, each tagged with the paths that cover it. Paths are filled using the NonZero fill rule and unclosed subpaths are implicitly closed. Regions that are not covered by any path, such as the space outside of all paths, are not returned. A region may contain holes when other regions are nested inside of it. Curves are flattened, so that the regions consist of linear segments only. Intersections between segments are found by a sweep line over the segments ordered by their left-most coordinate, so that only segments with overlapping bounds are intersected.
+func Arrangement(paths ...*Path) []Region {
+	// flatten all paths into closed polylines and collect their segments
+	segs := []arrangementSegment{}
+	polylines := make([][]*Polyline, len(paths))
+	for i, p := range paths {
+		for _, ps := range p.Split() {
+			polyline := &Polyline{ps.Close().Flatten().Coords()}
+			polylines[i] = append(polylines[i], polyline)
+			for _, seg := range intersectionSegments(polyline.ToPath()) {
+				segs = append(segs, arrangementSegment{seg, nil})
+			}
+		}
+	}
+
+	// find all intersections between segments, including touching end points and collinear overlaps
+	order := make([]int, len(segs))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return segs[order[i]].bounds.X < segs[order[j]].bounds.X
+	})
+	active := []int{}
+	for _, i := range order {
+		// remove the segments that end left of the sweep line
+		x, n := segs[i].bounds.X, 0
+		for _, j := range active {
+			if x <= segs[j].bounds.X+segs[j].bounds.W {
+				active[n] = j
+				n++
+			}
+		}
+		active = active[:n]
+		for _, j := range active {
+			if segs[i].bounds.Overlaps(segs[j].bounds) {
+				segs[i].intersect(&segs[j])
+			}
+		}
+		active = append(active, i)
+	}
+
+	// split segments at their intersections to obtain the edges of the planar graph
+	g := &arrangementGraph{}
+	edges := map[[2]int]bool{}
+	for _, seg := range segs {
+		pts := append(seg.splits, seg.p0, seg.p3)
+		d := seg.p3.Sub(seg.p0)
+		sort.Slice(pts, func(i, j int) bool {
+			return pts[i].Sub(seg.p0).Dot(d) < pts[j].Sub(seg.p0).Dot(d)
+		})
+		prev := g.vertex(pts[0])
+		for _, pt := range pts[1:] {
+			cur := g.vertex(pt)
+			key := [2]int{prev, cur}
+			if cur < prev {
+				key = [2]int{cur, prev}
+			}
+			if prev != cur && !edges[key] {
+				edges[key] = true
+				g.addEdge(prev, cur)
+			}
+			prev = cur
+		}
+	}
+
+	// cycles with a positive area are the outer boundaries of bounded faces, cycles with a negative area are the outer boundaries of connected components and thus the holes of the face containing them
+	faces, holes := [][]int{}, [][]int{}
+	for _, cycle := range g.cycles() {
+		if area := g.area(cycle); 0.0 < area {
+			faces = append(faces, cycle)
+		} else if area < 0.0 {
+			holes = append(holes, cycle)
+		}
+	}
+
+	faceHoles := make([][][]int, len(faces))
+	for _, hole := range holes {
+		// assign to the smallest face of another component that contains the hole
+		pos := g.vertices[hole[0]]
+		component := g.component(hole[0])
+		k, kArea := -1, math.Inf(1)
+		for i, face := range faces {
+			if g.component(face[0]) != component {
+				if area := g.area(face); area < kArea && g.polyline(face).FillCount(pos.X, pos.Y) != 0 {
+					k, kArea = i, area
+				}
+			}
+		}
+		if k != -1 {
+			faceHoles[k] = append(faceHoles[k], hole)
+		}
+	}
+
+	regions := []Region{}
+	for i, face := range faces {
+		// sample a point just left of the longest edge, which is inside the face
+		var a, b Point
+		for j := range face {
+			p0, p1 := g.vertices[face[j]], g.vertices[face[(j+1)%len(face)]]
+			if b.Sub(a).Length() < p1.Sub(p0).Length() {
+				a, b = p0, p1
+			}
+		}
+		test := a.Interpolate(b, 0.5).Add(b.Sub(a).Rot90CCW().Mul(1e-4))
+
+		mask := make([]bool, len(paths))
+		covered := false
+		for j := range paths {
+			count := 0
+			for _, polyline := range polylines[j] {
+				count += polyline.FillCount(test.X, test.Y)
+			}
+			if count != 0 {
+				mask[j] = true
+				covered = true
+			}
+		}
+		if !covered {
+			continue
+		}
+
+		p := g.polyline(face).ToPath()
+		for _, hole := range faceHoles[i] {
+			p = p.Append(g.polyline(hole).ToPath())
+		}
+		regions = append(regions, Region{p, mask})
+	}
+	sort.SliceStable(regions, func(i, j int) bool {
+		// order as binary numbers where the first path is the least significant bit
+		for k := len(paths) - 1; 0 <= k; k-- {
+			if regions[i].Mask[k] != regions[j].Mask[k] {
+				return regions[j].Mask[k]
+			}
+		}
+		return false
+	})
+	return regions
+}
+
+// arrangementSegment is a line segment of a flattened path with the points where it is intersected by other segments.
+type arrangementSegment struct {
+	intersectionSegment
+	splits []Point
+}
+
+// intersect adds the intersection points of both segments to their splits, where end points are kept exact so that they map to the same vertex.
+func (s *arrangementSegment) intersect(t *arrangementSegment) {
+	for _, st := range intersectSegments(s.intersectionSegment, t.intersectionSegment) {
+		var pos Point
+		if st[0] == 0.0 {
+			pos = s.p0
+		} else if st[0] == 1.0 {
+			pos = s.p3
+		} else if st[1] == 0.0 {
+			pos = t.p0
+		} else if st[1] == 1.0 {
+			pos = t.p3
+		} else {
+			pos = s.pos(st[0])
+		}
+		s.splits = append(s.splits, pos)
+		t.splits = append(t.splits, pos)
+	}
+}
+
+// arrangementGraph is a planar graph where each edge consists of two half-edges of opposite direction, half-edge i and i^1 are twins.
+type arrangementGraph struct {
+	vertices []Point
+	out      [][]int // outgoing half-edges per vertex
+	from, to []int   // vertices per half-edge
+	parent   []int   // union-find of connected components per vertex
+}
+
+func (g *arrangementGraph) vertex(pos Point) int {
+	for i, v := range g.vertices {
+		if v.Equals(pos) {
+			return i
+		}
+	}
+	g.vertices = append(g.vertices, pos)
+	g.out = append(g.out, nil)
+	g.parent = append(g.parent, len(g.parent))
+	return len(g.vertices) - 1
+}
+
+func (g *arrangementGraph) addEdge(u, v int) {
+	g.out[u] = append(g.out[u], len(g.from))
+	g.from, g.to = append(g.from, u), append(g.to, v)
+	g.out[v] = append(g.out[v], len(g.from))
+	g.from, g.to = append(g.from, v), append(g.to, u)
+	g.parent[g.component(u)] = g.component(v)
+}
+
+func (g *arrangementGraph) component(v int) int {
+	for g.parent[v] != v {
+		g.parent[v] = g.parent[g.parent[v]]
+		v = g.parent[v]
+	}
+	return v
+}
+
+// cycles returns the vertices of all cycles of half-edges, where each cycle has its face on the left.
+func (g *arrangementGraph) cycles() [][]int {
+	// sort outgoing half-edges counter clockwise
+	index := make([]int, len(g.from)) // index of half-edge in out of its vertex
+	for v, out := range g.out {
+		angles := make(map[int]float64, len(out))
+		for _, e := range out {
+			angles[e] = g.vertices[g.to[e]].Sub(g.vertices[v]).Angle()
+		}
+		sort.Slice(out, func(i, j int) bool {
+			return angles[out[i]] < angles[out[j]]
+		})
+		for i, e := range out {
+			index[e] = i
+		}
+	}
+
+	cycles := [][]int{}
+	visited := make([]bool, len(g.from))
+	for e0 := range g.from {
+		if visited[e0] {
+			continue
+		}
+		cycle := []int{}
+		for e := e0; !visited[e]; {
+			visited[e] = true
+			cycle = append(cycle, g.from[e])
+
+			// turn to the next half-edge clockwise from the twin
+			out := g.out[g.to[e]]
+			e = out[(index[e^1]+len(out)-1)%len(out)]
+		}
+		cycles = append(cycles, cycle)
+	}
+	return cycles
+}
+
+// area returns the signed area of a cycle, which is positive for counter clockwise cycles.
+func (g *arrangementGraph) area(cycle []int) float64 {
+	area := 0.0
+	for i := range cycle {
+		p0, p1 := g.vertices[cycle[i]], g.vertices[cycle[(i+1)%len(cycle)]]
+		area += p0.PerpDot(p1)
+	}
+	return area / 2.0
+}
+
+func (g *arrangementGraph) polyline(cycle []int) *Polyline {
+	coords := make([]Point, 0, len(cycle)+1)
+	for _, v := range cycle {
+		coords = append(coords, g.vertices[v])
+	}
+	return &Polyline{append(coords, g.vertices[cycle[0]])}
+}
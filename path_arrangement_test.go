@@ -0,0 +1,62 @@
+package canvas
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestArrangement(t *testing.T) {
+	var tts = []struct {
+		name     string
+		paths    []*Path
+		masks    [][]bool
+		inside   []Point
+		outside  []Point
+		subpaths []int
+	}{
+		{"overlapping", []*Path{Circle(1.0), Circle(1.0).Translate(1.0, 0.0)},
+			[][]bool{{true, false}, {false, true}, {true, true}}, []Point{{-0.5, 0.0}, {1.5, 0.0}, {0.5, 0.0}}, []Point{{0.5, 0.0}, {0.5, 0.0}, {-0.5, 0.0}}, []int{1, 1, 1}},
+		{"nested", []*Path{Circle(2.0), Circle(1.0)},
+			[][]bool{{true, false}, {true, true}}, []Point{{1.5, 0.0}, {0.0, 0.0}}, []Point{{0.0, 0.0}, {1.5, 0.0}}, []int{2, 1}},
+		{"disjoint", []*Path{Circle(1.0), Circle(1.0).Translate(5.0, 0.0)},
+			[][]bool{{true, false}, {false, true}}, []Point{{0.0, 0.0}, {5.0, 0.0}}, []Point{{5.0, 0.0}, {0.0, 0.0}}, []int{1, 1}},
+		{"adjacent", []*Path{Rectangle(1.0, 1.0), Rectangle(2.0, 1.0).Translate(1.0, 0.0)},
+			[][]bool{{true, false}, {false, true}}, []Point{{0.5, 0.5}, {2.0, 0.5}}, []Point{{1.5, 0.5}, {0.5, 0.5}}, []int{1, 1}},
+	}
+	for _, tt := range tts {
+		t.Run(tt.name, func(t *testing.T) {
+			regions := Arrangement(tt.paths...)
+			test.T(t, len(regions), len(tt.masks))
+			for i, region := range regions {
+				test.T(t, region.Mask, tt.masks[i], fmt.Sprint("mask of region ", i))
+				test.T(t, len(region.Path.Split()), tt.subpaths[i], fmt.Sprint("subpaths of region ", i))
+				test.That(t, region.Path.Interior(tt.inside[i].X, tt.inside[i].Y, NonZero), fmt.Sprint(tt.inside[i], " must be inside region ", i))
+				test.That(t, !region.Path.Interior(tt.outside[i].X, tt.outside[i].Y, NonZero), fmt.Sprint(tt.outside[i], " must be outside region ", i))
+			}
+		})
+	}
+}
+
+func TestArrangementManyPaths(t *testing.T) {
+	// more paths than bits in a machine word
+	paths := []*Path{}
+	for i := 0; i < 70; i++ {
+		paths = append(paths, Rectangle(1.0, 1.0).Translate(2.0*float64(i), 0.0))
+	}
+	paths = append(paths, Rectangle(2.0, 1.0).Translate(138.5, 0.0)) // overlaps half of the last square
+
+	regions := Arrangement(paths...)
+	test.T(t, len(regions), 72)
+	for i, region := range regions[:69] {
+		mask := make([]bool, len(paths))
+		mask[i] = true
+		test.T(t, region.Mask, mask, fmt.Sprint("mask of region ", i))
+		test.That(t, region.Path.Interior(2.0*float64(i)+0.5, 0.5, NonZero), fmt.Sprint("square ", i, " must be inside region ", i))
+	}
+	test.T(t, regions[69].Mask[69], true)
+	test.T(t, regions[70].Mask[70], true)
+	test.T(t, regions[71].Mask[69] && regions[71].Mask[70], true)
+	test.That(t, regions[71].Path.Interior(138.75, 0.5, NonZero), "overlap must be inside the last region")
+}
@@ -0,0 +1,128 @@
+package canvas
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Centroid returns the center of mass of the area of the path, where subpaths are implicitly closed and holes must wind in the opposite direction of their outer contour so that their area is subtracted, see NormalizeWinding. The centroid of a concave shape may lie outside of it, see VisualCenter for placing labels. It returns the center of the bounding box if the path has no area.
+func (p *Path) Centroid() Point {
+	area := 0.0
+	center := Point{}
+	for _, ps := range p.Split() {
+		coords := PolylineFromPath(ps.Close()).coords
+		for i := 1; i < len(coords); i++ {
+			cross := coords[i-1].PerpDot(coords[i])
+			area += cross
+			center = center.Add(coords[i-1].Add(coords[i]).Mul(cross))
+		}
+	}
+	if Equal(area, 0.0) {
+		bounds := p.Bounds()
+		return Point{bounds.X + bounds.W/2.0, bounds.Y + bounds.H/2.0}
+	}
+	return center.Div(3.0 * area)
+}
+
+// VisualCenter returns the pole of inaccessibility of the path, which is the point in the filled area that is farthest from its outline, ie. the center of the largest inscribed circle. It is a better position for labels than the centroid, as it is always inside the shape and away from holes, such as for a crescent or an L-shape. The point is found with the given precision in mm, see https://github.com/mapbox/polylabel. Subpaths are implicitly closed and filled with the NonZero fill rule. It returns the center of the bounding box if the path has no area.
+func (p *Path) VisualCenter(precision float64) Point {
+	bounds := p.Bounds()
+	center := Point{bounds.X + bounds.W/2.0, bounds.Y + bounds.H/2.0}
+	size := math.Min(bounds.W, bounds.H)
+	if size <= 0.0 {
+		return center
+	}
+	if precision <= 0.0 {
+		precision = size / 100.0
+	}
+
+	polylines := []*Polyline{}
+	for _, ps := range p.Split() {
+		polylines = append(polylines, PolylineFromPath(ps.Close()))
+	}
+	newCell := func(c Point, h float64) centerCell {
+		d := boundaryDistance(polylines, c)
+		return centerCell{c, h, d, d + h*math.Sqrt2}
+	}
+
+	// cover the bounding box with square cells and start with the best of the centroid and the center of the bounding box
+	cells := &centerCells{}
+	h := size / 2.0
+	for x := bounds.X; x < bounds.X+bounds.W; x += size {
+		for y := bounds.Y; y < bounds.Y+bounds.H; y += size {
+			heap.Push(cells, newCell(Point{x + h, y + h}, h))
+		}
+	}
+	best := newCell(p.Centroid(), 0.0)
+	if cell := newCell(center, 0.0); best.d < cell.d {
+		best = cell
+	}
+
+	// subdivide the cells that may contain a point farther from the outline than the best point found so far
+	for 0 < cells.Len() {
+		cell := heap.Pop(cells).(centerCell)
+		if best.d < cell.d {
+			best = cell
+		}
+		if cell.max-best.d <= precision {
+			continue
+		}
+		h := cell.h / 2.0
+		heap.Push(cells, newCell(Point{cell.c.X - h, cell.c.Y - h}, h))
+		heap.Push(cells, newCell(Point{cell.c.X + h, cell.c.Y - h}, h))
+		heap.Push(cells, newCell(Point{cell.c.X - h, cell.c.Y + h}, h))
+		heap.Push(cells, newCell(Point{cell.c.X + h, cell.c.Y + h}, h))
+	}
+	if best.d < 0.0 {
+		return center // no area
+	}
+	return best.c
+}
+
+// boundaryDistance returns the distance from the point to the closest segment of the polylines, which is negative when the point is outside of the filled area.
+func boundaryDistance(polylines []*Polyline, c Point) float64 {
+	return -signedDistance(polylines, c, NonZero)
+}
+
+// segmentDistance returns the distance from the point to the closest segment of the polylines.
+func segmentDistance(polylines []*Polyline, c Point) float64 {
+	dist := math.Inf(1)
+	for _, polyline := range polylines {
+		coords := polyline.coords
+		for i := 1; i < len(coords); i++ {
+			a, b := coords[i-1], coords[i]
+			ab := b.Sub(a)
+			t := 0.0
+			if length := ab.Dot(ab); 0.0 < length {
+				t = math.Max(0.0, math.Min(1.0, c.Sub(a).Dot(ab)/length))
+			}
+			dist = math.Min(dist, c.Sub(a.Add(ab.Mul(t))).Length())
+		}
+	}
+	return dist
+}
+
+// centerCell is a square cell with center c and half size h, where d is the distance of its center to the outline and max the largest distance to the outline of any point in the cell.
+type centerCell struct {
+	c      Point
+	h      float64
+	d, max float64
+}
+
+// centerCells is a priority queue of cells that pops the cell with the largest maximum distance first.
+type centerCells []centerCell
+
+func (cells centerCells) Len() int           { return len(cells) }
+func (cells centerCells) Less(i, j int) bool { return cells[i].max > cells[j].max }
+func (cells centerCells) Swap(i, j int)      { cells[i], cells[j] = cells[j], cells[i] }
+
+func (cells *centerCells) Push(cell interface{}) {
+	*cells = append(*cells, cell.(centerCell))
+}
+
+func (cells *centerCells) Pop() interface{} {
+	n := len(*cells)
+	cell := (*cells)[n-1]
+	*cells = (*cells)[:n-1]
+	return cell
+}
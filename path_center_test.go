@@ -0,0 +1,50 @@
+package canvas
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPathCentroid(t *testing.T) {
+	test.T(t, MustParseSVG("M0 0H10V4H0z").Centroid(), Point{5.0, 2.0})
+	test.T(t, MustParseSVG("M0 0H10V10H0zM2 2V8H8V2z").Centroid(), Point{5.0, 5.0})
+	test.T(t, MustParseSVG("M0 0H10").Centroid(), Point{5.0, 0.0})
+
+	// the centroid of an L-shape lies outside of it
+	centroid := MustParseSVG("M0 0H10V2H2V10H0z").Centroid()
+	test.Float(t, centroid.X, 116.0/36.0)
+	test.Float(t, centroid.Y, 116.0/36.0)
+}
+
+func TestPathVisualCenter(t *testing.T) {
+	var tts = []struct {
+		p    string
+		dist float64 // distance to the outline
+	}{
+		{"M0 0H10V4H0z", 2.0},
+		{"M0 0H10V2H2V10H0z", 2.0 / (1.0 + 1.0/math.Sqrt2)},        // touches the inner corner
+		{"M0 0H10V10H0zM2 2V8H8V2z", 2.0 / (1.0 + 1.0/math.Sqrt2)}, // in a corner of the ring around the hole
+		{"M0 0A5 5 0 0 0 10 0A6 6 0 0 1 0 0z", 0.0},                // crescent
+	}
+	for _, tt := range tts {
+		t.Run(tt.p, func(t *testing.T) {
+			p := MustParseSVG(tt.p)
+			center := p.VisualCenter(0.01)
+			test.That(t, p.Interior(center.X, center.Y, NonZero), "must be inside the shape", center)
+			if tt.dist != 0.0 {
+				polylines := []*Polyline{}
+				for _, ps := range p.Split() {
+					polylines = append(polylines, PolylineFromPath(ps))
+				}
+				d := boundaryDistance(polylines, center)
+				test.That(t, tt.dist-0.01 <= d && d <= tt.dist+0.01, "must be far from the outline", d)
+			}
+		})
+	}
+
+	// paths without area
+	test.T(t, MustParseSVG("M0 0H10").VisualCenter(0.01), Point{5.0, 0.0})
+	test.T(t, (&Path{}).VisualCenter(0.01), Point{0.0, 0.0})
+}
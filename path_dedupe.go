@@ -0,0 +1,95 @@
+package canvas
+
+import "math"
+
+// Dedupe returns a new path without the subpaths that are geometrically identical to an earlier subpath, such as shapes that are drawn twice in imported files. Duplicates would waste rendering time and darken twice when drawn with transparency. Two subpaths are identical when they consist of the same segments of which all coordinates, control points and arc parameters differ by at most tolerance, where closed subpaths may start at a different segment and either subpath may run in the opposite direction. Subpaths that are equal within tolerance are thus treated as duplicates even when they are not exactly the same. A tolerance of zero or less uses Epsilon.
+func (p *Path) Dedupe(tolerance float64) *Path {
+	if tolerance <= 0.0 {
+		tolerance = Epsilon
+	}
+	type subpath struct {
+		segs   [][]float64
+		closed bool
+	}
+	kept := []subpath{}
+	q := &Path{}
+	for _, ps := range p.Split() {
+		closed := ps.Closed()
+		segs := dedupeSegments(ps)
+		duplicate := false
+		for _, k := range kept {
+			if k.closed == closed && len(k.segs) == len(segs) {
+				if equalSegments(k.segs, segs, closed, tolerance) || equalSegments(k.segs, dedupeSegments(ps.Reverse()), closed, tolerance) {
+					duplicate = true
+					break
+				}
+			}
+		}
+		if !duplicate {
+			kept = append(kept, subpath{segs, closed})
+			q = q.Append(ps)
+		}
+	}
+	return q
+}
+
+// dedupeSegments returns the segments of a subpath as the start point followed by the values of its command, where Close is a line segment back to the start unless it has zero length.
+func dedupeSegments(p *Path) [][]float64 {
+	segs := [][]float64{}
+	var start Point
+	for i := 0; i < len(p.d); {
+		cmd := p.d[i]
+		n := cmdLen(cmd)
+		end := Point{p.d[i+n-3], p.d[i+n-2]}
+		if cmd == closeCmd {
+			if !start.Equals(end) {
+				segs = append(segs, []float64{lineToCmd, start.X, start.Y, end.X, end.Y})
+			}
+		} else if cmd != moveToCmd {
+			seg := []float64{cmd, start.X, start.Y}
+			seg = append(seg, p.d[i+1:i+n-1]...)
+			segs = append(segs, seg)
+		}
+		start = end
+		i += n
+	}
+	if len(segs) == 0 {
+		// a subpath with only a MoveTo is identified by its position
+		segs = append(segs, []float64{moveToCmd, start.X, start.Y})
+	}
+	return segs
+}
+
+// equalSegments returns true if both lists of segments are equal within tolerance, where for closed subpaths the segments of b may be rotated.
+func equalSegments(a, b [][]float64, closed bool, tolerance float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	offsets := 1
+	if closed {
+		offsets = len(b)
+	}
+	for offset := 0; offset < offsets; offset++ {
+		equal := true
+		for i := range a {
+			sa, sb := a[i], b[(i+offset)%len(b)]
+			if len(sa) != len(sb) || sa[0] != sb[0] {
+				equal = false
+				break
+			}
+			for j := 1; j < len(sa); j++ {
+				if tolerance < math.Abs(sa[j]-sb[j]) {
+					equal = false
+					break
+				}
+			}
+			if !equal {
+				break
+			}
+		}
+		if equal {
+			return true
+		}
+	}
+	return false
+}
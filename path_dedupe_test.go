@@ -0,0 +1,32 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPathDedupe(t *testing.T) {
+	var tts = []struct {
+		p         string
+		tolerance float64
+		r         string
+	}{
+		{"M0 0H5V5H0zM0 0H5V5H0z", 0.0, "M0 0H5V5H0z"},
+		{"M0 0H5V5H0zM5 5H0V0H5z", 0.0, "M0 0H5V5H0z"},    // other start point
+		{"M0 0H5V5H0zM0 0V5H5V0z", 0.0, "M0 0H5V5H0z"},    // reversed
+		{"M0 0H5V5H0zM5 0V5H0V0z", 0.0, "M0 0H5V5H0z"},    // reversed and other start point
+		{"M0 0H5V5H0zM0 0H5.01V5H0z", 0.1, "M0 0H5V5H0z"}, // within tolerance
+		{"M0 0H5V5H0zM0 0H5.01V5H0z", 0.001, "M0 0H5V5H0zM0 0H5.01V5H0z"},
+		{"M0 0H5V5H0zM10 0H15V5H10zM0 0H5V5H0z", 0.0, "M0 0H5V5H0zM10 0H15V5H10z"},
+		{"M0 0L5 5M5 5L0 0", 0.0, "M0 0L5 5"},
+		{"M0 0L5 5L10 0M5 5L10 0L0 0", 0.0, "M0 0L5 5L10 0M5 5L10 0L0 0"}, // open paths are not rotated
+		{"M0 0Q5 5 10 0M10 0Q5 5 0 0", 0.0, "M0 0Q5 5 10 0"},
+		{"M0 0A5 5 0 0 1 10 0zM10 0A5 5 0 0 0 0 0z", 0.0, "M0 0A5 5 0 0 1 10 0z"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.p, func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.p).Dedupe(tt.tolerance), MustParseSVG(tt.r))
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package canvas
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// Mesh is a triangle mesh in 3D space, such as a path extruded for 3D printing, see Path.Extrude. Triangles are indices into Vertices in counter clockwise order when seen from outside the mesh, so that their normals point outwards.
+type Mesh struct {
+	Vertices  [][3]float64
+	Triangles [][3]int
+}
+
+// Extrude returns the mesh of the filled path extruded along the z-axis from zero to depth, such as for raised letters of signage. The front and back faces are the triangulated area of the path and the side walls follow the flattened outline of the path, so that the mesh is closed (watertight). Subpaths at an odd nesting depth are holes, so that the path must not overlap or intersect itself, which is the case for the outlines of most glyphs. Open subpaths are closed implicitly.
+func (p *Path) Extrude(depth float64) Mesh {
+	// contours without duplicate and collinear points, with counter clockwise outlines and clockwise holes
+	coords := []Point{}
+	contours := [][]int{}
+	for _, q := range p.Flatten().Split() {
+		contour := extrudeContour(q.Coords())
+		if len(contour) < 3 {
+			continue
+		}
+		indices := make([]int, len(contour))
+		for i := range contour {
+			indices[i] = len(coords) + i
+		}
+		coords = append(coords, contour...)
+		contours = append(contours, indices)
+	}
+
+	// the parent of each contour is the innermost contour that contains it
+	containers := make([][]int, len(contours))
+	for i, contour := range contours {
+		test := coords[contour[0]]
+		for j, other := range contours {
+			if i != j && polygonContains(coords, other, test) {
+				containers[i] = append(containers[i], j)
+			}
+		}
+	}
+	parents := make([]int, len(contours))
+	for i := range contours {
+		parents[i] = -1
+		for _, j := range containers[i] {
+			if len(containers[j]) == len(containers[i])-1 {
+				parents[i] = j
+			}
+		}
+		hole := len(containers[i])%2 == 1
+		if (0.0 < polygonArea(coords, contours[i])) == hole {
+			reverseIndices(contours[i])
+		}
+	}
+
+	n := len(coords)
+	mesh := Mesh{}
+	for _, coord := range coords {
+		mesh.Vertices = append(mesh.Vertices, [3]float64{coord.X, coord.Y, 0.0})
+	}
+	for _, coord := range coords {
+		mesh.Vertices = append(mesh.Vertices, [3]float64{coord.X, coord.Y, depth})
+	}
+	for i, contour := range contours {
+		if len(containers[i])%2 == 0 {
+			holes := [][]int{}
+			for j := range contours {
+				if parents[j] == i {
+					holes = append(holes, contours[j])
+				}
+			}
+			for _, tri := range triangulatePolygon(coords, bridgeHoles(coords, contour, holes)) {
+				mesh.Triangles = append(mesh.Triangles, [3]int{n + tri[0], n + tri[1], n + tri[2]})
+				mesh.Triangles = append(mesh.Triangles, [3]int{tri[2], tri[1], tri[0]})
+			}
+		}
+
+		// side walls, where the interior is on the left of each edge
+		for k, a := range contour {
+			b := contour[(k+1)%len(contour)]
+			mesh.Triangles = append(mesh.Triangles, [3]int{a, b, n + b})
+			mesh.Triangles = append(mesh.Triangles, [3]int{a, n + b, n + a})
+		}
+	}
+	if depth < 0.0 {
+		for i, tri := range mesh.Triangles {
+			mesh.Triangles[i] = [3]int{tri[2], tri[1], tri[0]}
+		}
+	}
+	return mesh
+}
+
+// WriteOBJ writes the mesh to a Wavefront OBJ file.
+func (m Mesh) WriteOBJ(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	for _, v := range m.Vertices {
+		fmt.Fprintf(b, "v %v %v %v\n", num(v[0]), num(v[1]), num(v[2]))
+	}
+	for _, tri := range m.Triangles {
+		fmt.Fprintf(b, "f %d %d %d\n", tri[0]+1, tri[1]+1, tri[2]+1)
+	}
+	return b.Flush()
+}
+
+// WriteSTL writes the mesh to a binary STL file, as used by slicers for 3D printing. Coordinates are in millimeters.
+func (m Mesh) WriteSTL(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	b.Write(make([]byte, 80)) // header
+	binary.Write(b, binary.LittleEndian, uint32(len(m.Triangles)))
+	for _, tri := range m.Triangles {
+		v0, v1, v2 := m.Vertices[tri[0]], m.Vertices[tri[1]], m.Vertices[tri[2]]
+		normal := meshNormal(v0, v1, v2)
+		for _, v := range [][3]float64{normal, v0, v1, v2} {
+			binary.Write(b, binary.LittleEndian, [3]float32{float32(v[0]), float32(v[1]), float32(v[2])})
+		}
+		binary.Write(b, binary.LittleEndian, uint16(0)) // attribute byte count
+	}
+	return b.Flush()
+}
+
+// meshNormal returns the unit normal of a triangle with counter clockwise vertices, or zero for degenerate triangles.
+func meshNormal(v0, v1, v2 [3]float64) [3]float64 {
+	a := [3]float64{v1[0] - v0[0], v1[1] - v0[1], v1[2] - v0[2]}
+	b := [3]float64{v2[0] - v0[0], v2[1] - v0[1], v2[2] - v0[2]}
+	normal := [3]float64{a[1]*b[2] - a[2]*b[1], a[2]*b[0] - a[0]*b[2], a[0]*b[1] - a[1]*b[0]}
+	length := math.Sqrt(normal[0]*normal[0] + normal[1]*normal[1] + normal[2]*normal[2])
+	if length == 0.0 {
+		return [3]float64{}
+	}
+	return [3]float64{normal[0] / length, normal[1] / length, normal[2] / length}
+}
+
+// extrudeContour returns the points of a closed polygon without the closing point, duplicate points and collinear points.
+func extrudeContour(coords []Point) []Point {
+	contour := []Point{}
+	for _, coord := range coords {
+		if len(contour) == 0 || !coord.Equals(contour[len(contour)-1]) {
+			contour = append(contour, coord)
+		}
+	}
+	for 1 < len(contour) && contour[0].Equals(contour[len(contour)-1]) {
+		contour = contour[:len(contour)-1]
+	}
+	for i := 0; 2 < len(contour) && i < len(contour); {
+		a, b, c := contour[(i+len(contour)-1)%len(contour)], contour[i], contour[(i+1)%len(contour)]
+		if math.Abs(b.Sub(a).PerpDot(c.Sub(a))) <= Epsilon*c.Sub(a).Length() {
+			contour = append(contour[:i], contour[i+1:]...)
+			if 0 < i {
+				i--
+			}
+			continue
+		}
+		i++
+	}
+	return contour
+}
+
+// polygonArea returns the signed area of a polygon, which is positive for counter clockwise polygons.
+func polygonArea(coords []Point, polygon []int) float64 {
+	area := 0.0
+	for k, a := range polygon {
+		b := polygon[(k+1)%len(polygon)]
+		area += coords[a].PerpDot(coords[b])
+	}
+	return area / 2.0
+}
+
+// polygonContains returns true if the point is inside the polygon.
+func polygonContains(coords []Point, polygon []int, test Point) bool {
+	inside := false
+	for k, a := range polygon {
+		p0, p1 := coords[a], coords[polygon[(k+1)%len(polygon)]]
+		if (test.Y < p0.Y) != (test.Y < p1.Y) && test.X < (p1.X-p0.X)*(test.Y-p0.Y)/(p1.Y-p0.Y)+p0.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+func reverseIndices(indices []int) {
+	for i, j := 0, len(indices)-1; i < j; i, j = i+1, j-1 {
+		indices[i], indices[j] = indices[j], indices[i]
+	}
+}
+
+// bridgeHoles merges the clockwise holes into the counter clockwise polygon by connecting each hole to a vertex of the polygon visible from its rightmost vertex, so that the result is a single polygon that can be triangulated by ear clipping. Both vertices of a bridge appear twice in the result. See https://www.geometrictools.com/Documentation/TriangulationByEarClipping.pdf
+func bridgeHoles(coords []Point, polygon []int, holes [][]int) []int {
+	rightmost := func(hole []int) int {
+		m := 0
+		for k := range hole {
+			if coords[hole[m]].X < coords[hole[k]].X {
+				m = k
+			}
+		}
+		return m
+	}
+	sort.SliceStable(holes, func(i, j int) bool {
+		return coords[holes[j][rightmost(holes[j])]].X < coords[holes[i][rightmost(holes[i])]].X
+	})
+
+	polygon = append([]int{}, polygon...)
+	for _, hole := range holes {
+		m := rightmost(hole)
+		M := coords[hole[m]]
+
+		// find the closest edge to the right of M and its endpoint with the largest x
+		j, x := -1, math.Inf(1)
+		for k, a := range polygon {
+			p0, p1 := coords[a], coords[polygon[(k+1)%len(polygon)]]
+			if p0.Y == p1.Y || M.Y < math.Min(p0.Y, p1.Y) || math.Max(p0.Y, p1.Y) < M.Y {
+				continue
+			}
+			xi := p0.X + (M.Y-p0.Y)*(p1.X-p0.X)/(p1.Y-p0.Y)
+			if M.X <= xi && xi < x {
+				x = xi
+				if p0.X < p1.X {
+					j = (k + 1) % len(polygon)
+				} else {
+					j = k
+				}
+			}
+		}
+		if j == -1 {
+			continue // hole does not lie inside the polygon
+		}
+
+		// reflex vertices inside the triangle of M, the intersection and the endpoint may hide the endpoint, of which the one with the smallest angle to the ray is visible
+		I, P := Point{x, M.Y}, coords[polygon[j]]
+		if !I.Equals(P) {
+			best := math.Inf(1)
+			for k, a := range polygon {
+				V := coords[a]
+				prev, next := coords[polygon[(k+len(polygon)-1)%len(polygon)]], coords[polygon[(k+1)%len(polygon)]]
+				if k == j || 0.0 < V.Sub(prev).PerpDot(next.Sub(V)) || !inTriangle(V, M, I, P) {
+					continue
+				}
+				if angle := math.Abs(V.Sub(M).Angle()); angle < best {
+					best, j = angle, k
+				}
+			}
+		}
+
+		bridged := append([]int{}, polygon[:j+1]...)
+		for k := 0; k <= len(hole); k++ {
+			bridged = append(bridged, hole[(m+k)%len(hole)])
+		}
+		bridged = append(bridged, polygon[j:]...)
+		polygon = bridged
+	}
+	return polygon
+}
+
+// inTriangle returns true if the point is inside or on the edges of the triangle, in either orientation.
+func inTriangle(p, a, b, c Point) bool {
+	d0 := b.Sub(a).PerpDot(p.Sub(a))
+	d1 := c.Sub(b).PerpDot(p.Sub(b))
+	d2 := a.Sub(c).PerpDot(p.Sub(c))
+	return !((d0 < 0.0 || d1 < 0.0 || d2 < 0.0) && (0.0 < d0 || 0.0 < d1 || 0.0 < d2))
+}
+
+// triangulatePolygon triangulates a counter clockwise polygon by ear clipping and returns n-2 triangles for n vertices. For degenerate polygons it clips vertices that are not ears to guarantee that all edges of the polygon belong to a triangle.
+func triangulatePolygon(coords []Point, polygon []int) [][3]int {
+	polygon = append([]int{}, polygon...)
+	triangles := make([][3]int, 0, len(polygon))
+	for 3 < len(polygon) {
+		ear, convex := -1, -1
+		for k := range polygon {
+			a, b, c := polygon[(k+len(polygon)-1)%len(polygon)], polygon[k], polygon[(k+1)%len(polygon)]
+			A, B, C := coords[a], coords[b], coords[c]
+			if B.Sub(A).PerpDot(C.Sub(B)) <= 0.0 {
+				continue // reflex or collinear
+			} else if convex == -1 {
+				convex = k
+			}
+			isEar := true
+			for _, v := range polygon {
+				V := coords[v]
+				if !V.Equals(A) && !V.Equals(B) && !V.Equals(C) && inTriangle(V, A, B, C) {
+					isEar = false
+					break
+				}
+			}
+			if isEar {
+				ear = k
+				break
+			}
+		}
+		if ear == -1 {
+			ear = convex
+			if ear == -1 {
+				ear = 0
+			}
+		}
+		k := ear
+		triangles = append(triangles, [3]int{polygon[(k+len(polygon)-1)%len(polygon)], polygon[k], polygon[(k+1)%len(polygon)]})
+		polygon = append(polygon[:k], polygon[k+1:]...)
+	}
+	if len(polygon) == 3 {
+		triangles = append(triangles, [3]int{polygon[0], polygon[1], polygon[2]})
+	}
+	return triangles
+}
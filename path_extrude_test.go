@@ -0,0 +1,104 @@
+package canvas
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+// meshWatertight returns true if every edge of the mesh is shared by exactly two triangles with opposite orientations.
+func meshWatertight(m Mesh) bool {
+	edges := map[[2]int]int{}
+	for _, tri := range m.Triangles {
+		for k := 0; k < 3; k++ {
+			edges[[2]int{tri[k], tri[(k+1)%3]}]++
+		}
+	}
+	for edge, count := range edges {
+		if count != 1 || edges[[2]int{edge[1], edge[0]}] != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// meshVolume returns the signed volume enclosed by the mesh, which is positive for outward normals.
+func meshVolume(m Mesh) float64 {
+	volume := 0.0
+	for _, tri := range m.Triangles {
+		a, b, c := m.Vertices[tri[0]], m.Vertices[tri[1]], m.Vertices[tri[2]]
+		volume += a[0]*(b[1]*c[2]-b[2]*c[1]) - a[1]*(b[0]*c[2]-b[2]*c[0]) + a[2]*(b[0]*c[1]-b[1]*c[0])
+	}
+	return volume / 6.0
+}
+
+func TestPathExtrude(t *testing.T) {
+	// other tests change the tolerances
+	tolerance, epsilon := Tolerance, Epsilon
+	defer func() {
+		Tolerance, Epsilon = tolerance, epsilon
+	}()
+	Tolerance, Epsilon = 0.01, 1e-10
+
+	// square with a square hole of the same orientation
+	p := MustParseSVG("M0 0H10V10H0zM3 3H7V7H3z")
+	mesh := p.Extrude(2.0)
+	test.T(t, len(mesh.Vertices), 16)
+	test.T(t, len(mesh.Triangles), 2*8+2*8) // front and back faces, and side walls
+	test.That(t, meshWatertight(mesh), "mesh must be watertight")
+	test.Float(t, meshVolume(mesh), (100.0-16.0)*2.0)
+
+	// negative depth and a hole that is not convex
+	p = MustParseSVG("M0 0H10V10H0zM2 2V8H8V6H4V4H8V2z")
+	mesh = p.Extrude(-1.0)
+	test.That(t, meshWatertight(mesh), "mesh must be watertight")
+	test.Float(t, meshVolume(mesh), 100.0-28.0)
+
+	// two islands and a nested island in a hole
+	p = Rectangle(10.0, 10.0)
+	p = p.Append(Rectangle(6.0, 6.0).Translate(2.0, 2.0).Reverse())
+	p = p.Append(Rectangle(2.0, 2.0).Translate(4.0, 4.0))
+	p = p.Append(Rectangle(1.0, 1.0).Translate(20.0, 0.0))
+	mesh = p.Extrude(1.0)
+	test.That(t, meshWatertight(mesh), "mesh must be watertight")
+	test.Float(t, meshVolume(mesh), 100.0-36.0+4.0+1.0)
+
+	// curved glyph outlines
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFont(b, FontRegular)
+	face := family.Face(72.0, Black, FontRegular, FontNormal)
+	for _, r := range "oBg" {
+		glyph, _ := face.ToPath(string(r))
+		mesh = glyph.Extrude(3.0)
+		test.That(t, meshWatertight(mesh), "mesh of", string(r), "must be watertight")
+		area := 0.0
+		for _, q := range glyph.Flatten().Split() {
+			coords := q.Coords()
+			for i := 1; i < len(coords); i++ {
+				area += coords[i-1].PerpDot(coords[i]) / 2.0
+			}
+		}
+		test.That(t, math.Abs(meshVolume(mesh)-3.0*math.Abs(area)) < 1e-9*meshVolume(mesh), "volume of", string(r))
+	}
+}
+
+func TestMeshWrite(t *testing.T) {
+	mesh := Rectangle(1.0, 2.0).Extrude(3.0)
+
+	buf := &bytes.Buffer{}
+	test.Error(t, mesh.WriteOBJ(buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	test.T(t, len(lines), 8+12)
+	test.String(t, lines[6], "v 1 2 3")
+	test.String(t, lines[8], "f 8 5 6")
+
+	buf.Reset()
+	test.Error(t, mesh.WriteSTL(buf))
+	test.T(t, buf.Len(), 84+50*12)
+}
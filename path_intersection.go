@@ -1,6 +1,9 @@
 package canvas
 
-import "math"
+import (
+	"math"
+	"sort"
+)
 
 // intersection between two line segments
 // see http://www.cs.swan.ac.uk/~cssimon/line_intersection.html
@@ -62,3 +65,446 @@ func intersectionCircleCircle(c0 Point, r0 float64, c1 Point, r1 float64) (Point
 	i2 := Point{c1.Y - c0.Y, c0.X - c1.X}.Mul(c)
 	return i0.Add(i1).Add(i2), i0.Add(i1).Sub(i2), true
 }
+
+////////////////////////////////////////////////////////////////
+
+// intersectionTolerance is the maximum distance between two paths at an intersection, and intersectionEpsilon is the precision of the numerical methods that find them. Both are independent of Epsilon so that intersections are precise.
+const intersectionTolerance = 1e-8
+const intersectionEpsilon = 1e-12
+
+// Intersection is an intersection point of two paths at Pos, see Path.Intersections. The position along each path is given by the index of the subpath as returned by Path.Split, and by the index of the segment in the subpath plus the parameter in [0,1) along that segment, so that TA=2.5 is halfway the third segment after the MoveTo of subpath SubpathA. For arcs the parameter is linear in the angle. Angle is the angle in radians in (-π,π] from the direction of the first path to the direction of the second path at the intersection, and Tangent is true when both directions are parallel, such as when the paths touch, or when the intersection is an end point of coincident segments.
+type Intersection struct {
+	Pos                Point
+	SubpathA, SubpathB int
+	TA, TB             float64
+	Angle              float64
+	Tangent            bool
+}
+
+// Intersections returns the intersection points between p and q, ordered by their position along p. Intersections between curves are found by recursive subdivision and refined numerically. Paths that touch are returned as tangent intersections, and coincident segments that overlap return the end points of the overlap only. Intersections of a path with itself are not returned, and unclosed subpaths are not implicitly closed.
+func (p *Path) Intersections(q *Path) []Intersection {
+	segsA, segsB := intersectionSegments(p), intersectionSegments(q)
+	zs := []Intersection{}
+	for _, a := range segsA {
+		for _, b := range segsB {
+			if !a.bounds.Overlaps(b.bounds) {
+				continue
+			}
+			for _, st := range intersectSegments(a, b) {
+				s, t := st[0], st[1]
+				beforeA, afterA := onPathSides(segsA, a, s, segsB)
+				beforeB, afterB := onPathSides(segsB, b, t, segsA)
+				if beforeA && afterA || beforeB && afterB {
+					continue // interior of coincident segments
+				}
+
+				// move intersections at the end of a segment to the start of the next
+				a2, s2 := nextSegment(segsA, a, s)
+				b2, t2 := nextSegment(segsB, b, t)
+				da, db := a2.direction(s2), b2.direction(t2)
+				angle := math.Atan2(da.PerpDot(db), da.Dot(db))
+				if angle <= -math.Pi {
+					angle += 2.0 * math.Pi
+				}
+				z := Intersection{
+					Pos:      a.pos(s),
+					SubpathA: a2.subpath,
+					SubpathB: b2.subpath,
+					TA:       float64(a2.index) + s2,
+					TB:       float64(b2.index) + t2,
+					Angle:    angle,
+					Tangent:  math.Abs(math.Sin(angle)) < 1e-6 || beforeA || afterA || beforeB || afterB,
+				}
+
+				duplicate := false
+				for _, z2 := range zs {
+					if z2.SubpathA == z.SubpathA && z2.SubpathB == z.SubpathB && math.Abs(z2.TA-z.TA) < 1e-6 && math.Abs(z2.TB-z.TB) < 1e-6 {
+						duplicate = true
+						break
+					}
+				}
+				if !duplicate {
+					zs = append(zs, z)
+				}
+			}
+		}
+	}
+	sort.SliceStable(zs, func(i, j int) bool {
+		if zs[i].SubpathA != zs[j].SubpathA {
+			return zs[i].SubpathA < zs[j].SubpathA
+		} else if zs[i].TA != zs[j].TA {
+			return zs[i].TA < zs[j].TA
+		} else if zs[i].SubpathB != zs[j].SubpathB {
+			return zs[i].SubpathB < zs[j].SubpathB
+		}
+		return zs[i].TB < zs[j].TB
+	})
+	return zs
+}
+
+// intersectionSegment is a segment of a path in a parametric form, where quadratic Béziers are converted to cubic Béziers and arcs are given in center form.
+type intersectionSegment struct {
+	subpath, index int  // index of the subpath and of the segment in the subpath
+	closed         bool // whether the subpath is closed
+	last           bool // whether the segment is the last of the subpath
+
+	cmd                 float64
+	p0, p1, p2, p3      Point
+	rx, ry, phi, cx, cy float64
+	theta0, theta1      float64
+	bounds              Rect
+}
+
+// intersectionSegments returns all non-degenerate segments of the path.
+func intersectionSegments(p *Path) []intersectionSegment {
+	segs := []intersectionSegment{}
+	for k, ps := range p.Split() {
+		first := len(segs)
+		var start Point
+		index := 0
+		for i := 0; i < len(ps.d); {
+			cmd := ps.d[i]
+			end := Point{ps.d[i+cmdLen(cmd)-3], ps.d[i+cmdLen(cmd)-2]}
+			seg := intersectionSegment{subpath: k, index: index, cmd: cmd, p0: start, p3: end}
+			switch cmd {
+			case lineToCmd, closeCmd:
+				seg.cmd = lineToCmd
+				seg.p1, seg.p2 = start.Interpolate(end, 1.0/3.0), start.Interpolate(end, 2.0/3.0)
+			case quadToCmd:
+				seg.cmd = cubeToCmd
+				seg.p1, seg.p2 = quadraticToCubicBezier(start, Point{ps.d[i+1], ps.d[i+2]}, end)
+			case cubeToCmd:
+				seg.p1, seg.p2 = Point{ps.d[i+1], ps.d[i+2]}, Point{ps.d[i+3], ps.d[i+4]}
+			case arcToCmd:
+				seg.rx, seg.ry, seg.phi = ps.d[i+1], ps.d[i+2], ps.d[i+3]
+				large, sweep := toArcFlags(ps.d[i+4])
+				seg.cx, seg.cy, seg.theta0, seg.theta1 = ellipseToCenter(start.X, start.Y, seg.rx, seg.ry, seg.phi, large, sweep, end.X, end.Y)
+			}
+			if cmd != moveToCmd {
+				if cmd != arcToCmd || seg.theta0 != seg.theta1 {
+					if !start.Equals(end) || cmd == cubeToCmd || cmd == quadToCmd {
+						seg.bounds = seg.pieceBounds(seg.pieces())
+						segs = append(segs, seg)
+					}
+				}
+				index++
+			}
+			start = end
+			i += cmdLen(cmd)
+		}
+		if first < len(segs) {
+			segs[len(segs)-1].last = true
+			closed := ps.Closed()
+			for i := first; i < len(segs); i++ {
+				segs[i].closed = closed
+			}
+		}
+	}
+	return segs
+}
+
+func (s intersectionSegment) pos(t float64) Point {
+	if s.cmd == arcToCmd {
+		return ellipsePos(s.rx, s.ry, s.phi, s.cx, s.cy, s.theta0+t*(s.theta1-s.theta0))
+	}
+	return cubicBezierPos(s.p0, s.p1, s.p2, s.p3, t)
+}
+
+func (s intersectionSegment) deriv(t float64) Point {
+	if s.cmd == arcToCmd {
+		dtheta := s.theta1 - s.theta0
+		return ellipseDeriv(s.rx, s.ry, s.phi, true, s.theta0+t*dtheta).Mul(dtheta)
+	}
+	return cubicBezierDeriv(s.p0, s.p1, s.p2, s.p3, t)
+}
+
+func (s intersectionSegment) deriv2(t float64) Point {
+	if s.cmd == arcToCmd {
+		dtheta := s.theta1 - s.theta0
+		return ellipseDeriv2(s.rx, s.ry, s.phi, true, s.theta0+t*dtheta).Mul(dtheta * dtheta)
+	}
+	return cubicBezierDeriv2(s.p0, s.p1, s.p2, s.p3, t)
+}
+
+// direction returns the direction of the segment at t, which is also valid for Béziers with coinciding control points.
+func (s intersectionSegment) direction(t float64) Point {
+	d := s.deriv(t)
+	if d.Length() < intersectionEpsilon {
+		if t < 0.5 {
+			d = s.pos(math.Min(t+1e-6, 1.0)).Sub(s.pos(t))
+		} else {
+			d = s.pos(t).Sub(s.pos(math.Max(t-1e-6, 0.0)))
+		}
+	}
+	return d
+}
+
+// intersectionPiece is a cubic Bézier that covers the parameter range [t0,t1] of a segment.
+type intersectionPiece struct {
+	p      [4]Point
+	t0, t1 float64
+}
+
+// pieces returns cubic Béziers that cover the segment, where arcs are approximated by cubic Béziers.
+func (s intersectionSegment) pieces() []intersectionPiece {
+	if s.cmd != arcToCmd {
+		return []intersectionPiece{{[4]Point{s.p0, s.p1, s.p2, s.p3}, 0.0, 1.0}}
+	}
+	large, sweep := math.Pi < math.Abs(s.theta1-s.theta0), s.theta0 < s.theta1
+	beziers := ellipseToCubicBeziers(s.p0, s.rx, s.ry, s.phi, large, sweep, s.p3)
+	pieces := make([]intersectionPiece, len(beziers))
+	for i, bezier := range beziers {
+		pieces[i] = intersectionPiece{bezier, float64(i) / float64(len(beziers)), float64(i+1) / float64(len(beziers))}
+	}
+	return pieces
+}
+
+func (s intersectionSegment) pieceBounds(pieces []intersectionPiece) Rect {
+	r := pieces[0].bounds()
+	for _, piece := range pieces[1:] {
+		r = r.Add(piece.bounds())
+	}
+	return r
+}
+
+// bounds returns the bounds of the control points, which contain the Bézier, enlarged slightly to account for the approximation of arcs.
+func (piece intersectionPiece) bounds() Rect {
+	x0, x1 := piece.p[0].X, piece.p[0].X
+	y0, y1 := piece.p[0].Y, piece.p[0].Y
+	for _, p := range piece.p[1:] {
+		x0, x1 = math.Min(x0, p.X), math.Max(x1, p.X)
+		y0, y1 = math.Min(y0, p.Y), math.Max(y1, p.Y)
+	}
+	margin := 1e-6 * math.Max(x1-x0, y1-y0)
+	return Rect{x0 - margin, y0 - margin, x1 - x0 + 2.0*margin, y1 - y0 + 2.0*margin}
+}
+
+func (piece intersectionPiece) split() (intersectionPiece, intersectionPiece) {
+	q0, q1, q2, q3, r0, r1, r2, r3 := cubicBezierSplit(piece.p[0], piece.p[1], piece.p[2], piece.p[3], 0.5)
+	tm := (piece.t0 + piece.t1) / 2.0
+	return intersectionPiece{[4]Point{q0, q1, q2, q3}, piece.t0, tm}, intersectionPiece{[4]Point{r0, r1, r2, r3}, tm, piece.t1}
+}
+
+// flat returns true if the control points are close to the chord of the Bézier.
+func (piece intersectionPiece) flat() bool {
+	d := piece.p[3].Sub(piece.p[0])
+	length := d.Length()
+	if length < intersectionEpsilon {
+		return piece.p[1].Sub(piece.p[0]).Length() < intersectionEpsilon && piece.p[2].Sub(piece.p[0]).Length() < intersectionEpsilon
+	}
+	deviation := math.Max(math.Abs(d.PerpDot(piece.p[1].Sub(piece.p[0]))), math.Abs(d.PerpDot(piece.p[2].Sub(piece.p[0])))) / length
+	return deviation < 1e-3*length
+}
+
+// intersectSegments returns the parameters of the intersections of both segments.
+func intersectSegments(a, b intersectionSegment) [][2]float64 {
+	sts := [][2]float64{}
+	add := func(s, t float64) {
+		for _, st := range sts {
+			if math.Abs(st[0]-s) < 1e-9 && math.Abs(st[1]-t) < 1e-9 {
+				return
+			}
+		}
+		sts = append(sts, [2]float64{s, t})
+	}
+
+	// the end points of coincident segments are found by projecting the end points on the other segment
+	for _, s := range []float64{0.0, 1.0} {
+		if t, ok := b.project(a.pos(s)); ok {
+			add(s, t)
+		}
+	}
+	for _, t := range []float64{0.0, 1.0} {
+		if s, ok := a.project(b.pos(t)); ok {
+			add(s, t)
+		}
+	}
+
+	var rec func(pa, pb intersectionPiece, depth int)
+	rec = func(pa, pb intersectionPiece, depth int) {
+		if !pa.bounds().Overlaps(pb.bounds()) {
+			return
+		}
+		flatA, flatB := pa.flat(), pb.flat()
+		if depth == 0 || flatA && flatB {
+			// initial guess from the intersection of the chords
+			u, v := 0.5, 0.5
+			da, db := pa.p[3].Sub(pa.p[0]), pb.p[3].Sub(pb.p[0])
+			if div := da.PerpDot(db); intersectionEpsilon < math.Abs(div) {
+				u = math.Max(0.0, math.Min(1.0, pb.p[0].Sub(pa.p[0]).PerpDot(db)/div))
+				v = math.Max(0.0, math.Min(1.0, pb.p[0].Sub(pa.p[0]).PerpDot(da)/div))
+			}
+			s, t := pa.t0+u*(pa.t1-pa.t0), pb.t0+v*(pb.t1-pb.t0)
+			if s, t, ok := refineIntersection(a, b, s, t); ok {
+				add(s, t)
+			}
+			return
+		}
+		if flatA {
+			pb0, pb1 := pb.split()
+			rec(pa, pb0, depth-1)
+			rec(pa, pb1, depth-1)
+		} else if flatB {
+			pa0, pa1 := pa.split()
+			rec(pa0, pb, depth-1)
+			rec(pa1, pb, depth-1)
+		} else {
+			pa0, pa1 := pa.split()
+			pb0, pb1 := pb.split()
+			rec(pa0, pb0, depth-1)
+			rec(pa0, pb1, depth-1)
+			rec(pa1, pb0, depth-1)
+			rec(pa1, pb1, depth-1)
+		}
+	}
+	for _, pa := range a.pieces() {
+		for _, pb := range b.pieces() {
+			rec(pa, pb, 24)
+		}
+	}
+	return sts
+}
+
+// refineIntersection refines the parameters of an intersection of both segments using Newton's method, first for a crossing and otherwise for the closest points of touching segments. It returns false if the segments do not intersect near the initial parameters.
+func refineIntersection(a, b intersectionSegment, s, t float64) (float64, float64, bool) {
+	clamp := func(x float64) float64 {
+		return math.Max(0.0, math.Min(1.0, x))
+	}
+
+	// solve A(s) = B(t)
+	s0, t0 := s, t
+	for i := 0; i < 32; i++ {
+		f := a.pos(s0).Sub(b.pos(t0))
+		if f.Length() < intersectionEpsilon {
+			break
+		}
+		da, db := a.deriv(s0), b.deriv(t0)
+		div := da.PerpDot(db)
+		if math.Abs(div) < intersectionEpsilon {
+			break
+		}
+		s0, t0 = clamp(s0-f.PerpDot(db)/div), clamp(t0+da.PerpDot(f)/div)
+	}
+	if a.pos(s0).Sub(b.pos(t0)).Length() < intersectionTolerance {
+		return s0, t0, true
+	}
+
+	// minimize |A(s)-B(t)|, which finds touching segments
+	for i := 0; i < 64; i++ {
+		f := a.pos(s).Sub(b.pos(t))
+		da, db := a.deriv(s), b.deriv(t)
+		gs, gt := f.Dot(da), -f.Dot(db)
+		hss := da.Dot(da) + f.Dot(a.deriv2(s))
+		htt := db.Dot(db) - f.Dot(b.deriv2(t))
+		hst := -da.Dot(db)
+		det := hss*htt - hst*hst
+		if math.Abs(det) < intersectionEpsilon*intersectionEpsilon {
+			break
+		}
+		ds, dt := (htt*gs-hst*gt)/det, (hss*gt-hst*gs)/det
+		s, t = clamp(s-ds), clamp(t-dt)
+		if math.Abs(ds) < 1e-15 && math.Abs(dt) < 1e-15 {
+			break
+		}
+	}
+	if a.pos(s).Sub(b.pos(t)).Length() < intersectionTolerance {
+		return s, t, true
+	}
+	return 0.0, 0.0, false
+}
+
+// project returns the parameter of the point on the segment closest to p, and true if p is on the segment.
+func (s intersectionSegment) project(p Point) (float64, bool) {
+	best, bestDist := 0.0, math.Inf(1)
+	for _, t := range []float64{0.0, 0.25, 0.5, 0.75, 1.0} {
+		for i := 0; i < 32; i++ {
+			f := s.pos(t).Sub(p)
+			d := s.deriv(t)
+			h := d.Dot(d) + f.Dot(s.deriv2(t))
+			if math.Abs(h) < intersectionEpsilon {
+				break
+			}
+			dt := f.Dot(d) / h
+			t = math.Max(0.0, math.Min(1.0, t-dt))
+			if math.Abs(dt) < 1e-15 {
+				break
+			}
+		}
+		if dist := s.pos(t).Sub(p).Length(); dist < bestDist {
+			best, bestDist = t, dist
+		}
+	}
+	return best, bestDist < intersectionTolerance
+}
+
+// onPathSides returns whether the path of segment seg lies on the other path just before and just after parameter t, which is the case in the interior and at the end points of coincident segments.
+func onPathSides(segs []intersectionSegment, seg intersectionSegment, t float64, other []intersectionSegment) (bool, bool) {
+	const dt = 1e-3
+	onPath := func(t float64) bool {
+		p, ok := segmentPos(segs, seg, t)
+		if !ok {
+			return false
+		}
+		for _, o := range other {
+			if _, ok := o.project(p); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return onPath(t - dt), onPath(t + dt)
+}
+
+// segmentPos returns the position at t on the segment, where t outside [0,1] continues on the previous or next segment of the subpath. It returns false if the subpath is not closed and t is beyond its start or end.
+func segmentPos(segs []intersectionSegment, seg intersectionSegment, t float64) (Point, bool) {
+	if 0.0 <= t && t <= 1.0 {
+		return seg.pos(t), true
+	}
+	i := 0
+	for segs[i].subpath != seg.subpath || segs[i].index != seg.index {
+		i++
+	}
+	if t < 0.0 {
+		if 0 < i && segs[i-1].subpath == seg.subpath && segs[i-1].pos(1.0).Equals(seg.p0) {
+			return segs[i-1].pos(1.0 + t), true
+		} else if seg.closed {
+			j := i
+			for !segs[j].last {
+				j++
+			}
+			return segs[j].pos(1.0 + t), true
+		}
+		return Point{}, false
+	}
+	if !seg.last && segs[i+1].p0.Equals(seg.p3) {
+		return segs[i+1].pos(t - 1.0), true
+	} else if seg.last && seg.closed {
+		j := i
+		for 0 < j && segs[j-1].subpath == seg.subpath {
+			j--
+		}
+		return segs[j].pos(t - 1.0), true
+	}
+	return Point{}, false
+}
+
+// nextSegment returns the next segment and t=0 when t is at the end of the segment, so that intersections at the joints of segments have a unique position.
+func nextSegment(segs []intersectionSegment, seg intersectionSegment, t float64) (intersectionSegment, float64) {
+	if t < 1.0-1e-9 {
+		return seg, t
+	}
+	i := 0
+	for segs[i].subpath != seg.subpath || segs[i].index != seg.index {
+		i++
+	}
+	if !seg.last {
+		return segs[i+1], 0.0
+	} else if seg.closed {
+		j := i
+		for 0 < j && segs[j-1].subpath == seg.subpath {
+			j--
+		}
+		return segs[j], 0.0
+	}
+	return seg, 1.0
+}
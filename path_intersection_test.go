@@ -2,6 +2,7 @@ package canvas
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/tdewolff/test"
@@ -69,3 +70,46 @@ func TestIntersectionCircleCircle(t *testing.T) {
 		})
 	}
 }
+
+func TestPathIntersections(t *testing.T) {
+	// two circles intersect at two points
+	zs := Circle(2.0).Intersections(Circle(2.0).Translate(2.0, 0.0))
+	test.T(t, len(zs), 2)
+	test.T(t, zs[0].Pos, Point{1.0, math.Sqrt(3.0)})
+	test.T(t, zs[1].Pos, Point{1.0, -math.Sqrt(3.0)})
+	test.Float(t, zs[0].TA, 1.0/3.0) // 60 degrees along the first arc
+	test.Float(t, zs[0].TB, 2.0/3.0)
+	test.Float(t, zs[0].Angle, math.Pi/3.0)
+	test.That(t, !zs[0].Tangent)
+	test.Float(t, zs[1].TA, 1.0+2.0/3.0)
+
+	var tts = []struct {
+		p, q    *Path
+		pos     []Point
+		tangent []bool
+	}{
+		{Circle(1.0), Circle(1.0).Translate(3.0, 0.0), []Point{}, []bool{}},
+		{Circle(1.0), Circle(1.0).Translate(2.0, 0.0), []Point{{1.0, 0.0}}, []bool{true}},
+		{Circle(1.0), MustParseSVG("M-2 1L2 1"), []Point{{0.0, 1.0}}, []bool{true}},
+		{Rectangle(2.0, 2.0), Rectangle(2.0, 2.0).Translate(1.0, 1.0), []Point{{2.0, 1.0}, {1.0, 2.0}}, []bool{false, false}},
+		{MustParseSVG("M0 0C1 2 2 -2 3 0"), MustParseSVG("M0 0L3 0"), []Point{{0.0, 0.0}, {1.5, 0.0}, {3.0, 0.0}}, []bool{false, false, false}},
+		{MustParseSVG("M0 0Q1 2 2 0"), MustParseSVG("M0 1L2 1"), []Point{{1.0, 1.0}}, []bool{true}},
+
+		// coincident segments return the end points of the overlap
+		{Rectangle(2.0, 2.0), Rectangle(2.0, 2.0).Translate(1.0, 0.0), []Point{{1.0, 0.0}, {2.0, 0.0}, {2.0, 2.0}, {1.0, 2.0}}, []bool{true, true, true, true}},
+		{MustParseSVG("M0 0L4 0"), MustParseSVG("M1 0L2 0L3 0"), []Point{{1.0, 0.0}, {3.0, 0.0}}, []bool{true, true}},
+		{Circle(1.0), Circle(1.0), []Point{}, []bool{}},
+	}
+	for i, tt := range tts {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			zs := tt.p.Intersections(tt.q)
+			test.T(t, len(zs), len(tt.pos))
+			for j := range zs {
+				if j < len(tt.pos) {
+					test.T(t, zs[j].Pos, tt.pos[j])
+					test.T(t, zs[j].Tangent, tt.tangent[j])
+				}
+			}
+		})
+	}
+}
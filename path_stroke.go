@@ -576,3 +576,121 @@ func (p *Path) Stroke(w float64, cr Capper, jr Joiner) *Path {
 	}
 	return q
 }
+
+// VariableStroke converts a path into a stroke of varying width and returns a new path. The width is given by widthAt for t in [0,1], which is the fraction of the arc length along each subpath, and allows for tapering or pressure profiles. It uses jr to join path elements and butt caps at the start and end of the path. Curves are flattened, and segments are subdivided where the width deviates from linear interpolation by more than Tolerance so that rapidly changing widths are followed closely. Where the width reaches zero both sides of the stroke meet at the path, such as for a sharp taper.
+func (p *Path) VariableStroke(widthAt func(float64) float64, jr Joiner) *Path {
+	q := &Path{}
+	for _, ps := range p.Split() {
+		coords := []Point{}
+		for _, coord := range ps.Flatten().Coords() {
+			if len(coords) == 0 || !coord.Equals(coords[len(coords)-1]) {
+				coords = append(coords, coord)
+			}
+		}
+		if len(coords) < 2 {
+			continue
+		}
+
+		dists := make([]float64, len(coords))
+		for i := 1; i < len(coords); i++ {
+			dists[i] = dists[i-1] + coords[i].Sub(coords[i-1]).Length()
+		}
+		length := dists[len(dists)-1]
+		halfWidthAt := func(d float64) float64 {
+			return math.Max(0.0, widthAt(d/length)/2.0)
+		}
+
+		pts, halfWidths := []Point{coords[0]}, []float64{halfWidthAt(0.0)}
+		for i := 1; i < len(coords); i++ {
+			pts, halfWidths = subdivideVariableStroke(pts, halfWidths, coords[i-1], coords[i], dists[i-1], dists[i], halfWidths[len(halfWidths)-1], halfWidthAt(dists[i]), halfWidthAt, 0)
+		}
+
+		rhs, lhs := offsetVariableSegment(pts, halfWidths, ps.Closed(), jr)
+		if lhs != nil { // closed path
+			// inner path should go opposite direction to cancel the outer path
+			if ps.CCW() {
+				q = q.Append(rhs)
+				q = q.Append(lhs.Reverse())
+			} else {
+				q = q.Append(lhs)
+				q = q.Append(rhs.Reverse())
+			}
+		} else {
+			q = q.Append(rhs)
+		}
+	}
+	return q
+}
+
+// subdivideVariableStroke appends the line segment from p0 to p1 to pts, recursively subdividing it while the half width at its middle deviates from the linear interpolation of the half widths at its ends.
+func subdivideVariableStroke(pts []Point, halfWidths []float64, p0, p1 Point, d0, d1, h0, h1 float64, halfWidthAt func(float64) float64, depth int) ([]Point, []float64) {
+	dm := (d0 + d1) / 2.0
+	hm := halfWidthAt(dm)
+	if depth < 10 && Tolerance < math.Abs(hm-(h0+h1)/2.0) {
+		pm := p0.Interpolate(p1, 0.5)
+		pts, halfWidths = subdivideVariableStroke(pts, halfWidths, p0, pm, d0, dm, h0, hm, halfWidthAt, depth+1)
+		return subdivideVariableStroke(pts, halfWidths, pm, p1, dm, d1, hm, h1, halfWidthAt, depth+1)
+	}
+	return append(pts, p1), append(halfWidths, h1)
+}
+
+// offsetVariableSegment returns the rhs and lhs paths from offsetting a polyline by a half width that is given per point, similar to offsetSegment. For closed polylines the last point must equal the first.
+func offsetVariableSegment(pts []Point, halfWidths []float64, closed bool, jr Joiner) (*Path, *Path) {
+	n := len(pts) - 1
+	normals := make([]Point, n)
+	for i := 0; i < n; i++ {
+		normals[i] = pts[i+1].Sub(pts[i]).Rot90CW().Norm(1.0)
+	}
+
+	rhs, lhs := &Path{}, &Path{}
+	rStart := pts[0].Add(normals[0].Mul(halfWidths[0]))
+	lStart := pts[0].Sub(normals[0].Mul(halfWidths[0]))
+	rhs.MoveTo(rStart.X, rStart.Y)
+	lhs.MoveTo(lStart.X, lStart.Y)
+
+	rhsInnerBends := []int{}
+	lhsInnerBends := []int{}
+	for i := 0; i < n; i++ {
+		halfWidth := halfWidths[i+1]
+		rEnd := pts[i+1].Add(normals[i].Mul(halfWidth))
+		lEnd := pts[i+1].Sub(normals[i].Mul(halfWidth))
+		rhs.LineTo(rEnd.X, rEnd.Y)
+		lhs.LineTo(lEnd.X, lEnd.Y)
+
+		// join the cur and next path segments
+		if i+1 < n || closed {
+			n0 := normals[i].Mul(halfWidth)
+			n1 := normals[(i+1)%n].Mul(halfWidth)
+			if !n0.Equals(n1) {
+				jr.Join(rhs, lhs, halfWidth, pts[i+1], n0, n1, math.NaN(), math.NaN())
+
+				if !n0.Equals(n1.Neg()) {
+					// all turns except 0 degrees and 180 degrees are added
+					cw := n0.Rot90CW().Dot(n1) >= 0.0
+					if cw {
+						rhsInnerBends = append(rhsInnerBends, len(rhs.d)-cmdLen(lineToCmd))
+					} else {
+						lhsInnerBends = append(lhsInnerBends, len(lhs.d)-cmdLen(lineToCmd))
+					}
+				}
+			}
+		}
+	}
+
+	closeInnerBends(rhs, rhsInnerBends, closed)
+	closeInnerBends(lhs, lhsInnerBends, closed)
+
+	if closed {
+		rhs.Close()
+		lhs.Close()
+		return rhs, lhs
+	}
+
+	// default to CCW direction
+	lhs = lhs.Reverse()
+	ButtCap.Cap(rhs, halfWidths[n], pts[n], normals[n-1].Mul(halfWidths[n]))
+	rhs = rhs.Join(lhs)
+	ButtCap.Cap(rhs, halfWidths[0], pts[0], normals[0].Mul(-halfWidths[0]))
+	rhs.Close()
+	return rhs, nil
+}
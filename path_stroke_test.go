@@ -109,3 +109,36 @@ func TestPathOffset(t *testing.T) {
 		})
 	}
 }
+
+func TestPathVariableStroke(t *testing.T) {
+	Tolerance = 0.01
+	taper := func(t float64) float64 { return 10.0 * (1.0 - t) }
+	var tts = []struct {
+		orig    string
+		widthAt func(float64) float64
+		jr      Joiner
+		stroke  string
+	}{
+		{"M0 0L100 0", taper, RoundJoin, "M0 -5L100 0L0 5z"},
+		{"M0 0L100 0", func(float64) float64 { return 2.0 }, RoundJoin, "M0 -1L100 -1L100 1L0 1z"},
+		{"M0 0L10 0L10 10", func(float64) float64 { return 2.0 }, BevelJoin, "M0 -1L10 -1L11 0L11 10L9 10L9 1L0 1z"},
+		{"M0 0L10 0L10 10", func(t float64) float64 { return 4.0 * (1.0 - t) }, BevelJoin, "M0 -2L10 -1L11 0L10 10L9.10891089108911 1.0891089108910892L0 2z"},
+	}
+	for j, tt := range tts {
+		t.Run(fmt.Sprintf("%v", j), func(t *testing.T) {
+			stroke := MustParseSVG(tt.orig).VariableStroke(tt.widthAt, tt.jr)
+			test.T(t, stroke, MustParseSVG(tt.stroke))
+		})
+	}
+
+	// rapidly changing width is followed closely
+	wave := func(t float64) float64 { return 2.0 + math.Sin(7.0*math.Pi*t) }
+	stroke := MustParseSVG("M0 0L100 0").VariableStroke(wave, RoundJoin)
+	test.That(t, 32 < len(stroke.Coords()), "must subdivide the path")
+	test.That(t, math.Abs(stroke.Bounds().H-3.0) < Tolerance, "must follow the width closely")
+
+	// closed paths result in an inner and outer path
+	stroke = MustParseSVG("M0 0L10 0L10 10L0 10z").VariableStroke(func(float64) float64 { return 2.0 }, BevelJoin)
+	test.T(t, len(stroke.Split()), 2)
+	test.Float(t, stroke.Bounds().W, 12.0)
+}
@@ -188,6 +188,61 @@ func TestPathFilling(t *testing.T) {
 	test.T(t, fillings[0], true)
 }
 
+func TestPathNormalizeWinding(t *testing.T) {
+	var tts = []struct {
+		orig     string
+		expected string
+	}{
+		{"L10 0L10 10L0 10zM2 2L2 8L8 8L8 2z", "L10 0L10 10L0 10zM2 2L2 8L8 8L8 2z"}, // outer CCW, inner CW
+		{"L10 0L10 10L0 10zM2 2L8 2L8 8L2 8z", "L10 0L10 10L0 10zM2 2L2 8L8 8L8 2z"}, // outer CCW, inner CCW
+		{"L0 10L10 10L10 0zM2 2L8 2L8 8L2 8z", "L10 0L10 10L0 10zM2 2L2 8L8 8L8 2z"}, // outer CW, inner CCW
+		{"L0 10L10 10L10 0zM3 3L3 7L7 7L7 3zM4 4L4 6L6 6L6 4z", "L10 0L10 10L0 10zM3 3L3 7L7 7L7 3zM4 4L6 4L6 6L4 6z"},
+		{"L10 0L10 10L0 10zM12 0L12 10L22 10L22 0z", "L10 0L10 10L0 10zM12 0L22 0L22 10L12 10z"}, // two outers
+		{"M2 2L8 2L8 8L2 8zM0 0L10 0L10 10L0 10z", "M2 2L2 8L8 8L8 2zM0 0L10 0L10 10L0 10z"},     // hole before outer
+		{"L10 0L10 10L0 10zM5 5L15 5L15 15L5 15z", "L10 0L10 10L0 10zM5 5L15 5L15 15L5 15z"},     // overlapping outers
+		{"L10 0L10 10L0 10zM5 5L5 15L15 15L15 5z", "L10 0L10 10L0 10zM5 5L15 5L15 15L5 15z"},     // overlapping outers, one CW
+	}
+	for _, tt := range tts {
+		t.Run(tt.orig, func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.orig).NormalizeWinding(NonZero), MustParseSVG(tt.expected))
+		})
+	}
+
+	// donut with the hole in the same direction as the outer contour
+	donut := MustParseSVG("M0 0L10 0L10 10L0 10zM2 2L8 2L8 8L2 8z")
+	test.That(t, donut.Interior(5.0, 5.0, NonZero), "hole must be filled before normalization")
+	for _, fillRule := range []FillRule{NonZero, EvenOdd} {
+		p := donut.NormalizeWinding(fillRule)
+		test.That(t, p.Interior(1.0, 1.0, fillRule), "outer must be filled")
+		test.That(t, !p.Interior(5.0, 5.0, fillRule), "hole must be empty")
+	}
+
+	// overlapping contours that are not nested, where the first edge of each contour lies inside the other
+	test.T(t, donut.NormalizeWinding(EvenOdd), donut)
+
+	overlap := MustParseSVG("M5 0L5 10L-5 10L-5 0zM0 5L10 5L10 15L0 15z").NormalizeWinding(NonZero)
+	test.That(t, overlap.Interior(2.5, 7.5, NonZero), "overlap must be filled")
+	test.That(t, overlap.Interior(-2.5, 2.5, NonZero), "first contour must be filled")
+	test.That(t, overlap.Interior(7.5, 12.5, NonZero), "second contour must be filled")
+}
+
+func TestPathResolveOpenPaths(t *testing.T) {
+	closed := MustParseSVG("M20 0L30 0L30 10z")
+	p, err := closed.ResolveOpenPaths(ErrorOpenPaths)
+	test.Error(t, err)
+	test.That(t, p == closed, "must return closed paths unchanged")
+
+	mixed := MustParseSVG("M0 0L10 0L10 10M20 0L30 0L30 10z")
+	p, err = mixed.ResolveOpenPaths(CloseOpenPaths)
+	test.Error(t, err)
+	test.T(t, p, MustParseSVG("M0 0L10 0L10 10zM20 0L30 0L30 10z"))
+	p, err = mixed.ResolveOpenPaths(SkipOpenPaths)
+	test.Error(t, err)
+	test.T(t, p, closed)
+	_, err = mixed.ResolveOpenPaths(ErrorOpenPaths)
+	test.T(t, err, ErrOpenPath)
+}
+
 func TestPathInterior(t *testing.T) {
 	test.That(t, MustParseSVG("L10 0L10 10L0 10zM2 2L8 2L8 8L2 8z").Interior(1, 1, NonZero))
 	test.That(t, MustParseSVG("L10 0L10 10L0 10zM2 2L8 2L8 8L2 8z").Interior(3, 3, NonZero))
@@ -282,6 +337,79 @@ func TestPathTransform(t *testing.T) {
 	}
 }
 
+func TestPathSnap(t *testing.T) {
+	var tts = []struct {
+		orig         string
+		gridX, gridY float64
+		res          string
+	}{
+		{"M0.1 -0.2L9.9 0.3L10.2 4.8L-0.1 5.1z", 1.0, 1.0, "M0 0L10 0L10 5L0 5z"},
+		{"M0.1 0.2L10.4 0.3", 0.5, 0.0, "M0 0.2L10.5 0.3"},
+		{"M0.2 0.2Q4.9 5.1 10.1 0C13.2 4.9 17.1 5.2 19.8 0.1A5.1 5.1 0 0 0 29.8 0", 1.0, 1.0, "M0 0Q5 5 10 0C13 5 17 5 20 0A5.1 5.1 0 0 0 30 0"},
+		{"M0 0L0.2 0.1L10 0.1z", 1.0, 1.0, "M0 0L10 0z"}, // degenerate segments are removed
+		{"M0.1 0.1L10.1 0.1L10.2 10.3", 1.0, 1.0, "M0 0L10 0L10 10"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.orig, func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.orig).Snap(tt.gridX, tt.gridY), MustParseSVG(tt.res))
+		})
+	}
+
+	// 1px wide lines are snapped to pixel centers, 2px wide lines to pixel edges
+	test.T(t, MustParseSVG("M0.1 0.2L9.8 0.3").SnapStroke(1.0, 1.0, 1.0), MustParseSVG("M0.5 0.5L9.5 0.5"))
+	test.T(t, MustParseSVG("M0.1 0.2L9.8 0.3").SnapStroke(1.0, 1.0, 2.0), MustParseSVG("M0 0L10 0"))
+	test.T(t, MustParseSVG("M0.1 0.2L9.8 0.3").SnapStroke(0.5, 0.5, 1.5), MustParseSVG("M0.25 0.25L9.75 0.25"))
+
+	closed := MustParseSVG("M0.1 -0.2L9.9 0.3L10.2 4.8L-0.1 5.1z").Snap(1.0, 1.0)
+	test.That(t, closed.Closed(), "path must remain closed")
+	test.T(t, closed.StartPos(), Point{0.0, 0.0})
+}
+
+func TestPathFlattenWith(t *testing.T) {
+	p0, p1, p2, p3 := Point{0.0, 0.0}, Point{0.0, 10.0}, Point{10.0, 10.0}, Point{10.0, 0.0}
+	cubic := MustParseSVG("M0 0C0 10 10 10 10 0")
+
+	// maximum distance of the curve to the flattened path
+	deviation := func(p *Path, p0, p1, p2, p3 Point) float64 {
+		coords := p.Coords()
+		dev := 0.0
+		for i := 0; i <= 1000; i++ {
+			pos := cubicBezierPos(p0, p1, p2, p3, float64(i)/1000.0)
+			d := math.Inf(1)
+			for j := 1; j < len(coords); j++ {
+				d = math.Min(d, distanceToSegment(pos, coords[j-1], coords[j]))
+			}
+			dev = math.Max(dev, d)
+		}
+		return dev
+	}
+
+	for _, n := range []int{1, 3, 8} {
+		flat := cubic.FlattenWith(UniformSteps(n))
+		test.T(t, len(flat.Coords()), n+1)
+		test.T(t, flat.Coords()[1], cubicBezierPos(p0, p1, p2, p3, 1.0/float64(n)))
+		test.T(t, flat.Coords()[n], p3)
+	}
+	test.T(t, len(MustParseSVG("M0 0Q5 10 10 0").FlattenWith(UniformSteps(4)).Coords()), 5)
+	test.T(t, MustParseSVG("M10 0A10 10 0 0 1 -10 0").FlattenWith(UniformSteps(2)), MustParseSVG("M10 0L0 10L-10 0"))
+
+	DefaultFlattenMethod = UniformSteps(4)
+	test.T(t, cubic.Flatten(), cubic.FlattenWith(UniformSteps(4)))
+	DefaultFlattenMethod = nil
+	for _, tolerance := range []float64{0.1, 0.01} {
+		adaptive := cubic.FlattenWith(AdaptiveDeviation(tolerance))
+		recursive := cubic.FlattenWith(RecursiveSubdivision(tolerance))
+		test.That(t, deviation(adaptive, p0, p1, p2, p3) <= tolerance, "adaptive deviation must be within tolerance")
+		test.That(t, deviation(recursive, p0, p1, p2, p3) <= tolerance, "recursive deviation must be within tolerance")
+		test.That(t, len(adaptive.Coords()) <= len(recursive.Coords()), "adaptive must not use more segments")
+	}
+
+	// curve with an inflection point
+	q0, q1, q2, q3 := Point{0.0, 0.0}, Point{10.0, 10.0}, Point{0.0, -10.0}, Point{10.0, 0.0}
+	adaptive := MustParseSVG("M0 0C10 10 0 -10 10 0").FlattenWith(AdaptiveDeviation(0.01))
+	test.That(t, deviation(adaptive, q0, q1, q2, q3) <= 0.01, "adaptive deviation must be within tolerance")
+}
+
 func TestPathReplace(t *testing.T) {
 	line := func(p0, p1 Point) *Path {
 		return (&Path{}).MoveTo(p0.X, p0.Y).LineTo(p1.X, p1.Y-5.0)
@@ -457,6 +585,26 @@ func TestPathSplitAt(t *testing.T) {
 	}
 }
 
+func TestPathSplitN(t *testing.T) {
+	p := Circle(10.0)
+	ps := p.SplitN(4)
+	test.T(t, len(ps), 4)
+	for _, q := range ps {
+		test.That(t, math.Abs(q.Length()-p.Length()/4.0) < 1e-3, q.Length(), "!=", p.Length()/4.0)
+	}
+	test.That(t, ps[0].StartPos().Equals(p.StartPos()))
+	test.That(t, ps[1].StartPos().Equals(ps[0].Pos()))
+	test.That(t, ps[3].Pos().Equals(p.Pos()))
+
+	p = MustParseSVG("L10 0")
+	test.T(t, p.SplitN(1), []*Path{p})
+	test.T(t, (&Path{}).SplitN(3), []*Path{{}})
+	ps = p.SplitN(3)
+	test.T(t, len(ps), 3)
+	test.Float(t, ps[2].StartPos().X, 20.0/3.0)
+	test.Float(t, ps[2].Length(), 10.0/3.0)
+}
+
 func TestDashCanonical(t *testing.T) {
 	var tts = []struct {
 		origOffset float64
@@ -531,6 +679,62 @@ func TestPathDash(t *testing.T) {
 	}
 }
 
+func TestPathRoundCorners(t *testing.T) {
+	var tts = []struct {
+		orig   string
+		radius float64
+		round  string
+	}{
+		{"", 2.0, ""},
+		{"L10 0L10 10L0 10z", 2.0, "M2 0L8 0A2 2 0 0 1 10 2L10 8A2 2 0 0 1 8 10L2 10A2 2 0 0 1 0 8L0 2A2 2 0 0 1 2 0z"},
+		{"L0 10L10 10L10 0z", 2.0, "M0 2L0 8A2 2 0 0 0 2 10L8 10A2 2 0 0 0 10 8L10 2A2 2 0 0 0 8 0L2 0A2 2 0 0 0 0 2z"},
+		{"L10 0L10 10L0 10z", 8.0, "M5 0A5 5 0 0 1 10 5A5 5 0 0 1 5 10A5 5 0 0 1 0 5A5 5 0 0 1 5 0z"}, // clamped radius
+		{"L10 0L10 10", 2.0, "L8 0A2 2 0 0 1 10 2L10 10"},
+		{"L5 0L10 0L10 10", 2.0, "L5 0L8 0A2 2 0 0 1 10 2L10 10"}, // collinear vertex
+		{"L10 0Q15 5 10 10", 2.0, "L10 0Q15 5 10 10"},             // curved segment
+		{"L10 0L10 10zM20 0L30 0", 1.0, "M2.414213562373095 0L9 0A1 1 0 0 1 10 1L10 7.585786437626904A1 1 0 0 1 8.292893218813454 8.292893218813454L1.7071067811865472 1.7071067811865472A1 1 0 0 1 2.414213562373095 0zM20 0L30 0"},
+	}
+	for _, tt := range tts {
+		t.Run(tt.orig, func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.orig).RoundCorners(tt.radius), MustParseSVG(tt.round))
+		})
+	}
+}
+
+func TestPathClipRect(t *testing.T) {
+	var tts = []struct {
+		orig    string
+		rect    Rect
+		clipped string
+	}{
+		{"M-5 -5L15 15", Rect{0.0, 0.0, 10.0, 10.0}, "M0 0L10 10"},
+		{"M5 -5L5 15", Rect{0.0, 0.0, 10.0, 10.0}, "M5 0L5 10"},
+		{"M2 2L8 8", Rect{0.0, 0.0, 10.0, 10.0}, "M2 2L8 8"},
+		{"M-5 -5L-1 15", Rect{0.0, 0.0, 10.0, 10.0}, ""},
+		{"M0 12L12 0", Rect{0.0, 0.0, 10.0, 10.0}, "M2 10L10 2"},
+		{"M0 0L10 0L10 10L0 10z", Rect{5.0, -1.0, 10.0, 12.0}, "M5 0L10 0L10 10L5 10"},
+		{"M2 2L8 2L8 8z", Rect{0.0, 0.0, 10.0, 10.0}, "M2 2L8 2L8 8z"},
+		{"M-5 5L5 5L5 -5L15 5", Rect{0.0, 0.0, 10.0, 10.0}, "M0 5L5 5L5 0"},
+		{"M2 2Q5 8 8 2", Rect{0.0, 0.0, 10.0, 10.0}, "M2 2Q5 8 8 2"},
+		{"M2 2A3 3 0 0 0 8 2", Rect{0.0, 0.0, 10.0, 10.0}, "M2 2A3 3 0 0 0 8 2"},
+		{"M2 20Q5 30 8 20", Rect{0.0, 0.0, 10.0, 10.0}, ""},
+	}
+	for _, tt := range tts {
+		t.Run(tt.orig, func(t *testing.T) {
+			test.T(t, MustParseSVG(tt.orig).ClipRect(tt.rect), MustParseSVG(tt.clipped))
+		})
+	}
+
+	// curves crossing the boundary are subdivided and stay within the rectangle
+	p := Circle(1.0).ClipRect(Rect{0.0, -2.0, 2.0, 4.0})
+	bounds := p.Bounds()
+	test.Float(t, bounds.X, 0.0)
+	test.Float(t, bounds.W, 1.0)
+	test.Float(t, bounds.Y, -1.0)
+	test.Float(t, bounds.H, 2.0)
+	test.T(t, len(p.Split()), 1)
+}
+
 func TestPathReverse(t *testing.T) {
 	var tts = []struct {
 		orig string
@@ -776,3 +980,43 @@ func TestPathLengthParametrization(t *testing.T) {
 	}
 	plotPathLengthParametrization("test/len_param_ellipse.png", 20, speed, length, theta1, theta2)
 }
+
+func TestPathPartial(t *testing.T) {
+	circle := Circle(10.0)
+	length := circle.Length()
+	test.T(t, circle.Partial(0.0, 0.0).Empty(), true)
+	test.T(t, circle.Partial(0.6, 0.4).Empty(), true)
+	test.T(t, circle.Partial(0.0, 1.0), circle)
+	test.T(t, circle.Partial(-1.0, 2.0), circle)
+	test.That(t, math.Abs(circle.Partial(0.0, 0.5).Length()-length/2.0) < 1e-3, "half circle must be half the length")
+	test.That(t, math.Abs(circle.Partial(0.25, 0.5).Length()-length/4.0) < 1e-3, "quarter circle must be a quarter of the length")
+	test.That(t, !circle.Partial(0.0, 0.5).Closed(), "partial circle must be open")
+
+	// fractions span the subpaths
+	p := MustParseSVG("M0 0L10 0M0 10L30 10")
+	test.T(t, p.Partial(0.0, 0.25), MustParseSVG("M0 0L10 0"))
+	test.T(t, p.Partial(0.125, 0.5), MustParseSVG("M5 0L10 0M0 10L10 10"))
+	test.T(t, p.Partial(0.5, 1.0), MustParseSVG("M10 10L30 10"))
+	test.T(t, p.Partial(0.0, 1.0), p)
+}
+
+func TestPathHatchStroke(t *testing.T) {
+	tick := MustParseSVG("M0 -1L0 1")
+	p := MustParseSVG("M0 0L10 0").HatchStroke(tick, 2.0)
+	test.T(t, p, MustParseSVG("M1 -1L1 1M3 -1L3 1M5 -1L5 1M7 -1L7 1M9 -1L9 1"))
+
+	// the symbol is perpendicular to the path, also after a corner
+	ps := MustParseSVG("M0 0L0 10L10 10").HatchStroke(MustParseSVG("M0 0L0 1"), 5.0).Split()
+	test.T(t, len(ps), 4)
+	for i, ends := range [][2]Point{{{0, 2.5}, {-1, 2.5}}, {{0, 7.5}, {-1, 7.5}}, {{2.5, 10}, {2.5, 11}}, {{7.5, 10}, {7.5, 11}}} {
+		coords := ps[i].Coords()
+		test.T(t, coords[0], ends[0])
+		test.T(t, coords[1], ends[1])
+	}
+
+	// closed paths are spaced evenly
+	circle := Circle(10.0)
+	p = circle.HatchStroke(tick, 6.0)
+	test.T(t, len(p.Split()), 10)
+	test.T(t, MustParseSVG("M0 0L10 0").HatchStroke(tick, 0.0).Empty(), true)
+}
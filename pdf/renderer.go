@@ -8,20 +8,25 @@ import (
 	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
 	"io"
 	"math"
 	"sort"
 	"strings"
 	"time"
+	"unicode/utf16"
 
 	"github.com/tdewolff/canvas"
 	canvasFont "github.com/tdewolff/canvas/font"
+	"github.com/tdewolff/canvas/internal/gaussian"
+	"golang.org/x/image/vector"
 )
 
 type PDF struct {
 	w             *pdfPageWriter
 	width, height float64
 	imgEnc        canvas.ImageEncoding
+	flatten       canvas.FlattenMethod
 }
 
 // NewPDF creates a portable document format renderer.
@@ -38,6 +43,19 @@ func (r *PDF) SetImageEncoding(enc canvas.ImageEncoding) {
 	r.imgEnc = enc
 }
 
+// SetFlattenMethod sets the method used to flatten curves into linear segments, such as canvas.UniformSteps. By default curves are written as curves.
+func (r *PDF) SetFlattenMethod(method canvas.FlattenMethod) {
+	r.flatten = method
+}
+
+// flattenPath flattens the path in the coordinates of the output if a flatten method is set, so that its deviation is in millimeters of the output.
+func (r *PDF) flattenPath(path *canvas.Path, m canvas.Matrix) *canvas.Path {
+	if r.flatten == nil {
+		return path
+	}
+	return path.Transform(m).FlattenWith(r.flatten).Transform(m.Inv())
+}
+
 func (r *PDF) SetCompression(compress bool) {
 	r.w.pdf.SetCompression(compress)
 }
@@ -52,6 +70,7 @@ func (r *PDF) SetInfo(title, subject, keywords, author string) {
 // NewPage starts adds a new page where further rendering will be written to
 func (r *PDF) NewPage(width, height float64) {
 	r.w = r.w.pdf.NewPage(width, height)
+	r.width, r.height = width, height
 }
 
 func (r *PDF) Close() error {
@@ -63,6 +82,41 @@ func (r *PDF) Size() (float64, float64) {
 }
 
 func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	if style.CompositeOp.Erases() {
+		return // cannot erase previously drawn content
+	}
+	path = r.flattenPath(path, m)
+	r.w.SetOverprint(style.Overprint)
+	if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth && isGradient(style.StrokePaint) {
+		// fill the path and then fill the stroke outline with the gradient
+		fillStyle := style
+		fillStyle.StrokeColor = canvas.Transparent
+		if fillStyle.FillColor.A != 0 || fillStyle.FillPaint != nil {
+			r.RenderPath(path, fillStyle, m)
+		}
+		if 0 < len(style.Dashes) {
+			path = path.Dash(style.DashOffset, style.Dashes...)
+		}
+		path = r.flattenPath(path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner), m)
+		r.w.DrawGradient(path, style.StrokePaint, canvas.NonZero, m)
+		return
+	}
+	if solid, ok := style.StrokePaint.(canvas.SolidPaint); ok {
+		style.StrokeColor = solid.Color
+	} else if spot, ok := style.StrokePaint.(canvas.SpotColor); ok {
+		style.StrokeColor = spot.At(0.0, 0.0)
+	}
+	if solid, ok := style.FillPaint.(canvas.SolidPaint); ok {
+		style.FillColor = solid.Color
+	} else if spot, ok := style.FillPaint.(canvas.SpotColor); ok {
+		style.FillColor = spot.At(0.0, 0.0)
+	} else if paint, ok := imagePaint(style.FillPaint); ok {
+		r.w.DrawImagePaint(path, paint, style.FillRule, m)
+		style.FillColor = canvas.Transparent
+	} else if r.w.DrawGradient(path, style.FillPaint, style.FillRule, m) {
+		style.FillColor = canvas.Transparent
+	}
+
 	fill := style.FillColor.A != 0
 	stroke := style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth
 	differentAlpha := fill && stroke && style.FillColor.A != style.StrokeColor.A
@@ -94,7 +148,7 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 
 	if !stroke || !strokeUnsupported {
 		if fill && !stroke {
-			r.w.SetFillColor(style.FillColor)
+			r.setFillColor(style)
 			r.w.Write([]byte(" "))
 			r.w.Write([]byte(data))
 			r.w.Write([]byte(" f"))
@@ -102,7 +156,7 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 				r.w.Write([]byte("*"))
 			}
 		} else if !fill && stroke {
-			r.w.SetStrokeColor(style.StrokeColor)
+			r.setStrokeColor(style)
 			r.w.SetLineWidth(style.StrokeWidth)
 			r.w.SetLineCap(style.StrokeCapper)
 			r.w.SetLineJoin(style.StrokeJoiner)
@@ -119,8 +173,8 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 			}
 		} else if fill && stroke {
 			if !differentAlpha {
-				r.w.SetFillColor(style.FillColor)
-				r.w.SetStrokeColor(style.StrokeColor)
+				r.setFillColor(style)
+				r.setStrokeColor(style)
 				r.w.SetLineWidth(style.StrokeWidth)
 				r.w.SetLineCap(style.StrokeCapper)
 				r.w.SetLineJoin(style.StrokeJoiner)
@@ -136,7 +190,7 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 					r.w.Write([]byte("*"))
 				}
 			} else {
-				r.w.SetFillColor(style.FillColor)
+				r.setFillColor(style)
 				r.w.Write([]byte(" "))
 				r.w.Write([]byte(data))
 				r.w.Write([]byte(" f"))
@@ -144,7 +198,7 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 					r.w.Write([]byte("*"))
 				}
 
-				r.w.SetStrokeColor(style.StrokeColor)
+				r.setStrokeColor(style)
 				r.w.SetLineWidth(style.StrokeWidth)
 				r.w.SetLineCap(style.StrokeCapper)
 				r.w.SetLineJoin(style.StrokeJoiner)
@@ -164,7 +218,7 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 	} else {
 		// stroke && strokeUnsupported
 		if fill {
-			r.w.SetFillColor(style.FillColor)
+			r.setFillColor(style)
 			r.w.Write([]byte(" "))
 			r.w.Write([]byte(data))
 			r.w.Write([]byte(" f"))
@@ -177,9 +231,11 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 		if 0 < len(style.Dashes) {
 			path = path.Dash(style.DashOffset, style.Dashes...)
 		}
-		path = path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
+		path = r.flattenPath(path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner), m)
 
-		r.w.SetFillColor(style.StrokeColor)
+		strokeStyle := style
+		strokeStyle.FillColor, strokeStyle.FillPaint = style.StrokeColor, style.StrokePaint
+		r.setFillColor(strokeStyle)
 		r.w.Write([]byte(" "))
 		r.w.Write([]byte(path.ToPDF()))
 		r.w.Write([]byte(" f"))
@@ -189,41 +245,145 @@ func (r *PDF) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 	}
 }
 
-func (r *PDF) RenderText(text *canvas.Text, m canvas.Matrix) {
-	r.w.StartTextObject()
+// setFillColor sets the fill color of the style, which is a spot color for spot color paints.
+func (r *PDF) setFillColor(style canvas.Style) {
+	if spot, ok := style.FillPaint.(canvas.SpotColor); ok {
+		r.w.SetFillSpotColor(spot)
+		return
+	}
+	r.w.SetFillColor(style.FillColor)
+}
 
-	text.WalkSpans(func(y, dx float64, span canvas.TextSpan) {
-		r.w.SetFillColor(span.Face.Color)
-		r.w.SetFont(span.Face.Font, span.Face.Size*span.Face.Scale)
-		r.w.SetTextPosition(m.Translate(dx, y).Shear(span.Face.FauxItalic, 0.0))
-		r.w.SetTextCharSpace(span.GlyphSpacing)
+// setStrokeColor sets the stroke color of the style, which is a spot color for spot color paints.
+func (r *PDF) setStrokeColor(style canvas.Style) {
+	if spot, ok := style.StrokePaint.(canvas.SpotColor); ok {
+		r.w.SetStrokeSpotColor(spot)
+		return
+	}
+	r.w.SetStrokeColor(style.StrokeColor)
+}
 
-		if 0.0 < span.Face.FauxBold {
-			r.w.SetTextRenderMode(2)
-			fmt.Fprintf(r.w, " %v w", dec(span.Face.FauxBold*2.0))
-		} else {
-			r.w.SetTextRenderMode(0)
-		}
+func (r *PDF) RenderText(text *canvas.Text, m canvas.Matrix) {
+	text.RenderLayers(r, m, func() {
+		r.w.SetOverprint(false)
+		r.w.StartTextObject()
+
+		text.WalkSpans(func(y, dx float64, span canvas.TextSpan) {
+			r.w.SetFillColor(span.Face.Color)
+			r.w.SetFont(span.Face.Font, span.Face.Size*span.Face.Scale)
+			r.w.fontFace = span.Face
+			r.w.SetTextPosition(m.Translate(dx, y+span.Face.Voffset).Shear(span.Face.FauxItalic, 0.0))
+			r.w.SetTextCharSpace(span.GlyphSpacing)
+
+			if 0.0 < span.Face.FauxBold {
+				r.w.SetTextRenderMode(2)
+				fmt.Fprintf(r.w, " %v w", dec(span.Face.FauxBold*2.0))
+			} else {
+				r.w.SetTextRenderMode(0)
+			}
 
-		TJ := []interface{}{}
-		words := span.Words()
-		for i, w := range words {
-			TJ = append(TJ, w)
-			if i != len(words)-1 {
-				TJ = append(TJ, span.WordSpacing)
+			TJ := []interface{}{}
+			words := span.Words()
+			for i, w := range words {
+				TJ = append(TJ, w)
+				if i != len(words)-1 {
+					TJ = append(TJ, span.WordSpacing)
+				}
 			}
-		}
-		r.w.WriteText(TJ...)
+			r.w.WriteText(TJ...)
+		})
+		r.w.EndTextObject()
 	})
-	r.w.EndTextObject()
-
-	text.RenderDecoration(r, m)
 }
 
 func (r *PDF) RenderImage(img image.Image, m canvas.Matrix) {
+	r.w.SetOverprint(false)
 	r.w.DrawImage(img, r.imgEnc, m)
 }
 
+// shadowResolution is the resolution at which shadows are rasterized, which is low as shadows are blurred.
+const shadowResolution = canvas.DPMM(10.0)
+
+// RenderShadow draws a soft shadow of the path as an image, see canvas.Context.DrawShadow. The shadow is rasterized at a resolution of 10 dots-per-millimeter and is embedded as an image XObject that extends three times the blur beyond the path.
+func (r *PDF) RenderShadow(path *canvas.Path, blur float64, col color.RGBA, m canvas.Matrix) {
+	path = path.Transform(m)
+	bounds := path.Bounds()
+	margin := 3.0 * blur
+	x, y := bounds.X-margin, bounds.Y-margin
+	w := int(math.Ceil((bounds.W + 2.0*margin) * float64(shadowResolution)))
+	h := int(math.Ceil((bounds.H + 2.0*margin) * float64(shadowResolution)))
+	if w <= 0 || h <= 0 {
+		return
+	}
+
+	mask := shadowMask(path.Translate(-x, -y), w, h)
+	gaussian.Blur(mask, blur*float64(shadowResolution))
+	r.RenderImage(shadowImage(mask, image.Point{}, w, h, col), canvas.Identity.Translate(x, y).Scale(1.0/float64(shadowResolution), 1.0/float64(shadowResolution)))
+}
+
+// RenderInsetShadow draws a soft shadow inside of the path as an image that is clipped to the path, see canvas.Context.DrawInsetShadow. The shadow is rasterized at a resolution of 10 dots-per-millimeter, as for RenderShadow.
+func (r *PDF) RenderInsetShadow(path *canvas.Path, dx, dy, blur float64, col color.RGBA, m canvas.Matrix) {
+	offset := m.Dot(canvas.Point{X: dx, Y: dy}).Sub(m.Dot(canvas.Point{}))
+	path = path.Transform(m)
+	bounds := path.Bounds()
+	x, y := bounds.X, bounds.Y
+	w := int(math.Ceil(bounds.W * float64(shadowResolution)))
+	h := int(math.Ceil(bounds.H * float64(shadowResolution)))
+	if w <= 0 || h <= 0 {
+		return
+	}
+
+	// the shadow is the inverse of the offset silhouette, including the margin around the path
+	sigma := blur * float64(shadowResolution)
+	margin := int(math.Ceil(3.0 * sigma))
+	d := float64(margin) / float64(shadowResolution)
+	mask := shadowMask(path.Translate(offset.X-x+d, offset.Y-y+d), w+2*margin, h+2*margin)
+	for i, a := range mask.Pix {
+		mask.Pix[i] = 255 - a
+	}
+	gaussian.Blur(mask, sigma)
+
+	r.w.PushClip(path, canvas.Identity)
+	r.RenderImage(shadowImage(mask, image.Point{margin, margin}, w, h, col), canvas.Identity.Translate(x, y).Scale(1.0/float64(shadowResolution), 1.0/float64(shadowResolution)))
+	r.w.PopClip()
+}
+
+// shadowMask returns the coverage of the path in a mask of w by h pixels at the resolution of shadows.
+func shadowMask(path *canvas.Path, w, h int) *image.Alpha {
+	mask := image.NewAlpha(image.Rect(0, 0, w, h))
+	ras := vector.NewRasterizer(w, h)
+	path.ToRasterizer(ras, float64(shadowResolution))
+	ras.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+	return mask
+}
+
+// shadowImage returns an image of w by h pixels in the color of the shadow with the mask from point sp as its alpha.
+func shadowImage(mask *image.Alpha, sp image.Point, w, h int, col color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.DrawMask(img, img.Bounds(), image.NewUniform(col), image.Point{}, mask, sp, draw.Src)
+	return img
+}
+
+// BeginGroup starts a transparency group that is written as a form XObject, see canvas.Context.BeginGroup.
+func (r *PDF) BeginGroup(isolated, knockout bool) {
+	r.w.BeginGroup(isolated, knockout)
+}
+
+// EndGroup ends a transparency group.
+func (r *PDF) EndGroup() {
+	r.w.EndGroup()
+}
+
+// PushClip intersects the clipping path with the path, see canvas.Context.ClipPath.
+func (r *PDF) PushClip(path *canvas.Path, m canvas.Matrix) {
+	r.w.PushClip(r.flattenPath(path, m), m)
+}
+
+// PopClip restores the clipping path.
+func (r *PDF) PopClip() {
+	r.w.PopClip()
+}
+
 type pdfWriter struct {
 	w   io.Writer
 	err error
@@ -231,19 +391,23 @@ type pdfWriter struct {
 	pos        int
 	objOffsets []int
 
-	fonts    map[*canvas.Font]pdfRef
-	pages    []*pdfPageWriter
-	compress bool
-	title    string
-	subject  string
-	keywords string
-	author   string
+	fonts      map[*canvas.Font]pdfRef
+	toUnicode  map[*canvas.Font]pdfRef            // reserved objects of the ToUnicode CMaps, written on Close
+	fontGlyphs map[*canvas.Font]map[uint16]string // used glyphs and their characters
+	pages      []*pdfPageWriter
+	compress   bool
+	title      string
+	subject    string
+	keywords   string
+	author     string
 }
 
 func newPDFWriter(writer io.Writer) *pdfWriter {
 	w := &pdfWriter{
 		w:          writer,
 		fonts:      map[*canvas.Font]pdfRef{},
+		toUnicode:  map[*canvas.Font]pdfRef{},
+		fontGlyphs: map[*canvas.Font]map[uint16]string{},
 		objOffsets: []int{0, 0, 0}, // catalog, metadata, page tree
 	}
 
@@ -420,6 +584,20 @@ func (w *pdfWriter) writeObject(val interface{}) pdfRef {
 	return pdfRef(len(w.objOffsets))
 }
 
+// reserveObject returns the reference of an object that is written later with writeObjectAt, such as when its contents are only known on Close.
+func (w *pdfWriter) reserveObject() pdfRef {
+	w.objOffsets = append(w.objOffsets, 0)
+	return pdfRef(len(w.objOffsets))
+}
+
+func (w *pdfWriter) writeObjectAt(ref pdfRef, val interface{}) {
+	w.objOffsets[ref-1] = w.pos
+	w.write("%v 0 obj\n", int(ref))
+	w.writeVal(val)
+	w.write("\nendobj\n")
+}
+
+// getFont returns the reference of an embedded font, which is a Type0 composite font so that any number of glyphs can be addressed. Glyphs are encoded by their two-byte glyph ID (Identity-H), and since the whole font is embedded the CIDs are equal to the glyph IDs. TrueType fonts are embedded as CIDFontType2 with an Identity CIDToGIDMap, and fonts with CFF outlines as CIDFontType0 of which the charset maps the CIDs. A ToUnicode CMap maps the used glyphs to their characters so that text can be extracted.
 func (w *pdfWriter) getFont(font *canvas.Font) pdfRef {
 	if ref, ok := w.fonts[font]; ok {
 		return ref
@@ -438,13 +616,8 @@ func (w *pdfWriter) getFont(font *canvas.Font) pdfRef {
 		}
 	}
 
-	ffSubtype := ""
-	cidSubtype := ""
-	if mediatype == "font/truetype" {
-		ffSubtype = "TrueType"
-		cidSubtype = "CIDFontType2"
-	} else if mediatype == "font/opentype" {
-		ffSubtype = "OpenType"
+	cidSubtype := "CIDFontType2"
+	if mediatype == "font/opentype" {
 		cidSubtype = "CIDFontType0"
 	}
 
@@ -487,52 +660,109 @@ func (w *pdfWriter) getFont(font *canvas.Font) pdfRef {
 		W = append(W, i, arr)
 	}
 
-	baseFont := strings.ReplaceAll(font.Name(), " ", "_")
+	baseFont := font.SFNT().PostScriptName()
+	if baseFont == "" {
+		baseFont = font.Name()
+	}
+	baseFont = strings.ReplaceAll(baseFont, " ", "_")
 	bounds := font.Bounds(units)
 	metrics := font.Metrics(units)
-	fontfileRef := w.writeObject(pdfStream{
+	fontfile := pdfStream{
 		dict: pdfDict{
-			"Subtype": pdfName(ffSubtype),
-			"Filter":  pdfFilterFlate,
+			"Filter": pdfFilterFlate,
 		},
 		stream: b,
-	})
-	ref := w.writeObject(pdfDict{
+	}
+	fontfileKey := pdfName("FontFile2")
+	if cidSubtype == "CIDFontType0" {
+		fontfile.dict["Subtype"] = pdfName("OpenType")
+		fontfileKey = "FontFile3"
+	}
+	fontfileRef := w.writeObject(fontfile)
+	toUnicodeRef := w.reserveObject()
+	descendant := pdfDict{
 		"Type":     pdfName("Font"),
-		"Subtype":  pdfName("Type0"),
+		"Subtype":  pdfName(cidSubtype),
 		"BaseFont": pdfName(baseFont),
-		"Encoding": pdfName("Identity-H"),
-		"DescendantFonts": pdfArray{pdfDict{
-			"Type":        pdfName("Font"),
-			"Subtype":     pdfName(cidSubtype),
-			"BaseFont":    pdfName(baseFont),
-			"CIDToGIDMap": pdfName("Identity"),
-			"DW":          DW,
-			"W":           W,
-			"CIDSystemInfo": pdfDict{
-				"Registry":   "Adobe",
-				"Ordering":   "Identity",
-				"Supplement": 0,
-			},
-			"FontDescriptor": pdfDict{
-				"Type":        pdfName("FontDescriptor"),
-				"FontName":    pdfName(baseFont),
-				"Flags":       4,
-				"FontBBox":    pdfArray{int(f * bounds.X), -int(f * (bounds.Y + bounds.H)), int(f * (bounds.X + bounds.W)), -int(f * bounds.Y)},
-				"ItalicAngle": font.ItalicAngle(),
-				"Ascent":      int(f * metrics.Ascent),
-				"Descent":     -int(f * metrics.Descent),
-				"CapHeight":   -int(f * metrics.CapHeight),
-				"StemV":       80, // taken from Inkscape, should be calculated somehow
-				"StemH":       80,
-				"FontFile3":   fontfileRef,
-			},
-		}},
+		"DW":       DW,
+		"W":        W,
+		"CIDSystemInfo": pdfDict{
+			"Registry":   "Adobe",
+			"Ordering":   "Identity",
+			"Supplement": 0,
+		},
+		"FontDescriptor": pdfDict{
+			"Type":        pdfName("FontDescriptor"),
+			"FontName":    pdfName(baseFont),
+			"Flags":       4,
+			"FontBBox":    pdfArray{int(f * bounds.X), -int(f * (bounds.Y + bounds.H)), int(f * (bounds.X + bounds.W)), -int(f * bounds.Y)},
+			"ItalicAngle": font.ItalicAngle(),
+			"Ascent":      int(f * metrics.Ascent),
+			"Descent":     -int(f * metrics.Descent),
+			"CapHeight":   -int(f * metrics.CapHeight),
+			"StemV":       80, // taken from Inkscape, should be calculated somehow
+			"StemH":       80,
+			fontfileKey:   fontfileRef,
+		},
+	}
+	if cidSubtype == "CIDFontType2" {
+		descendant["CIDToGIDMap"] = pdfName("Identity")
+	}
+	ref := w.writeObject(pdfDict{
+		"Type":            pdfName("Font"),
+		"Subtype":         pdfName("Type0"),
+		"BaseFont":        pdfName(baseFont),
+		"Encoding":        pdfName("Identity-H"),
+		"ToUnicode":       toUnicodeRef,
+		"DescendantFonts": pdfArray{descendant},
 	})
 	w.fonts[font] = ref
+	w.toUnicode[font] = toUnicodeRef
+	w.fontGlyphs[font] = map[uint16]string{}
 	return ref
 }
 
+// writeToUnicode writes the ToUnicode CMap of a font, which maps the two-byte glyph IDs of the used glyphs to their characters in UTF-16BE.
+func (w *pdfWriter) writeToUnicode(ref pdfRef, glyphs map[uint16]string) {
+	ids := make([]int, 0, len(glyphs))
+	for id := range glyphs {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+
+	b := &bytes.Buffer{}
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	b.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	b.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	b.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for i := 0; i < len(ids); i += 100 {
+		// at most 100 mappings per block
+		n := len(ids) - i
+		if 100 < n {
+			n = 100
+		}
+		fmt.Fprintf(b, "%d beginbfchar\n", n)
+		for _, id := range ids[i : i+n] {
+			fmt.Fprintf(b, "<%04X> <", id)
+			for _, c := range utf16.Encode([]rune(glyphs[uint16(id)])) {
+				fmt.Fprintf(b, "%04X", c)
+			}
+			b.WriteString(">\n")
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend")
+
+	stream := pdfStream{
+		dict:   pdfDict{},
+		stream: b.Bytes(),
+	}
+	if w.compress {
+		stream.dict["Filter"] = pdfFilterFlate
+	}
+	w.writeObjectAt(ref, stream)
+}
+
 func (w *pdfWriter) Close() error {
 	// TODO: write pages directly to stream instead of using bytes.Buffer
 	kids := pdfArray{}
@@ -540,6 +770,17 @@ func (w *pdfWriter) Close() error {
 		kids = append(kids, p.writePage(pdfRef(3)))
 	}
 
+	fonts := make([]*canvas.Font, 0, len(w.toUnicode))
+	for font := range w.toUnicode {
+		fonts = append(fonts, font)
+	}
+	sort.Slice(fonts, func(i, j int) bool {
+		return w.toUnicode[fonts[i]] < w.toUnicode[fonts[j]]
+	})
+	for _, font := range fonts {
+		w.writeToUnicode(w.toUnicode[font], w.fontGlyphs[font])
+	}
+
 	// document catalog
 	w.objOffsets[0] = w.pos
 	w.write("%v 0 obj\n", 1)
@@ -604,7 +845,9 @@ type pdfPageWriter struct {
 	resources     pdfDict
 
 	graphicsStates map[float64]pdfName
+	spotColors     map[string]pdfName
 	alpha          float64
+	overprint      bool
 	fillColor      color.RGBA
 	strokeColor    color.RGBA
 	lineWidth      float64
@@ -614,10 +857,14 @@ type pdfPageWriter struct {
 	dashes         []float64
 	font           *canvas.Font
 	fontSize       float64
+	fontFace       canvas.FontFace // font face of the written text, of which the features substitute glyphs
 	inTextObject   bool
 	textPosition   canvas.Matrix
 	textCharSpace  float64
 	textRenderMode int
+
+	groups []pdfGroup
+	clips  []pdfPageWriter // page writer at each pushed clipping path
 }
 
 func (w *pdfWriter) NewPage(width, height float64) *pdfPageWriter {
@@ -629,6 +876,7 @@ func (w *pdfWriter) NewPage(width, height float64) *pdfPageWriter {
 		height:         height,
 		resources:      pdfDict{},
 		graphicsStates: map[float64]pdfName{},
+		spotColors:     map[string]pdfName{},
 		alpha:          1.0,
 		fillColor:      canvas.Black,
 		strokeColor:    canvas.Black,
@@ -687,6 +935,25 @@ func (w *pdfPageWriter) SetAlpha(alpha float64) {
 	}
 }
 
+// SetOverprint sets the overprint parameters of the graphics state for fills and strokes, with the nonzero overprint mode so that zero components of DeviceCMYK colors do not knock out the inks below.
+func (w *pdfPageWriter) SetOverprint(overprint bool) {
+	if overprint == w.overprint {
+		return
+	}
+	name := pdfName("OP0")
+	gs := pdfDict{"OP": false, "op": false}
+	if overprint {
+		name = pdfName("OP1")
+		gs = pdfDict{"OP": true, "op": true, "OPM": 1}
+	}
+	if _, ok := w.resources["ExtGState"]; !ok {
+		w.resources["ExtGState"] = pdfDict{}
+	}
+	w.resources["ExtGState"].(pdfDict)[name] = gs
+	fmt.Fprintf(w, " /%v gs", name)
+	w.overprint = overprint
+}
+
 func (w *pdfPageWriter) SetFillColor(fillColor color.RGBA) {
 	a := float64(fillColor.A) / 255.0
 	if fillColor != w.fillColor {
@@ -713,6 +980,74 @@ func (w *pdfPageWriter) SetStrokeColor(strokeColor color.RGBA) {
 	w.SetAlpha(a)
 }
 
+// SetFillSpotColor sets the fill color to a tint of the spot color, see canvas.SpotColor.
+func (w *pdfPageWriter) SetFillSpotColor(spot canvas.SpotColor) {
+	tint := math.Max(0.0, math.Min(1.0, spot.Tint))
+	fmt.Fprintf(w, " /%v cs %v scn", w.getSpotColorSpace(spot), dec(tint))
+	w.fillColor = canvas.Transparent // force resetting the fill color space
+	w.SetAlpha(1.0)
+}
+
+// SetStrokeSpotColor sets the stroke color to a tint of the spot color, see canvas.SpotColor.
+func (w *pdfPageWriter) SetStrokeSpotColor(spot canvas.SpotColor) {
+	tint := math.Max(0.0, math.Min(1.0, spot.Tint))
+	fmt.Fprintf(w, " /%v CS %v SCN", w.getSpotColorSpace(spot), dec(tint))
+	w.strokeColor = canvas.Transparent // force resetting the stroke color space
+	w.SetAlpha(1.0)
+}
+
+// getSpotColorSpace returns the name of the Separation color space of the spot color in the page resources. The alternate color space is ICC-based if the spot color has an ICC color, Lab if it has a Lab color, and DeviceCMYK otherwise. The tint transform interpolates linearly between no ink and the alternate color of the full ink.
+func (w *pdfPageWriter) getSpotColorSpace(spot canvas.SpotColor) pdfName {
+	if name, ok := w.spotColors[spot.Name]; ok {
+		return name
+	}
+
+	var alternate interface{}
+	var c0, c1 pdfArray
+	if spot.ICC != nil && iccComponents(spot.ICC.Profile) == len(spot.ICC.Components) {
+		n := len(spot.ICC.Components)
+		stream := pdfStream{
+			dict: pdfDict{
+				"N": n,
+			},
+			stream: spot.ICC.Profile,
+		}
+		if w.pdf.compress {
+			stream.dict["Filter"] = pdfFilterFlate
+		}
+		alternate = pdfArray{pdfName("ICCBased"), w.pdf.writeObject(stream)}
+		c0, c1 = iccWhite(spot.ICC.Profile), pdfArray{}
+		for _, v := range spot.ICC.Components {
+			c1 = append(c1, v)
+		}
+	} else if spot.Lab != nil {
+		alternate = pdfArray{pdfName("Lab"), pdfDict{
+			"WhitePoint": pdfArray{0.9642, 1.0, 0.8249},
+			"Range":      pdfArray{-128, 127, -128, 127},
+		}}
+		c0, c1 = pdfArray{100, 0, 0}, pdfArray{spot.Lab.L, spot.Lab.A, spot.Lab.B}
+	} else {
+		alternate = pdfName("DeviceCMYK")
+		c0 = pdfArray{0, 0, 0, 0}
+		c1 = pdfArray{float64(spot.CMYK.C) / 255.0, float64(spot.CMYK.M) / 255.0, float64(spot.CMYK.Y) / 255.0, float64(spot.CMYK.K) / 255.0}
+	}
+	ref := w.pdf.writeObject(pdfArray{pdfName("Separation"), spotName(spot.Name), alternate, pdfDict{
+		"FunctionType": 2,
+		"Domain":       pdfArray{0, 1},
+		"C0":           c0,
+		"C1":           c1,
+		"N":            1,
+	}})
+
+	if _, ok := w.resources["ColorSpace"]; !ok {
+		w.resources["ColorSpace"] = pdfDict{}
+	}
+	name := pdfName(fmt.Sprintf("CS%d", len(w.resources["ColorSpace"].(pdfDict))))
+	w.resources["ColorSpace"].(pdfDict)[name] = ref
+	w.spotColors[spot.Name] = name
+	return name
+}
+
 func (w *pdfPageWriter) SetLineWidth(lineWidth float64) {
 	if lineWidth != w.lineWidth {
 		fmt.Fprintf(w, " %v w", dec(lineWidth))
@@ -894,8 +1229,26 @@ func (w *pdfPageWriter) WriteText(TJ ...interface{}) {
 		}
 
 		buf := &bytes.Buffer{}
-		indices := w.font.IndicesOf(s)
+		var indices []uint16
+		if w.fontFace.Font == w.font {
+			indices = w.fontFace.IndicesOf(s)
+		} else {
+			indices = w.font.IndicesOf(s)
+		}
 		binary.Write(buf, binary.BigEndian, indices)
+		glyphs := w.pdf.fontGlyphs[w.font]
+		i := 0
+		for _, r := range s {
+			if _, ok := glyphs[indices[i]]; !ok && indices[i] != 0 {
+				// ligature glyphs without presentation form map to their components
+				if components, ok := w.font.LigatureText(r); ok {
+					glyphs[indices[i]] = components
+				} else {
+					glyphs[indices[i]] = string(r)
+				}
+			}
+			i++
+		}
 
 		s = buf.String()
 		s = strings.Replace(s, "\\", "\\\\", -1)
@@ -949,9 +1302,250 @@ func (w *pdfPageWriter) DrawImage(img image.Image, enc canvas.ImageEncoding, m c
 	fmt.Fprintf(w, " %v %v %v %v %v %v cm /%v Do Q", dec(m[0][0]), dec(m[1][0]), dec(m[0][1]), dec(m[1][1]), dec(m[0][2]), dec(m[1][2]), name)
 }
 
+// DrawGradient fills the path with a linear or radial gradient using a shading pattern, where the path and the gradient are transformed by m. The alpha of the gradient colors is not supported. It returns false if the paint is not a gradient.
+func (w *pdfPageWriter) DrawGradient(path *canvas.Path, paint canvas.Paint, fillRule canvas.FillRule, m canvas.Matrix) bool {
+	var shading pdfDict
+	var stops []canvas.GradientStop
+	var extend canvas.ExtendMode
+	switch p := gradientPaint(paint).(type) {
+	case canvas.LinearGradient:
+		shading = pdfDict{
+			"ShadingType": 2,
+			"Coords":      pdfArray{p.Start.X, p.Start.Y, p.End.X, p.End.Y},
+		}
+		stops, extend = p.Stops, p.Extend
+	case canvas.RadialGradient:
+		shading = pdfDict{
+			"ShadingType": 3,
+			"Coords":      pdfArray{p.Center.X, p.Center.Y, 0.0, p.Center.X, p.Center.Y, p.Radius},
+		}
+		stops, extend = p.Stops, p.Extend
+	default:
+		return false
+	}
+	if len(stops) == 0 {
+		return true
+	}
+
+	// extend the first and last colors to the start and end of the gradient
+	if 0.0 < stops[0].Offset {
+		stops = append([]canvas.GradientStop{{Offset: 0.0, Color: stops[0].Color}}, stops...)
+	}
+	if stops[len(stops)-1].Offset < 1.0 {
+		stops = append(stops, canvas.GradientStop{Offset: 1.0, Color: stops[len(stops)-1].Color})
+	}
+	rgb := func(col color.RGBA) pdfArray {
+		if col.A == 0 {
+			return pdfArray{0.0, 0.0, 0.0}
+		}
+		a := float64(col.A)
+		return pdfArray{float64(col.R) / a, float64(col.G) / a, float64(col.B) / a}
+	}
+	functions, bounds, encode := pdfArray{}, pdfArray{}, pdfArray{}
+	for i := 1; i < len(stops); i++ {
+		functions = append(functions, pdfDict{
+			"FunctionType": 2,
+			"Domain":       pdfArray{0, 1},
+			"C0":           rgb(stops[i-1].Color),
+			"C1":           rgb(stops[i].Color),
+			"N":            1,
+		})
+		if i != 1 {
+			bounds = append(bounds, stops[i-1].Offset)
+		}
+		encode = append(encode, 0, 1)
+	}
+	shading["ColorSpace"] = pdfName("DeviceRGB")
+	shading["Function"] = pdfDict{
+		"FunctionType": 3,
+		"Domain":       pdfArray{0, 1},
+		"Functions":    functions,
+		"Bounds":       bounds,
+		"Encode":       encode,
+	}
+	shading["Extend"] = pdfArray{extend != canvas.ExtendNone, extend != canvas.ExtendNone}
+
+	// the pattern matrix maps to the default coordinate space of the page, which is in points
+	pm := canvas.Identity.Scale(ptPerMm, ptPerMm).Mul(m)
+	ref := w.pdf.writeObject(pdfDict{
+		"Type":        pdfName("Pattern"),
+		"PatternType": 2,
+		"Shading":     shading,
+		"Matrix":      pdfArray{pm[0][0], pm[1][0], pm[0][1], pm[1][1], pm[0][2], pm[1][2]},
+	})
+	if _, ok := w.resources["Pattern"]; !ok {
+		w.resources["Pattern"] = pdfDict{}
+	}
+	name := pdfName(fmt.Sprintf("P%d", len(w.resources["Pattern"].(pdfDict))))
+	w.resources["Pattern"].(pdfDict)[name] = ref
+
+	w.SetAlpha(1.0)
+	fmt.Fprintf(w, " /Pattern cs /%v scn %v f", name, path.Transform(m).ToPDF())
+	if fillRule == canvas.EvenOdd {
+		fmt.Fprintf(w, "*")
+	}
+	w.fillColor = canvas.Transparent // force resetting the fill color space
+	return true
+}
+
+// DrawImagePaint fills the path with the image paint, where the path and the paint are transformed by m. Repeating paints use a tiling pattern, other paints draw the image clipped by the path.
+func (w *pdfPageWriter) DrawImagePaint(path *canvas.Path, paint canvas.ImagePaint, fillRule canvas.FillRule, m canvas.Matrix) {
+	data := path.Transform(m).ToPDF()
+	if paint.Extend == canvas.ExtendRepeat || paint.Extend == canvas.ExtendReflect {
+		img := paint.Image
+		if paint.Extend == canvas.ExtendReflect {
+			img = paint.ReflectedImage()
+		}
+		size := img.Bounds().Size()
+
+		// the pattern matrix maps to the default coordinate space of the page, which is in points
+		pm := canvas.Identity.Scale(ptPerMm, ptPerMm).Mul(m).Mul(paint.Transform)
+		stream := pdfStream{
+			dict: pdfDict{
+				"Type":        pdfName("Pattern"),
+				"PatternType": 1,
+				"PaintType":   1,
+				"TilingType":  1,
+				"BBox":        pdfArray{0, 0, size.X, size.Y},
+				"XStep":       size.X,
+				"YStep":       size.Y,
+				"Matrix":      pdfArray{pm[0][0], pm[1][0], pm[0][1], pm[1][1], pm[0][2], pm[1][2]},
+				"Resources": pdfDict{
+					"XObject": pdfDict{
+						"Im0": w.writeImage(img, canvas.Lossless),
+					},
+				},
+			},
+			stream: []byte(fmt.Sprintf("%d 0 0 %d 0 0 cm /Im0 Do", size.X, size.Y)),
+		}
+		if w.pdf.compress {
+			stream.dict["Filter"] = pdfFilterFlate
+		}
+		ref := w.pdf.writeObject(stream)
+
+		if _, ok := w.resources["Pattern"]; !ok {
+			w.resources["Pattern"] = pdfDict{}
+		}
+		name := pdfName(fmt.Sprintf("P%d", len(w.resources["Pattern"].(pdfDict))))
+		w.resources["Pattern"].(pdfDict)[name] = ref
+
+		w.SetAlpha(1.0)
+		fmt.Fprintf(w, " /Pattern cs /%v scn %v f", name, data)
+		if fillRule == canvas.EvenOdd {
+			fmt.Fprintf(w, "*")
+		}
+		w.fillColor = canvas.Transparent // force resetting the fill color space
+		return
+	}
+
+	img, transform := paint.Image, paint.Transform
+	if paint.Extend == canvas.ExtendClamp {
+		img, transform = paint.ClampedImage(path.Bounds())
+	}
+	fmt.Fprintf(w, " q %v W", data)
+	if fillRule == canvas.EvenOdd {
+		fmt.Fprintf(w, "*")
+	}
+	fmt.Fprintf(w, " n")
+	w.DrawImage(img, canvas.Lossless, m.Mul(transform))
+	fmt.Fprintf(w, " Q")
+	w.alpha = -1.0 // graphics state was restored, force setting the opacity again
+}
+
+// PushClip saves the graphics state and intersects the clipping path with the path.
+func (w *pdfPageWriter) PushClip(path *canvas.Path, m canvas.Matrix) {
+	if w.inTextObject {
+		w.EndTextObject()
+	}
+	w.clips = append(w.clips, *w)
+	data := path.Transform(m).ToPDF()
+	if data == "" {
+		data = "0 0 0 0 re" // clip everything
+	}
+	fmt.Fprintf(w, " q %v W n", data)
+}
+
+// PopClip restores the graphics state from before the matching PushClip, which removes the clipping path. The cached state of the page writer is restored as well.
+func (w *pdfPageWriter) PopClip() {
+	if len(w.clips) == 0 {
+		panic("must be in clip")
+	}
+	if w.inTextObject {
+		w.EndTextObject()
+	}
+	fmt.Fprintf(w, " Q")
+	state := w.clips[len(w.clips)-1]
+	buffer, groups, clips := w.Buffer, w.groups, w.clips[:len(w.clips)-1]
+	*w = state
+	w.Buffer, w.groups, w.clips = buffer, groups, clips
+}
+
+type pdfGroup struct {
+	state              pdfPageWriter // page writer at the start of the group
+	isolated, knockout bool
+}
+
+// BeginGroup starts writing to a new content stream for a transparency group. The graphics state is inherited by the group.
+func (w *pdfPageWriter) BeginGroup(isolated, knockout bool) {
+	w.groups = append(w.groups, pdfGroup{*w, isolated, knockout})
+	w.Buffer = &bytes.Buffer{}
+}
+
+// EndGroup writes the content stream of the current transparency group as a form XObject and draws it. Afterwards the graphics state is that of the start of the group, since drawing a form XObject saves and restores the graphics state.
+func (w *pdfPageWriter) EndGroup() {
+	if len(w.groups) == 0 {
+		panic("must be in group")
+	}
+	group := w.groups[len(w.groups)-1]
+
+	b := w.Bytes()
+	if 0 < len(b) && b[0] == ' ' {
+		b = b[1:]
+	}
+	stream := pdfStream{
+		dict: pdfDict{
+			"Type":    pdfName("XObject"),
+			"Subtype": pdfName("Form"),
+			"BBox":    pdfArray{0.0, 0.0, w.width, w.height},
+			"Group": pdfDict{
+				"Type": pdfName("Group"),
+				"S":    pdfName("Transparency"),
+				"I":    group.isolated,
+				"K":    group.knockout,
+			},
+			"Resources": w.resources,
+		},
+		stream: b,
+	}
+	if w.pdf.compress {
+		stream.dict["Filter"] = pdfFilterFlate
+	}
+	ref := w.pdf.writeObject(stream)
+
+	*w = group.state
+	if _, ok := w.resources["XObject"]; !ok {
+		w.resources["XObject"] = pdfDict{}
+	}
+	name := pdfName(fmt.Sprintf("Fm%d", len(w.resources["XObject"].(pdfDict))))
+	w.resources["XObject"].(pdfDict)[name] = ref
+	fmt.Fprintf(w, " /%v Do", name)
+}
+
 func (w *pdfPageWriter) embedImage(img image.Image, enc canvas.ImageEncoding) pdfName {
+	ref := w.writeImage(img, enc)
+	if _, ok := w.resources["XObject"]; !ok {
+		w.resources["XObject"] = pdfDict{}
+	}
+	name := pdfName(fmt.Sprintf("Im%d", len(w.resources["XObject"].(pdfDict))))
+	w.resources["XObject"].(pdfDict)[name] = ref
+	return name
+}
+
+func (w *pdfPageWriter) writeImage(img image.Image, enc canvas.ImageEncoding) pdfRef {
 	if i, ok := img.(canvas.JPEGImage); ok {
-		return w.embedJpeg(i)
+		return w.writeJpeg(i)
+	} else if i, ok := img.(*image.CMYK); ok {
+		return w.writeCMYK(i)
 	}
 	size := img.Bounds().Size()
 	sp := img.Bounds().Min // starting point
@@ -1002,20 +1596,13 @@ func (w *pdfPageWriter) embedImage(img image.Image, enc canvas.ImageEncoding) pd
 	}
 
 	// TODO: (PDF) implement JPXFilter for lossy image compression
-	ref := w.pdf.writeObject(pdfStream{
+	return w.pdf.writeObject(pdfStream{
 		dict:   dict,
 		stream: b,
 	})
-
-	if _, ok := w.resources["XObject"]; !ok {
-		w.resources["XObject"] = pdfDict{}
-	}
-	name := pdfName(fmt.Sprintf("Im%d", len(w.resources["XObject"].(pdfDict))))
-	w.resources["XObject"].(pdfDict)[name] = ref
-	return name
 }
 
-func (w *pdfPageWriter) embedJpeg(img canvas.JPEGImage) pdfName {
+func (w *pdfPageWriter) writeJpeg(img canvas.JPEGImage) pdfRef {
 	size := img.Bounds().Size()
 	dict := pdfDict{
 		"Type":    pdfName("XObject"),
@@ -1036,22 +1623,41 @@ func (w *pdfPageWriter) embedJpeg(img canvas.JPEGImage) pdfName {
 		dict["ColorSpace"] = pdfName("DeviceRGB")
 	case color.CMYKModel:
 		dict["ColorSpace"] = pdfName("DeviceCMYK")
-		dict["Decode"] = pdfArray([]interface{}{1, 0, 1, 0, 1, 0, 1, 0})
+		if isAdobeJPEG(img.JPEGBytes()) {
+			// Adobe applications write inverted CMYK values
+			dict["Decode"] = pdfArray([]interface{}{1, 0, 1, 0, 1, 0, 1, 0})
+		}
 	default:
 		panic("image JPEG buffer has unsupported color space: " + fmt.Sprint(img.ColorModel()))
 	}
 
-	ref := w.pdf.writeObject(pdfStream{
+	return w.pdf.writeObject(pdfStream{
 		dict:   dict,
 		stream: img.JPEGBytes(),
 	})
+}
 
-	if _, ok := w.resources["XObject"]; !ok {
-		w.resources["XObject"] = pdfDict{}
+func (w *pdfPageWriter) writeCMYK(img *image.CMYK) pdfRef {
+	size := img.Bounds().Size()
+	b := make([]byte, 0, size.X*size.Y*4)
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		i := img.PixOffset(img.Rect.Min.X, y)
+		b = append(b, img.Pix[i:i+size.X*4]...)
 	}
-	name := pdfName(fmt.Sprintf("Im%d", len(w.resources["XObject"].(pdfDict))))
-	w.resources["XObject"].(pdfDict)[name] = ref
-	return name
+
+	return w.pdf.writeObject(pdfStream{
+		dict: pdfDict{
+			"Type":             pdfName("XObject"),
+			"Subtype":          pdfName("Image"),
+			"Width":            size.X,
+			"Height":           size.Y,
+			"ColorSpace":       pdfName("DeviceCMYK"),
+			"BitsPerComponent": 8,
+			"Interpolate":      true,
+			"Filter":           pdfFilterFlate,
+		},
+		stream: b,
+	})
 }
 
 func (w *pdfPageWriter) getOpacityGS(a float64) pdfName {
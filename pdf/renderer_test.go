@@ -2,11 +2,18 @@ package pdf
 
 import (
 	"bytes"
+	"fmt"
 	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/rasterizer"
 	"github.com/tdewolff/test"
 )
 
@@ -59,6 +66,13 @@ func TestPDFPath(t *testing.T) {
 	test.String(t, pdf.String(), " 2.8346457 0 0 2.8346457 0 0 cm /A0 gs 1 0 0 rg /A1 gs 0 0 1 RG 5 w 1 J 1 j [1 2 3 1 2 3] 2 d")
 }
 
+func TestPDFFlattenMethod(t *testing.T) {
+	pdf := New(&bytes.Buffer{}, 20.0, 20.0)
+	pdf.SetFlattenMethod(canvas.UniformSteps(2))
+	pdf.RenderPath(canvas.MustParseSVG("M0 0Q5 10 10 0"), canvas.DefaultStyle, canvas.Identity.Translate(0.0, 5.0))
+	test.That(t, strings.Contains(pdf.w.String(), " 0 5 m 5 10 l 10 5 l f"), "curves must be flattened")
+}
+
 func TestPDFText(t *testing.T) {
 	//dejaVuSerif := NewFontFamily("dejavu-serif")
 	//dejaVuSerif.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
@@ -106,3 +120,283 @@ func TestPDFMultipage(t *testing.T) {
 	nbPages := strings.Count(out, "/Type /Page ")
 	test.That(t, nbPages == 2, "expected 2 pages, got", nbPages)
 }
+
+type cmykJPEGImage struct {
+	*image.CMYK
+	b []byte
+}
+
+func (img cmykJPEGImage) JPEGBytes() []byte {
+	return img.b
+}
+
+func TestPDFCMYKImage(t *testing.T) {
+	// only the headers of JPEG images are read by the PDF writer, the data is copied as is
+	adobe := []byte("\xFF\xD8\xFF\xEE\x00\x0EAdobe\x00\x64\x00\x00\x00\x00\x00\xFF\xDA\x00\x02\xFF\xD9")
+	plain := []byte("\xFF\xD8\xFF\xE0\x00\x04\x00\x00\xFF\xDA\x00\x02\xFF\xD9")
+	test.That(t, isAdobeJPEG(adobe))
+	test.That(t, !isAdobeJPEG(plain))
+
+	buf := &bytes.Buffer{}
+	pdf := New(buf, 10.0, 10.0)
+	pdf.RenderImage(cmykJPEGImage{image.NewCMYK(image.Rect(0, 0, 2, 2)), adobe}, canvas.Identity)
+	pdf.Close()
+	test.That(t, strings.Contains(buf.String(), "/ColorSpace /DeviceCMYK /Decode [1 0 1 0 1 0 1 0] /Filter /DCTDecode"))
+	test.That(t, bytes.Contains(buf.Bytes(), adobe), "JPEG bytes must be preserved")
+
+	buf.Reset()
+	pdf = New(buf, 10.0, 10.0)
+	pdf.RenderImage(cmykJPEGImage{image.NewCMYK(image.Rect(0, 0, 2, 2)), plain}, canvas.Identity)
+	pdf.Close()
+	test.That(t, strings.Contains(buf.String(), "/ColorSpace /DeviceCMYK /Filter /DCTDecode"))
+	test.That(t, bytes.Contains(buf.Bytes(), plain), "JPEG bytes must be preserved")
+
+	buf.Reset()
+	pdf = New(buf, 10.0, 10.0)
+	pdf.RenderImage(image.NewCMYK(image.Rect(0, 0, 2, 2)), canvas.Identity)
+	pdf.Close()
+	test.That(t, strings.Contains(buf.String(), "/ColorSpace /DeviceCMYK /Filter /FlateDecode"))
+}
+
+func TestPDFImagePaint(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	rect := canvas.Rectangle(4.0, 4.0)
+
+	buf := &bytes.Buffer{}
+	pdf := newPDFWriter(buf).NewPage(210.0, 297.0)
+	pdf.DrawImagePaint(rect, canvas.ImagePaint{Image: img, Transform: canvas.Identity}, canvas.NonZero, canvas.Identity)
+	test.String(t, pdf.String(), " 2.8346457 0 0 2.8346457 0 0 cm q 0 0 m 4 0 l 4 4 l 0 4 l h W n q 0 0 2 2 re W n 0 0 m 0 2 l 2 2 l 2 0 l h W n 2 0 0 2 0 0 cm /Im0 Do Q Q")
+
+	buf.Reset()
+	pdf = newPDFWriter(buf).NewPage(210.0, 297.0)
+	pdf.DrawImagePaint(rect, canvas.ImagePaint{Image: img, Transform: canvas.Identity, Extend: canvas.ExtendRepeat}, canvas.EvenOdd, canvas.Identity)
+	test.String(t, pdf.String(), " 2.8346457 0 0 2.8346457 0 0 cm /Pattern cs /P0 scn 0 0 m 4 0 l 4 4 l 0 4 l h f*")
+	test.That(t, pdf.resources["Pattern"] != nil, "pattern must be added to the page resources")
+}
+
+func TestPDFGradient(t *testing.T) {
+	stops := []canvas.GradientStop{{Offset: 0.2, Color: canvas.Red}, {Offset: 1.0, Color: canvas.Blue}}
+	rect := canvas.Rectangle(4.0, 4.0)
+
+	buf := &bytes.Buffer{}
+	pdf := newPDFWriter(buf).NewPage(210.0, 297.0)
+	test.That(t, pdf.DrawGradient(rect, canvas.LinearGradient{Start: canvas.Point{X: 0.0, Y: 0.0}, End: canvas.Point{X: 4.0, Y: 0.0}, Stops: stops}, canvas.NonZero, canvas.Identity), "must draw linear gradient")
+	test.String(t, pdf.String(), " 2.8346457 0 0 2.8346457 0 0 cm /Pattern cs /P0 scn 0 0 m 4 0 l 4 4 l 0 4 l h f")
+	test.That(t, pdf.resources["Pattern"] != nil, "pattern must be added to the page resources")
+	test.That(t, !pdf.DrawGradient(rect, canvas.ImagePaint{}, canvas.NonZero, canvas.Identity), "must not draw image paint")
+
+	buf.Reset()
+	w := newPDFWriter(buf)
+	w.SetCompression(false)
+	pdf = w.NewPage(210.0, 297.0)
+	pdf.DrawGradient(rect, canvas.RadialGradient{Center: canvas.Point{X: 2.0, Y: 2.0}, Radius: 2.0, Stops: stops}, canvas.EvenOdd, canvas.Identity)
+	w.Close()
+	test.That(t, strings.Contains(buf.String(), "/Coords [2 2 0 2 2 2] /Extend [false false]"), "must write radial shading")
+	test.That(t, strings.Contains(buf.String(), "/ShadingType 3"), "must write radial shading")
+}
+
+func TestPDFGroup(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pdf := newPDFWriter(buf).NewPage(210.0, 297.0)
+	pdf.SetFillColor(canvas.Red)
+	pdf.BeginGroup(true, true)
+	pdf.SetFillColor(canvas.Blue)
+	fmt.Fprintf(pdf, " 0 0 m 10 0 l 10 10 l f")
+	pdf.EndGroup()
+	pdf.SetFillColor(canvas.Blue)
+	test.String(t, pdf.String(), " 2.8346457 0 0 2.8346457 0 0 cm 1 0 0 rg /Fm0 Do 0 0 1 rg")
+	test.That(t, strings.Contains(buf.String(), "/Group << /Type /Group /I true /K true /S /Transparency >>"), "must write a transparency group")
+	test.That(t, strings.Contains(buf.String(), "0 0 1 rg 0 0 m 10 0 l 10 10 l f"), "must write the group content")
+	test.That(t, pdf.resources["XObject"] != nil, "group must be added to the page resources")
+}
+
+func TestPDFShadow(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pdf := New(buf, 20.0, 20.0)
+	pdf.RenderShadow(canvas.Rectangle(4.0, 4.0), 1.0, canvas.Black, canvas.Identity.Translate(1.0, 2.0))
+	// the image covers the path and three times the blur around it at 10 dots-per-millimeter
+	test.String(t, pdf.w.String(), " 2.8346457 0 0 2.8346457 0 0 cm q -2 -1 10 10 re W n -2 -1 m -2 9 l 8 9 l 8 -1 l h W n 10 0 0 10 -2 -1 cm /Im0 Do Q")
+	test.That(t, pdf.w.resources["XObject"] != nil, "image must be added to the page resources")
+}
+
+func TestPDFUnits(t *testing.T) {
+	c := canvas.New(100.0, 50.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(100.0, 50.0))
+
+	// the page size in points and the image size in pixels are the same physical size
+	buf := &bytes.Buffer{}
+	test.Error(t, Writer(buf, c))
+	mediaBox := regexp.MustCompile(`/MediaBox \[0 0 ([0-9.]+) ([0-9.]+)\]`).FindStringSubmatch(buf.String())
+	test.T(t, len(mediaBox), 3)
+	wPt, _ := strconv.ParseFloat(mediaBox[1], 64)
+	hPt, _ := strconv.ParseFloat(mediaBox[2], 64)
+	test.That(t, math.Abs(canvas.TypographicPoint.ToMillimeters(wPt)-100.0) < 1e-3)
+	test.That(t, math.Abs(canvas.TypographicPoint.ToMillimeters(hPt)-50.0) < 1e-3)
+
+	resolution := 300.0 * canvas.DPI
+	buf.Reset()
+	test.Error(t, rasterizer.PNGWriter(resolution)(buf, c))
+	img, err := png.Decode(buf)
+	test.Error(t, err)
+	size := img.Bounds().Size()
+	test.T(t, size, image.Point{1181, 591})
+	test.That(t, math.Abs(resolution.Pixel().ToMillimeters(float64(size.X))-100.0) < 0.1) // rounded to whole pixels
+	test.That(t, math.Abs(resolution.Pixel().ToMillimeters(float64(size.Y))-50.0) < 0.1)
+
+	wPx, hPx := c.SizeIn(resolution.Pixel())
+	test.Float(t, wPx, 1181.1023622)
+	test.Float(t, hPx, 590.5511811)
+	wPt, hPt = c.SizeIn(canvas.TypographicPoint)
+	test.Float(t, wPt, 283.4645669)
+	test.Float(t, hPt, 141.7322835)
+}
+
+func TestPDFPages(t *testing.T) {
+	pages := []*canvas.Canvas{canvas.New(210, 297), canvas.New(297, 210)}
+	buf := &bytes.Buffer{}
+	test.Error(t, WritePages(buf, pages))
+	out := buf.String()
+	test.T(t, strings.Count(out, "/Type /Page "), 2)
+	test.That(t, strings.Contains(out, "/MediaBox [0 0 841.88976 595.27559]"), "second page must be landscape")
+
+	test.That(t, WritePages(buf, nil) != nil, "must fail without pages")
+
+	calls := [][2]int{}
+	buf.Reset()
+	test.Error(t, WritePagesProgress(buf, pages, func(done, total int) {
+		calls = append(calls, [2]int{done, total})
+	}))
+	test.T(t, calls, [][2]int{{1, 2}, {2, 2}})
+	test.T(t, strings.Count(buf.String(), "/Type /Page "), 2)
+}
+
+func TestPDFSpotColor(t *testing.T) {
+	profile := make([]byte, 128)
+	copy(profile[12:], "prtrCMYKLab ")
+
+	buf := &bytes.Buffer{}
+	pdf := New(buf, 210, 297)
+	pdf.SetCompression(false)
+	style := canvas.DefaultStyle
+	style.FillPaint = canvas.SpotColor{
+		Name: "PANTONE 185 C",
+		Tint: 0.5,
+		CMYK: color.CMYK{0, 232, 199, 0},
+		Lab:  &canvas.LabColor{L: 47.0, A: 72.0, B: 40.0},
+		ICC:  &canvas.ICCColor{Profile: profile, Components: []float64{0.0, 0.91, 0.78, 0.0}},
+	}
+	pdf.RenderPath(canvas.Rectangle(10.0, 10.0), style, canvas.Identity)
+	style.FillPaint = nil
+	style.StrokeColor = canvas.Black
+	style.StrokePaint = canvas.SpotColor{Name: "Varnish", Tint: 1.0, CMYK: color.CMYK{0, 0, 0, 26}}
+	pdf.RenderPath(canvas.Rectangle(10.0, 10.0), style, canvas.Identity)
+	style.StrokePaint = canvas.SpotColor{Name: "Gold", Tint: 1.0, Lab: &canvas.LabColor{L: 70.0, A: 5.0, B: 60.0}}
+	pdf.RenderPath(canvas.Rectangle(10.0, 10.0), style, canvas.Identity)
+	test.Error(t, pdf.Close())
+	out := buf.String()
+
+	test.That(t, strings.Contains(out, "/CS0 cs .5 scn"), "must fill with a tint of the spot color")
+	test.That(t, strings.Contains(out, "/CS1 CS 1 SCN"), "must stroke with the spot color")
+	test.That(t, regexp.MustCompile(`\[/Separation /PANTONE#20185#20C \[/ICCBased \d+ 0 R\] << /C0 \[0 0 0 0\] /C1 \[0 \.91 \.78 0\] /Domain \[0 1\] /FunctionType 2 /N 1 >>\]`).MatchString(out), "alternate must be ICC-based:", out)
+	test.That(t, strings.Contains(out, "<< /Length 128 /N 4 >>"), "must embed the ICC profile")
+	test.That(t, strings.Contains(out, "[/Separation /Varnish /DeviceCMYK << /C0 [0 0 0 0] /C1 [0 0 0 .10196078] "), "alternate must fall back to CMYK")
+	test.That(t, strings.Contains(out, "[/Separation /Gold [/Lab << /Range [-128 127 -128 127] /WhitePoint [.9642 1 .8249] >>] << /C0 [100 0 0] /C1 [70 5 60] "), "alternate must be Lab")
+}
+
+func TestPDFOverprint(t *testing.T) {
+	buf := &bytes.Buffer{}
+	pdf := New(buf, 210, 297)
+	pdf.SetCompression(false)
+	style := canvas.DefaultStyle
+	style.Overprint = true
+	pdf.RenderPath(canvas.Rectangle(10.0, 10.0), style, canvas.Identity)
+	pdf.RenderPath(canvas.Rectangle(20.0, 10.0), style, canvas.Identity)
+	style.Overprint = false
+	pdf.RenderPath(canvas.Rectangle(30.0, 10.0), style, canvas.Identity)
+	test.Error(t, pdf.Close())
+	out := buf.String()
+
+	test.That(t, strings.Contains(out, "/OP1 << /OP true /OPM 1 /op true >>"), "must define the overprint graphics state:", out)
+	test.That(t, strings.Contains(out, "/OP0 << /OP false /op false >>"), "must define the knockout graphics state")
+	test.T(t, strings.Count(out, "/OP1 gs"), 1)
+	test.That(t, strings.Index(out, "/OP1 gs") < strings.Index(out, "/OP0 gs"), "must overprint the first paths only")
+
+	// without overprinting no graphics state is set
+	buf.Reset()
+	pdf = New(buf, 210, 297)
+	pdf.SetCompression(false)
+	pdf.RenderPath(canvas.Rectangle(10.0, 10.0), canvas.DefaultStyle, canvas.Identity)
+	test.Error(t, pdf.Close())
+	test.That(t, !strings.Contains(buf.String(), "/OP"))
+}
+
+func TestPDFToUnicode(t *testing.T) {
+	dejaVuSerif := canvas.NewFontFamily("dejavu-serif")
+	if err := dejaVuSerif.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular); err != nil {
+		t.Fatal(err)
+	}
+	face := dejaVuSerif.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+
+	// more than 255 distinct glyphs from Latin, Greek and Cyrillic
+	runes := []rune{}
+	for _, rng := range [][2]rune{{0xC0, 0x17F}, {0x391, 0x3A1}, {0x3A3, 0x3C9}, {0x410, 0x44F}} {
+		for r := rng[0]; r <= rng[1]; r++ {
+			runes = append(runes, r)
+		}
+	}
+	s := string(runes)
+	test.That(t, 300 < len(runes))
+
+	buf := &bytes.Buffer{}
+	pdf := New(buf, 210, 297)
+	pdf.SetCompression(false)
+	pdf.RenderText(canvas.NewTextLine(face, s, canvas.Left), canvas.Identity)
+	test.Error(t, pdf.Close())
+	out := buf.String()
+	test.That(t, strings.Contains(out, "/Subtype /CIDFontType2"), "must embed TrueType as CID font")
+	test.That(t, strings.Contains(out, "/CIDToGIDMap /Identity"), "must map CIDs to glyph IDs")
+	test.That(t, strings.Contains(out, "/FontFile2 "), "must embed TrueType as FontFile2")
+	test.That(t, regexp.MustCompile(`/ToUnicode \d+ 0 R`).MatchString(out), "must have a ToUnicode CMap")
+
+	// map the glyph IDs in the content back to characters using the ToUnicode CMap
+	cmap := map[string]rune{}
+	for _, m := range regexp.MustCompile(`<([0-9A-F]{4})> <([0-9A-F]{4})>\n`).FindAllStringSubmatch(out[strings.Index(out, "beginbfchar"):], -1) {
+		r, _ := strconv.ParseUint(m[2], 16, 16)
+		cmap[m[1]] = rune(r)
+	}
+	test.T(t, len(cmap), len(runes))
+	content := out[strings.Index(out, " BT"):strings.Index(out, " ET")]
+	extracted := []rune{}
+	for _, m := range regexp.MustCompile(`\(((?:[^\\)]|\\.)*)\)`).FindAllStringSubmatch(content, -1) {
+		b := regexp.MustCompile(`\\(.)`).ReplaceAllString(m[1], "$1")
+		for i := 0; i+1 < len(b); i += 2 {
+			extracted = append(extracted, cmap[fmt.Sprintf("%02X%02X", b[i], b[i+1])])
+		}
+	}
+	test.String(t, string(extracted), s)
+
+	// fonts with CFF outlines
+	ebGaramond := canvas.NewFontFamily("eb-garamond")
+	if err := ebGaramond.LoadFontFile("../font/EBGaramond12-Regular.otf", canvas.FontRegular); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	pdf = New(buf, 210, 297)
+	pdf.RenderText(canvas.NewTextLine(ebGaramond.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal), "Garamond", canvas.Left), canvas.Identity)
+	test.Error(t, pdf.Close())
+	out = buf.String()
+	test.That(t, strings.Contains(out, "/Subtype /CIDFontType0"), "must embed CFF as CID font")
+	test.That(t, strings.Contains(out, "/FontFile3 "), "must embed CFF as FontFile3")
+	test.That(t, !strings.Contains(out, "/CIDToGIDMap"), "CFF fonts have no CIDToGIDMap")
+
+	// ligature glyphs without presentation form map to their components
+	face = ebGaramond.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+	face.Ligatures = canvas.DiscretionaryLigatures
+	buf.Reset()
+	pdf = New(buf, 210, 297)
+	pdf.SetCompression(false)
+	pdf.RenderText(canvas.NewTextLine(face, "The", canvas.Left), canvas.Identity)
+	test.Error(t, pdf.Close())
+	test.That(t, strings.Contains(buf.String(), "<07BF> <00540068>\n"), "must map the ligature to its components")
+}
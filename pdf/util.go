@@ -9,7 +9,7 @@ import (
 	"github.com/tdewolff/minify/v2"
 )
 
-const ptPerMm = 72 / 25.4
+const ptPerMm = 1.0 / float64(canvas.TypographicPoint)
 
 ////////////////////////////////////////////////////////////////
 
@@ -37,3 +37,101 @@ func (f dec) String() string {
 	}
 	return s
 }
+
+// isAdobeJPEG returns true if the JPEG contains an Adobe APP14 marker, which indicates that CMYK values are stored inverted.
+func isAdobeJPEG(b []byte) bool {
+	if len(b) < 2 || b[0] != 0xFF || b[1] != 0xD8 {
+		return false
+	}
+	for i := 2; i+4 <= len(b); {
+		if b[i] != 0xFF {
+			return false
+		}
+		marker := b[i+1]
+		if marker == 0xFF {
+			i++ // fill byte
+			continue
+		} else if marker == 0xD9 || marker == 0xDA {
+			return false // EOI or SOS, no more headers
+		} else if 0xD0 <= marker && marker <= 0xD7 || marker == 0x01 {
+			i += 2 // markers without length
+			continue
+		}
+		n := int(b[i+2])<<8 | int(b[i+3])
+		if marker == 0xEE && 7 <= n && i+4+5 <= len(b) && string(b[i+4:i+9]) == "Adobe" {
+			return true
+		}
+		i += 2 + n
+	}
+	return false
+}
+
+// spotName returns the name of a spot color as a PDF name, where characters that are not allowed in names are escaped.
+func spotName(name string) pdfName {
+	sb := strings.Builder{}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c <= ' ' || '~' < c || strings.IndexByte("#()<>[]{}/%", c) != -1 {
+			fmt.Fprintf(&sb, "#%02X", c)
+		} else {
+			sb.WriteByte(c)
+		}
+	}
+	return pdfName(sb.String())
+}
+
+// iccComponents returns the number of components of the color space of an ICC profile, or zero if the profile is invalid or its color space is not gray, RGB or CMYK.
+func iccComponents(profile []byte) int {
+	if len(profile) < 128 {
+		return 0
+	}
+	switch string(profile[16:20]) {
+	case "GRAY":
+		return 1
+	case "RGB ":
+		return 3
+	case "CMYK":
+		return 4
+	}
+	return 0
+}
+
+// iccWhite returns the color values of white, ie. no ink, in the color space of an ICC profile.
+func iccWhite(profile []byte) pdfArray {
+	switch string(profile[16:20]) {
+	case "GRAY":
+		return pdfArray{1}
+	case "RGB ":
+		return pdfArray{1, 1, 1}
+	}
+	return pdfArray{0, 0, 0, 0}
+}
+
+func imagePaint(paint canvas.Paint) (canvas.ImagePaint, bool) {
+	switch p := paint.(type) {
+	case canvas.ImagePaint:
+		return p, true
+	case *canvas.ImagePaint:
+		return *p, true
+	}
+	return canvas.ImagePaint{}, false
+}
+
+// gradientPaint returns the gradient of the paint, dereferencing pointers to gradients.
+func gradientPaint(paint canvas.Paint) canvas.Paint {
+	switch p := paint.(type) {
+	case *canvas.LinearGradient:
+		return *p
+	case *canvas.RadialGradient:
+		return *p
+	}
+	return paint
+}
+
+func isGradient(paint canvas.Paint) bool {
+	switch gradientPaint(paint).(type) {
+	case canvas.LinearGradient, canvas.RadialGradient:
+		return true
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package pdf
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/tdewolff/canvas"
@@ -12,3 +13,27 @@ func Writer(w io.Writer, c *canvas.Canvas) error {
 	c.Render(pdf)
 	return pdf.Close()
 }
+
+// WritePages writes the canvases as the pages of a PDF file, where each page has the size of its canvas.
+func WritePages(w io.Writer, pages []*canvas.Canvas) error {
+	return WritePagesProgress(w, pages, nil)
+}
+
+// WritePagesProgress writes the canvases as the pages of a PDF file as WritePages, and reports the number of rendered pages to progress if it is not nil, see canvas.Progress. It is called after each page, or after every number of pages for many pages, and pages are rendered before the file is written so that the last call precedes writing the file.
+func WritePagesProgress(w io.Writer, pages []*canvas.Canvas, progress canvas.Progress) error {
+	if len(pages) == 0 {
+		return fmt.Errorf("no pages")
+	}
+	step := len(pages)/100 + 1 // throttle
+	pdf := New(w, pages[0].W, pages[0].H)
+	for i, page := range pages {
+		if 0 < i {
+			pdf.NewPage(page.W, page.H)
+		}
+		page.Render(pdf)
+		if progress != nil && ((i+1)%step == 0 || i+1 == len(pages)) {
+			progress(i+1, len(pages))
+		}
+	}
+	return pdf.Close()
+}
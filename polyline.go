@@ -1,5 +1,7 @@
 package canvas
 
+import "sort"
+
 // Polyline defines a list of points in 2D space that form a polyline. If the last coordinate equals the first coordinate, we assume the polyline to close itself.
 type Polyline struct {
 	coords []Point
@@ -74,6 +76,55 @@ func (p *Polyline) Interior(x, y float64, fillRule FillRule) bool {
 	return fillCount%2 != 0
 }
 
+// scanlineIntervals returns the intervals of x where the horizontal line at y is in the interior of the polylines, which are implicitly closed, ordered from left to right.
+func scanlineIntervals(polylines []*Polyline, y float64, fillRule FillRule) [][2]float64 {
+	type crossing struct {
+		x     float64
+		count int // winding direction
+	}
+	crossings := []crossing{}
+	for _, polyline := range polylines {
+		coords := polyline.coords
+		for i := 0; i < len(coords); i++ {
+			a, b := coords[len(coords)-1], coords[i] // polylines are implicitly closed
+			if 0 < i {
+				a = coords[i-1]
+			}
+			if (y < a.Y) == (y < b.Y) {
+				continue
+			}
+			x := a.X + (b.X-a.X)*(y-a.Y)/(b.Y-a.Y)
+			count := 1
+			if a.Y < b.Y {
+				count = -1
+			}
+			crossings = append(crossings, crossing{x, count})
+		}
+	}
+	sort.Slice(crossings, func(i, j int) bool {
+		return crossings[i].x < crossings[j].x
+	})
+
+	intervals := [][2]float64{}
+	count := 0
+	for i := 0; i+1 < len(crossings); i++ {
+		c := crossings[i]
+		count += c.count
+		filled := count != 0
+		if fillRule == EvenOdd {
+			filled = count%2 != 0
+		}
+		if filled && c.x < crossings[i+1].x {
+			if n := len(intervals); 0 < n && intervals[n-1][1] == c.x {
+				intervals[n-1][1] = crossings[i+1].x // join adjacent intervals
+			} else {
+				intervals = append(intervals, [2]float64{c.x, crossings[i+1].x})
+			}
+		}
+	}
+	return intervals
+}
+
 // Smoothen returns a new path that smoothens out a path using cubic Béziers between all the path points. It makes sure that the curvature is smooth along the whole path. If the path is closed, it will be smooth between start and end segment too.
 func (p *Polyline) Smoothen() *Path {
 	K := p.coords
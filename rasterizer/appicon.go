@@ -0,0 +1,79 @@
+package rasterizer
+
+import (
+	"image"
+	"math"
+
+	"github.com/tdewolff/canvas"
+	"golang.org/x/image/draw"
+)
+
+// Platform is the platform of a set of app icons, which determines the sizes, the mask and the safe area of the icons, see ExportAppIcons.
+type Platform int
+
+// see Platform
+const (
+	IOS     Platform = iota // squircle mask with the content filling the icon
+	Android                 // circular mask of adaptive icons with the content in the safe zone
+	Web                     // favicons and web app icons without a mask
+)
+
+// appIcon is the name and size in pixels of an icon of a platform.
+type appIcon struct {
+	name string
+	size int
+}
+
+var appIcons = map[Platform][]appIcon{
+	IOS: {
+		{"ios-20", 20}, {"ios-29", 29}, {"ios-40", 40}, {"ios-58", 58}, {"ios-60", 60}, {"ios-76", 76}, {"ios-80", 80},
+		{"ios-87", 87}, {"ios-120", 120}, {"ios-152", 152}, {"ios-167", 167}, {"ios-180", 180}, {"ios-1024", 1024},
+	},
+	Android: {
+		{"mipmap-mdpi", 48}, {"mipmap-hdpi", 72}, {"mipmap-xhdpi", 96}, {"mipmap-xxhdpi", 144}, {"mipmap-xxxhdpi", 192}, {"play-store", 512},
+	},
+	Web: {
+		{"favicon-16", 16}, {"favicon-32", 32}, {"favicon-48", 48}, {"apple-touch-icon", 180}, {"icon-192", 192}, {"icon-512", 512},
+	},
+}
+
+// ExportAppIcons rasterizes the canvas at each size of the app icons of the platform, as named by the returned map: "ios-180" for iOS icons by their size in pixels, "mipmap-xxhdpi" for Android launcher icons by their density and "play-store" for the store listing, and "favicon-32", "apple-touch-icon" and "icon-192" for the web. The canvas is scaled uniformly to fit the safe area of the platform and centered: the entire icon for iOS and the web, and the safe zone of adaptive icons for Android, which is the center of 66/108 of the icon. The icons are masked by a squircle for iOS and a circle for Android, so that the corners are transparent. It returns nil for an unknown platform.
+func ExportAppIcons(c *canvas.Canvas, platform Platform) map[string]*image.RGBA {
+	icons, ok := appIcons[platform]
+	if !ok {
+		return nil
+	}
+
+	safe := 1.0
+	if platform == Android {
+		safe = 66.0 / 108.0
+	}
+	w, h := c.Size()
+	imgs := map[string]*image.RGBA{}
+	for _, icon := range icons {
+		size := float64(icon.size)
+		img := image.NewRGBA(image.Rect(0, 0, icon.size, icon.size))
+		if 0.0 < w && 0.0 < h {
+			content := Draw(c, canvas.DPMM(safe*size/math.Max(w, h)))
+			offset := image.Point{(icon.size - content.Rect.Dx()) / 2, (icon.size - content.Rect.Dy()) / 2}
+			draw.Draw(img, content.Rect.Add(offset), content, image.Point{}, draw.Over)
+		}
+
+		var mask *canvas.Path
+		switch platform {
+		case IOS:
+			mask = canvas.Superellipse(size/2.0, size/2.0, 5.0).Translate(size/2.0, size/2.0)
+		case Android:
+			mask = canvas.Circle(size/2.0).Translate(size/2.0, size/2.0)
+		}
+		if mask != nil {
+			alpha := image.NewAlpha(img.Rect)
+			New(alpha, 1.0).RenderPath(mask, canvas.DefaultStyle, canvas.Identity)
+			masked := image.NewRGBA(img.Rect)
+			draw.DrawMask(masked, img.Rect, img, image.Point{}, alpha, image.Point{}, draw.Src)
+			img = masked
+		}
+		imgs[icon.name] = img
+	}
+	return imgs
+}
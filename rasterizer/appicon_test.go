@@ -0,0 +1,48 @@
+package rasterizer
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestExportAppIcons(t *testing.T) {
+	// red canvas with a green bottom half
+	c := canvas.New(10.0, 10.0)
+	style := canvas.DefaultStyle
+	style.FillColor = canvas.Red
+	c.RenderPath(canvas.Rectangle(10.0, 10.0), style, canvas.Identity)
+	style.FillColor = color.RGBA{0, 255, 0, 255}
+	c.RenderPath(canvas.Rectangle(10.0, 5.0), style, canvas.Identity)
+
+	icons := ExportAppIcons(c, IOS)
+	test.T(t, len(icons), 13)
+	test.T(t, icons["ios-180"].Rect.Dx(), 180)
+	test.T(t, icons["ios-1024"].Rect.Dy(), 1024)
+	icon := icons["ios-180"]
+	test.T(t, icon.RGBAAt(0, 0).A, uint8(0))     // masked corner
+	test.T(t, icon.RGBAAt(179, 179).A, uint8(0)) // masked corner
+	test.T(t, icon.RGBAAt(90, 0), canvas.Red)    // the content fills the icon up to the edges
+	test.T(t, icon.RGBAAt(1, 45), canvas.Red)
+	test.T(t, icon.RGBAAt(90, 179), color.RGBA{0, 255, 0, 255})
+	test.T(t, icon.RGBAAt(20, 20), canvas.Red) // a squircle covers more than a circle
+
+	icons = ExportAppIcons(c, Android)
+	test.T(t, len(icons), 6)
+	icon = icons["mipmap-xxxhdpi"]
+	test.T(t, icon.Rect.Dx(), 192)
+	test.T(t, icon.RGBAAt(0, 0).A, uint8(0))
+	test.T(t, icon.RGBAAt(96, 10).A, uint8(0)) // outside the safe zone
+	test.T(t, icon.RGBAAt(96, 40), canvas.Red)
+	test.T(t, icon.RGBAAt(96, 150), color.RGBA{0, 255, 0, 255})
+	test.T(t, icons["play-store"].Rect.Dx(), 512)
+
+	icons = ExportAppIcons(c, Web)
+	test.T(t, icons["favicon-16"].Rect.Dx(), 16)
+	test.T(t, icons["favicon-16"].RGBAAt(0, 0), canvas.Red) // without a mask
+	test.T(t, icons["apple-touch-icon"].Rect.Dx(), 180)
+
+	test.T(t, ExportAppIcons(c, Platform(-1)) == nil, true)
+}
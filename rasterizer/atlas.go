@@ -0,0 +1,154 @@
+package rasterizer
+
+import (
+	"image"
+	"math"
+	"sort"
+
+	"github.com/tdewolff/canvas"
+	"golang.org/x/image/draw"
+)
+
+// PackAtlas rasterizes the canvases with given resolution (in dots-per-millimeter) and packs them tightly into a single atlas image, such as a sprite sheet of icons for games or user interfaces. It returns the atlas and the rectangle in pixels of each canvas by name, which can be written as a manifest. Canvases are separated from each other and from the edges of the atlas by padding pixels. The canvases are placed using the MaxRects algorithm with the best short side fit heuristic, largest first, and the atlas grows until all canvases fit. If powerOfTwo is set, the width and height of the atlas are powers of two, as required by some GPU textures.
+func PackAtlas(canvases map[string]*canvas.Canvas, resolution canvas.DPMM, padding int, powerOfTwo bool) (*image.RGBA, map[string]image.Rectangle) {
+	if padding < 0 {
+		padding = 0
+	}
+
+	names := make([]string, 0, len(canvases))
+	sizes := map[string]image.Point{}
+	area, maxWidth, maxHeight := 0, 0, 0
+	for name, c := range canvases {
+		size := imageBounds(c, resolution).Size()
+		names = append(names, name)
+		sizes[name] = size
+		area += (size.X + padding) * (size.Y + padding)
+		maxWidth = imax(maxWidth, size.X)
+		maxHeight = imax(maxHeight, size.Y)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := sizes[names[i]], sizes[names[j]]
+		if a.Y != b.Y {
+			return a.Y > b.Y
+		} else if a.X != b.X {
+			return a.X > b.X
+		}
+		return names[i] < names[j]
+	})
+
+	// start with a square atlas of the total area and grow its smaller side until all canvases fit
+	w := imax(int(math.Ceil(math.Sqrt(float64(area)))), maxWidth+padding) + padding
+	h := imax(w, maxHeight+2*padding)
+	if powerOfTwo {
+		w, h = nextPowerOfTwo(w), nextPowerOfTwo(h)
+	}
+	var rects map[string]image.Rectangle
+	for {
+		var ok bool
+		if rects, ok = packRects(names, sizes, w, h, padding); ok {
+			break
+		}
+		if powerOfTwo {
+			if w <= h {
+				w *= 2
+			} else {
+				h *= 2
+			}
+		} else if w <= h {
+			w += imax(1, w/4)
+		} else {
+			h += imax(1, h/4)
+		}
+	}
+
+	// shrink the atlas to the packed canvases
+	if !powerOfTwo {
+		w, h = 0, 0
+		for _, rect := range rects {
+			w = imax(w, rect.Max.X+padding)
+			h = imax(h, rect.Max.Y+padding)
+		}
+	}
+
+	atlas := image.NewRGBA(image.Rect(0, 0, w, h))
+	for _, name := range names {
+		img := Draw(canvases[name], resolution)
+		draw.Draw(atlas, rects[name], img, image.Point{}, draw.Src)
+	}
+	return atlas, rects
+}
+
+// packRects places rectangles of the given sizes in a bin of size w×h using the MaxRects algorithm, separated by padding. It returns false if they do not fit.
+func packRects(names []string, sizes map[string]image.Point, w, h, padding int) (map[string]image.Rectangle, bool) {
+	free := []image.Rectangle{image.Rect(padding, padding, w, h)}
+	rects := make(map[string]image.Rectangle, len(names))
+	for _, name := range names {
+		size := sizes[name].Add(image.Point{padding, padding})
+
+		// best short side fit picks the free rectangle with the smallest leftover on its shortest side
+		best, bestShort, bestLong := -1, 0, 0
+		for i, rect := range free {
+			dx, dy := rect.Dx()-size.X, rect.Dy()-size.Y
+			if dx < 0 || dy < 0 {
+				continue
+			}
+			short, long := dx, dy
+			if long < short {
+				short, long = long, short
+			}
+			if best == -1 || short < bestShort || short == bestShort && long < bestLong {
+				best, bestShort, bestLong = i, short, long
+			}
+		}
+		if best == -1 {
+			return nil, false
+		}
+		placed := image.Rectangle{free[best].Min, free[best].Min.Add(size)}
+		rects[name] = image.Rectangle{placed.Min, placed.Min.Add(sizes[name])}
+
+		// split the free rectangles that overlap the placed rectangle into the maximal rectangles around it
+		split := []image.Rectangle{}
+		for _, rect := range free {
+			if !rect.Overlaps(placed) {
+				split = append(split, rect)
+				continue
+			}
+			if rect.Min.X < placed.Min.X {
+				split = append(split, image.Rect(rect.Min.X, rect.Min.Y, placed.Min.X, rect.Max.Y))
+			}
+			if placed.Max.X < rect.Max.X {
+				split = append(split, image.Rect(placed.Max.X, rect.Min.Y, rect.Max.X, rect.Max.Y))
+			}
+			if rect.Min.Y < placed.Min.Y {
+				split = append(split, image.Rect(rect.Min.X, rect.Min.Y, rect.Max.X, placed.Min.Y))
+			}
+			if placed.Max.Y < rect.Max.Y {
+				split = append(split, image.Rect(rect.Min.X, placed.Max.Y, rect.Max.X, rect.Max.Y))
+			}
+		}
+
+		// remove free rectangles that are contained in others
+		free = free[:0]
+		for i, rect := range split {
+			contained := false
+			for j, other := range split {
+				if i != j && rect.In(other) && (rect != other || j < i) {
+					contained = true
+					break
+				}
+			}
+			if !contained {
+				free = append(free, rect)
+			}
+		}
+	}
+	return rects, true
+}
+
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p *= 2
+	}
+	return p
+}
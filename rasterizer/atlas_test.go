@@ -0,0 +1,56 @@
+package rasterizer
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestPackAtlas(t *testing.T) {
+	canvases := map[string]*canvas.Canvas{}
+	sizes := []image.Point{{16, 16}, {32, 8}, {8, 24}, {12, 12}, {20, 10}, {6, 6}}
+	for i, size := range sizes {
+		c := canvas.New(float64(size.X), float64(size.Y))
+		style := canvas.DefaultStyle
+		style.FillColor = color.RGBA{uint8(40 * (i + 1)), 0, 0, 255}
+		c.RenderPath(canvas.Rectangle(float64(size.X), float64(size.Y)), style, canvas.Identity)
+		canvases[fmt.Sprintf("icon%d", i)] = c
+	}
+
+	for _, powerOfTwo := range []bool{false, true} {
+		atlas, rects := PackAtlas(canvases, 1.0, 2, powerOfTwo)
+		test.T(t, len(rects), len(sizes))
+		bounds := atlas.Bounds()
+		if powerOfTwo {
+			test.T(t, nextPowerOfTwo(bounds.Dx()), bounds.Dx())
+			test.T(t, nextPowerOfTwo(bounds.Dy()), bounds.Dy())
+		}
+
+		area := 0
+		for i, size := range sizes {
+			name := fmt.Sprintf("icon%d", i)
+			rect := rects[name]
+			test.T(t, rect.Size(), size)
+			test.That(t, rect.Inset(-2).In(bounds), name, "must be inside the atlas with padding")
+			for other, otherRect := range rects {
+				if other != name {
+					test.That(t, !rect.Inset(-2).Overlaps(otherRect), name, "must not overlap", other)
+				}
+			}
+			test.T(t, atlas.RGBAAt(rect.Min.X, rect.Min.Y), color.RGBA{uint8(40 * (i + 1)), 0, 0, 255})
+			test.T(t, atlas.RGBAAt(rect.Max.X-1, rect.Max.Y-1), color.RGBA{uint8(40 * (i + 1)), 0, 0, 255})
+			area += (size.X + 2) * (size.Y + 2)
+		}
+		if !powerOfTwo {
+			test.That(t, bounds.Dx()*bounds.Dy() < 2*area, "atlas must be tightly packed")
+		}
+	}
+
+	atlas, rects := PackAtlas(map[string]*canvas.Canvas{}, 1.0, 2, false)
+	test.T(t, atlas.Bounds().Empty(), true)
+	test.T(t, len(rects), 0)
+}
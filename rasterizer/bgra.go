@@ -0,0 +1,67 @@
+package rasterizer
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/tdewolff/canvas"
+	"golang.org/x/image/draw"
+)
+
+// BGRA is an in-memory image with premultiplied 8-bit colors in the channel order blue, green, red and alpha, as expected by many framebuffers and GPU textures. Its At method returns color.RGBA.
+type BGRA struct {
+	// Pix holds the image's pixels, in B, G, R, A order. The pixel at (x, y) starts at Pix[(y-Rect.Min.Y)*Stride + (x-Rect.Min.X)*4].
+	Pix []uint8
+	// Stride is the Pix stride (in bytes) between vertically adjacent pixels.
+	Stride int
+	// Rect is the image's bounds.
+	Rect image.Rectangle
+}
+
+// NewBGRA returns a new BGRA image with the given bounds.
+func NewBGRA(r image.Rectangle) *BGRA {
+	return &BGRA{
+		Pix:    make([]uint8, 4*r.Dx()*r.Dy()),
+		Stride: 4 * r.Dx(),
+		Rect:   r,
+	}
+}
+
+// ColorModel returns color.RGBAModel, as the colors are premultiplied.
+func (img *BGRA) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+// Bounds returns the bounds of the image.
+func (img *BGRA) Bounds() image.Rectangle {
+	return img.Rect
+}
+
+// At returns the color of the pixel at (x,y).
+func (img *BGRA) At(x, y int) color.Color {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return color.RGBA{}
+	}
+	i := img.PixOffset(x, y)
+	return color.RGBA{img.Pix[i+2], img.Pix[i+1], img.Pix[i], img.Pix[i+3]}
+}
+
+// Set sets the color of the pixel at (x,y).
+func (img *BGRA) Set(x, y int, c color.Color) {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return
+	}
+	i := img.PixOffset(x, y)
+	c1 := color.RGBAModel.Convert(c).(color.RGBA)
+	img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c1.B, c1.G, c1.R, c1.A
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds to the pixel at (x,y).
+func (img *BGRA) PixOffset(x, y int) int {
+	return (y-img.Rect.Min.Y)*img.Stride + (x-img.Rect.Min.X)*4
+}
+
+// DrawInto draws the canvas over the contents of dst with given resolution (in dots-per-millimeter), such as a framebuffer that is reused between frames, so that no image is allocated and converted. The canvas is drawn from the top-left corner of dst, whose bounds must start at (0,0). Colors are composited in premultiplied alpha and stored in the color model of dst, such as *image.RGBA, *image.NRGBA with straight alpha, or *BGRA with the channel order of many framebuffers.
+func DrawInto(dst draw.Image, c *canvas.Canvas, resolution canvas.DPMM) {
+	c.Render(New(dst, resolution))
+}
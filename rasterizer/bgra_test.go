@@ -0,0 +1,45 @@
+package rasterizer
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestDrawInto(t *testing.T) {
+	// an opaque orange square and a blue square at 50% opacity over the orange and the background
+	c := canvas.New(3.0, 1.0)
+	style := canvas.DefaultStyle
+	style.FillColor = color.RGBA{255, 128, 0, 255}
+	c.RenderPath(canvas.Rectangle(2.0, 1.0), style, canvas.Identity)
+	style.FillColor = color.RGBA{0, 0, 128, 128}
+	c.RenderPath(canvas.Rectangle(2.0, 1.0), style, canvas.Identity.Translate(1.0, 0.0))
+
+	rgba := Draw(c, 1.0)
+
+	bgra := NewBGRA(image.Rect(0, 0, 3, 1))
+	DrawInto(bgra, c, 1.0)
+	test.T(t, bgra.Pix[:4], []uint8{0, 128, 255, 255}) // blue, green, red, alpha
+	for x := 0; x < 3; x++ {
+		test.T(t, bgra.At(x, 0), rgba.At(x, 0))
+		i := bgra.PixOffset(x, 0)
+		p := rgba.Pix[rgba.PixOffset(x, 0):]
+		test.T(t, bgra.Pix[i:i+4], []uint8{p[2], p[1], p[0], p[3]})
+	}
+
+	nrgba := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	DrawInto(nrgba, c, 1.0)
+	test.T(t, nrgba.NRGBAAt(0, 0), color.NRGBA{255, 128, 0, 255})
+	test.T(t, nrgba.NRGBAAt(2, 0), color.NRGBA{0, 0, 255, 128}) // straight alpha
+
+	// drawing composites over the existing pixels
+	white := NewBGRA(image.Rect(0, 0, 3, 1))
+	for i := range white.Pix {
+		white.Pix[i] = 255
+	}
+	DrawInto(white, c, 1.0)
+	test.T(t, white.At(2, 0), color.RGBA{127, 127, 255, 255})
+}
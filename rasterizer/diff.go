@@ -0,0 +1,129 @@
+package rasterizer
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"reflect"
+
+	"github.com/tdewolff/canvas"
+	"golang.org/x/image/draw"
+)
+
+// diffMargin is the number of pixels that dirty regions are extended by to include the antialiasing fringe of the changed shapes.
+const diffMargin = 2
+
+// DrawDiff updates prevImg, the image of prev drawn with the given resolution, to the image of cur by rasterizing only the region that changed between both canvases, such as for interactive applications that redraw after small changes. It compares the display lists of both canvases and returns the updated image and the region in pixels that was rasterized. The region covers the old and new positions of all changed draw operations including their antialiasing, which is empty when nothing changed. Operations that are equal in both canvases but lie in the region are drawn again. When prevImg is nil or does not have the size of cur, or when transparency groups or clipping paths changed, the whole image of cur is drawn.
+func DrawDiff(prev, cur *canvas.Canvas, prevImg *image.RGBA, resolution canvas.DPMM) (*image.RGBA, image.Rectangle) {
+	bounds := imageBounds(cur, resolution)
+	if prevImg == nil || prevImg.Bounds() != bounds || prev.W != cur.W || prev.H != cur.H {
+		return Draw(cur, resolution), bounds
+	}
+
+	// skip the draw operations that are equal at the start and end of both display lists
+	dlPrev, dlCur := prev.DisplayList(), cur.DisplayList()
+	n := 0
+	for n < len(dlPrev) && n < len(dlCur) && equalCommands(dlPrev[n], dlCur[n]) {
+		n++
+	}
+	m := 0
+	for m < len(dlPrev)-n && m < len(dlCur)-n && equalCommands(dlPrev[len(dlPrev)-1-m], dlCur[len(dlCur)-1-m]) {
+		m++
+	}
+
+	r := &boundsRenderer{resolution: float64(resolution), height: bounds.Dy()}
+	append(dlPrev[n:len(dlPrev)-m:len(dlPrev)-m], dlCur[n:len(dlCur)-m]...).Render(r)
+	if r.groups {
+		return Draw(cur, resolution), bounds
+	}
+	dirty := r.dirty.Intersect(bounds)
+	if dirty.Empty() {
+		return prevImg, image.Rectangle{}
+	}
+
+	// rasterize the dirty region on its own image, where the canvas is translated by whole pixels so that antialiasing is the same as for the whole image
+	img := image.NewRGBA(image.Rect(0, 0, dirty.Dx(), dirty.Dy()))
+	dx := -float64(dirty.Min.X) / float64(resolution)
+	dy := -float64(bounds.Dy()-dirty.Max.Y) / float64(resolution)
+	cur.Render(&viewRenderer{New(img, resolution), canvas.Identity.Translate(dx, dy)})
+	draw.Draw(prevImg, dirty, img, image.Point{}, draw.Src)
+	return prevImg, dirty
+}
+
+// equalCommands returns true if both draw operations draw the same, where images are compared by their pixels.
+func equalCommands(a, b canvas.DisplayCommand) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// viewRenderer is a renderer that draws the canvas with a view transformation, see canvas.DisplayList.Render.
+type viewRenderer struct {
+	*Renderer
+	view canvas.Matrix
+}
+
+func (r *viewRenderer) View() canvas.Matrix {
+	return r.view
+}
+
+// boundsRenderer collects the region in pixels that is drawn by draw operations.
+type boundsRenderer struct {
+	resolution float64
+	height     int
+	dirty      image.Rectangle
+	groups     bool // whether groups or clipping paths are drawn
+}
+
+func (r *boundsRenderer) Size() (float64, float64) {
+	return 0.0, 0.0
+}
+
+// add adds the bounds in millimeters to the dirty region.
+func (r *boundsRenderer) add(rect canvas.Rect, margin float64) {
+	x0 := int(math.Floor((rect.X-margin)*r.resolution)) - diffMargin
+	y0 := int(math.Floor((rect.Y-margin)*r.resolution)) - diffMargin
+	x1 := int(math.Ceil((rect.X+rect.W+margin)*r.resolution)) + diffMargin
+	y1 := int(math.Ceil((rect.Y+rect.H+margin)*r.resolution)) + diffMargin
+	r.dirty = r.dirty.Union(image.Rect(x0, r.height-y1, x1, r.height-y0))
+}
+
+func (r *boundsRenderer) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	path = path.Transform(m)
+	rect := path.Bounds()
+	if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth || style.StrokePaint != nil {
+		rect = rect.Add(path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner).Bounds())
+	}
+	r.add(rect, 0.0)
+}
+
+func (r *boundsRenderer) RenderShadow(path *canvas.Path, blur float64, col color.RGBA, m canvas.Matrix) {
+	r.add(path.Transform(m).Bounds(), 3.0*blur)
+}
+
+func (r *boundsRenderer) RenderInsetShadow(path *canvas.Path, dx, dy, blur float64, col color.RGBA, m canvas.Matrix) {
+	r.add(path.Transform(m).Bounds(), 0.0)
+}
+
+func (r *boundsRenderer) RenderText(text *canvas.Text, m canvas.Matrix) {
+	canvas.RenderTextAsPath(r, text, m)
+}
+
+func (r *boundsRenderer) RenderImage(img image.Image, m canvas.Matrix) {
+	size := img.Bounds().Size()
+	r.add(canvas.Rect{W: float64(size.X), H: float64(size.Y)}.Transform(m), 0.0)
+}
+
+func (r *boundsRenderer) BeginGroup(isolated, knockout bool) {
+	r.groups = true
+}
+
+func (r *boundsRenderer) EndGroup() {
+	r.groups = true
+}
+
+func (r *boundsRenderer) PushClip(path *canvas.Path, m canvas.Matrix) {
+	r.groups = true
+}
+
+func (r *boundsRenderer) PopClip() {
+	r.groups = true
+}
@@ -0,0 +1,60 @@
+package rasterizer
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestDrawDiff(t *testing.T) {
+	scene := func(x float64) *canvas.Canvas {
+		c := canvas.New(40.0, 20.0)
+		ctx := canvas.NewContext(c)
+		ctx.SetFillColor(canvas.Lightgray)
+		ctx.DrawPath(0.0, 0.0, canvas.Rectangle(40.0, 20.0))
+		ctx.SetFillColor(canvas.Red)
+		ctx.DrawPath(x, 5.0, canvas.Circle(2.5))
+		ctx.SetFillColor(canvas.Blue)
+		ctx.DrawPath(25.0, 5.0, canvas.Rectangle(10.0, 10.0))
+		return c
+	}
+
+	resolution := canvas.DPMM(4.0)
+	prev, cur := scene(5.3), scene(12.1)
+	img := Draw(prev, resolution)
+	before := image.NewRGBA(img.Bounds())
+	copy(before.Pix, img.Pix)
+
+	img, dirty := DrawDiff(prev, cur, img, resolution)
+	test.That(t, !dirty.Empty(), "dirty region must not be empty")
+	test.That(t, dirty.Min.X <= 11 && 59 <= dirty.Max.X, "dirty region must cover the old and new circle:", dirty)
+	test.That(t, dirty.Max.X < 25*4, "dirty region must not cover the rectangle:", dirty)
+
+	// the updated image equals the image of the new canvas, and nothing changed outside of the dirty region
+	expected := Draw(cur, resolution)
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			test.That(t, colorsClose(img.RGBAAt(x, y), expected.RGBAAt(x, y)), "pixel", x, y, "must equal full rasterization")
+			if !(image.Point{x, y}).In(dirty) {
+				test.T(t, img.RGBAAt(x, y), before.RGBAAt(x, y))
+			}
+		}
+	}
+
+	// nothing changed
+	img, dirty = DrawDiff(cur, scene(12.1), img, resolution)
+	test.That(t, dirty.Empty(), "dirty region must be empty")
+
+	// no previous image
+	img, dirty = DrawDiff(prev, cur, nil, resolution)
+	test.T(t, dirty, img.Bounds())
+}
+
+func colorsClose(a, b color.RGBA) bool {
+	d := func(x, y uint8) bool { return x-y <= 1 || y-x <= 1 }
+	return d(a.R, b.R) && d(a.G, b.G) && d(a.B, b.B) && d(a.A, b.A)
+}
@@ -0,0 +1,95 @@
+package rasterizer
+
+import (
+	"image"
+
+	"github.com/tdewolff/canvas"
+)
+
+// DitherMode is the method to reduce gray levels to black and white, see Rasterize1Bit.
+type DitherMode int
+
+// see DitherMode
+const (
+	Threshold      DitherMode = iota // pixels darker than half gray become black
+	FloydSteinberg                   // error diffusion, best for photographs and gradients
+	Ordered                          // 8x8 Bayer matrix, gives a regular pattern that prints well on thermal printers
+)
+
+// bayer8 is the 8x8 Bayer threshold matrix.
+var bayer8 = [8][8]uint8{
+	{0, 32, 8, 40, 2, 34, 10, 42},
+	{48, 16, 56, 24, 50, 18, 58, 26},
+	{12, 44, 4, 36, 14, 46, 6, 38},
+	{60, 28, 52, 20, 62, 30, 54, 22},
+	{3, 35, 11, 43, 1, 33, 9, 41},
+	{51, 19, 59, 27, 49, 17, 57, 25},
+	{15, 47, 7, 39, 13, 45, 5, 37},
+	{63, 31, 55, 23, 61, 29, 53, 21},
+}
+
+// Rasterize1Bit draws the canvas with given resolution (in dots-per-millimeter) on white paper and reduces it to black and white using the given dither mode, such as for thermal and label printers. The returned image only has gray values of 0 (black) and 255 (white), see Pack1Bit to convert it to the bitmaps of ESC/POS or ZPL. Pure black and white regions stay black and white for every dither mode.
+func Rasterize1Bit(c *canvas.Canvas, resolution canvas.DPMM, dither DitherMode) *image.Gray {
+	img := Draw(c, resolution)
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+
+	// composite over white and convert to luminance
+	lum := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		pix := img.Pix[img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y+y):]
+		for x := 0; x < w; x++ {
+			r, g, b, a := float64(pix[4*x]), float64(pix[4*x+1]), float64(pix[4*x+2]), float64(pix[4*x+3])
+			lum[y*w+x] = 0.299*r + 0.587*g + 0.114*b + (255.0 - a)
+		}
+	}
+
+	dst := image.NewGray(img.Rect)
+	for y := 0; y < h; y++ {
+		pix := dst.Pix[y*dst.Stride:]
+		for x := 0; x < w; x++ {
+			v := lum[y*w+x]
+			threshold := 127.5
+			if dither == Ordered {
+				threshold = (float64(bayer8[y%8][x%8]) + 0.5) * 255.0 / 64.0
+			}
+			out := 0.0
+			if threshold < v {
+				out = 255.0
+				pix[x] = 255
+			}
+			if dither == FloydSteinberg {
+				// diffuse the quantization error to the unvisited neighbours
+				err := v - out
+				if x+1 < w {
+					lum[y*w+x+1] += err * 7.0 / 16.0
+				}
+				if y+1 < h {
+					if 0 < x {
+						lum[(y+1)*w+x-1] += err * 3.0 / 16.0
+					}
+					lum[(y+1)*w+x] += err * 5.0 / 16.0
+					if x+1 < w {
+						lum[(y+1)*w+x+1] += err * 1.0 / 16.0
+					}
+				}
+			}
+		}
+	}
+	return dst
+}
+
+// Pack1Bit packs a black and white image into rows of bits, with the most significant bit first and a set bit for black pixels, as used by the raster bitmaps of ESC/POS and ZPL printers. Each row is padded to a whole number of bytes, and it returns the bits and the number of bytes per row.
+func Pack1Bit(img *image.Gray) ([]byte, int) {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	stride := (w + 7) / 8
+	bits := make([]byte, stride*h)
+	for y := 0; y < h; y++ {
+		pix := img.Pix[img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y+y):]
+		for x := 0; x < w; x++ {
+			if pix[x] < 128 {
+				bits[y*stride+x/8] |= 0x80 >> uint(x%8)
+			}
+		}
+	}
+	return bits, stride
+}
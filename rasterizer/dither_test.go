@@ -0,0 +1,60 @@
+package rasterizer
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestRasterize1Bit(t *testing.T) {
+	for _, gray := range []uint8{64, 128, 192} {
+		c := canvas.New(32, 32)
+		style := canvas.DefaultStyle
+		style.FillColor = color.RGBA{gray, gray, gray, 255}
+		c.RenderPath(canvas.Rectangle(32, 32), style, canvas.Identity)
+
+		for _, dither := range []DitherMode{FloydSteinberg, Ordered} {
+			img := Rasterize1Bit(c, 1.0, dither)
+			sum := 0.0
+			for _, v := range img.Pix {
+				test.That(t, v == 0 || v == 255, "must be black or white")
+				sum += float64(v)
+			}
+			mean := sum / float64(len(img.Pix))
+			test.That(t, math.Abs(mean-float64(gray)) < 4.0, "mean", mean, "must approximate", gray, "for dither mode", dither)
+		}
+	}
+
+	// black on white thresholds cleanly
+	c := canvas.New(16, 16)
+	c.RenderPath(canvas.Rectangle(8, 16), canvas.DefaultStyle, canvas.Identity)
+	for _, dither := range []DitherMode{Threshold, FloydSteinberg, Ordered} {
+		img := Rasterize1Bit(c, 1.0, dither)
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 16; x++ {
+				if x < 8 {
+					test.T(t, img.GrayAt(x, y).Y, uint8(0))
+				} else {
+					test.T(t, img.GrayAt(x, y).Y, uint8(255))
+				}
+			}
+		}
+	}
+}
+
+func TestPack1Bit(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 10, 2))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	img.SetGray(0, 0, color.Gray{0})
+	img.SetGray(9, 0, color.Gray{0})
+	img.SetGray(3, 1, color.Gray{0})
+	bits, stride := Pack1Bit(img)
+	test.T(t, stride, 2)
+	test.Bytes(t, bits, []byte{0x80, 0x40, 0x10, 0x00})
+}
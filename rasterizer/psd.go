@@ -0,0 +1,182 @@
+package rasterizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"math"
+
+	"github.com/tdewolff/canvas"
+	"golang.org/x/image/draw"
+)
+
+// BlendMode is the mode to blend a layer of a PSD file with the layers below it, see PSDLayer.
+type BlendMode int
+
+// see BlendMode
+const (
+	BlendNormal   BlendMode = iota // paint the layer on top
+	BlendMultiply                  // multiply the colors, which darkens
+	BlendScreen                    // multiply the inverse colors, which lightens
+	BlendDarken                    // keep the darker color of each channel
+	BlendLighten                   // keep the lighter color of each channel
+)
+
+// psdBlendKeys are the blend mode keys of PSD layer records by BlendMode.
+var psdBlendKeys = []string{"norm", "mul ", "scrn", "dark", "lite"}
+
+// PSDLayer is a layer of a PSD file, which is the rasterized canvas with its name and blend mode, see WritePSD.
+type PSDLayer struct {
+	Name   string
+	Canvas *canvas.Canvas
+	Blend  BlendMode
+}
+
+// WritePSD writes the layers to a layered Photoshop (PSD) file with given resolution (in dots-per-millimeter), such as to hand off generated artwork to designers. The first layer is the bottom layer. Each canvas is rasterized into a separate layer of pixels, where the size of the file is the size of the first canvas and other canvases are aligned to its bottom-left corner. The file also holds the composite of the layers with their blend modes, which is matted against white as by Photoshop and used by readers that do not support layers.
+func WritePSD(w io.Writer, layers []PSDLayer, resolution canvas.DPMM) error {
+	if len(layers) == 0 {
+		return fmt.Errorf("PSD requires at least one layer")
+	} else if 1<<15 <= len(layers) {
+		return fmt.Errorf("PSD supports at most %d layers", 1<<15-1)
+	}
+	bounds := imageBounds(layers[0].Canvas, resolution)
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 || 30000 < width || 30000 < height {
+		return fmt.Errorf("PSD requires a size between 1 and 30000 pixels")
+	}
+
+	imgs := make([]*image.RGBA, len(layers))
+	for i, layer := range layers {
+		if layer.Blend < 0 || int(layer.Blend) >= len(psdBlendKeys) {
+			return fmt.Errorf("unknown blend mode %d", layer.Blend)
+		}
+		img := Draw(layer.Canvas, resolution)
+		if img.Rect != bounds {
+			// align to the bottom-left corner of the first canvas
+			aligned := image.NewRGBA(bounds)
+			draw.Draw(aligned, bounds, img, image.Point{0, img.Rect.Dy() - height}, draw.Src)
+			img = aligned
+		}
+		imgs[i] = img
+	}
+
+	// layer records followed by the channel image data of all layers
+	records, data := &bytes.Buffer{}, &bytes.Buffer{}
+	binary.Write(records, binary.BigEndian, int16(-len(layers))) // negative: the first alpha channel of the composite is its transparency
+	for i, layer := range layers {
+		binary.Write(records, binary.BigEndian, [4]int32{0, 0, int32(height), int32(width)})
+		binary.Write(records, binary.BigEndian, uint16(4))
+		for _, id := range []int16{-1, 0, 1, 2} {
+			binary.Write(records, binary.BigEndian, id)
+			binary.Write(records, binary.BigEndian, uint32(2+width*height))
+		}
+		records.WriteString("8BIM")
+		records.WriteString(psdBlendKeys[layer.Blend])
+		records.Write([]byte{255, 0, 0, 0}) // opacity, clipping, flags (visible), filler
+
+		// Pascal string padded to a multiple of four bytes
+		name := []byte(layer.Name)
+		if 255 < len(name) {
+			name = name[:255]
+		}
+		name = append([]byte{byte(len(name))}, name...)
+		for len(name)%4 != 0 {
+			name = append(name, 0)
+		}
+		binary.Write(records, binary.BigEndian, uint32(8+len(name)))
+		binary.Write(records, binary.BigEndian, uint32(0)) // layer mask data
+		binary.Write(records, binary.BigEndian, uint32(0)) // layer blending ranges
+		records.Write(name)
+
+		nrgba := StraightAlpha(imgs[i])
+		for _, channel := range []int{3, 0, 1, 2} {
+			binary.Write(data, binary.BigEndian, uint16(0)) // raw
+			data.Write(psdChannel(nrgba.Pix, nrgba.Stride, width, height, channel))
+		}
+	}
+	if (records.Len()+data.Len())%2 != 0 {
+		data.WriteByte(0)
+	}
+
+	// composite with the blend modes, matted against white
+	composite := psdComposite(imgs, layers)
+	for i := 0; i < len(composite.Pix); i += 4 {
+		a := uint32(composite.Pix[i+3])
+		for k := 0; k < 3; k++ {
+			composite.Pix[i+k] = uint8(uint32(composite.Pix[i+k]) + 255 - a)
+		}
+	}
+
+	b := &bytes.Buffer{}
+	b.WriteString("8BPS")
+	binary.Write(b, binary.BigEndian, uint16(1))
+	b.Write(make([]byte, 6))
+	binary.Write(b, binary.BigEndian, uint16(4)) // RGB and alpha
+	binary.Write(b, binary.BigEndian, uint32(height))
+	binary.Write(b, binary.BigEndian, uint32(width))
+	binary.Write(b, binary.BigEndian, uint16(8)) // bits per channel
+	binary.Write(b, binary.BigEndian, uint16(3)) // RGB color mode
+	binary.Write(b, binary.BigEndian, uint32(0)) // color mode data
+	binary.Write(b, binary.BigEndian, uint32(0)) // image resources
+	binary.Write(b, binary.BigEndian, uint32(4+records.Len()+data.Len()+4))
+	binary.Write(b, binary.BigEndian, uint32(records.Len()+data.Len()))
+	b.Write(records.Bytes())
+	b.Write(data.Bytes())
+	binary.Write(b, binary.BigEndian, uint32(0)) // global layer mask info
+	binary.Write(b, binary.BigEndian, uint16(0)) // raw
+	for _, channel := range []int{0, 1, 2, 3} {
+		b.Write(psdChannel(composite.Pix, composite.Stride, width, height, channel))
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// psdChannel returns a single channel of an image with four channels per pixel, row by row from the top.
+func psdChannel(pix []uint8, stride, width, height, channel int) []byte {
+	b := make([]byte, 0, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			b = append(b, pix[y*stride+4*x+channel])
+		}
+	}
+	return b
+}
+
+// psdComposite composites the layers with their blend modes, see https://www.w3.org/TR/compositing-1/#blending.
+func psdComposite(imgs []*image.RGBA, layers []PSDLayer) *image.RGBA {
+	dst := image.NewRGBA(imgs[0].Rect)
+	for i, img := range imgs {
+		blend := layers[i].Blend
+		for j := 0; j < len(dst.Pix); j += 4 {
+			as, ab := float64(img.Pix[j+3])/255.0, float64(dst.Pix[j+3])/255.0
+			if as == 0.0 {
+				continue
+			}
+			for k := 0; k < 3; k++ {
+				Cs, Cb := float64(img.Pix[j+k])/255.0, float64(dst.Pix[j+k])/255.0 // premultiplied
+				cs := Cs / as
+				cb := 0.0
+				if ab != 0.0 {
+					cb = Cb / ab
+				}
+				mix := cs
+				switch blend {
+				case BlendMultiply:
+					mix = cb * cs
+				case BlendScreen:
+					mix = cb + cs - cb*cs
+				case BlendDarken:
+					mix = math.Min(cb, cs)
+				case BlendLighten:
+					mix = math.Max(cb, cs)
+				}
+				c := (1.0-ab)*Cs + as*ab*mix + (1.0-as)*Cb
+				dst.Pix[j+k] = uint8(math.Min(255.0, c*255.0+0.5))
+			}
+			dst.Pix[j+3] = uint8(math.Min(255.0, (as+ab*(1.0-as))*255.0+0.5))
+		}
+	}
+	return dst
+}
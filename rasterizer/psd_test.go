@@ -0,0 +1,68 @@
+package rasterizer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/color"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
+)
+
+func TestWritePSD(t *testing.T) {
+	background := canvas.New(4.0, 2.0)
+	background.RenderPath(canvas.Rectangle(4.0, 2.0), canvas.Style{FillColor: color.RGBA{255, 255, 0, 255}}, canvas.Identity)
+	foreground := canvas.New(4.0, 2.0)
+	foreground.RenderPath(canvas.Rectangle(2.0, 2.0), canvas.Style{FillColor: color.RGBA{0, 255, 255, 255}}, canvas.Identity)
+
+	buf := &bytes.Buffer{}
+	err := WritePSD(buf, []PSDLayer{
+		{"Background", background, BlendNormal},
+		{"Cyan overlay", foreground, BlendMultiply},
+	}, 1.0)
+	test.Error(t, err)
+
+	b := buf.Bytes()
+	test.String(t, string(b[:4]), "8BPS")
+	test.T(t, binary.BigEndian.Uint16(b[12:]), uint16(4))
+	test.T(t, binary.BigEndian.Uint32(b[14:]), uint32(2)) // height
+	test.T(t, binary.BigEndian.Uint32(b[18:]), uint32(4)) // width
+
+	// parse the layer records
+	pos := 26 + 4 + 4
+	layerSectionEnd := pos + 4 + int(binary.BigEndian.Uint32(b[pos:]))
+	pos += 8
+	count := int16(binary.BigEndian.Uint16(b[pos:]))
+	test.T(t, count, int16(-2))
+	pos += 2
+
+	names, blends := []string{}, []string{}
+	for i := 0; i < 2; i++ {
+		pos += 16
+		channels := int(binary.BigEndian.Uint16(b[pos:]))
+		test.T(t, channels, 4)
+		pos += 2 + 6*channels
+		test.String(t, string(b[pos:pos+4]), "8BIM")
+		blends = append(blends, string(b[pos+4:pos+8]))
+		pos += 12
+		extra := int(binary.BigEndian.Uint32(b[pos:]))
+		name := b[pos+12:]
+		names = append(names, string(name[1:1+int(name[0])]))
+		pos += 4 + extra
+	}
+	test.T(t, names, []string{"Background", "Cyan overlay"})
+	test.T(t, blends, []string{"norm", "mul "})
+
+	// the composite image follows the layer section, in planar channels
+	composite := b[layerSectionEnd+2:]
+	test.T(t, len(composite), 4*8)
+	pixel := func(x, y int) [4]byte {
+		return [4]byte{composite[y*4+x], composite[8+y*4+x], composite[16+y*4+x], composite[24+y*4+x]}
+	}
+	test.T(t, pixel(0, 0), [4]byte{0, 255, 0, 255}) // yellow multiplied with cyan
+	test.T(t, pixel(3, 1), [4]byte{255, 255, 0, 255})
+
+	err = WritePSD(buf, nil, 1.0)
+	test.That(t, err != nil, "must require layers")
+}
@@ -2,8 +2,13 @@ package rasterizer
 
 import (
 	"image"
+	"image/color"
+	"math"
+	"sync"
 
 	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/font"
+	"github.com/tdewolff/canvas/internal/gaussian"
 	"golang.org/x/image/draw"
 	"golang.org/x/image/math/f64"
 	"golang.org/x/image/vector"
@@ -12,15 +17,136 @@ import (
 // Draw draws the canvas on a new image with given resolution (in dots-per-millimeter).
 // Higher resolution will result in bigger images.
 func Draw(c *canvas.Canvas, resolution canvas.DPMM) *image.RGBA {
-	img := image.NewRGBA(image.Rect(0, 0, int(c.W*float64(resolution)+0.5), int(c.H*float64(resolution)+0.5)))
+	img := image.NewRGBA(imageBounds(c, resolution))
 	ras := New(img, resolution)
 	c.Render(ras)
 	return img
 }
 
+// DrawProgress draws the canvas as Draw, and reports the number of rasterized drawing operations to progress, such as to update a progress bar for large canvases, see canvas.Progress.
+func DrawProgress(c *canvas.Canvas, resolution canvas.DPMM, progress canvas.Progress) *image.RGBA {
+	img := image.NewRGBA(imageBounds(c, resolution))
+	ras := New(img, resolution)
+	c.RenderProgress(ras, progress)
+	return img
+}
+
+// DrawMask draws the coverage of the canvas on a new alpha mask with given resolution (in dots-per-millimeter), ignoring colors. The mask equals the alpha channel of the image given by Draw, so that overlapping shapes are composited and never exceed full coverage.
+func DrawMask(c *canvas.Canvas, resolution canvas.DPMM) *image.Alpha {
+	img := image.NewAlpha(imageBounds(c, resolution))
+	ras := New(img, resolution)
+	c.Render(ras)
+	return img
+}
+
+// AlphaMode is the convention of the color channels of rasterized pixels with respect to their alpha channel, see DrawWithAlpha.
+type AlphaMode int
+
+// see AlphaMode
+const (
+	Premultiplied AlphaMode = iota // colors are multiplied by alpha, as for image.RGBA
+	Straight                       // colors are independent of alpha, as for image.NRGBA
+)
+
+// DrawWithAlpha draws the canvas as Draw, where the pixels of the returned image use the given alpha convention. It returns an *image.RGBA for Premultiplied, and an *image.NRGBA for Straight, which is converted from the premultiplied pixels after compositing, see StraightAlpha. Both have the same memory layout of 8-bit R, G, B and A channels so that their Pix field can be uploaded directly as a texture.
+func DrawWithAlpha(c *canvas.Canvas, resolution canvas.DPMM, mode AlphaMode) draw.Image {
+	img := Draw(c, resolution)
+	if mode == Straight {
+		return StraightAlpha(img)
+	}
+	return img
+}
+
+// StraightAlpha converts an image with premultiplied alpha to straight alpha by dividing the colors by alpha, with rounding to the nearest value. Fully transparent pixels have no defined color and are set to transparent black.
+func StraightAlpha(img *image.RGBA) *image.NRGBA {
+	dst := image.NewNRGBA(img.Rect)
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		src := img.Pix[img.PixOffset(img.Rect.Min.X, y):]
+		pix := dst.Pix[dst.PixOffset(dst.Rect.Min.X, y):]
+		for i := 0; i < 4*img.Rect.Dx(); i += 4 {
+			a := uint32(src[i+3])
+			if a == 0 {
+				pix[i], pix[i+1], pix[i+2], pix[i+3] = 0, 0, 0, 0
+				continue
+			}
+			for k := 0; k < 3; k++ {
+				v := (uint32(src[i+k])*255 + a/2) / a
+				if 255 < v {
+					v = 255 // invalid premultiplied colors larger than alpha
+				}
+				pix[i+k] = uint8(v)
+			}
+			pix[i+3] = uint8(a)
+		}
+	}
+	return dst
+}
+
+func imageBounds(c *canvas.Canvas, resolution canvas.DPMM) image.Rectangle {
+	return image.Rect(0, 0, int(c.W*float64(resolution)+0.5), int(c.H*float64(resolution)+0.5))
+}
+
+// Image returns an image of the canvas with given resolution (in dots-per-millimeter), that is rasterized on the first access of its pixels. This allows passing a canvas to functions accepting an image.Image, such as png.Encode or draw.Draw. Its bounds are the size of the canvas times the resolution. The canvas must not change until the image has been rasterized.
+func Image(c *canvas.Canvas, resolution canvas.DPMM) image.Image {
+	return &lazyImage{c: c, resolution: resolution}
+}
+
+type lazyImage struct {
+	c          *canvas.Canvas
+	resolution canvas.DPMM
+
+	once sync.Once
+	img  *image.RGBA
+}
+
+func (img *lazyImage) rasterize() *image.RGBA {
+	img.once.Do(func() {
+		img.img = Draw(img.c, img.resolution)
+	})
+	return img.img
+}
+
+func (img *lazyImage) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (img *lazyImage) Bounds() image.Rectangle {
+	return imageBounds(img.c, img.resolution)
+}
+
+func (img *lazyImage) At(x, y int) color.Color {
+	return img.rasterize().At(x, y)
+}
+
+// Antialias is the anti-aliasing of edges when rasterizing, see Renderer.SetShapeAntialias and Renderer.SetTextAntialias.
+type Antialias int
+
+// see Antialias
+const (
+	DefaultAntialias Antialias = iota // antialiased, except for text when the gasp table of its font disables antialiasing
+	Antialiased                       // edges are smooth with partial coverage
+	Aliased                           // edges are hard, pixels are covered when at least half of their area is
+)
+
 type Renderer struct {
 	img        draw.Image
 	resolution canvas.DPMM
+	groups     []rasterGroup
+	flatten    canvas.FlattenMethod
+
+	shapeAntialias, textAntialias Antialias
+	roundBaselines                bool
+}
+
+// rasterGroup is a transparency group that is drawn to an offscreen image before being composited onto the parent image.
+type rasterGroup struct {
+	parent             draw.Image
+	img                *image.RGBA
+	backdrop           *image.RGBA  // initial backdrop for knockout groups, nil if transparent
+	element            *image.RGBA  // element of knockout groups, reused for each element
+	shape              *image.Alpha // coverage of the element of knockout groups, reused for each element
+	clip               *image.Alpha // coverage of the clipping path, nil for transparency groups
+	isolated, knockout bool
 }
 
 // New creates a renderer that draws to a rasterized image.
@@ -31,13 +157,231 @@ func New(img draw.Image, resolution canvas.DPMM) *Renderer {
 	}
 }
 
+// SetFlattenMethod sets the method used to flatten curves before rasterization, such as canvas.UniformSteps. By default curves are passed to the rasterizer, which flattens them itself.
+func (r *Renderer) SetFlattenMethod(method canvas.FlattenMethod) {
+	r.flatten = method
+}
+
+// SetShapeAntialias sets the anti-aliasing of paths, including their strokes, such as to draw crisp pixel-art shapes with Aliased. Shadows and images are always antialiased. By default paths are antialiased.
+func (r *Renderer) SetShapeAntialias(antialias Antialias) {
+	r.shapeAntialias = antialias
+}
+
+// SetTextAntialias sets the anti-aliasing of text independently of paths, see SetShapeAntialias. By default text is antialiased unless the gasp tables of the fonts of all its spans disable antialiasing at their size in pixels per em, see font.SFNT.Gasp.
+func (r *Renderer) SetTextAntialias(antialias Antialias) {
+	r.textAntialias = antialias
+}
+
+// SetTextBaselineRounding sets whether the baselines of text lines are rounded to whole pixel rows, which sharpens small multi-line text as antialiasing of adjacent lines no longer overlaps, without hinting the glyphs. Rounding does not accumulate over lines, so that the line spacing stays the same on average, see canvas.Text.RoundBaselines. By default baselines are not rounded.
+func (r *Renderer) SetTextBaselineRounding(round bool) {
+	r.roundBaselines = round
+}
+
 // Size returns the width and height in millimeters
 func (r *Renderer) Size() (float64, float64) {
 	size := r.img.Bounds().Size()
 	return float64(size.X) / float64(r.resolution), float64(size.Y) / float64(r.resolution)
 }
 
+// BeginGroup starts a transparency group that is drawn to an offscreen image, see canvas.Context.BeginGroup.
+func (r *Renderer) BeginGroup(isolated, knockout bool) {
+	group := rasterGroup{
+		parent:   r.img,
+		img:      image.NewRGBA(r.img.Bounds()),
+		isolated: isolated,
+		knockout: knockout,
+	}
+	if knockout {
+		group.element = image.NewRGBA(r.img.Bounds())
+		group.shape = image.NewAlpha(r.img.Bounds())
+	}
+	if !isolated {
+		draw.Draw(group.img, group.img.Bounds(), r.img, r.img.Bounds().Min, draw.Src)
+		if knockout {
+			group.backdrop = image.NewRGBA(r.img.Bounds())
+			draw.Draw(group.backdrop, group.backdrop.Bounds(), r.img, r.img.Bounds().Min, draw.Src)
+		}
+	}
+	r.groups = append(r.groups, group)
+	r.img = group.img
+}
+
+// EndGroup composites the transparency group onto the image it was started on.
+func (r *Renderer) EndGroup() {
+	if len(r.groups) == 0 || r.groups[len(r.groups)-1].clip != nil {
+		panic("must be in group")
+	}
+	group := r.groups[len(r.groups)-1]
+	r.groups = r.groups[:len(r.groups)-1]
+	r.img = group.parent
+	if group.isolated {
+		draw.Draw(r.img, r.img.Bounds(), group.img, group.img.Bounds().Min, draw.Over)
+	} else {
+		// the group was drawn on top of a copy of the backdrop
+		draw.Draw(r.img, r.img.Bounds(), group.img, group.img.Bounds().Min, draw.Src)
+	}
+}
+
+// PushClip starts drawing to an offscreen image that is composited onto the current image through the coverage of the path upon PopClip, see canvas.Context.ClipPath.
+func (r *Renderer) PushClip(path *canvas.Path, m canvas.Matrix) {
+	bounds := r.img.Bounds()
+	clip := image.NewAlpha(bounds)
+	path = path.Transform(m)
+	if r.flatten != nil {
+		path = path.FlattenWith(r.flatten)
+	}
+	ras := vector.NewRasterizer(bounds.Dx(), bounds.Dy())
+	path.ToRasterizer(ras, float64(r.resolution))
+	ras.Draw(clip, bounds, image.Opaque, image.Point{})
+
+	group := rasterGroup{
+		parent:   r.img,
+		img:      image.NewRGBA(bounds),
+		clip:     clip,
+		isolated: true,
+	}
+	r.groups = append(r.groups, group)
+	r.img = group.img
+}
+
+// PopClip composites the offscreen image of the matching PushClip onto the image it was started on, through the coverage of the clipping path.
+func (r *Renderer) PopClip() {
+	if len(r.groups) == 0 || r.groups[len(r.groups)-1].clip == nil {
+		panic("must be in clip")
+	}
+	group := r.groups[len(r.groups)-1]
+	r.groups = r.groups[:len(r.groups)-1]
+	r.img = group.parent
+	bounds := r.img.Bounds()
+	draw.DrawMask(r.img, bounds, group.img, bounds.Min, group.clip, bounds.Min, draw.Over)
+}
+
+// knockout draws an element of a knockout group, where shape draws the coverage of the element. The element is composited onto the initial backdrop of the group and replaces the group's image in proportion to its coverage.
+func (r *Renderer) knockout(element, shape func(*Renderer)) {
+	group := r.groups[len(r.groups)-1]
+	bounds := group.img.Bounds()
+	img, mask := group.element, group.shape
+	for i := range img.Pix {
+		img.Pix[i] = 0
+	}
+	for i := range mask.Pix {
+		mask.Pix[i] = 0
+	}
+	element(&Renderer{img: img, resolution: r.resolution, flatten: r.flatten, shapeAntialias: r.shapeAntialias, textAntialias: r.textAntialias, roundBaselines: r.roundBaselines})
+	shape(&Renderer{img: mask, resolution: r.resolution, flatten: r.flatten, shapeAntialias: r.shapeAntialias, textAntialias: r.textAntialias, roundBaselines: r.roundBaselines})
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			a := uint32(mask.AlphaAt(x, y).A)
+			if a == 0 {
+				continue
+			}
+			src := img.RGBAAt(x, y)
+			if group.backdrop != nil {
+				src = over(src, group.backdrop.RGBAAt(x, y))
+			}
+			dst := group.img.RGBAAt(x, y)
+			group.img.SetRGBA(x, y, color.RGBA{
+				uint8((uint32(src.R)*a + uint32(dst.R)*(255-a)) / 255),
+				uint8((uint32(src.G)*a + uint32(dst.G)*(255-a)) / 255),
+				uint8((uint32(src.B)*a + uint32(dst.B)*(255-a)) / 255),
+				uint8((uint32(src.A)*a + uint32(dst.A)*(255-a)) / 255),
+			})
+		}
+	}
+}
+
+// over composites premultiplied colors src over dst.
+func over(src, dst color.RGBA) color.RGBA {
+	a := 255 - uint32(src.A)
+	return color.RGBA{
+		uint8(uint32(src.R) + uint32(dst.R)*a/255),
+		uint8(uint32(src.G) + uint32(dst.G)*a/255),
+		uint8(uint32(src.B) + uint32(dst.B)*a/255),
+		uint8(uint32(src.A) + uint32(dst.A)*a/255),
+	}
+}
+
+func (r *Renderer) inKnockout() bool {
+	return 0 < len(r.groups) && r.groups[len(r.groups)-1].knockout
+}
+
+// composite draws an element with the given Porter-Duff operator, where shape draws the coverage of the element. Outside of the coverage the image is unchanged, and within the coverage the result of the operator is blended with the image in proportion to the coverage.
+func (r *Renderer) composite(op canvas.CompositeOp, element, shape func(*Renderer)) {
+	bounds := r.img.Bounds()
+	img := image.NewRGBA(bounds)
+	element(&Renderer{img: img, resolution: r.resolution, flatten: r.flatten, shapeAntialias: r.shapeAntialias, textAntialias: r.textAntialias, roundBaselines: r.roundBaselines})
+	mask := image.NewAlpha(bounds)
+	shape(&Renderer{img: mask, resolution: r.resolution, flatten: r.flatten, shapeAntialias: r.shapeAntialias, textAntialias: r.textAntialias, roundBaselines: r.roundBaselines})
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := uint32(mask.AlphaAt(x, y).A)
+			if c == 0 {
+				continue
+			}
+
+			// the element has the coverage multiplied in, so that the operators reduce to factors for the element and the image
+			src := img.RGBAAt(x, y)
+			dst := color.RGBAModel.Convert(r.img.At(x, y)).(color.RGBA)
+			fsrc, fdst := uint32(0), 255-uint32(src.A)
+			switch op {
+			case canvas.SourceOver:
+				fsrc = 255
+			case canvas.Source:
+				fsrc, fdst = 255, 255-c
+			case canvas.DestinationIn:
+				fdst = 255 - c + uint32(src.A)
+			case canvas.SourceAtop:
+				fsrc = uint32(dst.A)
+			case canvas.Xor:
+				fsrc = 255 - uint32(dst.A)
+			case canvas.Clear:
+				fdst = 255 - c
+			}
+			r.img.Set(x, y, color.RGBA{
+				uint8((uint32(src.R)*fsrc + uint32(dst.R)*fdst) / 255),
+				uint8((uint32(src.G)*fsrc + uint32(dst.G)*fdst) / 255),
+				uint8((uint32(src.B)*fsrc + uint32(dst.B)*fdst) / 255),
+				uint8((uint32(src.A)*fsrc + uint32(dst.A)*fdst) / 255),
+			})
+		}
+	}
+}
+
+// shapeStyle returns the style that draws the coverage of the path in black.
+func shapeStyle(style canvas.Style) canvas.Style {
+	if style.FillPaint != nil || style.FillColor.A != 0 {
+		style.FillPaint = nil
+		style.FillColor = canvas.Black
+	}
+	if style.StrokeColor.A != 0 {
+		style.StrokePaint = nil
+		style.StrokeColor = canvas.Black
+	}
+	style.CompositeOp = canvas.SourceOver
+	return style
+}
+
 func (r *Renderer) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
+	if style.CompositeOp != canvas.SourceOver {
+		elementStyle := style
+		elementStyle.CompositeOp = canvas.SourceOver
+		r.composite(style.CompositeOp, func(ras *Renderer) {
+			ras.RenderPath(path, elementStyle, m)
+		}, func(ras *Renderer) {
+			ras.RenderPath(path, shapeStyle(style), m)
+		})
+		return
+	} else if r.inKnockout() {
+		r.knockout(func(ras *Renderer) {
+			ras.RenderPath(path, style, m)
+		}, func(ras *Renderer) {
+			ras.RenderPath(path, shapeStyle(style), m)
+		})
+		return
+	}
+
 	// TODO: use fill rule (EvenOdd, NonZero) for rasterizer
 	path = path.Transform(m)
 
@@ -77,28 +421,213 @@ func (r *Renderer) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Ma
 	}
 
 	path = path.Translate(-float64(x)/resolution, -float64(y)/resolution)
-	if style.FillColor.A != 0 {
+	fill := path
+	if r.flatten != nil {
+		fill = path.FlattenWith(r.flatten)
+	}
+	if style.FillPaint != nil {
 		ras := vector.NewRasterizer(w, h)
-		path.ToRasterizer(ras, resolution)
-		ras.Draw(r.img, image.Rect(x, size.Y-y, x+w, size.Y-y-h), image.NewUniform(style.FillColor), image.Point{dx, dy})
+		fill.ToRasterizer(ras, resolution)
+		rect := image.Rect(x, size.Y-y, x+w, size.Y-y-h)
+		r.draw(ras, rect, paintImage{style.FillPaint, m.Inv(), r.img.Bounds(), resolution}, rect.Min)
+	} else if style.FillColor.A != 0 {
+		ras := vector.NewRasterizer(w, h)
+		fill.ToRasterizer(ras, resolution)
+		r.draw(ras, image.Rect(x, size.Y-y, x+w, size.Y-y-h), image.NewUniform(style.FillColor), image.Point{dx, dy})
 	}
 	if style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth {
 		if 0 < len(style.Dashes) {
 			path = path.Dash(style.DashOffset, style.Dashes...)
 		}
 		path = path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
+		if r.flatten != nil {
+			path = path.FlattenWith(r.flatten)
+		}
 
 		ras := vector.NewRasterizer(w, h)
 		path.ToRasterizer(ras, resolution)
-		ras.Draw(r.img, image.Rect(x, size.Y-y, x+w, size.Y-y-h), image.NewUniform(style.StrokeColor), image.Point{dx, dy})
+		if style.StrokePaint != nil {
+			rect := image.Rect(x, size.Y-y, x+w, size.Y-y-h)
+			r.draw(ras, rect, paintImage{style.StrokePaint, m.Inv(), r.img.Bounds(), resolution}, rect.Min)
+		} else {
+			r.draw(ras, image.Rect(x, size.Y-y, x+w, size.Y-y-h), image.NewUniform(style.StrokeColor), image.Point{dx, dy})
+		}
 	}
 }
 
+// draw composites src through the coverage of the rasterizer onto rect of the image, where the coverage is thresholded when shapes are aliased.
+func (r *Renderer) draw(ras *vector.Rasterizer, rect image.Rectangle, src image.Image, sp image.Point) {
+	if r.shapeAntialias != Aliased {
+		ras.Draw(r.img, rect, src, sp)
+		return
+	}
+	rect = rect.Canon()
+	mask := image.NewAlpha(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	ras.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+	for i, a := range mask.Pix {
+		if a < 128 {
+			mask.Pix[i] = 0
+		} else {
+			mask.Pix[i] = 255
+		}
+	}
+	draw.DrawMask(r.img, rect, src, sp, mask, image.Point{}, draw.Over)
+}
+
+// RenderShadow draws a soft shadow of the path, see canvas.Context.DrawShadow. The coverage of the path is rasterized to an offscreen mask that extends three times the blur beyond the path, which is blurred and composited in the given color.
+func (r *Renderer) RenderShadow(path *canvas.Path, blur float64, col color.RGBA, m canvas.Matrix) {
+	if r.inKnockout() {
+		r.knockout(func(ras *Renderer) {
+			ras.RenderShadow(path, blur, col, m)
+		}, func(ras *Renderer) {
+			ras.RenderShadow(path, blur, canvas.Black, m)
+		})
+		return
+	}
+
+	path = path.Transform(m)
+	if r.flatten != nil {
+		path = path.FlattenWith(r.flatten)
+	}
+
+	// only the part of the mask that can reach the image is rasterized
+	resolution := float64(r.resolution)
+	sigma := blur * resolution
+	margin := math.Ceil(3.0 * sigma)
+	size := r.img.Bounds().Size()
+	bounds := path.Bounds()
+	x0 := math.Max(math.Floor(bounds.X*resolution)-margin, -margin)
+	y0 := math.Max(math.Floor(bounds.Y*resolution)-margin, -margin)
+	x1 := math.Min(math.Ceil((bounds.X+bounds.W)*resolution)+margin, float64(size.X)+margin)
+	y1 := math.Min(math.Ceil((bounds.Y+bounds.H)*resolution)+margin, float64(size.Y)+margin)
+	if x1 <= x0 || y1 <= y0 {
+		return // outside canvas
+	}
+
+	w, h := int(x1-x0), int(y1-y0)
+	mask := image.NewAlpha(image.Rect(0, 0, w, h))
+	ras := vector.NewRasterizer(w, h)
+	path.Translate(-x0/resolution, -y0/resolution).ToRasterizer(ras, resolution)
+	ras.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+	gaussian.Blur(mask, sigma)
+
+	rect := image.Rect(int(x0), size.Y-int(y1), int(x1), size.Y-int(y0))
+	draw.DrawMask(r.img, rect, image.NewUniform(col), image.Point{}, mask, image.Point{}, draw.Over)
+}
+
+// RenderInsetShadow draws a soft shadow inside of the path, see canvas.Context.DrawInsetShadow. The inverse of the coverage of the offset path is rasterized to an offscreen mask that extends three times the blur beyond the path, which is blurred, clipped to the coverage of the path and composited in the given color.
+func (r *Renderer) RenderInsetShadow(path *canvas.Path, dx, dy, blur float64, col color.RGBA, m canvas.Matrix) {
+	if r.inKnockout() {
+		r.knockout(func(ras *Renderer) {
+			ras.RenderInsetShadow(path, dx, dy, blur, col, m)
+		}, func(ras *Renderer) {
+			ras.RenderInsetShadow(path, dx, dy, blur, canvas.Black, m)
+		})
+		return
+	}
+
+	clip := path.Transform(m)
+	shadow := path.Transform(m.Translate(dx, dy))
+	if r.flatten != nil {
+		clip = clip.FlattenWith(r.flatten)
+		shadow = shadow.FlattenWith(r.flatten)
+	}
+
+	// only the part of the path that is inside the image is drawn
+	resolution := float64(r.resolution)
+	sigma := blur * resolution
+	margin := math.Ceil(3.0 * sigma)
+	size := r.img.Bounds().Size()
+	bounds := clip.Bounds()
+	x0 := math.Max(math.Floor(bounds.X*resolution), 0.0)
+	y0 := math.Max(math.Floor(bounds.Y*resolution), 0.0)
+	x1 := math.Min(math.Ceil((bounds.X+bounds.W)*resolution), float64(size.X))
+	y1 := math.Min(math.Ceil((bounds.Y+bounds.H)*resolution), float64(size.Y))
+	if x1 <= x0 || y1 <= y0 {
+		return // outside canvas
+	}
+
+	// the shadow is the inverse of the offset silhouette, including the margin around the path
+	w, h := int(x1-x0), int(y1-y0)
+	mw, mh := w+2*int(margin), h+2*int(margin)
+	mask := image.NewAlpha(image.Rect(0, 0, mw, mh))
+	ras := vector.NewRasterizer(mw, mh)
+	shadow.Translate(-(x0-margin)/resolution, -(y0-margin)/resolution).ToRasterizer(ras, resolution)
+	ras.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+	for i, a := range mask.Pix {
+		mask.Pix[i] = 255 - a
+	}
+	gaussian.Blur(mask, sigma)
+
+	coverage := image.NewAlpha(image.Rect(0, 0, w, h))
+	ras = vector.NewRasterizer(w, h)
+	clip.Translate(-x0/resolution, -y0/resolution).ToRasterizer(ras, resolution)
+	ras.Draw(coverage, coverage.Bounds(), image.Opaque, image.Point{})
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			a := uint32(mask.Pix[(y+int(margin))*mask.Stride+x+int(margin)])
+			coverage.Pix[y*coverage.Stride+x] = uint8((a*uint32(coverage.Pix[y*coverage.Stride+x]) + 127) / 255)
+		}
+	}
+
+	rect := image.Rect(int(x0), size.Y-int(y1), int(x1), size.Y-int(y0))
+	draw.DrawMask(r.img, rect, image.NewUniform(col), image.Point{}, coverage, image.Point{}, draw.Over)
+}
+
+func imax(a, b int) int {
+	if a < b {
+		return b
+	}
+	return a
+}
+
+// RenderText renders the glyphs of the text as paths, with the anti-aliasing of SetTextAntialias and the baselines rounded to whole pixels with SetTextBaselineRounding. By default the text is rendered without antialiasing when the gasp tables of the fonts of all its spans disable antialiasing at their size in pixels per em, see font.SFNT.Gasp. Grid-fitting is not supported, as glyphs are not hinted.
 func (r *Renderer) RenderText(text *canvas.Text, m canvas.Matrix) {
+	antialias := r.textAntialias
+	if antialias == DefaultAntialias {
+		antialias = Antialiased
+		if !r.smoothText(text, m) {
+			antialias = Aliased
+		}
+	}
+
+	if r.roundBaselines {
+		text = text.RoundBaselines(m, r.resolution)
+	}
+
+	// text is drawn as paths with the anti-aliasing of text instead of shapes
+	shapeAntialias := r.shapeAntialias
+	r.shapeAntialias = antialias
 	canvas.RenderTextAsPath(r, text, m)
+	r.shapeAntialias = shapeAntialias
+}
+
+// smoothText returns false if the gasp tables of the fonts of all spans disable antialiasing at their size in pixels per em.
+func (r *Renderer) smoothText(text *canvas.Text, m canvas.Matrix) bool {
+	_, _, _, _, yscale, _ := m.Decompose()
+	smooth, spans := false, 0
+	text.WalkSpans(func(_, _ float64, span canvas.TextSpan) {
+		spans++
+		ppem := span.Face.Size * span.Face.Scale * float64(r.resolution) * math.Abs(yscale)
+		if span.Face.Font.SFNT().Gasp(uint16(math.Min(ppem+0.5, math.MaxUint16)))&font.GaspDoGray != 0 {
+			smooth = true
+		}
+	})
+	return smooth || spans == 0
 }
 
 func (r *Renderer) RenderImage(img image.Image, m canvas.Matrix) {
+	if r.inKnockout() {
+		size := img.Bounds().Size()
+		rect := canvas.Rectangle(float64(size.X), float64(size.Y))
+		r.knockout(func(ras *Renderer) {
+			ras.RenderImage(img, m)
+		}, func(ras *Renderer) {
+			ras.RenderPath(rect, canvas.DefaultStyle, m)
+		})
+		return
+	}
+
 	// add transparent margin to image for smooth borders when rotating
 	margin := 4
 	size := img.Bounds().Size()
@@ -116,3 +645,28 @@ func (r *Renderer) RenderImage(img image.Image, m canvas.Matrix) {
 	aff3 := f64.Aff3{m[0][0], -m[0][1], origin.X, -m[1][0], m[1][1], h - origin.Y}
 	draw.CatmullRom.Transform(r.img, aff3, img2, img2.Bounds(), draw.Over, nil)
 }
+
+// paintImage is an image in the pixel coordinates of the destination image that samples a paint at the pixel centers.
+type paintImage struct {
+	paint      canvas.Paint
+	inv        canvas.Matrix // from canvas coordinates to path coordinates
+	bounds     image.Rectangle
+	resolution float64
+}
+
+func (img paintImage) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+func (img paintImage) Bounds() image.Rectangle {
+	return img.bounds
+}
+
+func (img paintImage) At(x, y int) color.Color {
+	pos := canvas.Point{
+		X: (float64(x-img.bounds.Min.X) + 0.5) / img.resolution,
+		Y: (float64(img.bounds.Max.Y-y) - 0.5) / img.resolution,
+	}
+	pos = img.inv.Dot(pos)
+	return img.paint.At(pos.X, pos.Y)
+}
@@ -0,0 +1,552 @@
+package rasterizer
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"math"
+	"testing"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/canvas/font"
+	"github.com/tdewolff/test"
+)
+
+func checkerboard() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	img.Set(0, 0, canvas.Black)
+	img.Set(1, 0, canvas.White)
+	img.Set(0, 1, canvas.White)
+	img.Set(1, 1, canvas.Black)
+	return img
+}
+
+func TestRendererImagePaint(t *testing.T) {
+	var tts = []struct {
+		extend canvas.ExtendMode
+		m      canvas.Matrix
+		col    color.RGBA // at (2.5,0.5) relative to the center
+	}{
+		{canvas.ExtendRepeat, canvas.Identity.Scale(2.0, 2.0), canvas.Black},
+		{canvas.ExtendReflect, canvas.Identity.Scale(2.0, 2.0), canvas.Black},
+		{canvas.ExtendNone, canvas.Identity.Translate(-4.0, -4.0).Scale(4.0, 4.0), canvas.White},
+		{canvas.ExtendClamp, canvas.Identity.Translate(-4.0, -4.0).Scale(4.0, 4.0), canvas.White},
+	}
+	for _, tt := range tts {
+		paint := canvas.ImagePaint{Image: checkerboard(), Transform: tt.m, Extend: tt.extend}
+
+		c := canvas.New(20.0, 20.0)
+		ctx := canvas.NewContext(c)
+		ctx.SetFillPaint(paint)
+		ctx.DrawPath(10.0, 10.0, canvas.Circle(8.0))
+		img := Draw(c, 1.0)
+
+		test.T(t, img.RGBAAt(12, 9), tt.col)
+
+		colors := map[color.RGBA]bool{}
+		for y := 0; y < 20; y++ {
+			for x := 0; x < 20; x++ {
+				px, py := float64(x)+0.5, 20.0-float64(y)-0.5
+				d := math.Hypot(px-10.0, py-10.0)
+				if d < 7.0 {
+					test.T(t, img.RGBAAt(x, y), paint.At(px-10.0, py-10.0), "pixel", x, y)
+					colors[img.RGBAAt(x, y)] = true
+				} else if 9.0 < d {
+					test.T(t, img.RGBAAt(x, y), canvas.Transparent, "pixel", x, y)
+				}
+			}
+		}
+		test.That(t, colors[canvas.Black] && colors[canvas.White], "both colors of the checkerboard must be drawn")
+		if tt.extend == canvas.ExtendNone {
+			test.That(t, colors[canvas.Transparent], "the image must not extend beyond its bounds")
+		} else {
+			test.That(t, !colors[canvas.Transparent], "the image must extend beyond its bounds")
+		}
+	}
+}
+
+func TestRendererStrokePaint(t *testing.T) {
+	gradient := canvas.LinearGradient{
+		Start: canvas.Point{X: 0.0, Y: 0.0},
+		End:   canvas.Point{X: 20.0, Y: 0.0},
+		Stops: []canvas.GradientStop{{Offset: 0.0, Color: canvas.Red}, {Offset: 1.0, Color: canvas.Blue}},
+	}
+
+	c := canvas.New(20.0, 10.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetStrokeWidth(4.0)
+	ctx.StrokePath(canvas.MustParseSVG("M0 5L20 5"), gradient)
+	img := Draw(c, 1.0)
+
+	for x := 0; x < 20; x++ {
+		test.T(t, img.RGBAAt(x, 5), gradient.At(float64(x)+0.5, 5.0), "pixel", x)
+	}
+	test.T(t, img.RGBAAt(10, 0), canvas.Transparent)
+	test.T(t, img.RGBAAt(0, 4), color.RGBA{249, 0, 6, 255})
+	test.T(t, img.RGBAAt(19, 4), color.RGBA{6, 0, 249, 255})
+}
+
+func TestRendererFillOpenPaths(t *testing.T) {
+	path := canvas.MustParseSVG("M1 1L9 1L5 9M12 2L18 2L18 8L12 8z")
+	var tts = []struct {
+		policy       canvas.FillOpenPaths
+		open, closed bool
+	}{
+		{canvas.CloseOpenPaths, true, true},
+		{canvas.SkipOpenPaths, false, true},
+		{canvas.ErrorOpenPaths, false, false},
+	}
+	for _, tt := range tts {
+		c := canvas.New(20.0, 10.0)
+		ctx := canvas.NewContext(c)
+		ctx.SetFillOpenPaths(tt.policy)
+		ctx.DrawPath(0.0, 0.0, path)
+		img := Draw(c, 1.0)
+		test.T(t, img.RGBAAt(5, 5) == canvas.Black, tt.open, "open subpath", tt.policy)
+		test.T(t, img.RGBAAt(15, 5) == canvas.Black, tt.closed, "closed subpath", tt.policy)
+	}
+}
+
+func TestImage(t *testing.T) {
+	c := canvas.New(6.0, 4.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.Red)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(3.0, 4.0))
+
+	img := Image(c, 2.0)
+	test.T(t, img.Bounds(), image.Rect(0, 0, 12, 8))
+	test.That(t, img.(*lazyImage).img == nil, "must not rasterize before accessing pixels")
+
+	buf := &bytes.Buffer{}
+	test.Error(t, png.Encode(buf, img))
+	decoded, err := png.Decode(buf)
+	test.Error(t, err)
+	test.T(t, decoded.Bounds(), image.Rect(0, 0, 12, 8))
+	test.T(t, color.RGBAModel.Convert(decoded.At(2, 4)), canvas.Red)
+	test.T(t, color.RGBAModel.Convert(decoded.At(10, 4)), canvas.Transparent)
+}
+
+func TestDrawMask(t *testing.T) {
+	c := canvas.New(10.0, 10.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(color.RGBA{128, 0, 0, 128})
+	ctx.DrawPath(1.0, 1.0, canvas.Circle(3.0).Translate(3.0, 3.0))
+	ctx.SetFillColor(canvas.Blue)
+	ctx.SetStrokeColor(color.RGBA{0, 64, 0, 64})
+	ctx.SetStrokeWidth(0.5)
+	ctx.DrawPath(4.0, 4.0, canvas.Rectangle(5.0, 5.0))
+
+	img := Draw(c, 5.0)
+	mask := DrawMask(c, 5.0)
+	test.T(t, mask.Bounds(), img.Bounds())
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			a := int(img.RGBAAt(x, y).A) - int(mask.AlphaAt(x, y).A)
+			test.That(t, -1 <= a && a <= 1, "mask must equal alpha at", x, y)
+		}
+	}
+	test.T(t, mask.AlphaAt(25, 25).A, uint8(255)) // overlap does not exceed full coverage
+	test.T(t, mask.AlphaAt(10, 40).A, uint8(128))
+	test.T(t, mask.AlphaAt(1, 1).A, uint8(0))
+}
+
+func TestRendererGroup(t *testing.T) {
+	draw := func(isolated, knockout, group bool) *image.RGBA {
+		c := canvas.New(3.0, 1.0)
+		ctx := canvas.NewContext(c)
+		ctx.SetFillColor(canvas.White)
+		ctx.DrawPath(0.0, 0.0, canvas.Rectangle(3.0, 1.0))
+		if group {
+			ctx.BeginGroup(isolated, knockout)
+		}
+		ctx.SetFillColor(color.RGBA{0, 0, 128, 128}) // semi-transparent blue
+		ctx.DrawPath(0.0, 0.0, canvas.Rectangle(2.0, 1.0))
+		ctx.DrawPath(1.0, 0.0, canvas.Rectangle(2.0, 1.0))
+		if group {
+			ctx.EndGroup()
+		}
+		return Draw(c, 1.0)
+	}
+
+	// outside of the overlap all groups are the same
+	ungrouped := draw(false, false, false)
+	test.T(t, ungrouped.RGBAAt(0, 0), color.RGBA{127, 127, 255, 255})
+	test.T(t, ungrouped.RGBAAt(1, 0), color.RGBA{63, 63, 255, 255}) // darker at the overlap
+	for _, tt := range []struct {
+		isolated, knockout bool
+	}{{false, false}, {true, false}, {false, true}, {true, true}} {
+		img := draw(tt.isolated, tt.knockout, true)
+		test.T(t, img.RGBAAt(0, 0), ungrouped.RGBAAt(0, 0), tt.isolated, tt.knockout)
+		test.T(t, img.RGBAAt(2, 0), ungrouped.RGBAAt(2, 0), tt.isolated, tt.knockout)
+		if tt.knockout {
+			// the second rectangle replaces the first within the group
+			test.T(t, img.RGBAAt(1, 0), ungrouped.RGBAAt(0, 0), tt.isolated, tt.knockout)
+		} else {
+			test.T(t, img.RGBAAt(1, 0), ungrouped.RGBAAt(1, 0), tt.isolated, tt.knockout)
+		}
+	}
+}
+
+func TestRendererBake(t *testing.T) {
+	dejaVuSerif := canvas.NewFontFamily("dejavu-serif")
+	if err := dejaVuSerif.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular); err != nil {
+		t.Fatal(err)
+	}
+	face := dejaVuSerif.Face(20.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+
+	c := canvas.New(20.0, 20.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetFillColor(canvas.White)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(20.0, 20.0))
+	ctx.RotateAbout(20.0, 10.0, 10.0)
+	ctx.Scale(1.5, 1.0)
+	ctx.SetFillPaint(canvas.LinearGradient{Start: canvas.Point{X: 0.0, Y: 0.0}, End: canvas.Point{X: 8.0, Y: 0.0}, Stops: []canvas.GradientStop{{Offset: 0.0, Color: canvas.Red}, {Offset: 1.0, Color: canvas.Blue}}, Extend: canvas.ExtendClamp})
+	ctx.SetStrokeColor(canvas.Green)
+	ctx.SetStrokeWidth(0.5)
+	ctx.SetDashes(0.0, 2.0, 1.0)
+	ctx.DrawPath(2.0, 2.0, canvas.Circle(3.0))
+	ctx.SetFillColor(canvas.Black)
+	ctx.SetStrokeColor(canvas.Transparent)
+	ctx.DrawText(2.0, 10.0, canvas.NewTextLine(face, "ab", canvas.Left))
+
+	// the baked canvas rasterizes the same as the original
+	img := Draw(c, 4.0)
+	for _, opts := range []canvas.BakeOptions{{}, {OutlineStrokes: true}} {
+		baked := Draw(c.Bake(opts), 4.0)
+		test.T(t, baked.Bounds(), img.Bounds())
+		diff := 0
+		for i := range img.Pix {
+			if d := int(img.Pix[i]) - int(baked.Pix[i]); d < -1 || 1 < d {
+				diff++
+			}
+		}
+		test.T(t, diff, 0, opts)
+	}
+}
+
+func TestRendererShadow(t *testing.T) {
+	c := canvas.New(20.0, 20.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawShadow(canvas.Rectangle(6.0, 6.0).Translate(6.0, 6.0), [2]float64{4.0, -4.0}, 1.0, canvas.Black)
+	ctx.SetFillColor(canvas.White)
+	ctx.DrawPath(6.0, 6.0, canvas.Rectangle(6.0, 6.0))
+	img := Draw(c, 1.0)
+
+	at := func(x, y int) color.RGBA {
+		return img.RGBAAt(x, 19-y) // rows run downwards
+	}
+	test.T(t, at(8, 8), canvas.White)                   // shape on top of the shadow
+	test.That(t, 224 < at(14, 4).A)                     // shadow offset behind the shape
+	test.That(t, 64 < at(16, 4).A && at(16, 4).A < 128) // blurred along the edge of the silhouette
+	test.That(t, 0 < at(17, 4).A && at(17, 4).A < 32)
+	test.T(t, at(4, 15).A, uint8(0)) // away from the shadow
+
+	// large blurs are not clipped to the bounds of the path
+	c = canvas.New(20.0, 20.0)
+	ctx = canvas.NewContext(c)
+	ctx.DrawShadow(canvas.Rectangle(2.0, 2.0).Translate(9.0, 9.0), [2]float64{0.0, 0.0}, 3.0, canvas.Black)
+	img = Draw(c, 1.0)
+	test.That(t, 0 < at(3, 10).A)
+	test.That(t, at(3, 10).A < at(6, 10).A)
+	test.That(t, at(6, 10).A < at(10, 10).A)
+	test.T(t, at(10, 10), at(9, 9)) // symmetric
+}
+
+func TestRendererInsetShadow(t *testing.T) {
+	c := canvas.New(30.0, 30.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawInsetShadow(canvas.Rectangle(20.0, 20.0).Translate(5.0, 5.0), 0.0, 0.0, 1.0, canvas.Black)
+	img := Draw(c, 1.0)
+
+	at := func(x, y int) color.RGBA {
+		return img.RGBAAt(x, 29-y) // rows run downwards
+	}
+	test.That(t, 64 < at(5, 15).A, "inside of the edge must be dark")
+	test.That(t, at(6, 15).A < at(5, 15).A && at(7, 15).A < at(6, 15).A, "shadow must fade out inwards")
+	test.T(t, at(15, 15).A, uint8(0)) // center of the path
+	test.T(t, at(4, 15).A, uint8(0))  // no shadow outside of the path
+	test.T(t, at(15, 4).A, uint8(0))
+	test.That(t, at(5, 5).A > at(5, 15).A, "corners are darker")
+
+	// the offset darkens the top and left edges
+	c = canvas.New(30.0, 30.0)
+	ctx = canvas.NewContext(c)
+	ctx.DrawInsetShadow(canvas.Rectangle(20.0, 20.0).Translate(5.0, 5.0), 2.0, -2.0, 1.0, canvas.Black)
+	img = Draw(c, 1.0)
+	test.That(t, at(24, 15).A < at(5, 15).A)
+	test.That(t, at(15, 5).A < at(15, 24).A)
+	test.T(t, at(4, 15).A, uint8(0))
+}
+
+func TestDrawWithAlpha(t *testing.T) {
+	// two overlapping semi-transparent squares and a square covering half a pixel
+	c := canvas.New(4.0, 1.0)
+	style := canvas.DefaultStyle
+	style.FillColor = color.RGBA{128, 0, 0, 128} // red at 50% opacity
+	c.RenderPath(canvas.Rectangle(2.0, 1.0), style, canvas.Identity)
+	style.FillColor = color.RGBA{0, 0, 128, 128} // blue at 50% opacity
+	c.RenderPath(canvas.Rectangle(1.0, 1.0), style, canvas.Identity)
+	style.FillColor = color.RGBA{0, 255, 0, 255}
+	c.RenderPath(canvas.Rectangle(0.5, 1.0), style, canvas.Identity.Translate(2.0, 0.0))
+
+	premultiplied := DrawWithAlpha(c, 1.0, Premultiplied).(*image.RGBA)
+	test.T(t, premultiplied.RGBAAt(0, 0), color.RGBA{63, 0, 128, 192})
+	test.T(t, premultiplied.RGBAAt(1, 0), color.RGBA{128, 0, 0, 128})
+	test.T(t, premultiplied.RGBAAt(2, 0), color.RGBA{0, 128, 0, 128})
+	test.T(t, premultiplied.RGBAAt(3, 0), color.RGBA{0, 0, 0, 0})
+
+	straight := DrawWithAlpha(c, 1.0, Straight).(*image.NRGBA)
+	test.T(t, straight.NRGBAAt(0, 0), color.NRGBA{84, 0, 170, 192})
+	test.T(t, straight.NRGBAAt(1, 0), color.NRGBA{255, 0, 0, 128})
+	test.T(t, straight.NRGBAAt(2, 0), color.NRGBA{0, 255, 0, 128}) // no dark fringe
+	test.T(t, straight.NRGBAAt(3, 0), color.NRGBA{0, 0, 0, 0})
+
+	// both conventions represent the same colors
+	for x := 0; x < 4; x++ {
+		r0, g0, b0, a0 := premultiplied.At(x, 0).RGBA()
+		r1, g1, b1, a1 := straight.At(x, 0).RGBA()
+		test.T(t, a1, a0)
+		test.That(t, math.Abs(float64(r1)-float64(r0)) <= 257.0 && math.Abs(float64(g1)-float64(g0)) <= 257.0 && math.Abs(float64(b1)-float64(b0)) <= 257.0, "colors must match at", x)
+	}
+}
+
+func TestRendererClipText(t *testing.T) {
+	dejaVuSerif := canvas.NewFontFamily("dejavu-serif")
+	if err := dejaVuSerif.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular); err != nil {
+		t.Fatal(err)
+	}
+	face := dejaVuSerif.Face(60.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+
+	c := canvas.New(40.0, 25.0)
+	ctx := canvas.NewContext(c)
+	ctx.Push()
+	ctx.ClipText(2.0, 4.0, face, "HI")
+	ctx.SetFillPaint(canvas.LinearGradient{Start: canvas.Point{X: 0.0, Y: 0.0}, End: canvas.Point{X: 40.0, Y: 0.0}, Stops: []canvas.GradientStop{{Offset: 0.0, Color: canvas.Red}, {Offset: 1.0, Color: canvas.Blue}}, Extend: canvas.ExtendClamp})
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(40.0, 25.0))
+	ctx.Pop()
+	ctx.SetFillColor(canvas.Green)
+	ctx.DrawPath(38.0, 23.0, canvas.Rectangle(2.0, 2.0)) // the clip is reset
+	img := Draw(c, 4.0)
+
+	glyphs := canvas.New(40.0, 25.0)
+	canvas.NewContext(glyphs).DrawText(2.0, 4.0, canvas.NewTextLine(face, "HI", canvas.Left))
+	mask := DrawMask(glyphs, 4.0)
+
+	// compare away from the antialiased edges of the glyphs
+	covered := func(x, y int) (bool, bool) {
+		all, none := true, true
+		for j := y - 1; j <= y+1; j++ {
+			for i := x - 1; i <= x+1; i++ {
+				a := mask.AlphaAt(i, j).A
+				all = all && a == 255
+				none = none && a == 0
+			}
+		}
+		return all, none
+	}
+	inside, outside := 0, 0
+	for y := 8; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx()-8; x++ {
+			if all, none := covered(x, y); all {
+				test.T(t, img.RGBAAt(x, y).A, uint8(255), x, y)
+				test.That(t, 0 < img.RGBAAt(x, y).R || 0 < img.RGBAAt(x, y).B, x, y)
+				inside++
+			} else if none {
+				test.T(t, img.RGBAAt(x, y), color.RGBA{}, x, y)
+				outside++
+			}
+		}
+	}
+	test.That(t, 0 < inside && 0 < outside)
+	test.T(t, img.RGBAAt(159, 0), canvas.Green)
+}
+
+func TestRendererGasp(t *testing.T) {
+	b, err := ioutil.ReadFile("../font/DejaVuSerif.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sfnt, err := font.NewSFNT(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gasp, _ := sfnt.Table("gasp")
+	test.T(t, len(gasp), 12)
+	gasp[6], gasp[7] = 0, byte(font.GaspGridFit) // disable antialiasing up to 8 ppem
+
+	dejaVuSerif := canvas.NewFontFamily("dejavu-serif")
+	if err := dejaVuSerif.LoadFont(b, canvas.FontRegular); err != nil {
+		t.Fatal(err)
+	}
+	gray := func(ppem float64) int {
+		face := dejaVuSerif.Face(ppem*72.0/25.4, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+		c := canvas.New(80.0, 20.0)
+		canvas.NewContext(c).DrawText(0.0, 2.0, canvas.NewTextLine(face, "Aliased", canvas.Left))
+		n := 0
+		for _, a := range DrawMask(c, 1.0).Pix {
+			if 0 < a && a < 255 {
+				n++
+			}
+		}
+		return n
+	}
+	test.T(t, gray(8.0), 0)
+	test.That(t, 0 < gray(12.0))
+}
+
+func TestRendererAntialias(t *testing.T) {
+	dejaVuSerif := canvas.NewFontFamily("dejavu-serif")
+	if err := dejaVuSerif.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular); err != nil {
+		t.Fatal(err)
+	}
+	face := dejaVuSerif.Face(24.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+
+	c := canvas.New(60.0, 20.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetStrokeColor(canvas.Black)
+	ctx.SetStrokeWidth(1.0)
+	ctx.DrawPath(10.0, 10.0, canvas.Circle(6.0))
+	ctx.DrawText(22.0, 14.0, canvas.NewTextLine(face, "Text", canvas.Left))
+
+	// count the pixels with partial coverage of the shapes at the left and the text at the right
+	gray := func(shapes, text Antialias) (int, int) {
+		img := image.NewAlpha(image.Rect(0, 0, 120, 40))
+		ras := New(img, 2.0)
+		ras.SetShapeAntialias(shapes)
+		ras.SetTextAntialias(text)
+		c.Render(ras)
+		nShapes, nText := 0, 0
+		for y := 0; y < 40; y++ {
+			for x := 0; x < 120; x++ {
+				if a := img.AlphaAt(x, y).A; 0 < a && a < 255 {
+					if x < 40 {
+						nShapes++
+					} else {
+						nText++
+					}
+				}
+			}
+		}
+		return nShapes, nText
+	}
+
+	nShapes, nText := gray(Aliased, Antialiased)
+	test.T(t, nShapes, 0)
+	test.That(t, 0 < nText, "text must be antialiased")
+
+	nShapes, nText = gray(Antialiased, Aliased)
+	test.That(t, 0 < nShapes, "shapes must be antialiased")
+	test.T(t, nText, 0)
+
+	nShapes, nText = gray(DefaultAntialias, DefaultAntialias)
+	test.That(t, 0 < nShapes && 0 < nText, "shapes and text must be antialiased by default")
+}
+
+func TestRendererCompositeOp(t *testing.T) {
+	c := canvas.New(20.0, 10.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(20.0, 10.0))
+	ctx.SetCompositeOp(canvas.DestinationOut)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(5.0, 10.0))
+	ctx.SetFillColor(color.RGBA{0, 0, 0, 128})
+	ctx.DrawPath(5.0, 0.0, canvas.Rectangle(5.0, 10.0))
+	ctx.SetCompositeOp(canvas.Clear)
+	ctx.DrawPath(10.0, 0.0, canvas.Rectangle(3.0, 10.0))
+	ctx.SetFillColor(canvas.Black)
+	ctx.SetCompositeOp(canvas.DestinationOut)
+	ctx.DrawPath(15.5, 0.0, canvas.Rectangle(1.0, 10.0)) // half coverage of two pixels
+	ctx.SetFillColor(color.RGBA{255, 0, 0, 255})
+	ctx.SetCompositeOp(canvas.SourceAtop)
+	ctx.DrawPath(0.0, 0.0, canvas.Rectangle(20.0, 5.0))
+
+	img := Draw(c, 1.0)
+	test.T(t, img.RGBAAt(2, 2), color.RGBA{0, 0, 0, 0})
+	test.T(t, img.RGBAAt(7, 2), color.RGBA{0, 0, 0, 127})
+	test.T(t, img.RGBAAt(11, 2), color.RGBA{0, 0, 0, 0})
+	test.T(t, img.RGBAAt(14, 2), color.RGBA{0, 0, 0, 255})
+	test.That(t, 120 < img.RGBAAt(15, 2).A && img.RGBAAt(15, 2).A < 136, "partial coverage must erase half of the alpha")
+	test.That(t, 120 < img.RGBAAt(16, 2).A && img.RGBAAt(16, 2).A < 136, "partial coverage must erase half of the alpha")
+	test.T(t, img.RGBAAt(18, 2), color.RGBA{0, 0, 0, 255})
+
+	// source atop only paints on what remains, at the bottom half of the image
+	test.T(t, img.RGBAAt(2, 7), color.RGBA{0, 0, 0, 0})
+	test.T(t, img.RGBAAt(7, 7), color.RGBA{127, 0, 0, 127})
+	test.T(t, img.RGBAAt(18, 7), color.RGBA{255, 0, 0, 255})
+}
+
+func TestRendererTextBaselineRounding(t *testing.T) {
+	dejaVuSerif := canvas.NewFontFamily("dejavu-serif")
+	if err := dejaVuSerif.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular); err != nil {
+		t.Fatal(err)
+	}
+	face := dejaVuSerif.Face(20.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+
+	c := canvas.New(20.0, 40.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawText(2.0, 37.3, canvas.NewTextBox(face, "H\nH\nH\nH", 0.0, 0.0, canvas.Left, canvas.Top, 0.0, 0.0))
+
+	// count the lines of which the bottom row of pixels, where H sits on the baseline, is not fully covered
+	partial := func(round bool) int {
+		img := image.NewAlpha(image.Rect(0, 0, 80, 160))
+		ras := New(img, 4.0)
+		ras.SetTextBaselineRounding(round)
+		c.Render(ras)
+		n, prev := 0, uint8(0)
+		for y := 159; 0 <= y; y-- {
+			row := uint8(0)
+			for x := 0; x < 80; x++ {
+				if a := img.AlphaAt(x, y).A; row < a {
+					row = a
+				}
+			}
+			if prev == 0 && row != 0 && row != 255 {
+				n++
+			}
+			prev = row
+		}
+		return n
+	}
+	test.T(t, partial(true), 0)
+	test.T(t, partial(false), 4)
+}
+
+func TestRendererDashGapColor(t *testing.T) {
+	for _, dashColor := range []color.RGBA{canvas.Red, {128, 0, 0, 128}} {
+		c := canvas.New(40.0, 4.0)
+		ctx := canvas.NewContext(c)
+		ctx.SetFillColor(canvas.Transparent)
+		ctx.SetStrokeColor(dashColor)
+		ctx.SetStrokeWidth(2.0)
+		ctx.SetDashes(0.0, 5.0, 5.0)
+		ctx.SetDashGapColor(canvas.Blue)
+		ctx.DrawPath(0.0, 2.0, canvas.MustParseSVG("H40"))
+
+		img := Draw(c, 4.0)
+		for x := 0; x < 160; x++ {
+			col := img.RGBAAt(x, 8)
+			if (x/20)%2 == 0 {
+				test.T(t, col, dashColor, "dash at", x)
+			} else {
+				test.T(t, col, canvas.Blue, "gap at", x)
+			}
+		}
+	}
+}
+
+func TestDrawProgress(t *testing.T) {
+	c := canvas.New(4.0, 4.0)
+	ctx := canvas.NewContext(c)
+	for i := 0; i < 4; i++ {
+		ctx.DrawPath(float64(i), 0.0, canvas.Rectangle(1.0, 4.0))
+	}
+
+	done := []int{}
+	img := DrawProgress(c, 1.0, func(n, total int) {
+		test.T(t, total, 4)
+		done = append(done, n)
+	})
+	test.T(t, done, []int{1, 2, 3, 4})
+	test.T(t, img.Pix, Draw(c, 1.0).Pix)
+}
@@ -0,0 +1,59 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// SignedDistance returns the shortest distance from the point (x,y) to the outline of the path, which is negative when the point is in the interior of the path as given by the fill rule, see Interior. Subpaths are implicitly closed and curves are flattened, so that the distance to curves is approximate, see Tolerance. It returns +Inf for an empty path.
+func (p *Path) SignedDistance(x, y float64, fillRule FillRule) float64 {
+	polylines := []*Polyline{}
+	for _, ps := range p.Split() {
+		polylines = append(polylines, PolylineFromPath(ps.Close()))
+	}
+	return signedDistance(polylines, Point{x, y}, fillRule)
+}
+
+func signedDistance(polylines []*Polyline, c Point, fillRule FillRule) float64 {
+	fillCount := 0
+	for _, polyline := range polylines {
+		if len(polyline.coords) != 0 {
+			fillCount += polyline.FillCount(c.X, c.Y)
+		}
+	}
+	dist := segmentDistance(polylines, c)
+	if fillRule == NonZero && fillCount != 0 || fillRule == EvenOdd && fillCount%2 != 0 {
+		return -dist
+	}
+	return dist
+}
+
+// GenerateSDF returns a signed distance field of the path with given resolution (in dots-per-millimeter), as used for rendering shapes and text on the GPU at any scale. The image covers the bounds of the path extended by spread on all sides, with the top-left pixel at the top-left of the extended bounds. Each pixel holds the signed distance of its center to the outline mapped from [spread,-spread] to [0,255], so that the outline is at 128 and values increase towards the interior, which is filled with the NonZero fill rule. Distances beyond the spread are clamped.
+func GenerateSDF(p *Path, resolution DPMM, spread float64) *image.Gray {
+	polylines := []*Polyline{}
+	for _, ps := range p.Split() {
+		polylines = append(polylines, PolylineFromPath(ps.Close()))
+	}
+	bounds := p.Bounds()
+	x0, y1 := bounds.X-spread, bounds.Y+bounds.H+spread
+	width := int(math.Ceil((bounds.W + 2.0*spread) * float64(resolution)))
+	height := int(math.Ceil((bounds.H + 2.0*spread) * float64(resolution)))
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for j := 0; j < height; j++ {
+		for i := 0; i < width; i++ {
+			c := Point{x0 + (float64(i)+0.5)/float64(resolution), y1 - (float64(j)+0.5)/float64(resolution)}
+			v := 0.5
+			if d := signedDistance(polylines, c, NonZero); 0.0 < spread {
+				v = math.Max(0.0, math.Min(1.0, 0.5-d/(2.0*spread)))
+			} else if d < 0.0 {
+				v = 1.0
+			} else if 0.0 < d {
+				v = 0.0
+			}
+			img.SetGray(i, j, color.Gray{uint8(v*255.0 + 0.5)})
+		}
+	}
+	return img
+}
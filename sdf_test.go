@@ -0,0 +1,41 @@
+package canvas
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestPathSignedDistance(t *testing.T) {
+	square := MustParseSVG("M0 0H10V10H0z")
+	test.Float(t, square.SignedDistance(5.0, 5.0, NonZero), -5.0)
+	test.Float(t, square.SignedDistance(5.0, 2.0, NonZero), -2.0)
+	test.Float(t, square.SignedDistance(13.0, 14.0, NonZero), 5.0) // closest to the corner
+	test.Float(t, square.SignedDistance(10.0, 5.0, NonZero), 0.0)
+
+	// the hole is outside for both fill rules, overlapping squares only for NonZero
+	ring := MustParseSVG("M0 0H10V10H0zM2 2V8H8V2z")
+	test.Float(t, ring.SignedDistance(5.0, 5.0, NonZero), 3.0)
+	test.Float(t, ring.SignedDistance(5.0, 5.0, EvenOdd), 3.0)
+	test.Float(t, ring.SignedDistance(1.0, 5.0, EvenOdd), -1.0)
+	overlap := MustParseSVG("M0 0H10V10H0zM2 2H8V8H2z")
+	test.Float(t, overlap.SignedDistance(5.0, 5.0, NonZero), -3.0)
+	test.Float(t, overlap.SignedDistance(5.0, 5.0, EvenOdd), 3.0)
+
+	circle := Circle(5.0)
+	test.That(t, math.Abs(circle.SignedDistance(0.0, 0.0, NonZero)+5.0) < 5.0*Tolerance)
+	test.That(t, math.Abs(circle.SignedDistance(6.0, 8.0, NonZero)-5.0) < 5.0*Tolerance)
+
+	test.That(t, math.IsInf((&Path{}).SignedDistance(0.0, 0.0, NonZero), 1))
+}
+
+func TestGenerateSDF(t *testing.T) {
+	img := GenerateSDF(MustParseSVG("M0 0H10V10H0z"), 1.0, 2.0)
+	test.T(t, img.Rect.Dx(), 14)
+	test.T(t, img.Rect.Dy(), 14)
+	test.T(t, img.GrayAt(7, 7).Y, uint8(255)) // deep inside
+	test.T(t, img.GrayAt(0, 7).Y, uint8(32))  // 1.5 outside
+	test.T(t, img.GrayAt(2, 7).Y, uint8(159)) // 0.5 inside
+	test.T(t, img.GrayAt(7, 12).Y, uint8(96)) // 0.5 outside at the bottom
+}
@@ -91,6 +91,28 @@ func Ellipse(rx, ry float64) *Path {
 	return p
 }
 
+// Superellipse returns a superellipse with radii rx,ry and exponent n, for which |x/rx|^n + |y/ry|^n = 1. An exponent of 2 gives an ellipse and larger exponents approach a rectangle with smoothly rounded corners, such as the squircle of app icons for an exponent of about 5. The outline is approximated by line segments.
+func Superellipse(rx, ry, n float64) *Path {
+	if Equal(rx, 0.0) || Equal(ry, 0.0) || n <= 0.0 {
+		return &Path{}
+	}
+
+	const segments = 256
+	p := &Path{}
+	for i := 0; i < segments; i++ {
+		sintheta, costheta := math.Sincos(2.0 * math.Pi * float64(i) / segments)
+		x := rx * math.Copysign(math.Pow(math.Abs(costheta), 2.0/n), costheta)
+		y := ry * math.Copysign(math.Pow(math.Abs(sintheta), 2.0/n), sintheta)
+		if i == 0 {
+			p.MoveTo(x, y)
+		} else {
+			p.LineTo(x, y)
+		}
+	}
+	p.Close()
+	return p
+}
+
 // RegularPolygon returns a regular polygon with radius r and rotation rot in degrees. It uses n vertices/edges, so when n approaches infinity this will return a path that approximates a circle. n must be 3 or more. The up boolean defines whether the first point will point north or not.
 func RegularPolygon(n int, r float64, up bool) *Path {
 	return RegularStarPolygon(n, 1, r, up)
@@ -19,6 +19,10 @@ func TestShapes(t *testing.T) {
 	test.T(t, BeveledRectangle(5.0, 10.0, 2.0), MustParseSVG("M0 2 2 0 3 0 5 2 5 8 3 10 2 10 0 8z"))
 	test.T(t, Circle(0.0), &Path{})
 	test.T(t, Circle(2.0), MustParseSVG("M2 0A2 2 0 0 1 -2 0A2 2 0 0 1 2 0z"))
+	test.T(t, Superellipse(0.0, 2.0, 5.0), &Path{})
+	test.T(t, Superellipse(2.0, 1.0, 2.0).Bounds(), Rect{-2.0, -1.0, 4.0, 2.0})
+	test.That(t, Superellipse(2.0, 2.0, 5.0).Interior(1.7, 1.7, NonZero), "squircle must fill the corner of an ellipse")
+	test.That(t, !Superellipse(2.0, 2.0, 5.0).Interior(1.95, 1.95, NonZero), "squircle must round the corner")
 	test.T(t, RegularPolygon(2, 2.0, true), &Path{})
 	test.T(t, RegularPolygon(4, 0.0, true), &Path{})
 	test.T(t, RegularPolygon(4, 2.0, true), MustParseSVG("M0 2 -2 0 0 -2 2 0z"))
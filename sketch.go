@@ -0,0 +1,96 @@
+package canvas
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Sketch is a hand-drawn style for paths, similar to rough.js, that is useful for wireframes and informal diagrams. When set in the style of a context, outlines of paths are stroked as two wobbly lines and fills are drawn as hatch lines within the path, see Context.SetSketch. The jitter is deterministic for the seed, so that drawings render the same every time.
+type Sketch struct {
+	Seed         int64
+	Roughness    float64 // maximum jitter of the lines in mm
+	HachureAngle float64 // angle of the hatch lines in degrees
+	HachureGap   float64 // distance between hatch lines in mm
+	HachureWidth float64 // width of the hatch lines in mm
+}
+
+// Sketchy returns a hand-drawn style with the given seed and roughness, which is the maximum jitter of the lines in mm. Fills are hatched with lines at an angle of -41 degrees that are 2mm apart and 0.3mm wide.
+func Sketchy(seed int64, roughness float64) *Sketch {
+	return &Sketch{
+		Seed:         seed,
+		Roughness:    roughness,
+		HachureAngle: -41.0,
+		HachureGap:   2.0,
+		HachureWidth: 0.3,
+	}
+}
+
+// Outline returns the outline of the path as two wobbly lines drawn on top of each other, where curves are flattened first. The result is meant to be stroked.
+func (s *Sketch) Outline(p *Path) *Path {
+	rnd := rand.New(rand.NewSource(s.Seed))
+	q := &Path{}
+	for _, ps := range p.Split() {
+		coords := ps.Flatten().Coords()
+		if len(coords) < 2 {
+			continue
+		}
+		for pass := 0; pass < 2; pass++ {
+			// each pass is offset as a whole, and each line bows and its end points jitter
+			offset := Point{s.jitter(rnd, 0.5), s.jitter(rnd, 0.5)}
+			start := coords[0].Add(offset)
+			q.MoveTo(start.X, start.Y)
+			for _, coord := range coords[1:] {
+				end := coord.Add(offset)
+				start = s.roughLine(q, rnd, start, end)
+			}
+		}
+	}
+	return q
+}
+
+// Hachure returns parallel hatch lines that fill the path with the given fill rule, where each line wobbles slightly. The result is meant to be stroked, such as with a width of HachureWidth.
+func (s *Sketch) Hachure(p *Path, fillRule FillRule) *Path {
+	rnd := rand.New(rand.NewSource(s.Seed))
+	q := &Path{}
+	if s.HachureGap <= 0.0 {
+		return q
+	}
+
+	// rotate the path so that the hatch lines are horizontal
+	rot := Identity.Rotate(-s.HachureAngle)
+	inv := rot.Inv()
+	polylines := []*Polyline{}
+	for _, ps := range p.Split() {
+		polylines = append(polylines, PolylineFromPath(ps.Close().Transform(rot)))
+	}
+	bounds := p.Transform(rot).Bounds()
+
+	for y := bounds.Y + s.HachureGap/2.0; y < bounds.Y+bounds.H; y += s.HachureGap {
+		// draw the line over the intervals that are filled
+		for _, interval := range scanlineIntervals(polylines, y, fillRule) {
+			start := inv.Dot(Point{interval[0], y})
+			end := inv.Dot(Point{interval[1], y})
+			q.MoveTo(start.X, start.Y)
+			s.roughLine(q, rnd, start, end)
+		}
+	}
+	return q
+}
+
+// jitter returns a random offset of at most f times the roughness.
+func (s *Sketch) jitter(rnd *rand.Rand, f float64) float64 {
+	return (2.0*rnd.Float64() - 1.0) * f * s.Roughness
+}
+
+// roughLine adds a line from start to a jittered end point to the path as a cubic Bézier that bows sideways, and returns its end point. The jitter is smaller for short lines so that flattened curves remain smooth.
+func (s *Sketch) roughLine(p *Path, rnd *rand.Rand, start, end Point) Point {
+	d := end.Sub(start)
+	f := math.Min(1.0, d.Length()/10.0)
+	end = end.Add(Point{s.jitter(rnd, 0.5*f), s.jitter(rnd, 0.5*f)})
+	d = end.Sub(start)
+	normal := d.Rot90CCW().Norm(1.0)
+	cp1 := start.Add(d.Mul(0.5)).Add(normal.Mul(s.jitter(rnd, f)))
+	cp2 := start.Add(d.Mul(0.75)).Add(normal.Mul(s.jitter(rnd, f)))
+	p.CubeTo(cp1.X, cp1.Y, cp2.X, cp2.Y, end.X, end.Y)
+	return end
+}
@@ -0,0 +1,47 @@
+package canvas
+
+import (
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestSketch(t *testing.T) {
+	p := Rectangle(10.0, 10.0)
+	sketch := Sketchy(42, 0.5)
+	test.T(t, sketch.Outline(p), Sketchy(42, 0.5).Outline(p))
+	test.T(t, sketch.Hachure(p, NonZero), Sketchy(42, 0.5).Hachure(p, NonZero))
+	test.That(t, !sketch.Outline(p).Equals(Sketchy(43, 0.5).Outline(p)), "different seeds must give different outlines")
+
+	// two passes over the outline
+	test.T(t, len(sketch.Outline(p).Split()), 2)
+
+	// hatch lines stay within the filled area and skip holes
+	ring := Rectangle(10.0, 10.0).Append(Rectangle(6.0, 6.0).Translate(2.0, 2.0).Reverse())
+	hachure := Sketchy(42, 0.0).Hachure(ring, NonZero)
+	test.That(t, 0 < len(hachure.Split()))
+	for _, line := range hachure.Split() {
+		start, end := line.StartPos(), line.Pos()
+		mid := start.Interpolate(end, 0.5)
+		test.That(t, ring.Interior(mid.X, mid.Y, NonZero), "hatch line must be inside", start, end)
+	}
+	test.T(t, Sketchy(42, 0.5).Hachure(MustParseSVG("M0 0H10"), NonZero).Empty(), true)
+}
+
+func TestContextSketch(t *testing.T) {
+	c := New(100, 100)
+	ctx := NewContext(c)
+	ctx.SetFillColor(Red)
+	ctx.SetStrokeColor(Blue)
+	ctx.SetStrokeWidth(0.5)
+	ctx.SetSketch(Sketchy(42, 0.5))
+	ctx.DrawPath(10.0, 10.0, Rectangle(20.0, 20.0))
+	test.T(t, len(c.layers), 2)
+	test.T(t, c.layers[0].style.StrokeColor, Red)
+	test.T(t, c.layers[0].style.FillColor, Transparent)
+	test.Float(t, c.layers[0].style.StrokeWidth, 0.3)
+	test.T(t, c.layers[1].style.StrokeColor, Blue)
+	test.T(t, c.layers[1].style.FillColor, Transparent)
+	test.T(t, c.layers[1].path, Sketchy(42, 0.5).Outline(Rectangle(20.0, 20.0)))
+	test.T(t, c.layers[1].style.Sketch, (*Sketch)(nil))
+}
@@ -10,7 +10,9 @@ import (
 	"image/png"
 	"io"
 	"math"
+	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/tdewolff/canvas"
 )
@@ -19,9 +21,15 @@ type SVG struct {
 	w             io.Writer
 	width, height float64
 	embedFonts    bool
+	exactGlyphs   bool
+	safe          bool
 	fonts         map[*canvas.Font]bool
 	maskID        int
+	paintID       int
+	shadowID      int
+	clipID        int
 	imgEnc        canvas.ImageEncoding
+	flatten       canvas.FlattenMethod
 
 	classes []string
 }
@@ -36,6 +44,9 @@ func New(w io.Writer, width, height float64) *SVG {
 		embedFonts: true,
 		fonts:      map[*canvas.Font]bool{},
 		maskID:     0,
+		paintID:    0,
+		shadowID:   0,
+		clipID:     0,
 		imgEnc:     canvas.Lossless,
 		classes:    []string{},
 	}
@@ -69,7 +80,16 @@ func (r *SVG) RemoveClass(class string) {
 
 func (r *SVG) writeClasses(w io.Writer) {
 	if len(r.classes) != 0 {
-		fmt.Fprintf(w, `" class="%s`, strings.Join(r.classes, " "))
+		classes := r.classes
+		if r.safe {
+			classes = make([]string, 0, len(r.classes))
+			for _, class := range r.classes {
+				if class = safeName(class, false); class != "" {
+					classes = append(classes, class)
+				}
+			}
+		}
+		fmt.Fprintf(w, `" class="%s`, strings.Join(classes, " "))
 	}
 }
 
@@ -77,10 +97,41 @@ func (r *SVG) EmbedFonts(embedFonts bool) {
 	r.embedFonts = embedFonts
 }
 
+// ExactGlyphPositions sets whether text is written with the position of each glyph, so that browsers reproduce the glyph positions of the text layout exactly instead of laying out the text themselves. Kerning and ligatures of the browser are disabled.
+func (r *SVG) ExactGlyphPositions(exact bool) {
+	r.exactGlyphs = exact
+}
+
+// SafeMode sets whether the output is sanitized so that it is safe to embed in web pages even for adversarial input, such as text from users. Text content is always XML-escaped, and in safe mode characters that are not allowed in XML are also replaced. Font family names are restricted to letters, digits, spaces, dots, hyphens and underscores, and classes to letters, digits, hyphens and underscores, so that they cannot break out of their attributes or style sheets. The renderer never writes scripts, event handler attributes or external references, and images and fonts are only embedded as data URIs.
+func (r *SVG) SafeMode(safe bool) {
+	r.safe = safe
+}
+
+// fontName returns the name of the font family, which is sanitized in safe mode.
+func (r *SVG) fontName(name string) string {
+	if r.safe {
+		return safeName(name, true)
+	}
+	return name
+}
+
 func (r *SVG) SetImageEncoding(enc canvas.ImageEncoding) {
 	r.imgEnc = enc
 }
 
+// SetFlattenMethod sets the method used to flatten curves into linear segments, such as canvas.UniformSteps. By default curves are written as curves.
+func (r *SVG) SetFlattenMethod(method canvas.FlattenMethod) {
+	r.flatten = method
+}
+
+// flattenPath flattens the path in the coordinates of the output if a flatten method is set, so that its deviation is in millimeters of the output.
+func (r *SVG) flattenPath(path *canvas.Path, m canvas.Matrix) *canvas.Path {
+	if r.flatten == nil {
+		return path
+	}
+	return path.Transform(m).FlattenWith(r.flatten).Transform(m.Inv())
+}
+
 func (r *SVG) writeFonts(fonts []*canvas.Font) {
 	is := []int{}
 	for i, font := range fonts {
@@ -94,7 +145,7 @@ func (r *SVG) writeFonts(fonts []*canvas.Font) {
 		fmt.Fprintf(r.w, "<style>")
 		for _, i := range is {
 			mediatype, raw := fonts[i].Raw()
-			fmt.Fprintf(r.w, "\n@font-face{font-family:'%s';src:url('data:%s;base64,", fonts[i].Name(), mediatype)
+			fmt.Fprintf(r.w, "\n@font-face{font-family:'%s';src:url('data:%s;base64,", r.fontName(fonts[i].Name()), mediatype)
 			encoder := base64.NewEncoder(base64.StdEncoding, r.w)
 			encoder.Write(raw)
 			encoder.Close()
@@ -109,8 +160,45 @@ func (r *SVG) Size() (float64, float64) {
 }
 
 func (r *SVG) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
-	fill := style.FillColor.A != 0
+	if style.CompositeOp.Erases() {
+		return // cannot erase previously drawn content
+	}
+	path = r.flattenPath(path, m)
 	stroke := style.StrokeColor.A != 0 && 0.0 < style.StrokeWidth
+	if stroke && isGradient(style.StrokePaint) {
+		// fill the path and then fill the stroke outline with the gradient
+		fillStyle := style
+		fillStyle.StrokeColor = canvas.Transparent
+		if fillStyle.FillColor.A != 0 || fillStyle.FillPaint != nil {
+			r.RenderPath(path, fillStyle, m)
+		}
+		if 0 < len(style.Dashes) {
+			path = path.Dash(style.DashOffset, style.Dashes...)
+		}
+		path = r.flattenPath(path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner), m)
+		id, _ := r.writeGradient(style.StrokePaint, m)
+		r.renderGradientPath(path, id, canvas.NonZero, m)
+		return
+	}
+	if solid, ok := style.StrokePaint.(canvas.SolidPaint); ok {
+		style.StrokeColor = solid.Color
+	}
+	if solid, ok := style.FillPaint.(canvas.SolidPaint); ok {
+		style.FillColor = solid.Color
+	} else if paint, ok := imagePaint(style.FillPaint); ok {
+		r.renderImagePaint(path, paint, style.FillRule, m)
+		if !stroke {
+			return
+		}
+		style.FillColor = canvas.Transparent
+	} else if id, ok := r.writeGradient(style.FillPaint, m); ok {
+		r.renderGradientPath(path, id, style.FillRule, m)
+		if !stroke {
+			return
+		}
+		style.FillColor = canvas.Transparent
+	}
+	fill := style.FillColor.A != 0
 
 	path = path.Transform(canvas.Identity.ReflectYAbout(r.height / 2.0).Mul(m))
 	fmt.Fprintf(r.w, `<path d="%s`, path.ToSVG())
@@ -202,6 +290,9 @@ func (r *SVG) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 			path = path.Dash(style.DashOffset, style.Dashes...)
 		}
 		path = path.Stroke(style.StrokeWidth, style.StrokeCapper, style.StrokeJoiner)
+		if r.flatten != nil {
+			path = path.FlattenWith(r.flatten)
+		}
 		fmt.Fprintf(r.w, `<path d="%s`, path.ToSVG())
 		if style.StrokeColor != canvas.Black {
 			fmt.Fprintf(r.w, `" fill="%v`, canvas.CSSColor(style.StrokeColor))
@@ -214,6 +305,97 @@ func (r *SVG) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix)
 	}
 }
 
+// RenderShadow draws a soft shadow of the path using a Gaussian blur filter, see canvas.Context.DrawShadow. The filter region extends three times the blur beyond the path so that the shadow is not clipped.
+func (r *SVG) RenderShadow(path *canvas.Path, blur float64, col color.RGBA, m canvas.Matrix) {
+	path = path.Transform(canvas.Identity.ReflectYAbout(r.height / 2.0).Mul(m))
+	if r.flatten != nil {
+		path = path.FlattenWith(r.flatten)
+	}
+	bounds := path.Bounds()
+	margin := 3.0 * blur
+	id := fmt.Sprintf("s%v", r.shadowID)
+	r.shadowID++
+
+	fmt.Fprintf(r.w, `<defs><filter id="%s" filterUnits="userSpaceOnUse" x="%v" y="%v" width="%v" height="%v"><feGaussianBlur stdDeviation="%v"/></filter></defs>`,
+		id, dec(bounds.X-margin), dec(bounds.Y-margin), dec(bounds.W+2.0*margin), dec(bounds.H+2.0*margin), dec(blur))
+	fmt.Fprintf(r.w, `<path d="%s" filter="url(#%s)`, path.ToSVG(), id)
+	if col != canvas.Black {
+		fmt.Fprintf(r.w, `" fill="%v`, canvas.CSSColor(col))
+	}
+	r.writeClasses(r.w)
+	fmt.Fprintf(r.w, `"/>`)
+}
+
+// RenderInsetShadow draws a soft shadow inside of the path using a filter, see canvas.Context.DrawInsetShadow. The filter floods the area outside of the path, which is offset and blurred, and is clipped to the path.
+func (r *SVG) RenderInsetShadow(path *canvas.Path, dx, dy, blur float64, col color.RGBA, m canvas.Matrix) {
+	m = canvas.Identity.ReflectYAbout(r.height / 2.0).Mul(m)
+	offset := m.Dot(canvas.Point{X: dx, Y: dy}).Sub(m.Dot(canvas.Point{}))
+	path = path.Transform(m)
+	if r.flatten != nil {
+		path = path.FlattenWith(r.flatten)
+	}
+	bounds := path.Bounds()
+	margin := 3.0*blur + math.Max(math.Abs(offset.X), math.Abs(offset.Y))
+	id := fmt.Sprintf("s%v", r.shadowID)
+	r.shadowID++
+
+	fmt.Fprintf(r.w, `<defs><filter id="%s" filterUnits="userSpaceOnUse" x="%v" y="%v" width="%v" height="%v">`,
+		id, dec(bounds.X-margin), dec(bounds.Y-margin), dec(bounds.W+2.0*margin), dec(bounds.H+2.0*margin))
+	fmt.Fprintf(r.w, `<feFlood flood-color="%v"/><feComposite in2="SourceAlpha" operator="out"/>`, canvas.CSSColor(col))
+	fmt.Fprintf(r.w, `<feOffset dx="%v" dy="%v"/><feGaussianBlur stdDeviation="%v"/>`, dec(offset.X), dec(offset.Y), dec(blur))
+	fmt.Fprintf(r.w, `<feComposite in2="SourceAlpha" operator="in"/></filter></defs>`)
+	fmt.Fprintf(r.w, `<path d="%s" filter="url(#%s)`, path.ToSVG(), id)
+	r.writeClasses(r.w)
+	fmt.Fprintf(r.w, `"/>`)
+}
+
+// PushClip starts a group that is clipped by the path, see canvas.Context.ClipPath.
+func (r *SVG) PushClip(path *canvas.Path, m canvas.Matrix) {
+	path = path.Transform(canvas.Identity.ReflectYAbout(r.height / 2.0).Mul(m))
+	if r.flatten != nil {
+		path = path.FlattenWith(r.flatten)
+	}
+	id := fmt.Sprintf("c%v", r.clipID)
+	r.clipID++
+	fmt.Fprintf(r.w, `<defs><clipPath id="%s"><path d="%s"/></clipPath></defs><g clip-path="url(#%s)">`, id, path.ToSVG(), id)
+}
+
+// PopClip ends the group of the matching PushClip.
+func (r *SVG) PopClip() {
+	fmt.Fprintf(r.w, `</g>`)
+}
+
+// disabledFeatures are the CSS font feature settings that disable the features that change glyph positions, see SVG.ExactGlyphPositions.
+var disabledFeatures = []string{`'kern' 0`, `'liga' 0`, `'clig' 0`}
+
+// fontFeatureSettings returns the CSS font feature settings that enable the features of the font face that the font has, in sorted order, see canvas.FontFace.Features. Without exact glyph positions, these include the discretionary and historical ligatures that are enabled, see canvas.FontFace.LigatureFeatures.
+func (r *SVG) fontFeatureSettings(ff canvas.FontFace) []string {
+	settings := []string{}
+	for tag := range ff.Features {
+		if ff.Font.HasFeature(tag) {
+			settings = append(settings, fmt.Sprintf("'%s' 1", tag))
+		}
+	}
+	if !r.exactGlyphs {
+		// ligatures without presentation form are written as their components and formed natively
+		for _, tag := range ff.LigatureFeatures() {
+			if _, ok := ff.Features[tag]; !ok && tag != "liga" {
+				settings = append(settings, fmt.Sprintf("'%s' 1", tag))
+			}
+		}
+	}
+	if len(settings) == 0 {
+		return nil
+	}
+	sort.Strings(settings)
+	if r.exactGlyphs {
+		// settings of the text element are overridden and not merged
+		settings = append(append([]string{}, disabledFeatures...), settings...)
+	}
+	return settings
+}
+
+// writeFontStyle writes the attributes of a text span that differ from the main font face of the text, and the features of the font face.
 func (r *SVG) writeFontStyle(ff, ffMain canvas.FontFace) {
 	boldness := ff.Boldness()
 	differences := 0
@@ -230,6 +412,7 @@ func (r *SVG) writeFontStyle(ff, ffMain canvas.FontFace) {
 	if ff.Color != ffMain.Color {
 		differences++
 	}
+	style := true // a style attribute is written
 	if ff.Name() != ffMain.Name() || ff.Size*ff.Scale != ffMain.Size || differences == 3 {
 		fmt.Fprintf(r.w, `" style="font:`)
 
@@ -246,7 +429,7 @@ func (r *SVG) writeFontStyle(ff, ffMain canvas.FontFace) {
 			fmt.Fprintf(buf, ` small-caps`)
 		}
 
-		fmt.Fprintf(buf, ` %vpx %s`, num(ff.Size*ff.Scale), ff.Name())
+		fmt.Fprintf(buf, ` %vpx %s`, num(ff.Size*ff.Scale), r.fontName(ff.Name()))
 		buf.ReadByte()
 		buf.WriteTo(r.w)
 
@@ -255,6 +438,7 @@ func (r *SVG) writeFontStyle(ff, ffMain canvas.FontFace) {
 		}
 	} else if differences == 1 && ff.Color != ffMain.Color {
 		fmt.Fprintf(r.w, `" fill="%v`, canvas.CSSColor(ff.Color))
+		style = false
 	} else if 0 < differences {
 		fmt.Fprintf(r.w, `" style="`)
 		buf := &bytes.Buffer{}
@@ -272,7 +456,48 @@ func (r *SVG) writeFontStyle(ff, ffMain canvas.FontFace) {
 		}
 		buf.ReadByte()
 		buf.WriteTo(r.w)
+	} else {
+		style = false
+	}
+
+	if settings := r.fontFeatureSettings(ff); settings != nil {
+		if style {
+			fmt.Fprintf(r.w, `;`)
+		} else {
+			fmt.Fprintf(r.w, `" style="`)
+		}
+		fmt.Fprintf(r.w, `font-feature-settings:%s`, strings.Join(settings, ","))
+	}
+}
+
+// spanText returns the text of a span and the x positions of its characters, where the ligature glyphs without presentation form are replaced by their components, see canvas.Font.LigatureText. The components divide the advance of the ligature.
+func spanText(span canvas.TextSpan) (string, []float64) {
+	positions := span.GlyphPositions()
+	if strings.IndexFunc(span.Text, func(r rune) bool {
+		_, ok := span.Face.Font.LigatureText(r)
+		return ok
+	}) == -1 {
+		return span.Text, positions
 	}
+
+	var sb strings.Builder
+	xs := make([]float64, 0, len(positions))
+	i := 0
+	for _, r := range span.Text {
+		if components, ok := span.Face.Font.LigatureText(r); ok {
+			n := utf8.RuneCountInString(components)
+			advance := span.Face.TextWidth(string(r))
+			for j := 0; j < n; j++ {
+				xs = append(xs, positions[i]+float64(j)*advance/float64(n))
+			}
+			sb.WriteString(components)
+		} else {
+			xs = append(xs, positions[i])
+			sb.WriteRune(r)
+		}
+		i++
+	}
+	return sb.String(), xs
 }
 
 func (r *SVG) RenderText(text *canvas.Text, m canvas.Matrix) {
@@ -284,49 +509,69 @@ func (r *SVG) RenderText(text *canvas.Text, m canvas.Matrix) {
 		return
 	}
 
-	ffMain := text.MostCommonFontFace()
+	text.RenderLayers(r, m, func() {
+		ffMain := text.MostCommonFontFace()
 
-	x0, y0 := 0.0, 0.0
-	if m.IsTranslation() {
-		x0, y0 = m.Pos()
-		y0 = r.height - y0
-		fmt.Fprintf(r.w, `<text x="%v" y="%v`, num(x0), num(y0))
-	} else {
-		fmt.Fprintf(r.w, `<text transform="%s`, m.ToSVG(r.height))
-	}
-	fmt.Fprintf(r.w, `" style="font:`)
-	if ffMain.Style&canvas.FontItalic != 0 {
-		fmt.Fprintf(r.w, ` italic`)
-	}
-	if boldness := ffMain.Boldness(); boldness != 400 {
-		fmt.Fprintf(r.w, ` %d`, boldness)
-	}
-	if ffMain.Variant&canvas.FontSmallcaps != 0 {
-		fmt.Fprintf(r.w, ` small-caps`)
-	}
-	fmt.Fprintf(r.w, ` %vpx %s`, num(ffMain.Size*ffMain.Scale), ffMain.Name())
-	if ffMain.Color != canvas.Black {
-		fmt.Fprintf(r.w, `;fill:%v`, canvas.CSSColor(ffMain.Color))
-	}
-	r.writeClasses(r.w)
-	fmt.Fprintf(r.w, `">`)
-
-	text.WalkSpans(func(y, dx float64, span canvas.TextSpan) {
-		fmt.Fprintf(r.w, `<tspan x="%v" y="%v`, num(x0+dx), num(y0-y-span.Face.Voffset))
-		if span.WordSpacing > 0.0 {
-			fmt.Fprintf(r.w, `" word-spacing="%v`, num(span.WordSpacing))
+		x0, y0 := 0.0, 0.0
+		if m.IsTranslation() {
+			x0, y0 = m.Pos()
+			y0 = r.height - y0
+			fmt.Fprintf(r.w, `<text x="%v" y="%v`, num(x0), num(y0))
+		} else {
+			fmt.Fprintf(r.w, `<text transform="%s`, m.ToSVG(r.height))
 		}
-		if span.GlyphSpacing > 0.0 {
-			fmt.Fprintf(r.w, `" letter-spacing="%v`, num(span.GlyphSpacing))
+		fmt.Fprintf(r.w, `" style="font:`)
+		if ffMain.Style&canvas.FontItalic != 0 {
+			fmt.Fprintf(r.w, ` italic`)
+		}
+		if boldness := ffMain.Boldness(); boldness != 400 {
+			fmt.Fprintf(r.w, ` %d`, boldness)
+		}
+		if ffMain.Variant&canvas.FontSmallcaps != 0 {
+			fmt.Fprintf(r.w, ` small-caps`)
+		}
+		fmt.Fprintf(r.w, ` %vpx %s`, num(ffMain.Size*ffMain.Scale), r.fontName(ffMain.Name()))
+		if ffMain.Color != canvas.Black {
+			fmt.Fprintf(r.w, `;fill:%v`, canvas.CSSColor(ffMain.Color))
+		}
+		if r.exactGlyphs {
+			fmt.Fprintf(r.w, `;font-kerning:none;font-feature-settings:%s`, strings.Join(disabledFeatures, ","))
 		}
-		r.writeFontStyle(span.Face, ffMain)
-		s := span.Text
-		s = strings.ReplaceAll(s, `"`, `&quot;`)
 		r.writeClasses(r.w)
-		fmt.Fprintf(r.w, `">%s</tspan>`, s)
+		fmt.Fprintf(r.w, `">`)
+
+		text.WalkSpans(func(y, dx float64, span canvas.TextSpan) {
+			if r.exactGlyphs {
+				// x positions of all glyphs include kerning, glyph, word and sentence spacing
+				fmt.Fprintf(r.w, `<tspan x="`)
+				_, positions := spanText(span)
+				for i, x := range positions {
+					if i != 0 {
+						fmt.Fprintf(r.w, " ")
+					}
+					fmt.Fprintf(r.w, "%v", num(x0+dx+x))
+				}
+				fmt.Fprintf(r.w, `" y="%v`, num(y0-y-span.Face.Voffset))
+			} else {
+				fmt.Fprintf(r.w, `<tspan x="%v" y="%v`, num(x0+dx), num(y0-y-span.Face.Voffset))
+				if span.WordSpacing > 0.0 {
+					fmt.Fprintf(r.w, `" word-spacing="%v`, num(span.WordSpacing))
+				}
+				if span.GlyphSpacing > 0.0 {
+					fmt.Fprintf(r.w, `" letter-spacing="%v`, num(span.GlyphSpacing))
+				}
+			}
+			r.writeFontStyle(span.Face, ffMain)
+			s, _ := spanText(span)
+			if r.safe {
+				s = safeText(s)
+			}
+			s = textEscaper.Replace(s)
+			r.writeClasses(r.w)
+			fmt.Fprintf(r.w, `">%s</tspan>`, s)
+		})
+		fmt.Fprintf(r.w, `</text>`)
 	})
-	fmt.Fprintf(r.w, `</text>`)
-	text.RenderDecoration(r, m)
 }
 
 func (r *SVG) RenderImage(img image.Image, m canvas.Matrix) {
@@ -365,8 +610,108 @@ func (r *SVG) RenderImage(img image.Image, m canvas.Matrix) {
 	fmt.Fprintf(r.w, `"/>`)
 }
 
+// renderImagePaint fills the path with the image paint. Repeating paints use a pattern, other paints draw the image clipped by the path.
+func (r *SVG) renderImagePaint(path *canvas.Path, paint canvas.ImagePaint, fillRule canvas.FillRule, m canvas.Matrix) {
+	id := fmt.Sprintf("p%v", r.paintID)
+	r.paintID++
+
+	img, transform := paint.Image, paint.Transform
+	if paint.Extend == canvas.ExtendReflect {
+		img = paint.ReflectedImage()
+	} else if paint.Extend == canvas.ExtendClamp {
+		img, transform = paint.ClampedImage(path.Bounds())
+	}
+	size := img.Bounds().Size()
+	transform = m.Mul(transform).Translate(0.0, float64(size.Y))
+
+	data := path.Transform(canvas.Identity.ReflectYAbout(r.height / 2.0).Mul(m)).ToSVG()
+	if paint.Extend == canvas.ExtendRepeat || paint.Extend == canvas.ExtendReflect {
+		fmt.Fprintf(r.w, `<defs><pattern id="%s" patternUnits="userSpaceOnUse" width="%d" height="%d" patternTransform="%s"><image width="%d" height="%d" xlink:href="data:image/png;base64,`,
+			id, size.X, size.Y, transform.ToSVG(r.height), size.X, size.Y)
+		r.writeImageData(img)
+		fmt.Fprintf(r.w, `"/></pattern></defs><path d="%s" fill="url(#%s)`, data, id)
+		if fillRule == canvas.EvenOdd {
+			fmt.Fprintf(r.w, `" fill-rule="evenodd`)
+		}
+		r.writeClasses(r.w)
+		fmt.Fprintf(r.w, `"/>`)
+		return
+	}
+
+	fmt.Fprintf(r.w, `<clipPath id="%s"><path d="%s`, id, data)
+	if fillRule == canvas.EvenOdd {
+		fmt.Fprintf(r.w, `" clip-rule="evenodd`)
+	}
+	fmt.Fprintf(r.w, `"/></clipPath><image clip-path="url(#%s)" transform="%s" width="%d" height="%d" xlink:href="data:image/png;base64,`,
+		id, transform.ToSVG(r.height), size.X, size.Y)
+	r.writeImageData(img)
+	r.writeClasses(r.w)
+	fmt.Fprintf(r.w, `"/>`)
+}
+
+// writeGradient writes the definition of a linear or radial gradient, where m transforms the gradient to the coordinate system of the canvas. It returns the ID of the gradient, or false if the paint is not a gradient.
+func (r *SVG) writeGradient(paint canvas.Paint, m canvas.Matrix) (string, bool) {
+	var stops []canvas.GradientStop
+	var extend canvas.ExtendMode
+	id := fmt.Sprintf("p%v", r.paintID)
+	switch p := gradientPaint(paint).(type) {
+	case canvas.LinearGradient:
+		fmt.Fprintf(r.w, `<defs><linearGradient id="%s" gradientUnits="userSpaceOnUse" x1="%v" y1="%v" x2="%v" y2="%v"`, id, dec(p.Start.X), dec(p.Start.Y), dec(p.End.X), dec(p.End.Y))
+		stops, extend = p.Stops, p.Extend
+	case canvas.RadialGradient:
+		fmt.Fprintf(r.w, `<defs><radialGradient id="%s" gradientUnits="userSpaceOnUse" cx="%v" cy="%v" r="%v"`, id, dec(p.Center.X), dec(p.Center.Y), dec(p.Radius))
+		stops, extend = p.Stops, p.Extend
+	default:
+		return "", false
+	}
+	r.paintID++
+
+	// the gradient is defined in the coordinate system of the path
+	t := canvas.Identity.ReflectYAbout(r.height / 2.0).Mul(m)
+	if !t.Equals(canvas.Identity) {
+		fmt.Fprintf(r.w, ` gradientTransform="matrix(%v %v %v %v %v %v)"`, dec(t[0][0]), dec(t[1][0]), dec(t[0][1]), dec(t[1][1]), dec(t[0][2]), dec(t[1][2]))
+	}
+	if extend == canvas.ExtendRepeat {
+		fmt.Fprintf(r.w, ` spreadMethod="repeat"`)
+	} else if extend == canvas.ExtendReflect {
+		fmt.Fprintf(r.w, ` spreadMethod="reflect"`)
+	}
+	fmt.Fprintf(r.w, `>`)
+	for _, stop := range stops {
+		fmt.Fprintf(r.w, `<stop offset="%v" stop-color="%v"/>`, dec(stop.Offset), canvas.CSSColor(stop.Color))
+	}
+	if _, ok := gradientPaint(paint).(canvas.LinearGradient); ok {
+		fmt.Fprintf(r.w, `</linearGradient></defs>`)
+	} else {
+		fmt.Fprintf(r.w, `</radialGradient></defs>`)
+	}
+	return id, true
+}
+
+// renderGradientPath fills the path with the gradient of the given ID.
+func (r *SVG) renderGradientPath(path *canvas.Path, id string, fillRule canvas.FillRule, m canvas.Matrix) {
+	data := path.Transform(canvas.Identity.ReflectYAbout(r.height / 2.0).Mul(m)).ToSVG()
+	fmt.Fprintf(r.w, `<path d="%s" fill="url(#%s)`, data, id)
+	if fillRule == canvas.EvenOdd {
+		fmt.Fprintf(r.w, `" fill-rule="evenodd`)
+	}
+	r.writeClasses(r.w)
+	fmt.Fprintf(r.w, `"/>`)
+}
+
+func (r *SVG) writeImageData(img image.Image) {
+	encoder := base64.NewEncoder(base64.StdEncoding, r.w)
+	if err := png.Encode(encoder, img); err != nil {
+		panic(err)
+	}
+	if err := encoder.Close(); err != nil {
+		panic(err)
+	}
+}
+
 func writeImageBytes(img image.Image, mimetype string, w io.Writer) error {
-	if j, ok := img.(canvas.JPEGImage); ok {
+	if j, ok := img.(canvas.JPEGImage); ok && j.ColorModel() != color.CMYKModel {
+		// CMYK JPEGs are not well supported by browsers and are converted to RGB below
 		_, err := w.Write(j.JPEGBytes())
 		return err
 	}
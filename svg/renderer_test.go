@@ -1,7 +1,17 @@
 package svg
 
 import (
+	"bytes"
+	"image"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tdewolff/canvas"
+	"github.com/tdewolff/test"
 )
 
 func TestSVGText(t *testing.T) {
@@ -30,3 +40,262 @@ func TestSVGText(t *testing.T) {
 	//s := regexp.MustCompile(`base64,.+'`).ReplaceAllString(buf.String(), "base64,'") // remove embedded font
 	//test.String(t, s, `<style>`+"\n"+`@font-face{font-family:'dejavu-serif';src:url('data:font/truetype;base64,');}`+"\n"+`@font-face{font-family:'eb-garamond';src:url('data:font/opentype;base64,');}`+"\n"+`</style><text x="0" y="0" style="font: 12px dejavu-serif"><tspan x="0" y="7.421875" style="font:8px dejavu-serif">dejaVu8</tspan><tspan x="0" y="20.453125" letter-spacing="1" style="font-style:italic;fill:#f00">glyphspacing</tspan><tspan x="0" y="33.725625" style="font:700 6.996px dejavu-serif">dejaVu12sub</tspan><tspan x="0" y="38.5" style="font:700 10px eb-garamond">garamond10</tspan></text><path d="M0 22.703125H91.71875V21.803125H0z" fill="#f00"/>`)
 }
+
+func TestSVGImagePaint(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	style := canvas.DefaultStyle
+
+	buf := &bytes.Buffer{}
+	svg := New(buf, 10.0, 10.0)
+	style.FillPaint = canvas.ImagePaint{Image: img, Transform: canvas.Identity}
+	svg.RenderPath(canvas.Rectangle(4.0, 4.0), style, canvas.Identity)
+	s := regexp.MustCompile(`base64,[^"]+`).ReplaceAllString(buf.String(), "base64,")
+	test.String(t, s, `<svg version="1.1" width="10mm" height="10mm" viewBox="0 0 10 10" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><clipPath id="p0"><path d="M0 10H4V6H0z"/></clipPath><image clip-path="url(#p0)" transform="translate(0,8)" width="2" height="2" xlink:href="data:image/png;base64,"/>`)
+
+	buf.Reset()
+	svg = New(buf, 10.0, 10.0)
+	style.FillPaint = canvas.ImagePaint{Image: img, Transform: canvas.Identity, Extend: canvas.ExtendRepeat}
+	svg.RenderPath(canvas.Rectangle(4.0, 4.0), style, canvas.Identity)
+	s = regexp.MustCompile(`base64,[^"]+`).ReplaceAllString(buf.String(), "base64,")
+	test.String(t, s, `<svg version="1.1" width="10mm" height="10mm" viewBox="0 0 10 10" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><defs><pattern id="p0" patternUnits="userSpaceOnUse" width="2" height="2" patternTransform="translate(0,8)"><image width="2" height="2" xlink:href="data:image/png;base64,"/></pattern></defs><path d="M0 10H4V6H0z" fill="url(#p0)"/>`)
+}
+
+func TestSVGDisplayList(t *testing.T) {
+	c := canvas.New(10.0, 10.0)
+	ctx := canvas.NewContext(c)
+	ctx.SetStrokeColor(canvas.Red)
+	ctx.DrawPath(1.0, 1.0, canvas.Circle(2.0))
+	ctx.BeginGroup(true, false)
+	ctx.SetFillColor(canvas.Blue)
+	ctx.DrawPath(5.0, 5.0, canvas.Rectangle(3.0, 2.0))
+	ctx.EndGroup()
+
+	buf := &bytes.Buffer{}
+	svg := New(buf, c.W, c.H)
+	c.Render(svg)
+	svg.Close()
+
+	// replay the display list into a new canvas
+	replay := canvas.New(c.W, c.H)
+	c.DisplayList().Render(replay)
+
+	bufReplay := &bytes.Buffer{}
+	svg = New(bufReplay, c.W, c.H)
+	replay.Render(svg)
+	svg.Close()
+	test.String(t, bufReplay.String(), buf.String())
+}
+
+func TestSVGGradient(t *testing.T) {
+	stops := []canvas.GradientStop{{Offset: 0.0, Color: canvas.Red}, {Offset: 1.0, Color: canvas.Blue}}
+	style := canvas.DefaultStyle
+
+	buf := &bytes.Buffer{}
+	svg := New(buf, 10.0, 10.0)
+	style.FillPaint = canvas.LinearGradient{Start: canvas.Point{X: 0.0, Y: 0.0}, End: canvas.Point{X: 4.0, Y: 0.0}, Stops: stops, Extend: canvas.ExtendReflect}
+	svg.RenderPath(canvas.Rectangle(4.0, 4.0), style, canvas.Identity)
+	test.String(t, buf.String(), `<svg version="1.1" width="10mm" height="10mm" viewBox="0 0 10 10" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><defs><linearGradient id="p0" gradientUnits="userSpaceOnUse" x1="0" y1="0" x2="4" y2="0" gradientTransform="matrix(1 0 0 -1 0 10)" spreadMethod="reflect"><stop offset="0" stop-color="#f00"/><stop offset="1" stop-color="#00f"/></linearGradient></defs><path d="M0 10H4V6H0z" fill="url(#p0)"/>`)
+
+	// strokes are drawn as outlines filled with the gradient
+	buf.Reset()
+	svg = New(buf, 10.0, 10.0)
+	style = canvas.DefaultStyle
+	style.FillColor = canvas.Transparent
+	style.StrokeColor = canvas.Red
+	style.StrokePaint = canvas.RadialGradient{Center: canvas.Point{X: 2.0, Y: 0.0}, Radius: 2.0, Stops: stops}
+	svg.RenderPath(canvas.MustParseSVG("M0 0L4 0"), style, canvas.Identity.Translate(1.0, 1.0))
+	test.String(t, buf.String(), `<svg version="1.1" width="10mm" height="10mm" viewBox="0 0 10 10" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><defs><radialGradient id="p0" gradientUnits="userSpaceOnUse" cx="2" cy="0" r="2" gradientTransform="matrix(1 0 0 -1 1 9)"><stop offset="0" stop-color="#f00"/><stop offset="1" stop-color="#00f"/></radialGradient></defs><path d="M1 9.5H5V8.5H1z" fill="url(#p0)"/>`)
+}
+
+func TestSVGExactGlyphPositions(t *testing.T) {
+	dejaVuSerif := canvas.NewFontFamily("dejavu-serif")
+	dejaVuSerif.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular)
+	face := dejaVuSerif.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+	text := canvas.NewRichText().Add(face, "aaa bbb ccc ddd eee fff").ToText(40.0, 0.0, canvas.Justify, canvas.Top, 0.0, 0.0)
+
+	buf := &bytes.Buffer{}
+	svg := New(buf, 100.0, 100.0)
+	svg.EmbedFonts(false)
+	svg.ExactGlyphPositions(true)
+	svg.RenderText(text, canvas.Identity)
+
+	s := buf.String()
+	test.That(t, strings.Contains(s, "font-kerning:none"), "must disable kerning of the browser")
+	matches := regexp.MustCompile(`<tspan x="([^"]*)"[^>]*>([^<]*)</tspan>`).FindAllStringSubmatch(s, -1)
+	test.That(t, 1 < len(matches), "must break into several lines")
+
+	// the first line is justified, so that the last glyph ends at the width of the text box
+	xs := strings.Fields(matches[0][1])
+	test.T(t, len(xs), utf8.RuneCountInString(matches[0][2]))
+	x0, _ := strconv.ParseFloat(xs[0], 64)
+	x1, _ := strconv.ParseFloat(xs[len(xs)-1], 64)
+	test.Float(t, x0, 0.0)
+	test.Float(t, x1+face.TextWidth(matches[0][2][len(matches[0][2])-1:]), 40.0)
+
+	// glyph positions are increasing
+	for i := 1; i < len(xs); i++ {
+		a, _ := strconv.ParseFloat(xs[i-1], 64)
+		b, _ := strconv.ParseFloat(xs[i], 64)
+		test.That(t, a < b, "glyph positions must increase")
+	}
+
+	// features that the font has are enabled in the browser
+	face.Features = map[string]canvas.FallbackPolicy{"case": canvas.FallbackError}
+	buf.Reset()
+	svg = New(buf, 100.0, 100.0)
+	svg.EmbedFonts(false)
+	svg.ExactGlyphPositions(true)
+	svg.RenderText(canvas.NewTextLine(face, "(A)", canvas.Left), canvas.Identity)
+	test.That(t, strings.Contains(buf.String(), `" style="font-feature-settings:'kern' 0,'liga' 0,'clig' 0,'case' 1">`))
+}
+
+func TestSVGLigatures(t *testing.T) {
+	ebGaramond := canvas.NewFontFamily("eb-garamond")
+	ebGaramond.LoadFontFile("../font/EBGaramond12-Regular.otf", canvas.FontRegular)
+	face := ebGaramond.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+	face.Ligatures = canvas.DiscretionaryLigatures
+	text := canvas.NewTextLine(face, "The", canvas.Left)
+
+	// ligatures without presentation form are written as their components
+	buf := &bytes.Buffer{}
+	svg := New(buf, 100.0, 100.0)
+	svg.EmbedFonts(false)
+	svg.RenderText(text, canvas.Identity)
+	test.That(t, strings.Contains(buf.String(), `style="font-feature-settings:'dlig' 1">The</tspan>`), "must form the ligature in the browser")
+
+	buf.Reset()
+	svg = New(buf, 100.0, 100.0)
+	svg.EmbedFonts(false)
+	svg.ExactGlyphPositions(true)
+	svg.RenderText(text, canvas.Identity)
+	matches := regexp.MustCompile(`<tspan x="([^"]*)"[^>]*>([^<]*)</tspan>`).FindAllStringSubmatch(buf.String(), -1)
+	test.T(t, len(matches), 1)
+	test.String(t, matches[0][2], "The")
+	test.T(t, len(strings.Fields(matches[0][1])), 3)
+}
+
+func TestSVGAnimation(t *testing.T) {
+	frames := []*canvas.Canvas{}
+	for i := 0; i < 3; i++ {
+		c := canvas.New(10.0, 10.0)
+		c.RenderPath(canvas.Rectangle(float64(i+1), 1.0), canvas.DefaultStyle, canvas.Identity)
+		frames = append(frames, c)
+	}
+
+	buf := &bytes.Buffer{}
+	test.Error(t, WriteAnimation(buf, frames, []time.Duration{time.Second, 2 * time.Second, time.Second}))
+	test.String(t, buf.String(), `<svg version="1.1" width="10mm" height="10mm" viewBox="0 0 10 10" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><style>`+
+		"\n@keyframes f0{0%{visibility:visible}25%{visibility:hidden}}\n.f0{visibility:hidden;animation:f0 4s step-end infinite}"+
+		"\n@keyframes f1{25%{visibility:visible}75%{visibility:hidden}}\n.f1{visibility:hidden;animation:f1 4s step-end infinite}"+
+		"\n@keyframes f2{75%{visibility:visible}}\n.f2{visibility:hidden;animation:f2 4s step-end infinite}"+
+		"\n</style>"+`<g class="f0"><path d="M0 10H1V9H0z"/></g><g class="f1"><path d="M0 10H2V9H0z"/></g><g class="f2"><path d="M0 10H3V9H0z"/></g></svg>`)
+	test.T(t, strings.Count(buf.String(), "@keyframes"), len(frames))
+
+	test.That(t, WriteAnimation(buf, frames, []time.Duration{time.Second}) != nil, "must fail for missing durations")
+	test.That(t, WriteAnimation(buf, frames, []time.Duration{time.Second, 0, time.Second}) != nil, "must fail for a zero frame duration")
+	test.That(t, WriteAnimation(buf, frames, []time.Duration{time.Second, -time.Second, 2 * time.Second}) != nil, "must fail for a negative frame duration")
+	test.That(t, WriteAnimation(buf, nil, nil) != nil, "must fail without frames")
+}
+
+func TestSVGSafeMode(t *testing.T) {
+	family := canvas.NewFontFamily("evil'}</style><script>alert(1)</script>")
+	family.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular)
+	face := family.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+	text := canvas.NewTextLine(face, "a</text><script>alert(1)</script>&\x00", canvas.Left)
+
+	for _, safe := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		svg := New(buf, 100.0, 100.0)
+		svg.SafeMode(safe)
+		svg.AddClass(`x" onload="alert(1)`)
+		svg.RenderText(text, canvas.Identity)
+		s := buf.String()
+
+		// text content is always escaped
+		test.That(t, strings.Contains(s, `&lt;/text&gt;&lt;script&gt;alert(1)&lt;/script&gt;&amp;`), s)
+		if safe {
+			test.That(t, !strings.Contains(s, "<script"), s)
+			test.That(t, !strings.Contains(s, "onload="), s)
+			test.That(t, !strings.Contains(s, "\x00"), s)
+			test.That(t, strings.Contains(s, `font-family:'evilstylescriptalert1script'`), s)
+			test.That(t, strings.Contains(s, ` class="xonloadalert1"`), s)
+		}
+	}
+}
+
+func TestSVGShadow(t *testing.T) {
+	buf := &bytes.Buffer{}
+	svg := New(buf, 20.0, 20.0)
+	buf.Reset()
+	svg.RenderShadow(canvas.Rectangle(4.0, 4.0), 1.0, canvas.Red, canvas.Identity.Translate(1.0, 2.0))
+	test.String(t, buf.String(), `<defs><filter id="s0" filterUnits="userSpaceOnUse" x="-2" y="11" width="10" height="10"><feGaussianBlur stdDeviation="1"/></filter></defs><path d="M1 18H5V14H1z" filter="url(#s0)" fill="#f00"/>`)
+}
+
+func TestSVGInsetShadow(t *testing.T) {
+	buf := &bytes.Buffer{}
+	svg := New(buf, 20.0, 20.0)
+	buf.Reset()
+	svg.RenderInsetShadow(canvas.Rectangle(4.0, 4.0), 1.0, -1.0, 1.0, canvas.Red, canvas.Identity.Translate(1.0, 2.0))
+	test.String(t, buf.String(), `<defs><filter id="s0" filterUnits="userSpaceOnUse" x="-3" y="10" width="12" height="12"><feFlood flood-color="#f00"/><feComposite in2="SourceAlpha" operator="out"/><feOffset dx="1" dy="1"/><feGaussianBlur stdDeviation="1"/><feComposite in2="SourceAlpha" operator="in"/></filter></defs><path d="M1 18H5V14H1z" filter="url(#s0)"/>`)
+}
+
+func TestSVGFlattenMethod(t *testing.T) {
+	buf := &bytes.Buffer{}
+	svg := New(buf, 20.0, 20.0)
+	svg.SetFlattenMethod(canvas.UniformSteps(2))
+	buf.Reset()
+	svg.RenderPath(canvas.MustParseSVG("M0 0Q5 10 10 0"), canvas.DefaultStyle, canvas.Identity.Translate(0.0, 5.0))
+	test.String(t, buf.String(), `<path d="M0 15L5 10L10 15"/>`)
+}
+
+func TestSVGPages(t *testing.T) {
+	family := canvas.NewFontFamily("dejavu-serif")
+	family.LoadFontFile("../font/DejaVuSerif.ttf", canvas.FontRegular)
+	face := family.Face(12.0, canvas.Black, canvas.FontRegular, canvas.FontNormal)
+	rt := canvas.NewRichText()
+	rt.Add(face, strings.Repeat("Lorem ipsum dolor sit amet. ", 20))
+
+	// paginate the text over linked frames of a page each
+	area := canvas.Rectangle(40.0, 20.0)
+	first := canvas.NewTextFrame(area, canvas.Left, canvas.Top)
+	frames := []*canvas.TextFrame{first}
+	for frame := first; len(frames) < 10; {
+		frame = frame.LinkTo(canvas.NewTextFrame(area, canvas.Left, canvas.Top))
+		frames = append(frames, frame)
+	}
+	test.That(t, first.Flow(rt) == nil, "text must fit in frames")
+
+	pages := []*canvas.Canvas{}
+	for _, frame := range frames {
+		if frame.Text() == nil || frame.Text().Empty() {
+			break
+		}
+		c := canvas.New(40.0, 20.0)
+		frame.Draw(canvas.NewContext(c))
+		pages = append(pages, c)
+	}
+	test.That(t, 1 < len(pages), "text must span several pages")
+
+	buf := &bytes.Buffer{}
+	test.Error(t, WritePages(buf, pages))
+	out := buf.String()
+	height := 20.0*float64(len(pages)) + 5.0*float64(len(pages)-1)
+	test.That(t, strings.HasPrefix(out, `<svg version="1.1" width="40mm" height="`+strconv.FormatFloat(height, 'g', -1, 64)+`mm"`), "wrong size:", out[:80])
+	test.T(t, strings.Count(out, `<svg class="page"`), len(pages))
+	test.T(t, strings.Count(out, `style="break-after:page"`), len(pages))
+	test.That(t, strings.Contains(out, `<svg class="page" y="25" width="40" height="20" viewBox="0 0 40 20"`), "second page must be below the first")
+	test.T(t, strings.Count(out, "@font-face"), 1)
+
+	test.That(t, WritePages(buf, nil) != nil, "must fail without pages")
+}
+
+func TestSVGPattern(t *testing.T) {
+	c := canvas.New(12.5, 8.0)
+	ctx := canvas.NewContext(c)
+	ctx.DrawPath(2.0, 2.0, canvas.Rectangle(4.0, 4.0))
+	ctx.DrawPath(10.0, 6.0, canvas.Rectangle(4.0, 4.0)) // partly outside the tile
+
+	test.String(t, Pattern(c, "tile\""), `<svg version="1.1" width="12.5mm" height="8mm" viewBox="0 0 12.5 8" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">`+
+		`<defs><pattern id="tile" patternUnits="userSpaceOnUse" width="12.5" height="8"><path d="M2 6H6V2H2z"/><path d="M10 2H14V-2H10z"/></pattern></defs>`+
+		`<rect width="12.5" height="8" fill="url(#tile)"/></svg>`)
+}
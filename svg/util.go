@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/tdewolff/canvas"
 	"github.com/tdewolff/minify/v2"
@@ -35,3 +36,59 @@ func (f dec) String() string {
 	}
 	return s
 }
+
+var textEscaper = strings.NewReplacer(`&`, `&amp;`, `<`, `&lt;`, `>`, `&gt;`, `"`, `&quot;`)
+
+// safeText replaces invalid UTF-8 and characters that are not allowed in XML 1.0 by the replacement character.
+func safeText(s string) string {
+	sb := strings.Builder{}
+	for i := 0; i < len(s); {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' || 0xD800 <= r && r <= 0xDFFF || r == 0xFFFE || r == 0xFFFF {
+			r = utf8.RuneError
+		}
+		sb.WriteRune(r)
+		i += n
+	}
+	return sb.String()
+}
+
+// safeName removes all characters from a name except for ASCII letters, digits, hyphens and underscores, and also spaces and dots for font family names.
+func safeName(s string, fontFamily bool) string {
+	sb := strings.Builder{}
+	for _, r := range s {
+		if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '-' || r == '_' || fontFamily && (r == ' ' || r == '.') {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func imagePaint(paint canvas.Paint) (canvas.ImagePaint, bool) {
+	switch p := paint.(type) {
+	case canvas.ImagePaint:
+		return p, true
+	case *canvas.ImagePaint:
+		return *p, true
+	}
+	return canvas.ImagePaint{}, false
+}
+
+// gradientPaint returns the gradient of the paint, dereferencing pointers to gradients.
+func gradientPaint(paint canvas.Paint) canvas.Paint {
+	switch p := paint.(type) {
+	case *canvas.LinearGradient:
+		return *p
+	case *canvas.RadialGradient:
+		return *p
+	}
+	return paint
+}
+
+func isGradient(paint canvas.Paint) bool {
+	switch gradientPaint(paint).(type) {
+	case canvas.LinearGradient, canvas.RadialGradient:
+		return true
+	}
+	return false
+}
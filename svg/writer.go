@@ -1,7 +1,11 @@
 package svg
 
 import (
+	"bytes"
+	"fmt"
 	"io"
+	"math"
+	"time"
 
 	"github.com/tdewolff/canvas"
 )
@@ -12,3 +16,91 @@ func Writer(w io.Writer, c *canvas.Canvas) error {
 	c.Render(svg)
 	return svg.Close()
 }
+
+// Pattern returns a standalone SVG file of the canvas as a repeating tile, such as to use it as a CSS background with background-image and background-repeat. The canvas is drawn in a pattern with the given ID of which the tile is the size of the canvas, so that tiles join seamlessly and content outside of the canvas is clipped. The SVG is filled with the pattern and has the size of the canvas, so that the tile can also be referenced as url(#id) from within the document.
+func Pattern(c *canvas.Canvas, id string) string {
+	b := &bytes.Buffer{}
+	id = safeName(id, false)
+	svg := New(b, c.W, c.H)
+	fmt.Fprintf(b, `<defs><pattern id="%s" patternUnits="userSpaceOnUse" width="%v" height="%v">`, id, dec(c.W), dec(c.H))
+	c.Render(svg)
+	fmt.Fprintf(b, `</pattern></defs><rect width="%v" height="%v" fill="url(#%s)"/>`, dec(c.W), dec(c.H), id)
+	svg.Close()
+	return b.String()
+}
+
+// WriteAnimation writes the canvases as frames of an animated SVG file (a flipbook), where each frame is shown for its duration, which must be positive, and the animation loops indefinitely. Frames are toggled using CSS keyframes on their visibility. The size of the SVG is the largest width and height of the frames.
+func WriteAnimation(w io.Writer, frames []*canvas.Canvas, durations []time.Duration) error {
+	if len(frames) != len(durations) {
+		return fmt.Errorf("number of frames and durations must be equal")
+	}
+
+	width, height := 0.0, 0.0
+	total := time.Duration(0)
+	for i, frame := range frames {
+		if durations[i] <= 0 {
+			return fmt.Errorf("frame duration must be positive")
+		}
+		width = math.Max(width, frame.W)
+		height = math.Max(height, frame.H)
+		total += durations[i]
+	}
+	if total == 0 {
+		return fmt.Errorf("must have at least one frame")
+	}
+
+	svg := New(w, width, height)
+	fmt.Fprintf(w, "<style>")
+	start := time.Duration(0)
+	for i := range frames {
+		end := start + durations[i]
+		t0 := 100.0 * float64(start) / float64(total)
+		t1 := 100.0 * float64(end) / float64(total)
+		fmt.Fprintf(w, "\n@keyframes f%d{%v%%{visibility:visible}", i, dec(t0))
+		if end < total {
+			fmt.Fprintf(w, "%v%%{visibility:hidden}", dec(t1))
+		}
+		fmt.Fprintf(w, "}")
+		fmt.Fprintf(w, "\n.f%d{visibility:hidden;animation:f%d %vs step-end infinite}", i, i, dec(total.Seconds()))
+		start = end
+	}
+	fmt.Fprintf(w, "\n</style>")
+
+	for i, frame := range frames {
+		fmt.Fprintf(w, `<g class="f%d">`, i)
+		frame.Render(svg)
+		fmt.Fprintf(w, "</g>")
+	}
+	return svg.Close()
+}
+
+// pageGap is the vertical distance in mm between pages, see WritePages.
+const pageGap = 5.0
+
+// WritePages writes the canvases as pages of a single SVG file, so that a document can be scrolled through or printed from one file. Each page is a nested SVG element of the size of its canvas with class "page" and a page-break hint for print style sheets, and pages are stacked vertically with a gap of 5mm between them. The page sizes are the same as for the pages written by pdf.WritePages. The size of the SVG is the largest width of the pages and the total height of the pages including the gaps.
+func WritePages(w io.Writer, pages []*canvas.Canvas) error {
+	if len(pages) == 0 {
+		return fmt.Errorf("no pages")
+	}
+
+	width, height := 0.0, 0.0
+	for i, page := range pages {
+		if 0 < i {
+			height += pageGap
+		}
+		width = math.Max(width, page.W)
+		height += page.H
+	}
+
+	svg := New(w, width, height)
+	y := 0.0
+	for _, page := range pages {
+		fmt.Fprintf(w, `<svg class="page" y="%v" width="%v" height="%v" viewBox="0 0 %v %v" style="break-after:page">`, dec(y), dec(page.W), dec(page.H), dec(page.W), dec(page.H))
+		svg.width, svg.height = page.W, page.H
+		page.Render(svg)
+		fmt.Fprintf(w, "</svg>")
+		y += page.H + pageGap
+	}
+	svg.width, svg.height = width, height
+	return svg.Close()
+}
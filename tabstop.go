@@ -0,0 +1,144 @@
+package canvas
+
+import (
+	"math"
+	"strings"
+)
+
+// TabStop is a horizontal position in mm relative to the start of a line that text following a tab character is aligned to, see RichText.SetTabStops. The text up to the next tab or the end of the line starts at the tab stop for Left, ends at it for Right, or is centered on it for Center. If Leader is set, such as "." for the dot leaders of a table of contents, the gap before the aligned text is filled by repeating the leader in the font face of the aligned text.
+type TabStop struct {
+	Pos    float64
+	Align  TextAlign
+	Leader string
+}
+
+// SetTabStops sets the tab stops that the text after each tab character is aligned to, in increasing order of position. Each tab advances to the first tab stop after the text before it, and tabs without a next tab stop are replaced by a space. Leaders are aligned to multiples of the leader width measured from the start of the line, so that the leaders of adjacent lines line up vertically, and only whole leaders that fit the gap are drawn. Tab stops only apply to lines that are already broken, so that the width of tab characters is used for line breaking.
+func (rt *RichText) SetTabStops(stops ...TabStop) {
+	rt.tabStops = stops
+}
+
+// tabField is the text between two tab characters of a line together with the number of tabs preceding it.
+type tabField struct {
+	tabs  int
+	spans []TextSpan
+}
+
+// applyTabStops positions the text of a line after its tab characters at the tab stops and adds the leaders. The tab characters are removed.
+func (rt *RichText) applyTabStops(spans []TextSpan) []TextSpan {
+	fields := []tabField{{}}
+	for _, span := range spans {
+		for {
+			i := tabBoundary(span)
+			if i == -1 {
+				break
+			}
+			tabs := strings.Count(span.Text[span.boundaries[i].pos:span.boundaries[i].pos+span.boundaries[i].size], "\t")
+			span0, span1 := span.split(i)
+			if span0.Text != "" {
+				fields[len(fields)-1].spans = append(fields[len(fields)-1].spans, span0)
+			}
+			fields = append(fields, tabField{tabs: tabs})
+			span = span1
+		}
+		if span.Text != "" {
+			fields[len(fields)-1].spans = append(fields[len(fields)-1].spans, span)
+		}
+	}
+	if len(fields) == 1 {
+		return spans
+	}
+
+	ss := []TextSpan{}
+	x := spans[0].dx
+	for _, field := range fields {
+		if len(field.spans) == 0 {
+			continue // empty text between tabs
+		}
+		face := field.spans[0].Face
+		w := 0.0
+		for _, span := range field.spans {
+			w += span.width
+		}
+
+		start := x
+		if 0 < field.tabs {
+			var stop *TabStop
+			for i := 0; i < field.tabs; i++ {
+				stop = nil
+				for j := range rt.tabStops {
+					if x+Epsilon < rt.tabStops[j].Pos {
+						stop = &rt.tabStops[j]
+						break
+					}
+				}
+				if stop == nil {
+					break
+				}
+				x = stop.Pos
+			}
+
+			if stop == nil {
+				start = x + face.TextWidth(" ")
+			} else {
+				if stop.Align == Right {
+					start = stop.Pos - w
+				} else if stop.Align == Center {
+					start = stop.Pos - w/2.0
+				} else {
+					start = stop.Pos
+				}
+				start = math.Max(start, prevEnd(ss, spans[0].dx))
+				if stop.Leader != "" {
+					if leader, ok := leaderSpan(face, stop.Leader, prevEnd(ss, spans[0].dx), start); ok {
+						ss = append(ss, leader)
+					}
+				}
+			}
+		}
+
+		x = start
+		for _, span := range field.spans {
+			span.dx = x
+			ss = append(ss, span)
+			x += span.width
+		}
+	}
+	if len(ss) == 0 {
+		return spans[:1]
+	}
+	return ss
+}
+
+// tabBoundary returns the index of the first word or sentence boundary of the span that contains a tab character, or -1 if there is none.
+func tabBoundary(span TextSpan) int {
+	for i, boundary := range span.boundaries {
+		if (boundary.kind == wordBoundary || boundary.kind == sentenceBoundary) && strings.ContainsRune(span.Text[boundary.pos:boundary.pos+boundary.size], '\t') {
+			return i
+		}
+	}
+	return -1
+}
+
+// prevEnd returns the end position of the last span, or x0 if there are no spans.
+func prevEnd(spans []TextSpan, x0 float64) float64 {
+	if len(spans) == 0 {
+		return x0
+	}
+	return spans[len(spans)-1].dx + spans[len(spans)-1].width
+}
+
+// leaderSpan returns a span of leaders that fills the gap between x0 and x1, where the leaders are aligned to multiples of their width. It returns false if not even a single leader fits.
+func leaderSpan(ff FontFace, leader string, x0, x1 float64) (TextSpan, bool) {
+	w := ff.TextWidth(leader)
+	if w <= 0.0 {
+		return TextSpan{}, false
+	}
+	k0, k1 := math.Ceil(x0/w-Epsilon), math.Floor(x1/w+Epsilon)
+	if k1 <= k0 {
+		return TextSpan{}, false
+	}
+	s := strings.Repeat(leader, int(k1-k0))
+	span := newTextSpan(ff, s, 0)
+	span.dx = k0 * w
+	return span, true
+}
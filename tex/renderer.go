@@ -14,8 +14,9 @@ type TeX struct {
 	w             io.Writer
 	width, height float64
 
-	style  canvas.Style
-	colors map[color.RGBA]string
+	style   canvas.Style
+	colors  map[color.RGBA]string
+	flatten canvas.FlattenMethod
 }
 
 // New creates a TeX/pgf renderer.
@@ -35,6 +36,11 @@ func (r *TeX) Close() error {
 	return err
 }
 
+// SetFlattenMethod sets the method used to flatten curves into linear segments, such as canvas.UniformSteps. By default curves are written as curves.
+func (r *TeX) SetFlattenMethod(method canvas.FlattenMethod) {
+	r.flatten = method
+}
+
 func (r *TeX) Size() (float64, float64) {
 	return r.width, r.height
 }
@@ -55,10 +61,13 @@ func (r *TeX) getColor(col color.RGBA) string {
 }
 
 func (r *TeX) RenderPath(path *canvas.Path, style canvas.Style, m canvas.Matrix) {
-	if path.Empty() {
+	if path.Empty() || style.CompositeOp.Erases() {
 		return
 	}
 	path = path.Transform(m)
+	if r.flatten != nil {
+		path = path.FlattenWith(r.flatten)
+	}
 	path = path.ReplaceArcs()
 
 	path.Iterate(func(start, end canvas.Point) {
@@ -4,8 +4,11 @@ import (
 	"image/color"
 	"math"
 	"sort"
+	"strings"
 	"unicode"
 	"unicode/utf8"
+
+	"golang.org/x/image/font/sfnt"
 )
 
 // MaxSentenceSpacing is the maximum amount times the x-height of the font that sentence spaces can expand.
@@ -17,6 +20,17 @@ const MaxWordSpacing = 2.5
 // MaxGlyphSpacing is the maximum amount times the x-height of the font that glyphs can be spaced.
 const MaxGlyphSpacing = 0.5
 
+// Overflow specifies how words that are wider than the text box are laid out.
+type Overflow int
+
+// see Overflow
+const (
+	OverflowVisible       Overflow = iota // the word extends beyond the text box
+	OverflowClip                          // the word is truncated at the edge of the text box
+	OverflowBreakAnywhere                 // the word is broken between any two characters
+	OverflowScale                         // the word is scaled down to fit the text box
+)
+
 // TextAlign specifies how the text should align or whether it should be justified.
 type TextAlign int
 
@@ -34,6 +48,9 @@ type line struct {
 	spans []TextSpan
 	decos []decoSpan
 	y     float64
+
+	paragraphEnd bool    // line ends with a newline, which is not justified
+	left, right  float64 // widths taken by floats at the sides of the line
 }
 
 func (l line) Heights() (float64, float64, float64, float64) {
@@ -50,15 +67,60 @@ func (l line) Heights() (float64, float64, float64, float64) {
 
 ////////////////////////////////////////////////////////////////
 
+// TextLayer is a layer of text that is drawn separately, see Text.SetDrawOrder.
+type TextLayer int
+
+// see TextLayer
+const (
+	TextShadow          TextLayer = iota // shadow of the glyphs and decorations
+	TextDecorationUnder                  // decorations that are drawn under the glyphs, such as underlines and overlines
+	TextStroke                           // outline of the glyphs
+	TextFill                             // glyphs
+	TextDecorationOver                   // decorations that are drawn over the glyphs, such as strikethroughs
+)
+
+// DefaultTextDrawOrder is the order in which the layers of text are drawn by default.
+var DefaultTextDrawOrder = []TextLayer{TextShadow, TextDecorationUnder, TextStroke, TextFill, TextDecorationOver}
+
 // Text holds the representation of text using lines and text spans.
 type Text struct {
 	lines []line
 	fonts map[*Font]bool
+
+	shadowOffset Point
+	shadowColor  color.RGBA
+	strokeColor  color.RGBA
+	strokeWidth  float64
+	order        []TextLayer
+	opacity      func(int, float64) float64
+
+	err error
 }
 
 // NewTextLine is a simple text line using a font face, a string (supporting new lines) and horizontal alignment (Left, Center, Right).
 func NewTextLine(ff FontFace, s string, halign TextAlign) *Text {
+	if err := checkTextLimits(len(s), utf8.RuneCountInString(s)); err != nil {
+		return &Text{lines: []line{}, fonts: map[*Font]bool{}, err: err}
+	}
 	ascent, descent, spacing := ff.Metrics().Ascent, ff.Metrics().Descent, ff.Metrics().LineHeight-ff.Metrics().Ascent-ff.Metrics().Descent
+	s = ff.Font.cleanText(s, 0)
+	runs, err := ff.featureRuns(s)
+	if err != nil {
+		return &Text{lines: []line{}, fonts: map[*Font]bool{}, err: err}
+	} else if runs == nil {
+		runs = []featureRun{{ff, s}}
+	}
+	runs = ff.fallbackRuns(runs)
+
+	// runs of synthesized features and fallback fonts have their own font face and end at the given positions
+	s = ""
+	ends := make([]int, len(runs))
+	fonts := map[*Font]bool{ff.Font: true}
+	for k, run := range runs {
+		s += run.Face.substituteLigatures(run.Text)
+		ends[k] = len(s)
+		fonts[run.Face.Font] = true
+	}
 
 	i := 0
 	y := 0.0
@@ -68,16 +130,37 @@ func NewTextLine(ff FontFace, s string, halign TextAlign) *Text {
 			j := boundary.pos + boundary.size
 			if i < j {
 				l := line{y: y}
-				span := newTextSpan(ff, s[:j], i)
+				width := 0.0
+				for k, a := 0, i; a < j; k++ {
+					if ends[k] <= a {
+						continue
+					}
+					b := ends[k]
+					if j < b {
+						b = j
+					}
+					span := newTextSpan(runs[k].Face, s[:b], a)
+					span.dx = width
+					width += span.width
+					l.spans = append(l.spans, span)
+					if len(runs[k].Face.deco) != 0 {
+						l.decos = append(l.decos, decoSpan{runs[k].Face, span.dx, span.dx + span.width})
+					}
+					a = b
+				}
+
+				dx := 0.0
 				if halign == Center {
-					span.dx = -span.width / 2.0
+					dx = -width / 2.0
 				} else if halign == Right {
-					span.dx = -span.width
+					dx = -width
 				}
-
-				l.spans = append(l.spans, span)
-				if len(ff.deco) != 0 {
-					l.decos = append(l.decos, decoSpan{ff, span.dx, span.dx + span.width})
+				for k := range l.spans {
+					l.spans[k].dx += dx
+				}
+				for k := range l.decos {
+					l.decos[k].x0 += dx
+					l.decos[k].x1 += dx
 				}
 				lines = append(lines, l)
 			}
@@ -85,7 +168,7 @@ func NewTextLine(ff FontFace, s string, halign TextAlign) *Text {
 			i = j
 		}
 	}
-	return &Text{lines, map[*Font]bool{ff.Font: true}}
+	return &Text{lines: lines, fonts: fonts}
 }
 
 // NewTextBox is an advanced text formatter that will calculate text placement based on the setteings. It takes a font face, a string, the width or height of the box (can be zero for no limit), horizontal and vertical alignment (Left, Center, Right, Top, Bottom or Justify), text indentation for the first line and line stretch (percentage to stretch the line based on the line height).
@@ -93,11 +176,48 @@ func NewTextBox(ff FontFace, s string, width, height float64, halign, valign Tex
 	return NewRichText().Add(ff, s).ToText(width, height, halign, valign, indent, lineStretch)
 }
 
+// SingleLine returns a text of a single line using the font face, which is a fast path for NewTextBox with zero width and height for the common case of short strings such as labels. It skips the segmentation of the text into words and sentences and produces the same layout as NewTextBox. When the string contains new lines or the font face enables features or has fallback fonts, it falls back to NewTextBox.
+func (ff FontFace) SingleLine(s string) *Text {
+	if err := checkTextLimits(len(s), utf8.RuneCountInString(s)); err != nil {
+		return &Text{lines: []line{}, fonts: map[*Font]bool{}, err: err}
+	}
+	if strings.IndexFunc(s, isNewline) != -1 || strings.TrimFunc(s, isWhitespace) == "" || len(ff.Features) != 0 || len(ff.Fallbacks) != 0 {
+		return NewTextBox(ff, s, 0.0, 0.0, Left, Top, 0.0, 0.0)
+	}
+	s = strings.TrimFunc(ff.prepareText(s, 0), isWhitespace)
+
+	span := TextSpan{
+		Face:       ff,
+		Text:       s,
+		width:      ff.TextWidth(s),
+		boundaries: []textBoundary{{eofBoundary, len(s), 0}},
+	}
+	l := line{spans: []TextSpan{span}, decos: []decoSpan{}, y: -ff.Metrics().Ascent}
+	if len(ff.deco) != 0 {
+		l.decos = append(l.decos, decoSpan{ff, 0.0, span.width})
+	}
+	return &Text{lines: []line{l}, fonts: map[*Font]bool{ff.Font: true}}
+}
+
 // RichText allows to build up a rich text with text spans of different font faces and by fitting that into a box.
 type RichText struct {
 	spans []TextSpan
 	fonts map[*Font]bool
 	text  string
+
+	riverPenalty float64
+	firstLine    *FontFace
+	firstLetter  *FontFace
+	overflow     Overflow
+	trailingLine bool
+	kashida      bool
+	hanging      bool
+	tabStops     []TabStop
+	floats       []TextFloat
+	region       []*Polyline // closed region relative to the top-left of the text box, see ToTextInPath
+
+	glyphs int
+	err    error
 }
 
 // NewRichText returns a new RichText.
@@ -107,15 +227,48 @@ func NewRichText() *RichText {
 	}
 }
 
-// Add adds a new text span element.
+// Add adds a new text span element. Newlines in s always force a line break, also when the text is wrapped to a width, and consecutive newlines result in empty lines in between. Features of the font face that the font does not have are synthesized by adding spans in derived font faces, see FontFace.Features, and characters that the font has no glyph for are added in spans of the first fallback font that has the glyph, see FontFace.Fallbacks.
 func (rt *RichText) Add(ff FontFace, s string) *RichText {
+	if rt.err != nil {
+		return rt
+	}
+	runs, err := ff.featureRuns(s)
+	if err != nil {
+		rt.err = err
+		return rt
+	} else if runs == nil {
+		runs = []featureRun{{ff, s}}
+	}
+	for _, run := range ff.fallbackRuns(runs) {
+		rt.add(run.Face, run.Text)
+	}
+	return rt
+}
+
+func (rt *RichText) add(ff FontFace, s string) *RichText {
+	if rt.err != nil {
+		return rt
+	}
+	glyphs := utf8.RuneCountInString(s)
+	if err := checkTextLimits(len(rt.text)+len(s), rt.glyphs+glyphs); err != nil {
+		rt.err = err
+		return rt
+	}
+	rt.glyphs += glyphs
+
+	rLast := rune(0)
+	if 0 < len(rt.text) {
+		rLast, _ = utf8.DecodeLastRuneInString(rt.text)
+	}
+	s = ff.prepareText(s, rLast)
+
 	if 0 < len(s) {
 		rPrev := ' '
 		rNext, size := utf8.DecodeRuneInString(s)
 		if 0 < len(rt.text) {
 			rPrev, _ = utf8.DecodeLastRuneInString(rt.text)
 		}
-		if isWhitespace(rPrev) && isWhitespace(rNext) {
+		if isWhitespace(rPrev) && isWhitespace(rNext) && !isNewline(rNext) {
 			s = s[size:]
 		}
 	}
@@ -156,25 +309,280 @@ func (rt *RichText) Add(ff FontFace, s string) *RichText {
 	return rt
 }
 
+// Err returns ErrLimitExceeded if the added text exceeds MaxTextLength or MaxGlyphs, in which case the text that exceeds the limits was not added.
+func (rt *RichText) Err() error {
+	return rt.err
+}
+
+// SetRiverPenalty sets the penalty for word spaces of justified lines that align vertically with word spaces of the previous line, which form rivers of white space running through a paragraph. When positive, the line breaker considers breaking a line one word earlier if that reduces the number of aligned spaces without making the line too loose. The penalty is weighed for each aligned space against the looseness of the line, which ranges from 0 (not stretched) to 100 (maximally stretched). When rivers are unavoidable, such as in narrow columns, the default line breaks are kept. A penalty of zero (the default) disables river avoidance.
+func (rt *RichText) SetRiverPenalty(penalty float64) {
+	rt.riverPenalty = penalty
+}
+
+// SetFirstLineFace sets the font face of the first line of the text, similar to the ::first-line pseudo-element in CSS. As the contents of the first line depend on its font face, the override is applied while breaking lines and the first line is measured and aligned in the given font face. The remaining text of a span continues on the next line in its own font face.
+func (rt *RichText) SetFirstLineFace(ff FontFace) {
+	rt.firstLine = &ff
+	rt.fonts[ff.Font] = true
+}
+
+// SetFirstLetterFace sets the font face of the first letter of the text, including any punctuation that precedes it, similar to the ::first-letter pseudo-element in CSS. It takes precedence over the font face of the first line.
+func (rt *RichText) SetFirstLetterFace(ff FontFace) {
+	rt.firstLetter = &ff
+	rt.fonts[ff.Font] = true
+}
+
+// textSpans returns the text spans of the rich text, where the first letter is split off into its own span when it has an override font face. It returns true if the first letter was split off.
+func (rt *RichText) textSpans() ([]TextSpan, bool) {
+	if rt.firstLetter == nil {
+		return rt.spans, false
+	}
+
+	span := rt.spans[0].TrimLeft()
+	n := 0
+	for i, r := range span.Text {
+		n = i + utf8.RuneLen(r)
+		if !unicode.IsPunct(r) || isNewline(r) {
+			break
+		}
+	}
+	if n == 0 || isWhitespace([]rune(span.Text[:n])[0]) {
+		return rt.spans, false
+	}
+
+	letter, rest := span.splitAt(n)
+	spans := []TextSpan{letter.withFace(*rt.firstLetter)}
+	if rest.Text != "" {
+		spans = append(spans, rest)
+	}
+	return append(spans, rt.spans[1:]...), true
+}
+
+// SetTrailingNewline sets whether a newline at the end of the text is followed by an empty line, as a text editor would show. By default the trailing newline is trimmed and only ends the last line.
+func (rt *RichText) SetTrailingNewline(keep bool) {
+	rt.trailingLine = keep
+}
+
+// SetOverflow sets how words are laid out that do not fit the width of the text box by themselves, such as long URLs. By default they extend beyond the box (OverflowVisible). Characters are never separated from their combining marks or from the other characters of an emoji sequence.
+func (rt *RichText) SetOverflow(overflow Overflow) {
+	rt.overflow = overflow
+}
+
+// SetKashida sets whether justified lines of Arabic text are stretched by elongating the connections between letters with tatweel characters (kashida), before the spaces between words are stretched. Tatweels are inserted at the last connection of each group of connected letters first and then at the other connections, and each connection is elongated by at most the maximum word spacing (see MaxWordSpacing). The remaining width is justified by stretching spaces as usual, which is also the case for text in fonts without a tatweel glyph.
+func (rt *RichText) SetKashida(kashida bool) {
+	rt.kashida = kashida
+}
+
+// SetHangingPunctuation sets whether punctuation at the start and end of lines hangs into the margins, so that the edges of the text look optically straight (optical margin alignment). Quotes, periods, commas and hyphens hang by their full advance, and colons, semicolons, dashes and guillemets partly, see hangingFraction. Lines are broken as without hanging punctuation, and the hanging punctuation at the ends of justified lines extends the width to which the line is justified.
+func (rt *RichText) SetHangingPunctuation(hanging bool) {
+	rt.hanging = hanging
+}
+
+// hangingFraction returns the fraction of the advance of a punctuation character that hangs into the margin at the start or end of a line, see RichText.SetHangingPunctuation.
+func hangingFraction(r rune) float64 {
+	switch r {
+	case '.', ',', '-', '\u2010', '\u2011', '"', '\'', '\u2018', '\u2019', '\u201A', '\u201C', '\u201D', '\u201E', '\u3001', '\u3002':
+		return 1.0
+	case ':', ';', '\u2013', '\u00AB', '\u00BB', '\u2039', '\u203A':
+		return 0.5
+	case '\u2014':
+		return 0.25
+	}
+	return 0.0
+}
+
+// hang returns the widths by which the first and last characters of the line hang into the left and right margins, which scale with the size of their font face, see RichText.SetHangingPunctuation.
+func (rt *RichText) hang(l line) (float64, float64) {
+	if !rt.hanging || len(l.spans) == 0 {
+		return 0.0, 0.0
+	}
+	lead, trail := 0.0, 0.0
+	if first := l.spans[0]; first.Text != "" {
+		r, _ := utf8.DecodeRuneInString(first.Text)
+		if fraction := hangingFraction(r); fraction != 0.0 {
+			lead = fraction * first.Face.TextWidth(string(r))
+		}
+	}
+	if last := l.spans[len(l.spans)-1]; last.Text != "" {
+		r, _ := utf8.DecodeLastRuneInString(last.Text)
+		if fraction := hangingFraction(r); fraction != 0.0 {
+			trail = fraction * last.Face.TextWidth(string(r))
+		}
+	}
+	return lead, trail
+}
+
+// overflowWord lays out the first word of a span that does not fit the width of an empty line according to the overflow policy, and returns the span for the current line and optionally the remaining span for the next line.
+func (rt *RichText) overflowWord(span TextSpan, width float64) []TextSpan {
+	if span.boundaries[0].pos == 0 {
+		return []TextSpan{span} // starts with a line boundary
+	}
+
+	word, spans := span, []TextSpan{span}
+	if span.boundaries[0].kind != eofBoundary {
+		var rest TextSpan
+		word, rest = span.split(0)
+		spans = []TextSpan{word, rest}
+	}
+	if width <= 0.0 {
+		return spans
+	}
+
+	switch rt.overflow {
+	case OverflowClip:
+		n, _ := word.fit(width)
+		spans[0], _ = word.splitAt(n)
+	case OverflowBreakAnywhere:
+		n, breaks := word.fit(width)
+		if n == 0 && 0 < len(breaks) {
+			n = breaks[0] // place at least one character on each line
+		}
+		if n != 0 && n < len(word.Text) {
+			span0, span1 := span.splitAt(n)
+			spans = []TextSpan{span0, span1}
+		}
+	case OverflowScale:
+		// the width scales linearly with the font size in pixels per em, which is truncated to 1/64, up to the rounding of each glyph advance and kerning to 1/64 millimeter
+		ff := word.Face
+		ppem := fromI26_6(toI26_6(ff.Size * ff.Scale))
+		margin := float64(utf8.RuneCountInString(word.Text)) / 64.0
+		ff.Size = ppem * math.Max(0.0, width-margin) / (word.width + margin) / ff.Scale
+		spans[0] = word.withFace(ff)
+	}
+	return spans
+}
+
+// lineGaps returns the centers and widths of the word and sentence spaces of a line when justified to width, and the looseness of the line which is 100 times the cube of the ratio of the extra width over the maximum stretch. The looseness is infinite if the line cannot be justified.
+func (rt *RichText) lineGaps(spans []TextSpan, width float64) ([]float64, []float64, float64) {
+	naturalWidth, stretch := 0.0, 0.0
+	for i, span := range spans {
+		if i == 0 {
+			naturalWidth += span.dx
+		}
+		naturalWidth += span.width
+
+		sentences, words := 0, 0
+		for _, boundary := range span.boundaries {
+			if boundary.kind == sentenceBoundary {
+				sentences++
+			} else if boundary.kind == wordBoundary {
+				words++
+			}
+		}
+		glyphs := span.CountGlyphs()
+		if i+1 == len(spans) {
+			glyphs--
+		}
+		xHeight := span.Face.Metrics().XHeight
+		stretch += (float64(sentences)*MaxSentenceSpacing + float64(words)*MaxWordSpacing + float64(glyphs)*MaxGlyphSpacing) * xHeight
+	}
+
+	looseness := math.Inf(1)
+	if extra := width - naturalWidth; extra <= 0.0 {
+		looseness = 0.0
+	} else if extra <= stretch {
+		looseness = 100.0 * math.Pow(extra/stretch, 3.0)
+	}
+
+	l := line{spans: append([]TextSpan{}, spans...)}
+	rt.halign([]line{l}, true, width, Justify)
+
+	centers, widths := []float64{}, []float64{}
+	for _, span := range l.spans {
+		spanCenters, spanWidths := span.gaps()
+		for i := range spanCenters {
+			centers = append(centers, span.dx+spanCenters[i])
+			widths = append(widths, spanWidths[i])
+		}
+	}
+	return centers, widths, looseness
+}
+
+// riverCost returns the cost of a candidate line given the spaces of the previous line, which is the looseness of the line plus the penalty for each space that overlaps with a space of the previous line.
+func (rt *RichText) riverCost(spans []TextSpan, width float64, prevCenters, prevWidths []float64) float64 {
+	centers, widths, looseness := rt.lineGaps(spans, width)
+	aligned := 0
+	for i, center := range centers {
+		for j, prevCenter := range prevCenters {
+			if math.Abs(center-prevCenter) < (widths[i]+prevWidths[j])/2.0 {
+				aligned++
+				break
+			}
+		}
+	}
+	return looseness + rt.riverPenalty*float64(aligned)
+}
+
+// avoidRiver returns the break one word earlier than the greedy break of the line spans ss if it lowers the river cost, as the index of the span in ss and of its boundary. The earlier break may be in any span of the line.
+func (rt *RichText) avoidRiver(prev line, ss []TextSpan, width float64) (int, int, bool) {
+	prevCenters, prevWidths, _ := rt.lineGaps(prev.spans, width)
+	cost := func(spans []TextSpan) float64 {
+		spans = append(spans[:len(spans)-1:len(spans)-1], spans[len(spans)-1].TrimRight())
+		return rt.riverCost(spans, width, prevCenters, prevWidths)
+	}
+	for j := len(ss) - 1; 0 <= j; j-- {
+		span := ss[j]
+		for i := len(span.boundaries) - 2; 0 <= i; i-- {
+			boundary := span.boundaries[i]
+			if j == 0 && boundary.pos == 0 {
+				return 0, 0, false
+			} else if boundary.kind != wordBoundary && boundary.kind != sentenceBoundary || j+1 == len(ss) && boundary.pos+boundary.size == len(span.Text) {
+				continue // not a word boundary or it is the greedy break
+			}
+
+			span0, _ := span.split(i)
+			if cost(append(ss[:j:j], span0)) < cost(ss) {
+				return j, i, true
+			}
+			return 0, 0, false
+		}
+	}
+	return 0, 0, false
+}
+
 func (rt *RichText) halign(lines []line, yoverflow bool, width float64, halign TextAlign) {
 	if halign == Right || halign == Center {
 		for _, l := range lines {
 			firstSpan := l.spans[0]
 			lastSpan := l.spans[len(l.spans)-1]
-			dx := width - lastSpan.dx - lastSpan.width - firstSpan.dx
+			dx := width - l.right - lastSpan.dx - lastSpan.width - (firstSpan.dx - l.left)
 			if halign == Center {
 				dx /= 2.0
 			}
+			if lead, trail := rt.hang(l); halign == Right {
+				dx += trail
+			} else {
+				dx += (trail - lead) / 2.0
+			}
 			for i := range l.spans {
 				l.spans[i].dx += dx
 			}
 		}
-	} else if 0.0 < width && halign == Justify {
+		return
+	}
+
+	// hang the punctuation at the start of lines into the left margin
+	for _, l := range lines {
+		if lead, _ := rt.hang(l); lead != 0.0 {
+			for i := range l.spans {
+				l.spans[i].dx -= lead
+			}
+		}
+	}
+	if 0.0 < width && halign == Justify {
 		n := len(lines) - 1
 		if yoverflow {
 			n++
 		}
 		for _, l := range lines[:n] {
+			if l.paragraphEnd {
+				continue // the last line of each paragraph is not justified
+			}
+			_, trail := rt.hang(l)
+			width := width - l.right + trail
+			if rt.kashida {
+				insertKashidas(l, width)
+			}
+
 			// get the width range of our spans (eg. for text width can increase with extra character spacing)
 			textWidth, maxSentenceSpacing, maxWordSpacing, maxGlyphSpacing := 0.0, 0.0, 0.0, 0.0
 			for i, span := range l.spans {
@@ -261,6 +669,112 @@ func (rt *RichText) halign(lines []line, yoverflow bool, width float64, halign T
 	}
 }
 
+// kashidaJoin is a position in a span between two joining Arabic letters where tatweels can be inserted.
+type kashidaJoin struct {
+	span, pos int
+	n         int // number of tatweels to insert
+}
+
+// insertKashidas inserts tatweels at the connections between Arabic letters of the line to stretch it towards width.
+func insertKashidas(l line, width float64) {
+	textWidth := l.spans[0].dx
+	for _, span := range l.spans {
+		textWidth += span.width
+	}
+	extra := width - textWidth
+	if extra <= Epsilon {
+		return
+	}
+
+	// the last connection of each group of connected letters is preferred
+	preferred, others := []kashidaJoin{}, []kashidaJoin{}
+	tatweels := make([]float64, len(l.spans))
+	for i, span := range l.spans {
+		if coverage, _ := span.Face.CanRender("\u0640"); coverage != 1.0 {
+			continue
+		}
+		tatweels[i] = span.Face.TextWidth("\u0640")
+
+		last := -1
+		prev := rune(0)
+		for pos, r := range span.Text {
+			if unicode.Is(unicode.Mn, r) {
+				continue // marks are transparent to joining
+			} else if arabicJoinsNext(prev) && arabicJoinsPrev(r) {
+				if last != -1 {
+					others = append(others, kashidaJoin{i, last, 0})
+				}
+				last = pos
+			} else if last != -1 {
+				preferred = append(preferred, kashidaJoin{i, last, 0})
+				last = -1
+			}
+			prev = r
+		}
+		if last != -1 {
+			preferred = append(preferred, kashidaJoin{i, last, 0})
+		}
+	}
+	joins := append(preferred, others...)
+	if len(joins) == 0 {
+		return
+	}
+
+	// distribute tatweels over the connections in turn
+	for inserted := true; inserted; {
+		inserted = false
+		for j, join := range joins {
+			tatweel := tatweels[join.span]
+			maxWidth := MaxWordSpacing * l.spans[join.span].Face.Metrics().XHeight
+			if 0.0 < tatweel && tatweel <= extra && float64(join.n+1)*tatweel <= maxWidth {
+				joins[j].n++
+				extra -= tatweel
+				inserted = true
+			}
+		}
+	}
+
+	sort.SliceStable(joins, func(i, j int) bool {
+		if joins[i].span != joins[j].span {
+			return joins[i].span < joins[j].span
+		}
+		return joins[i].pos > joins[j].pos
+	})
+	for _, join := range joins {
+		if join.n == 0 {
+			continue
+		}
+		span := l.spans[join.span]
+		s := strings.Repeat("\u0640", join.n)
+		span.Text = span.Text[:join.pos] + s + span.Text[join.pos:]
+		span.boundaries = append([]textBoundary{}, span.boundaries...)
+		for k := range span.boundaries {
+			if join.pos <= span.boundaries[k].pos {
+				span.boundaries[k].pos += len(s)
+			}
+		}
+		span.width = span.Face.TextWidth(span.Text)
+		l.spans[join.span] = span
+	}
+}
+
+// arabicJoinsNext returns true for Arabic letters that connect to the following letter, ie. dual-joining letters and the tatweel.
+func arabicJoinsNext(r rune) bool {
+	if !arabicJoinsPrev(r) {
+		return false
+	}
+	switch r {
+	case '\u0622', '\u0623', '\u0624', '\u0625', '\u0627', '\u0629', '\u062F', '\u0630', '\u0631', '\u0632', '\u0648', '\u0671', '\u0672', '\u0673', '\u0675', '\u0676', '\u0677', '\u06D2', '\u06D3', '\u06D5', '\u06EE', '\u06EF':
+		return false // right-joining letters such as alef, dal, reh, waw and teh marbuta
+	}
+	return !('\u0688' <= r && r <= '\u0699' || '\u06C0' <= r && r <= '\u06CB' || r == '\u06CD' || r == '\u06CF')
+}
+
+// arabicJoinsPrev returns true for Arabic letters that connect to the preceding letter, ie. dual- and right-joining letters and the tatweel.
+func arabicJoinsPrev(r rune) bool {
+	return r == '\u0640' || unicode.Is(unicode.Arabic, r) && unicode.IsLetter(r) && r != '\u0621' && r != '\u0674'
+}
+
 func (rt *RichText) valign(lines []line, h, height float64, valign TextAlign) {
 	dy := 0.0
 	extraLineSpacing := 0.0
@@ -301,49 +815,235 @@ func (rt *RichText) decorate(lines []line) {
 	}
 }
 
+// alignBaselines shifts the spans of a line vertically so that they align on its dominant baseline using the BASE tables of their fonts, such as for Latin text mixed with the hanging baseline of Devanagari. The dominant baseline is the default baseline of the script of the first span. Spans whose font has no BASE table or does not define the dominant baseline align on the alphabetic baseline of the first span.
+func alignBaselines(spans []TextSpan) {
+	if len(spans) < 2 {
+		return
+	}
+	script := textScript(spans[0].Text)
+	tag := spans[0].Face.defaultBaseline(script)
+	y, _ := spans[0].Face.baseline(script, tag)
+	yAlphabetic, _ := spans[0].Face.baseline(script, "romn")
+	for i := 1; i < len(spans); i++ {
+		spanScript := textScript(spans[i].Text)
+		if spanScript == "" {
+			spanScript = script // such as punctuation and digits
+		}
+		if spanY, ok := spans[i].Face.baseline(spanScript, tag); ok {
+			spans[i].Face.Voffset += y - spanY
+		} else {
+			spans[i].Face.Voffset += yAlphabetic
+		}
+	}
+}
+
+// ToTextInPath lays out the text within a closed region, such as a circle, where each line is as wide as the horizontal extent of the region over the height of the line. Lines at the top and bottom of the region that have no room for their first word are left empty. Concave regions may have several extents next to each other for a line, in which case the widest one is used. The text is aligned to the top of the region and should be drawn at the top-left corner of the bounds of the region, as for TextFrame.
+func (rt *RichText) ToTextInPath(region *Path, halign TextAlign, indent, lineStretch float64) *Text {
+	bounds := region.Bounds()
+	if bounds.W <= 0.0 || bounds.H <= 0.0 {
+		return &Text{lines: []line{}, fonts: rt.fonts, err: rt.err}
+	}
+	rt.setRegion(region, bounds)
+	text, _ := rt.toText(bounds.W, bounds.H, halign, Top, indent, lineStretch, false)
+	rt.region = nil
+	return text
+}
+
+// splitInPath lays out the text that fits within a closed region as ToTextInPath, and returns the remaining text as Split.
+func (rt *RichText) splitInPath(region *Path, halign TextAlign, indent, lineStretch float64) (*Text, *RichText) {
+	bounds := region.Bounds()
+	if bounds.W <= 0.0 || bounds.H <= 0.0 {
+		return &Text{lines: []line{}, fonts: rt.fonts, err: rt.err}, rt
+	}
+	rt.setRegion(region, bounds)
+	text, rest := rt.Split(bounds.W, bounds.H, halign, Top, indent, lineStretch)
+	rt.region = nil
+	return text, rest
+}
+
+// setRegion sets the region that the lines are fit into, relative to the top-left corner of its bounds, see RichText.regionMargins.
+func (rt *RichText) setRegion(region *Path, bounds Rect) {
+	rt.region = []*Polyline{}
+	for _, ps := range region.Translate(-bounds.X, -bounds.Y-bounds.H).Split() {
+		rt.region = append(rt.region, PolylineFromPath(ps.Close()))
+	}
+}
+
+// regionMargins returns the widths at the left and right side of a line that are outside of the region, where top and bottom are the distances from the top of the text box to the top and bottom of the line. It returns false when the region has no room for the line.
+func (rt *RichText) regionMargins(width, top, bottom float64) (float64, float64, bool) {
+	// the extent is narrowest at the top or bottom of the line or at a vertex of the region in between
+	ys := []float64{-top, -bottom}
+	for _, polyline := range rt.region {
+		for _, coord := range polyline.coords {
+			if -bottom < coord.Y && coord.Y < -top {
+				ys = append(ys, coord.Y)
+			}
+		}
+	}
+
+	intervals := [][2]float64(nil)
+	for _, y := range ys {
+		yIntervals := scanlineIntervals(rt.region, y, NonZero)
+		if intervals == nil {
+			intervals = yIntervals
+			continue
+		}
+
+		// intersect with the intervals of the previous scanlines
+		overlap := [][2]float64{}
+		for _, a := range intervals {
+			for _, b := range yIntervals {
+				if x0, x1 := math.Max(a[0], b[0]), math.Min(a[1], b[1]); x0 < x1 {
+					overlap = append(overlap, [2]float64{x0, x1})
+				}
+			}
+		}
+		intervals = overlap
+	}
+	if len(intervals) == 0 {
+		return 0.0, 0.0, false
+	}
+	widest := intervals[0]
+	for _, interval := range intervals[1:] {
+		if widest[1]-widest[0] < interval[1]-interval[0] {
+			widest = interval
+		}
+	}
+	return math.Max(0.0, widest[0]), math.Max(0.0, width-widest[1]), true
+}
+
 // ToText takes the added text spans and fits them within a given box of certain width and height.
 func (rt *RichText) ToText(width, height float64, halign, valign TextAlign, indent, lineStretch float64) *Text {
-	if len(rt.spans) == 0 {
-		return &Text{[]line{}, rt.fonts}
+	text, _ := rt.toText(width, height, halign, valign, indent, lineStretch, false)
+	return text
+}
+
+// Split lays out the text that fits within a box of certain width and height as ToText, and returns the remaining text as a new RichText that can be laid out in the next box, such as on the next page. The remainder keeps the font faces of its spans and the settings of rt, except for the first line and first letter faces which only apply to the first box. The first line is always placed even if it is higher than the box, so that splitting the remainder repeatedly always advances. The remainder is nil when all text fits.
+func (rt *RichText) Split(width, height float64, halign, valign TextAlign, indent, lineStretch float64) (*Text, *RichText) {
+	text, rest := rt.toText(width, height, halign, valign, indent, lineStretch, true)
+	if len(rest) == 0 {
+		return text, nil
+	}
+
+	overflow := NewRichText()
+	overflow.riverPenalty = rt.riverPenalty
+	overflow.overflow = rt.overflow
+	overflow.trailingLine = rt.trailingLine
+	overflow.kashida = rt.kashida
+	overflow.hanging = rt.hanging
+	overflow.tabStops = rt.tabStops
+	for _, span := range rest {
+		overflow.Add(span.Face, span.Text)
+	}
+	return text, overflow
+}
+
+// toText lays out the text spans as ToText. If split is true, the first line is placed even if it exceeds the height, and the spans that did not fit are returned.
+func (rt *RichText) toText(width, height float64, halign, valign TextAlign, indent, lineStretch float64, split bool) (*Text, []TextSpan) {
+	if rt.err != nil || len(rt.spans) == 0 {
+		return &Text{lines: []line{}, fonts: rt.fonts, err: rt.err}, nil
 	}
-	spans := []TextSpan{rt.spans[0]}
+	rtSpans, firstLetter := rt.textSpans()
+	spans := []TextSpan{rtSpans[0]}
 
-	k := 0 // index into rt.spans and rt.positions
+	k := 0 // index into rtSpans
 	lines := []line{}
 	yoverflow := false
 	y, prevLineSpacing := 0.0, 0.0
-	for k < len(rt.spans) {
-		dx := indent
+	addLine := func(l line) bool {
+		top, ascent, descent, bottom := l.Heights()
+		lineSpacing := math.Max(top-ascent, prevLineSpacing)
+		if len(lines) != 0 {
+			y -= lineSpacing * (1.0 + lineStretch)
+			y -= ascent * lineStretch
+		}
+		y -= ascent
+		l.y = y
+		y -= descent * (1.0 + lineStretch)
+		prevLineSpacing = bottom - descent
+
+		if height != 0.0 && y < -height && (!split || len(lines) != 0) {
+			yoverflow = true
+			return false
+		}
+		lines = append(lines, l)
+		return true
+	}
+	var rest []TextSpan // spans that did not fit when splitting
+	for k < len(rtSpans) {
+		// shorten the line by the floats it overlaps
+		top := -y
+		if len(lines) != 0 {
+			top += prevLineSpacing * (1.0 + lineStretch)
+		}
+		metrics := spans[0].Face.Metrics()
+		left, right := rt.floatMargins(width, top, top+metrics.Ascent+metrics.Descent)
+		if rt.region != nil {
+			// leave the line empty when there is no room in the region for its first word
+			regionLeft, regionRight, ok := rt.regionMargins(width, top, top+metrics.Ascent+metrics.Descent)
+			left, right = math.Max(left, regionLeft), math.Max(right, regionRight)
+			if room := width - right - left - indent; ok && 0.0 < room {
+				_, fits := spans[0].TrimLeft().Split(room)
+				_, fitsWidth := spans[0].TrimLeft().Split(width)
+				ok = fits || !fitsWidth
+			} else {
+				ok = false
+			}
+			if !ok {
+				if !addLine(line{spans: []TextSpan{newTextSpan(spans[0].Face, "", 0)}, decos: []decoSpan{}, paragraphEnd: true}) {
+					if split {
+						rest = append(append([]TextSpan{}, spans...), rtSpans[k+1:]...)
+					}
+					break
+				}
+				continue
+			}
+		}
+		lineWidth := width - right
+
+		dx := indent + left
 		indent = 0.0
 
 		// trim left spaces
 		spans[0] = spans[0].TrimLeft()
 		for spans[0].Text == "" {
 			// TODO: reachable?
-			if k+1 == len(rt.spans) {
+			if k+1 == len(rtSpans) {
 				break
 			}
 			k++
-			spans = []TextSpan{rt.spans[k]}
+			spans = []TextSpan{rtSpans[k]}
 			spans[0] = spans[0].TrimLeft()
 		}
+		kLine, spansLine := k, append([]TextSpan{}, spans...)
 
 		// accumulate line spans for a full line, ie. either split span1 to fit or if it fits retrieve the next span1 and repeat
 		ss := []TextSpan{}
+		var face *FontFace // original font face of the span when overridden for the first line
+		newline := false
 		for {
+			if len(lines) == 0 && rt.firstLine != nil && (!firstLetter || k != 0) {
+				orig := spans[0].Face
+				face = &orig
+				spans[0] = spans[0].withFace(*rt.firstLine)
+			}
+
 			// space or inter-word splitting
 			if width != 0.0 && len(spans) == 1 {
 				// there is a width limit and we have only one (unsplit) span to process
 				var ok bool
-				spans, ok = spans[0].Split(width - dx)
+				spans, ok = spans[0].Split(lineWidth - dx)
 				if !ok && len(ss) != 0 {
 					// span couln't fit but this line already has a span, try next line
 					break
+				} else if !ok {
+					// the first word of the span doesn't fit on an empty line
+					spans = rt.overflowWord(spans[0], lineWidth-dx)
 				}
 			}
 
 			// if this span ends with a newline, split off that newline boundary
-			newline := 1 < len(spans[0].boundaries) && spans[0].boundaries[len(spans[0].boundaries)-2].kind == lineBoundary
+			newline = spans[0].endsWithNewline()
 			if newline {
 				spans[0], _ = spans[0].split(len(spans[0].boundaries) - 2)
 			}
@@ -354,11 +1054,12 @@ func (rt *RichText) ToText(width, height float64, halign, valign TextAlign, inde
 
 			spans = spans[1:]
 			if len(spans) == 0 {
+				face = nil
 				k++
-				if k == len(rt.spans) {
+				if k == len(rtSpans) {
 					break
 				}
-				spans = []TextSpan{rt.spans[k]}
+				spans = []TextSpan{rtSpans[k]}
 			} else {
 				break // span couldn't fully fit, we have a full line
 			}
@@ -367,6 +1068,27 @@ func (rt *RichText) ToText(width, height float64, halign, valign TextAlign, inde
 			}
 		}
 
+		if face != nil && 0 < len(spans) {
+			spans[0] = spans[0].withFace(*face) // the remainder of the span continues on the next line
+		}
+
+		// break the line one word earlier if that avoids a river, the remainder continues on the next line
+		if 0.0 < rt.riverPenalty && halign == Justify && width != 0.0 && len(lines) != 0 && !newline && 0 < len(spans) {
+			if j, i, ok := rt.avoidRiver(lines[len(lines)-1], ss, lineWidth); ok {
+				// ss[j] is the start of the first span of the line or of a span of rtSpans, which has the same boundaries up to the break
+				k = kLine + j
+				span := rtSpans[k]
+				if j == 0 {
+					span = spansLine[0]
+				}
+				span.dx = ss[j].dx
+				var span1 TextSpan
+				ss[j], span1 = span.split(i)
+				ss = ss[:j+1]
+				spans = []TextSpan{span1}
+			}
+		}
+
 		// trim right spaces
 		for 0 < len(ss) {
 			ss[len(ss)-1] = ss[len(ss)-1].TrimRight()
@@ -377,27 +1099,25 @@ func (rt *RichText) ToText(width, height float64, halign, valign TextAlign, inde
 			}
 		}
 
-		l := line{ss, []decoSpan{}, 0.0}
-		top, ascent, descent, bottom := l.Heights()
-		lineSpacing := math.Max(top-ascent, prevLineSpacing)
-		if len(lines) != 0 {
-			y -= lineSpacing * (1.0 + lineStretch)
-			y -= ascent * lineStretch
+		if 0 < len(rt.tabStops) {
+			ss = rt.applyTabStops(ss)
 		}
-		y -= ascent
-		l.y = y
-		y -= descent * (1.0 + lineStretch)
-		prevLineSpacing = bottom - descent
-
-		if height != 0.0 && y < -height {
-			yoverflow = true
+		alignBaselines(ss)
+		if !addLine(line{spans: ss, decos: []decoSpan{}, paragraphEnd: newline, left: left, right: right}) {
+			if split {
+				rest = append(spansLine, rtSpans[kLine+1:]...)
+			}
 			break
 		}
-		lines = append(lines, l)
+	}
+
+	// add an empty line after a trailing newline
+	if lastSpan := rtSpans[len(rtSpans)-1]; rt.trailingLine && !yoverflow && lastSpan.endsWithNewline() {
+		addLine(line{spans: []TextSpan{newTextSpan(lastSpan.Face, "", 0)}, decos: []decoSpan{}})
 	}
 
 	if len(lines) == 0 {
-		return &Text{lines, rt.fonts}
+		return &Text{lines: lines, fonts: rt.fonts}, rest
 	}
 
 	// apply horizontal alignment
@@ -409,7 +1129,12 @@ func (rt *RichText) ToText(width, height float64, halign, valign TextAlign, inde
 	// set decorations
 	rt.decorate(lines)
 
-	return &Text{lines, rt.fonts}
+	return &Text{lines: lines, fonts: rt.fonts}, rest
+}
+
+// Err returns ErrLimitExceeded if the text passed to layout exceeds MaxTextLength or MaxGlyphs, in which case the text is empty.
+func (t *Text) Err() error {
+	return t.err
 }
 
 // Empty is true if there are no text lines or no text spans.
@@ -462,6 +1187,36 @@ func (t *Text) OutlineBounds() Rect {
 	return r
 }
 
+// BackgroundPath returns a rounded rectangle around the text box given by Bounds, ie. from the ascent of the first line to the descent of the last line, grown by the padding on each side. It can be filled behind the text to draw a tag or label. For multiple lines the rectangle encloses all lines.
+func (t *Text) BackgroundPath(padding EdgeInsets, cornerRadius float64) *Path {
+	if t.Empty() {
+		return &Path{}
+	}
+	r := t.Bounds().Expand(padding)
+	return RoundedRectangle(r.W, r.H, cornerRadius).Translate(r.X, r.Y)
+}
+
+// InkBounds returns the bounding box of the glyph outlines grown by the safe area on each side, which includes the overshoot of round and pointed glyphs such as 'O' and 'A' above the cap height and below the baseline, unlike the em metrics of Bounds that may not contain the entire glyphs. It falls back to Bounds, grown by the safe area, for text without glyph outlines such as whitespace and for text with emoji, of which the images are drawn within the em box.
+func (t *Text) InkBounds(safeArea float64) Rect {
+	if t.Empty() {
+		return Rect{}
+	}
+	r := t.OutlineBounds()
+	if r.W == 0.0 || r.H == 0.0 || 0 < len(t.Emojis()) {
+		r = t.Bounds()
+	}
+	return r.Expand(EdgeInsets{safeArea, safeArea, safeArea, safeArea})
+}
+
+// InkBackgroundPath returns a rounded rectangle around the glyph outlines given by InkBounds, grown by the padding on each side, so that a tight background or border does not clip the overshoot of glyphs as BackgroundPath might. It falls back to the em metrics as InkBounds.
+func (t *Text) InkBackgroundPath(padding EdgeInsets, cornerRadius float64) *Path {
+	if t.Empty() {
+		return &Path{}
+	}
+	r := t.InkBounds(0.0).Expand(padding)
+	return RoundedRectangle(r.W, r.H, cornerRadius).Translate(r.X, r.Y)
+}
+
 // Fonts returns list of fonts used.
 func (t *Text) Fonts() []*Font {
 	fonts := []*Font{}
@@ -549,6 +1304,208 @@ func (t *Text) ToPaths() ([]*Path, []color.RGBA) {
 	return paths, colors
 }
 
+// SetShadow sets the shadow of the text that is drawn with the given color and offset by (dx,dy), it includes the decorations.
+func (t *Text) SetShadow(dx, dy float64, col color.RGBA) {
+	t.shadowOffset = Point{dx, dy}
+	t.shadowColor = col
+}
+
+// SetStroke sets the color and width of the outline that is drawn around the glyphs.
+func (t *Text) SetStroke(col color.RGBA, width float64) {
+	t.strokeColor = col
+	t.strokeWidth = width
+}
+
+// SetDrawOrder sets the order in which the layers of the text are drawn, layers that are not given are not drawn. By default the order is DefaultTextDrawOrder, ie. shadow, decorations under the glyphs, stroke, fill, and decorations over the glyphs. Each layer is drawn for all text spans before drawing the next layer.
+func (t *Text) SetDrawOrder(order ...TextLayer) {
+	t.order = order
+}
+
+// SetGlyphOpacity sets a function that returns the opacity of each glyph, such as to fade out the last characters of a truncated line. It is called with the index of the glyph in the text, counting the characters of all lines, and the horizontal position of the glyph relative to the start of the lines. The opacity is clamped between 0 and 1 and multiplies the alpha of the glyph color. Glyphs with an opacity of 0 are not drawn at all, also not for the shadow and stroke layers. When set, the glyphs are rendered as paths also by renderers that support text natively.
+func (t *Text) SetGlyphOpacity(opacity func(glyphIndex int, x float64) float64) {
+	t.opacity = opacity
+}
+
+// NudgeGlyph moves the glyph at the given index of the given line by (dx,dy) after layout, such as for manual kerning of logos and wordmarks. Lines and glyphs are counted from zero, where glyphs are the characters of all spans of the line. The glyph is separated into its own text span with a horizontal and vertical offset, so that all renderers, the text bounds and the glyph positions of its spans use the nudged position, while the other glyphs keep their positions. Decorations are not moved. Nudges of the same glyph add up, and indices that are out of range are ignored.
+func (t *Text) NudgeGlyph(lineIndex, glyphIndex int, dx, dy float64) {
+	if lineIndex < 0 || len(t.lines) <= lineIndex || glyphIndex < 0 {
+		return
+	}
+	l := &t.lines[lineIndex]
+	for j, span := range l.spans {
+		n := utf8.RuneCountInString(span.Text)
+		if n <= glyphIndex {
+			glyphIndex -= n
+			continue
+		}
+
+		pos, size := 0, 0
+		for i, r := range span.Text {
+			if glyphIndex == 0 {
+				pos, size = i, utf8.RuneLen(r)
+				break
+			}
+			glyphIndex--
+		}
+		positions := span.GlyphPositions()
+		k := utf8.RuneCountInString(span.Text[:pos])
+
+		spans := []TextSpan{}
+		if 0 < pos {
+			spans = append(spans, span.slice(0, pos))
+		}
+		glyph := span.slice(pos, pos+size)
+		glyph.dx += positions[k] + dx
+		glyph.Face.Voffset += dy
+		spans = append(spans, glyph)
+		if pos+size < len(span.Text) {
+			after := span.slice(pos+size, len(span.Text))
+			after.dx += positions[k+1]
+			spans = append(spans, after)
+		}
+		l.spans = append(l.spans[:j], append(spans, l.spans[j+1:]...)...)
+		return
+	}
+}
+
+// RenderLayers renders the layers of the text in draw order using the RenderPath method of the Renderer, except for the glyphs which are rendered by calling fill. This allows renderers that support text natively to draw the glyphs while all renderers layer the text the same way.
+func (t *Text) RenderLayers(r Renderer, m Matrix, fill func()) {
+	order := t.order
+	if order == nil {
+		order = DefaultTextDrawOrder
+	}
+	for _, layer := range order {
+		switch layer {
+		case TextShadow:
+			if t.shadowColor.A == 0 {
+				continue
+			}
+			style := DefaultStyle
+			style.FillColor = t.shadowColor
+			paths, _ := t.glyphPaths()
+			under, _ := t.decorationPaths(false)
+			over, _ := t.decorationPaths(true)
+			for _, p := range append(append(paths, under...), over...) {
+				r.RenderPath(p, style, m.Translate(t.shadowOffset.X, t.shadowOffset.Y))
+			}
+		case TextDecorationUnder, TextDecorationOver:
+			style := DefaultStyle
+			paths, colors := t.decorationPaths(layer == TextDecorationOver)
+			for i, p := range paths {
+				style.FillColor = colors[i]
+				r.RenderPath(p, style, m)
+			}
+		case TextStroke:
+			if t.strokeColor.A == 0 || t.strokeWidth <= 0.0 {
+				continue
+			}
+			style := DefaultStyle
+			style.FillColor = Transparent
+			style.StrokeColor = t.strokeColor
+			style.StrokeWidth = t.strokeWidth
+			paths, _ := t.glyphPaths()
+			for _, p := range paths {
+				r.RenderPath(p, style, m)
+			}
+		case TextFill:
+			if t.opacity != nil {
+				style := DefaultStyle
+				paths, colors := t.glyphPaths()
+				for i, p := range paths {
+					style.FillColor = colors[i]
+					r.RenderPath(p, style, m)
+				}
+				continue
+			}
+			fill()
+		}
+	}
+}
+
+// glyphPaths returns the paths of the glyphs and their colors for each text span.
+func (t *Text) glyphPaths() ([]*Path, []color.RGBA) {
+	paths := []*Path{}
+	colors := []color.RGBA{}
+	glyph := 0
+	for _, line := range t.lines {
+		for _, span := range line.spans {
+			if t.opacity != nil {
+				// draw each glyph separately with its own opacity
+				buffer := &sfnt.Buffer{}
+				for _, g := range span.glyphs() {
+					opacity := math.Max(0.0, math.Min(1.0, t.opacity(glyph, span.dx+g.x)))
+					glyph++
+					if opacity == 0.0 {
+						continue
+					}
+
+					var ps []*Path
+					var cols []color.RGBA
+					if span.Face.Font.colored {
+						ps, cols = span.Face.colorGlyph(g.index, g.x, g.y)
+					}
+					if len(ps) == 0 {
+						ps, cols = []*Path{span.glyphPath(buffer, g)}, []color.RGBA{span.Face.Color}
+					}
+					for k, p := range ps {
+						if !p.Empty() {
+							col := cols[k]
+							paths = append(paths, p.Translate(span.dx, line.y))
+							colors = append(colors, color.RGBA{
+								uint8(float64(col.R)*opacity + 0.5),
+								uint8(float64(col.G)*opacity + 0.5),
+								uint8(float64(col.B)*opacity + 0.5),
+								uint8(float64(col.A)*opacity + 0.5),
+							})
+						}
+					}
+				}
+				continue
+			}
+			if span.Face.Font.colored {
+				ps, cols := span.colorPaths()
+				for i, p := range ps {
+					paths = append(paths, p.Translate(span.dx, line.y))
+					colors = append(colors, cols[i])
+				}
+				continue
+			}
+			p, _, col := span.ToPath(span.width)
+			paths = append(paths, p.Translate(span.dx, line.y))
+			colors = append(colors, col)
+		}
+	}
+	return paths, colors
+}
+
+// decorationPaths returns the paths of the decorations drawn either over or under the glyphs and their colors.
+func (t *Text) decorationPaths(over bool) ([]*Path, []color.RGBA) {
+	paths := []*Path{}
+	colors := []color.RGBA{}
+	if !over {
+		// highlights are drawn behind all other decorations
+		for _, line := range t.lines {
+			for _, deco := range line.decos {
+				highlights, cols := deco.face.highlights(deco.x1 - deco.x0)
+				for i, p := range highlights {
+					paths = append(paths, p.Translate(deco.x0, line.y+deco.face.Voffset))
+					colors = append(colors, cols[i])
+				}
+			}
+		}
+	}
+	for _, line := range t.lines {
+		for _, deco := range line.decos {
+			p := deco.face.decorate(deco.x1-deco.x0, over)
+			if !p.Empty() {
+				paths = append(paths, p.Translate(deco.x0, line.y+deco.face.Voffset))
+				colors = append(colors, deco.face.Color)
+			}
+		}
+	}
+	return paths, colors
+}
+
 // RenderDecoration renders the text decorations using the RenderPath method of the Renderer.
 // TODO: check text decoration z-positions when text lines are overlapping https://github.com/tdewolff/canvas/pull/40#pullrequestreview-400951503
 // TODO: check compliance with https://drafts.csswg.org/css-text-decor-4/#text-line-constancy
@@ -572,6 +1529,21 @@ func (t *Text) WalkSpans(cb func(y, dx float64, span TextSpan)) {
 	}
 }
 
+// RoundBaselines returns a copy of the text of which the baseline of each line is moved to the nearest whole pixel row of an image at the given resolution when drawn with the transformation matrix m, so that the antialiasing of adjacent lines at small sizes does not bleed into each other. Each baseline is rounded from its exact position, so that rounding errors do not accumulate over the lines. The text is returned as is when m rotates or skews the baselines.
+func (t *Text) RoundBaselines(m Matrix, resolution DPMM) *Text {
+	if m[1][0] != 0.0 || m[1][1] == 0.0 {
+		return t
+	}
+	rt := *t
+	rt.lines = make([]line, len(t.lines))
+	for i, l := range t.lines {
+		y := (m[1][1]*l.y + m[1][2]) * float64(resolution)
+		l.y += (math.Floor(y+0.5) - y) / float64(resolution) / m[1][1]
+		rt.lines[i] = l
+	}
+	return &rt
+}
+
 ////////////////////////////////////////////////////////////////
 
 type decoSpan struct {
@@ -604,6 +1576,15 @@ func newTextSpan(ff FontFace, text string, i int) TextSpan {
 	}
 }
 
+// endsWithNewline returns true if the span ends with a line boundary.
+func (span TextSpan) endsWithNewline() bool {
+	if len(span.boundaries) < 2 {
+		return false
+	}
+	boundary := span.boundaries[len(span.boundaries)-2]
+	return boundary.kind == lineBoundary && boundary.pos+boundary.size == len(span.Text)
+}
+
 func (span TextSpan) TrimLeft() TextSpan {
 	if 0 < len(span.boundaries) && span.boundaries[0].pos == 0 && span.boundaries[0].kind != lineBoundary {
 		_, span1 := span.split(0)
@@ -621,6 +1602,83 @@ func (span TextSpan) TrimRight() TextSpan {
 	return span
 }
 
+// splitAt splits the span at byte position pos, which must not be inside a boundary.
+func (span TextSpan) splitAt(pos int) (TextSpan, TextSpan) {
+	span0, span1 := span, span
+	span0.Text, span1.Text = span.Text[:pos], span.Text[pos:]
+	span0.width, span1.width = span.Face.TextWidth(span0.Text), span.Face.TextWidth(span1.Text)
+	span0.boundaries, span1.boundaries = []textBoundary{}, []textBoundary{}
+	for _, boundary := range span.boundaries[:len(span.boundaries)-1] {
+		if boundary.pos < pos {
+			span0.boundaries = append(span0.boundaries, boundary)
+		} else {
+			boundary.pos -= pos
+			span1.boundaries = append(span1.boundaries, boundary)
+		}
+	}
+	span0.boundaries = append(span0.boundaries, textBoundary{eofBoundary, len(span0.Text), 0})
+	span1.boundaries = append(span1.boundaries, textBoundary{eofBoundary, len(span1.Text), 0})
+	return span0, span1
+}
+
+// slice returns the part of the span between byte positions a and b at the same horizontal position, where boundaries are cut off at a and b.
+func (span TextSpan) slice(a, b int) TextSpan {
+	span.Text = span.Text[a:b]
+	span.width = span.Face.TextWidth(span.Text)
+	boundaries := []textBoundary{}
+	for _, boundary := range span.boundaries[:len(span.boundaries)-1] {
+		start, end := boundary.pos, boundary.pos+boundary.size
+		if end <= a || b <= start {
+			continue
+		}
+		if start < a {
+			start = a
+		}
+		if b < end {
+			end = b
+		}
+		boundaries = append(boundaries, textBoundary{boundary.kind, start - a, end - start})
+	}
+	span.boundaries = append(boundaries, textBoundary{eofBoundary, len(span.Text), 0})
+	return span
+}
+
+// fit returns the byte position of the longest prefix of the span that fits in the given width, breaking only between grapheme clusters. It also returns all the positions where the span can be broken.
+func (span TextSpan) fit(width float64) (int, []int) {
+	breaks := graphemeBreaks(span.Text)
+	if runs, err := span.Face.featureRuns(span.Text); err == nil && runs != nil {
+		// synthesized features depend on the adjacent characters, bisect the prefixes instead
+		k := sort.Search(len(breaks), func(k int) bool {
+			return width < span.Face.TextWidth(span.Text[:breaks[k]])
+		})
+		if k == 0 {
+			return 0, breaks
+		}
+		return breaks[k-1], breaks
+	}
+
+	// accumulate the glyph advances, which gives the width of each prefix as TextWidth
+	n, k := 0, 0
+	m := newTextMeasurer(span.Face)
+	for i, r := range span.Text {
+		if k < len(breaks) && breaks[k] == i {
+			if width < m.width {
+				break
+			}
+			n = i
+			k++
+		}
+		m.add(r)
+	}
+	return n, breaks
+}
+
+func (span TextSpan) withFace(ff FontFace) TextSpan {
+	span.Face = ff
+	span.width = ff.TextWidth(span.Text)
+	return span
+}
+
 func (span TextSpan) Bounds(width float64) Rect {
 	p, deco, _ := span.ToPath(width)
 	return p.Bounds().Add(deco.Bounds()) // TODO: make more efficient?
@@ -657,7 +1715,12 @@ func (span TextSpan) Split(width float64) ([]TextSpan, bool) {
 		return []TextSpan{span}, true // span fits
 	}
 	for i := len(span.boundaries) - 2; i >= 0; i-- {
-		if span.boundaries[i].pos == 0 {
+		if i == len(span.boundaries)-2 && span.endsWithNewline() {
+			if span0, _ := span.split(i); span0.width <= width {
+				return []TextSpan{span}, true // span fits up to the newline, which has no width
+			}
+			continue
+		} else if span.boundaries[i].pos == 0 {
 			return []TextSpan{span}, false // boundary is at the beginning, do not split
 		}
 
@@ -677,7 +1740,7 @@ func (span TextSpan) Split(width float64) ([]TextSpan, bool) {
 func (span TextSpan) CountGlyphs() int {
 	n := 0
 	for _, r := range span.Text {
-		if s, ok := ligatures[r]; ok {
+		if s, ok := span.Face.Font.decomposeLigature(r); ok {
 			n += len(s)
 		} else {
 			n++
@@ -688,40 +1751,121 @@ func (span TextSpan) CountGlyphs() int {
 
 // ReplaceLigatures replaces all ligatures by their constituent parts
 func (span TextSpan) ReplaceLigatures() TextSpan {
-	shift := 0
+	var sb strings.Builder
+	boundaries := append([]textBoundary{}, span.boundaries...)
 	iBoundary := 0
 	for i, r := range span.Text {
-		if span.boundaries[iBoundary].pos == i {
-			span.boundaries[iBoundary].pos += shift
+		for iBoundary < len(boundaries) && boundaries[iBoundary].pos == i {
+			boundaries[iBoundary].pos = sb.Len()
 			iBoundary++
-		} else if s, ok := ligatures[r]; ok {
-			span.Text = span.Text[:i] + s + span.Text[i+utf8.RuneLen(r):]
-			shift += len(s) - 1
+		}
+		if s, ok := span.Face.Font.decomposeLigature(r); ok {
+			sb.WriteString(s)
+		} else {
+			sb.WriteRune(r)
 		}
 	}
+	span.Text = sb.String()
+	span.boundaries = boundaries
 	span.boundaries[len(span.boundaries)-1].pos = len(span.Text)
 	span.width = span.Face.TextWidth(span.Text)
 	return span
 }
 
+// spanGlyph is a glyph of a span, which is shaped for the whole text of the span.
+type spanGlyph struct {
+	r     rune
+	index sfnt.GlyphIndex // glyph index after substitution, zero for custom and missing glyphs
+	x, y  float64         // position relative to the start of the span
+}
+
+// glyphs returns a glyph for each character of the span, with the glyph indices substituted by the enabled features and the positions including kerning, the cursive attachments and the extra spacing of justification.
+func (span TextSpan) glyphs() []spanGlyph {
+	buffer := &sfnt.Buffer{}
+	positions := span.GlyphPositions()
+	offsets := span.Face.cursiveOffsets(span.Text)
+	glyphs := make([]spanGlyph, 0, len(positions))
+	for _, r := range span.Text {
+		glyph := spanGlyph{r: r, x: positions[len(glyphs)]}
+		if offsets != nil {
+			glyph.y = offsets[len(glyphs)]
+		}
+		if _, _, ok := span.Face.customGlyph(r); !ok && !isFormat(r) {
+			glyph.index, _ = span.Face.glyphIndex(buffer, r)
+		}
+		glyphs = append(glyphs, glyph)
+	}
+	return glyphs
+}
+
+// glyphPath returns the outline of a glyph of the span at its position, see FontFace.ToPath.
+func (span TextSpan) glyphPath(buffer *sfnt.Buffer, glyph spanGlyph) *Path {
+	ff := span.Face
+	if isFormat(glyph.r) {
+		return &Path{}
+	} else if p, _, ok := ff.customGlyph(glyph.r); ok {
+		return p.Translate(glyph.x, 0.0)
+	} else if glyph.index == 0 {
+		if _, ok := ff.missingAdvance(0, glyph.r); !ok && ff.Font.missing == MissingHexBox {
+			p, _ := ff.hexBox(glyph.r)
+			return p.Translate(glyph.x, 0.0)
+		} else if ok {
+			return &Path{}
+		}
+	}
+	p, err := ff.glyphPath(buffer, glyph.index, glyph.x)
+	if err != nil {
+		return &Path{}
+	}
+	return p.Translate(0.0, glyph.y)
+}
+
 // TODO: transform to Draw to canvas and cache the glyph rasterizations?
 // TODO: remove width argument and use span.width?
 func (span TextSpan) ToPath(width float64) (*Path, *Path, color.RGBA) {
+	buffer := &sfnt.Buffer{}
+	p := &Path{}
+	for _, glyph := range span.glyphs() {
+		p = p.Append(span.glyphPath(buffer, glyph))
+	}
+	return p, span.Face.Decorate(width), span.Face.Color
+}
+
+// colorPaths returns the paths and colors of the span for a font with color glyphs, where each color layer of a color glyph is a separate path and all other glyphs are drawn in the color of the span.
+func (span TextSpan) colorPaths() ([]*Path, []color.RGBA) {
+	buffer := &sfnt.Buffer{}
+	p := &Path{}
+	paths := []*Path{p}
+	colors := []color.RGBA{span.Face.Color}
+	for _, glyph := range span.glyphs() {
+		if layers, cols := span.Face.colorGlyph(glyph.index, glyph.x, glyph.y); 0 < len(layers) {
+			paths = append(paths, layers...)
+			colors = append(colors, cols...)
+		} else {
+			p = p.Append(span.glyphPath(buffer, glyph))
+		}
+	}
+	if p.Empty() {
+		return paths[1:], colors[1:]
+	}
+	paths[0] = p
+	return paths, colors
+}
+
+// GlyphPositions returns the horizontal position of each character of the span relative to the start of the span, including kerning and the extra spacing of justification.
+func (span TextSpan) GlyphPositions() []float64 {
 	iBoundary := 0
+	positions := []float64{}
 
 	x := 0.0
-	p := &Path{}
 	var rPrev rune
 	for i, r := range span.Text {
-		if i > 0 {
+		if 0 < i && rPrev != 0 && !isFormat(r) {
 			x += span.Face.Kerning(rPrev, r)
 		}
+		positions = append(positions, x)
 
-		pr, advance := span.Face.ToPath(string(r))
-		pr = pr.Translate(x, 0.0)
-		p = p.Append(pr)
-
-		x += advance + span.GlyphSpacing
+		x += span.Face.TextWidth(string(r)) + span.GlyphSpacing
 		if iBoundary < len(span.boundaries) && span.boundaries[iBoundary].pos == i {
 			boundary := span.boundaries[iBoundary]
 			if boundary.kind == sentenceBoundary {
@@ -731,9 +1875,43 @@ func (span TextSpan) ToPath(width float64) (*Path, *Path, color.RGBA) {
 			}
 			iBoundary++
 		}
+		if !isFormat(r) {
+			rPrev = r // format characters do not interrupt kerning
+		}
+	}
+	return positions
+}
+
+// gaps returns the centers and widths of the word and sentence spaces of the span, including the extra spacing of justification.
+func (span TextSpan) gaps() ([]float64, []float64) {
+	iBoundary := 0
+	centers, widths := []float64{}, []float64{}
+
+	x := 0.0
+	var rPrev rune
+	for i, r := range span.Text {
+		if i > 0 {
+			x += span.Face.Kerning(rPrev, r)
+		}
+
+		x0 := x
+		x += span.Face.TextWidth(string(r)) + span.GlyphSpacing
+		if iBoundary < len(span.boundaries) && span.boundaries[iBoundary].pos == i {
+			boundary := span.boundaries[iBoundary]
+			if boundary.kind == sentenceBoundary || boundary.kind == wordBoundary {
+				if boundary.kind == sentenceBoundary {
+					x += span.SentenceSpacing
+				} else {
+					x += span.WordSpacing
+				}
+				centers = append(centers, (x0+x)/2.0)
+				widths = append(widths, x-x0)
+			}
+			iBoundary++
+		}
 		rPrev = r
 	}
-	return p, span.Face.Decorate(width), span.Face.Color
+	return centers, widths
 }
 
 // Words returns the text of the span, split on wordBoundaries
@@ -821,6 +1999,104 @@ func calcTextBoundaries(s string, a, b int) []textBoundary {
 	return boundaries
 }
 
+// graphemeBreaks returns the byte positions between grapheme clusters, excluding the start and end of the string. This is a simplification of https://unicode.org/reports/tr29/ that keeps combining marks, variation selectors, emoji modifiers and tags, zero width joiner sequences, and regional indicator pairs together.
+func graphemeBreaks(s string) []int {
+	breaks := []int{}
+	var rPrev rune
+	regionalIndicators := 0
+	for i, r := range s {
+		isRegionalIndicator := '\U0001F1E6' <= r && r <= '\U0001F1FF'
+		if 0 < i && !isGraphemeExtend(r) && rPrev != '\u200D' && (!isRegionalIndicator || regionalIndicators%2 == 0) {
+			breaks = append(breaks, i)
+		}
+		if isRegionalIndicator {
+			regionalIndicators++
+		} else {
+			regionalIndicators = 0
+		}
+		rPrev = r
+	}
+	return breaks
+}
+
+func isGraphemeExtend(r rune) bool {
+	return isCombiningMark(r) || r == '\u200D' || '\uFE00' <= r && r <= '\uFE0F' || '\U0001F3FB' <= r && r <= '\U0001F3FF' || '\U000E0020' <= r && r <= '\U000E007F' || '\U000E0100' <= r && r <= '\U000E01EF'
+}
+
+func isZeroWidth(r rune) bool {
+	return isCombiningMark(r) || isFormat(r) || '\uFE00' <= r && r <= '\uFE0F'
+}
+
+// isFormat returns true for invisible format characters that have no advance and are not drawn, such as the zero width space and joiners, directional marks and the byte order mark (U+FEFF).
+func isFormat(r rune) bool {
+	return '\u200B' <= r && r <= '\u200F' || '\u202A' <= r && r <= '\u202E' || '\u2060' <= r && r <= '\u2064' || '\u2066' <= r && r <= '\u206F' || r == '\uFEFF'
+}
+
+// isControl returns true for the C0 and C1 control characters except for tabs and newlines.
+func isControl(r rune) bool {
+	return (r < 0x20 || 0x7F <= r && r < 0xA0) && r != '\t' && !isNewline(r)
+}
+
+// isWide returns true for characters with an East Asian Width of Wide or Fullwidth, see https://www.unicode.org/reports/tr11/
+func isWide(r rune) bool {
+	return '\u1100' <= r && r <= '\u115F' || // Hangul Jamo
+		'\u2E80' <= r && r <= '\u303E' || // CJK Radicals, Kangxi Radicals, CJK Symbols and Punctuation
+		'\u3041' <= r && r <= '\u33FF' || // Hiragana, Katakana, Bopomofo, Hangul Compatibility Jamo, Kanbun, CJK Compatibility
+		'\u3400' <= r && r <= '\u4DBF' || // CJK Unified Ideographs Extension A
+		'\u4E00' <= r && r <= '\u9FFF' || // CJK Unified Ideographs
+		'\uA000' <= r && r <= '\uA4CF' || // Yi Syllables and Radicals
+		'\uA960' <= r && r <= '\uA97F' || // Hangul Jamo Extended-A
+		'\uAC00' <= r && r <= '\uD7A3' || // Hangul Syllables
+		'\uF900' <= r && r <= '\uFAFF' || // CJK Compatibility Ideographs
+		'\uFE10' <= r && r <= '\uFE19' || // Vertical Forms
+		'\uFE30' <= r && r <= '\uFE6F' || // CJK Compatibility Forms, Small Form Variants
+		'\uFF00' <= r && r <= '\uFF60' || // Fullwidth Forms
+		'\uFFE0' <= r && r <= '\uFFE6' || // Fullwidth Signs
+		'\U0001F300' <= r && r <= '\U0001F64F' || // Miscellaneous Symbols and Pictographs, Emoticons
+		'\U0001F900' <= r && r <= '\U0001F9FF' || // Supplemental Symbols and Pictographs
+		'\U00020000' <= r && r <= '\U0002FFFD' || // CJK Unified Ideographs Extension B to F
+		'\U00030000' <= r && r <= '\U0003FFFD' // CJK Unified Ideographs Extension G
+}
+
+// scriptTags are the OpenType script tags of Unicode scripts, see https://docs.microsoft.com/en-us/typography/opentype/spec/scripttags
+var scriptTags = []struct {
+	table *unicode.RangeTable
+	tag   string
+}{
+	{unicode.Latin, "latn"},
+	{unicode.Greek, "grek"},
+	{unicode.Cyrillic, "cyrl"},
+	{unicode.Armenian, "armn"},
+	{unicode.Hebrew, "hebr"},
+	{unicode.Arabic, "arab"},
+	{unicode.Devanagari, "deva"},
+	{unicode.Bengali, "beng"},
+	{unicode.Gurmukhi, "guru"},
+	{unicode.Gujarati, "gujr"},
+	{unicode.Tamil, "taml"},
+	{unicode.Telugu, "telu"},
+	{unicode.Kannada, "knda"},
+	{unicode.Malayalam, "mlym"},
+	{unicode.Tibetan, "tibt"},
+	{unicode.Thai, "thai"},
+	{unicode.Han, "hani"},
+	{unicode.Hiragana, "kana"},
+	{unicode.Katakana, "kana"},
+	{unicode.Hangul, "hang"},
+}
+
+// textScript returns the OpenType script tag of the first character of s that belongs to a script, or an empty string if there is none.
+func textScript(s string) string {
+	for _, r := range s {
+		for _, script := range scriptTags {
+			if unicode.Is(script.table, r) {
+				return script.tag
+			}
+		}
+	}
+	return ""
+}
+
 func isNewline(r rune) bool {
 	return r == '\n' || r == '\r' || r == '\f' || r == '\v' || r == '\u2028' || r == '\u2029'
 }
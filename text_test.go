@@ -1,9 +1,16 @@
 package canvas
 
 import (
+	"encoding/binary"
+	"image"
+	"io/ioutil"
+	"math"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/tdewolff/test"
+	"golang.org/x/image/font/sfnt"
 )
 
 func TestTextLine(t *testing.T) {
@@ -32,6 +39,112 @@ func TestTextLine(t *testing.T) {
 	test.Float(t, text.lines[1].spans[0].dx, -text.lines[1].spans[0].width)
 }
 
+func TestTextCounters(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	paths, _ := NewTextLine(face, "oa", Left).ToPaths()
+	test.T(t, len(paths), 1)
+	p := paths[0]
+
+	// counters are holes when filling with NonZero, as they are with EvenOdd
+	o, _ := face.ToPath("o")
+	center := o.Bounds()
+	test.That(t, !p.Interior(center.X+center.W/2.0, center.Y+center.H/2.0, NonZero), "counter of 'o' must be empty")
+	bounds := p.Bounds()
+	for y := bounds.Y; y < bounds.Y+bounds.H; y += bounds.H / 20.0 {
+		for x := bounds.X; x < bounds.X+bounds.W; x += bounds.W / 20.0 {
+			test.T(t, p.Interior(x, y, NonZero), p.Interior(x, y, EvenOdd), x, y)
+		}
+	}
+
+	// overlapping glyphs do not cancel out
+	rt := NewRichText()
+	rt.Add(face, "oo")
+	text := rt.ToText(0.0, 0.0, Left, Top, 0.0, 0.0)
+	text.lines[0].spans[0].GlyphSpacing = -face.TextWidth("o") / 2.0
+	paths, _ = text.ToPaths()
+	q := paths[0]
+	o1, o2 := o.Translate(0.0, text.lines[0].y), o.Translate(face.TextWidth("o")/2.0, text.lines[0].y)
+	bounds = q.Bounds()
+	for y := bounds.Y; y < bounds.Y+bounds.H; y += bounds.H / 20.0 {
+		for x := bounds.X; x < bounds.X+bounds.W; x += bounds.W / 20.0 {
+			test.T(t, q.Interior(x, y, NonZero), o1.Interior(x, y, EvenOdd) || o2.Interior(x, y, EvenOdd), x, y)
+		}
+	}
+
+	// only glyphs with inconsistent winding are normalized, which is determined once per glyph
+	index, _ := face.glyphIndex(&sfnt.Buffer{}, 'o')
+	test.That(t, !face.Font.inconsistentWinding(index, o), "glyph of 'o' must be wound consistently")
+	donut := MustParseSVG("M0 0L10 0L10 10L0 10zM2 2L8 2L8 8L2 8z")
+	test.That(t, !face.Font.inconsistentWinding(index, donut), "winding must be determined once per glyph")
+	test.That(t, face.Font.inconsistentWinding(0xFFFF, donut), "donut must be wound inconsistently")
+}
+
+func TestTextSingleLine(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal, FontUnderline)
+
+	// the preprocessing of the text is the same for each feature
+	dlig := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	dlig.Ligatures = DiscretionaryLigatures
+	special := NewFontFamily("dejavu-serif")
+	special.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	special.Use(NoCommonLigatures)
+	special.SetDefectiveCluster(DefectiveDrop)
+	special.Face(12.0*ptPerMm, Black, FontRegular, FontNormal).Font.SetControlCharacter(ControlReplace)
+	faces := map[string]FontFace{
+		"underline": face,
+		"dlig":      dlig,
+		"special":   special.Face(12.0*ptPerMm, Black, FontRegular, FontNormal),
+	}
+
+	for name, face := range faces {
+		for _, s := range []string{"label", "  Caption AV. Twice!  ", "", "  ", "two\nlines", "first fly", "\u0301e", "a\x07b"} {
+			t.Run(name+"/"+s, func(t *testing.T) {
+				text := face.SingleLine(s)
+				general := NewTextBox(face, s, 0.0, 0.0, Left, Top, 0.0, 0.0)
+				test.T(t, text.Bounds(), general.Bounds())
+				test.T(t, text.OutlineBounds(), general.OutlineBounds())
+				if !text.Empty() {
+					test.String(t, text.lines[0].spans[0].Text, general.lines[0].spans[0].Text)
+				}
+
+				paths, _ := text.ToPaths()
+				generalPaths, _ := general.ToPaths()
+				p, q := &Path{}, &Path{}
+				for _, path := range paths {
+					p = p.Append(path)
+				}
+				for _, path := range generalPaths {
+					q = q.Append(path)
+				}
+				test.T(t, p, q)
+			})
+		}
+	}
+}
+
+func BenchmarkTextSingleLine(b *testing.B) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	for i := 0; i < b.N; i++ {
+		face.SingleLine("Figure 1. Axis label")
+	}
+}
+
+func BenchmarkTextBoxSingleLine(b *testing.B) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	for i := 0; i < b.N; i++ {
+		NewTextBox(face, "Figure 1. Axis label", 0.0, 0.0, Left, Top, 0.0, 0.0)
+	}
+}
+
 func TestRichText(t *testing.T) {
 	family := NewFontFamily("dejavu-serif")
 	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
@@ -122,8 +235,8 @@ func TestRichText(t *testing.T) {
 	text = rt.ToText(75.0, 30.0, Justify, Top, 0.0, 0.0)
 	test.T(t, len(text.lines), 2)
 	test.Float(t, text.lines[0].spans[0].dx, 0.0)
-	test.Float(t, text.lines[0].spans[0].width, 75.0)
-	test.Float(t, text.lines[0].spans[0].GlyphSpacing, (75.0-22.75-3.8125-MaxWordSpacing*face.Metrics().XHeight-22.75)/4)
+	test.Float(t, text.lines[0].spans[0].width, 49.3125) // ends the paragraph
+	test.Float(t, text.lines[0].spans[0].GlyphSpacing, 0.0)
 	test.Float(t, text.lines[1].spans[0].dx, 0.0)
 	test.Float(t, text.lines[1].spans[0].width, 45.5) // cannot stretch in any reasonable way
 
@@ -144,6 +257,476 @@ func TestRichText(t *testing.T) {
 	test.T(t, len(text.lines), 1)
 }
 
+// alignedGaps counts the spaces that overlap with a space on the previous line
+func alignedGaps(text *Text) int {
+	n := 0
+	var prevCenters, prevWidths []float64
+	for _, l := range text.lines {
+		centers, widths := []float64{}, []float64{}
+		for _, span := range l.spans {
+			spanCenters, spanWidths := span.gaps()
+			for i := range spanCenters {
+				centers = append(centers, span.dx+spanCenters[i])
+				widths = append(widths, spanWidths[i])
+			}
+		}
+		for i, center := range centers {
+			for j, prevCenter := range prevCenters {
+				if math.Abs(center-prevCenter) < (widths[i]+prevWidths[j])/2.0 {
+					n++
+					break
+				}
+			}
+		}
+		prevCenters, prevWidths = centers, widths
+	}
+	return n
+}
+
+func TestRichTextRivers(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	red := family.Face(12.0*ptPerMm, Red, FontRegular, FontNormal)
+
+	s := "The quick brown fox jumps over the lazy dog and the dog is not amused by the fox at all so it barks at the fox which runs into the woods where the trees are tall and the air is cool and calm at the end of a long day in the summer"
+	for _, multi := range []bool{false, true} {
+		rt := NewRichText()
+		if multi {
+			// every third word in another span
+			for i, word := range strings.Split(s, " ") {
+				if i%3 == 0 {
+					rt.Add(red, word+" ")
+				} else {
+					rt.Add(face, word+" ")
+				}
+			}
+		} else {
+			rt.Add(face, s)
+		}
+
+		for _, width := range []float64{190.0, 60.0} { // narrow columns have lines of a single word that cannot be justified
+			rt.SetRiverPenalty(0.0)
+			rivers := alignedGaps(rt.ToText(width, 0.0, Justify, Top, 0.0, 0.0))
+
+			rt.SetRiverPenalty(50.0)
+			text := rt.ToText(width, 0.0, Justify, Top, 0.0, 0.0)
+			test.That(t, alignedGaps(text) < rivers, "must have fewer aligned spaces:", multi, width)
+
+			words := []string{}
+			for i, l := range text.lines {
+				for _, span := range l.spans {
+					words = append(words, strings.Fields(span.Text)...)
+				}
+				if i+1 < len(text.lines) && width == 190.0 {
+					lastSpan := l.spans[len(l.spans)-1]
+					test.Float(t, lastSpan.dx+lastSpan.width, width) // lines are still justified
+				}
+			}
+			test.T(t, strings.Join(words, " "), s) // no text is lost
+		}
+	}
+}
+
+func TestRichTextJustifyParagraphs(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	rt := NewRichText()
+	rt.Add(face, "The quick brown fox jumps over the lazy dog.\nThe dog is not amused by the fox at all.\nIt barks.")
+	text := rt.ToText(150.0, 0.0, Justify, Top, 0.0, 0.0)
+	test.T(t, len(text.lines), 5)
+	for i, l := range text.lines {
+		lastSpan := l.spans[len(l.spans)-1]
+		if i == 1 || i == 3 || i == 4 {
+			test.Float(t, lastSpan.dx+lastSpan.width, face.TextWidth(lastSpan.Text)) // last line of paragraph is not justified
+		} else {
+			test.Float(t, lastSpan.dx+lastSpan.width, 150.0)
+		}
+	}
+}
+
+func TestRichTextFloats(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	rt := NewRichText()
+	rt.Add(face, "The quick brown fox jumps over the lazy dog and the dog is not amused by the fox at all so it barks at the fox which runs into the woods")
+	rt.SetFloats(TextFloat{Side: Left, Top: 0.0, Width: 40.0, Height: 20.0})
+	text := rt.ToText(150.0, 0.0, Left, Top, 0.0, 0.0)
+	test.That(t, 3 < len(text.lines))
+	for i, l := range text.lines {
+		lastSpan := l.spans[len(l.spans)-1]
+		test.That(t, lastSpan.dx+lastSpan.width <= 150.0)
+		if i < 2 {
+			test.Float(t, l.spans[0].dx, 40.0) // overlaps the float
+		} else {
+			test.Float(t, l.spans[0].dx, 0.0) // below the float
+		}
+	}
+
+	rt.SetFloats(TextFloat{Side: Right, Top: 15.0, Width: 60.0, Height: 5.0})
+	text = rt.ToText(150.0, 0.0, Justify, Top, 0.0, 0.0)
+	lastSpan := text.lines[0].spans[len(text.lines[0].spans)-1]
+	test.Float(t, lastSpan.dx+lastSpan.width, 150.0)
+	lastSpan = text.lines[1].spans[len(text.lines[1].spans)-1]
+	test.Float(t, lastSpan.dx+lastSpan.width, 90.0) // justified next to the float
+	test.Float(t, text.lines[1].spans[0].dx, 0.0)
+
+	// floats as wide as the box are ignored
+	rt.SetFloats(TextFloat{Side: Left, Top: 0.0, Width: 150.0, Height: 20.0})
+	text = rt.ToText(150.0, 0.0, Left, Top, 0.0, 0.0)
+	test.Float(t, text.lines[0].spans[0].dx, 0.0)
+}
+
+func TestRichTextInPath(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0, Black, FontRegular, FontNormal)
+	metrics := face.Metrics()
+
+	rt := NewRichText()
+	rt.Add(face, "The quick brown fox jumps over the lazy dog and the dog is not amused by the fox at all so it barks at the fox which runs into the woods where it hides from the dog until the night falls and the moon rises over the hills")
+	text := rt.ToTextInPath(Circle(40.0).Translate(60.0, 50.0), Center, 0.0, 0.0)
+	test.That(t, 5 < len(text.lines))
+	test.T(t, text.lines[0].spans[0].Text, "") // no room at the top
+
+	widths := []float64{}
+	for _, l := range text.lines {
+		firstSpan, lastSpan := l.spans[0], l.spans[len(l.spans)-1]
+		if lastSpan.Text == "" {
+			continue
+		}
+
+		// the line fits in the circle over its full height
+		dy := math.Max(math.Abs(l.y+metrics.Ascent+40.0), math.Abs(l.y-metrics.Descent+40.0))
+		test.That(t, dy < 40.0)
+		dx := math.Sqrt(40.0*40.0 - dy*dy)
+		x0, x1 := firstSpan.dx, lastSpan.dx+lastSpan.width
+		test.That(t, 40.0-dx-0.01 <= x0 && x1 <= 40.0+dx+0.01, "line must be inside the circle")
+		test.Float(t, x0-l.left, 80.0-l.right-x1) // centered
+		widths = append(widths, x1-x0)
+	}
+	n := len(widths)
+	test.That(t, widths[0] < widths[n/2] && widths[n-1] < widths[n/2], "lines must narrow towards the top and bottom")
+}
+
+func TestRichTextFirstLine(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	big := family.Face(24.0*ptPerMm, Red, FontRegular, FontNormal)
+
+	// "mm mm" does not fit on the first line in the bigger font face
+	rt := NewRichText()
+	rt.Add(face, "mm mm mm")
+	rt.SetFirstLineFace(big)
+	text := rt.ToText(55.0, 100.0, Left, Top, 0.0, 0.0)
+	test.T(t, len(text.lines), 2)
+	test.T(t, text.lines[0].spans[0].Text, "mm")
+	test.That(t, text.lines[0].spans[0].Face.Equals(big), "first line must use the override face")
+	test.Float(t, text.lines[0].spans[0].width, big.TextWidth("mm"))
+	test.T(t, text.lines[1].spans[0].Text, "mm mm")
+	test.That(t, text.lines[1].spans[0].Face.Equals(face), "second line must use the original face")
+
+	// justification of the first line uses the override face
+	text = rt.ToText(55.0, 100.0, Justify, Top, 0.0, 0.0)
+	test.T(t, len(text.lines), 2)
+	test.That(t, text.lines[0].spans[0].Face.Equals(big), "first line must use the override face")
+
+	// the first letter takes precedence over the first line
+	rt = NewRichText()
+	rt.Add(face, "\"mm mm mm")
+	rt.SetFirstLineFace(big)
+	rt.SetFirstLetterFace(face)
+	text = rt.ToText(100.0, 100.0, Left, Top, 0.0, 0.0)
+	test.T(t, len(text.lines), 2)
+	test.T(t, text.lines[0].spans[0].Text, "\"m")
+	test.That(t, text.lines[0].spans[0].Face.Equals(face), "first letter must use the first letter face")
+	test.T(t, text.lines[0].spans[1].Text, "m mm")
+	test.That(t, text.lines[0].spans[1].Face.Equals(big), "first line must use the override face")
+	test.Float(t, text.lines[0].spans[1].dx, face.TextWidth("\"m"))
+	test.T(t, text.lines[1].spans[0].Text, "mm")
+	test.That(t, text.lines[1].spans[0].Face.Equals(face), "second line must use the original face")
+}
+
+func TestRichTextOverflow(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	word := "https://example.com/averyveryverylongpath"
+	width := 50.0
+	var tts = []struct {
+		overflow Overflow
+		word     string
+	}{
+		{OverflowVisible, word},
+		{OverflowClip, word},
+		{OverflowBreakAnywhere, word},
+		{OverflowScale, word},
+		{OverflowBreakAnywhere, strings.Repeat("e\u0301", 20)},              // combining acute accent
+		{OverflowBreakAnywhere, strings.Repeat("\U0001F44D\U0001F3FD", 20)}, // thumbs up with skin tone modifier
+	}
+	for _, tt := range tts {
+		rt := NewRichText()
+		rt.Add(face, tt.word+" next")
+		rt.SetOverflow(tt.overflow)
+		text := rt.ToText(width, 0.0, Left, Top, 0.0, 0.0)
+
+		lines := []string{}
+		for _, line := range text.lines {
+			s := ""
+			for _, span := range line.spans {
+				s += span.Text
+				if tt.overflow != OverflowVisible {
+					test.That(t, span.dx+span.width <= width, "line must fit:", span.Text)
+				}
+			}
+			lines = append(lines, s)
+		}
+		test.T(t, lines[len(lines)-1], "next")
+
+		switch tt.overflow {
+		case OverflowVisible:
+			test.T(t, lines, []string{tt.word, "next"})
+			test.That(t, width < text.lines[0].spans[0].width, "word must overflow")
+		case OverflowClip:
+			test.T(t, len(lines), 2)
+			test.That(t, strings.HasPrefix(tt.word, lines[0]) && len(lines[0]) < len(tt.word), "word must be truncated:", lines[0])
+		case OverflowBreakAnywhere:
+			test.That(t, 2 < len(lines), "word must be broken over lines")
+			test.T(t, strings.Join(lines[:len(lines)-1], ""), tt.word)
+			for _, line := range lines[1:] {
+				r, _ := utf8.DecodeRuneInString(line)
+				test.That(t, !isGraphemeExtend(r), "line must not start inside a grapheme cluster:", line)
+			}
+		case OverflowScale:
+			test.T(t, lines, []string{tt.word, "next"})
+			test.That(t, text.lines[0].spans[0].Face.Size < face.Size, "word must be scaled down")
+			test.That(t, 0.95*width < text.lines[0].spans[0].width, "word must fill the line")
+			test.That(t, text.lines[1].spans[0].Face.Size == face.Size, "next word must not be scaled")
+		}
+	}
+}
+
+func TestTextSpanFit(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	for _, s := range []string{"AVAVAV Tot.", "e\u0301e\u0301e\u0301e\u0301"} {
+		span := newTextSpan(face, s, 0)
+		for _, width := range []float64{0.0, 5.0, 10.0, 20.0, 100.0} {
+			// the longest prefix as measured by TextWidth
+			n := 0
+			breaks := graphemeBreaks(s)
+			for _, pos := range breaks {
+				if width < face.TextWidth(s[:pos]) {
+					break
+				}
+				n = pos
+			}
+			pos, spanBreaks := span.fit(width)
+			test.T(t, pos, n, s, width)
+			test.T(t, spanBreaks, breaks)
+		}
+	}
+}
+
+func TestRichTextNewlines(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	width := face.TextWidth("aaa bbb")
+	var tts = []struct {
+		texts        []string
+		trailingLine bool
+		lines        []string
+	}{
+		{[]string{"aaa bbb ccc\nddd\n\neee"}, false, []string{"aaa bbb", "ccc", "ddd", "", "eee"}},
+		{[]string{"aaa bbb\n", "ccc"}, false, []string{"aaa bbb", "ccc"}}, // newline at the width limit
+		{[]string{"aaa bbb", "\n", "ccc"}, false, []string{"aaa bbb", "ccc"}},
+		{[]string{"aaa\n", "\nbbb"}, false, []string{"aaa", "", "bbb"}},
+		{[]string{"aaa ", "\nbbb"}, false, []string{"aaa", "bbb"}},
+		{[]string{"\naaa"}, false, []string{"", "aaa"}},
+		{[]string{"aaa\n"}, false, []string{"aaa"}},
+		{[]string{"aaa\n"}, true, []string{"aaa", ""}},
+		{[]string{"aaa\n\n"}, false, []string{"aaa", ""}},
+		{[]string{"aaa\n\n"}, true, []string{"aaa", "", ""}},
+		{[]string{"aaa"}, true, []string{"aaa"}},
+	}
+	for _, tt := range tts {
+		rt := NewRichText()
+		for _, s := range tt.texts {
+			rt.Add(face, s)
+		}
+		rt.SetTrailingNewline(tt.trailingLine)
+		text := rt.ToText(width, 0.0, Left, Top, 0.0, 0.0)
+
+		lines := []string{}
+		for _, line := range text.lines {
+			s := ""
+			for _, span := range line.spans {
+				s += strings.TrimSpace(span.Text)
+			}
+			lines = append(lines, s)
+		}
+		test.T(t, lines, tt.lines, tt.texts)
+		for i := 1; i < len(text.lines); i++ {
+			test.That(t, text.lines[i].y < text.lines[i-1].y, "lines must be below each other")
+		}
+	}
+}
+
+type pathRecorder struct {
+	paths  []*Path
+	styles []Style
+}
+
+func (r *pathRecorder) Size() (float64, float64) {
+	return 100.0, 100.0
+}
+
+func (r *pathRecorder) RenderPath(path *Path, style Style, m Matrix) {
+	r.paths = append(r.paths, path.Transform(m))
+	r.styles = append(r.styles, style)
+}
+
+func (r *pathRecorder) RenderText(text *Text, m Matrix) {
+	RenderTextAsPath(r, text, m)
+}
+
+func (r *pathRecorder) RenderImage(img image.Image, m Matrix) {}
+
+func TestTextGlyphOpacity(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	// fade out towards the right edge, where the last glyph is fully transparent
+	text := NewTextLine(face, "abcd", Left)
+	width := face.TextWidth("abc")
+	text.SetGlyphOpacity(func(i int, x float64) float64 {
+		return 1.0 - x/width
+	})
+
+	r := &pathRecorder{}
+	filled := false
+	text.RenderLayers(r, Identity, func() {
+		filled = true
+	})
+	test.That(t, !filled, "glyphs must be rendered as paths")
+	test.T(t, len(r.paths), 3)
+	test.T(t, r.styles[0].FillColor, Black)
+	positions := text.lines[0].spans[0].GlyphPositions()
+	for i := 1; i < 3; i++ {
+		a := uint8(255.0*(1.0-positions[i]/width) + 0.5)
+		test.T(t, r.styles[i].FillColor.A, a)
+		test.That(t, r.styles[i].FillColor.A < r.styles[i-1].FillColor.A, "glyphs must fade out")
+		glyph, _ := face.ToPath("abc"[i : i+1])
+		test.T(t, r.paths[i], glyph.Translate(positions[i], 0.0))
+	}
+
+	// glyphs are shaped for the whole span, including kerning and ligatures
+	text = NewTextLine(face, "AVfi", Left)
+	text.SetGlyphOpacity(func(i int, x float64) float64 {
+		return 1.0
+	})
+	paths, _ := text.glyphPaths()
+	test.T(t, len(paths), 3) // A, V and the fi ligature
+	p := &Path{}
+	for _, path := range paths {
+		p = p.Append(path)
+	}
+	span := text.lines[0].spans[0]
+	spanPath, _, _ := span.ToPath(span.width)
+	test.T(t, p, spanPath.Translate(span.dx, text.lines[0].y))
+}
+
+func TestTextNudgeGlyph(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	text := NewTextLine(face, "AV AV", Left)
+	positions := text.lines[0].spans[0].GlyphPositions()
+	text.NudgeGlyph(0, 1, 0.5, 1.0)
+	text.NudgeGlyph(0, 1, 0.5, 0.0)
+	text.NudgeGlyph(0, 10, 1.0, 1.0) // out of range
+	text.NudgeGlyph(1, 0, 1.0, 1.0)  // out of range
+	test.T(t, len(text.lines[0].spans), 3)
+
+	paths, _ := text.ToPaths()
+	p := &Path{}
+	for _, path := range paths {
+		p = p.Append(path)
+	}
+	q := &Path{}
+	for i, r := range "AV AV" {
+		glyph, _ := face.ToPath(string(r))
+		if i == 1 {
+			glyph = glyph.Translate(1.0, 1.0)
+		}
+		q = q.Append(glyph.Translate(positions[i], 0.0))
+	}
+	test.T(t, p, q)
+
+	// the nudged span moves the bounds
+	spans := text.lines[0].spans
+	test.Float(t, spans[1].dx, positions[1]+1.0)
+	test.Float(t, spans[2].dx, positions[2])
+	test.Float(t, spans[1].Face.Voffset, 1.0)
+	test.T(t, spans[2].boundaries[0].kind, wordBoundary)
+}
+
+func TestTextDrawOrder(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Red, FontRegular, FontNormal, FontUnderline, FontStrikethrough)
+
+	text := NewTextLine(face, "x", Left)
+	text.SetShadow(1.0, -1.0, Gray)
+	text.SetStroke(Blue, 0.5)
+
+	r := &pathRecorder{}
+	r.RenderText(text, Identity)
+	test.T(t, len(r.styles), 7)
+
+	// shadow of glyph, underline and strikethrough
+	for i := 0; i < 3; i++ {
+		test.T(t, r.styles[i].FillColor, Gray)
+	}
+	test.Float(t, r.paths[0].Bounds().X, r.paths[4].Bounds().X+1.0)
+
+	// underline
+	test.T(t, r.styles[3].FillColor, Red)
+	test.That(t, r.paths[3].Bounds().Y+r.paths[3].Bounds().H < 0.0, "underline must be below the baseline")
+
+	// stroke and fill of glyph
+	test.T(t, r.styles[4].FillColor, Transparent)
+	test.T(t, r.styles[4].StrokeColor, Blue)
+	test.Float(t, r.styles[4].StrokeWidth, 0.5)
+	test.T(t, r.styles[5].FillColor, Red)
+	test.T(t, r.paths[5], r.paths[4])
+
+	// strikethrough
+	test.T(t, r.styles[6].FillColor, Red)
+	test.That(t, 0.0 < r.paths[6].Bounds().Y, "strikethrough must be above the baseline")
+
+	// custom order with the underline over the glyph and no shadow
+	text.SetDrawOrder(TextFill, TextDecorationUnder)
+	glyph := r.paths[5]
+	r = &pathRecorder{}
+	r.RenderText(text, Identity)
+	test.T(t, len(r.styles), 2)
+	test.T(t, r.paths[0], glyph)
+	test.That(t, r.paths[1].Bounds().Y+r.paths[1].Bounds().H < 0.0, "underline must be drawn last")
+}
+
 func TestTextBounds(t *testing.T) {
 	family := NewFontFamily("dejavu-serif")
 	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
@@ -175,3 +758,469 @@ func TestTextBounds(t *testing.T) {
 	test.Float(t, bounds.W, face8.TextWidth("test")+face12.TextWidth("test"))
 	test.Float(t, bounds.H, 10.40625)
 }
+
+func TestTextBackgroundPath(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	text := NewTextBox(face, "chip\ntag label", 0.0, 0.0, Left, Top, 0.0, 0.0)
+	bounds := text.Bounds()
+	p := text.BackgroundPath(EdgeInsets{1.0, 2.0, 3.0, 4.0}, 1.5)
+	r := p.Bounds()
+	test.Float(t, r.X, bounds.X-4.0)
+	test.Float(t, r.Y, bounds.Y-3.0)
+	test.Float(t, r.W, bounds.W+6.0)
+	test.Float(t, r.H, bounds.H+4.0)
+	test.Float(t, r.H, text.Height()+4.0) // both lines
+
+	test.T(t, NewTextLine(face, "", Left).BackgroundPath(EdgeInsets{}, 0.0).Empty(), true)
+}
+
+func TestTextInkBounds(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	text := NewTextLine(face, "OAV", Left)
+	ink := text.OutlineBounds()
+	bounds := text.InkBounds(0.5)
+	test.Float(t, bounds.H, ink.H+1.0)
+	test.Float(t, bounds.W, ink.W+1.0)
+	test.That(t, ink.Y < 0.0, "O overshoots the baseline")
+	test.That(t, face.Metrics().CapHeight < ink.Y+ink.H, "O overshoots the cap height")
+
+	r := text.InkBackgroundPath(EdgeInsets{1.0, 2.0, 3.0, 4.0}, 1.0).Bounds()
+	test.Float(t, r.Y, ink.Y-3.0)
+	test.Float(t, r.H, ink.H+4.0)
+
+	// fall back to em metrics
+	whitespace := NewTextLine(face, "   ", Left)
+	test.T(t, whitespace.InkBounds(0.0), whitespace.Bounds())
+	emoji := NewTextLine(face, "A \U0001F600", Left)
+	test.T(t, emoji.InkBounds(0.0), emoji.Bounds())
+	test.T(t, NewTextLine(face, "", Left).InkBounds(1.0), Rect{})
+}
+
+// baseTable returns a BASE table for one script with the hanging and alphabetic baselines, where the default baseline is the hanging baseline if hanging is true.
+func baseTable(script string, hanging bool, hang int16) []byte {
+	b := make([]byte, 52)
+	binary.BigEndian.PutUint32(b[0:], 0x00010000) // version
+	binary.BigEndian.PutUint16(b[4:], 8)          // horizAxisOffset
+	binary.BigEndian.PutUint16(b[8:], 4)          // baseTagListOffset
+	binary.BigEndian.PutUint16(b[10:], 14)        // baseScriptListOffset
+	binary.BigEndian.PutUint16(b[12:], 2)         // baseTagCount
+	copy(b[14:], "hangromn")
+	binary.BigEndian.PutUint16(b[22:], 1) // baseScriptCount
+	copy(b[24:], script)
+	binary.BigEndian.PutUint16(b[28:], 8) // baseScriptOffset
+	binary.BigEndian.PutUint16(b[30:], 6) // baseValuesOffset
+	if !hanging {
+		binary.BigEndian.PutUint16(b[36:], 1) // defaultBaselineIndex
+	}
+	binary.BigEndian.PutUint16(b[38:], 2) // baseCoordCount
+	binary.BigEndian.PutUint16(b[40:], 8)
+	binary.BigEndian.PutUint16(b[42:], 12)
+	binary.BigEndian.PutUint16(b[44:], 1) // format
+	binary.BigEndian.PutUint16(b[46:], uint16(hang))
+	binary.BigEndian.PutUint16(b[48:], 1) // format
+	return b
+}
+
+func TestTextBaselines(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+
+	latin := NewFontFamily("latin")
+	test.Error(t, latin.LoadFont(addFontTables(b, map[string][]byte{"BASE": baseTable("latn", false, 1500)}), FontRegular))
+	devanagari := NewFontFamily("devanagari")
+	test.Error(t, devanagari.LoadFont(addFontTables(b, map[string][]byte{"BASE": baseTable("deva", true, 1800)}), FontRegular))
+	plain := NewFontFamily("plain")
+	test.Error(t, plain.LoadFont(b, FontRegular))
+
+	size := 12.0 * ptPerMm
+	faceLatin := latin.Face(size, Black, FontRegular, FontNormal)
+	faceDeva := devanagari.Face(size, Black, FontRegular, FontNormal)
+	facePlain := plain.Face(size, Black, FontRegular, FontNormal)
+	units := faceLatin.Size / faceLatin.Font.UnitsPerEm()
+
+	voffsets := func(faces []FontFace, texts []string) []float64 {
+		rt := NewRichText()
+		for i, face := range faces {
+			rt.Add(face, texts[i])
+		}
+		text := rt.ToText(0.0, 0.0, Left, Top, 0.0, 0.0)
+		offsets := []float64{}
+		for _, span := range text.lines[0].spans {
+			offsets = append(offsets, span.Face.Voffset)
+		}
+		return offsets
+	}
+
+	// alphabetic dominant baseline, both fonts have the alphabetic baseline at zero
+	offsets := voffsets([]FontFace{faceLatin, faceDeva}, []string{"Hello ", "नमस्ते"})
+	test.Float(t, offsets[0], 0.0)
+	test.Float(t, offsets[1], 0.0)
+
+	// hanging dominant baseline, the Latin text moves up to align its hanging baseline
+	offsets = voffsets([]FontFace{faceDeva, faceLatin}, []string{"नमस्ते ", "Hello"})
+	test.Float(t, offsets[0], 0.0)
+	test.Float(t, offsets[1], 300.0*units)
+
+	// fonts without BASE table fall back to the alphabetic baseline
+	offsets = voffsets([]FontFace{faceDeva, facePlain}, []string{"नमस्ते ", "Hello"})
+	test.Float(t, offsets[1], 0.0)
+	offsets = voffsets([]FontFace{facePlain, faceLatin}, []string{"Hello ", "world"})
+	test.Float(t, offsets[1], 0.0)
+}
+
+func TestRichTextSplit(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	faceBold := family.Face(12.0*ptPerMm, Red, FontBold, FontNormal)
+
+	words := func(text *Text) []string {
+		ws := []string{}
+		for _, line := range text.lines {
+			for _, span := range line.spans {
+				ws = append(ws, strings.Fields(span.Text)...)
+			}
+		}
+		return ws
+	}
+
+	s := "Lorem ipsum dolor sit amet, consectetur adipiscing elit. Sed do eiusmod tempor incididunt ut labore et dolore magna aliqua."
+	rt := NewRichText()
+	rt.Add(face, s)
+	rt.Add(faceBold, " Ut enim ad minim veniam.")
+
+	lineHeight := face.Metrics().LineHeight
+	text, rest := rt.Split(100.0, 2.5*lineHeight, Left, Top, 0.0, 0.0)
+	test.T(t, len(text.lines), 2)
+	test.That(t, rest != nil, "must overflow")
+	next, rest2 := rest.Split(100.0, 100.0*lineHeight, Left, Top, 0.0, 0.0)
+	test.T(t, rest2, (*RichText)(nil))
+	test.T(t, strings.Join(append(words(text), words(next)...), " "), s+" Ut enim ad minim veniam.")
+
+	// the faces of the remainder are preserved
+	lastLine := next.lines[len(next.lines)-1]
+	test.T(t, lastLine.spans[len(lastLine.spans)-1].Face.Color, Red)
+
+	// the first line is placed even if it does not fit
+	text, rest = rt.Split(100.0, 1.0, Left, Top, 0.0, 0.0)
+	test.T(t, len(text.lines), 1)
+	test.That(t, len(rest.text) < len(rt.text), "must advance")
+
+	text, rest = rt.Split(100.0, 0.0, Left, Top, 0.0, 0.0)
+	test.T(t, rest, (*RichText)(nil))
+	test.T(t, len(text.lines), len(rt.ToText(100.0, 0.0, Left, Top, 0.0, 0.0).lines))
+}
+
+func TestTextDefectiveCluster(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	text := NewTextLine(face, "́e", Left)
+	test.String(t, text.lines[0].spans[0].Text, "◌́e")
+
+	rt := NewRichText()
+	rt.Add(face, "́a")
+	rt.Add(face, "́b")
+	test.String(t, rt.ToText(0.0, 0.0, Left, Top, 0.0, 0.0).lines[0].spans[0].Text, "◌́áb")
+
+	family.SetDefectiveCluster(DefectiveDrop)
+	text = face.SingleLine("́e")
+	test.String(t, text.lines[0].spans[0].Text, "e")
+}
+
+func TestTextLigatures(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+
+	// common ligatures are enabled by default, discretionary and historical ligatures are disabled
+	text := NewTextLine(face, "first ſt", Left)
+	test.String(t, text.lines[0].spans[0].Text, "ﬁrst ſt")
+	plain := face.Font.IndicesOf(text.lines[0].spans[0].Text)
+
+	dlig := face
+	dlig.Ligatures = DiscretionaryLigatures
+	test.That(t, !dlig.Equals(face), "ligatures must distinguish font faces")
+	text = NewRichText().Add(face, "first ").Add(dlig, "first").ToText(0.0, 0.0, Left, Top, 0.0, 0.0)
+	test.String(t, text.lines[0].spans[0].Text, "ﬁrst ")
+	test.String(t, text.lines[0].spans[1].Text, "ﬁrﬆ")
+	test.That(t, face.Font.IndicesOf(text.lines[0].spans[1].Text)[2] != plain[2], "glyph must be substituted")
+
+	// the font has no hlig feature
+	hlig := face
+	hlig.Ligatures = HistoricalLigatures
+	test.String(t, hlig.SingleLine("first ſt").lines[0].spans[0].Text, "ﬁrst ſt")
+
+	// font-wide
+	family.Use(NoCommonLigatures | DiscretionaryLigatures)
+	test.String(t, NewTextLine(face, "first", Left).lines[0].spans[0].Text, "firﬆ")
+	family.Use(0)
+
+	// ligature glyphs without presentation form
+	garamond := NewFontFamily("eb-garamond")
+	garamond.LoadFontFile("font/EBGaramond12-Regular.otf", FontRegular)
+	dlig = garamond.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	dlig.Ligatures = DiscretionaryLigatures | HistoricalLigatures
+	span := NewTextLine(dlig, "The act", Left).lines[0].spans[0]
+	test.T(t, []rune(span.Text), []rune{'\U000F07BF', 'e', ' ', 'a', '\U000F07CE'})
+	components, ok := dlig.Font.LigatureText('\U000F07BF')
+	test.That(t, ok)
+	test.String(t, components, "Th")
+	test.T(t, dlig.IndicesOf(span.Text), []uint16{1983, dlig.IndicesOf("e")[0], dlig.IndicesOf(" ")[0], dlig.IndicesOf("a")[0], 1998})
+	test.T(t, span.CountGlyphs(), 7)
+	test.String(t, span.ReplaceLigatures().Text, "The act")
+	_, ok = dlig.Font.LigatureText('\U000F0001')
+	test.That(t, !ok)
+}
+
+func TestTextCellWidth(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0, Black, FontRegular, FontNormal)
+	face.CellWidth = 5.0
+
+	text := NewTextLine(face, "AV中ｱ́x", Left)
+	span := text.lines[0].spans[0]
+	test.T(t, span.GlyphPositions(), []float64{0.0, 5.0, 10.0, 20.0, 25.0, 25.0})
+	test.Float(t, span.width, 30.0)
+	test.Float(t, face.Kerning('A', 'V'), 0.0)
+
+	_, advance := face.ToPath("AV中")
+	test.Float(t, advance, 20.0)
+	p, _ := face.ToPath("AV")
+	test.That(t, 5.0 < p.Bounds().X+p.Bounds().W && p.Bounds().X+p.Bounds().W < 10.0, "glyphs must stay in their cells")
+
+	// glyphs wider than a cell occupy two cells
+	face.CellWidth = 1.0
+	test.Float(t, face.TextWidth("ab"), 4.0)
+}
+
+func TestRichTextKashida(t *testing.T) {
+	b, err := ioutil.ReadFile("font/DejaVuSerif.ttf")
+	test.Error(t, err)
+
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFont(b, FontRegular)
+	indices := family.Face(12.0, Black, FontRegular, FontNormal).Font.IndicesOf(" lox")
+
+	// map space, alef, beh and tatweel to the glyphs of space, l, o and x, and keep x for the x-height
+	groups := []struct {
+		r     rune
+		index uint16
+	}{{' ', indices[0]}, {'x', indices[3]}, {'ا', indices[1]}, {'ب', indices[2]}, {'ـ', indices[3]}}
+	cmap := make([]byte, 28+12*len(groups))
+	binary.BigEndian.PutUint16(cmap[2:], 1)   // numTables
+	binary.BigEndian.PutUint16(cmap[4:], 3)   // platformID
+	binary.BigEndian.PutUint16(cmap[6:], 10)  // encodingID
+	binary.BigEndian.PutUint32(cmap[8:], 12)  // subtableOffset
+	binary.BigEndian.PutUint16(cmap[12:], 12) // format
+	binary.BigEndian.PutUint32(cmap[16:], uint32(16+12*len(groups)))
+	binary.BigEndian.PutUint32(cmap[24:], uint32(len(groups)))
+	for i, group := range groups {
+		binary.BigEndian.PutUint32(cmap[28+12*i:], uint32(group.r))
+		binary.BigEndian.PutUint32(cmap[32+12*i:], uint32(group.r))
+		binary.BigEndian.PutUint32(cmap[36+12*i:], uint32(group.index))
+	}
+
+	arabic := NewFontFamily("arabic")
+	test.Error(t, arabic.LoadFont(addFontTables(b, map[string][]byte{"cmap": cmap}), FontRegular))
+	ff := arabic.Face(12.0, Black, FontRegular, FontNormal)
+	tatweel := ff.TextWidth("ـ")
+
+	layout := func(ff FontFace, kashida bool) string {
+		width := ff.TextWidth("ببب ببا") + 2.5*tatweel
+		rt := NewRichText()
+		rt.SetKashida(kashida)
+		rt.Add(ff, "ببب ببا ببب")
+		text := rt.ToText(width, 0.0, Justify, Top, 0.0, 0.0)
+		test.T(t, len(text.lines), 2)
+		s := ""
+		for _, span := range text.lines[0].spans {
+			s += span.Text
+		}
+		return s
+	}
+	test.String(t, layout(ff, false), "ببب ببا")
+	test.String(t, layout(ff, true), "ببـب ببـا") // at the last connection of each word, the remainder is justified with spaces
+
+	// fonts without tatweel fall back to justifying with spaces
+	test.String(t, layout(family.Face(12.0, Black, FontRegular, FontNormal), true), "ببب ببا")
+}
+
+func TestRichTextHangingPunctuation(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	width := face.TextWidth("Hello world,") + 1.0
+	comma, quote := face.TextWidth(","), face.TextWidth("“")
+
+	layout := func(hanging bool, halign TextAlign) *Text {
+		rt := NewRichText()
+		rt.SetHangingPunctuation(hanging)
+		rt.Add(face, "Hello world, “hello” world.")
+		text := rt.ToText(width, 0.0, halign, Top, 0.0, 0.0)
+		test.T(t, 2 <= len(text.lines), true)
+		return text
+	}
+	end := func(l line) float64 {
+		lastSpan := l.spans[len(l.spans)-1]
+		return lastSpan.dx + lastSpan.width
+	}
+
+	text := layout(false, Justify)
+	test.Float(t, end(text.lines[0]), width)
+	test.Float(t, text.lines[1].spans[0].dx, 0.0)
+
+	// the comma hangs past the right margin and the opening quote into the left margin
+	text = layout(true, Justify)
+	test.Float(t, end(text.lines[0]), width+comma)
+	test.Float(t, text.lines[0].spans[0].dx, 0.0)
+	test.Float(t, text.lines[1].spans[0].dx, -quote)
+
+	text = layout(true, Right)
+	test.Float(t, end(text.lines[0]), width+comma)
+
+	text = layout(true, Left)
+	test.Float(t, end(text.lines[0]), face.TextWidth("Hello world,"))
+	test.Float(t, text.lines[1].spans[0].dx, -quote)
+
+	// the amount scales with the font size
+	face.Size *= 2.0
+	lead, trail := (&RichText{hanging: true}).hang(line{spans: []TextSpan{newTextSpan(face, "“a,", 0)}})
+	test.Float(t, lead, face.TextWidth("“"))
+	test.Float(t, trail, face.TextWidth(","))
+	test.That(t, 1.9*quote < lead && 1.9*comma < trail, "hanging must scale with the font size")
+}
+
+func TestRichTextTabStops(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	dot := face.TextWidth(".")
+
+	rt := NewRichText()
+	rt.Add(face, "Introduction\t1\nA longer chapter title\t23\nTitle\t1")
+	rt.SetTabStops(TabStop{Pos: 250.0, Align: Right, Leader: "."})
+	text := rt.ToText(0.0, 0.0, Left, Top, 0.0, 0.0)
+	test.T(t, len(text.lines), 3)
+
+	for j, l := range text.lines[:2] {
+		test.T(t, len(l.spans), 3)
+		title, leader, number := l.spans[0], l.spans[1], l.spans[2]
+		test.That(t, !strings.ContainsRune(title.Text, '\t'), "tab must be removed")
+		test.String(t, number.Text, []string{"1", "23"}[j])
+		test.Float(t, number.dx+number.width, 250.0)
+
+		// leaders fill the gap on a grid of the leader width
+		test.String(t, strings.Trim(leader.Text, "."), "")
+		k := leader.dx / dot
+		test.Float(t, k, math.Round(k))
+		test.That(t, title.dx+title.width <= leader.dx, "leaders must start after the title")
+		test.That(t, leader.dx < title.dx+title.width+dot, "leaders must fill the gap")
+		test.That(t, leader.dx+float64(len(leader.Text))*dot <= number.dx+Epsilon, "leaders must end before the number")
+		test.That(t, number.dx < leader.dx+float64(len(leader.Text)+1)*dot, "leaders must fill the gap")
+	}
+
+	// gap too small for a single leader
+	rt = NewRichText()
+	rt.Add(face, "Title\t1")
+	w := face.TextWidth("Title") + face.TextWidth("1") + 0.5*dot
+	rt.SetTabStops(TabStop{Pos: w, Align: Right, Leader: "."})
+	text = rt.ToText(0.0, 0.0, Left, Top, 0.0, 0.0)
+	test.T(t, len(text.lines[0].spans), 2)
+	test.Float(t, text.lines[0].spans[1].dx, w-face.TextWidth("1"))
+
+	// left aligned tab stops and tabs without a tab stop
+	rt = NewRichText()
+	rt.Add(face, "a\tb\tc")
+	rt.SetTabStops(TabStop{Pos: 20.0})
+	text = rt.ToText(0.0, 0.0, Left, Top, 0.0, 0.0)
+	test.T(t, len(text.lines[0].spans), 3)
+	test.Float(t, text.lines[0].spans[1].dx, 20.0)
+	test.Float(t, text.lines[0].spans[2].dx, 20.0+face.TextWidth("b")+face.TextWidth(" "))
+}
+
+func TestTextControlCharacters(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	face.Font.SetMissingGlyph(MissingHexBox)
+	defer face.Font.SetMissingGlyph(MissingNotdef)
+
+	// control characters are dropped and format characters have no advance
+	text := NewTextLine(face, "\uFEFF\x01AV\x1b\u200Dmm\u2060m\x7f", Left)
+	test.String(t, text.lines[0].spans[0].Text, "\uFEFFAV\u200Dmm\u2060m")
+	test.Float(t, text.lines[0].spans[0].width, face.TextWidth("AVmmm"))
+	positions := text.lines[0].spans[0].GlyphPositions()
+	test.Float(t, positions[1], 0.0)
+	test.Float(t, positions[3], face.TextWidth("AV"))
+	test.Float(t, positions[4], face.TextWidth("AV"))
+	p, advance := face.ToPath("\uFEFF\u200B")
+	test.That(t, p.Empty(), "format characters must not be drawn")
+	test.Float(t, advance, 0.0)
+
+	// control characters are replaced
+	face.Font.SetControlCharacter(ControlReplace)
+	text = NewTextBox(face, "a\x00b\tc", 0.0, 0.0, Left, Top, 0.0, 0.0)
+	test.String(t, text.lines[0].spans[0].Text, "a\uFFFDb\tc")
+	face.Font.SetControlCharacter(ControlDrop)
+
+	// zero width space is a break opportunity but the byte order mark and word joiner are not
+	width := face.TextWidth("mmmm")
+	rt := NewRichText()
+	rt.Add(face, "mmmm\u200Bmmmm")
+	text = rt.ToText(1.5*width, 0.0, Left, Top, 0.0, 0.0)
+	test.T(t, len(text.lines), 2)
+
+	rt = NewRichText()
+	rt.Add(face, "mmmm\uFEFFmm\u2060mm")
+	text = rt.ToText(1.5*width, 0.0, Left, Top, 0.0, 0.0)
+	test.T(t, len(text.lines), 1)
+	test.Float(t, text.lines[0].spans[0].width, 2.0*width)
+}
+
+func TestFitTextToLines(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(24.0, Black, FontRegular, FontNormal)
+	s := "The quick brown fox jumps over the lazy dog"
+
+	// fits at the size of the font face
+	ff, text, truncated := FitTextToLines(face, "fox", 100.0, 1, 6.0)
+	test.That(t, !truncated)
+	test.Float(t, ff.Size, face.Size)
+	test.T(t, len(text.lines), 1)
+
+	// the largest size at which the text fits in two lines
+	width := 60.0
+	ff, text, truncated = FitTextToLines(face, s, width, 2, 6.0)
+	test.That(t, !truncated)
+	test.That(t, ff.Size < face.Size, "must be smaller")
+	test.That(t, len(text.lines) <= 2 && text.Bounds().W <= width, "must fit")
+	larger := ff
+	larger.Size *= 1.02
+	bigger := NewTextBox(larger, s, width, 0.0, Left, Top, 0.0, 0.0)
+	test.That(t, 2 < len(bigger.lines) || width < bigger.Bounds().W, "must be the largest size within 2%")
+
+	// truncated at the minimum size
+	ff, text, truncated = FitTextToLines(face, s, 20.0, 1, 10.0)
+	test.That(t, truncated)
+	test.Float(t, ff.Size, 10.0*mmPerPt)
+	test.T(t, len(text.lines), 1)
+	test.That(t, text.Bounds().W <= 20.0, "must fit")
+	line := text.lines[0].spans[len(text.lines[0].spans)-1].Text
+	test.That(t, strings.HasPrefix(s, strings.TrimSuffix(line, "…")) && strings.HasSuffix(line, "…"), "must end with an ellipsis:", line)
+
+	// a single word that is too wide
+	ff, text, truncated = FitTextToLines(face, "Pneumonoultramicroscopic", 15.0, 3, 10.0)
+	test.That(t, truncated)
+	test.T(t, len(text.lines), 1)
+	test.That(t, text.Bounds().W <= 15.0, "must fit")
+}
@@ -0,0 +1,220 @@
+package canvas
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	"golang.org/x/image/font/sfnt"
+)
+
+// textMeasureCache memoizes text widths for repeated layouts of the same strings, such as table cells or chart labels. It is disabled by default.
+var textMeasureCache = &lruCache{}
+
+// glyphOutlineCache memoizes decoded glyph outlines for rendering texts as paths. It is disabled by default.
+var glyphOutlineCache = &lruCache{}
+
+// SetTextMeasureCacheSize sets the maximum number of text widths that are memoized by FontFace.TextWidth, which is used by all text layout. The least recently used widths are evicted when the cache is full. A size of zero or less disables and clears the cache, which is the default. The cache is safe for concurrent use.
+func SetTextMeasureCacheSize(n int) {
+	textMeasureCache.resize(n)
+}
+
+// SetGlyphCacheSize sets the maximum number of glyph outlines that are memoized by FontFace.ToPath, which is used when rendering texts as paths such as by the rasterizer. The least recently used outlines are evicted when the cache is full. A size of zero or less disables and clears the cache, which is the default. The cache is safe for concurrent use, see Canvas.PreloadGlyphs to fill it in parallel.
+func SetGlyphCacheSize(n int) {
+	glyphOutlineCache.resize(n)
+}
+
+// measureKey holds all properties of a font face that affect the width of a string.
+type measureKey struct {
+	font      *Font
+	missing   MissingGlyph
+	customID  int
+	size      float64
+	cellWidth float64
+	features  string // enabled features and their fallback policies
+	s         string
+}
+
+func newMeasureKey(ff FontFace, s string) measureKey {
+	features := ""
+	for _, tag := range ff.featureTags() {
+		features += fmt.Sprintf("%s=%d,", tag, ff.Features[tag])
+	}
+	return measureKey{ff.Font, ff.Font.missing, ff.Font.customID, ff.Size * ff.Scale, ff.CellWidth, features, s}
+}
+
+// glyphKey holds all properties of a font face that affect the outline of a glyph.
+type glyphKey struct {
+	font       *Font
+	index      sfnt.GlyphIndex
+	size       float64
+	fauxBold   float64
+	fauxItalic float64
+	voffset    float64
+}
+
+func newGlyphKey(ff FontFace, index sfnt.GlyphIndex) glyphKey {
+	return glyphKey{ff.Font, index, ff.Size * ff.Scale, ff.FauxBold, ff.FauxItalic, ff.Voffset}
+}
+
+type lruEntry struct {
+	key   interface{}
+	value interface{}
+}
+
+// lruCache is a least recently used cache.
+type lruCache struct {
+	sync.Mutex
+	size    int
+	entries map[interface{}]*list.Element
+	order   *list.List // front is most recently used
+
+	hits, misses int
+}
+
+func (c *lruCache) resize(n int) {
+	c.Lock()
+	defer c.Unlock()
+	if n <= 0 {
+		c.size, c.entries, c.order = 0, nil, nil
+		c.hits, c.misses = 0, 0
+		return
+	}
+	c.size = n
+	if c.entries == nil {
+		c.entries = map[interface{}]*list.Element{}
+		c.order = list.New()
+	}
+	for c.size < c.order.Len() {
+		c.evict()
+	}
+}
+
+func (c *lruCache) enabled() bool {
+	c.Lock()
+	defer c.Unlock()
+	return c.size != 0
+}
+
+func (c *lruCache) get(key interface{}) (interface{}, bool) {
+	c.Lock()
+	defer c.Unlock()
+	if c.size == 0 {
+		return nil, false
+	}
+	if e, ok := c.entries[key]; ok {
+		c.order.MoveToFront(e)
+		c.hits++
+		return e.Value.(*lruEntry).value, true
+	}
+	c.misses++
+	return nil, false
+}
+
+func (c *lruCache) put(key, value interface{}) {
+	c.Lock()
+	defer c.Unlock()
+	if c.size == 0 {
+		return
+	}
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*lruEntry).value = value
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key, value})
+	if c.size < c.order.Len() {
+		c.evict()
+	}
+}
+
+// evict removes the least recently used entry, the cache must be locked.
+func (c *lruCache) evict() {
+	e := c.order.Back()
+	c.order.Remove(e)
+	delete(c.entries, e.Value.(*lruEntry).key)
+}
+
+func (c *lruCache) stats() (int, int) {
+	c.Lock()
+	defer c.Unlock()
+	return c.hits, c.misses
+}
+
+// PreloadGlyphs decodes the outlines of all glyphs of the texts on the canvas into the glyph cache, with at most parallelism glyphs decoded at the same time, so that rendering texts as paths afterwards does not decode them serially. This speeds up batch rendering of large documents with many distinct glyphs. It returns the number of distinct glyphs that were loaded, which is zero when the glyph cache is disabled, see SetGlyphCacheSize. Glyphs that are already cached are not decoded again, and glyphs beyond the size of the cache are evicted again.
+func (c *Canvas) PreloadGlyphs(parallelism int) int {
+	if !glyphOutlineCache.enabled() {
+		return 0
+	}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	// collect the distinct glyphs of all texts in the same way as FontFace.ToPath
+	type glyph struct {
+		ff    FontFace
+		index sfnt.GlyphIndex
+	}
+	glyphs := []glyph{}
+	seen := map[glyphKey]bool{}
+	buffer := &sfnt.Buffer{}
+	add := func(ff FontFace, index sfnt.GlyphIndex) {
+		key := newGlyphKey(ff, index)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		glyphs = append(glyphs, glyph{ff, index})
+	}
+	for _, l := range c.layers {
+		if l.text == nil {
+			continue
+		}
+		l.text.WalkSpans(func(_, _ float64, span TextSpan) {
+			ff := span.Face
+			for _, r := range span.Text {
+				if isFormat(r) {
+					continue
+				} else if _, _, ok := ff.customGlyph(r); ok {
+					continue
+				}
+				index, err := ff.glyphIndex(buffer, r)
+				if _, ok := ff.missingAdvance(index, r); err != nil || ok || index == 0 && ff.Font.missing == MissingHexBox {
+					continue
+				}
+				add(ff, index)
+				if ff.Font.colored {
+					for _, layer := range ff.Font.tables.ColorLayers(uint16(index)) {
+						add(ff, sfnt.GlyphIndex(layer.GlyphID))
+					}
+				}
+			}
+		})
+	}
+
+	// decode the outlines with a bounded number of workers, each with its own buffer
+	n := 0
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	queue := make(chan glyph)
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buffer := &sfnt.Buffer{}
+			for g := range queue {
+				if _, err := g.ff.glyphPath(buffer, g.index, 0.0); err == nil {
+					mu.Lock()
+					n++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, g := range glyphs {
+		queue <- g
+	}
+	close(queue)
+	wg.Wait()
+	return n
+}
@@ -0,0 +1,178 @@
+package canvas
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/tdewolff/test"
+	"golang.org/x/image/font/sfnt"
+)
+
+func TestTextMeasureCache(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	width := face.textWidth("Figure 1", &sfnt.Buffer{})
+
+	SetTextMeasureCacheSize(2)
+	defer SetTextMeasureCacheSize(0)
+
+	test.Float(t, face.TextWidth("Figure 1"), width)
+	test.Float(t, face.TextWidth("Figure 1"), width)
+	hits, misses := textMeasureCache.stats()
+	test.T(t, hits, 1)
+	test.T(t, misses, 1)
+
+	// different size and cell width are cached separately
+	face2 := family.Face(24.0*ptPerMm, Black, FontRegular, FontNormal)
+	test.Float(t, face2.TextWidth("Figure 1"), face2.textWidth("Figure 1", &sfnt.Buffer{}))
+	face.CellWidth = 20.0
+	test.Float(t, face.TextWidth("Figure 1"), 160.0)
+	hits, misses = textMeasureCache.stats()
+	test.T(t, hits, 1)
+	test.T(t, misses, 3)
+
+	// least recently used entry is evicted
+	test.T(t, textMeasureCache.order.Len(), 2)
+	face.CellWidth = 0.0
+	test.Float(t, face.TextWidth("Figure 1"), width)
+	hits, misses = textMeasureCache.stats()
+	test.T(t, hits, 1)
+	test.T(t, misses, 4)
+
+	// concurrent use
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				test.Float(t, face.TextWidth("Figure 1"), width)
+			}
+		}()
+	}
+	wg.Wait()
+
+	SetTextMeasureCacheSize(0)
+	hits, misses = textMeasureCache.stats()
+	test.T(t, hits, 0)
+	test.T(t, misses, 0)
+}
+
+func TestMeasureInto(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	buf := &MeasureBuffer{}
+	for _, s := range []string{"", "Figure 1", "AVAVA", "a\u200Db"} {
+		test.Float(t, face.MeasureInto(s, buf), face.TextWidth(s), s)
+	}
+	face.CellWidth = 20.0
+	test.Float(t, face.MeasureInto("Figure 1", buf), 160.0)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		face.MeasureInto("Figure 1. Axis label", buf)
+	})
+	test.T(t, allocs, 0.0)
+}
+
+func BenchmarkTextWidth(b *testing.B) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	for i := 0; i < b.N; i++ {
+		face.TextWidth("Figure 1. Axis label")
+	}
+}
+
+func BenchmarkMeasureInto(b *testing.B) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	buf := &MeasureBuffer{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		face.MeasureInto("Figure 1. Axis label", buf)
+	}
+}
+
+func BenchmarkTextWidthCache(b *testing.B) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	SetTextMeasureCacheSize(1024)
+	defer SetTextMeasureCacheSize(0)
+	for i := 0; i < b.N; i++ {
+		face.TextWidth("Figure 1. Axis label")
+	}
+}
+
+func TestGlyphCache(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	bold := family.Face(12.0*ptPerMm, Black, FontBold, FontNormal) // faux bold
+	path, _ := face.ToPath("abba")
+	boldPath, _ := bold.ToPath("ab")
+
+	c := New(100.0, 100.0)
+	ctx := NewContext(c)
+	ctx.DrawText(0.0, 0.0, NewTextLine(face, "abba", Left))
+	ctx.DrawText(0.0, 50.0, NewTextLine(bold, "ab", Left))
+	test.T(t, c.PreloadGlyphs(4), 0) // disabled
+
+	SetGlyphCacheSize(10)
+	defer SetGlyphCacheSize(0)
+
+	test.T(t, c.PreloadGlyphs(4), 4)
+	hits, misses := glyphOutlineCache.stats()
+	test.T(t, hits, 0)
+	test.T(t, misses, 4)
+
+	// rendering uses the preloaded outlines
+	cached, _ := face.ToPath("abba")
+	test.T(t, cached, path)
+	cached, _ = bold.ToPath("ab")
+	test.T(t, cached, boldPath)
+	hits, misses = glyphOutlineCache.stats()
+	test.T(t, hits, 6)
+	test.T(t, misses, 4)
+}
+
+func BenchmarkPreloadGlyphs(b *testing.B) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	c := New(200.0, 200.0)
+	ctx := NewContext(c)
+	for i := 0; i < 8; i++ {
+		face := family.Face(float64(8+i)*ptPerMm, Black, FontRegular, FontNormal)
+		s := []rune{}
+		for r := rune(0x21); r < 0x24F; r++ {
+			s = append(s, r)
+		}
+		for r := rune(0x391); r < 0x4FF; r++ {
+			s = append(s, r)
+		}
+		ctx.DrawText(0.0, float64(20*i), NewTextLine(face, string(s), Left))
+	}
+
+	for _, parallelism := range []int{0, 1, 4} {
+		name := "serial"
+		if 0 < parallelism {
+			name = fmt.Sprintf("preload%d", parallelism)
+		}
+		b.Run(name, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				SetGlyphCacheSize(10000)
+				if 0 < parallelism {
+					c.PreloadGlyphs(parallelism)
+				}
+				for _, l := range c.layers {
+					RenderTextAsPath(New(200.0, 200.0), l.text, Identity)
+				}
+				SetGlyphCacheSize(0)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package canvas
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// FitTextToLines lays out the text in the largest font size that fits within the given width in at most maxLines lines, such as for titles of responsive cards. It returns the font face with the chosen size and the text laid out as NewTextBox at the top-left. The size is searched between the size of ff and minSize, which is in points as for FontFamily.Face. If the text does not fit at minSize, it is truncated at the character after which an ellipsis (…) still fits, and truncated is true. This is also the case for a single word that is wider than the width at minSize.
+func FitTextToLines(ff FontFace, s string, width float64, maxLines int, minSize float64) (FontFace, *Text, bool) {
+	layout := func(size float64, s string) (FontFace, *Text, bool) {
+		face := ff
+		face.Size = size
+		text := NewTextBox(face, s, width, 0.0, Left, Top, 0.0, 0.0)
+		return face, text, len(text.lines) <= maxLines && text.Bounds().W <= width+Epsilon
+	}
+
+	if face, text, ok := layout(ff.Size, s); ok {
+		return face, text, false
+	}
+
+	// the font size at which the text fits is found by bisection until it is within 1% of the font size
+	lo, hi := minSize*mmPerPt, ff.Size
+	face, text, ok := layout(lo, s)
+	if ok {
+		for 0.01*lo < hi-lo {
+			mid := (lo + hi) / 2.0
+			if midFace, midText, ok := layout(mid, s); ok {
+				lo, face, text = mid, midFace, midText
+			} else {
+				hi = mid
+			}
+		}
+		return face, text, false
+	}
+
+	// truncate the text at minSize by bisection of the number of characters
+	n, m := 0, utf8.RuneCountInString(s)
+	face, text, _ = layout(lo, "…")
+	for n+1 < m {
+		mid := (n + m) / 2
+		if midFace, midText, ok := layout(lo, truncateRunes(s, mid)+"…"); ok {
+			n, face, text = mid, midFace, midText
+		} else {
+			m = mid
+		}
+	}
+	return face, text, true
+}
+
+// truncateRunes returns the first n characters of s, without trailing whitespace.
+func truncateRunes(s string, n int) string {
+	i := 0
+	for j := range s {
+		if i == n {
+			s = s[:j]
+			break
+		}
+		i++
+	}
+	return strings.TrimRightFunc(s, isWhitespace)
+}
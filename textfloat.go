@@ -0,0 +1,37 @@
+package canvas
+
+import "math"
+
+// TextFloat is a region at the left or right side of a text box that text wraps around, such as an image together with its margin, see RichText.SetFloats. Top is the distance in mm from the top of the text box to the top of the region, and Width and Height are the size of the region in mm.
+type TextFloat struct {
+	Side          TextAlign // Left or Right
+	Top           float64
+	Width, Height float64
+}
+
+// SetFloats sets the regions at the sides of the text box that text wraps around. Lines that overlap a region vertically are shortened by the width of the region and start after it for regions at the left side, and lines below all regions use the full width of the box. A region that is at least as wide as the box is ignored, so that the lines it overlaps are not wrapped around it. Floats only apply to text boxes with a width.
+func (rt *RichText) SetFloats(floats ...TextFloat) {
+	rt.floats = floats
+}
+
+// floatMargins returns the widths at the left and right side of a line that are taken by the floats overlapping the line vertically, where top and bottom are the distances from the top of the text box to the top and bottom of the line.
+func (rt *RichText) floatMargins(width, top, bottom float64) (float64, float64) {
+	left, right := 0.0, 0.0
+	if width == 0.0 {
+		return left, right
+	}
+	for _, f := range rt.floats {
+		if width <= f.Width || bottom <= f.Top || f.Top+f.Height <= top {
+			continue
+		}
+		if f.Side == Right {
+			right = math.Max(right, f.Width)
+		} else {
+			left = math.Max(left, f.Width)
+		}
+	}
+	if width <= left+right {
+		return 0.0, 0.0 // no room for text between the floats
+	}
+	return left, right
+}
@@ -0,0 +1,80 @@
+package canvas
+
+// TextFrame is an area that rich text is laid out in, where frames can be linked so that the text that does not fit one frame continues in the next, such as for columns or pages in desktop publishing. Each line of areas that are not rectangles, such as circles, is as wide as the extent of the area over the height of the line as for RichText.ToTextInPath, where the text is always aligned to the top.
+type TextFrame struct {
+	Area           *Path
+	Halign, Valign TextAlign
+	LineStretch    float64
+
+	next *TextFrame
+	text *Text
+}
+
+// NewTextFrame returns a new text frame for the given area and text alignment.
+func NewTextFrame(area *Path, halign, valign TextAlign) *TextFrame {
+	return &TextFrame{
+		Area:   area,
+		Halign: halign,
+		Valign: valign,
+	}
+}
+
+// LinkTo links the frame to the next frame, where the text continues that does not fit into this frame. It returns the next frame so that calls can be chained.
+func (f *TextFrame) LinkTo(next *TextFrame) *TextFrame {
+	f.next = next
+	return next
+}
+
+// Next returns the next linked frame or nil.
+func (f *TextFrame) Next() *TextFrame {
+	return f.next
+}
+
+// Flow lays out the rich text through the chain of linked frames starting at f, see RichText.Split and RichText.ToTextInPath. Each frame receives its portion of the text, which is empty for frames after the text has run out. It returns the text that does not fit in any of the frames, or nil when all text fits.
+func (f *TextFrame) Flow(rt *RichText) *RichText {
+	visited := map[*TextFrame]bool{}
+	for frame := f; frame != nil && !visited[frame]; frame = frame.next {
+		visited[frame] = true
+		if rt == nil {
+			frame.text = nil
+			continue
+		}
+		if bounds := frame.Area.Bounds(); isRectangle(frame.Area, bounds) {
+			frame.text, rt = rt.Split(bounds.W, bounds.H, frame.Halign, frame.Valign, 0.0, frame.LineStretch)
+		} else {
+			frame.text, rt = rt.splitInPath(frame.Area, frame.Halign, 0.0, frame.LineStretch)
+		}
+	}
+	return rt
+}
+
+// isRectangle returns true if the area is a single axis-aligned rectangle that fills its bounds.
+func isRectangle(area *Path, bounds Rect) bool {
+	if len(area.Split()) != 1 {
+		return false
+	}
+	coords := PolylineFromPath(area).coords
+	for i, coord := range coords {
+		prev := coords[(i+len(coords)-1)%len(coords)]
+		if !Equal(coord.X, bounds.X) && !Equal(coord.X, bounds.X+bounds.W) || !Equal(coord.Y, bounds.Y) && !Equal(coord.Y, bounds.Y+bounds.H) {
+			return false // not at a corner
+		} else if !Equal(coord.X, prev.X) && !Equal(coord.Y, prev.Y) {
+			return false // diagonal edge
+		}
+	}
+	return true
+}
+
+// Text returns the text laid out in the frame by Flow, or nil if the frame has no text.
+func (f *TextFrame) Text() *Text {
+	return f.text
+}
+
+// Draw draws the text of the frame at the top-left corner of its area.
+func (f *TextFrame) Draw(c *Context) {
+	if f.text == nil {
+		return
+	}
+	bounds := f.Area.Bounds()
+	c.DrawText(bounds.X, bounds.Y+bounds.H, f.text)
+}
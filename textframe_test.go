@@ -0,0 +1,81 @@
+package canvas
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestTextFrame(t *testing.T) {
+	family := NewFontFamily("dejavu-serif")
+	family.LoadFontFile("font/DejaVuSerif.ttf", FontRegular)
+	face := family.Face(12.0*ptPerMm, Black, FontRegular, FontNormal)
+	lineHeight := face.Metrics().LineHeight
+
+	words := func(frame *TextFrame) []string {
+		ws := []string{}
+		if frame.Text() != nil {
+			for _, line := range frame.Text().lines {
+				for _, span := range line.spans {
+					ws = append(ws, strings.Fields(span.Text)...)
+				}
+			}
+		}
+		return ws
+	}
+
+	s := "Lorem ipsum dolor sit amet, consectetur adipiscing elit. Sed do eiusmod tempor incididunt ut labore et dolore magna aliqua."
+	rt := NewRichText()
+	rt.Add(face, s)
+
+	first := NewTextFrame(Rectangle(60.0, 2.5*lineHeight), Left, Top)
+	second := NewTextFrame(Rectangle(60.0, 20.0*lineHeight).Translate(70.0, 0.0), Left, Top)
+	test.T(t, first.LinkTo(second), second)
+	test.T(t, first.Next(), second)
+	test.T(t, first.Flow(rt), (*RichText)(nil))
+	test.T(t, len(first.Text().lines), 2)
+	test.That(t, 0 < len(words(second)), "text must continue in the second frame")
+	test.T(t, strings.Join(append(words(first), words(second)...), " "), s)
+
+	// text exceeding all frames overflows
+	second.Area = Rectangle(60.0, 1.5*lineHeight)
+	rest := first.Flow(rt)
+	test.That(t, rest != nil, "must overflow")
+	test.T(t, len(second.Text().lines), 1)
+	test.T(t, strings.Join(append(append(words(first), words(second)...), strings.Fields(rest.text)...), " "), s)
+
+	// frames after the text has run out are empty
+	third := NewTextFrame(Rectangle(60.0, 10.0*lineHeight), Left, Top)
+	second.LinkTo(third)
+	first.Area = Rectangle(200.0, 20.0*lineHeight)
+	test.T(t, first.Flow(rt), (*RichText)(nil))
+	test.T(t, second.Text(), (*Text)(nil))
+	test.T(t, third.Text(), (*Text)(nil))
+
+	// draw at the top-left corner of the area
+	c := New(200.0, 100.0)
+	first.Draw(NewContext(c))
+	second.Draw(NewContext(c))
+	test.T(t, len(c.layers), 1)
+	test.T(t, c.layers[0].m, Identity.Translate(0.0, 20.0*lineHeight))
+
+	// lines of non-rectangular areas are as wide as the extent of the area
+	r := 3.0 * lineHeight
+	circle := Circle(r).Translate(r, r)
+	first = NewTextFrame(circle, Left, Top)
+	second = NewTextFrame(Rectangle(60.0, 20.0*lineHeight).Translate(70.0, 0.0), Left, Top)
+	first.LinkTo(second)
+	test.T(t, first.Flow(rt), (*RichText)(nil))
+	test.T(t, strings.Join(append(words(first), words(second)...), " "), s)
+	test.That(t, 1 < len(words(first)), "text must be laid out in the circle")
+	for _, l := range first.Text().lines {
+		for _, span := range l.spans {
+			if span.Text == "" {
+				continue
+			}
+			y := 2.0*r + l.y
+			test.That(t, circle.Interior(span.dx+Epsilon, y, NonZero) && circle.Interior(span.dx+span.width-Epsilon, y, NonZero), "span must be inside of the circle:", span.Text)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package canvas
+
+import (
+	"image"
+	"math"
+)
+
+// TraceOptions are the options to trace bitmaps into paths, see TraceBitmap.
+type TraceOptions struct {
+	TurdSize  int     // regions and holes with an area of at most this many pixels are removed
+	AlphaMax  float64 // corner threshold, where 0.0 gives polygons, 1.0 is the default and 4/3 or more gives no corners
+	Tolerance float64 // maximum distance in pixels between the polygons and the pixel boundaries
+}
+
+// DefaultTraceOptions are the default options for TraceBitmap, which are the same as for Potrace.
+var DefaultTraceOptions = TraceOptions{
+	TurdSize:  2,
+	AlphaMax:  1.0,
+	Tolerance: 1.0,
+}
+
+// TraceBitmap traces the black regions of an image into a path, such as to convert a scanned logo into a scalable path. Pixels are black when their luminance is below the threshold, which ranges from 0.0 to 1.0, where transparent pixels are white. Similar to Potrace, the boundaries of the black regions are approximated by polygons, after which each vertex becomes a corner or a smooth cubic Bézier depending on how sharp it is. Outer boundaries wind counter clockwise and the boundaries of holes wind clockwise, so that the path can be filled with either fill rule. Black pixels that only touch diagonally are separate regions. The path is in pixel units with the origin at the bottom-left of the image, and can be scaled to the desired size, such as with Path.Scale.
+func TraceBitmap(img image.Image, threshold float64, opts TraceOptions) *Path {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	black := make([]bool, w*h) // in rows from the bottom up
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Max.Y-1-y).RGBA()
+			lum := (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b) + float64(0xffff-a)) / 0xffff
+			black[y*w+x] = lum < threshold
+		}
+	}
+	isBlack := func(x, y int) bool {
+		return 0 <= x && x < w && 0 <= y && y < h && black[y*w+x]
+	}
+
+	// the boundary edges between black and white pixels, directed with the black pixel on the left
+	type edge struct {
+		start, dir [2]int
+	}
+	edges := []edge{}
+	outgoing := map[[2]int][]int{}
+	addEdge := func(x, y, dx, dy int) {
+		outgoing[[2]int{x, y}] = append(outgoing[[2]int{x, y}], len(edges))
+		edges = append(edges, edge{[2]int{x, y}, [2]int{dx, dy}})
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if !black[y*w+x] {
+				continue
+			}
+			if !isBlack(x, y-1) {
+				addEdge(x, y, 1, 0)
+			}
+			if !isBlack(x+1, y) {
+				addEdge(x+1, y, 0, 1)
+			}
+			if !isBlack(x, y+1) {
+				addEdge(x+1, y+1, -1, 0)
+			}
+			if !isBlack(x-1, y) {
+				addEdge(x, y+1, 0, -1)
+			}
+		}
+	}
+
+	p := &Path{}
+	used := make([]bool, len(edges))
+	for i := range edges {
+		if used[i] {
+			continue
+		}
+
+		// follow the edges until the boundary is closed, where at vertices with two outgoing edges we turn left so that diagonally touching pixels are separate
+		polygon := []Point{}
+		area := 0
+		for j := i; !used[j]; {
+			used[j] = true
+			e := edges[j]
+			end := [2]int{e.start[0] + e.dir[0], e.start[1] + e.dir[1]}
+			area += e.start[0]*end[1] - end[0]*e.start[1]
+			polygon = append(polygon, Point{float64(e.start[0]), float64(e.start[1])})
+
+			next := -1
+			for _, k := range outgoing[end] {
+				if !used[k] || k == i {
+					if next == -1 || e.dir[0]*edges[k].dir[1]-e.dir[1]*edges[k].dir[0] == 1 {
+						next = k // prefer the left turn
+					}
+				}
+			}
+			if next == -1 {
+				break
+			}
+			j = next
+		}
+		if math.Abs(float64(area))/2.0 <= float64(opts.TurdSize) {
+			continue
+		}
+		p = p.Append(tracePolygon(polygon, opts))
+	}
+	return p
+}
+
+// tracePolygon returns the closed path of a pixel boundary, which is simplified into a polygon of which the vertices become corners or are smoothed by cubic Béziers.
+func tracePolygon(boundary []Point, opts TraceOptions) *Path {
+	// keep only the vertices where the boundary turns
+	turns := []Point{}
+	for i, v := range boundary {
+		prev := boundary[(i+len(boundary)-1)%len(boundary)]
+		next := boundary[(i+1)%len(boundary)]
+		if v.Sub(prev).PerpDot(next.Sub(v)) != 0.0 {
+			turns = append(turns, v)
+		}
+	}
+	vertices := simplifyPolygon(turns, opts.Tolerance)
+	if len(vertices) < 3 {
+		vertices = turns
+	}
+
+	// vertices are corners when they are sharp, see Potrace
+	n := len(vertices)
+	corners := make([]bool, n)
+	alphas := make([]float64, n)
+	first := -1
+	for j := range vertices {
+		vi, vj, vk := vertices[(j+n-1)%n], vertices[j], vertices[(j+1)%n]
+		alpha := 4.0 / 3.0
+		d := vk.Sub(vi)
+		if denom := math.Abs(d.X) + math.Abs(d.Y); denom != 0.0 {
+			// distance of the vertex to the line through its neighbours, relative to the distance between the neighbours in the L1 norm
+			dd := math.Abs(vj.Sub(vi).PerpDot(d)) / denom
+			alpha = 0.0
+			if 1.0 < dd {
+				alpha = (1.0 - 1.0/dd) / 0.75
+			}
+		}
+		if opts.AlphaMax <= alpha {
+			corners[j] = true
+			if first == -1 {
+				first = j
+			}
+		}
+		alphas[j] = math.Max(0.55, math.Min(1.0, alpha))
+	}
+
+	// corners are connected by lines through the vertex, and smooth vertices by a cubic Bézier between the midpoints of the adjacent edges
+	p := &Path{}
+	start := 0
+	if first == -1 {
+		mid := vertices[n-1].Interpolate(vertices[0], 0.5)
+		p.MoveTo(mid.X, mid.Y)
+	} else {
+		start = first + 1
+		p.MoveTo(vertices[first].X, vertices[first].Y)
+	}
+	for i := start; i < start+n; i++ {
+		j := i % n
+		vi, vj, vk := vertices[(j+n-1)%n], vertices[j], vertices[(j+1)%n]
+		if corners[j] {
+			if j != first {
+				p.LineTo(vj.X, vj.Y)
+			}
+			continue
+		}
+		if corners[(j+n-1)%n] {
+			mid := vi.Interpolate(vj, 0.5)
+			p.LineTo(mid.X, mid.Y)
+		}
+		cp1 := vi.Interpolate(vj, 0.5+0.5*alphas[j])
+		cp2 := vk.Interpolate(vj, 0.5+0.5*alphas[j])
+		end := vj.Interpolate(vk, 0.5)
+		p.CubeTo(cp1.X, cp1.Y, cp2.X, cp2.Y, end.X, end.Y)
+	}
+	p.Close()
+	return p
+}
+
+// simplifyPolygon returns the vertices of a closed polygon that are needed to stay within tolerance of the original polygon, using the Ramer-Douglas-Peucker algorithm from the first vertex and the vertex farthest from it.
+func simplifyPolygon(polygon []Point, tolerance float64) []Point {
+	if len(polygon) < 4 {
+		return polygon
+	}
+	far, farDist := 0, 0.0
+	for i, v := range polygon {
+		if dist := v.Sub(polygon[0]).Length(); farDist < dist {
+			far, farDist = i, dist
+		}
+	}
+
+	keep := make([]bool, len(polygon))
+	keep[0], keep[far] = true, true
+	var simplify func(i, j int)
+	simplify = func(i, j int) {
+		a, b := polygon[i], polygon[j%len(polygon)]
+		d := b.Sub(a)
+		k, kDist := -1, tolerance
+		for m := i + 1; m < j; m++ {
+			if dist := math.Abs(d.PerpDot(polygon[m].Sub(a))) / d.Length(); kDist < dist {
+				k, kDist = m, dist
+			}
+		}
+		if k != -1 {
+			keep[k] = true
+			simplify(i, k)
+			simplify(k, j)
+		}
+	}
+	simplify(0, far)
+	simplify(far, len(polygon))
+
+	vertices := []Point{}
+	for i, v := range polygon {
+		if keep[i] {
+			vertices = append(vertices, v)
+		}
+	}
+	return vertices
+}
@@ -0,0 +1,50 @@
+package canvas
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/tdewolff/test"
+)
+
+func TestTraceBitmap(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 20, 20))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(5, 5, 15, 15), image.Black, image.Point{}, draw.Src)
+
+	p := TraceBitmap(img, 0.5, DefaultTraceOptions)
+	test.T(t, p.String(), "M5 5L15 5L15 15L5 15z")
+	test.That(t, p.CCW(), "outer boundary must be counter clockwise")
+
+	// a hole becomes a clockwise subpath
+	draw.Draw(img, image.Rect(8, 8, 12, 12), image.White, image.Point{}, draw.Src)
+	ps := TraceBitmap(img, 0.5, DefaultTraceOptions).Split()
+	test.T(t, len(ps), 2)
+	test.That(t, ps[0].CCW(), "outer boundary must be counter clockwise")
+	test.That(t, !ps[1].CCW(), "hole must be clockwise")
+	test.T(t, ps[1].Bounds(), Rect{8.0, 8.0, 4.0, 4.0})
+
+	// small regions are removed
+	img.SetGray(1, 1, color.Gray{0})
+	test.T(t, len(TraceBitmap(img, 0.5, DefaultTraceOptions).Split()), 2)
+	test.T(t, len(TraceBitmap(img, 0.5, TraceOptions{AlphaMax: 1.0, Tolerance: 1.0}).Split()), 3)
+
+	// a circle is smooth
+	img = image.NewGray(image.Rect(0, 0, 40, 40))
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 40; x++ {
+			if dx, dy := float64(x)-19.5, float64(y)-19.5; 15.0*15.0 < dx*dx+dy*dy {
+				img.SetGray(x, y, color.Gray{255})
+			}
+		}
+	}
+	p = TraceBitmap(img, 0.5, DefaultTraceOptions)
+	test.T(t, len(p.Split()), 1)
+	bounds := p.Bounds()
+	test.That(t, 4.5 <= bounds.X && bounds.X <= 5.5 && 29.0 <= bounds.W && bounds.W <= 31.0, "bounds must approximate the circle:", bounds)
+	for i := 0; i < len(p.d); i += cmdLen(p.d[i]) {
+		test.That(t, p.d[i] != lineToCmd, "circle must not have corners:", p)
+	}
+}
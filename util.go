@@ -17,6 +17,34 @@ var Epsilon = 1e-10
 // Precision is the number of significant digits at which floating point value will be printed to output formats.
 var Precision = 8
 
+// MaxTextLength is the maximum length in bytes of text passed to text layout, zero means no limit. See also Text.Err.
+var MaxTextLength = 0
+
+// MaxGlyphs is the maximum number of glyphs (counted as characters) of text passed to text layout, zero means no limit. See also Text.Err.
+var MaxGlyphs = 0
+
+// MaxPathPoints is the maximum number of segments of paths parsed from SVG path data and rendered to a Canvas, zero means no limit. See also Canvas.Err.
+var MaxPathPoints = 0
+
+// ErrLimitExceeded is returned when the input exceeds MaxTextLength, MaxGlyphs or MaxPathPoints.
+var ErrLimitExceeded = fmt.Errorf("resource limit exceeded")
+
+// ErrOpenPath is returned when filling a path with open subpaths while the fill policy is ErrorOpenPaths.
+var ErrOpenPath = fmt.Errorf("path has open subpaths")
+
+// ErrUnbalancedGroup is returned when a transparency group is ended without a matching BeginGroup, or while clipping paths pushed within the group are still active.
+var ErrUnbalancedGroup = fmt.Errorf("group ended without matching begin")
+
+// ErrMissingFeature is returned when text uses an OpenType feature that the font does not have while its fallback policy is FallbackError, see FontFace.Features.
+var ErrMissingFeature = fmt.Errorf("font does not have feature")
+
+func checkTextLimits(length, glyphs int) error {
+	if 0 < MaxTextLength && MaxTextLength < length || 0 < MaxGlyphs && MaxGlyphs < glyphs {
+		return ErrLimitExceeded
+	}
+	return nil
+}
+
 // Equal returns true if a and b are Equal with tolerance Epsilon.
 func Equal(a, b float64) bool {
 	return math.Abs(a-b) < Epsilon
@@ -237,6 +265,13 @@ type Rect struct {
 	X, Y, W, H float64
 }
 
+// RectFromPoints returns the rect with the given points at opposite corners.
+func RectFromPoints(a, b Point) Rect {
+	x0, x1 := math.Min(a.X, b.X), math.Max(a.X, b.X)
+	y0, y1 := math.Min(a.Y, b.Y), math.Max(a.Y, b.Y)
+	return Rect{x0, y0, x1 - x0, y1 - y0}
+}
+
 // Equals returns true if rectangles are equal with tolerance Epsilon.
 func (r Rect) Equals(q Rect) bool {
 	return Equal(r.X, q.X) && Equal(r.Y, q.Y) && Equal(r.W, q.W) && Equal(r.H, q.H)
@@ -263,6 +298,48 @@ func (r Rect) Add(q Rect) Rect {
 	return Rect{x0, y0, x1 - x0, y1 - y0}
 }
 
+// Intersect returns the rect that both the current rect and the given rect cover. It returns an empty rect if they do not overlap.
+func (r Rect) Intersect(q Rect) Rect {
+	x0 := math.Max(r.X, q.X)
+	y0 := math.Max(r.Y, q.Y)
+	x1 := math.Min(r.X+r.W, q.X+q.W)
+	y1 := math.Min(r.Y+r.H, q.Y+q.H)
+	if x1 <= x0 || y1 <= y0 {
+		return Rect{}
+	}
+	return Rect{x0, y0, x1 - x0, y1 - y0}
+}
+
+// Overlaps returns true if the current rect and the given rect overlap or touch.
+func (r Rect) Overlaps(q Rect) bool {
+	return r.X <= q.X+q.W && q.X <= r.X+r.W && r.Y <= q.Y+q.H && q.Y <= r.Y+r.H
+}
+
+// Contains returns true if the point is inside the rect or on its boundary.
+func (r Rect) Contains(p Point) bool {
+	return r.X <= p.X && p.X <= r.X+r.W && r.Y <= p.Y && p.Y <= r.Y+r.H
+}
+
+// ContainsRect returns true if the given rect is inside the current rect, where their boundaries may touch.
+func (r Rect) ContainsRect(q Rect) bool {
+	return r.X <= q.X && q.X+q.W <= r.X+r.W && r.Y <= q.Y && q.Y+q.H <= r.Y+r.H
+}
+
+// Empty returns true if the rect has no area.
+func (r Rect) Empty() bool {
+	return r.W <= 0.0 || r.H <= 0.0
+}
+
+// Min returns the bottom-left corner of the rect.
+func (r Rect) Min() Point {
+	return Point{r.X, r.Y}
+}
+
+// Max returns the top-right corner of the rect.
+func (r Rect) Max() Point {
+	return Point{r.X + r.W, r.Y + r.H}
+}
+
 // Transform transforms the rectangle by affine transformation matrix m and returns the new bounds of that rectangle.
 func (r Rect) Transform(m Matrix) Rect {
 	p0 := m.Dot(Point{r.X, r.Y})
@@ -286,6 +363,94 @@ func (r Rect) String() string {
 	return fmt.Sprintf("(%g,%g)-(%g,%g)", r.X, r.Y, r.X+r.W, r.Y+r.H)
 }
 
+// EdgeInsets are the distances to each side of a rectangle, such as the padding around a text box.
+type EdgeInsets struct {
+	Top, Right, Bottom, Left float64
+}
+
+// Expand returns the rectangle grown outwards by the insets on each side.
+func (r Rect) Expand(insets EdgeInsets) Rect {
+	return Rect{r.X - insets.Left, r.Y - insets.Bottom, r.W + insets.Left + insets.Right, r.H + insets.Bottom + insets.Top}
+}
+
+// SplitRows divides the rectangle into rows from top to bottom, where the height of each row is proportional to its ratio, such as a header, body and footer with SplitRows(1, 8, 1). Negative ratios are taken as zero, and the rows are equally high if all ratios are zero.
+func (r Rect) SplitRows(ratios ...float64) []Rect {
+	rows := []Rect{}
+	y := r.Y + r.H
+	for _, h := range splitRatios(r.H, ratios) {
+		y -= h
+		rows = append(rows, Rect{r.X, y, r.W, h})
+	}
+	return rows
+}
+
+// SplitCols divides the rectangle into columns from left to right, where the width of each column is proportional to its ratio, see SplitRows.
+func (r Rect) SplitCols(ratios ...float64) []Rect {
+	cols := []Rect{}
+	x := r.X
+	for _, w := range splitRatios(r.W, ratios) {
+		cols = append(cols, Rect{x, r.Y, w, r.H})
+		x += w
+	}
+	return cols
+}
+
+// splitRatios divides the length into parts that are proportional to the ratios.
+func splitRatios(length float64, ratios []float64) []float64 {
+	sum := 0.0
+	for _, ratio := range ratios {
+		sum += math.Max(0.0, ratio)
+	}
+	parts := make([]float64, len(ratios))
+	for i, ratio := range ratios {
+		if sum == 0.0 {
+			parts[i] = length / float64(len(ratios))
+		} else {
+			parts[i] = length * math.Max(0.0, ratio) / sum
+		}
+	}
+	return parts
+}
+
+// Grid divides a rectangle into rows and columns of equal size that are separated by gutters, for composing pages such as reports. Rows are numbered from top to bottom and columns from left to right.
+type Grid struct {
+	Rect       Rect
+	Rows, Cols int
+	Gutter     float64 // distance between cells in mm
+}
+
+// NewGrid returns a grid of rows and columns in the rectangle, separated by gutters. The number of rows and columns is at least one, and the gutter is reduced if the gutters would not fit in the rectangle, so that cells never overlap or have a negative size.
+func NewGrid(rect Rect, rows, cols int, gutter float64) Grid {
+	if rows < 1 {
+		rows = 1
+	}
+	if cols < 1 {
+		cols = 1
+	}
+	gutter = math.Max(0.0, gutter)
+	if 1 < rows {
+		gutter = math.Min(gutter, rect.H/float64(rows-1))
+	}
+	if 1 < cols {
+		gutter = math.Min(gutter, rect.W/float64(cols-1))
+	}
+	return Grid{rect, rows, cols, gutter}
+}
+
+// Cell returns the rectangle of the cell at the given row and column.
+func (g Grid) Cell(row, col int) Rect {
+	return g.Span(row, col, 1, 1)
+}
+
+// Span returns the rectangle that spans the given number of rows and columns starting at the cell at the given row and column, including the gutters between them.
+func (g Grid) Span(row, col, rows, cols int) Rect {
+	w := (g.Rect.W - float64(g.Cols-1)*g.Gutter) / float64(g.Cols)
+	h := (g.Rect.H - float64(g.Rows-1)*g.Gutter) / float64(g.Rows)
+	x := g.Rect.X + float64(col)*(w+g.Gutter)
+	y := g.Rect.Y + g.Rect.H - float64(row+rows)*(h+g.Gutter) + g.Gutter
+	return Rect{x, y, float64(cols)*(w+g.Gutter) - g.Gutter, float64(rows)*(h+g.Gutter) - g.Gutter}
+}
+
 ////////////////////////////////////////////////////////////////
 
 // Matrix is used for affine transformations. Be aware that concatenating transformation function will be evaluated right-to-left! So that Identity.Rotate(30).Translate(20,0) will first translate 20 points horizontally and then rotate 30 degrees counter clockwise.
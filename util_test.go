@@ -79,6 +79,62 @@ func TestRect(t *testing.T) {
 	test.String(t, r.String(), "(0,0)-(5,5)")
 }
 
+func TestRectSetOperations(t *testing.T) {
+	r := Rect{0, 0, 10, 10}
+	test.T(t, r.Intersect(Rect{5, 5, 10, 10}), Rect{5, 5, 5, 5})
+	test.T(t, r.Intersect(Rect{2, 2, 4, 4}), Rect{2, 2, 4, 4}) // contained
+	test.T(t, r.Intersect(Rect{20, 20, 5, 5}), Rect{})         // disjoint
+	test.T(t, r.Intersect(Rect{10, 0, 5, 5}), Rect{})          // touching
+	test.T(t, r.Add(Rect{20, 20, 5, 5}), Rect{0, 0, 25, 25})
+	test.T(t, r.Add(Rect{2, 2, 4, 4}), r)
+
+	test.That(t, r.Overlaps(Rect{5, 5, 10, 10}))
+	test.That(t, r.Overlaps(Rect{10, 0, 5, 5}), "touching rects overlap")
+	test.That(t, !r.Overlaps(Rect{20, 20, 5, 5}))
+	test.That(t, r.Contains(Point{5, 5}))
+	test.That(t, r.Contains(Point{10, 0}))
+	test.That(t, !r.Contains(Point{11, 5}))
+	test.That(t, r.ContainsRect(Rect{2, 2, 4, 4}))
+	test.That(t, r.ContainsRect(r))
+	test.That(t, !r.ContainsRect(Rect{5, 5, 10, 10}))
+
+	test.That(t, !r.Empty())
+	test.That(t, Rect{5, 5, 0, 5}.Empty())
+	test.That(t, r.Intersect(Rect{20, 20, 5, 5}).Empty())
+	test.T(t, r.Min(), Point{0, 0})
+	test.T(t, r.Max(), Point{10, 10})
+	test.T(t, RectFromPoints(Point{10, 2}, Point{4, 8}), Rect{4, 2, 6, 6})
+}
+
+func TestRectSplit(t *testing.T) {
+	r := Rect{10, 20, 40, 100}
+	test.T(t, r.SplitRows(1, 8, 1), []Rect{{10, 110, 40, 10}, {10, 30, 40, 80}, {10, 20, 40, 10}})
+	test.T(t, r.SplitCols(1, 3), []Rect{{10, 20, 10, 100}, {20, 20, 30, 100}})
+	test.T(t, r.SplitCols(-1, 1), []Rect{{10, 20, 0, 100}, {10, 20, 40, 100}})
+	test.T(t, r.SplitCols(0, 0), []Rect{{10, 20, 20, 100}, {30, 20, 20, 100}})
+	test.T(t, len(r.SplitRows()), 0)
+}
+
+func TestGrid(t *testing.T) {
+	g := NewGrid(Rect{0, 0, 210, 300}, 3, 2, 15)
+	test.T(t, g.Cell(0, 0), Rect{0, 210, 97.5, 90})
+	test.T(t, g.Cell(0, 1), Rect{112.5, 210, 97.5, 90})
+	test.T(t, g.Cell(1, 0), Rect{0, 105, 97.5, 90})
+	test.T(t, g.Cell(1, 1), Rect{112.5, 105, 97.5, 90})
+	test.T(t, g.Cell(2, 0), Rect{0, 0, 97.5, 90})
+	test.T(t, g.Cell(2, 1), Rect{112.5, 0, 97.5, 90})
+	test.T(t, g.Span(0, 0, 2, 2), Rect{0, 105, 210, 195})
+
+	// gutters are reduced to fit
+	g = NewGrid(Rect{0, 0, 10, 10}, 3, 2, 20)
+	test.Float(t, g.Gutter, 5.0)
+	test.T(t, g.Cell(0, 1), Rect{7.5, 10, 2.5, 0})
+	test.T(t, g.Cell(2, 1), Rect{7.5, 0, 2.5, 0})
+
+	g = NewGrid(Rect{0, 0, 10, 10}, 0, 1, -5)
+	test.T(t, g.Cell(0, 0), Rect{0, 0, 10, 10})
+}
+
 func TestMatrix(t *testing.T) {
 	Epsilon = 0.01
 	p := Point{3, 4}